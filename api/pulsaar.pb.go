@@ -1,667 +0,0 @@
-// Code generated by protoc-gen-go. DO NOT EDIT.
-// versions:
-// 	protoc-gen-go v1.36.11
-// 	protoc        v6.33.2
-// source: api/pulsaar.proto
-
-package api
-
-import (
-	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
-	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
-	emptypb "google.golang.org/protobuf/types/known/emptypb"
-	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
-	reflect "reflect"
-	sync "sync"
-	unsafe "unsafe"
-)
-
-const (
-	// Verify that this generated code is sufficiently up-to-date.
-	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
-	// Verify that runtime/protoimpl is sufficiently up-to-date.
-	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
-)
-
-type ListRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	AllowedRoots  []string               `protobuf:"bytes,2,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *ListRequest) Reset() {
-	*x = ListRequest{}
-	mi := &file_api_pulsaar_proto_msgTypes[0]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *ListRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*ListRequest) ProtoMessage() {}
-
-func (x *ListRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[0]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
-func (*ListRequest) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{0}
-}
-
-func (x *ListRequest) GetPath() string {
-	if x != nil {
-		return x.Path
-	}
-	return ""
-}
-
-func (x *ListRequest) GetAllowedRoots() []string {
-	if x != nil {
-		return x.AllowedRoots
-	}
-	return nil
-}
-
-type FileInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	IsDir         bool                   `protobuf:"varint,2,opt,name=is_dir,json=isDir,proto3" json:"is_dir,omitempty"`
-	SizeBytes     int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
-	Mode          string                 `protobuf:"bytes,4,opt,name=mode,proto3" json:"mode,omitempty"`
-	Mtime         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=mtime,proto3" json:"mtime,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *FileInfo) Reset() {
-	*x = FileInfo{}
-	mi := &file_api_pulsaar_proto_msgTypes[1]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *FileInfo) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*FileInfo) ProtoMessage() {}
-
-func (x *FileInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[1]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
-func (*FileInfo) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{1}
-}
-
-func (x *FileInfo) GetName() string {
-	if x != nil {
-		return x.Name
-	}
-	return ""
-}
-
-func (x *FileInfo) GetIsDir() bool {
-	if x != nil {
-		return x.IsDir
-	}
-	return false
-}
-
-func (x *FileInfo) GetSizeBytes() int64 {
-	if x != nil {
-		return x.SizeBytes
-	}
-	return 0
-}
-
-func (x *FileInfo) GetMode() string {
-	if x != nil {
-		return x.Mode
-	}
-	return ""
-}
-
-func (x *FileInfo) GetMtime() *timestamppb.Timestamp {
-	if x != nil {
-		return x.Mtime
-	}
-	return nil
-}
-
-type ListResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Entries       []*FileInfo            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *ListResponse) Reset() {
-	*x = ListResponse{}
-	mi := &file_api_pulsaar_proto_msgTypes[2]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *ListResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*ListResponse) ProtoMessage() {}
-
-func (x *ListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[2]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
-func (*ListResponse) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{2}
-}
-
-func (x *ListResponse) GetEntries() []*FileInfo {
-	if x != nil {
-		return x.Entries
-	}
-	return nil
-}
-
-type StatRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	AllowedRoots  []string               `protobuf:"bytes,2,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *StatRequest) Reset() {
-	*x = StatRequest{}
-	mi := &file_api_pulsaar_proto_msgTypes[3]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *StatRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*StatRequest) ProtoMessage() {}
-
-func (x *StatRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[3]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use StatRequest.ProtoReflect.Descriptor instead.
-func (*StatRequest) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{3}
-}
-
-func (x *StatRequest) GetPath() string {
-	if x != nil {
-		return x.Path
-	}
-	return ""
-}
-
-func (x *StatRequest) GetAllowedRoots() []string {
-	if x != nil {
-		return x.AllowedRoots
-	}
-	return nil
-}
-
-type StatResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Info          *FileInfo              `protobuf:"bytes,1,opt,name=info,proto3" json:"info,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *StatResponse) Reset() {
-	*x = StatResponse{}
-	mi := &file_api_pulsaar_proto_msgTypes[4]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *StatResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*StatResponse) ProtoMessage() {}
-
-func (x *StatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[4]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use StatResponse.ProtoReflect.Descriptor instead.
-func (*StatResponse) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{4}
-}
-
-func (x *StatResponse) GetInfo() *FileInfo {
-	if x != nil {
-		return x.Info
-	}
-	return nil
-}
-
-type ReadRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
-	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
-	AllowedRoots  []string               `protobuf:"bytes,4,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *ReadRequest) Reset() {
-	*x = ReadRequest{}
-	mi := &file_api_pulsaar_proto_msgTypes[5]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *ReadRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*ReadRequest) ProtoMessage() {}
-
-func (x *ReadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[5]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use ReadRequest.ProtoReflect.Descriptor instead.
-func (*ReadRequest) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{5}
-}
-
-func (x *ReadRequest) GetPath() string {
-	if x != nil {
-		return x.Path
-	}
-	return ""
-}
-
-func (x *ReadRequest) GetOffset() int64 {
-	if x != nil {
-		return x.Offset
-	}
-	return 0
-}
-
-func (x *ReadRequest) GetLength() int64 {
-	if x != nil {
-		return x.Length
-	}
-	return 0
-}
-
-func (x *ReadRequest) GetAllowedRoots() []string {
-	if x != nil {
-		return x.AllowedRoots
-	}
-	return nil
-}
-
-type ReadResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
-	Eof           bool                   `protobuf:"varint,2,opt,name=eof,proto3" json:"eof,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *ReadResponse) Reset() {
-	*x = ReadResponse{}
-	mi := &file_api_pulsaar_proto_msgTypes[6]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *ReadResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*ReadResponse) ProtoMessage() {}
-
-func (x *ReadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[6]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use ReadResponse.ProtoReflect.Descriptor instead.
-func (*ReadResponse) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{6}
-}
-
-func (x *ReadResponse) GetData() []byte {
-	if x != nil {
-		return x.Data
-	}
-	return nil
-}
-
-func (x *ReadResponse) GetEof() bool {
-	if x != nil {
-		return x.Eof
-	}
-	return false
-}
-
-type StreamRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	ChunkSize     int64                  `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
-	AllowedRoots  []string               `protobuf:"bytes,3,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *StreamRequest) Reset() {
-	*x = StreamRequest{}
-	mi := &file_api_pulsaar_proto_msgTypes[7]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *StreamRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*StreamRequest) ProtoMessage() {}
-
-func (x *StreamRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[7]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
-func (*StreamRequest) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{7}
-}
-
-func (x *StreamRequest) GetPath() string {
-	if x != nil {
-		return x.Path
-	}
-	return ""
-}
-
-func (x *StreamRequest) GetChunkSize() int64 {
-	if x != nil {
-		return x.ChunkSize
-	}
-	return 0
-}
-
-func (x *StreamRequest) GetAllowedRoots() []string {
-	if x != nil {
-		return x.AllowedRoots
-	}
-	return nil
-}
-
-type HealthResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ready         bool                   `protobuf:"varint,1,opt,name=ready,proto3" json:"ready,omitempty"`
-	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
-	StatusMessage string                 `protobuf:"bytes,3,opt,name=status_message,json=statusMessage,proto3" json:"status_message,omitempty"`
-	Commit        string                 `protobuf:"bytes,4,opt,name=commit,proto3" json:"commit,omitempty"`
-	Date          string                 `protobuf:"bytes,5,opt,name=date,proto3" json:"date,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *HealthResponse) Reset() {
-	*x = HealthResponse{}
-	mi := &file_api_pulsaar_proto_msgTypes[8]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *HealthResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*HealthResponse) ProtoMessage() {}
-
-func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_pulsaar_proto_msgTypes[8]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
-func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_api_pulsaar_proto_rawDescGZIP(), []int{8}
-}
-
-func (x *HealthResponse) GetReady() bool {
-	if x != nil {
-		return x.Ready
-	}
-	return false
-}
-
-func (x *HealthResponse) GetVersion() string {
-	if x != nil {
-		return x.Version
-	}
-	return ""
-}
-
-func (x *HealthResponse) GetStatusMessage() string {
-	if x != nil {
-		return x.StatusMessage
-	}
-	return ""
-}
-
-func (x *HealthResponse) GetCommit() string {
-	if x != nil {
-		return x.Commit
-	}
-	return ""
-}
-
-func (x *HealthResponse) GetDate() string {
-	if x != nil {
-		return x.Date
-	}
-	return ""
-}
-
-var File_api_pulsaar_proto protoreflect.FileDescriptor
-
-const file_api_pulsaar_proto_rawDesc = "" +
-	"\n" +
-	"\x11api/pulsaar.proto\x12\n" +
-	"pulsaar.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\"F\n" +
-	"\vListRequest\x12\x12\n" +
-	"\x04path\x18\x01 \x01(\tR\x04path\x12#\n" +
-	"\rallowed_roots\x18\x02 \x03(\tR\fallowedRoots\"\x9a\x01\n" +
-	"\bFileInfo\x12\x12\n" +
-	"\x04name\x18\x01 \x01(\tR\x04name\x12\x15\n" +
-	"\x06is_dir\x18\x02 \x01(\bR\x05isDir\x12\x1d\n" +
-	"\n" +
-	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12\x12\n" +
-	"\x04mode\x18\x04 \x01(\tR\x04mode\x120\n" +
-	"\x05mtime\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x05mtime\">\n" +
-	"\fListResponse\x12.\n" +
-	"\aentries\x18\x01 \x03(\v2\x14.pulsaar.v1.FileInfoR\aentries\"F\n" +
-	"\vStatRequest\x12\x12\n" +
-	"\x04path\x18\x01 \x01(\tR\x04path\x12#\n" +
-	"\rallowed_roots\x18\x02 \x03(\tR\fallowedRoots\"8\n" +
-	"\fStatResponse\x12(\n" +
-	"\x04info\x18\x01 \x01(\v2\x14.pulsaar.v1.FileInfoR\x04info\"v\n" +
-	"\vReadRequest\x12\x12\n" +
-	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
-	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
-	"\x06length\x18\x03 \x01(\x03R\x06length\x12#\n" +
-	"\rallowed_roots\x18\x04 \x03(\tR\fallowedRoots\"4\n" +
-	"\fReadResponse\x12\x12\n" +
-	"\x04data\x18\x01 \x01(\fR\x04data\x12\x10\n" +
-	"\x03eof\x18\x02 \x01(\bR\x03eof\"g\n" +
-	"\rStreamRequest\x12\x12\n" +
-	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1d\n" +
-	"\n" +
-	"chunk_size\x18\x02 \x01(\x03R\tchunkSize\x12#\n" +
-	"\rallowed_roots\x18\x03 \x03(\tR\fallowedRoots\"g\n" +
-	"\x0eHealthResponse\x12\x14\n" +
-	"\x05ready\x18\x01 \x01(\bR\x05ready\x12\x18\n" +
-	"\aversion\x18\x02 \x01(\tR\aversion\x12%\n" +
-	"\x0estatus_message\x18\x03 \x01(\tR\rstatusMessage2\xcf\x02\n" +
-	"\fPulsaarAgent\x12B\n" +
-	"\rListDirectory\x12\x17.pulsaar.v1.ListRequest\x1a\x18.pulsaar.v1.ListResponse\x129\n" +
-	"\x04Stat\x12\x17.pulsaar.v1.StatRequest\x1a\x18.pulsaar.v1.StatResponse\x12=\n" +
-	"\bReadFile\x12\x17.pulsaar.v1.ReadRequest\x1a\x18.pulsaar.v1.ReadResponse\x12C\n" +
-	"\n" +
-	"StreamFile\x12\x19.pulsaar.v1.StreamRequest\x1a\x18.pulsaar.v1.ReadResponse0\x01\x12<\n" +
-	"\x06Health\x12\x16.google.protobuf.Empty\x1a\x1a.pulsaar.v1.HealthResponseB*Z(github.com/VrushankPatel/pulsaar/api;apib\x06proto3"
-
-var (
-	file_api_pulsaar_proto_rawDescOnce sync.Once
-	file_api_pulsaar_proto_rawDescData []byte
-)
-
-func file_api_pulsaar_proto_rawDescGZIP() []byte {
-	file_api_pulsaar_proto_rawDescOnce.Do(func() {
-		file_api_pulsaar_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_pulsaar_proto_rawDesc), len(file_api_pulsaar_proto_rawDesc)))
-	})
-	return file_api_pulsaar_proto_rawDescData
-}
-
-var file_api_pulsaar_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
-var file_api_pulsaar_proto_goTypes = []any{
-	(*ListRequest)(nil),           // 0: pulsaar.v1.ListRequest
-	(*FileInfo)(nil),              // 1: pulsaar.v1.FileInfo
-	(*ListResponse)(nil),          // 2: pulsaar.v1.ListResponse
-	(*StatRequest)(nil),           // 3: pulsaar.v1.StatRequest
-	(*StatResponse)(nil),          // 4: pulsaar.v1.StatResponse
-	(*ReadRequest)(nil),           // 5: pulsaar.v1.ReadRequest
-	(*ReadResponse)(nil),          // 6: pulsaar.v1.ReadResponse
-	(*StreamRequest)(nil),         // 7: pulsaar.v1.StreamRequest
-	(*HealthResponse)(nil),        // 8: pulsaar.v1.HealthResponse
-	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
-	(*emptypb.Empty)(nil),         // 10: google.protobuf.Empty
-}
-var file_api_pulsaar_proto_depIdxs = []int32{
-	9,  // 0: pulsaar.v1.FileInfo.mtime:type_name -> google.protobuf.Timestamp
-	1,  // 1: pulsaar.v1.ListResponse.entries:type_name -> pulsaar.v1.FileInfo
-	1,  // 2: pulsaar.v1.StatResponse.info:type_name -> pulsaar.v1.FileInfo
-	0,  // 3: pulsaar.v1.PulsaarAgent.ListDirectory:input_type -> pulsaar.v1.ListRequest
-	3,  // 4: pulsaar.v1.PulsaarAgent.Stat:input_type -> pulsaar.v1.StatRequest
-	5,  // 5: pulsaar.v1.PulsaarAgent.ReadFile:input_type -> pulsaar.v1.ReadRequest
-	7,  // 6: pulsaar.v1.PulsaarAgent.StreamFile:input_type -> pulsaar.v1.StreamRequest
-	10, // 7: pulsaar.v1.PulsaarAgent.Health:input_type -> google.protobuf.Empty
-	2,  // 8: pulsaar.v1.PulsaarAgent.ListDirectory:output_type -> pulsaar.v1.ListResponse
-	4,  // 9: pulsaar.v1.PulsaarAgent.Stat:output_type -> pulsaar.v1.StatResponse
-	6,  // 10: pulsaar.v1.PulsaarAgent.ReadFile:output_type -> pulsaar.v1.ReadResponse
-	6,  // 11: pulsaar.v1.PulsaarAgent.StreamFile:output_type -> pulsaar.v1.ReadResponse
-	8,  // 12: pulsaar.v1.PulsaarAgent.Health:output_type -> pulsaar.v1.HealthResponse
-	8,  // [8:13] is the sub-list for method output_type
-	3,  // [3:8] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
-}
-
-func init() { file_api_pulsaar_proto_init() }
-func file_api_pulsaar_proto_init() {
-	if File_api_pulsaar_proto != nil {
-		return
-	}
-	type x struct{}
-	out := protoimpl.TypeBuilder{
-		File: protoimpl.DescBuilder{
-			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_pulsaar_proto_rawDesc), len(file_api_pulsaar_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   9,
-			NumExtensions: 0,
-			NumServices:   1,
-		},
-		GoTypes:           file_api_pulsaar_proto_goTypes,
-		DependencyIndexes: file_api_pulsaar_proto_depIdxs,
-		MessageInfos:      file_api_pulsaar_proto_msgTypes,
-	}.Build()
-	File_api_pulsaar_proto = out.File
-	file_api_pulsaar_proto_goTypes = nil
-	file_api_pulsaar_proto_depIdxs = nil
-}
@@ -0,0 +1,65 @@
+package apiv1
+
+import "slices"
+
+// Request options that agents report in CapabilitiesResponse.Features, and
+// in HealthResponse.Capabilities next to their RPCs. Agents ignore request
+// fields they do not know, so clients check for these before relying on
+// them.
+const (
+	// FeatureDecompress is the decompress field of ReadRequest and
+	// StreamRequest.
+	FeatureDecompress = "Decompress"
+	// FeatureJQ is the jq field of StreamRequest.
+	FeatureJQ = "JQ"
+	// FeatureTailPaths is the paths field of TailRequest, with globs, and
+	// the path each ReadResponse of the tail is tagged with.
+	FeatureTailPaths = "TailPaths"
+	// FeatureListFilters is the sort_by, order, name_glob, min_size and
+	// modified_since fields of ListRequest.
+	FeatureListFilters = "ListFilters"
+	// FeatureTailResume is the resume_offsets field of TailRequest and the
+	// offset each ReadResponse of the tail reports.
+	FeatureTailResume = "TailResume"
+	// FeatureReadRanges is the ranges field of ReadRequest and the parts
+	// of its ReadResponse.
+	FeatureReadRanges = "ReadRanges"
+)
+
+// How clients authenticate to an agent, as reported in
+// CapabilitiesResponse.AuthModes.
+const (
+	// AuthModeTLS is server-authenticated TLS; any client may connect.
+	AuthModeTLS = "tls"
+	// AuthModeMTLS requires a client certificate signed by the agent's CA.
+	AuthModeMTLS = "mtls"
+)
+
+// RPCs returns the names of the PulsaarAgent RPCs this version implements.
+func RPCs() []string {
+	var names []string
+	for _, m := range PulsaarAgent_ServiceDesc.Methods {
+		names = append(names, m.MethodName)
+	}
+	for _, s := range PulsaarAgent_ServiceDesc.Streams {
+		names = append(names, s.StreamName)
+	}
+	return names
+}
+
+// Features returns the request features this version implements.
+func Features() []string {
+	return []string{FeatureDecompress, FeatureJQ, FeatureTailPaths, FeatureListFilters, FeatureTailResume, FeatureReadRanges}
+}
+
+// Capabilities returns the names of the PulsaarAgent RPCs and the request
+// features this version implements, as agents report them in
+// HealthResponse.Capabilities.
+func Capabilities() []string {
+	return append(RPCs(), Features()...)
+}
+
+// Supports reports whether the agent implements the named RPC or feature.
+func (x *CapabilitiesResponse) Supports(name string) bool {
+	return slices.Contains(x.GetRpcs(), name) || slices.Contains(x.GetFeatures(), name)
+}
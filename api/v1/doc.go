@@ -0,0 +1,5 @@
+// Package apiv1 holds the generated Go types and gRPC stubs for the
+// pulsaar.v1 API defined in pulsaar.proto, with helpers for capabilities and
+// file types. Changes within v1 are additive; see "Compatibility" in
+// docs/API_REFERENCE.md.
+package apiv1
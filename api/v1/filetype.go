@@ -0,0 +1,37 @@
+package apiv1
+
+import "io/fs"
+
+// FileTypeOf returns the FileInfo.file_type for a file mode, as agents
+// report it.
+func FileTypeOf(mode fs.FileMode) FileType {
+	switch {
+	case mode.IsRegular():
+		return FileType_FILE_TYPE_REGULAR
+	case mode.IsDir():
+		return FileType_FILE_TYPE_DIRECTORY
+	case mode&fs.ModeSymlink != 0:
+		return FileType_FILE_TYPE_SYMLINK
+	case mode&fs.ModeNamedPipe != 0:
+		return FileType_FILE_TYPE_NAMED_PIPE
+	case mode&fs.ModeSocket != 0:
+		return FileType_FILE_TYPE_SOCKET
+	case mode&fs.ModeCharDevice != 0:
+		return FileType_FILE_TYPE_CHAR_DEVICE
+	case mode&fs.ModeDevice != 0:
+		return FileType_FILE_TYPE_BLOCK_DEVICE
+	}
+	return FileType_FILE_TYPE_IRREGULAR
+}
+
+// IsSpecial reports whether the entry is a named pipe, socket, device or
+// irregular file, whose content cannot be read like a file's: opening a
+// pipe blocks until a writer appears and a device may never end. Entries
+// from agents that predate file_type are never special.
+func (x *FileInfo) IsSpecial() bool {
+	switch x.GetFileType() {
+	case FileType_FILE_TYPE_UNSPECIFIED, FileType_FILE_TYPE_REGULAR, FileType_FILE_TYPE_DIRECTORY, FileType_FILE_TYPE_SYMLINK:
+		return false
+	}
+	return true
+}
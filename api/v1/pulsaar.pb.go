@@ -0,0 +1,2558 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.2
+// source: api/v1/pulsaar.proto
+
+// Package pulsaar.v1 is the agent and audit API. Within v1, changes are
+// additive only: fields and RPCs are added, never renumbered, retyped or
+// renamed. A field that must go is marked deprecated first and, once
+// removed, its number and name are reserved so they are never reused. buf
+// breaking (buf.yaml) enforces this against master. See "Compatibility" in
+// docs/API_REFERENCE.md.
+
+package apiv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FileType int32
+
+const (
+	FileType_FILE_TYPE_UNSPECIFIED  FileType = 0
+	FileType_FILE_TYPE_REGULAR      FileType = 1
+	FileType_FILE_TYPE_DIRECTORY    FileType = 2
+	FileType_FILE_TYPE_SYMLINK      FileType = 3
+	FileType_FILE_TYPE_NAMED_PIPE   FileType = 4
+	FileType_FILE_TYPE_SOCKET       FileType = 5
+	FileType_FILE_TYPE_BLOCK_DEVICE FileType = 6
+	FileType_FILE_TYPE_CHAR_DEVICE  FileType = 7
+	FileType_FILE_TYPE_IRREGULAR    FileType = 8
+)
+
+// Enum value maps for FileType.
+var (
+	FileType_name = map[int32]string{
+		0: "FILE_TYPE_UNSPECIFIED",
+		1: "FILE_TYPE_REGULAR",
+		2: "FILE_TYPE_DIRECTORY",
+		3: "FILE_TYPE_SYMLINK",
+		4: "FILE_TYPE_NAMED_PIPE",
+		5: "FILE_TYPE_SOCKET",
+		6: "FILE_TYPE_BLOCK_DEVICE",
+		7: "FILE_TYPE_CHAR_DEVICE",
+		8: "FILE_TYPE_IRREGULAR",
+	}
+	FileType_value = map[string]int32{
+		"FILE_TYPE_UNSPECIFIED":  0,
+		"FILE_TYPE_REGULAR":      1,
+		"FILE_TYPE_DIRECTORY":    2,
+		"FILE_TYPE_SYMLINK":      3,
+		"FILE_TYPE_NAMED_PIPE":   4,
+		"FILE_TYPE_SOCKET":       5,
+		"FILE_TYPE_BLOCK_DEVICE": 6,
+		"FILE_TYPE_CHAR_DEVICE":  7,
+		"FILE_TYPE_IRREGULAR":    8,
+	}
+)
+
+func (x FileType) Enum() *FileType {
+	p := new(FileType)
+	*p = x
+	return p
+}
+
+func (x FileType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FileType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_v1_pulsaar_proto_enumTypes[0].Descriptor()
+}
+
+func (FileType) Type() protoreflect.EnumType {
+	return &file_api_v1_pulsaar_proto_enumTypes[0]
+}
+
+func (x FileType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FileType.Descriptor instead.
+func (FileType) EnumDescriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{0}
+}
+
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	AllowedRoots  []string               `protobuf:"bytes,2,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	PageSize      int32                  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	SortBy        string                 `protobuf:"bytes,5,opt,name=sort_by,json=sortBy,proto3" json:"sort_by,omitempty"`
+	Order         string                 `protobuf:"bytes,6,opt,name=order,proto3" json:"order,omitempty"`
+	NameGlob      string                 `protobuf:"bytes,7,opt,name=name_glob,json=nameGlob,proto3" json:"name_glob,omitempty"`
+	MinSize       int64                  `protobuf:"varint,8,opt,name=min_size,json=minSize,proto3" json:"min_size,omitempty"`
+	ModifiedSince *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=modified_since,json=modifiedSince,proto3" json:"modified_since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ListRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+func (x *ListRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListRequest) GetSortBy() string {
+	if x != nil {
+		return x.SortBy
+	}
+	return ""
+}
+
+func (x *ListRequest) GetOrder() string {
+	if x != nil {
+		return x.Order
+	}
+	return ""
+}
+
+func (x *ListRequest) GetNameGlob() string {
+	if x != nil {
+		return x.NameGlob
+	}
+	return ""
+}
+
+func (x *ListRequest) GetMinSize() int64 {
+	if x != nil {
+		return x.MinSize
+	}
+	return 0
+}
+
+func (x *ListRequest) GetModifiedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModifiedSince
+	}
+	return nil
+}
+
+type FileInfo struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Name      string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	IsDir     bool                   `protobuf:"varint,2,opt,name=is_dir,json=isDir,proto3" json:"is_dir,omitempty"`
+	SizeBytes int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	Mode      string                 `protobuf:"bytes,4,opt,name=mode,proto3" json:"mode,omitempty"`
+	Mtime     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=mtime,proto3" json:"mtime,omitempty"`
+	// "uid:gid" on Unix; the owner account on Windows.
+	Owner    string   `protobuf:"bytes,6,opt,name=owner,proto3" json:"owner,omitempty"`
+	FileType FileType `protobuf:"varint,7,opt,name=file_type,json=fileType,proto3,enum=pulsaar.v1.FileType" json:"file_type,omitempty"`
+	// What a symlink points at, as stored in the link; empty otherwise.
+	LinkTarget string `protobuf:"bytes,8,opt,name=link_target,json=linkTarget,proto3" json:"link_target,omitempty"`
+	// The number of hard links, or 0 where the platform does not report it.
+	HardLinks     int64 `protobuf:"varint,9,opt,name=hard_links,json=hardLinks,proto3" json:"hard_links,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FileInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FileInfo) GetIsDir() bool {
+	if x != nil {
+		return x.IsDir
+	}
+	return false
+}
+
+func (x *FileInfo) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *FileInfo) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *FileInfo) GetMtime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Mtime
+	}
+	return nil
+}
+
+func (x *FileInfo) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *FileInfo) GetFileType() FileType {
+	if x != nil {
+		return x.FileType
+	}
+	return FileType_FILE_TYPE_UNSPECIFIED
+}
+
+func (x *FileInfo) GetLinkTarget() string {
+	if x != nil {
+		return x.LinkTarget
+	}
+	return ""
+}
+
+func (x *FileInfo) GetHardLinks() int64 {
+	if x != nil {
+		return x.HardLinks
+	}
+	return 0
+}
+
+type ListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*FileInfo            `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListResponse) GetEntries() []*FileInfo {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type StatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	AllowedRoots  []string               `protobuf:"bytes,2,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatRequest) Reset() {
+	*x = StatRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatRequest) ProtoMessage() {}
+
+func (x *StatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatRequest.ProtoReflect.Descriptor instead.
+func (*StatRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StatRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *StatRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+type StatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Info          *FileInfo              `protobuf:"bytes,1,opt,name=info,proto3" json:"info,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatResponse) Reset() {
+	*x = StatResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatResponse) ProtoMessage() {}
+
+func (x *StatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatResponse.ProtoReflect.Descriptor instead.
+func (*StatResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StatResponse) GetInfo() *FileInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+type ReadRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Path         string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Offset       int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length       int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	AllowedRoots []string               `protobuf:"bytes,4,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	Decompress   bool                   `protobuf:"varint,5,opt,name=decompress,proto3" json:"decompress,omitempty"`
+	// Ranges to read instead of offset and length, returned in
+	// ReadResponse.parts in the same order. Their lengths count against the
+	// same limit as one read.
+	Ranges        []*ByteRange `protobuf:"bytes,6,rep,name=ranges,proto3" json:"ranges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadRequest) Reset() {
+	*x = ReadRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadRequest) ProtoMessage() {}
+
+func (x *ReadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadRequest.ProtoReflect.Descriptor instead.
+func (*ReadRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ReadRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ReadRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ReadRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *ReadRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+func (x *ReadRequest) GetDecompress() bool {
+	if x != nil {
+		return x.Decompress
+	}
+	return false
+}
+
+func (x *ReadRequest) GetRanges() []*ByteRange {
+	if x != nil {
+		return x.Ranges
+	}
+	return nil
+}
+
+type ByteRange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Negative offsets count back from the end of the file, so -4096 is the
+	// last 4KB.
+	Offset        int64 `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length        int64 `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ByteRange) Reset() {
+	*x = ByteRange{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ByteRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ByteRange) ProtoMessage() {}
+
+func (x *ByteRange) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ByteRange.ProtoReflect.Descriptor instead.
+func (*ByteRange) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ByteRange) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ByteRange) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type ReadResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Data  []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Eof   bool                   `protobuf:"varint,2,opt,name=eof,proto3" json:"eof,omitempty"`
+	Path  string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	// For TailFile, the offset in the file just past the data read for this
+	// message, to resume from with TailRequest.resume_offsets.
+	Offset int64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	// One part per ReadRequest.ranges entry.
+	Parts         []*ReadPart `protobuf:"bytes,5,rep,name=parts,proto3" json:"parts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadResponse) Reset() {
+	*x = ReadResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadResponse) ProtoMessage() {}
+
+func (x *ReadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadResponse.ProtoReflect.Descriptor instead.
+func (*ReadResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReadResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ReadResponse) GetEof() bool {
+	if x != nil {
+		return x.Eof
+	}
+	return false
+}
+
+func (x *ReadResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ReadResponse) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ReadResponse) GetParts() []*ReadPart {
+	if x != nil {
+		return x.Parts
+	}
+	return nil
+}
+
+type ReadPart struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Where the part starts in the file, with negative request offsets
+	// resolved.
+	Offset int64  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Data   []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	// The part reached the end of the file.
+	Eof           bool `protobuf:"varint,3,opt,name=eof,proto3" json:"eof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadPart) Reset() {
+	*x = ReadPart{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadPart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadPart) ProtoMessage() {}
+
+func (x *ReadPart) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadPart.ProtoReflect.Descriptor instead.
+func (*ReadPart) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReadPart) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ReadPart) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ReadPart) GetEof() bool {
+	if x != nil {
+		return x.Eof
+	}
+	return false
+}
+
+type StreamRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	ChunkSize     int64                  `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	AllowedRoots  []string               `protobuf:"bytes,3,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	Decompress    bool                   `protobuf:"varint,4,opt,name=decompress,proto3" json:"decompress,omitempty"`
+	Jq            string                 `protobuf:"bytes,5,opt,name=jq,proto3" json:"jq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamRequest) Reset() {
+	*x = StreamRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest) ProtoMessage() {}
+
+func (x *StreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
+func (*StreamRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StreamRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *StreamRequest) GetChunkSize() int64 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+func (x *StreamRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+func (x *StreamRequest) GetDecompress() bool {
+	if x != nil {
+		return x.Decompress
+	}
+	return false
+}
+
+func (x *StreamRequest) GetJq() string {
+	if x != nil {
+		return x.Jq
+	}
+	return ""
+}
+
+type HealthResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ready         bool                   `protobuf:"varint,1,opt,name=ready,proto3" json:"ready,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	StatusMessage string                 `protobuf:"bytes,3,opt,name=status_message,json=statusMessage,proto3" json:"status_message,omitempty"`
+	Commit        string                 `protobuf:"bytes,4,opt,name=commit,proto3" json:"commit,omitempty"`
+	Date          string                 `protobuf:"bytes,5,opt,name=date,proto3" json:"date,omitempty"`
+	// Superseded by the Capabilities RPC; still set for clients that predate
+	// it.
+	//
+	// Deprecated: Marked as deprecated in api/v1/pulsaar.proto.
+	Capabilities []string `protobuf:"bytes,6,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// What the agent process may read on Linux: non-root, dac-read-search
+	// (CAP_DAC_READ_SEARCH only), root, or elevated for other capabilities.
+	// Empty when unknown, such as on Windows.
+	Privilege string `protobuf:"bytes,7,opt,name=privilege,proto3" json:"privilege,omitempty"`
+	// How the agent is confined; unset by agents that predate it.
+	Posture       *SecurityPosture `protobuf:"bytes,8,opt,name=posture,proto3" json:"posture,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *HealthResponse) GetReady() bool {
+	if x != nil {
+		return x.Ready
+	}
+	return false
+}
+
+func (x *HealthResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetStatusMessage() string {
+	if x != nil {
+		return x.StatusMessage
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+// Deprecated: Marked as deprecated in api/v1/pulsaar.proto.
+func (x *HealthResponse) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *HealthResponse) GetPrivilege() string {
+	if x != nil {
+		return x.Privilege
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetPosture() *SecurityPosture {
+	if x != nil {
+		return x.Posture
+	}
+	return nil
+}
+
+// SecurityPosture is what an agent runs with and may do, so its
+// confinement can be checked from outside the pod.
+type SecurityPosture struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Effective user and group IDs; -1 where unknown, such as on Windows.
+	Uid int64 `protobuf:"varint,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Gid int64 `protobuf:"varint,2,opt,name=gid,proto3" json:"gid,omitempty"`
+	// Effective Linux capabilities, such as CAP_DAC_READ_SEARCH.
+	LinuxCapabilities []string `protobuf:"bytes,3,rep,name=linux_capabilities,json=linuxCapabilities,proto3" json:"linux_capabilities,omitempty"`
+	// Sandbox layers in force, such as Landlock and seccomp.
+	Sandbox []string `protobuf:"bytes,4,rep,name=sandbox,proto3" json:"sandbox,omitempty"`
+	// Why the sandbox is not complete, such as being turned off, the agent
+	// not being ready yet or the kernel lacking Landlock; empty when it is.
+	SandboxMessage string `protobuf:"bytes,5,opt,name=sandbox_message,json=sandboxMessage,proto3" json:"sandbox_message,omitempty"`
+	// Roots served when a request names none; empty until the agent is
+	// ready.
+	AllowedRoots []string `protobuf:"bytes,6,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	// Request limits; 0 means none.
+	MaxDecompressedBytes int64 `protobuf:"varint,7,opt,name=max_decompressed_bytes,json=maxDecompressedBytes,proto3" json:"max_decompressed_bytes,omitempty"`
+	RpcTimeoutMs         int64 `protobuf:"varint,8,opt,name=rpc_timeout_ms,json=rpcTimeoutMs,proto3" json:"rpc_timeout_ms,omitempty"`
+	MaxStreamDurationMs  int64 `protobuf:"varint,9,opt,name=max_stream_duration_ms,json=maxStreamDurationMs,proto3" json:"max_stream_duration_ms,omitempty"`
+	RequestsPerSecond    int32 `protobuf:"varint,10,opt,name=requests_per_second,json=requestsPerSecond,proto3" json:"requests_per_second,omitempty"`
+	// Go runtime limits, which the CLI sets from an injected agent's
+	// resources; 0 means none.
+	Gomaxprocs       int32 `protobuf:"varint,11,opt,name=gomaxprocs,proto3" json:"gomaxprocs,omitempty"`
+	MemoryLimitBytes int64 `protobuf:"varint,12,opt,name=memory_limit_bytes,json=memoryLimitBytes,proto3" json:"memory_limit_bytes,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *SecurityPosture) Reset() {
+	*x = SecurityPosture{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecurityPosture) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecurityPosture) ProtoMessage() {}
+
+func (x *SecurityPosture) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecurityPosture.ProtoReflect.Descriptor instead.
+func (*SecurityPosture) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SecurityPosture) GetUid() int64 {
+	if x != nil {
+		return x.Uid
+	}
+	return 0
+}
+
+func (x *SecurityPosture) GetGid() int64 {
+	if x != nil {
+		return x.Gid
+	}
+	return 0
+}
+
+func (x *SecurityPosture) GetLinuxCapabilities() []string {
+	if x != nil {
+		return x.LinuxCapabilities
+	}
+	return nil
+}
+
+func (x *SecurityPosture) GetSandbox() []string {
+	if x != nil {
+		return x.Sandbox
+	}
+	return nil
+}
+
+func (x *SecurityPosture) GetSandboxMessage() string {
+	if x != nil {
+		return x.SandboxMessage
+	}
+	return ""
+}
+
+func (x *SecurityPosture) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+func (x *SecurityPosture) GetMaxDecompressedBytes() int64 {
+	if x != nil {
+		return x.MaxDecompressedBytes
+	}
+	return 0
+}
+
+func (x *SecurityPosture) GetRpcTimeoutMs() int64 {
+	if x != nil {
+		return x.RpcTimeoutMs
+	}
+	return 0
+}
+
+func (x *SecurityPosture) GetMaxStreamDurationMs() int64 {
+	if x != nil {
+		return x.MaxStreamDurationMs
+	}
+	return 0
+}
+
+func (x *SecurityPosture) GetRequestsPerSecond() int32 {
+	if x != nil {
+		return x.RequestsPerSecond
+	}
+	return 0
+}
+
+func (x *SecurityPosture) GetGomaxprocs() int32 {
+	if x != nil {
+		return x.Gomaxprocs
+	}
+	return 0
+}
+
+func (x *SecurityPosture) GetMemoryLimitBytes() int64 {
+	if x != nil {
+		return x.MemoryLimitBytes
+	}
+	return 0
+}
+
+type CapabilitiesResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Version           string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Rpcs              []string               `protobuf:"bytes,2,rep,name=rpcs,proto3" json:"rpcs,omitempty"`
+	Features          []string               `protobuf:"bytes,3,rep,name=features,proto3" json:"features,omitempty"`
+	CompressionCodecs []string               `protobuf:"bytes,4,rep,name=compression_codecs,json=compressionCodecs,proto3" json:"compression_codecs,omitempty"`
+	MaxReadSize       int64                  `protobuf:"varint,5,opt,name=max_read_size,json=maxReadSize,proto3" json:"max_read_size,omitempty"`
+	AuthModes         []string               `protobuf:"bytes,6,rep,name=auth_modes,json=authModes,proto3" json:"auth_modes,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CapabilitiesResponse) Reset() {
+	*x = CapabilitiesResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilitiesResponse) ProtoMessage() {}
+
+func (x *CapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*CapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CapabilitiesResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *CapabilitiesResponse) GetRpcs() []string {
+	if x != nil {
+		return x.Rpcs
+	}
+	return nil
+}
+
+func (x *CapabilitiesResponse) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *CapabilitiesResponse) GetCompressionCodecs() []string {
+	if x != nil {
+		return x.CompressionCodecs
+	}
+	return nil
+}
+
+func (x *CapabilitiesResponse) GetMaxReadSize() int64 {
+	if x != nil {
+		return x.MaxReadSize
+	}
+	return 0
+}
+
+func (x *CapabilitiesResponse) GetAuthModes() []string {
+	if x != nil {
+		return x.AuthModes
+	}
+	return nil
+}
+
+type ShutdownRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	GraceSeconds  int64                  `protobuf:"varint,2,opt,name=grace_seconds,json=graceSeconds,proto3" json:"grace_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownRequest) Reset() {
+	*x = ShutdownRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownRequest) ProtoMessage() {}
+
+func (x *ShutdownRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownRequest.ProtoReflect.Descriptor instead.
+func (*ShutdownRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ShutdownRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ShutdownRequest) GetGraceSeconds() int64 {
+	if x != nil {
+		return x.GraceSeconds
+	}
+	return 0
+}
+
+type ShutdownResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownResponse) Reset() {
+	*x = ShutdownResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownResponse) ProtoMessage() {}
+
+func (x *ShutdownResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownResponse.ProtoReflect.Descriptor instead.
+func (*ShutdownResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ShutdownResponse) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+type SearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Pattern       string                 `protobuf:"bytes,2,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	MaxMatches    int32                  `protobuf:"varint,3,opt,name=max_matches,json=maxMatches,proto3" json:"max_matches,omitempty"`
+	AllowedRoots  []string               `protobuf:"bytes,4,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SearchRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetMaxMatches() int32 {
+	if x != nil {
+		return x.MaxMatches
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+type SearchMatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Line          int64                  `protobuf:"varint,2,opt,name=line,proto3" json:"line,omitempty"`
+	Text          string                 `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchMatch) Reset() {
+	*x = SearchMatch{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchMatch) ProtoMessage() {}
+
+func (x *SearchMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchMatch.ProtoReflect.Descriptor instead.
+func (*SearchMatch) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SearchMatch) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SearchMatch) GetLine() int64 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *SearchMatch) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Matches       []*SearchMatch         `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	Truncated     bool                   `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	FilesSearched int64                  `protobuf:"varint,3,opt,name=files_searched,json=filesSearched,proto3" json:"files_searched,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SearchResponse) GetMatches() []*SearchMatch {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+func (x *SearchResponse) GetFilesSearched() int64 {
+	if x != nil {
+		return x.FilesSearched
+	}
+	return 0
+}
+
+type TailRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Path         string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	SinceSeconds int64                  `protobuf:"varint,2,opt,name=since_seconds,json=sinceSeconds,proto3" json:"since_seconds,omitempty"`
+	Pattern      string                 `protobuf:"bytes,3,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	Follow       bool                   `protobuf:"varint,4,opt,name=follow,proto3" json:"follow,omitempty"`
+	FromStart    bool                   `protobuf:"varint,5,opt,name=from_start,json=fromStart,proto3" json:"from_start,omitempty"`
+	AllowedRoots []string               `protobuf:"bytes,6,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	Paths        []string               `protobuf:"bytes,7,rep,name=paths,proto3" json:"paths,omitempty"`
+	// Offsets to continue files from, by path, as reported in
+	// ReadResponse.offset, instead of where since_seconds or from_start
+	// would start them. A file now shorter than its offset is read from the
+	// start.
+	ResumeOffsets map[string]int64 `protobuf:"bytes,8,rep,name=resume_offsets,json=resumeOffsets,proto3" json:"resume_offsets,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TailRequest) Reset() {
+	*x = TailRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TailRequest) ProtoMessage() {}
+
+func (x *TailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TailRequest.ProtoReflect.Descriptor instead.
+func (*TailRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *TailRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *TailRequest) GetSinceSeconds() int64 {
+	if x != nil {
+		return x.SinceSeconds
+	}
+	return 0
+}
+
+func (x *TailRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *TailRequest) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+func (x *TailRequest) GetFromStart() bool {
+	if x != nil {
+		return x.FromStart
+	}
+	return false
+}
+
+func (x *TailRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+func (x *TailRequest) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *TailRequest) GetResumeOffsets() map[string]int64 {
+	if x != nil {
+		return x.ResumeOffsets
+	}
+	return nil
+}
+
+type PreviewRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	HeadBytes     int64                  `protobuf:"varint,2,opt,name=head_bytes,json=headBytes,proto3" json:"head_bytes,omitempty"`
+	TailBytes     int64                  `protobuf:"varint,3,opt,name=tail_bytes,json=tailBytes,proto3" json:"tail_bytes,omitempty"`
+	AllowedRoots  []string               `protobuf:"bytes,4,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PreviewRequest) Reset() {
+	*x = PreviewRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PreviewRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PreviewRequest) ProtoMessage() {}
+
+func (x *PreviewRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PreviewRequest.ProtoReflect.Descriptor instead.
+func (*PreviewRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PreviewRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *PreviewRequest) GetHeadBytes() int64 {
+	if x != nil {
+		return x.HeadBytes
+	}
+	return 0
+}
+
+func (x *PreviewRequest) GetTailBytes() int64 {
+	if x != nil {
+		return x.TailBytes
+	}
+	return 0
+}
+
+func (x *PreviewRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+type PreviewResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Info          *FileInfo              `protobuf:"bytes,1,opt,name=info,proto3" json:"info,omitempty"`
+	Head          []byte                 `protobuf:"bytes,2,opt,name=head,proto3" json:"head,omitempty"`
+	Tail          []byte                 `protobuf:"bytes,3,opt,name=tail,proto3" json:"tail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PreviewResponse) Reset() {
+	*x = PreviewResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PreviewResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PreviewResponse) ProtoMessage() {}
+
+func (x *PreviewResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PreviewResponse.ProtoReflect.Descriptor instead.
+func (*PreviewResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PreviewResponse) GetInfo() *FileInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+func (x *PreviewResponse) GetHead() []byte {
+	if x != nil {
+		return x.Head
+	}
+	return nil
+}
+
+func (x *PreviewResponse) GetTail() []byte {
+	if x != nil {
+		return x.Tail
+	}
+	return nil
+}
+
+type ChecksumRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Path  string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// Hash only the first length bytes, such as the part of a growing log a
+	// client has already copied; 0 hashes the whole file.
+	Length        int64    `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+	AllowedRoots  []string `protobuf:"bytes,3,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChecksumRequest) Reset() {
+	*x = ChecksumRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChecksumRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChecksumRequest) ProtoMessage() {}
+
+func (x *ChecksumRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChecksumRequest.ProtoReflect.Descriptor instead.
+func (*ChecksumRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ChecksumRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ChecksumRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *ChecksumRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+type ChecksumResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "sha256".
+	Algorithm string `protobuf:"bytes,1,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	// The lowercase hex digest.
+	Digest string `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+	// How many bytes were hashed, fewer than length if the file is shorter.
+	SizeBytes     int64 `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChecksumResponse) Reset() {
+	*x = ChecksumResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChecksumResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChecksumResponse) ProtoMessage() {}
+
+func (x *ChecksumResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChecksumResponse.ProtoReflect.Descriptor instead.
+func (*ChecksumResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ChecksumResponse) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *ChecksumResponse) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+func (x *ChecksumResponse) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+type FindRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Path  string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// Filters, as in ListRequest; an entry must pass all of them.
+	NameGlob      string                 `protobuf:"bytes,2,opt,name=name_glob,json=nameGlob,proto3" json:"name_glob,omitempty"`
+	MinSize       int64                  `protobuf:"varint,3,opt,name=min_size,json=minSize,proto3" json:"min_size,omitempty"`
+	ModifiedSince *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=modified_since,json=modifiedSince,proto3" json:"modified_since,omitempty"`
+	// Only entries of this type; unspecified matches every type.
+	FileType      FileType `protobuf:"varint,5,opt,name=file_type,json=fileType,proto3,enum=pulsaar.v1.FileType" json:"file_type,omitempty"`
+	MaxResults    int32    `protobuf:"varint,6,opt,name=max_results,json=maxResults,proto3" json:"max_results,omitempty"`
+	AllowedRoots  []string `protobuf:"bytes,7,rep,name=allowed_roots,json=allowedRoots,proto3" json:"allowed_roots,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindRequest) Reset() {
+	*x = FindRequest{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindRequest) ProtoMessage() {}
+
+func (x *FindRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindRequest.ProtoReflect.Descriptor instead.
+func (*FindRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *FindRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FindRequest) GetNameGlob() string {
+	if x != nil {
+		return x.NameGlob
+	}
+	return ""
+}
+
+func (x *FindRequest) GetMinSize() int64 {
+	if x != nil {
+		return x.MinSize
+	}
+	return 0
+}
+
+func (x *FindRequest) GetModifiedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModifiedSince
+	}
+	return nil
+}
+
+func (x *FindRequest) GetFileType() FileType {
+	if x != nil {
+		return x.FileType
+	}
+	return FileType_FILE_TYPE_UNSPECIFIED
+}
+
+func (x *FindRequest) GetMaxResults() int32 {
+	if x != nil {
+		return x.MaxResults
+	}
+	return 0
+}
+
+func (x *FindRequest) GetAllowedRoots() []string {
+	if x != nil {
+		return x.AllowedRoots
+	}
+	return nil
+}
+
+type FindMatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Info          *FileInfo              `protobuf:"bytes,2,opt,name=info,proto3" json:"info,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindMatch) Reset() {
+	*x = FindMatch{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindMatch) ProtoMessage() {}
+
+func (x *FindMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindMatch.ProtoReflect.Descriptor instead.
+func (*FindMatch) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *FindMatch) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FindMatch) GetInfo() *FileInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+type FindResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Matches        []*FindMatch           `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	Truncated      bool                   `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	EntriesScanned int64                  `protobuf:"varint,3,opt,name=entries_scanned,json=entriesScanned,proto3" json:"entries_scanned,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *FindResponse) Reset() {
+	*x = FindResponse{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindResponse) ProtoMessage() {}
+
+func (x *FindResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindResponse.ProtoReflect.Descriptor instead.
+func (*FindResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *FindResponse) GetMatches() []*FindMatch {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+func (x *FindResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+func (x *FindResponse) GetEntriesScanned() int64 {
+	if x != nil {
+		return x.EntriesScanned
+	}
+	return 0
+}
+
+type AuditEvent struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp  string                 `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Operation  string                 `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"`
+	Path       string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	AgentId    string                 `protobuf:"bytes,4,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	User       string                 `protobuf:"bytes,5,opt,name=user,proto3" json:"user,omitempty"`
+	Namespace  string                 `protobuf:"bytes,6,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Pod        string                 `protobuf:"bytes,7,opt,name=pod,proto3" json:"pod,omitempty"`
+	Container  string                 `protobuf:"bytes,8,opt,name=container,proto3" json:"container,omitempty"`
+	Result     string                 `protobuf:"bytes,9,opt,name=result,proto3" json:"result,omitempty"`
+	BytesRead  int64                  `protobuf:"varint,10,opt,name=bytes_read,json=bytesRead,proto3" json:"bytes_read,omitempty"`
+	DurationMs int64                  `protobuf:"varint,11,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	RequestId  string                 `protobuf:"bytes,12,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	ClientAddr string                 `protobuf:"bytes,13,opt,name=client_addr,json=clientAddr,proto3" json:"client_addr,omitempty"`
+	// The gRPC status code name, such as PermissionDenied, when result is
+	// denied or error.
+	ErrorCode string `protobuf:"bytes,14,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	// The node the agent runs on.
+	Node          string `protobuf:"bytes,15,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditEvent) Reset() {
+	*x = AuditEvent{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditEvent) ProtoMessage() {}
+
+func (x *AuditEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditEvent.ProtoReflect.Descriptor instead.
+func (*AuditEvent) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *AuditEvent) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetPod() string {
+	if x != nil {
+		return x.Pod
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetContainer() string {
+	if x != nil {
+		return x.Container
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetBytesRead() int64 {
+	if x != nil {
+		return x.BytesRead
+	}
+	return 0
+}
+
+func (x *AuditEvent) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *AuditEvent) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetClientAddr() string {
+	if x != nil {
+		return x.ClientAddr
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+type AuditAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Received      int64                  `protobuf:"varint,1,opt,name=received,proto3" json:"received,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditAck) Reset() {
+	*x = AuditAck{}
+	mi := &file_api_v1_pulsaar_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditAck) ProtoMessage() {}
+
+func (x *AuditAck) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_pulsaar_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditAck.ProtoReflect.Descriptor instead.
+func (*AuditAck) Descriptor() ([]byte, []int) {
+	return file_api_v1_pulsaar_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *AuditAck) GetReceived() int64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+var File_api_v1_pulsaar_proto protoreflect.FileDescriptor
+
+const file_api_v1_pulsaar_proto_rawDesc = "" +
+	"\n" +
+	"\x14api/v1/pulsaar.proto\x12\n" +
+	"pulsaar.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\"\xac\x02\n" +
+	"\vListRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12#\n" +
+	"\rallowed_roots\x18\x02 \x03(\tR\fallowedRoots\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\x12\x17\n" +
+	"\asort_by\x18\x05 \x01(\tR\x06sortBy\x12\x14\n" +
+	"\x05order\x18\x06 \x01(\tR\x05order\x12\x1b\n" +
+	"\tname_glob\x18\a \x01(\tR\bnameGlob\x12\x19\n" +
+	"\bmin_size\x18\b \x01(\x03R\aminSize\x12A\n" +
+	"\x0emodified_since\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\rmodifiedSince\"\xa3\x02\n" +
+	"\bFileInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x15\n" +
+	"\x06is_dir\x18\x02 \x01(\bR\x05isDir\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12\x12\n" +
+	"\x04mode\x18\x04 \x01(\tR\x04mode\x120\n" +
+	"\x05mtime\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x05mtime\x12\x14\n" +
+	"\x05owner\x18\x06 \x01(\tR\x05owner\x121\n" +
+	"\tfile_type\x18\a \x01(\x0e2\x14.pulsaar.v1.FileTypeR\bfileType\x12\x1f\n" +
+	"\vlink_target\x18\b \x01(\tR\n" +
+	"linkTarget\x12\x1d\n" +
+	"\n" +
+	"hard_links\x18\t \x01(\x03R\thardLinks\"f\n" +
+	"\fListResponse\x12.\n" +
+	"\aentries\x18\x01 \x03(\v2\x14.pulsaar.v1.FileInfoR\aentries\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"F\n" +
+	"\vStatRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12#\n" +
+	"\rallowed_roots\x18\x02 \x03(\tR\fallowedRoots\"8\n" +
+	"\fStatResponse\x12(\n" +
+	"\x04info\x18\x01 \x01(\v2\x14.pulsaar.v1.FileInfoR\x04info\"\xc5\x01\n" +
+	"\vReadRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\x12#\n" +
+	"\rallowed_roots\x18\x04 \x03(\tR\fallowedRoots\x12\x1e\n" +
+	"\n" +
+	"decompress\x18\x05 \x01(\bR\n" +
+	"decompress\x12-\n" +
+	"\x06ranges\x18\x06 \x03(\v2\x15.pulsaar.v1.ByteRangeR\x06ranges\";\n" +
+	"\tByteRange\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x02 \x01(\x03R\x06length\"\x8c\x01\n" +
+	"\fReadResponse\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x10\n" +
+	"\x03eof\x18\x02 \x01(\bR\x03eof\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\x12*\n" +
+	"\x05parts\x18\x05 \x03(\v2\x14.pulsaar.v1.ReadPartR\x05parts\"H\n" +
+	"\bReadPart\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x10\n" +
+	"\x03eof\x18\x03 \x01(\bR\x03eof\"\x97\x01\n" +
+	"\rStreamRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\x02 \x01(\x03R\tchunkSize\x12#\n" +
+	"\rallowed_roots\x18\x03 \x03(\tR\fallowedRoots\x12\x1e\n" +
+	"\n" +
+	"decompress\x18\x04 \x01(\bR\n" +
+	"decompress\x12\x0e\n" +
+	"\x02jq\x18\x05 \x01(\tR\x02jq\"\x90\x02\n" +
+	"\x0eHealthResponse\x12\x14\n" +
+	"\x05ready\x18\x01 \x01(\bR\x05ready\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12%\n" +
+	"\x0estatus_message\x18\x03 \x01(\tR\rstatusMessage\x12\x16\n" +
+	"\x06commit\x18\x04 \x01(\tR\x06commit\x12\x12\n" +
+	"\x04date\x18\x05 \x01(\tR\x04date\x12&\n" +
+	"\fcapabilities\x18\x06 \x03(\tB\x02\x18\x01R\fcapabilities\x12\x1c\n" +
+	"\tprivilege\x18\a \x01(\tR\tprivilege\x125\n" +
+	"\aposture\x18\b \x01(\v2\x1b.pulsaar.v1.SecurityPostureR\aposture\"\xdb\x03\n" +
+	"\x0fSecurityPosture\x12\x10\n" +
+	"\x03uid\x18\x01 \x01(\x03R\x03uid\x12\x10\n" +
+	"\x03gid\x18\x02 \x01(\x03R\x03gid\x12-\n" +
+	"\x12linux_capabilities\x18\x03 \x03(\tR\x11linuxCapabilities\x12\x18\n" +
+	"\asandbox\x18\x04 \x03(\tR\asandbox\x12'\n" +
+	"\x0fsandbox_message\x18\x05 \x01(\tR\x0esandboxMessage\x12#\n" +
+	"\rallowed_roots\x18\x06 \x03(\tR\fallowedRoots\x124\n" +
+	"\x16max_decompressed_bytes\x18\a \x01(\x03R\x14maxDecompressedBytes\x12$\n" +
+	"\x0erpc_timeout_ms\x18\b \x01(\x03R\frpcTimeoutMs\x123\n" +
+	"\x16max_stream_duration_ms\x18\t \x01(\x03R\x13maxStreamDurationMs\x12.\n" +
+	"\x13requests_per_second\x18\n" +
+	" \x01(\x05R\x11requestsPerSecond\x12\x1e\n" +
+	"\n" +
+	"gomaxprocs\x18\v \x01(\x05R\n" +
+	"gomaxprocs\x12,\n" +
+	"\x12memory_limit_bytes\x18\f \x01(\x03R\x10memoryLimitBytes\"\xd2\x01\n" +
+	"\x14CapabilitiesResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x12\n" +
+	"\x04rpcs\x18\x02 \x03(\tR\x04rpcs\x12\x1a\n" +
+	"\bfeatures\x18\x03 \x03(\tR\bfeatures\x12-\n" +
+	"\x12compression_codecs\x18\x04 \x03(\tR\x11compressionCodecs\x12\"\n" +
+	"\rmax_read_size\x18\x05 \x01(\x03R\vmaxReadSize\x12\x1d\n" +
+	"\n" +
+	"auth_modes\x18\x06 \x03(\tR\tauthModes\"N\n" +
+	"\x0fShutdownRequest\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\x12#\n" +
+	"\rgrace_seconds\x18\x02 \x01(\x03R\fgraceSeconds\".\n" +
+	"\x10ShutdownResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\bR\baccepted\"\x83\x01\n" +
+	"\rSearchRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\apattern\x18\x02 \x01(\tR\apattern\x12\x1f\n" +
+	"\vmax_matches\x18\x03 \x01(\x05R\n" +
+	"maxMatches\x12#\n" +
+	"\rallowed_roots\x18\x04 \x03(\tR\fallowedRoots\"I\n" +
+	"\vSearchMatch\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\x03R\x04line\x12\x12\n" +
+	"\x04text\x18\x03 \x01(\tR\x04text\"\x88\x01\n" +
+	"\x0eSearchResponse\x121\n" +
+	"\amatches\x18\x01 \x03(\v2\x17.pulsaar.v1.SearchMatchR\amatches\x12\x1c\n" +
+	"\ttruncated\x18\x02 \x01(\bR\ttruncated\x12%\n" +
+	"\x0efiles_searched\x18\x03 \x01(\x03R\rfilesSearched\"\xe7\x02\n" +
+	"\vTailRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12#\n" +
+	"\rsince_seconds\x18\x02 \x01(\x03R\fsinceSeconds\x12\x18\n" +
+	"\apattern\x18\x03 \x01(\tR\apattern\x12\x16\n" +
+	"\x06follow\x18\x04 \x01(\bR\x06follow\x12\x1d\n" +
+	"\n" +
+	"from_start\x18\x05 \x01(\bR\tfromStart\x12#\n" +
+	"\rallowed_roots\x18\x06 \x03(\tR\fallowedRoots\x12\x14\n" +
+	"\x05paths\x18\a \x03(\tR\x05paths\x12Q\n" +
+	"\x0eresume_offsets\x18\b \x03(\v2*.pulsaar.v1.TailRequest.ResumeOffsetsEntryR\rresumeOffsets\x1a@\n" +
+	"\x12ResumeOffsetsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x87\x01\n" +
+	"\x0ePreviewRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1d\n" +
+	"\n" +
+	"head_bytes\x18\x02 \x01(\x03R\theadBytes\x12\x1d\n" +
+	"\n" +
+	"tail_bytes\x18\x03 \x01(\x03R\ttailBytes\x12#\n" +
+	"\rallowed_roots\x18\x04 \x03(\tR\fallowedRoots\"c\n" +
+	"\x0fPreviewResponse\x12(\n" +
+	"\x04info\x18\x01 \x01(\v2\x14.pulsaar.v1.FileInfoR\x04info\x12\x12\n" +
+	"\x04head\x18\x02 \x01(\fR\x04head\x12\x12\n" +
+	"\x04tail\x18\x03 \x01(\fR\x04tail\"b\n" +
+	"\x0fChecksumRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
+	"\x06length\x18\x02 \x01(\x03R\x06length\x12#\n" +
+	"\rallowed_roots\x18\x03 \x03(\tR\fallowedRoots\"g\n" +
+	"\x10ChecksumResponse\x12\x1c\n" +
+	"\talgorithm\x18\x01 \x01(\tR\talgorithm\x12\x16\n" +
+	"\x06digest\x18\x02 \x01(\tR\x06digest\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\"\x95\x02\n" +
+	"\vFindRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1b\n" +
+	"\tname_glob\x18\x02 \x01(\tR\bnameGlob\x12\x19\n" +
+	"\bmin_size\x18\x03 \x01(\x03R\aminSize\x12A\n" +
+	"\x0emodified_since\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\rmodifiedSince\x121\n" +
+	"\tfile_type\x18\x05 \x01(\x0e2\x14.pulsaar.v1.FileTypeR\bfileType\x12\x1f\n" +
+	"\vmax_results\x18\x06 \x01(\x05R\n" +
+	"maxResults\x12#\n" +
+	"\rallowed_roots\x18\a \x03(\tR\fallowedRoots\"I\n" +
+	"\tFindMatch\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12(\n" +
+	"\x04info\x18\x02 \x01(\v2\x14.pulsaar.v1.FileInfoR\x04info\"\x86\x01\n" +
+	"\fFindResponse\x12/\n" +
+	"\amatches\x18\x01 \x03(\v2\x15.pulsaar.v1.FindMatchR\amatches\x12\x1c\n" +
+	"\ttruncated\x18\x02 \x01(\bR\ttruncated\x12'\n" +
+	"\x0fentries_scanned\x18\x03 \x01(\x03R\x0eentriesScanned\"\xa4\x03\n" +
+	"\n" +
+	"AuditEvent\x12\x1c\n" +
+	"\ttimestamp\x18\x01 \x01(\tR\ttimestamp\x12\x1c\n" +
+	"\toperation\x18\x02 \x01(\tR\toperation\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x19\n" +
+	"\bagent_id\x18\x04 \x01(\tR\aagentId\x12\x12\n" +
+	"\x04user\x18\x05 \x01(\tR\x04user\x12\x1c\n" +
+	"\tnamespace\x18\x06 \x01(\tR\tnamespace\x12\x10\n" +
+	"\x03pod\x18\a \x01(\tR\x03pod\x12\x1c\n" +
+	"\tcontainer\x18\b \x01(\tR\tcontainer\x12\x16\n" +
+	"\x06result\x18\t \x01(\tR\x06result\x12\x1d\n" +
+	"\n" +
+	"bytes_read\x18\n" +
+	" \x01(\x03R\tbytesRead\x12\x1f\n" +
+	"\vduration_ms\x18\v \x01(\x03R\n" +
+	"durationMs\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\f \x01(\tR\trequestId\x12\x1f\n" +
+	"\vclient_addr\x18\r \x01(\tR\n" +
+	"clientAddr\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\x0e \x01(\tR\terrorCode\x12\x12\n" +
+	"\x04node\x18\x0f \x01(\tR\x04node\"&\n" +
+	"\bAuditAck\x12\x1a\n" +
+	"\breceived\x18\x01 \x01(\x03R\breceived*\xec\x01\n" +
+	"\bFileType\x12\x19\n" +
+	"\x15FILE_TYPE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11FILE_TYPE_REGULAR\x10\x01\x12\x17\n" +
+	"\x13FILE_TYPE_DIRECTORY\x10\x02\x12\x15\n" +
+	"\x11FILE_TYPE_SYMLINK\x10\x03\x12\x18\n" +
+	"\x14FILE_TYPE_NAMED_PIPE\x10\x04\x12\x14\n" +
+	"\x10FILE_TYPE_SOCKET\x10\x05\x12\x1a\n" +
+	"\x16FILE_TYPE_BLOCK_DEVICE\x10\x06\x12\x19\n" +
+	"\x15FILE_TYPE_CHAR_DEVICE\x10\a\x12\x17\n" +
+	"\x13FILE_TYPE_IRREGULAR\x10\b2\xf4\x06\n" +
+	"\fPulsaarAgent\x12B\n" +
+	"\rListDirectory\x12\x17.pulsaar.v1.ListRequest\x1a\x18.pulsaar.v1.ListResponse\x129\n" +
+	"\x04Stat\x12\x17.pulsaar.v1.StatRequest\x1a\x18.pulsaar.v1.StatResponse\x12=\n" +
+	"\bReadFile\x12\x17.pulsaar.v1.ReadRequest\x1a\x18.pulsaar.v1.ReadResponse\x12C\n" +
+	"\n" +
+	"StreamFile\x12\x19.pulsaar.v1.StreamRequest\x1a\x18.pulsaar.v1.ReadResponse0\x01\x12<\n" +
+	"\x06Health\x12\x16.google.protobuf.Empty\x1a\x1a.pulsaar.v1.HealthResponse\x12E\n" +
+	"\bShutdown\x12\x1b.pulsaar.v1.ShutdownRequest\x1a\x1c.pulsaar.v1.ShutdownResponse\x12?\n" +
+	"\x06Search\x12\x19.pulsaar.v1.SearchRequest\x1a\x1a.pulsaar.v1.SearchResponse\x12B\n" +
+	"\aPreview\x12\x1a.pulsaar.v1.PreviewRequest\x1a\x1b.pulsaar.v1.PreviewResponse\x12?\n" +
+	"\bTailFile\x12\x17.pulsaar.v1.TailRequest\x1a\x18.pulsaar.v1.ReadResponse0\x01\x12J\n" +
+	"\x13ListDirectoryStream\x12\x17.pulsaar.v1.ListRequest\x1a\x18.pulsaar.v1.ListResponse0\x01\x12H\n" +
+	"\fCapabilities\x12\x16.google.protobuf.Empty\x1a .pulsaar.v1.CapabilitiesResponse\x12E\n" +
+	"\bChecksum\x12\x1b.pulsaar.v1.ChecksumRequest\x1a\x1c.pulsaar.v1.ChecksumResponse\x129\n" +
+	"\x04Find\x12\x17.pulsaar.v1.FindRequest\x1a\x18.pulsaar.v1.FindResponse2J\n" +
+	"\tAuditSink\x12=\n" +
+	"\vStreamAudit\x12\x16.pulsaar.v1.AuditEvent\x1a\x14.pulsaar.v1.AuditAck(\x01B/Z-github.com/VrushankPatel/pulsaar/api/v1;apiv1b\x06proto3"
+
+var (
+	file_api_v1_pulsaar_proto_rawDescOnce sync.Once
+	file_api_v1_pulsaar_proto_rawDescData []byte
+)
+
+func file_api_v1_pulsaar_proto_rawDescGZIP() []byte {
+	file_api_v1_pulsaar_proto_rawDescOnce.Do(func() {
+		file_api_v1_pulsaar_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_v1_pulsaar_proto_rawDesc), len(file_api_v1_pulsaar_proto_rawDesc)))
+	})
+	return file_api_v1_pulsaar_proto_rawDescData
+}
+
+var file_api_v1_pulsaar_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_v1_pulsaar_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
+var file_api_v1_pulsaar_proto_goTypes = []any{
+	(FileType)(0),                 // 0: pulsaar.v1.FileType
+	(*ListRequest)(nil),           // 1: pulsaar.v1.ListRequest
+	(*FileInfo)(nil),              // 2: pulsaar.v1.FileInfo
+	(*ListResponse)(nil),          // 3: pulsaar.v1.ListResponse
+	(*StatRequest)(nil),           // 4: pulsaar.v1.StatRequest
+	(*StatResponse)(nil),          // 5: pulsaar.v1.StatResponse
+	(*ReadRequest)(nil),           // 6: pulsaar.v1.ReadRequest
+	(*ByteRange)(nil),             // 7: pulsaar.v1.ByteRange
+	(*ReadResponse)(nil),          // 8: pulsaar.v1.ReadResponse
+	(*ReadPart)(nil),              // 9: pulsaar.v1.ReadPart
+	(*StreamRequest)(nil),         // 10: pulsaar.v1.StreamRequest
+	(*HealthResponse)(nil),        // 11: pulsaar.v1.HealthResponse
+	(*SecurityPosture)(nil),       // 12: pulsaar.v1.SecurityPosture
+	(*CapabilitiesResponse)(nil),  // 13: pulsaar.v1.CapabilitiesResponse
+	(*ShutdownRequest)(nil),       // 14: pulsaar.v1.ShutdownRequest
+	(*ShutdownResponse)(nil),      // 15: pulsaar.v1.ShutdownResponse
+	(*SearchRequest)(nil),         // 16: pulsaar.v1.SearchRequest
+	(*SearchMatch)(nil),           // 17: pulsaar.v1.SearchMatch
+	(*SearchResponse)(nil),        // 18: pulsaar.v1.SearchResponse
+	(*TailRequest)(nil),           // 19: pulsaar.v1.TailRequest
+	(*PreviewRequest)(nil),        // 20: pulsaar.v1.PreviewRequest
+	(*PreviewResponse)(nil),       // 21: pulsaar.v1.PreviewResponse
+	(*ChecksumRequest)(nil),       // 22: pulsaar.v1.ChecksumRequest
+	(*ChecksumResponse)(nil),      // 23: pulsaar.v1.ChecksumResponse
+	(*FindRequest)(nil),           // 24: pulsaar.v1.FindRequest
+	(*FindMatch)(nil),             // 25: pulsaar.v1.FindMatch
+	(*FindResponse)(nil),          // 26: pulsaar.v1.FindResponse
+	(*AuditEvent)(nil),            // 27: pulsaar.v1.AuditEvent
+	(*AuditAck)(nil),              // 28: pulsaar.v1.AuditAck
+	nil,                           // 29: pulsaar.v1.TailRequest.ResumeOffsetsEntry
+	(*timestamppb.Timestamp)(nil), // 30: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 31: google.protobuf.Empty
+}
+var file_api_v1_pulsaar_proto_depIdxs = []int32{
+	30, // 0: pulsaar.v1.ListRequest.modified_since:type_name -> google.protobuf.Timestamp
+	30, // 1: pulsaar.v1.FileInfo.mtime:type_name -> google.protobuf.Timestamp
+	0,  // 2: pulsaar.v1.FileInfo.file_type:type_name -> pulsaar.v1.FileType
+	2,  // 3: pulsaar.v1.ListResponse.entries:type_name -> pulsaar.v1.FileInfo
+	2,  // 4: pulsaar.v1.StatResponse.info:type_name -> pulsaar.v1.FileInfo
+	7,  // 5: pulsaar.v1.ReadRequest.ranges:type_name -> pulsaar.v1.ByteRange
+	9,  // 6: pulsaar.v1.ReadResponse.parts:type_name -> pulsaar.v1.ReadPart
+	12, // 7: pulsaar.v1.HealthResponse.posture:type_name -> pulsaar.v1.SecurityPosture
+	17, // 8: pulsaar.v1.SearchResponse.matches:type_name -> pulsaar.v1.SearchMatch
+	29, // 9: pulsaar.v1.TailRequest.resume_offsets:type_name -> pulsaar.v1.TailRequest.ResumeOffsetsEntry
+	2,  // 10: pulsaar.v1.PreviewResponse.info:type_name -> pulsaar.v1.FileInfo
+	30, // 11: pulsaar.v1.FindRequest.modified_since:type_name -> google.protobuf.Timestamp
+	0,  // 12: pulsaar.v1.FindRequest.file_type:type_name -> pulsaar.v1.FileType
+	2,  // 13: pulsaar.v1.FindMatch.info:type_name -> pulsaar.v1.FileInfo
+	25, // 14: pulsaar.v1.FindResponse.matches:type_name -> pulsaar.v1.FindMatch
+	1,  // 15: pulsaar.v1.PulsaarAgent.ListDirectory:input_type -> pulsaar.v1.ListRequest
+	4,  // 16: pulsaar.v1.PulsaarAgent.Stat:input_type -> pulsaar.v1.StatRequest
+	6,  // 17: pulsaar.v1.PulsaarAgent.ReadFile:input_type -> pulsaar.v1.ReadRequest
+	10, // 18: pulsaar.v1.PulsaarAgent.StreamFile:input_type -> pulsaar.v1.StreamRequest
+	31, // 19: pulsaar.v1.PulsaarAgent.Health:input_type -> google.protobuf.Empty
+	14, // 20: pulsaar.v1.PulsaarAgent.Shutdown:input_type -> pulsaar.v1.ShutdownRequest
+	16, // 21: pulsaar.v1.PulsaarAgent.Search:input_type -> pulsaar.v1.SearchRequest
+	20, // 22: pulsaar.v1.PulsaarAgent.Preview:input_type -> pulsaar.v1.PreviewRequest
+	19, // 23: pulsaar.v1.PulsaarAgent.TailFile:input_type -> pulsaar.v1.TailRequest
+	1,  // 24: pulsaar.v1.PulsaarAgent.ListDirectoryStream:input_type -> pulsaar.v1.ListRequest
+	31, // 25: pulsaar.v1.PulsaarAgent.Capabilities:input_type -> google.protobuf.Empty
+	22, // 26: pulsaar.v1.PulsaarAgent.Checksum:input_type -> pulsaar.v1.ChecksumRequest
+	24, // 27: pulsaar.v1.PulsaarAgent.Find:input_type -> pulsaar.v1.FindRequest
+	27, // 28: pulsaar.v1.AuditSink.StreamAudit:input_type -> pulsaar.v1.AuditEvent
+	3,  // 29: pulsaar.v1.PulsaarAgent.ListDirectory:output_type -> pulsaar.v1.ListResponse
+	5,  // 30: pulsaar.v1.PulsaarAgent.Stat:output_type -> pulsaar.v1.StatResponse
+	8,  // 31: pulsaar.v1.PulsaarAgent.ReadFile:output_type -> pulsaar.v1.ReadResponse
+	8,  // 32: pulsaar.v1.PulsaarAgent.StreamFile:output_type -> pulsaar.v1.ReadResponse
+	11, // 33: pulsaar.v1.PulsaarAgent.Health:output_type -> pulsaar.v1.HealthResponse
+	15, // 34: pulsaar.v1.PulsaarAgent.Shutdown:output_type -> pulsaar.v1.ShutdownResponse
+	18, // 35: pulsaar.v1.PulsaarAgent.Search:output_type -> pulsaar.v1.SearchResponse
+	21, // 36: pulsaar.v1.PulsaarAgent.Preview:output_type -> pulsaar.v1.PreviewResponse
+	8,  // 37: pulsaar.v1.PulsaarAgent.TailFile:output_type -> pulsaar.v1.ReadResponse
+	3,  // 38: pulsaar.v1.PulsaarAgent.ListDirectoryStream:output_type -> pulsaar.v1.ListResponse
+	13, // 39: pulsaar.v1.PulsaarAgent.Capabilities:output_type -> pulsaar.v1.CapabilitiesResponse
+	23, // 40: pulsaar.v1.PulsaarAgent.Checksum:output_type -> pulsaar.v1.ChecksumResponse
+	26, // 41: pulsaar.v1.PulsaarAgent.Find:output_type -> pulsaar.v1.FindResponse
+	28, // 42: pulsaar.v1.AuditSink.StreamAudit:output_type -> pulsaar.v1.AuditAck
+	29, // [29:43] is the sub-list for method output_type
+	15, // [15:29] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_api_v1_pulsaar_proto_init() }
+func file_api_v1_pulsaar_proto_init() {
+	if File_api_v1_pulsaar_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_v1_pulsaar_proto_rawDesc), len(file_api_v1_pulsaar_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   29,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_api_v1_pulsaar_proto_goTypes,
+		DependencyIndexes: file_api_v1_pulsaar_proto_depIdxs,
+		EnumInfos:         file_api_v1_pulsaar_proto_enumTypes,
+		MessageInfos:      file_api_v1_pulsaar_proto_msgTypes,
+	}.Build()
+	File_api_v1_pulsaar_proto = out.File
+	file_api_v1_pulsaar_proto_goTypes = nil
+	file_api_v1_pulsaar_proto_depIdxs = nil
+}
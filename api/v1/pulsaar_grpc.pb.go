@@ -0,0 +1,690 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             v6.33.2
+// source: api/v1/pulsaar.proto
+
+// Package pulsaar.v1 is the agent and audit API. Within v1, changes are
+// additive only: fields and RPCs are added, never renumbered, retyped or
+// renamed. A field that must go is marked deprecated first and, once
+// removed, its number and name are reserved so they are never reused. buf
+// breaking (buf.yaml) enforces this against master. See "Compatibility" in
+// docs/API_REFERENCE.md.
+
+package apiv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PulsaarAgent_ListDirectory_FullMethodName       = "/pulsaar.v1.PulsaarAgent/ListDirectory"
+	PulsaarAgent_Stat_FullMethodName                = "/pulsaar.v1.PulsaarAgent/Stat"
+	PulsaarAgent_ReadFile_FullMethodName            = "/pulsaar.v1.PulsaarAgent/ReadFile"
+	PulsaarAgent_StreamFile_FullMethodName          = "/pulsaar.v1.PulsaarAgent/StreamFile"
+	PulsaarAgent_Health_FullMethodName              = "/pulsaar.v1.PulsaarAgent/Health"
+	PulsaarAgent_Shutdown_FullMethodName            = "/pulsaar.v1.PulsaarAgent/Shutdown"
+	PulsaarAgent_Search_FullMethodName              = "/pulsaar.v1.PulsaarAgent/Search"
+	PulsaarAgent_Preview_FullMethodName             = "/pulsaar.v1.PulsaarAgent/Preview"
+	PulsaarAgent_TailFile_FullMethodName            = "/pulsaar.v1.PulsaarAgent/TailFile"
+	PulsaarAgent_ListDirectoryStream_FullMethodName = "/pulsaar.v1.PulsaarAgent/ListDirectoryStream"
+	PulsaarAgent_Capabilities_FullMethodName        = "/pulsaar.v1.PulsaarAgent/Capabilities"
+	PulsaarAgent_Checksum_FullMethodName            = "/pulsaar.v1.PulsaarAgent/Checksum"
+	PulsaarAgent_Find_FullMethodName                = "/pulsaar.v1.PulsaarAgent/Find"
+)
+
+// PulsaarAgentClient is the client API for PulsaarAgent service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PulsaarAgentClient interface {
+	ListDirectory(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error)
+	ReadFile(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*ReadResponse, error)
+	StreamFile(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReadResponse], error)
+	Health(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*HealthResponse, error)
+	Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error)
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	Preview(ctx context.Context, in *PreviewRequest, opts ...grpc.CallOption) (*PreviewResponse, error)
+	TailFile(ctx context.Context, in *TailRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReadResponse], error)
+	ListDirectoryStream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ListResponse], error)
+	Capabilities(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
+	Checksum(ctx context.Context, in *ChecksumRequest, opts ...grpc.CallOption) (*ChecksumResponse, error)
+	Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindResponse, error)
+}
+
+type pulsaarAgentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPulsaarAgentClient(cc grpc.ClientConnInterface) PulsaarAgentClient {
+	return &pulsaarAgentClient{cc}
+}
+
+func (c *pulsaarAgentClient) ListDirectory(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_ListDirectory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_Stat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) ReadFile(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*ReadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReadResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_ReadFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) StreamFile(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReadResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PulsaarAgent_ServiceDesc.Streams[0], PulsaarAgent_StreamFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRequest, ReadResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PulsaarAgent_StreamFileClient = grpc.ServerStreamingClient[ReadResponse]
+
+func (c *pulsaarAgentClient) Health(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ShutdownResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_Shutdown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) Preview(ctx context.Context, in *PreviewRequest, opts ...grpc.CallOption) (*PreviewResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PreviewResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_Preview_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) TailFile(ctx context.Context, in *TailRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReadResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PulsaarAgent_ServiceDesc.Streams[1], PulsaarAgent_TailFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TailRequest, ReadResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PulsaarAgent_TailFileClient = grpc.ServerStreamingClient[ReadResponse]
+
+func (c *pulsaarAgentClient) ListDirectoryStream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ListResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PulsaarAgent_ServiceDesc.Streams[2], PulsaarAgent_ListDirectoryStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListRequest, ListResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PulsaarAgent_ListDirectoryStreamClient = grpc.ServerStreamingClient[ListResponse]
+
+func (c *pulsaarAgentClient) Capabilities(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_Capabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) Checksum(ctx context.Context, in *ChecksumRequest, opts ...grpc.CallOption) (*ChecksumResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChecksumResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_Checksum_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pulsaarAgentClient) Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindResponse)
+	err := c.cc.Invoke(ctx, PulsaarAgent_Find_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PulsaarAgentServer is the server API for PulsaarAgent service.
+// All implementations must embed UnimplementedPulsaarAgentServer
+// for forward compatibility.
+type PulsaarAgentServer interface {
+	ListDirectory(context.Context, *ListRequest) (*ListResponse, error)
+	Stat(context.Context, *StatRequest) (*StatResponse, error)
+	ReadFile(context.Context, *ReadRequest) (*ReadResponse, error)
+	StreamFile(*StreamRequest, grpc.ServerStreamingServer[ReadResponse]) error
+	Health(context.Context, *emptypb.Empty) (*HealthResponse, error)
+	Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error)
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	Preview(context.Context, *PreviewRequest) (*PreviewResponse, error)
+	TailFile(*TailRequest, grpc.ServerStreamingServer[ReadResponse]) error
+	ListDirectoryStream(*ListRequest, grpc.ServerStreamingServer[ListResponse]) error
+	Capabilities(context.Context, *emptypb.Empty) (*CapabilitiesResponse, error)
+	Checksum(context.Context, *ChecksumRequest) (*ChecksumResponse, error)
+	Find(context.Context, *FindRequest) (*FindResponse, error)
+	mustEmbedUnimplementedPulsaarAgentServer()
+}
+
+// UnimplementedPulsaarAgentServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPulsaarAgentServer struct{}
+
+func (UnimplementedPulsaarAgentServer) ListDirectory(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListDirectory not implemented")
+}
+func (UnimplementedPulsaarAgentServer) Stat(context.Context, *StatRequest) (*StatResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Stat not implemented")
+}
+func (UnimplementedPulsaarAgentServer) ReadFile(context.Context, *ReadRequest) (*ReadResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReadFile not implemented")
+}
+func (UnimplementedPulsaarAgentServer) StreamFile(*StreamRequest, grpc.ServerStreamingServer[ReadResponse]) error {
+	return status.Error(codes.Unimplemented, "method StreamFile not implemented")
+}
+func (UnimplementedPulsaarAgentServer) Health(context.Context, *emptypb.Empty) (*HealthResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedPulsaarAgentServer) Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Shutdown not implemented")
+}
+func (UnimplementedPulsaarAgentServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedPulsaarAgentServer) Preview(context.Context, *PreviewRequest) (*PreviewResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Preview not implemented")
+}
+func (UnimplementedPulsaarAgentServer) TailFile(*TailRequest, grpc.ServerStreamingServer[ReadResponse]) error {
+	return status.Error(codes.Unimplemented, "method TailFile not implemented")
+}
+func (UnimplementedPulsaarAgentServer) ListDirectoryStream(*ListRequest, grpc.ServerStreamingServer[ListResponse]) error {
+	return status.Error(codes.Unimplemented, "method ListDirectoryStream not implemented")
+}
+func (UnimplementedPulsaarAgentServer) Capabilities(context.Context, *emptypb.Empty) (*CapabilitiesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Capabilities not implemented")
+}
+func (UnimplementedPulsaarAgentServer) Checksum(context.Context, *ChecksumRequest) (*ChecksumResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Checksum not implemented")
+}
+func (UnimplementedPulsaarAgentServer) Find(context.Context, *FindRequest) (*FindResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Find not implemented")
+}
+func (UnimplementedPulsaarAgentServer) mustEmbedUnimplementedPulsaarAgentServer() {}
+func (UnimplementedPulsaarAgentServer) testEmbeddedByValue()                      {}
+
+// UnsafePulsaarAgentServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PulsaarAgentServer will
+// result in compilation errors.
+type UnsafePulsaarAgentServer interface {
+	mustEmbedUnimplementedPulsaarAgentServer()
+}
+
+func RegisterPulsaarAgentServer(s grpc.ServiceRegistrar, srv PulsaarAgentServer) {
+	// If the following call panics, it indicates UnimplementedPulsaarAgentServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PulsaarAgent_ServiceDesc, srv)
+}
+
+func _PulsaarAgent_ListDirectory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).ListDirectory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_ListDirectory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).ListDirectory(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_Stat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).Stat(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_ReadFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).ReadFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_ReadFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).ReadFile(ctx, req.(*ReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_StreamFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PulsaarAgentServer).StreamFile(m, &grpc.GenericServerStream[StreamRequest, ReadResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PulsaarAgent_StreamFileServer = grpc.ServerStreamingServer[ReadResponse]
+
+func _PulsaarAgent_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).Health(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShutdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_Shutdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).Shutdown(ctx, req.(*ShutdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_Preview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PreviewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).Preview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_Preview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).Preview(ctx, req.(*PreviewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_TailFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PulsaarAgentServer).TailFile(m, &grpc.GenericServerStream[TailRequest, ReadResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PulsaarAgent_TailFileServer = grpc.ServerStreamingServer[ReadResponse]
+
+func _PulsaarAgent_ListDirectoryStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PulsaarAgentServer).ListDirectoryStream(m, &grpc.GenericServerStream[ListRequest, ListResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PulsaarAgent_ListDirectoryStreamServer = grpc.ServerStreamingServer[ListResponse]
+
+func _PulsaarAgent_Capabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).Capabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_Capabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).Capabilities(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_Checksum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChecksumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).Checksum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_Checksum_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).Checksum(ctx, req.(*ChecksumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PulsaarAgent_Find_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PulsaarAgentServer).Find(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PulsaarAgent_Find_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PulsaarAgentServer).Find(ctx, req.(*FindRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PulsaarAgent_ServiceDesc is the grpc.ServiceDesc for PulsaarAgent service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PulsaarAgent_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pulsaar.v1.PulsaarAgent",
+	HandlerType: (*PulsaarAgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDirectory",
+			Handler:    _PulsaarAgent_ListDirectory_Handler,
+		},
+		{
+			MethodName: "Stat",
+			Handler:    _PulsaarAgent_Stat_Handler,
+		},
+		{
+			MethodName: "ReadFile",
+			Handler:    _PulsaarAgent_ReadFile_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _PulsaarAgent_Health_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _PulsaarAgent_Shutdown_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _PulsaarAgent_Search_Handler,
+		},
+		{
+			MethodName: "Preview",
+			Handler:    _PulsaarAgent_Preview_Handler,
+		},
+		{
+			MethodName: "Capabilities",
+			Handler:    _PulsaarAgent_Capabilities_Handler,
+		},
+		{
+			MethodName: "Checksum",
+			Handler:    _PulsaarAgent_Checksum_Handler,
+		},
+		{
+			MethodName: "Find",
+			Handler:    _PulsaarAgent_Find_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamFile",
+			Handler:       _PulsaarAgent_StreamFile_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "TailFile",
+			Handler:       _PulsaarAgent_TailFile_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListDirectoryStream",
+			Handler:       _PulsaarAgent_ListDirectoryStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/v1/pulsaar.proto",
+}
+
+const (
+	AuditSink_StreamAudit_FullMethodName = "/pulsaar.v1.AuditSink/StreamAudit"
+)
+
+// AuditSinkClient is the client API for AuditSink service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuditSinkClient interface {
+	StreamAudit(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[AuditEvent, AuditAck], error)
+}
+
+type auditSinkClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuditSinkClient(cc grpc.ClientConnInterface) AuditSinkClient {
+	return &auditSinkClient{cc}
+}
+
+func (c *auditSinkClient) StreamAudit(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[AuditEvent, AuditAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AuditSink_ServiceDesc.Streams[0], AuditSink_StreamAudit_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AuditEvent, AuditAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AuditSink_StreamAuditClient = grpc.ClientStreamingClient[AuditEvent, AuditAck]
+
+// AuditSinkServer is the server API for AuditSink service.
+// All implementations must embed UnimplementedAuditSinkServer
+// for forward compatibility.
+type AuditSinkServer interface {
+	StreamAudit(grpc.ClientStreamingServer[AuditEvent, AuditAck]) error
+	mustEmbedUnimplementedAuditSinkServer()
+}
+
+// UnimplementedAuditSinkServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAuditSinkServer struct{}
+
+func (UnimplementedAuditSinkServer) StreamAudit(grpc.ClientStreamingServer[AuditEvent, AuditAck]) error {
+	return status.Error(codes.Unimplemented, "method StreamAudit not implemented")
+}
+func (UnimplementedAuditSinkServer) mustEmbedUnimplementedAuditSinkServer() {}
+func (UnimplementedAuditSinkServer) testEmbeddedByValue()                   {}
+
+// UnsafeAuditSinkServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuditSinkServer will
+// result in compilation errors.
+type UnsafeAuditSinkServer interface {
+	mustEmbedUnimplementedAuditSinkServer()
+}
+
+func RegisterAuditSinkServer(s grpc.ServiceRegistrar, srv AuditSinkServer) {
+	// If the following call panics, it indicates UnimplementedAuditSinkServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AuditSink_ServiceDesc, srv)
+}
+
+func _AuditSink_StreamAudit_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AuditSinkServer).StreamAudit(&grpc.GenericServerStream[AuditEvent, AuditAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AuditSink_StreamAuditServer = grpc.ClientStreamingServer[AuditEvent, AuditAck]
+
+// AuditSink_ServiceDesc is the grpc.ServiceDesc for AuditSink service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuditSink_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pulsaar.v1.AuditSink",
+	HandlerType: (*AuditSinkServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamAudit",
+			Handler:       _AuditSink_StreamAudit_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/v1/pulsaar.proto",
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/access"
+)
+
+const (
+	// approvalCacheTTL is how long the access requests listed for one
+	// check are reused for the next.
+	approvalCacheTTL = 10 * time.Second
+	// approvalRelistInterval bounds how often a request without a matching
+	// approval lists again, so a fresh approval takes effect at once
+	// without every refused request reaching the API server.
+	approvalRelistInterval = time.Second
+)
+
+// listAccessRequests returns the PulsaarAccessRequests in namespace. It is
+// a variable so tests can stand in for the API server.
+var listAccessRequests = func(ctx context.Context, namespace string) ([]access.Request, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("approvals are read from the Kubernetes API, which is not reachable outside a cluster: %v", err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return access.List(ctx, client, namespace)
+}
+
+var (
+	approvalsMu     sync.Mutex
+	approvals       []access.Request
+	approvalsListed time.Time
+)
+
+// approvedRequests returns the access requests in the agent's namespace,
+// listing them again when the cache is stale or, when relist is set, at
+// most once per approvalRelistInterval.
+func approvedRequests(ctx context.Context, relist bool) ([]access.Request, error) {
+	approvalsMu.Lock()
+	defer approvalsMu.Unlock()
+	age := time.Since(approvalsListed)
+	if age < approvalCacheTTL && (!relist || age < approvalRelistInterval) {
+		return approvals, nil
+	}
+	requests, err := listAccessRequests(ctx, getNamespace())
+	if err != nil {
+		return nil, err
+	}
+	approvals, approvalsListed = requests, time.Now()
+	return approvals, nil
+}
+
+// agentPodName is the pod access requests must name, the pod's hostname
+// unless --pod-name is set.
+func agentPodName() string {
+	if settings.podName != "" {
+		return settings.podName
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// requestPaths returns every path a request names.
+func requestPaths(req any) []string {
+	if r, ok := req.(*api.TailRequest); ok {
+		paths := slices.Clone(r.Paths)
+		if r.Path != "" {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+	if p := auditPath(req); p != "" {
+		return []string{p}
+	}
+	return nil
+}
+
+// checkApproval refuses a request under --require-approval unless an
+// approved, unexpired PulsaarAccessRequest grants its caller the paths it
+// names on this pod.
+func checkApproval(ctx context.Context, req any) error {
+	user := callerIdentity(ctx)
+	if user == "" {
+		return status.Error(codes.PermissionDenied, "This agent only serves callers with an approved access request, and the caller could not be identified. Connect with a client certificate or an identity token.")
+	}
+	pod, paths, now := agentPodName(), requestPaths(req), time.Now()
+	granted := func(requests []access.Request) bool {
+		return slices.ContainsFunc(requests, func(r access.Request) bool { return r.Grants(user, pod, paths, now) })
+	}
+	for _, relist := range []bool{false, true} {
+		requests, err := approvedRequests(ctx, relist)
+		if err != nil {
+			warnf("Failed to list access requests: %v", err)
+			return status.Error(codes.Unavailable, "Access approvals cannot be checked right now. Retry later.")
+		}
+		if granted(requests) {
+			return nil
+		}
+	}
+	return status.Errorf(codes.PermissionDenied, "No approved access request grants %s access to %s on pod %s/%s. Ask for one with: pulsaar access request --namespace %s --pod %s --path <path> --reason <why>",
+		user, strings.Join(paths, ","), getNamespace(), pod, getNamespace(), pod)
+}
+
+// approvalRequired reports whether a call of method on ctx needs an
+// approval. Only the lifecycle controller may shut the agent down without
+// one, so it can always retire the agent.
+func approvalRequired(ctx context.Context, method string) bool {
+	if !settings.requireApproval || !audited(method) {
+		return false
+	}
+	if method == api.PulsaarAgent_Shutdown_FullMethodName {
+		_, err := shutdownCaller(ctx)
+		return err != nil
+	}
+	return true
+}
+
+func approvalUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if approvalRequired(ctx, info.FullMethod) {
+		if err := checkApproval(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+func approvalStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !approvalRequired(ss.Context(), info.FullMethod) {
+		return handler(srv, ss)
+	}
+	return handler(srv, &approvalStream{ServerStream: ss})
+}
+
+// approvalStream checks the approval for the request message as the
+// handler receives it.
+type approvalStream struct {
+	grpc.ServerStream
+}
+
+func (s *approvalStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return checkApproval(s.Context(), m)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/access"
+)
+
+func approvalFor(t *testing.T, user string, paths ...string) access.Request {
+	t.Helper()
+	r, err := access.NewRequest("shop", access.Spec{User: user, Pod: "web-0", Paths: paths, Duration: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Decide("approver", true, "", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return *r
+}
+
+func TestApprovalInterceptor(t *testing.T) {
+	setSetting(t, &settings.requireApproval, true)
+	setSetting(t, &settings.podName, "web-0")
+	var requests []access.Request
+	var listErr error
+	lists := 0
+	setSetting(t, &listAccessRequests, func(ctx context.Context, namespace string) ([]access.Request, error) {
+		lists++
+		return requests, listErr
+	})
+	reset := func() {
+		approvalsMu.Lock()
+		approvals, approvalsListed = nil, time.Time{}
+		approvalsMu.Unlock()
+	}
+	t.Cleanup(reset)
+
+	call := func(ctx context.Context, method string, req any) error {
+		_, err := approvalUnaryInterceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+			return nil, nil
+		})
+		return err
+	}
+	read := &api.ReadRequest{Path: "/var/log/app.log"}
+
+	if err := call(context.Background(), api.PulsaarAgent_ReadFile_FullMethodName, read); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected an unidentified caller to be refused, got %v", err)
+	}
+	if err := call(certContext("alice"), api.PulsaarAgent_ReadFile_FullMethodName, read); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected a caller without an approval to be refused, got %v", err)
+	}
+
+	// An approval granted after a refusal takes effect without waiting for
+	// the cache, because a refusal lists again.
+	requests = []access.Request{approvalFor(t, "alice", "/var/log")}
+	time.Sleep(approvalRelistInterval)
+	if err := call(certContext("alice"), api.PulsaarAgent_ReadFile_FullMethodName, read); err != nil {
+		t.Errorf("expected the approved read to be served, got %v", err)
+	}
+	if err := call(certContext("alice"), api.PulsaarAgent_ReadFile_FullMethodName, &api.ReadRequest{Path: "/etc/shadow"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected a path outside the approval to be refused, got %v", err)
+	}
+	if err := call(certContext("mallory"), api.PulsaarAgent_ReadFile_FullMethodName, read); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected another user to be refused, got %v", err)
+	}
+	if err := call(certContext(defaultShutdownIdentity), api.PulsaarAgent_Shutdown_FullMethodName, &api.ShutdownRequest{}); err != nil {
+		t.Errorf("expected Shutdown to stay open for the controller, got %v", err)
+	}
+	for _, ctx := range []context.Context{context.Background(), certContext("mallory")} {
+		if err := call(ctx, api.PulsaarAgent_Shutdown_FullMethodName, &api.ShutdownRequest{}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected Shutdown by an ordinary caller to need an approval, got %v", err)
+		}
+	}
+
+	reset()
+	listErr = errors.New("forbidden")
+	if err := call(certContext("alice"), api.PulsaarAgent_ReadFile_FullMethodName, read); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable when approvals cannot be listed, got %v", err)
+	}
+
+	settings.requireApproval = false
+	before := lists
+	if err := call(context.Background(), api.PulsaarAgent_ReadFile_FullMethodName, read); err != nil || lists != before {
+		t.Errorf("expected no check without --require-approval, got %v", err)
+	}
+}
+
+func TestRequestPaths(t *testing.T) {
+	got := requestPaths(&api.TailRequest{Path: "/var/log/a.log", Paths: []string{"/var/log/*.txt"}})
+	if len(got) != 2 || got[0] != "/var/log/*.txt" || got[1] != "/var/log/a.log" {
+		t.Errorf("unexpected tail paths %v", got)
+	}
+	if got := requestPaths(&api.ShutdownRequest{}); got != nil {
+		t.Errorf("expected no paths for Shutdown, got %v", got)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// Audit results. A request is denied when the agent refuses the path or
+// the caller, and an error when it was allowed but could not be served.
+const (
+	auditAllowed = "allowed"
+	auditDenied  = "denied"
+	auditError   = "error"
+)
+
+// unaudited are the RPCs that only describe the agent.
+var unaudited = map[string]bool{
+	api.PulsaarAgent_Health_FullMethodName:       true,
+	api.PulsaarAgent_Capabilities_FullMethodName: true,
+}
+
+// audited reports whether method is a PulsaarAgent RPC that touches files
+// or the agent's lifecycle.
+func audited(method string) bool {
+	return strings.HasPrefix(method, "/"+api.PulsaarAgent_ServiceDesc.ServiceName+"/") && !unaudited[method]
+}
+
+// auditPath returns the path a request names, or for TailFile every path
+// and pattern it follows.
+func auditPath(req any) string {
+	switch r := req.(type) {
+	case *api.ListRequest:
+		return r.Path
+	case *api.StatRequest:
+		return r.Path
+	case *api.ReadRequest:
+		return r.Path
+	case *api.StreamRequest:
+		return r.Path
+	case *api.SearchRequest:
+		return r.Path
+	case *api.PreviewRequest:
+		return r.Path
+	case *api.ChecksumRequest:
+		return r.Path
+	case *api.FindRequest:
+		return r.Path
+	case *api.TailRequest:
+		patterns := r.Paths
+		if r.Path != "" {
+			patterns = append([]string{r.Path}, patterns...)
+		}
+		return strings.Join(patterns, ",")
+	}
+	return ""
+}
+
+// servedBytes counts the file content in a response.
+func servedBytes(resp any) int64 {
+	switch r := resp.(type) {
+	case *api.ReadResponse:
+		n := int64(len(r.GetData()))
+		for _, part := range r.GetParts() {
+			n += int64(len(part.GetData()))
+		}
+		return n
+	case *api.PreviewResponse:
+		return int64(len(r.GetHead()) + len(r.GetTail()))
+	}
+	return 0
+}
+
+// auditResult classifies the error a request ended with.
+func auditResult(err error) (result, code string) {
+	switch c := status.Code(err); c {
+	case codes.OK:
+		return auditAllowed, ""
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return auditDenied, c.String()
+	default:
+		return auditError, c.String()
+	}
+}
+
+// checkAuditable refuses requests under --audit-fail-closed while their
+// audit events could not be kept.
+func checkAuditable() error {
+	if settings.auditFailClosed && audits != nil && audits.Full() {
+		return status.Error(codes.Unavailable, "audit events cannot be recorded until the aggregator is reachable")
+	}
+	return nil
+}
+
+// finishAudit records how a request ended and sends its audit event.
+func finishAudit(ev *api.AuditEvent, start time.Time, bytes int64, err error) {
+	ev.Result, ev.ErrorCode = auditResult(err)
+	ev.BytesRead = bytes
+	ev.DurationMs = time.Since(start).Milliseconds()
+	auditLog(ev)
+}
+
+// auditUnaryInterceptor audits each request once the handler has decided
+// it, so denied and failed attempts are recorded along with reads.
+func auditUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !audited(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	if err := checkAuditable(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	ev := newAuditEvent(ctx, path.Base(info.FullMethod), auditPath(req))
+	resp, err := handler(ctx, req)
+	finishAudit(ev, start, servedBytes(resp), err)
+	return resp, err
+}
+
+func auditStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !audited(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	if err := checkAuditable(); err != nil {
+		return err
+	}
+	start := time.Now()
+	ev := newAuditEvent(ss.Context(), path.Base(info.FullMethod), "")
+	as := &auditingStream{ServerStream: ss}
+	err := handler(srv, as)
+	ev.Path = auditPath(as.req)
+	finishAudit(ev, start, as.bytes, err)
+	return err
+}
+
+// auditingStream remembers the request and counts the content sent back.
+type auditingStream struct {
+	grpc.ServerStream
+	req   any
+	bytes int64
+}
+
+func (s *auditingStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if s.req == nil {
+		s.req = m
+	}
+	return err
+}
+
+func (s *auditingStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.bytes += servedBytes(m)
+	}
+	return err
+}
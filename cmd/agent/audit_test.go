@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// captureAudit collects the events the agent sends for the rest of the test.
+func captureAudit(t *testing.T) chan *api.AuditEvent {
+	events := make(chan *api.AuditEvent, 10)
+	var sink auditSink = &auditBuffer{events: events}
+	setSetting(t, &audits, sink)
+	return events
+}
+
+func TestAuditUnaryOutcomes(t *testing.T) {
+	events := captureAudit(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(file, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: api.PulsaarAgent_ReadFile_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return (&server{}).ReadFile(ctx, req.(*api.ReadRequest))
+	}
+
+	tests := []struct {
+		name, path, result, code string
+		bytes                    int64
+	}{
+		{"allowed", file, auditAllowed, "", 5},
+		{"denied", "/etc/passwd", auditDenied, "PermissionDenied", 0},
+		{"failed", filepath.Join(dir, "missing.log"), auditError, "NotFound", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &api.ReadRequest{Path: tt.path, AllowedRoots: []string{dir}}
+			_, _ = auditUnaryInterceptor(context.Background(), req, info, handler)
+			ev := <-events
+			if ev.Operation != "ReadFile" || ev.Path != tt.path {
+				t.Errorf("expected ReadFile of %s, got %s of %s", tt.path, ev.Operation, ev.Path)
+			}
+			if ev.Result != tt.result || ev.ErrorCode != tt.code || ev.BytesRead != tt.bytes {
+				t.Errorf("expected %s/%q/%d, got %s/%q/%d", tt.result, tt.code, tt.bytes, ev.Result, ev.ErrorCode, ev.BytesRead)
+			}
+		})
+	}
+
+	info.FullMethod = api.PulsaarAgent_Health_FullMethodName
+	_, _ = auditUnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) { return nil, nil })
+	if len(events) != 0 {
+		t.Error("expected Health not to be audited")
+	}
+}
+
+// chunkStream answers one request and records nothing it sends.
+type chunkStream struct {
+	grpc.ServerStream
+	req *api.StreamRequest
+}
+
+func (s *chunkStream) Context() context.Context { return context.Background() }
+
+func (s *chunkStream) RecvMsg(m any) error {
+	*m.(*api.StreamRequest) = api.StreamRequest{Path: s.req.Path}
+	return nil
+}
+
+func (s *chunkStream) SendMsg(m any) error { return nil }
+
+func TestAuditStreamCountsBytes(t *testing.T) {
+	events := captureAudit(t)
+	info := &grpc.StreamServerInfo{FullMethod: api.PulsaarAgent_StreamFile_FullMethodName}
+	ss := &chunkStream{req: &api.StreamRequest{Path: "/var/log/app.log"}}
+	err := auditStreamInterceptor(nil, ss, info, func(srv any, stream grpc.ServerStream) error {
+		var req api.StreamRequest
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		for _, chunk := range []string{"first ", "second"} {
+			if err := stream.SendMsg(&api.ReadResponse{Data: []byte(chunk)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if ev.Operation != "StreamFile" || ev.Path != "/var/log/app.log" || ev.Result != auditAllowed || ev.BytesRead != 12 {
+		t.Errorf("expected an allowed 12-byte StreamFile of /var/log/app.log, got %v", ev)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+const (
+	// auditPostMaxBatch caps the events in one POST, keeping it well under
+	// the aggregator's default 64 KiB body limit.
+	auditPostMaxBatch = 50
+	// auditPostInterval is the longest an event waits for a batch to fill.
+	auditPostInterval = time.Second
+	// acceptedEventsHeader tells how much of a refused batch the aggregator
+	// kept anyway.
+	acceptedEventsHeader = "Pulsaar-Accepted-Events"
+)
+
+// auditEncoder renders a batch of events as a request body.
+type auditEncoder func(batch []*api.AuditEvent) ([]byte, error)
+
+// encodeAuditBatch renders a batch as the JSON array the aggregator's
+// /audit endpoint accepts.
+func encodeAuditBatch(batch []*api.AuditEvent) ([]byte, error) {
+	return json.Marshal(batch)
+}
+
+// auditPoster posts audit events to an HTTP endpoint in batches, instead of
+// one request per file operation.
+type auditPoster struct {
+	auditBuffer
+	url    string
+	encode auditEncoder
+	client *http.Client
+}
+
+func newAuditPoster(url string, encode auditEncoder, bufferSize int, spool *auditSpool, client *http.Client) *auditPoster {
+	a := &auditPoster{
+		auditBuffer: newAuditBuffer(bufferSize, spool),
+		url:         url,
+		encode:      encode,
+		client:      client,
+	}
+	go a.run()
+	go a.replay()
+	return a
+}
+
+func (a *auditPoster) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(auditPostInterval)
+	defer ticker.Stop()
+
+	var batch []*api.AuditEvent
+	for {
+		select {
+		case ev, ok := <-a.events:
+			if !ok {
+				a.deliver(batch)
+				return
+			}
+			batch = append(batch, ev)
+			if len(batch) < auditPostMaxBatch {
+				continue
+			}
+		case <-ticker.C:
+		case <-a.ctx.Done():
+			return
+		}
+		a.deliver(batch)
+		batch = nil
+	}
+}
+
+// deliver posts batch, retrying with backoff until the aggregator takes it
+// or the poster is closed. Events meanwhile wait in the buffer.
+func (a *auditPoster) deliver(batch []*api.AuditEvent) {
+	backoff := auditStreamMinBackoff
+	for len(batch) > 0 {
+		accepted, err := a.post(batch)
+		batch = batch[accepted:]
+		if err == nil {
+			return
+		}
+		warnf("Failed to send %d audit events to %s, retrying in %s: %v", len(batch), a.url, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-a.ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > auditStreamMaxBackoff {
+			backoff = auditStreamMaxBackoff
+		}
+	}
+}
+
+// post sends batch and returns how many of its events were accepted.
+// Events the aggregator rejects as invalid or too large are dropped, since
+// sending them again cannot succeed.
+func (a *auditPoster) post(batch []*api.AuditEvent) (int, error) {
+	body, err := a.encode(batch)
+	if err != nil {
+		return len(batch), nil
+	}
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return len(batch), nil
+	case resp.StatusCode == http.StatusRequestEntityTooLarge && len(batch) > 1:
+		// Split until each part fits.
+		half := len(batch) / 2
+		n, err := a.post(batch[:half])
+		if err != nil || n < half {
+			return n, err
+		}
+		m, err := a.post(batch[half:])
+		return half + m, err
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		accepted, _ := strconv.Atoi(resp.Header.Get(acceptedEventsHeader))
+		return min(max(accepted, 0), len(batch)), fmt.Errorf("aggregator answered %s", resp.Status)
+	default:
+		warnf("Dropping %d audit events the aggregator refused: %s", len(batch), resp.Status)
+		return len(batch), nil
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestAuditPosterBatches(t *testing.T) {
+	var mu sync.Mutex
+	var posts [][]*api.AuditEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []*api.AuditEvent
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("expected a JSON array, got %v", err)
+		}
+		mu.Lock()
+		posts = append(posts, batch)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	a := newAuditPoster(srv.URL, encodeAuditBatch, 10, nil, srv.Client())
+	for _, p := range []string{"/a", "/b", "/c"} {
+		a.Send(&api.AuditEvent{Operation: "ReadFile", Path: p})
+	}
+	a.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	var paths []string
+	for _, batch := range posts {
+		for _, ev := range batch {
+			paths = append(paths, ev.Path)
+		}
+	}
+	if len(paths) != 3 || paths[0] != "/a" || paths[2] != "/c" {
+		t.Errorf("expected /a, /b and /c in order, got %v", paths)
+	}
+	if len(posts) > 2 {
+		t.Errorf("expected the events to be batched, got %d posts", len(posts))
+	}
+}
+
+func TestAuditPosterResumesAfterPartialAccept(t *testing.T) {
+	var mu sync.Mutex
+	var posts [][]*api.AuditEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []*api.AuditEvent
+		_ = json.NewDecoder(r.Body).Decode(&batch)
+		mu.Lock()
+		posts = append(posts, batch)
+		first := len(posts) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set(acceptedEventsHeader, "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	a := &auditPoster{auditBuffer: newAuditBuffer(1, nil), url: srv.URL, encode: encodeAuditBatch, client: srv.Client()}
+	a.deliver([]*api.AuditEvent{{Path: "/a"}, {Path: "/b"}})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posts) != 2 {
+		t.Fatalf("expected a retry after the 429, got %d posts", len(posts))
+	}
+	if len(posts[1]) != 1 || posts[1][0].Path != "/b" {
+		t.Errorf("expected only the unaccepted event to be resent, got %v", posts[1])
+	}
+}
+
+func TestAuditPosterSplitsTooLarge(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []*api.AuditEvent
+		_ = json.NewDecoder(r.Body).Decode(&batch)
+		mu.Lock()
+		sizes = append(sizes, len(batch))
+		mu.Unlock()
+		if len(batch) > 1 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	a := &auditPoster{auditBuffer: newAuditBuffer(1, nil), url: srv.URL, encode: encodeAuditBatch, client: srv.Client()}
+	a.deliver([]*api.AuditEvent{{Path: "/a"}, {Path: "/b"}, {Path: "/c"}})
+
+	mu.Lock()
+	defer mu.Unlock()
+	delivered := 0
+	for _, n := range sizes {
+		if n == 1 {
+			delivered++
+		}
+	}
+	if delivered != 3 {
+		t.Errorf("expected each event to be posted alone, got batches of %v", sizes)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// auditHTTPClient posts audit events to --audit-url.
+var auditHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// otlpHTTPClient posts audit events to --audit-otlp-url. The audit TLS
+// settings are for the aggregator, so it trusts the system roots.
+var otlpHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// initAuditSinks sets up every configured audit output: the aggregator,
+// over TLS when cfg.auditTLS is set and overflowing to cfg.auditSpool,
+// plus stdout, a local file and an OTLP collector.
+func initAuditSinks(cfg *agentConfig) {
+	var sinks auditSinks
+	creds := insecure.NewCredentials()
+	if cfg.auditTLS != nil {
+		creds = credentials.NewTLS(cfg.auditTLS)
+		auditHTTPClient = &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: cfg.auditTLS},
+		}
+	}
+	if addr := settings.auditGRPCAddr; addr != "" {
+		sinks = append(sinks, newAuditStreamer(addr, settings.auditBufferSize, cfg.auditSpool, grpc.WithTransportCredentials(creds)))
+		infof("Streaming audit events to %s (tls=%t)", addr, cfg.auditTLS != nil)
+	} else if url := settings.auditURL; url != "" {
+		sinks = append(sinks, newAuditPoster(url, encodeAuditBatch, settings.auditBufferSize, cfg.auditSpool, auditHTTPClient))
+		infof("Posting audit events to %s", url)
+	}
+	if url := settings.auditOTLPURL; url != "" {
+		sinks = append(sinks, newAuditPoster(url, encodeOTLPLogs, settings.auditBufferSize, nil, otlpHTTPClient))
+		infof("Exporting audit events as OTLP logs to %s", url)
+	}
+	if settings.auditStdout {
+		sinks = append(sinks, &auditWriter{w: os.Stdout})
+		infof("Writing audit events to stdout")
+	}
+	if cfg.auditFile != nil {
+		sinks = append(sinks, &auditWriter{w: cfg.auditFile})
+		infof("Writing audit events to %s", cfg.auditFile.Name())
+	}
+	if len(sinks) > 0 {
+		audits = sinks
+	}
+}
+
+// auditSinks sends each event to every configured output.
+type auditSinks []auditSink
+
+func (s auditSinks) Send(ev *api.AuditEvent) {
+	for _, sink := range s {
+		sink.Send(ev)
+	}
+}
+
+// Full reports whether any output may drop events sent now.
+func (s auditSinks) Full() bool {
+	for _, sink := range s {
+		if sink.Full() {
+			return true
+		}
+	}
+	return false
+}
+
+// Close flushes the outputs together, so each gets the whole timeout.
+func (s auditSinks) Close(timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, sink := range s {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sink.Close(timeout)
+		}()
+	}
+	wg.Wait()
+}
+
+// auditWriter writes each event as a line of JSON, for log collectors that
+// tail stdout or a file.
+type auditWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (a *auditWriter) Send(ev *api.AuditEvent) {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		warnf("Failed to write audit event: %v", err)
+	}
+}
+
+// Full is false: writes are local and never queued.
+func (a *auditWriter) Full() bool { return false }
+
+func (a *auditWriter) Close(time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if f, ok := a.w.(*os.File); ok && f != os.Stdout {
+		_ = f.Close()
+	}
+}
+
+// openAuditFile opens --audit-file for appending.
+func openAuditFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+}
+
+// The OTLP/HTTP JSON encoding of a logs export request, limited to the
+// fields audit events use.
+type (
+	otlpLogsRequest struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeLogs struct {
+		Scope      otlpScope       `json:"scope"`
+		LogRecords []otlpLogRecord `json:"logRecords"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpLogRecord struct {
+		TimeUnixNano   string         `json:"timeUnixNano,omitempty"`
+		SeverityNumber int            `json:"severityNumber"`
+		SeverityText   string         `json:"severityText"`
+		Body           otlpAnyValue   `json:"body"`
+		Attributes     []otlpKeyValue `json:"attributes"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+)
+
+// OTLP severity numbers for INFO and WARN.
+const (
+	otlpSeverityInfo = 9
+	otlpSeverityWarn = 13
+)
+
+func otlpStringValue(v string) otlpAnyValue {
+	return otlpAnyValue{StringValue: &v}
+}
+
+func otlpString(key, v string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpStringValue(v)}
+}
+
+func otlpInt(key string, v int64) otlpKeyValue {
+	s := strconv.FormatInt(v, 10)
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{IntValue: &s}}
+}
+
+// encodeOTLPLogs renders a batch as an OTLP logs export request. Allowed
+// requests are INFO records and denied or failed ones WARN; event fields
+// become attributes, using the OpenTelemetry names where there is one.
+func encodeOTLPLogs(batch []*api.AuditEvent) ([]byte, error) {
+	records := make([]otlpLogRecord, len(batch))
+	for i, ev := range batch {
+		r := otlpLogRecord{
+			SeverityNumber: otlpSeverityInfo,
+			SeverityText:   "INFO",
+			Body:           otlpStringValue(ev.Operation + " " + ev.Path + " " + ev.Result),
+			Attributes: []otlpKeyValue{
+				otlpString("pulsaar.operation", ev.Operation),
+				otlpString("pulsaar.path", ev.Path),
+				otlpString("pulsaar.result", ev.Result),
+				otlpString("pulsaar.error_code", ev.ErrorCode),
+				otlpString("pulsaar.agent_id", ev.AgentId),
+				otlpString("enduser.id", ev.User),
+				otlpString("client.address", ev.ClientAddr),
+				otlpString("k8s.namespace.name", ev.Namespace),
+				otlpString("k8s.pod.name", ev.Pod),
+				otlpString("k8s.container.name", ev.Container),
+				otlpString("k8s.node.name", ev.Node),
+				otlpString("pulsaar.request_id", ev.RequestId),
+				otlpInt("pulsaar.bytes_read", ev.BytesRead),
+				otlpInt("pulsaar.duration_ms", ev.DurationMs),
+			},
+		}
+		if ev.Result != auditAllowed {
+			r.SeverityNumber, r.SeverityText = otlpSeverityWarn, "WARN"
+		}
+		if t, err := time.Parse(time.RFC3339, ev.Timestamp); err == nil {
+			r.TimeUnixNano = strconv.FormatInt(t.UnixNano(), 10)
+		}
+		records[i] = r
+	}
+	return json.Marshal(otlpLogsRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource:  otlpResource{Attributes: []otlpKeyValue{otlpString("service.name", "pulsaar-agent")}},
+		ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: "pulsaar.audit", Version: version}, LogRecords: records}},
+	}}})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestInitAuditSinksWritesEveryOutput(t *testing.T) {
+	setSetting(t, &audits, nil)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	f, err := openAuditFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initAuditSinks(&agentConfig{auditFile: f})
+	var buf bytes.Buffer
+	sinks := append(audits.(auditSinks), &auditWriter{w: &buf})
+
+	sinks.Send(&api.AuditEvent{Operation: "ReadFile", Path: "/etc/hosts", Result: auditAllowed})
+	sinks.Close(time.Second)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{"file": string(data), "writer": buf.String()} {
+		var ev api.AuditEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &ev); err != nil || ev.Path != "/etc/hosts" {
+			t.Errorf("expected the %s output to hold the event as JSON, got %q", name, out)
+		}
+	}
+}
+
+func TestEncodeOTLPLogs(t *testing.T) {
+	body, err := encodeOTLPLogs([]*api.AuditEvent{
+		{Timestamp: "2024-05-01T12:00:00Z", Operation: "ReadFile", Path: "/etc/hosts", Result: auditAllowed, BytesRead: 42},
+		{Operation: "ReadFile", Path: "/etc/shadow", Result: auditDenied, ErrorCode: "PermissionDenied"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req otlpLogsRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatalf("expected 2 log records, got %d", len(records))
+	}
+	if records[0].TimeUnixNano != "1714564800000000000" || records[0].SeverityText != "INFO" {
+		t.Errorf("expected an INFO record at the event time, got %+v", records[0])
+	}
+	if records[1].SeverityText != "WARN" {
+		t.Errorf("expected a denied request to be a WARN record, got %s", records[1].SeverityText)
+	}
+	attrs := map[string]string{}
+	for _, kv := range records[0].Attributes {
+		if kv.Value.StringValue != nil {
+			attrs[kv.Key] = *kv.Value.StringValue
+		} else {
+			attrs[kv.Key] = *kv.Value.IntValue
+		}
+	}
+	if attrs["pulsaar.path"] != "/etc/hosts" || attrs["pulsaar.bytes_read"] != "42" {
+		t.Errorf("expected the event fields as attributes, got %v", attrs)
+	}
+}
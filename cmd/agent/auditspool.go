@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+const (
+	defaultAuditSpoolMaxBytes = 64 << 20
+	auditSpoolFile            = "audit-spool.jsonl"
+	// auditSpoolReserve is room kept for one more event; a spool with less
+	// free space counts as full.
+	auditSpoolReserve = 4 << 10
+)
+
+// auditSpool keeps audit events on disk, one JSON object per line, while
+// the in-memory buffer is full. Events are handed back oldest first, and
+// the file is emptied once all of them have been. Events still spooled
+// when the agent stops are delivered after it restarts.
+type auditSpool struct {
+	mu       sync.Mutex
+	file     *os.File
+	maxBytes int64
+	size     int64 // bytes written
+	offset   int64 // bytes handed back
+	added    chan struct{}
+}
+
+// openAuditSpool opens the spool in dir, keeping any events left from an
+// earlier run.
+func openAuditSpool(dir string, maxBytes int64) (*auditSpool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, auditSpoolFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if info.Size() > 0 {
+		infof("Audit spool %s holds %d bytes of undelivered events", f.Name(), info.Size())
+	}
+	return &auditSpool{file: f, maxBytes: maxBytes, size: info.Size(), added: make(chan struct{}, 1)}, nil
+}
+
+// add appends ev. It reports false when the spool has no room for it.
+func (s *auditSpool) add(ev *api.AuditEvent) bool {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return false
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size+int64(len(line)) > s.maxBytes {
+		return false
+	}
+	if _, err := s.file.WriteAt(line, s.size); err != nil {
+		warnf("Failed to spool audit event: %v", err)
+		return false
+	}
+	s.size += int64(len(line))
+	select {
+	case s.added <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pending reports whether events are waiting in the spool.
+func (s *auditSpool) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset < s.size
+}
+
+// full reports whether the spool may have no room for another event.
+func (s *auditSpool) full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxBytes-s.size < auditSpoolReserve
+}
+
+// peek returns the oldest spooled event and the bytes it takes up, without
+// removing it. It returns 0 bytes when the spool is empty, and a nil event
+// for a line that cannot be read back, such as one cut short by a crash.
+func (s *auditSpool) peek() (*api.AuditEvent, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offset >= s.size {
+		return nil, 0
+	}
+	r := bufio.NewReader(io.NewSectionReader(s.file, s.offset, s.size-s.offset))
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		if err != io.EOF {
+			warnf("Failed to read the audit spool, discarding it: %v", err)
+		}
+		return nil, s.size - s.offset
+	}
+	ev := &api.AuditEvent{}
+	if err := json.Unmarshal(line, ev); err != nil {
+		warnf("Discarding an unreadable audit spool entry: %v", err)
+		return nil, int64(len(line))
+	}
+	return ev, int64(len(line))
+}
+
+// remove drops n bytes of events returned by peek.
+func (s *auditSpool) remove(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += n
+	if s.offset < s.size {
+		return
+	}
+	if err := s.file.Truncate(0); err != nil {
+		warnf("Failed to empty the audit spool: %v", err)
+		return
+	}
+	s.size, s.offset = 0, 0
+}
+
+func (s *auditSpool) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.file.Close(); err != nil {
+		warnf("Failed to close the audit spool: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestAuditSpoolKeepsEventsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := openAuditSpool(dir, defaultAuditSpoolMaxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/a", "/b"} {
+		if !spool.add(&api.AuditEvent{Path: p}) {
+			t.Fatalf("expected %s to be spooled", p)
+		}
+	}
+	spool.close()
+
+	spool, err = openAuditSpool(dir, defaultAuditSpoolMaxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.close()
+	for _, want := range []string{"/a", "/b"} {
+		ev, n := spool.peek()
+		if ev == nil || ev.Path != want {
+			t.Fatalf("expected %s, got %v", want, ev)
+		}
+		spool.remove(n)
+	}
+	if spool.pending() {
+		t.Error("expected the spool to be empty")
+	}
+	if info, err := os.Stat(filepath.Join(dir, auditSpoolFile)); err != nil || info.Size() != 0 {
+		t.Errorf("expected the drained spool file to be truncated, got %v, %v", info, err)
+	}
+}
+
+func TestAuditSpoolSkipsTruncatedEntry(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, auditSpoolFile), []byte(`{"path":"/a"}`+"\n"+`{"path":"/b`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spool, err := openAuditSpool(dir, defaultAuditSpoolMaxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.close()
+	ev, n := spool.peek()
+	if ev == nil || ev.Path != "/a" {
+		t.Fatalf("expected /a, got %v", ev)
+	}
+	spool.remove(n)
+	if ev, n = spool.peek(); ev != nil || n == 0 {
+		t.Fatalf("expected the cut-off entry to be skipped, got %v, %d", ev, n)
+	}
+	spool.remove(n)
+	if spool.pending() {
+		t.Error("expected the spool to be empty")
+	}
+}
+
+func TestAuditBufferOverflowsToSpool(t *testing.T) {
+	spool, err := openAuditSpool(t.TempDir(), defaultAuditSpoolMaxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newAuditBuffer(1, spool)
+	for _, p := range []string{"/a", "/b", "/c"} {
+		a.Send(&api.AuditEvent{Path: p})
+	}
+	if !spool.pending() {
+		t.Fatal("expected events beyond the buffer to be spooled")
+	}
+
+	go a.replay()
+	for _, want := range []string{"/a", "/b", "/c"} {
+		select {
+		case ev := <-a.events:
+			if ev.Path != want {
+				t.Errorf("expected %s, got %s", want, ev.Path)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+	a.stopReplay()
+	<-a.replayed
+	spool.close()
+}
+
+func TestAuditFailClosed(t *testing.T) {
+	events := make(chan *api.AuditEvent, 1)
+	var sink auditSink = &auditBuffer{events: events}
+	setSetting(t, &audits, sink)
+	setSetting(t, &settings.auditFailClosed, true)
+
+	info := &grpc.UnaryServerInfo{FullMethod: api.PulsaarAgent_Stat_FullMethodName}
+	called := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		called++
+		return &api.StatResponse{}, nil
+	}
+	req := &api.StatRequest{Path: "/tmp"}
+	if _, err := auditUnaryInterceptor(context.Background(), req, info, handler); err != nil {
+		t.Fatalf("expected the request to be served while the buffer has room, got %v", err)
+	}
+	_, err := auditUnaryInterceptor(context.Background(), req, info, handler)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable once events would be dropped, got %v", err)
+	}
+	if called != 1 {
+		t.Errorf("expected the refused request not to reach the handler, got %d calls", called)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+const (
+	defaultAuditBufferSize = 1000
+	auditStreamMinBackoff  = 500 * time.Millisecond
+	auditStreamMaxBackoff  = 30 * time.Second
+)
+
+// auditSink delivers audit events to the aggregator in the background.
+type auditSink interface {
+	Send(ev *api.AuditEvent)
+	Full() bool
+	Close(timeout time.Duration)
+}
+
+// audits is where auditLog sends events; nil when no aggregator is set.
+var audits auditSink
+
+// auditBuffer holds events for a background worker, so file operations
+// never wait on the aggregator. When it is full new events go to the
+// spool, if there is one, or are dropped and logged rather than blocking
+// the RPC path.
+type auditBuffer struct {
+	events chan *api.AuditEvent
+	spool  *auditSpool
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	once       sync.Once
+	replayCtx  context.Context
+	stopReplay context.CancelFunc
+	replayed   chan struct{}
+}
+
+func newAuditBuffer(size int, spool *auditSpool) auditBuffer {
+	ctx, cancel := context.WithCancel(context.Background())
+	replayCtx, stopReplay := context.WithCancel(context.Background())
+	return auditBuffer{
+		events:     make(chan *api.AuditEvent, size),
+		spool:      spool,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		replayCtx:  replayCtx,
+		stopReplay: stopReplay,
+		replayed:   make(chan struct{}),
+	}
+}
+
+// Send buffers ev for delivery. It never blocks.
+func (a *auditBuffer) Send(ev *api.AuditEvent) {
+	// Once events are spooled, later ones queue behind them to keep order.
+	if a.spool != nil && a.spool.pending() {
+		a.spill(ev)
+		return
+	}
+	select {
+	case a.events <- ev:
+	default:
+		a.spill(ev)
+	}
+}
+
+func (a *auditBuffer) spill(ev *api.AuditEvent) {
+	if a.spool != nil && a.spool.add(ev) {
+		return
+	}
+	warnf("Audit buffer full, dropping audit event: %s %s", ev.Operation, ev.Path)
+}
+
+// Full reports whether events sent now may be dropped.
+func (a *auditBuffer) Full() bool {
+	if a.spool != nil {
+		return a.spool.full()
+	}
+	return len(a.events) == cap(a.events)
+}
+
+// replay moves spooled events back into the buffer as the worker makes
+// room for them. Workers start it alongside themselves.
+func (a *auditBuffer) replay() {
+	defer close(a.replayed)
+	if a.spool == nil {
+		return
+	}
+	ctx := a.replayCtx
+	for {
+		ev, n := a.spool.peek()
+		if n == 0 {
+			select {
+			case <-a.spool.added:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ev != nil {
+			select {
+			case a.events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+		a.spool.remove(n)
+	}
+}
+
+// Close flushes buffered events, waiting at most timeout, and stops the
+// worker. Events it could not deliver are kept in the spool, if there is
+// one, for the next start.
+func (a *auditBuffer) Close(timeout time.Duration) {
+	a.once.Do(func() {
+		if a.spool != nil {
+			a.stopReplay()
+			<-a.replayed
+		}
+		close(a.events)
+	})
+	select {
+	case <-a.done:
+	case <-time.After(timeout):
+		a.cancel()
+		<-a.done
+	}
+	if a.spool != nil {
+		for ev := range a.events {
+			a.spool.add(ev)
+		}
+		a.spool.close()
+	}
+}
+
+// auditStreamer ships audit events to the aggregator's AuditSink over a
+// single long-lived client stream.
+type auditStreamer struct {
+	auditBuffer
+	addr     string
+	dialOpts []grpc.DialOption
+}
+
+// loadAuditClientTLSConfig builds the TLS configuration used to reach the
+// aggregator. --audit-tls-ca-file verifies the aggregator certificate;
+// --audit-tls-cert-file and --audit-tls-key-file supply a client
+// certificate for mTLS. It returns nil when none of them are set.
+func loadAuditClientTLSConfig() (*tls.Config, error) {
+	caFile := settings.auditTLSCAFile
+	certFile := settings.auditTLSCertFile
+	keyFile := settings.auditTLSKeyFile
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse audit CA certificate")
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("both --audit-tls-cert-file and --audit-tls-key-file must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load audit client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func newAuditStreamer(addr string, bufferSize int, spool *auditSpool, dialOpts ...grpc.DialOption) *auditStreamer {
+	a := &auditStreamer{
+		auditBuffer: newAuditBuffer(bufferSize, spool),
+		addr:        addr,
+		dialOpts:    dialOpts,
+	}
+	go a.run()
+	go a.replay()
+	return a
+}
+
+func (a *auditStreamer) run() {
+	defer close(a.done)
+
+	conn, err := grpc.NewClient(a.addr, a.dialOpts...)
+	if err != nil {
+		warnf("Failed to create audit stream client for %s: %v", a.addr, err)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	client := api.NewAuditSinkClient(conn)
+
+	var pending *api.AuditEvent
+	backoff := auditStreamMinBackoff
+	for {
+		stream, err := client.StreamAudit(a.ctx)
+		if err == nil {
+			var closed bool
+			pending, closed, err = a.pump(stream, pending)
+			if _, ackErr := stream.CloseAndRecv(); err == nil {
+				err = ackErr
+			}
+			if closed && err == nil {
+				return
+			}
+		}
+		if a.ctx.Err() != nil {
+			return
+		}
+		warnf("Audit stream to %s interrupted, retrying in %s: %v", a.addr, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-a.ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > auditStreamMaxBackoff {
+			backoff = auditStreamMaxBackoff
+		}
+	}
+}
+
+// pump sends pending (if any) and then buffered events until the buffer is
+// closed or a send fails. It returns the event that could not be delivered.
+func (a *auditStreamer) pump(stream api.AuditSink_StreamAuditClient, pending *api.AuditEvent) (*api.AuditEvent, bool, error) {
+	for {
+		ev := pending
+		if ev == nil {
+			var ok bool
+			select {
+			case ev, ok = <-a.events:
+				if !ok {
+					return nil, true, nil
+				}
+			case <-a.ctx.Done():
+				return nil, true, a.ctx.Err()
+			}
+		}
+		if err := stream.Send(ev); err != nil {
+			return ev, false, err
+		}
+		pending = nil
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+type fakeAuditSink struct {
+	api.UnimplementedAuditSinkServer
+	mu     sync.Mutex
+	events []*api.AuditEvent
+}
+
+func (f *fakeAuditSink) StreamAudit(stream api.AuditSink_StreamAuditServer) error {
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&api.AuditAck{})
+		}
+		if err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.events = append(f.events, ev)
+		f.mu.Unlock()
+	}
+}
+
+func TestAuditStreamerDelivers(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	sink := &fakeAuditSink{}
+	s := grpc.NewServer()
+	api.RegisterAuditSinkServer(s, sink)
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+
+	a := newAuditStreamer("passthrough:///bufnet", 10, nil,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	a.Send(&api.AuditEvent{Operation: "ReadFile", Path: "/etc/hosts"})
+	a.Send(&api.AuditEvent{Operation: "Stat", Path: "/tmp"})
+	a.Close(5 * time.Second)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.events) != 2 {
+		t.Fatalf("expected 2 delivered events, got %d", len(sink.events))
+	}
+	if sink.events[1].Path != "/tmp" {
+		t.Errorf("expected events in order, got %v", sink.events)
+	}
+}
+
+func TestAuditStreamerDropsWhenFull(t *testing.T) {
+	a := &auditStreamer{auditBuffer: auditBuffer{events: make(chan *api.AuditEvent, 1)}}
+	a.Send(&api.AuditEvent{Path: "/a"})
+	a.Send(&api.AuditEvent{Path: "/b"})
+	if len(a.events) != 1 {
+		t.Errorf("expected buffer to hold 1 event, got %d", len(a.events))
+	}
+}
+
+func TestLoadAuditClientTLSConfig(t *testing.T) {
+	if config, err := loadAuditClientTLSConfig(); err != nil || config != nil {
+		t.Fatalf("expected nil config without TLS settings, got %v, %v", config, err)
+	}
+
+	setSetting(t, &settings.auditTLSCertFile, "/nonexistent/tls.crt")
+	if _, err := loadAuditClientTLSConfig(); err == nil {
+		t.Error("expected error when only the client certificate is set")
+	}
+
+	settings.auditTLSCertFile = ""
+	setSetting(t, &settings.auditTLSCAFile, "/nonexistent/ca.crt")
+	if _, err := loadAuditClientTLSConfig(); err == nil {
+		t.Error("expected error for a missing CA file")
+	}
+}
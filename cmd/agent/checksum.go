@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// Checksum returns the SHA-256 of a file, or of its first length bytes, so
+// a client can check a copy against the source.
+func (s *server) Checksum(ctx context.Context, req *api.ChecksumRequest) (*api.ChecksumResponse, error) {
+	if !getLimiterForIP(ctx).Allow() {
+		return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
+	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
+	if !isPathAllowed(req.Path, allowedRoots) {
+		return nil, status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
+	}
+	file, err := openFile(req.Path, allowedRoots)
+	if err != nil {
+		return nil, openError("Unable to open file '%s' for reading: %v", req.Path, err)
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, status.Errorf(codes.FailedPrecondition, "'%s' is not a regular file", req.Path)
+	}
+
+	var r io.Reader = &ctxReader{ctx: ctx, r: file}
+	if req.Length > 0 {
+		r = io.LimitReader(r, req.Length)
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Errorf(codes.Internal, "Unable to read file '%s': %v", req.Path, err)
+	}
+	return &api.ChecksumResponse{Algorithm: "sha256", Digest: hex.EncodeToString(h.Sum(nil)), SizeBytes: n}, nil
+}
+
+// ctxReader stops reading once ctx is done, so hashing a large file ends
+// with its request.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
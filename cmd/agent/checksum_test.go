@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestChecksum(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(p, []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{}
+	ctx := context.Background()
+	roots := []string{dir}
+
+	tests := []struct {
+		length int64
+		data   string
+	}{
+		{0, "hello world"},
+		{5, "hello"},
+		{100, "hello world"},
+	}
+	for _, tt := range tests {
+		resp, err := s.Checksum(ctx, &api.ChecksumRequest{Path: p, Length: tt.length, AllowedRoots: roots})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(tt.data))
+		if resp.Algorithm != "sha256" || resp.Digest != hex.EncodeToString(sum[:]) || resp.SizeBytes != int64(len(tt.data)) {
+			t.Errorf("Checksum(length=%d) = %v; want the sha256 of %q", tt.length, resp, tt.data)
+		}
+	}
+
+	if _, err := s.Checksum(ctx, &api.ChecksumRequest{Path: dir, AllowedRoots: roots}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition for a directory, got %v", err)
+	}
+	if _, err := s.Checksum(ctx, &api.ChecksumRequest{Path: "/etc/passwd", AllowedRoots: roots}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied outside the allowed roots, got %v", err)
+	}
+}
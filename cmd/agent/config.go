@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+// settings holds the agent configuration read where it is used. Limits
+// with their own variables, such as rpcTimeout, are bound to those instead.
+var settings = struct {
+	listenAddr, metricsAddr            string
+	tlsCertFile, tlsKeyFile, tlsCAFile string
+	allowedRoots                       []string
+	hostRoot, targetContainer          string
+	namespace, podName, containerName  string
+	nodeName                           string
+	auditURL, auditGRPCAddr            string
+	auditBufferSize                    int
+	auditSpoolDir                      string
+	auditSpoolMaxBytes                 int64
+	auditFailClosed                    bool
+	auditStdout                        bool
+	auditFile, auditOTLPURL            string
+	auditTLSCAFile                     string
+	auditTLSCertFile, auditTLSKeyFile  string
+	logLevel                           string
+	metricsTLS                         bool
+	metricsTokenFile                   string
+	requireApproval                    bool
+	shutdownIdentities                 []string
+	privilege                          string
+	sandbox                            bool
+}{
+	listenAddr:         ":50051",
+	metricsAddr:        ":9090",
+	auditBufferSize:    defaultAuditBufferSize,
+	auditSpoolMaxBytes: defaultAuditSpoolMaxBytes,
+	logLevel:           "info",
+	shutdownIdentities: []string{defaultShutdownIdentity},
+	privilege:          privilege.NonRoot,
+	sandbox:            true,
+}
+
+// settingEnv maps each flag to the environment variable that sets it when
+// the flag is not given. Pods injected by the CLI and webhook are
+// configured through these variables.
+var settingEnv = map[string]string{
+	"listen":                 "PULSAAR_LISTEN_ADDR",
+	"metrics-listen":         "PULSAAR_METRICS_ADDR",
+	"tls-cert-file":          "PULSAAR_TLS_CERT_FILE",
+	"tls-key-file":           "PULSAAR_TLS_KEY_FILE",
+	"tls-ca-file":            "PULSAAR_TLS_CA_FILE",
+	"allowed-roots":          "PULSAAR_ALLOWED_ROOTS",
+	"host-root":              "PULSAAR_HOST_ROOT",
+	"target-container":       "PULSAAR_TARGET_CONTAINER",
+	"namespace":              "PULSAAR_NAMESPACE",
+	"pod-name":               "PULSAAR_POD_NAME",
+	"container-name":         "PULSAAR_CONTAINER_NAME",
+	"node-name":              "PULSAAR_NODE_NAME",
+	"audit-url":              "PULSAAR_AUDIT_AGGREGATOR_URL",
+	"audit-grpc-addr":        "PULSAAR_AUDIT_AGGREGATOR_GRPC_ADDR",
+	"audit-buffer-size":      "PULSAAR_AUDIT_BUFFER_SIZE",
+	"audit-spool-dir":        "PULSAAR_AUDIT_SPOOL_DIR",
+	"audit-spool-max-bytes":  "PULSAAR_AUDIT_SPOOL_MAX_BYTES",
+	"audit-fail-closed":      "PULSAAR_AUDIT_FAIL_CLOSED",
+	"audit-stdout":           "PULSAAR_AUDIT_STDOUT",
+	"audit-file":             "PULSAAR_AUDIT_FILE",
+	"audit-otlp-url":         "PULSAAR_AUDIT_OTLP_URL",
+	"audit-tls-ca-file":      "PULSAAR_AUDIT_TLS_CA_FILE",
+	"audit-tls-cert-file":    "PULSAAR_AUDIT_TLS_CERT_FILE",
+	"audit-tls-key-file":     "PULSAAR_AUDIT_TLS_KEY_FILE",
+	"max-decompressed-bytes": "PULSAAR_MAX_DECOMPRESSED_BYTES",
+	"rpc-timeout":            "PULSAAR_RPC_TIMEOUT",
+	"max-stream-duration":    "PULSAAR_MAX_STREAM_DURATION",
+	"metrics-identity":       "PULSAAR_METRICS_IDENTITY",
+	"metrics-max-identities": "PULSAAR_METRICS_MAX_IDENTITIES",
+	"metrics-tls":            "PULSAAR_METRICS_TLS",
+	"metrics-token-file":     "PULSAAR_METRICS_TOKEN_FILE",
+	"log-level":              "PULSAAR_LOG_LEVEL",
+	"require-approval":       "PULSAAR_REQUIRE_APPROVAL",
+	"shutdown-identities":    "PULSAAR_SHUTDOWN_IDENTITIES",
+	"privilege":              privilege.Env,
+	"sandbox":                "PULSAAR_SANDBOX",
+}
+
+// configFileEnv names the config file when --config is not given.
+const configFileEnv = "PULSAAR_AGENT_CONFIG"
+
+func addSettingFlags(fs *pflag.FlagSet) {
+	fs.String("config", "", "YAML config file whose keys are these flag names, e.g. rpc-timeout: 1m ($"+configFileEnv+")")
+	fs.StringVar(&settings.listenAddr, "listen", settings.listenAddr, "Address the gRPC server listens on")
+	fs.StringVar(&settings.metricsAddr, "metrics-listen", settings.metricsAddr, "Address the Prometheus /metrics and /readyz server listens on, e.g. localhost:9090; \"off\" disables it")
+	fs.StringVar(&settings.tlsCertFile, "tls-cert-file", "", "Server certificate; a self-signed one is generated when unset")
+	fs.StringVar(&settings.tlsKeyFile, "tls-key-file", "", "Server key")
+	fs.StringVar(&settings.tlsCAFile, "tls-ca-file", "", "CA that client certificates must be signed by; enables mTLS")
+	fs.StringSliceVar(&settings.allowedRoots, "allowed-roots", nil, "Directories clients may read, used when neither the pulsaar.io/allowed-roots pod annotation nor the pulsaar-config ConfigMap sets them (default /)")
+	fs.StringVar(&settings.hostRoot, "host-root", "", "Serve node paths from this mount, for a DaemonSet agent")
+	fs.StringVar(&settings.targetContainer, "target-container", "", "Serve this container's filesystem through /proc/1/root")
+	fs.StringVar(&settings.namespace, "namespace", "", "Pod namespace, recorded in audit events (default the service account's namespace)")
+	fs.StringVar(&settings.podName, "pod-name", "", "Pod name, recorded in audit events")
+	fs.StringVar(&settings.containerName, "container-name", "", "Container name, recorded in audit events")
+	fs.StringVar(&settings.nodeName, "node-name", "", "Node name, recorded in audit events")
+	fs.StringVar(&settings.auditURL, "audit-url", "", "Aggregator URL audit events are POSTed to in batches")
+	fs.StringVar(&settings.auditGRPCAddr, "audit-grpc-addr", "", "Aggregator gRPC address audit events are streamed to; preferred over --audit-url")
+	fs.IntVar(&settings.auditBufferSize, "audit-buffer-size", settings.auditBufferSize, "Audit events buffered in memory while the aggregator is unreachable")
+	fs.StringVar(&settings.auditSpoolDir, "audit-spool-dir", "", "Directory where audit events are spooled once the buffer is full, and kept across restarts until delivered")
+	fs.Int64Var(&settings.auditSpoolMaxBytes, "audit-spool-max-bytes", settings.auditSpoolMaxBytes, "Largest size of the audit spool")
+	fs.BoolVar(&settings.auditFailClosed, "audit-fail-closed", false, "Refuse requests while audit events would be dropped, instead of serving them unaudited")
+	fs.BoolVar(&settings.auditStdout, "audit-stdout", false, "Also write each audit event to stdout as a line of JSON")
+	fs.StringVar(&settings.auditFile, "audit-file", "", "Also append each audit event to this file as a line of JSON")
+	fs.StringVar(&settings.auditOTLPURL, "audit-otlp-url", "", "Also export audit events as OTLP logs to this OTLP/HTTP endpoint, e.g. http://otel-collector:4318/v1/logs")
+	fs.StringVar(&settings.auditTLSCAFile, "audit-tls-ca-file", "", "CA that verifies the aggregator; enables TLS for audit delivery")
+	fs.StringVar(&settings.auditTLSCertFile, "audit-tls-cert-file", "", "Client certificate presented to the aggregator")
+	fs.StringVar(&settings.auditTLSKeyFile, "audit-tls-key-file", "", "Client key presented to the aggregator")
+	fs.Int64Var(&maxDecompressedSize, "max-decompressed-bytes", maxDecompressedSize, "Most bytes one decompressed read or stream may produce")
+	fs.DurationVar(&rpcTimeout, "rpc-timeout", rpcTimeout, "Longest a unary request may run; 0 disables")
+	fs.DurationVar(&maxStreamDuration, "max-stream-duration", maxStreamDuration, "Longest a stream may run; 0 disables")
+	fs.StringVar(&metricsIdentity, "metrics-identity", metricsIdentity, `Count requests per client certificate, labelled by "name" or "hash"; needs --tls-ca-file`)
+	fs.IntVar(&maxMetricsIdentities, "metrics-max-identities", maxMetricsIdentities, `Identities labelled before further clients share the "other" label`)
+	fs.BoolVar(&settings.metricsTLS, "metrics-tls", false, "Serve /metrics and /readyz over HTTPS with the agent's certificate")
+	fs.StringVar(&settings.metricsTokenFile, "metrics-token-file", "", "File holding a token that /metrics requests must send as a bearer token; /readyz stays open for probes")
+	fs.BoolVar(&settings.requireApproval, "require-approval", false, "Serve a caller only within an approved, unexpired PulsaarAccessRequest for this pod")
+	fs.StringSliceVar(&settings.shutdownIdentities, "shutdown-identities", settings.shutdownIdentities, "Callers that may stop the agent with Shutdown: the lifecycle controller's client certificate common name, or its ServiceAccount username when it sends an identity token")
+	fs.StringVar(&settings.privilege, "privilege", settings.privilege, `Privilege the agent must run with: "non-root", "dac-read-search" to read files regardless of their permissions, or "root"; it is not ready otherwise`)
+	fs.BoolVar(&settings.sandbox, "sandbox", settings.sandbox, "Once ready, restrict the agent with Landlock to reading its allowed roots and with seccomp from writing files or executing programs (Linux)")
+	fs.StringVar(&settings.logLevel, "log-level", settings.logLevel, `"info", "warn" (drops audit and startup lines from the log) or "error"`)
+
+	fs.VisitAll(func(f *pflag.Flag) {
+		if env, ok := settingEnv[f.Name]; ok {
+			f.Usage += " ($" + env + ")"
+		}
+	})
+}
+
+// loadSettings fills in the settings not given as flags, from their
+// environment variables and then the config file.
+func loadSettings(fs *pflag.FlagSet) error {
+	file, err := readConfigFile(fs)
+	if err != nil {
+		return err
+	}
+	for key := range file {
+		if _, ok := settingEnv[key]; !ok {
+			return fmt.Errorf("unknown setting %q in the config file", key)
+		}
+	}
+	var names []string
+	for name := range settingEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f.Changed {
+			continue
+		}
+		if v := os.Getenv(settingEnv[name]); v != "" {
+			if err := f.Value.Set(v); err != nil {
+				return fmt.Errorf("invalid %s %q: %v", settingEnv[name], v, err)
+			}
+		} else if v, ok := file[name]; ok {
+			if err := f.Value.Set(v); err != nil {
+				return fmt.Errorf("invalid %s %q in the config file: %v", name, v, err)
+			}
+		}
+	}
+	for i, root := range settings.allowedRoots {
+		settings.allowedRoots[i] = strings.TrimSpace(root)
+	}
+	for i, id := range settings.shutdownIdentities {
+		settings.shutdownIdentities[i] = strings.TrimSpace(id)
+	}
+	if err := checkSettings(); err != nil {
+		return err
+	}
+	return initLogLevel()
+}
+
+// checkSettings rejects values that parse but make no sense.
+func checkSettings() error {
+	switch {
+	case maxDecompressedSize <= 0:
+		return fmt.Errorf("invalid max-decompressed-bytes %d: must be a positive number of bytes", maxDecompressedSize)
+	case rpcTimeout < 0:
+		return fmt.Errorf("invalid rpc-timeout %s: must be a duration such as 30s, or 0 to disable", rpcTimeout)
+	case maxStreamDuration < 0:
+		return fmt.Errorf("invalid max-stream-duration %s: must be a duration such as 30m, or 0 to disable", maxStreamDuration)
+	case metricsIdentity != identityOff && metricsIdentity != identityName && metricsIdentity != identityHash:
+		return fmt.Errorf("invalid metrics-identity %q: must be %q or %q", metricsIdentity, identityName, identityHash)
+	case maxMetricsIdentities < 1:
+		return fmt.Errorf("invalid metrics-max-identities %d: must be a positive number", maxMetricsIdentities)
+	case settings.auditBufferSize < 1:
+		return fmt.Errorf("invalid audit-buffer-size %d: must be a positive number", settings.auditBufferSize)
+	case settings.auditSpoolMaxBytes < auditSpoolReserve:
+		return fmt.Errorf("invalid audit-spool-max-bytes %d: must be at least %d", settings.auditSpoolMaxBytes, auditSpoolReserve)
+	}
+	return nil
+}
+
+// readConfigFile returns the settings in the config file as flag values;
+// lists become comma-separated.
+func readConfigFile(fs *pflag.FlagSet) (map[string]string, error) {
+	path, _ := fs.GetString("config")
+	if path == "" {
+		path = os.Getenv(configFileEnv)
+	}
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	j, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	var raw map[string]any
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: expected a map of settings", path)
+	}
+	values := map[string]string{}
+	for key, v := range raw {
+		s, ok := settingString(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid config file %s: %s must be a string, number, boolean or list", path, key)
+		}
+		values[key] = s
+	}
+	return values, nil
+}
+
+func settingString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := settingString(item)
+			if !ok {
+				return "", false
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), true
+	}
+	return "", false
+}
+
+// Log levels for --log-level. Audit lines and lifecycle messages are info;
+// problems the agent works around, such as a lost aggregator, are warnings.
+const (
+	levelInfo = iota
+	levelWarn
+	levelError
+)
+
+var logLevel = levelInfo
+
+func initLogLevel() error {
+	switch settings.logLevel {
+	case "info":
+		logLevel = levelInfo
+	case "warn":
+		logLevel = levelWarn
+	case "error":
+		logLevel = levelError
+	default:
+		return fmt.Errorf(`invalid log level %q: must be "info", "warn" or "error"`, settings.logLevel)
+	}
+	return nil
+}
+
+func infof(format string, args ...any) {
+	if logLevel <= levelInfo {
+		log.Printf(format, args...)
+	}
+}
+
+func warnf(format string, args ...any) {
+	if logLevel <= levelWarn {
+		log.Printf(format, args...)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pulsaar-agent",
+		Short: "Serve read-only, audited file access to a pod over gRPC",
+		Long: "pulsaar-agent serves the Pulsaar API over TLS. Each setting can be a flag, the\n" +
+			"environment variable shown with it, or a key in the --config file, in that\n" +
+			"order of precedence.",
+		Version:       fmt.Sprintf("%s (commit %s, built %s)", version, commit, date),
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadSettings(cmd.Flags()); err != nil {
+				return err
+			}
+			return run()
+		},
+	}
+	addSettingFlags(cmd.Flags())
+	return cmd
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// setSetting sets *p to v for the duration of the test.
+func setSetting[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	orig := *p
+	*p = v
+	t.Cleanup(func() { *p = orig })
+}
+
+// testFlags returns the agent's flags parsed from args, restoring every
+// setting they bind once the test ends.
+func testFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	origSettings, origTimeout, origStream := settings, rpcTimeout, maxStreamDuration
+	origDecompressed, origIdentity, origMax := maxDecompressedSize, metricsIdentity, maxMetricsIdentities
+	t.Cleanup(func() {
+		settings, rpcTimeout, maxStreamDuration = origSettings, origTimeout, origStream
+		maxDecompressedSize, metricsIdentity, maxMetricsIdentities = origDecompressed, origIdentity, origMax
+		logLevel = levelInfo
+	})
+	fs := pflag.NewFlagSet("pulsaar-agent", pflag.ContinueOnError)
+	addSettingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func TestLoadSettingsPrecedence(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "agent.yaml")
+	data := "rpc-timeout: 1m\nmax-stream-duration: 2h\naudit-buffer-size: 64\nallowed-roots: [/var/log, /tmp]\nlog-level: warn\n"
+	if err := os.WriteFile(config, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PULSAAR_MAX_STREAM_DURATION", "90m")
+	t.Setenv("PULSAAR_AUDIT_BUFFER_SIZE", "32")
+
+	fs := testFlags(t, "--config", config, "--audit-buffer-size=16")
+	if err := loadSettings(fs); err != nil {
+		t.Fatal(err)
+	}
+	if rpcTimeout != time.Minute {
+		t.Errorf("expected rpc-timeout from the config file, got %s", rpcTimeout)
+	}
+	if maxStreamDuration != 90*time.Minute {
+		t.Errorf("expected the environment to override the config file, got %s", maxStreamDuration)
+	}
+	if settings.auditBufferSize != 16 {
+		t.Errorf("expected the flag to override the environment, got %d", settings.auditBufferSize)
+	}
+	if len(settings.allowedRoots) != 2 || settings.allowedRoots[1] != "/tmp" {
+		t.Errorf("expected a list of roots from the config file, got %v", settings.allowedRoots)
+	}
+	if logLevel != levelWarn {
+		t.Errorf("expected log level warn, got %d", logLevel)
+	}
+	if settings.listenAddr != ":50051" {
+		t.Errorf("expected the default listen address, got %q", settings.listenAddr)
+	}
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	dir := t.TempDir()
+	unknown := filepath.Join(dir, "unknown.yaml")
+	if err := os.WriteFile(unknown, []byte("rpc-timout: 1m\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		env  string
+	}{
+		{"unknown key", []string{"--config", unknown}, ""},
+		{"missing file", []string{"--config", filepath.Join(dir, "missing.yaml")}, ""},
+		{"bad env duration", nil, "soon"},
+		{"negative duration", []string{"--rpc-timeout=-1s"}, ""},
+		{"bad identity mode", []string{"--metrics-identity=email"}, ""},
+		{"bad log level", []string{"--log-level=debug"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PULSAAR_RPC_TIMEOUT", tt.env)
+			if err := loadSettings(testFlags(t, tt.args...)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
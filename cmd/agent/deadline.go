@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// rpcTimeout bounds each unary RPC (PULSAAR_RPC_TIMEOUT) and
+// maxStreamDuration each streaming RPC (PULSAAR_MAX_STREAM_DURATION). Zero
+// disables either.
+var (
+	rpcTimeout        = 30 * time.Second
+	maxStreamDuration = time.Hour
+)
+
+// Handlers stop when their context ends, but a read blocked in the kernel,
+// as on a hung NFS mount, does not return. Once abandonGrace has passed
+// after the deadline, the client is answered and the handler is left
+// behind, still holding its goroutine and usually a file descriptor. At
+// maxStuckRPCs such handlers the agent refuses new requests until some
+// return.
+const (
+	abandonGrace = time.Second
+	maxStuckRPCs = 64
+)
+
+// stuckRPCs counts abandoned handlers that have not returned yet.
+var stuckRPCs atomic.Int64
+
+// errAbandoned is returned by runDetached when it gives up on a handler.
+var errAbandoned = errors.New("handler abandoned")
+
+// checkStuck refuses new requests while too many handlers are stuck. Health
+// and Shutdown are always served, so probes and the lifecycle controller
+// still reach the agent.
+func checkStuck(method string) error {
+	if method == api.PulsaarAgent_Health_FullMethodName || method == api.PulsaarAgent_Shutdown_FullMethodName {
+		return nil
+	}
+	if n := stuckRPCs.Load(); n >= maxStuckRPCs {
+		return status.Errorf(codes.Unavailable, "%d earlier requests are stuck on an unresponsive filesystem; retry later", n)
+	}
+	return nil
+}
+
+// runDetached runs fn and returns its error. If ctx ends first, fn is given
+// abandonGrace to return, and then abandoned as soon as abandon agrees,
+// returning errAbandoned.
+func runDetached(ctx context.Context, fn func() error, abandon func() bool) error {
+	const (
+		running = iota
+		returned
+		abandoned
+	)
+	var state atomic.Int32
+	done := make(chan error, 1)
+	go func() {
+		err := fn()
+		if !state.CompareAndSwap(running, returned) {
+			stuckRPCs.Add(-1)
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	timer := time.NewTimer(abandonGrace)
+	defer timer.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-timer.C:
+		}
+		if abandon() {
+			if state.CompareAndSwap(running, abandoned) {
+				stuckRPCs.Add(1)
+				return errAbandoned
+			}
+			return <-done
+		}
+		timer.Reset(100 * time.Millisecond)
+	}
+}
+
+// deadlineError describes a request cut off by its deadline or abandoned
+// after the client went away.
+func deadlineError(ctx context.Context, method string, limit time.Duration, stream bool) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if stream {
+		return status.Errorf(codes.DeadlineExceeded, "%s exceeded the agent's maximum stream duration of %v", method, limit)
+	}
+	return status.Errorf(codes.DeadlineExceeded, "%s did not finish within the agent's timeout of %v; the filesystem may be unresponsive", method, limit)
+}
+
+func deadlineUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := checkStuck(info.FullMethod); err != nil {
+		return nil, err
+	}
+	if rpcTimeout <= 0 {
+		return handler(ctx, req)
+	}
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+	var resp any
+	err := runDetached(ctx, func() error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	}, func() bool { return true })
+	if errors.Is(err, errAbandoned) {
+		warnf("Abandoned %s after its deadline; %d requests are stuck", info.FullMethod, stuckRPCs.Load())
+		return nil, deadlineError(ctx, info.FullMethod, rpcTimeout, false)
+	}
+	return resp, err
+}
+
+func deadlineStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := checkStuck(info.FullMethod); err != nil {
+		return err
+	}
+	if maxStreamDuration <= 0 {
+		return handler(srv, ss)
+	}
+	ctx, cancel := context.WithTimeout(ss.Context(), maxStreamDuration)
+	defer cancel()
+	ds := &deadlineStream{ServerStream: ss, ctx: ctx}
+	err := runDetached(ctx, func() error { return handler(srv, ds) }, ds.abandon)
+	if errors.Is(err, errAbandoned) {
+		warnf("Abandoned %s after its deadline; %d requests are stuck", info.FullMethod, stuckRPCs.Load())
+		return deadlineError(ctx, info.FullMethod, maxStreamDuration, true)
+	}
+	// Following handlers such as TailFile end quietly when their context
+	// does; tell the client the stream was cut off.
+	if err == nil && ctx.Err() != nil && ss.Context().Err() == nil {
+		return deadlineError(ctx, info.FullMethod, maxStreamDuration, true)
+	}
+	return err
+}
+
+// deadlineStream carries the stream's deadline, and stops an abandoned
+// handler from using the stream once the interceptor has returned.
+type deadlineStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	mu        sync.Mutex
+	abandoned bool
+}
+
+func (s *deadlineStream) Context() context.Context {
+	return s.ctx
+}
+
+// abandon closes the stream to the handler, unless it is sending or
+// receiving at that moment, for example while a slow client is reading.
+func (s *deadlineStream) abandon() bool {
+	if !s.mu.TryLock() {
+		return false
+	}
+	s.abandoned = true
+	s.mu.Unlock()
+	return true
+}
+
+func (s *deadlineStream) guard(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.abandoned {
+		return fmt.Errorf("stream abandoned: %w", s.ctx.Err())
+	}
+	return fn()
+}
+
+func (s *deadlineStream) SendMsg(m any) error {
+	return s.guard(func() error { return s.ServerStream.SendMsg(m) })
+}
+
+func (s *deadlineStream) RecvMsg(m any) error {
+	return s.guard(func() error { return s.ServerStream.RecvMsg(m) })
+}
+
+func (s *deadlineStream) SetHeader(md metadata.MD) error {
+	return s.guard(func() error { return s.ServerStream.SetHeader(md) })
+}
+
+func (s *deadlineStream) SendHeader(md metadata.MD) error {
+	return s.guard(func() error { return s.ServerStream.SendHeader(md) })
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func setDeadlines(t *testing.T, unary, stream time.Duration) {
+	origUnary, origStream := rpcTimeout, maxStreamDuration
+	rpcTimeout, maxStreamDuration = unary, stream
+	t.Cleanup(func() { rpcTimeout, maxStreamDuration = origUnary, origStream })
+}
+
+func waitStuck(t *testing.T, want int64) {
+	t.Helper()
+	for i := 0; i < 100 && stuckRPCs.Load() != want; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := stuckRPCs.Load(); n != want {
+		t.Fatalf("expected %d stuck requests, got %d", want, n)
+	}
+}
+
+func TestDeadlineUnary(t *testing.T) {
+	setDeadlines(t, 50*time.Millisecond, time.Hour)
+	info := &grpc.UnaryServerInfo{FullMethod: api.PulsaarAgent_ReadFile_FullMethodName}
+
+	// A handler that honours its context returns its own error.
+	_, err := deadlineUnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	})
+	if status.Code(err) != codes.DeadlineExceeded || stuckRPCs.Load() != 0 {
+		t.Errorf("expected DeadlineExceeded without a stuck request, got %v", err)
+	}
+
+	// A handler blocked as on a hung mount is abandoned.
+	release := make(chan struct{})
+	start := time.Now()
+	_, err = deadlineUnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		<-release
+		return &api.ReadResponse{}, nil
+	})
+	if status.Code(err) != codes.DeadlineExceeded || time.Since(start) > 5*time.Second {
+		t.Errorf("expected the stuck handler to be abandoned, got %v after %v", err, time.Since(start))
+	}
+	waitStuck(t, 1)
+	close(release)
+	waitStuck(t, 0)
+}
+
+func TestStuckLimit(t *testing.T) {
+	stuckRPCs.Store(maxStuckRPCs)
+	t.Cleanup(func() { stuckRPCs.Store(0) })
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return &api.HealthResponse{}, nil
+	}
+	_, err := deadlineUnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: api.PulsaarAgent_Stat_FullMethodName}, handler)
+	if status.Code(err) != codes.Unavailable || called {
+		t.Errorf("expected Unavailable without calling the handler, got %v", err)
+	}
+	if _, err := deadlineUnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: api.PulsaarAgent_Health_FullMethodName}, handler); err != nil || !called {
+		t.Errorf("expected Health to be served, got %v", err)
+	}
+	resp, _ := (&server{}).Health(context.Background(), nil)
+	if resp.Ready {
+		t.Error("expected Health to report not ready")
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent int
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+func (s *fakeServerStream) SendMsg(m any) error      { s.sent++; return nil }
+
+func TestDeadlineStream(t *testing.T) {
+	setDeadlines(t, time.Minute, 50*time.Millisecond)
+	info := &grpc.StreamServerInfo{FullMethod: api.PulsaarAgent_TailFile_FullMethodName}
+
+	// A following handler that ends quietly is reported as cut off.
+	ss := &fakeServerStream{ctx: context.Background()}
+	err := deadlineStreamInterceptor(nil, ss, info, func(srv any, stream grpc.ServerStream) error {
+		<-stream.Context().Done()
+		return nil
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+
+	// An abandoned handler cannot use the stream afterwards.
+	release := make(chan struct{})
+	sendErr := make(chan error, 1)
+	ss = &fakeServerStream{ctx: context.Background()}
+	err = deadlineStreamInterceptor(nil, ss, info, func(srv any, stream grpc.ServerStream) error {
+		<-release
+		err := stream.SendMsg(&api.ReadResponse{})
+		sendErr <- err
+		return err
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	waitStuck(t, 1)
+	close(release)
+	if err := <-sendErr; err == nil || ss.sent != 0 {
+		t.Errorf("expected the late send to fail, got %v with %d sent", err, ss.sent)
+	}
+	waitStuck(t, 0)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxDecompressedSize caps the output of one decompressed read or stream
+// (--max-decompressed-bytes), so a small compressed file cannot make
+// the agent produce unbounded data.
+var maxDecompressedSize int64 = 1 << 30
+
+// errDecompressedTooLarge is returned once output passes maxDecompressedSize.
+var errDecompressedTooLarge = errors.New("decompressed size exceeds the agent's limit")
+
+// decompressionCodecs are the formats decompressReader recognises.
+var decompressionCodecs = []string{"gzip", "zstd", "bzip2"}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+)
+
+// decompressReader returns a reader of f's decompressed content if f is
+// gzip, zstd or bzip2 compressed, judged by its magic bytes, and of f as it
+// is otherwise. Closing it does not close f.
+func decompressReader(f io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+	var r io.Reader
+	closer := func() {}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		r, closer = gz, func() { _ = gz.Close() }
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		r, closer = zr, zr.Close
+	case bytes.HasPrefix(magic, bzip2Magic):
+		r = bzip2.NewReader(br)
+	default:
+		return io.NopCloser(br), nil
+	}
+	return &cappedReader{r: r, left: maxDecompressedSize, close: closer}, nil
+}
+
+// readDecompressed returns up to length bytes of f's decompressed content
+// starting at offset, and whether the content ended within them.
+func readDecompressed(f io.Reader, offset, length int64) ([]byte, bool, error) {
+	r, err := decompressReader(f)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = r.Close() }()
+	if _, err := io.CopyN(io.Discard, r, offset); err != nil {
+		if err == io.EOF {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	data := make([]byte, length)
+	n, err := io.ReadFull(r, data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return data[:n], true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, false, nil
+}
+
+// decompressStatus maps a decompression failure to a gRPC status.
+func decompressStatus(path string, err error) error {
+	if errors.Is(err, errDecompressedTooLarge) {
+		return status.Errorf(codes.FailedPrecondition, "Decompressed size of '%s' exceeds the agent's limit of %d bytes (PULSAAR_MAX_DECOMPRESSED_BYTES)", path, maxDecompressedSize)
+	}
+	return status.Errorf(codes.Internal, "Unable to decompress file '%s': %v", path, err)
+}
+
+// cappedReader fails with errDecompressedTooLarge rather than return more
+// than left bytes.
+type cappedReader struct {
+	r     io.Reader
+	left  int64
+	close func()
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.left <= 0 {
+		// Output that ends exactly at the cap is still complete.
+		if n, err := c.r.Read(make([]byte, 1)); n == 0 && err == io.EOF {
+			return 0, io.EOF
+		}
+		return 0, errDecompressedTooLarge
+	}
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	// Decoders may return 0, nil between frames; callers treat that as EOF.
+	n, err := c.r.Read(p)
+	for n == 0 && err == nil {
+		n, err = c.r.Read(p)
+	}
+	c.left -= int64(n)
+	return n, err
+}
+
+func (c *cappedReader) Close() error {
+	c.close()
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// bzip2 of "hello from bzip2\n"; the standard library only decompresses.
+var bzip2Hello = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x55, 0xe9, 0xaf, 0xe5, 0x00, 0x00,
+	0x03, 0xd9, 0x80, 0x00, 0x10, 0x40, 0x00, 0x10, 0x00, 0x13, 0x66, 0xd0, 0x10, 0x20, 0x00, 0x22,
+	0x9a, 0x32, 0x69, 0xe9, 0x1f, 0xa8, 0x40, 0x00, 0x0d, 0x2a, 0xf4, 0x26, 0xe0, 0xbf, 0x2c, 0x01,
+	0x62, 0xee, 0x48, 0xa7, 0x0a, 0x12, 0x0a, 0xbd, 0x35, 0xfc, 0xa0,
+}
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func zstdBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	w, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = w.Close() }()
+	return w.EncodeAll([]byte(s), nil)
+}
+
+func TestReadFileDecompress(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"app.log.gz":  gzipBytes(t, "hello from gzip\n"),
+		"app.log.zst": zstdBytes(t, "hello from zstd\n"),
+		"app.log.bz2": bzip2Hello,
+		"app.log":     []byte("hello plain\n"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &server{}
+	roots := []string{dir}
+
+	for name, want := range map[string]string{
+		"app.log.gz":  "hello from gzip\n",
+		"app.log.zst": "hello from zstd\n",
+		"app.log.bz2": "hello from bzip2\n",
+		"app.log":     "hello plain\n",
+	} {
+		resp, err := s.ReadFile(context.Background(), &api.ReadRequest{Path: filepath.Join(dir, name), Decompress: true, AllowedRoots: roots})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(resp.Data) != want || !resp.Eof {
+			t.Errorf("%s: got %q (eof %t), want %q", name, resp.Data, resp.Eof, want)
+		}
+	}
+
+	resp, err := s.ReadFile(context.Background(), &api.ReadRequest{Path: filepath.Join(dir, "app.log.gz"), Offset: 6, Length: 4, Decompress: true, AllowedRoots: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Data) != "from" || resp.Eof {
+		t.Errorf("expected offset and length in decompressed bytes, got %q (eof %t)", resp.Data, resp.Eof)
+	}
+
+	resp, err = s.ReadFile(context.Background(), &api.ReadRequest{Path: filepath.Join(dir, "app.log.gz"), AllowedRoots: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp.Data, files["app.log.gz"]) {
+		t.Error("expected compressed bytes without Decompress")
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "big.gz")
+	if err := os.WriteFile(p, gzipBytes(t, strings.Repeat("a", 100)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	original := maxDecompressedSize
+	t.Cleanup(func() { maxDecompressedSize = original })
+	s := &server{}
+
+	maxDecompressedSize = 100
+	if resp, err := s.ReadFile(context.Background(), &api.ReadRequest{Path: p, Decompress: true, AllowedRoots: []string{dir}}); err != nil || len(resp.Data) != 100 {
+		t.Errorf("expected output of exactly the limit to be allowed, got %v", err)
+	}
+
+	maxDecompressedSize = 99
+	_, err := s.ReadFile(context.Background(), &api.ReadRequest{Path: p, Decompress: true, AllowedRoots: []string{dir}})
+	if status.Code(err) != codes.FailedPrecondition || !strings.Contains(err.Error(), "PULSAAR_MAX_DECOMPRESSED_BYTES") {
+		t.Errorf("expected FailedPrecondition naming the limit, got %v", err)
+	}
+
+	stream, err := tailClient(t).StreamFile(context.Background(), &api.StreamRequest{Path: p, ChunkSize: 10, Decompress: true, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected the stream to fail at the limit, got %v", err)
+	}
+}
+
+func TestStreamFileDecompress(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log.zst")
+	content := strings.Repeat("streamed line\n", 1000)
+	if err := os.WriteFile(p, zstdBytes(t, content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := tailClient(t).StreamFile(context.Background(), &api.StreamRequest{Path: p, ChunkSize: 4096, Decompress: true, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, stream); got != content {
+		t.Errorf("expected %d decompressed bytes, got %d", len(content), len(got))
+	}
+}
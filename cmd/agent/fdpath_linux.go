@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// fdPath returns the real path of an open file from /proc.
+func fdPath(f *os.File) (string, bool) {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return "", false
+	}
+	var p string
+	var rerr error
+	if err := conn.Control(func(fd uintptr) {
+		p, rerr = os.Readlink("/proc/self/fd/" + strconv.Itoa(int(fd)))
+	}); err != nil || rerr != nil {
+		return "", false
+	}
+	return p, true
+}
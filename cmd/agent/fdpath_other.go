@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// fdPath is only implemented on Linux, where the agent runs in pods.
+func fdPath(f *os.File) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,81 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestSpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	fifo := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(fifo, 0o644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+	l, err := net.Listen("unix", filepath.Join(dir, "sock"))
+	if err != nil {
+		t.Skipf("unix socket: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte("ERROR boom\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("app.log", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{}
+	roots := []string{dir}
+
+	resp, err := s.ListDirectory(context.Background(), &api.ListRequest{Path: dir, AllowedRoots: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]api.FileType)
+	for _, e := range resp.Entries {
+		types[e.Name] = e.FileType
+	}
+	want := map[string]api.FileType{
+		"app.log": api.FileType_FILE_TYPE_REGULAR,
+		"fifo":    api.FileType_FILE_TYPE_NAMED_PIPE,
+		"sock":    api.FileType_FILE_TYPE_SOCKET,
+		"link":    api.FileType_FILE_TYPE_SYMLINK,
+	}
+	for name, ft := range want {
+		if types[name] != ft {
+			t.Errorf("%s: expected %v, got %v", name, ft, types[name])
+		}
+	}
+
+	stat, err := s.Stat(context.Background(), &api.StatRequest{Path: "/dev/null", AllowedRoots: []string{"/dev"}})
+	if err != nil || stat.Info.FileType != api.FileType_FILE_TYPE_CHAR_DEVICE || !stat.Info.IsSpecial() {
+		t.Errorf("expected /dev/null to be a character device, got %v, %v", stat.GetInfo(), err)
+	}
+
+	// Nothing opens the pipe, which would block without a writer.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	preview, err := s.Preview(ctx, &api.PreviewRequest{Path: fifo, AllowedRoots: roots})
+	if err != nil || preview.Info.FileType != api.FileType_FILE_TYPE_NAMED_PIPE || len(preview.Head) != 0 {
+		t.Errorf("expected metadata only for the pipe, got %v, %v", preview, err)
+	}
+	for _, p := range []string{fifo, dir} {
+		search, err := s.Search(ctx, &api.SearchRequest{Path: p, Pattern: "ERROR", AllowedRoots: roots})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p == dir && (search.FilesSearched != 1 || len(search.Matches) != 1) {
+			t.Errorf("expected only app.log searched, got %d files, %v", search.FilesSearched, search.Matches)
+		}
+		if p == fifo && search.FilesSearched != 0 {
+			t.Errorf("expected the pipe to be skipped, got %d files", search.FilesSearched)
+		}
+	}
+}
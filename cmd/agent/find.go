@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/listing"
+)
+
+// Find limits keep a single request from walking a whole volume.
+const (
+	defaultFindResults = 1000
+	maxFindResults     = 10000
+	maxFindEntries     = 100000
+)
+
+var errFindLimit = errors.New("find limit reached")
+
+// finder walks a directory tree with the agent's file helpers, so host mode
+// applies, and records the entries that pass the filters. Symlinks to
+// directories are reported but not followed.
+type finder struct {
+	q          *listing.Query
+	fileType   api.FileType
+	roots      []string
+	maxResults int
+	scanned    int64
+	matches    []*api.FindMatch
+	truncated  bool
+}
+
+func (f *finder) walk(ctx context.Context, dir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entries, err := readDir(dir, f.roots)
+	if err != nil {
+		return nil // unreadable directories are skipped
+	}
+	for _, entry := range entries {
+		if f.scanned >= maxFindEntries {
+			f.truncated = true
+			return errFindLimit
+		}
+		f.scanned++
+		if err := f.check(dir, entry); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if err := f.walk(ctx, path.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check records entry if it passes the filters.
+func (f *finder) check(dir string, entry os.DirEntry) error {
+	e, ok := listEntry(f.q, entry)
+	if !ok || !f.q.Match(e) {
+		return nil
+	}
+	infos := entryInfos(dir, []os.DirEntry{entry})
+	if len(infos) == 0 {
+		return nil // vanished
+	}
+	if f.fileType != api.FileType_FILE_TYPE_UNSPECIFIED && infos[0].FileType != f.fileType {
+		return nil
+	}
+	if len(f.matches) >= f.maxResults {
+		f.truncated = true
+		return errFindLimit
+	}
+	f.matches = append(f.matches, &api.FindMatch{Path: path.Join(dir, entry.Name()), Info: infos[0]})
+	return nil
+}
+
+// Find walks a directory tree and returns the entries whose name, size,
+// modification time and type pass the request's filters, so a client can
+// locate files without listing every directory itself.
+func (s *server) Find(ctx context.Context, req *api.FindRequest) (*api.FindResponse, error) {
+	if !getLimiterForIP(ctx).Allow() {
+		return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
+	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
+	if !isPathAllowed(req.Path, allowedRoots) {
+		return nil, status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
+	}
+	q, err := listing.NewQuery(&api.ListRequest{NameGlob: req.NameGlob, MinSize: req.MinSize, ModifiedSince: req.ModifiedSince})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid find request: %v", err)
+	}
+	maxResults := int(req.MaxResults)
+	if maxResults <= 0 {
+		maxResults = defaultFindResults
+	}
+	if maxResults > maxFindResults {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested max results (%d) exceeds the maximum of %d", maxResults, maxFindResults)
+	}
+
+	info, err := statFile(req.Path)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+	if !info.IsDir() {
+		return nil, status.Errorf(codes.InvalidArgument, "'%s' is not a directory", req.Path)
+	}
+	f := &finder{q: q, fileType: req.FileType, roots: allowedRoots, maxResults: maxResults}
+	if err := f.walk(ctx, path.Clean(req.Path)); err != nil && !errors.Is(err, errFindLimit) {
+		return nil, status.FromContextError(err).Err()
+	}
+	return &api.FindResponse{Matches: f.matches, Truncated: f.truncated, EntriesScanned: f.scanned}, nil
+}
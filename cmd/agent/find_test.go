@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestFind(t *testing.T) {
+	dir := writeSearchFixture(t)
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"old/app.log.1", "old/long.log", "old/empty.log"} {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &server{}
+	ctx := context.Background()
+	find := func(req *api.FindRequest) []string {
+		t.Helper()
+		req.Path, req.AllowedRoots = dir, []string{dir}
+		resp, err := s.Find(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, m := range resp.Matches {
+			rel, _ := filepath.Rel(dir, m.Path)
+			paths = append(paths, rel)
+		}
+		return paths
+	}
+
+	if got := find(&api.FindRequest{NameGlob: "*.log", ModifiedSince: timestamppb.New(time.Now().Add(-time.Hour))}); len(got) != 1 || got[0] != "app.log" {
+		t.Errorf("expected only the recent log, got %v", got)
+	}
+	if got := find(&api.FindRequest{MinSize: 1000, FileType: api.FileType_FILE_TYPE_REGULAR}); len(got) != 1 || got[0] != "old/long.log" {
+		t.Errorf("expected only the large file, got %v", got)
+	}
+	if got := find(&api.FindRequest{FileType: api.FileType_FILE_TYPE_DIRECTORY}); len(got) != 2 || got[0] != "old" || got[1] != "other" {
+		t.Errorf("expected the two directories, got %v", got)
+	}
+	if got := find(&api.FindRequest{FileType: api.FileType_FILE_TYPE_SYMLINK}); len(got) != 1 || got[0] != "etc-link" {
+		t.Errorf("expected the symlink, not the files it points at, got %v", got)
+	}
+
+	resp, err := s.Find(ctx, &api.FindRequest{Path: dir, MaxResults: 2, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Matches) != 2 || !resp.Truncated {
+		t.Errorf("expected 2 matches and truncation, got %d (truncated=%t)", len(resp.Matches), resp.Truncated)
+	}
+	if _, err := s.Find(ctx, &api.FindRequest{Path: filepath.Join(dir, "app.log"), AllowedRoots: []string{dir}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a file, got %v", err)
+	}
+	if _, err := s.Find(ctx, &api.FindRequest{Path: "/etc", AllowedRoots: []string{dir}}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied outside the allowed roots, got %v", err)
+	}
+}
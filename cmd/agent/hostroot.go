@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// hostRoot is set in host mode (--host-root), where the agent runs in
+// a DaemonSet with node directories mounted under that path. Request paths
+// are node paths such as /var/log/syslog and are resolved inside hostRoot,
+// so symlinks on the node cannot lead outside the mount.
+var hostRoot *os.Root
+
+func initHostRoot() {
+	dir := settings.hostRoot
+	if dir == "" {
+		return
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		log.Fatalf("failed to open host root %s: %v", dir, err)
+	}
+	hostRoot = root
+	infof("Host mode: serving node paths from %s", dir)
+}
+
+// targetRoot is set when the agent is an ephemeral container targeting
+// another container (--target-container). It then shares that
+// container's process namespace, where the container's main process is PID
+// 1, and request paths resolve in its filesystem rather than the agent
+// image's.
+var targetRoot *os.Root
+
+// targetProcRoot is the targeted container's filesystem as seen through its
+// main process.
+var targetProcRoot = "/proc/1/root"
+
+func initTargetRoot() {
+	target := settings.targetContainer
+	if target == "" || hostRoot != nil {
+		return
+	}
+	root, err := os.OpenRoot(targetProcRoot)
+	if err != nil {
+		log.Fatalf("failed to open the filesystem of container %s at %s; the agent must run as the container's user or with CAP_SYS_PTRACE: %v", target, targetProcRoot, err)
+	}
+	targetRoot = root
+	infof("Serving the filesystem of container %s from %s", target, targetProcRoot)
+}
+
+// fileRoot is the directory request paths resolve in, or nil for the
+// agent's own filesystem.
+func fileRoot() *os.Root {
+	if hostRoot != nil {
+		return hostRoot
+	}
+	return targetRoot
+}
+
+// effectiveRoots returns the roots a request is checked against: its own,
+// or the configured roots when it has none. In host mode the configured
+// roots always apply, so clients cannot widen node access.
+func effectiveRoots(requested []string) []string {
+	if len(requested) == 0 || hostRoot != nil {
+		return configuredAllowedRoots
+	}
+	return requested
+}
+
+// hostRelative turns an absolute request path into a path relative to
+// fileRoot.
+func hostRelative(p string) string {
+	rel := strings.TrimPrefix(filepath.Clean("/"+p), "/")
+	if rel == "" {
+		return "."
+	}
+	return rel
+}
+
+func statFile(p string) (os.FileInfo, error) {
+	root := fileRoot()
+	if root == nil {
+		return os.Stat(p)
+	}
+	return root.Stat(hostRelative(p))
+}
+
+// readLink matches os.Readlink.
+func readLink(p string) (string, error) {
+	root := fileRoot()
+	if root == nil {
+		return os.Readlink(p)
+	}
+	return root.Readlink(hostRelative(p))
+}
+
+// globFiles matches filepath.Glob.
+func globFiles(pattern string) ([]string, error) {
+	root := fileRoot()
+	if root == nil {
+		return filepath.Glob(pattern)
+	}
+	matches, err := fs.Glob(root.FS(), filepath.ToSlash(hostRelative(pattern)))
+	for i, m := range matches {
+		matches[i] = "/" + m
+	}
+	return matches, err
+}
+
+// readDir matches os.ReadDir, including sorting by name, opening p with
+// openDir.
+func readDir(p string, allowedRoots []string) ([]os.DirEntry, error) {
+	f, err := openDir(p, allowedRoots)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	entries, err := f.ReadDir(-1)
+	slices.SortFunc(entries, func(a, b os.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, err
+}
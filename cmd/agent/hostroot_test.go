@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostRoot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "var", "log"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"syslog", "kern.log"} {
+		if err := os.WriteFile(filepath.Join(dir, "var", "log", name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("/etc/hostname", filepath.Join(dir, "var", "log", "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "etc", "shadow"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../etc/shadow", filepath.Join(dir, "var", "log", "shadow")); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostRoot = root
+	t.Cleanup(func() {
+		hostRoot = nil
+		_ = root.Close()
+	})
+
+	entries, err := readDir("/var/log", []string{"/var/log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[0].Name() != "escape" || entries[1].Name() != "kern.log" {
+		t.Errorf("expected sorted entries, got %v", entries)
+	}
+
+	f, err := openFile("/var/log/syslog", []string{"/var/log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	_ = f.Close()
+	if string(data) != "syslog" {
+		t.Errorf("expected the node file, got %q", data)
+	}
+
+	if info, err := statFile("/"); err != nil || !info.IsDir() {
+		t.Errorf("expected / to be the host root directory: %v", err)
+	}
+	if _, err := openFile("/var/log/escape", []string{"/"}); err == nil {
+		t.Error("expected a symlink out of the host root to be refused")
+	}
+	if _, err := openFile("/var/log/../../../etc/hostname", []string{"/"}); err == nil {
+		t.Error("expected .. not to leave the host root")
+	}
+	// A symlink inside the host root still cannot leave the allowed roots.
+	if _, err := openFile("/var/log/shadow", []string{"/var/log"}); !errors.Is(err, errOutsideRoots) {
+		t.Errorf("expected the symlink out of /var/log to be refused, got %v", err)
+	}
+}
+
+func TestEffectiveRootsInHostMode(t *testing.T) {
+	original := configuredAllowedRoots
+	configuredAllowedRoots = []string{"/var/log"}
+	t.Cleanup(func() { configuredAllowedRoots = original })
+
+	if roots := effectiveRoots([]string{"/"}); len(roots) != 1 || roots[0] != "/" {
+		t.Errorf("expected requested roots outside host mode, got %v", roots)
+	}
+
+	root, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostRoot = root
+	t.Cleanup(func() {
+		hostRoot = nil
+		_ = root.Close()
+	})
+	if roots := effectiveRoots([]string{"/"}); len(roots) != 1 || roots[0] != "/var/log" {
+		t.Errorf("expected configured roots in host mode, got %v", roots)
+	}
+}
+
+func TestTargetRoot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "app", "config"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app", "config", "settings.yaml"), []byte("debug: true"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	original := targetProcRoot
+	targetProcRoot = dir
+	setSetting(t, &settings.targetContainer, "app")
+	t.Cleanup(func() {
+		targetProcRoot = original
+		if targetRoot != nil {
+			_ = targetRoot.Close()
+			targetRoot = nil
+		}
+	})
+
+	initTargetRoot()
+	f, err := openFile("/app/config/settings.yaml", []string{"/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	_ = f.Close()
+	if string(data) != "debug: true" {
+		t.Errorf("expected the target container's file, got %q", data)
+	}
+	entries, err := readDir("/app", []string{"/app"})
+	if err != nil || len(entries) != 1 || entries[0].Name() != "config" {
+		t.Errorf("expected the target container's directory, got %v, %v", entries, err)
+	}
+	// Unlike host mode, clients may still narrow or choose roots.
+	if roots := effectiveRoots([]string{"/app"}); len(roots) != 1 || roots[0] != "/app" {
+		t.Errorf("expected requested roots to apply, got %v", roots)
+	}
+}
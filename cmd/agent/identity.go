@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// tokenAudience is the audience a ServiceAccount token must be issued for
+// to identify its caller to the agent. The API server rejects such tokens,
+// so handing one to an agent does not hand over the caller's access.
+const tokenAudience = "pulsaar"
+
+// tokenCacheTTL is how long a reviewed token's username is reused, so a
+// caller's requests cost one TokenReview rather than one each.
+const tokenCacheTTL = 5 * time.Minute
+
+type reviewedToken struct {
+	username string
+	expires  time.Time
+}
+
+var (
+	tokensMu sync.Mutex
+	tokens   = map[[sha256.Size]byte]reviewedToken{}
+)
+
+// reviewToken returns the username the API server authenticates token as,
+// or "" when it does not. It is a variable so tests can stand in for the
+// API server.
+var reviewToken = func(ctx context.Context, token string) (string, error) {
+	clientset, err := inClusterClient()
+	if clientset == nil {
+		return "", err
+	}
+	return tokenReview(ctx, clientset, token)
+}
+
+func tokenReview(ctx context.Context, clientset kubernetes.Interface, token string) (string, error) {
+	review, err := clientset.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: []string{tokenAudience}},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+	if !review.Status.Authenticated {
+		return "", nil
+	}
+	return review.Status.User.Username, nil
+}
+
+// callerIdentity names who sent a request: the client certificate's
+// subject under mTLS, otherwise the ServiceAccount whose bearer token came
+// with it. It is "" for callers the agent cannot authenticate; their
+// claims are never recorded.
+func callerIdentity(ctx context.Context) string {
+	if id := peerIdentity(ctx); id != "" {
+		return id
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok && token != "" {
+			return tokenIdentity(ctx, token)
+		}
+	}
+	return ""
+}
+
+func tokenIdentity(ctx context.Context, token string) string {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+	tokensMu.Lock()
+	cached, ok := tokens[key]
+	tokensMu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.username
+	}
+
+	username, err := reviewToken(ctx, token)
+	if err != nil {
+		// Not cached, so the review is retried once the agent may create
+		// TokenReviews.
+		warnf("Failed to review the caller's token: %v", err)
+		return ""
+	}
+	tokensMu.Lock()
+	for k, t := range tokens {
+		if now.After(t.expires) {
+			delete(tokens, k)
+		}
+	}
+	tokens[key] = reviewedToken{username: username, expires: now.Add(tokenCacheTTL)}
+	tokensMu.Unlock()
+	return username
+}
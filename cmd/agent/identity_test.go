@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestPeerIdentitySubject(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://cluster.local/ns/ops/sa/backup")
+	tests := []struct {
+		cert *x509.Certificate
+		want string
+	}{
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "alice", Organization: []string{"sre"}}}, "alice"},
+		{&x509.Certificate{Subject: pkix.Name{Organization: []string{"sre"}, OrganizationalUnit: []string{"oncall"}}}, "OU=oncall,O=sre"},
+		{&x509.Certificate{URIs: []*url.URL{spiffe}}, "spiffe://cluster.local/ns/ops/sa/backup"},
+	}
+	for _, tt := range tests {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr:     &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 4242},
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{tt.cert}}},
+		})
+		if got := callerIdentity(ctx); got != tt.want {
+			t.Errorf("callerIdentity() = %q; want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCallerIdentityToken(t *testing.T) {
+	reviews := 0
+	review := func(ctx context.Context, token string) (string, error) {
+		reviews++
+		switch token {
+		case "backup-token":
+			return "system:serviceaccount:ops:backup", nil
+		case "unreachable":
+			return "", errors.New("connection refused")
+		}
+		return "", nil
+	}
+	setSetting(t, &reviewToken, review)
+	t.Cleanup(func() { clear(tokens) })
+
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+	for range 2 {
+		if got := callerIdentity(withToken("backup-token")); got != "system:serviceaccount:ops:backup" {
+			t.Errorf("expected the ServiceAccount name, got %q", got)
+		}
+	}
+	if reviews != 1 {
+		t.Errorf("expected the review to be cached, got %d reviews", reviews)
+	}
+	if got := callerIdentity(withToken("forged")); got != "" {
+		t.Errorf("expected no identity for a token the API server rejects, got %q", got)
+	}
+	if got := callerIdentity(withToken("unreachable")); got != "" {
+		t.Errorf("expected no identity when the review fails, got %q", got)
+	}
+	if got := callerIdentity(context.Background()); got != "" {
+		t.Errorf("expected no identity without credentials, got %q", got)
+	}
+
+	// A client certificate takes precedence over a token.
+	ctx := metadata.NewIncomingContext(certContext("alice"), metadata.Pairs("authorization", "Bearer backup-token"))
+	if got := callerIdentity(ctx); got != "alice" {
+		t.Errorf("expected the certificate identity, got %q", got)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/jq"
+)
+
+// streamFiltered sends the output of filter for each JSON line of r in
+// chunks of about chunkSize bytes. Lines that are not JSON are skipped.
+func streamFiltered(path string, r io.Reader, filter *jq.Filter, chunkSize int64, stream api.PulsaarAgent_StreamFileServer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), int(maxReadSize))
+	var buf bytes.Buffer
+	for scanner.Scan() {
+		if err := filter.Apply(scanner.Bytes(), &buf); err != nil && err != jq.ErrNotJSON {
+			return status.Errorf(codes.Internal, "Unable to filter file '%s': %v", path, err)
+		}
+		if int64(buf.Len()) >= chunkSize {
+			if err := stream.Send(&api.ReadResponse{Data: buf.Bytes()}); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return status.Errorf(codes.FailedPrecondition, "File '%s' has a line longer than %d bytes, which cannot be filtered", path, maxReadSize)
+		}
+		if errors.Is(err, errDecompressedTooLarge) {
+			return decompressStatus(path, err)
+		}
+		return status.Errorf(codes.Internal, "Unable to read file '%s' during streaming: %v", path, err)
+	}
+	return stream.Send(&api.ReadResponse{Data: buf.Bytes(), Eof: true})
+}
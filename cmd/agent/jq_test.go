@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestStreamFileJQ(t *testing.T) {
+	dir := t.TempDir()
+	var log, want strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&log, `{"ts":"2026-10-16T09:00:%02dZ","level":"info","msg":"request %d","req":{"path":"/api","headers":{"accept":"*/*"}}}`+"\n", i%60, i)
+		fmt.Fprintf(&want, "\"info\"\n\"request %d\"\n", i)
+		if i == 10 {
+			log.WriteString("panic: not json\n")
+		}
+	}
+	p := filepath.Join(dir, "app.jsonl")
+	if err := os.WriteFile(p, []byte(log.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gz := filepath.Join(dir, "app.jsonl.gz")
+	if err := os.WriteFile(gz, gzipBytes(t, log.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := tailClient(t)
+
+	stream, err := c.StreamFile(context.Background(), &api.StreamRequest{Path: p, ChunkSize: 1024, Jq: ".level,.msg", AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, stream); got != want.String() {
+		t.Errorf("unexpected filtered output:\n%.200s", got)
+	}
+
+	stream, err = c.StreamFile(context.Background(), &api.StreamRequest{Path: gz, Jq: "{msg}", Decompress: true, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, stream); !strings.HasPrefix(got, "{\"msg\":\"request 0\"}\n{\"msg\":\"request 1\"}\n") || strings.Count(got, "\n") != 500 {
+		t.Errorf("unexpected filtered output of the decompressed file:\n%.200s", got)
+	}
+}
+
+func TestStreamFileJQErrors(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.jsonl")
+	if err := os.WriteFile(p, []byte(`{"msg":"`+strings.Repeat("x", int(maxReadSize))+`"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := tailClient(t)
+
+	for filter, code := range map[string]codes.Code{".msg | length": codes.InvalidArgument, ".msg": codes.FailedPrecondition} {
+		stream, err := c.StreamFile(context.Background(), &api.StreamRequest{Path: p, Jq: filter, AllowedRoots: []string{dir}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = stream.Recv()
+		if status.Code(err) != code {
+			t.Errorf("%q: expected %v, got %v", filter, code, err)
+		}
+	}
+}
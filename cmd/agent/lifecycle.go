@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+const (
+	// defaultShutdownIdentity is the common name of the lifecycle
+	// controller's client certificate.
+	defaultShutdownIdentity = "pulsaar-controller"
+	maxShutdownGrace        = 5 * time.Minute
+	// stopTimeout bounds how long in-flight streams may delay exit.
+	stopTimeout = 10 * time.Second
+)
+
+var (
+	// stopServer is set by main to stop the gRPC server.
+	stopServer   func()
+	shutdownOnce sync.Once
+)
+
+// gracefulStopper stops s, cancelling streams still open after stopTimeout.
+func gracefulStopper(s *grpc.Server) func() {
+	return func() {
+		done := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(stopTimeout):
+			s.Stop()
+		}
+	}
+}
+
+// shutdownCaller returns the identity of the caller when it may stop the
+// agent, which only the lifecycle controller named by --shutdown-identities
+// may do.
+func shutdownCaller(ctx context.Context) (string, error) {
+	caller := callerIdentity(ctx)
+	if caller == "" || !slices.Contains(settings.shutdownIdentities, caller) {
+		return "", status.Error(codes.PermissionDenied, "Only the lifecycle controller may shut down this agent.")
+	}
+	return caller, nil
+}
+
+// Shutdown stops the agent after the requested grace period. Ephemeral
+// containers cannot be removed from a pod, so exiting is how an injected
+// agent is retired once its TTL expires. Repeated requests are accepted=false
+// while a shutdown is already under way.
+func (s *server) Shutdown(ctx context.Context, req *api.ShutdownRequest) (*api.ShutdownResponse, error) {
+	if !getLimiterForIP(ctx).Allow() {
+		return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
+	}
+	if req.GraceSeconds < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Grace period must not be negative.")
+	}
+	caller, err := shutdownCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	grace := min(time.Duration(req.GraceSeconds)*time.Second, maxShutdownGrace)
+
+	accepted := false
+	shutdownOnce.Do(func() {
+		accepted = true
+		infof("Shutdown requested by %s (reason: %q); stopping in %s", caller, req.Reason, grace)
+		go func() {
+			time.Sleep(grace)
+			if stopServer != nil {
+				stopServer()
+			}
+		}()
+	})
+	return &api.ShutdownResponse{Accepted: accepted}, nil
+}
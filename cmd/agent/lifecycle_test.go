@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestShutdown(t *testing.T) {
+	shutdownOnce = sync.Once{}
+	stopped := make(chan struct{})
+	stopServer = func() { close(stopped) }
+	defer func() { stopServer = nil }()
+
+	s := &server{}
+	controller := certContext(defaultShutdownIdentity)
+	resp, err := s.Shutdown(controller, &api.ShutdownRequest{Reason: "ttl expired"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Accepted {
+		t.Error("expected first shutdown request to be accepted")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("server was not stopped")
+	}
+
+	resp, err = s.Shutdown(controller, &api.ShutdownRequest{Reason: "again"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Accepted {
+		t.Error("expected repeated shutdown request to report accepted=false")
+	}
+}
+
+func TestShutdownRefusesOtherCallers(t *testing.T) {
+	shutdownOnce = sync.Once{}
+	stopServer = func() { t.Error("expected the agent to keep running") }
+	defer func() { stopServer = nil }()
+	events := captureAudit(t)
+
+	info := &grpc.UnaryServerInfo{FullMethod: api.PulsaarAgent_Shutdown_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return (&server{}).Shutdown(ctx, req.(*api.ShutdownRequest))
+	}
+	for _, ctx := range []context.Context{context.Background(), certContext("mallory")} {
+		_, err := auditUnaryInterceptor(ctx, &api.ShutdownRequest{Reason: "bye"}, info, handler)
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied, got %v", err)
+		}
+		ev := <-events
+		if ev.Operation != "Shutdown" || ev.Result != auditDenied || ev.User != callerIdentity(ctx) {
+			t.Errorf("expected a denied Shutdown by %q to be audited, got %+v", callerIdentity(ctx), ev)
+		}
+	}
+
+	setSetting(t, &settings.shutdownIdentities, []string{"system:serviceaccount:pulsaar:controller"})
+	if _, err := (&server{}).Shutdown(certContext(defaultShutdownIdentity), &api.ShutdownRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected only the configured identities to be allowed, got %v", err)
+	}
+}
+
+func TestShutdownRejectsNegativeGrace(t *testing.T) {
+	s := &server{}
+	_, err := s.Shutdown(context.Background(), &api.ShutdownRequest{GraceSeconds: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
@@ -1,41 +1,45 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"math/big"
-	"net"
-	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	grpcPrometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
-	api "github.com/VrushankPatel/pulsaar/api"
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/jq"
+	"github.com/VrushankPatel/pulsaar/pkg/listing"
 )
 
 var (
@@ -46,41 +50,54 @@ var (
 
 type server struct {
 	api.UnimplementedPulsaarAgentServer
+	// tailPollInterval is how often a followed file is checked for new
+	// data; zero means defaultTailPollInterval.
+	tailPollInterval time.Duration
 }
 
 const maxReadSize int64 = 1024 * 1024 // 1MB
 
 var limiters sync.Map // map[string]*rate.Limiter
+
+// requestsPerSecond is the rate each client IP address is limited to.
+const requestsPerSecond = 10
+
 var configuredAllowedRoots []string
 
+// authMode is how clients authenticate, reported by Capabilities.
+var authMode = api.AuthModeTLS
+
 func getLimiterForIP(ctx context.Context) *rate.Limiter {
 	p, ok := peer.FromContext(ctx)
 	if !ok {
 		// Fallback: allow unlimited if can't determine peer
 		return rate.NewLimiter(rate.Inf, 1)
 	}
-	addr := p.Addr.String()
-	host, _, err := net.SplitHostPort(addr)
-	if err != nil {
-		host = addr
-	}
+	host := peerKey(p.Addr)
 	limiter, ok := limiters.Load(host)
 	if !ok {
-		limiter = rate.NewLimiter(rate.Limit(10), 10) // 10 operations per second per IP
+		limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), requestsPerSecond)
 		limiters.Store(host, limiter)
 	}
 	return limiter.(*rate.Limiter)
 }
 
 func loadOrGenerateCert() (tls.Certificate, error) {
-	certFile := os.Getenv("PULSAAR_TLS_CERT_FILE")
-	keyFile := os.Getenv("PULSAAR_TLS_KEY_FILE")
+	certFile := settings.tlsCertFile
+	keyFile := settings.tlsKeyFile
 
 	if certFile != "" && keyFile != "" {
 		return tls.LoadX509KeyPair(certFile, keyFile)
 	}
+	if certFile != "" || keyFile != "" {
+		return tls.Certificate{}, fmt.Errorf("both --tls-cert-file and --tls-key-file must be set")
+	}
+	return selfSignedCert()
+}
 
-	// Fallback to self-signed for MVP
+// selfSignedCert generates the certificate served when none is configured,
+// and while a configured one cannot be loaded yet.
+func selfSignedCert() (tls.Certificate, error) {
 	priv, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		return tls.Certificate{}, err
@@ -95,7 +112,7 @@ func loadOrGenerateCert() (tls.Certificate, error) {
 		NotAfter:    time.Now().Add(time.Hour * 24 * 365),
 		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		IPAddresses: certIPs(),
 		DNSNames:    []string{"localhost"},
 	}
 
@@ -111,7 +128,7 @@ func loadOrGenerateCert() (tls.Certificate, error) {
 }
 
 func loadCACertPool() (*x509.CertPool, error) {
-	caFile := os.Getenv("PULSAAR_TLS_CA_FILE")
+	caFile := settings.tlsCAFile
 	if caFile == "" {
 		return nil, nil // No client cert verification
 	}
@@ -129,37 +146,56 @@ func loadCACertPool() (*x509.CertPool, error) {
 	return caCertPool, nil
 }
 
-func initConfiguredAllowedRoots() {
+// loadAllowedRoots returns the configured roots from, in order, the pod's
+// pulsaar.io/allowed-roots annotation, the pulsaar-config ConfigMap and
+// --allowed-roots. A lookup that fails for any reason other than the object
+// or permission being absent is an error, so a flaky API server cannot
+// widen access to the --allowed-roots default of "/".
+func loadAllowedRoots() ([]string, error) {
 	namespace := getNamespace()
-	podName := os.Getenv("PULSAAR_POD_NAME")
+	podName := settings.podName
+	if hostRoot != nil {
+		// Host mode is scoped by its mounts and --allowed-roots only.
+		namespace = ""
+	}
 	if namespace != "" && podName != "" {
-		roots := loadAllowedRootsFromPodAnnotations(namespace, podName)
+		roots, err := loadAllowedRootsFromPodAnnotations(namespace, podName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the allowed roots of pod %s/%s: %v", namespace, podName, err)
+		}
 		if roots != nil {
-			configuredAllowedRoots = roots
-			return
+			return roots, checkRoots("the pulsaar.io/allowed-roots annotation", roots)
 		}
 	}
 	if namespace != "" {
-		roots := loadAllowedRootsFromConfigMap(namespace)
+		roots, err := loadAllowedRootsFromConfigMap(namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ConfigMap %s/pulsaar-config: %v", namespace, err)
+		}
 		if roots != nil {
-			configuredAllowedRoots = roots
-			return
+			return roots, checkRoots("the pulsaar-config ConfigMap", roots)
 		}
 	}
-	// Fallback to env
-	roots := os.Getenv("PULSAAR_ALLOWED_ROOTS")
-	if roots == "" {
-		configuredAllowedRoots = []string{"/"}
-	} else {
-		configuredAllowedRoots = strings.Split(roots, ",")
-		for i, root := range configuredAllowedRoots {
-			configuredAllowedRoots[i] = strings.TrimSpace(root)
+	if len(settings.allowedRoots) == 0 {
+		warnf("No allowed roots are configured; clients may read any path")
+		return []string{"/"}, nil
+	}
+	return settings.allowedRoots, checkRoots("--allowed-roots", settings.allowedRoots)
+}
+
+// checkRoots rejects roots that no request path could match, which
+// usually means a typo that would otherwise deny everything silently.
+func checkRoots(source string, roots []string) error {
+	for _, root := range roots {
+		if (root != "/" && !filepath.IsAbs(root)) || strings.ContainsRune(root, 0) {
+			return fmt.Errorf("invalid allowed root %q in %s: must be an absolute path", root, source)
 		}
 	}
+	return nil
 }
 
 func getNamespace() string {
-	if ns := os.Getenv("PULSAAR_NAMESPACE"); ns != "" {
+	if ns := settings.namespace; ns != "" {
 		return ns
 	}
 	data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
@@ -169,23 +205,32 @@ func getNamespace() string {
 	return strings.TrimSpace(string(data))
 }
 
-func loadAllowedRootsFromConfigMap(namespace string) []string {
+// inClusterClient returns a client for the API server, or nil outside a
+// cluster.
+func inClusterClient() (kubernetes.Interface, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
-		return nil
-	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil
+		return nil, nil
 	}
-	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), "pulsaar-config", metav1.GetOptions{})
-	if err != nil {
-		return nil
+	return kubernetes.NewForConfig(config)
+}
+
+// absent reports whether err means the object is missing or the agent may
+// not read it, in which case the next source of roots applies.
+func absent(err error) bool {
+	if apierrors.IsNotFound(err) {
+		return true
 	}
-	rootsStr, ok := cm.Data["allowed-roots"]
-	if !ok {
-		return nil
+	if apierrors.IsForbidden(err) {
+		warnf("Skipping allowed roots the agent may not read: %v", err)
+		return true
 	}
+	return false
+}
+
+// splitRoots parses a comma-separated list of roots. An empty list denies
+// every path.
+func splitRoots(rootsStr string) []string {
 	if rootsStr == "" {
 		return []string{}
 	}
@@ -196,62 +241,95 @@ func loadAllowedRootsFromConfigMap(namespace string) []string {
 	return roots
 }
 
-func loadAllowedRootsFromPodAnnotations(namespace, podName string) []string {
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return nil
+// loadAllowedRootsFromConfigMap returns nil roots when the ConfigMap or its
+// allowed-roots key is absent.
+func loadAllowedRootsFromConfigMap(namespace string) ([]string, error) {
+	clientset, err := inClusterClient()
+	if clientset == nil {
+		return nil, err
 	}
-	clientset, err := kubernetes.NewForConfig(config)
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), "pulsaar-config", metav1.GetOptions{})
 	if err != nil {
-		return nil
+		if absent(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	rootsStr, ok := cm.Data["allowed-roots"]
+	if !ok {
+		return nil, nil
+	}
+	return splitRoots(rootsStr), nil
+}
+
+// loadAllowedRootsFromPodAnnotations returns nil roots when the pod has no
+// pulsaar.io/allowed-roots annotation.
+func loadAllowedRootsFromPodAnnotations(namespace, podName string) ([]string, error) {
+	clientset, err := inClusterClient()
+	if clientset == nil {
+		return nil, err
 	}
 	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
 	if err != nil {
-		return nil
+		if absent(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
 	rootsStr, ok := pod.Annotations["pulsaar.io/allowed-roots"]
 	if !ok {
-		return nil
+		return nil, nil
 	}
-	if rootsStr == "" {
-		return []string{}
-	}
-	roots := strings.Split(rootsStr, ",")
-	for i, root := range roots {
-		roots[i] = strings.TrimSpace(root)
-	}
-	return roots
+	return splitRoots(rootsStr), nil
 }
 
-func isPathAllowed(path string, allowedRoots []string) bool {
-	cleanPath := filepath.Clean(path)
-	for _, root := range allowedRoots {
-		cleanRoot := filepath.Clean(root)
-		if cleanRoot == "/" || cleanPath == cleanRoot || strings.HasPrefix(cleanPath, cleanRoot+"/") {
-			return true
+// newAuditEvent describes an operation together with who requested it and
+// which pod served it.
+func newAuditEvent(ctx context.Context, operation, path string) *api.AuditEvent {
+	hostname, _ := os.Hostname()
+	ev := &api.AuditEvent{
+		Timestamp: time.Now().Format(time.RFC3339),
+		Operation: operation,
+		Path:      path,
+		AgentId:   hostname,
+		Namespace: getNamespace(),
+		Pod:       settings.podName,
+		Container: settings.containerName,
+		Node:      settings.nodeName,
+		RequestId: requestIDFromContext(ctx),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		ev.ClientAddr = peerString(p.Addr)
+	}
+	ev.User = callerIdentity(ctx)
+	return ev
+}
+
+// requestIDFromContext returns the caller-supplied x-request-id, or a new
+// random ID so every audit event can be correlated with agent logs.
+func requestIDFromContext(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get("x-request-id"); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
 		}
 	}
-	return false
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
 }
 
-func auditLog(operation, path string) {
-	log.Printf("Audit: %s request for path: %s", operation, path)
-	if url := os.Getenv("PULSAAR_AUDIT_AGGREGATOR_URL"); url != "" {
-		hostname, _ := os.Hostname()
-		data := map[string]any{
-			"timestamp": time.Now().Format(time.RFC3339),
-			"operation": operation,
-			"path":      path,
-			"agent_id":  hostname,
-		}
-		jsonData, _ := json.Marshal(data)
-		resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-		if resp != nil {
-			defer func() { _ = resp.Body.Close() }()
-		}
-		if err != nil {
-			log.Printf("Failed to send audit log: %v", err)
-		}
+// auditLog logs ev and delivers it to the aggregator, if one is set.
+func auditLog(ev *api.AuditEvent) {
+	result := ev.Result
+	if ev.ErrorCode != "" {
+		result += " (" + ev.ErrorCode + ")"
+	}
+	infof("Audit: %s request for path: %s %s (user=%q client=%s request_id=%s bytes=%d duration=%dms)",
+		ev.Operation, ev.Path, result, ev.User, ev.ClientAddr, ev.RequestId, ev.BytesRead, ev.DurationMs)
+	if audits != nil {
+		audits.Send(ev)
 	}
 }
 
@@ -259,80 +337,153 @@ func (s *server) ListDirectory(ctx context.Context, req *api.ListRequest) (*api.
 	if !getLimiterForIP(ctx).Allow() {
 		return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
 	}
-	auditLog("ListDirectory", req.Path)
-	allowedRoots := req.AllowedRoots
-	if len(allowedRoots) == 0 {
-		allowedRoots = configuredAllowedRoots
-	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
 	if !isPathAllowed(req.Path, allowedRoots) {
 		return nil, status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
 	}
 
-	entries, err := os.ReadDir(req.Path)
+	q, err := listing.NewQuery(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid list request: %v", err)
+	}
+	entries, err := readDir(req.Path, allowedRoots)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Unable to list contents of directory '%s': %v", req.Path, err)
+		return nil, openError("Unable to list contents of directory '%s': %v", req.Path, err)
 	}
+	entries, nextPageToken := listing.Page(q, entries, func(entry os.DirEntry) (listing.Entry, bool) {
+		return listEntry(q, entry)
+	})
 
+	return &api.ListResponse{Entries: entryInfos(req.Path, entries), NextPageToken: nextPageToken}, nil
+}
+
+// entryInfos describes the entries of dir, skipping any that vanished
+// after they were listed.
+func entryInfos(dir string, entries []os.DirEntry) []*api.FileInfo {
 	var fileInfos []*api.FileInfo
 	for _, entry := range entries {
 		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
-		fileInfos = append(fileInfos, &api.FileInfo{
-			Name:      entry.Name(),
-			IsDir:     entry.IsDir(),
-			SizeBytes: info.Size(),
-			Mode:      info.Mode().String(),
-			Mtime:     timestamppb.New(info.ModTime()),
+		fileInfos = append(fileInfos, describeFile(filepath.Join(dir, entry.Name()), entry.Name(), info))
+	}
+	return fileInfos
+}
+
+// describeFile converts the information about the file at p to its API
+// form under name.
+func describeFile(p, name string, info fs.FileInfo) *api.FileInfo {
+	fi := &api.FileInfo{
+		Name:      name,
+		IsDir:     info.IsDir(),
+		SizeBytes: info.Size(),
+		Mode:      info.Mode().String(),
+		Mtime:     timestamppb.New(info.ModTime()),
+		Owner:     fileOwner(p, info),
+		FileType:  api.FileTypeOf(info.Mode()),
+		HardLinks: hardLinks(info),
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		fi.LinkTarget, _ = readLink(p)
+	}
+	return fi
+}
+
+// listStreamBatch is how many entries ListDirectoryStream reads and sends
+// at a time.
+const listStreamBatch = 500
+
+// ListDirectoryStream sends the entries of a directory in batches as they
+// are read, in directory order rather than sorted, so huge directories can
+// be shown progressively. Filters apply; sorting and paging fields are
+// ignored.
+func (s *server) ListDirectoryStream(req *api.ListRequest, stream api.PulsaarAgent_ListDirectoryStreamServer) error {
+	ctx := stream.Context()
+	if !getLimiterForIP(ctx).Allow() {
+		return status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
+	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
+	if !isPathAllowed(req.Path, allowedRoots) {
+		return status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
+	}
+
+	q, err := listing.NewQuery(&api.ListRequest{NameGlob: req.NameGlob, MinSize: req.MinSize, ModifiedSince: req.ModifiedSince})
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid list request: %v", err)
+	}
+	dir, err := openDir(req.Path, allowedRoots)
+	if err != nil {
+		return openError("Unable to list contents of directory '%s': %v", req.Path, err)
+	}
+	defer func() { _ = dir.Close() }()
+	for {
+		entries, err := dir.ReadDir(listStreamBatch)
+		entries = slices.DeleteFunc(entries, func(entry os.DirEntry) bool {
+			e, ok := listEntry(q, entry)
+			return !ok || !q.Match(e)
 		})
+		if len(entries) > 0 {
+			if err := stream.Send(&api.ListResponse{Entries: entryInfos(req.Path, entries)}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "Unable to list contents of directory '%s': %v", req.Path, err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
 	}
+}
 
-	return &api.ListResponse{Entries: fileInfos}, nil
+// listEntry describes entry for pkg/listing, reading its size and mtime
+// only when the query filters or sorts on them.
+func listEntry(q *listing.Query, entry os.DirEntry) (listing.Entry, bool) {
+	e := listing.Entry{Name: entry.Name()}
+	if q.NeedsInfo() {
+		info, err := entry.Info()
+		if err != nil {
+			return e, false
+		}
+		e.Size, e.ModTime = info.Size(), info.ModTime()
+	}
+	return e, true
 }
 
 func (s *server) Stat(ctx context.Context, req *api.StatRequest) (*api.StatResponse, error) {
 	if !getLimiterForIP(ctx).Allow() {
 		return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
 	}
-	auditLog("Stat", req.Path)
-	allowedRoots := req.AllowedRoots
-	if len(allowedRoots) == 0 {
-		allowedRoots = configuredAllowedRoots
-	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
 	if !isPathAllowed(req.Path, allowedRoots) {
 		return nil, status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
 	}
 
-	info, err := os.Stat(req.Path)
+	info, err := statFile(req.Path)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Unable to get information for path '%s': %v", req.Path, err)
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
 	}
 
-	return &api.StatResponse{
-		Info: &api.FileInfo{
-			Name:      filepath.Base(req.Path),
-			IsDir:     info.IsDir(),
-			SizeBytes: info.Size(),
-			Mode:      info.Mode().String(),
-			Mtime:     timestamppb.New(info.ModTime()),
-		},
-	}, nil
+	return &api.StatResponse{Info: describeFile(req.Path, filepath.Base(req.Path), info)}, nil
 }
 
 func (s *server) ReadFile(ctx context.Context, req *api.ReadRequest) (*api.ReadResponse, error) {
 	if !getLimiterForIP(ctx).Allow() {
 		return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
 	}
-	auditLog("ReadFile", req.Path)
-	allowedRoots := req.AllowedRoots
-	if len(allowedRoots) == 0 {
-		allowedRoots = configuredAllowedRoots
-	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
 	if !isPathAllowed(req.Path, allowedRoots) {
 		return nil, status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
 	}
 
+	if len(req.Ranges) > 0 {
+		return readRanges(req, allowedRoots)
+	}
+
 	readLen := req.Length
 	if readLen == 0 {
 		readLen = maxReadSize
@@ -341,12 +492,20 @@ func (s *server) ReadFile(ctx context.Context, req *api.ReadRequest) (*api.ReadR
 		return nil, status.Errorf(codes.InvalidArgument, "Requested read length (%d bytes) exceeds the maximum allowed size of %d bytes", readLen, maxReadSize)
 	}
 
-	file, err := os.Open(req.Path)
+	file, err := openFile(req.Path, allowedRoots)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Unable to open file '%s' for reading: %v", req.Path, err)
+		return nil, openError("Unable to open file '%s' for reading: %v", req.Path, err)
 	}
 	defer func() { _ = file.Close() }()
 
+	if req.Decompress {
+		data, eof, err := readDecompressed(file, req.Offset, readLen)
+		if err != nil {
+			return nil, decompressStatus(req.Path, err)
+		}
+		return &api.ReadResponse{Data: data, Eof: eof}, nil
+	}
+
 	data := make([]byte, readLen)
 	n, err := file.ReadAt(data, req.Offset)
 	if err != nil && err != io.EOF {
@@ -361,11 +520,7 @@ func (s *server) StreamFile(req *api.StreamRequest, stream api.PulsaarAgent_Stre
 	if !getLimiterForIP(stream.Context()).Allow() {
 		return status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
 	}
-	auditLog("StreamFile", req.Path)
-	allowedRoots := req.AllowedRoots
-	if len(allowedRoots) == 0 {
-		allowedRoots = configuredAllowedRoots
-	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
 	if !isPathAllowed(req.Path, allowedRoots) {
 		return status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
 	}
@@ -378,15 +533,39 @@ func (s *server) StreamFile(req *api.StreamRequest, stream api.PulsaarAgent_Stre
 		return status.Errorf(codes.InvalidArgument, "Requested chunk size (%d bytes) exceeds the maximum allowed size of %d bytes", chunkSize, maxReadSize)
 	}
 
-	file, err := os.Open(req.Path)
+	var filter *jq.Filter
+	if req.Jq != "" {
+		var err error
+		if filter, err = jq.Parse(req.Jq); err != nil {
+			return status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+
+	file, err := openFile(req.Path, allowedRoots)
 	if err != nil {
-		return status.Errorf(codes.Internal, "Unable to open file '%s' for streaming: %v", req.Path, err)
+		return openError("Unable to open file '%s' for streaming: %v", req.Path, err)
 	}
 	defer func() { _ = file.Close() }()
 
+	var r io.Reader = file
+	if req.Decompress {
+		dr, err := decompressReader(file)
+		if err != nil {
+			return decompressStatus(req.Path, err)
+		}
+		defer func() { _ = dr.Close() }()
+		r = dr
+	}
+	if filter != nil {
+		return streamFiltered(req.Path, r, filter, chunkSize, stream)
+	}
+
 	buf := make([]byte, chunkSize)
 	for {
-		n, err := file.Read(buf)
+		n, err := r.Read(buf)
+		if req.Decompress && err != nil && err != io.EOF {
+			return decompressStatus(req.Path, err)
+		}
 		if err != nil && err != io.EOF {
 			return status.Errorf(codes.Internal, "Unable to read file '%s' during streaming: %v", req.Path, err)
 		}
@@ -405,61 +584,112 @@ func (s *server) StreamFile(req *api.StreamRequest, stream api.PulsaarAgent_Stre
 }
 
 func (s *server) Health(ctx context.Context, req *emptypb.Empty) (*api.HealthResponse, error) {
+	ready, message := readyStatus()
 	return &api.HealthResponse{
-		Ready:         true,
+		Ready:         ready,
 		Version:       version,
-		StatusMessage: "Agent ready",
+		StatusMessage: message,
 		Commit:        commit,
 		Date:          date,
+		Capabilities:  api.Capabilities(), //nolint:staticcheck // for clients that predate the Capabilities RPC
+		Privilege:     effectivePrivilege(),
+		Posture:       securityPosture(),
 	}, nil
 }
 
-func main() {
-	initConfiguredAllowedRoots()
-
-	cert, err := loadOrGenerateCert()
-	if err != nil {
-		log.Fatalf("failed to load or generate cert: %v", err)
-	}
+// Capabilities describes what this agent supports, so clients can adapt
+// before calling RPCs or sending fields it does not know.
+func (s *server) Capabilities(ctx context.Context, req *emptypb.Empty) (*api.CapabilitiesResponse, error) {
+	return &api.CapabilitiesResponse{
+		Version:           version,
+		Rpcs:              api.RPCs(),
+		Features:          api.Features(),
+		CompressionCodecs: decompressionCodecs,
+		MaxReadSize:       maxReadSize,
+		AuthModes:         []string{authMode},
+	}, nil
+}
 
-	caCertPool, err := loadCACertPool()
-	if err != nil {
-		log.Fatalf("failed to load CA cert pool: %v", err)
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		log.Fatal(err)
 	}
+}
 
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-	}
-	if caCertPool != nil {
-		tlsConfig.ClientCAs = caCertPool
-		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
-	}
+func run() error {
+	initHostRoot()
+	initTargetRoot()
 
-	creds := credentials.NewTLS(tlsConfig)
+	// Until the configuration is valid, serve a self-signed certificate so
+	// Health can explain what is wrong; with mTLS no client is accepted.
+	cert, err := selfSignedCert()
+	if err != nil {
+		return fmt.Errorf("failed to generate cert: %v", err)
+	}
+	var pendingCAs *x509.CertPool
+	if settings.tlsCAFile != "" {
+		pendingCAs = x509.NewCertPool()
+		authMode = api.AuthModeMTLS
+	}
+	var serving atomic.Pointer[tls.Config]
+	serving.Store(serverTLSConfig(cert, pendingCAs))
+	creds := credentials.NewTLS(&tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return serving.Load(), nil
+		},
+	})
 
-	lis, err := net.Listen("tcp", ":50051")
+	lis, err := listen(settings.listenAddr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return fmt.Errorf("failed to listen: %v", err)
 	}
 
+	unary := []grpc.UnaryServerInterceptor{grpcPrometheus.UnaryServerInterceptor}
+	stream := []grpc.StreamServerInterceptor{grpcPrometheus.StreamServerInterceptor}
+	if initIdentityMetrics(settings.tlsCAFile != "") {
+		unary = append(unary, identityUnaryInterceptor)
+		stream = append(stream, identityStreamInterceptor)
+	}
 	s := grpc.NewServer(
 		grpc.Creds(creds),
-		grpc.UnaryInterceptor(grpcPrometheus.UnaryServerInterceptor),
-		grpc.StreamInterceptor(grpcPrometheus.StreamServerInterceptor),
+		grpc.ChainUnaryInterceptor(append(unary, readyUnaryInterceptor, auditUnaryInterceptor, validateUnaryInterceptor, approvalUnaryInterceptor, deadlineUnaryInterceptor)...),
+		grpc.ChainStreamInterceptor(append(stream, readyStreamInterceptor, auditStreamInterceptor, validateStreamInterceptor, approvalStreamInterceptor, deadlineStreamInterceptor)...),
 	)
 	api.RegisterPulsaarAgentServer(s, &server{})
+	healthpb.RegisterHealthServer(s, healthServer)
 	grpcPrometheus.Register(s)
+	stopServer = gracefulStopper(s)
 
-	go func() {
-		http.Handle("/metrics", promhttp.Handler())
-		log.Printf("Metrics server listening on :9090")
-		if err := http.ListenAndServe(":9090", nil); err != nil {
-			log.Printf("Failed to start metrics server: %v", err)
+	var metricsCert func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	if settings.metricsTLS {
+		// The gRPC certificate, but without requiring client certificates.
+		metricsCert = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &serving.Load().Certificates[0], nil
 		}
-	}()
+	}
+	stopMetrics, err := startMetricsServer(settings.metricsAddr, metricsCert, settings.metricsTokenFile)
+	if err != nil {
+		warnf("Failed to start metrics server: %v", err)
+		stopMetrics = func() {}
+	}
 
-	log.Printf("Pulsaar agent listening on :50051 with TLS")
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	setConfigProblem("configuration is being validated")
+	go awaitConfig(func(cfg *agentConfig) {
+		configuredAllowedRoots = cfg.allowedRoots
+		initAuditSinks(cfg)
+		serving.Store(cfg.tls)
+		applySandbox(cfg.allowedRoots)
+	})
+
+	infof("Pulsaar agent listening on %s with TLS", settings.listenAddr)
+	err = s.Serve(lis)
+	stopMetrics()
+	if err != nil {
+		return fmt.Errorf("failed to serve: %v", err)
 	}
+	if audits != nil {
+		audits.Close(5 * time.Second)
+	}
+	infof("Pulsaar agent stopped")
+	return nil
 }
@@ -2,17 +2,26 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
-	api "github.com/VrushankPatel/pulsaar/api"
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/listing"
 )
 
 func TestIsPathAllowed(t *testing.T) {
@@ -37,18 +46,65 @@ func TestIsPathAllowed(t *testing.T) {
 	}
 }
 
+func TestIsPathAllowedWindows(t *testing.T) {
+	tests := []struct {
+		path         string
+		allowedRoots []string
+		expected     bool
+	}{
+		{`C:\app\logs\app.log`, []string{`C:\app`}, true},
+		{`c:/APP/logs/app.log`, []string{`C:\app`}, true},
+		{`C:\app\..\Windows\win.ini`, []string{`C:\app`}, false},
+		{`C:\appdata\x`, []string{`C:\app`}, false},
+		{`D:\app\x`, []string{`C:\app`}, false},
+		{`C:\anything`, []string{`C:\`}, true},
+		{`C:\anything`, []string{"/"}, true},
+		{`C:\app\file.txt:secret`, []string{`C:\app`}, false},
+		{`C:app\file.txt`, []string{`C:\`}, false},
+		{`\\server\share\app\x`, []string{"/"}, false},
+		{`\\?\C:\app\x`, []string{`C:\app`}, false},
+	}
+
+	for _, tt := range tests {
+		if result := isPathAllowedOS(tt.path, tt.allowedRoots, true); result != tt.expected {
+			t.Errorf("isPathAllowedOS(%s, %v, windows) = %v; want %v", tt.path, tt.allowedRoots, result, tt.expected)
+		}
+	}
+}
+
 func TestAuditLog(t *testing.T) {
 	// Test audit log without aggregator
-	auditLog("TestOperation", "/test/path")
+	auditLog(&api.AuditEvent{Operation: "TestOperation", Path: "/test/path", Result: auditAllowed})
+
+	// Events go to the sink without waiting on delivery
+	events := captureAudit(t)
+	auditLog(&api.AuditEvent{Operation: "TestOperation2", Path: "/test/path2", Result: auditDenied, ErrorCode: "PermissionDenied"})
+	if ev := <-events; ev.Operation != "TestOperation2" {
+		t.Errorf("expected TestOperation2 to be sent, got %s", ev.Operation)
+	}
+}
+
+func TestNewAuditEvent(t *testing.T) {
+	setSetting(t, &settings.podName, "web-0")
+	setSetting(t, &settings.namespace, "shop")
+	setSetting(t, &settings.nodeName, "node-3")
 
-	// Test with invalid aggregator URL (should not panic)
-	original := os.Getenv("PULSAAR_AUDIT_AGGREGATOR_URL")
-	if err := os.Setenv("PULSAAR_AUDIT_AGGREGATOR_URL", "http://invalid-url-that-will-fail"); err != nil {
-		t.Fatalf("failed to set env: %v", err)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 4242}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", "req-123"))
+
+	ev := newAuditEvent(ctx, "ReadFile", "/etc/hosts")
+	if ev.Pod != "web-0" || ev.Namespace != "shop" || ev.Node != "node-3" {
+		t.Errorf("expected pod identity from settings, got pod=%q namespace=%q node=%q", ev.Pod, ev.Namespace, ev.Node)
+	}
+	if ev.RequestId != "req-123" {
+		t.Errorf("expected request ID from metadata, got %q", ev.RequestId)
 	}
-	auditLog("TestOperation2", "/test/path2")
-	if err := os.Setenv("PULSAAR_AUDIT_AGGREGATOR_URL", original); err != nil {
-		t.Fatalf("failed to restore env: %v", err)
+	if ev.ClientAddr != "10.0.0.7:4242" {
+		t.Errorf("expected client address from peer, got %q", ev.ClientAddr)
+	}
+
+	if id := newAuditEvent(context.Background(), "Stat", "/").RequestId; id == "" {
+		t.Error("expected generated request ID when none is supplied")
 	}
 }
 
@@ -89,6 +145,27 @@ func TestHealth(t *testing.T) {
 	if resp.StatusMessage != "Agent ready" {
 		t.Errorf("expected StatusMessage to be 'Agent ready', got %s", resp.StatusMessage)
 	}
+	//nolint:staticcheck // Health still reports capabilities for older clients
+	if caps := resp.Capabilities; !slices.Contains(caps, "TailFile") || !slices.Contains(caps, "ListDirectory") {
+		t.Errorf("expected every RPC in Capabilities, got %v", caps)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	s := &server{}
+	resp, err := s.Capabilities(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("Capabilities returned error: %v", err)
+	}
+	if !resp.Supports("Capabilities") || !resp.Supports("Search") || !resp.Supports(api.FeatureJQ) {
+		t.Errorf("expected every RPC and feature, got %v and %v", resp.Rpcs, resp.Features)
+	}
+	if resp.MaxReadSize != maxReadSize || !slices.Contains(resp.CompressionCodecs, "zstd") {
+		t.Errorf("expected the read limit and codecs, got %d and %v", resp.MaxReadSize, resp.CompressionCodecs)
+	}
+	if !slices.Equal(resp.AuthModes, []string{api.AuthModeTLS}) {
+		t.Errorf("expected tls without a client CA, got %v", resp.AuthModes)
+	}
 }
 
 func TestRateLimiting(t *testing.T) {
@@ -125,18 +202,15 @@ func TestRateLimiting(t *testing.T) {
 }
 
 func TestGetNamespace(t *testing.T) {
-	// Test with env var
-	original := os.Getenv("PULSAAR_NAMESPACE")
-	defer func() { _ = os.Setenv("PULSAAR_NAMESPACE", original) }() //nolint:errcheck
-
-	_ = os.Setenv("PULSAAR_NAMESPACE", "test-ns") //nolint:errcheck
+	// Test with the setting
+	setSetting(t, &settings.namespace, "test-ns")
 	ns := getNamespace()
 	if ns != "test-ns" {
 		t.Errorf("expected test-ns, got %s", ns)
 	}
 
-	// Clear env, test file
-	_ = os.Setenv("PULSAAR_NAMESPACE", "") //nolint:errcheck
+	// Clear the setting, test file
+	settings.namespace = ""
 	// Since we can't easily mock the file path, test that it returns "" when file not found
 	ns = getNamespace()
 	if ns != "" {
@@ -147,17 +221,180 @@ func TestGetNamespace(t *testing.T) {
 func TestLoadAllowedRootsFromConfigMap(t *testing.T) {
 	// Since this requires a k8s cluster, skip if not available
 	// In CI, it might not be, so just test that it returns nil when no cluster
-	roots := loadAllowedRootsFromConfigMap("default")
-	if roots != nil {
-		t.Errorf("expected nil when no cluster, got %v", roots)
+	roots, err := loadAllowedRootsFromConfigMap("default")
+	if roots != nil || err != nil {
+		t.Errorf("expected nil when no cluster, got %v, %v", roots, err)
 	}
 }
 
 func TestLoadAllowedRootsFromPodAnnotations(t *testing.T) {
 	// Since this requires a k8s cluster, skip if not available
 	// In CI, it might not be, so just test that it returns nil when no cluster
-	roots := loadAllowedRootsFromPodAnnotations("default", "test-pod")
-	if roots != nil {
-		t.Errorf("expected nil when no cluster, got %v", roots)
+	roots, err := loadAllowedRootsFromPodAnnotations("default", "test-pod")
+	if roots != nil || err != nil {
+		t.Errorf("expected nil when no cluster, got %v, %v", roots, err)
+	}
+}
+
+func TestListDirectoryPages(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"e", "a", "d", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &server{}
+	req := &api.ListRequest{Path: dir, AllowedRoots: []string{dir}, PageSize: 2}
+	var pages []string
+	for {
+		resp, err := s.ListDirectory(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range resp.Entries {
+			names = append(names, e.Name)
+		}
+		pages = append(pages, strings.Join(names, ""))
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+		if len(pages) == 1 {
+			// Entries removed before the token do not shift later pages.
+			if err := os.Remove(filepath.Join(dir, "a")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if strings.Join(pages, ",") != "ab,cd,e" {
+		t.Errorf("unexpected pages %q", pages)
+	}
+
+	resp, err := s.ListDirectory(context.Background(), &api.ListRequest{Path: dir, AllowedRoots: []string{dir}})
+	if err != nil || len(resp.Entries) != 4 || resp.NextPageToken != "" {
+		t.Errorf("expected every entry without a page size, got %d entries, token %q, %v", len(resp.GetEntries()), resp.GetNextPageToken(), err)
+	}
+
+	for _, req := range []*api.ListRequest{
+		{Path: dir, AllowedRoots: []string{dir}, PageSize: listing.MaxPageSize + 1},
+		{Path: dir, AllowedRoots: []string{dir}, PageSize: -1},
+		{Path: dir, AllowedRoots: []string{dir}, PageToken: "not base64!"},
+		{Path: dir, AllowedRoots: []string{dir}, SortBy: "owner"},
+		{Path: dir, AllowedRoots: []string{dir}, NameGlob: "["},
+	} {
+		if _, err := s.ListDirectory(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%+v: expected InvalidArgument, got %v", req, err)
+		}
+	}
+}
+
+func TestListDirectoryStream(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < listStreamBatch+10; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%04d", i)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := tailClient(t)
+	stream, err := c.ListDirectoryStream(context.Background(), &api.ListRequest{Path: dir, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batches int
+	names := make(map[string]bool)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		batches++
+		for _, e := range resp.Entries {
+			names[e.Name] = e.IsDir
+		}
+	}
+	if batches != 2 || len(names) != listStreamBatch+11 || !names["sub"] {
+		t.Errorf("expected every entry in 2 batches, got %d entries in %d", len(names), batches)
+	}
+
+	stream, err = c.ListDirectoryStream(context.Background(), &api.ListRequest{Path: "/etc", AllowedRoots: []string{dir}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestListDirectoryFilters(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for name, size := range map[string]int{"a.log": 30, "b.log": 10, "c.log": 20, "d.txt": 50, "e.log": 0} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if name == "c.log" {
+			if err := os.Chtimes(p, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	s := &server{}
+	req := &api.ListRequest{Path: dir, AllowedRoots: []string{dir}, SortBy: "size", Order: "desc", NameGlob: "*.log", MinSize: 1, PageSize: 2}
+	var pages []string
+	for {
+		resp, err := s.ListDirectory(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range resp.Entries {
+			names = append(names, e.Name)
+		}
+		pages = append(pages, strings.Join(names, " "))
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	if strings.Join(pages, ",") != "a.log c.log,b.log" {
+		t.Errorf("unexpected pages %q", pages)
+	}
+
+	// A token only continues the sort order it was issued for.
+	req.Order = "asc"
+	if _, err := s.ListDirectory(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a token of another order, got %v", err)
+	}
+
+	stream, err := tailClient(t).ListDirectoryStream(context.Background(), &api.ListRequest{
+		Path: dir, AllowedRoots: []string{dir}, NameGlob: "*.log", ModifiedSince: timestamppb.New(time.Now().Add(-time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range resp.Entries {
+			names = append(names, e.Name)
+		}
+	}
+	slices.Sort(names)
+	if strings.Join(names, " ") != "a.log b.log e.log" {
+		t.Errorf("unexpected streamed entries %q", names)
 	}
 }
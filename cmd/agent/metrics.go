@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// metricsOff as --metrics-listen turns the metrics server off, for
+// clusters that allow no port but the agent's.
+const metricsOff = "off"
+
+// metricsShutdownTimeout bounds how long scrapes in flight may delay exit.
+const metricsShutdownTimeout = 5 * time.Second
+
+// newMetricsServer serves /metrics and /readyz on a mux of its own, so
+// handlers that libraries register on http.DefaultServeMux are not exposed.
+// With a token file, every path but /readyz, which kubelet probes without
+// credentials, needs the token as a bearer token.
+func newMetricsServer(tokenFile string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	var handler http.Handler = mux
+	if tokenFile != "" {
+		handler = requireBearerToken(tokenFile, mux)
+	}
+	root := http.NewServeMux()
+	root.HandleFunc("/readyz", handleReadyz)
+	root.Handle("/", handler)
+	return &http.Server{Handler: root, ReadHeaderTimeout: 10 * time.Second}
+}
+
+// requireBearerToken passes requests carrying the token in tokenFile as a
+// bearer token to next. The file is read for each request, so a rotated
+// Secret is picked up; while it cannot be read every request is refused.
+func requireBearerToken(tokenFile string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile(tokenFile)
+		token := strings.TrimSpace(string(data))
+		if err != nil || token == "" {
+			warnf("Refusing metrics request: cannot read --metrics-token-file: %v", err)
+			http.Error(w, "metrics token unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pulsaar-agent"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startMetricsServer serves metrics on addr in the background and returns
+// a function that shuts the server down, letting scrapes in flight finish.
+// With getCert it serves HTTPS, without asking for client certificates so
+// Prometheus and kubelet probes need none. It returns a no-op when addr is
+// empty or "off".
+func startMetricsServer(addr string, getCert func(*tls.ClientHelloInfo) (*tls.Certificate, error), tokenFile string) (stop func(), err error) {
+	if addr == "" || addr == metricsOff {
+		infof("Metrics server disabled")
+		return func() {}, nil
+	}
+	if tokenFile != "" && getCert == nil {
+		warnf("--metrics-token-file is set without --metrics-tls; the token is sent in plaintext")
+	}
+	lis, err := listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := newMetricsServer(tokenFile)
+	scheme := "http"
+	if getCert != nil {
+		srv.TLSConfig = &tls.Config{GetCertificate: getCert, MinVersion: tls.VersionTLS12}
+		lis = tls.NewListener(lis, srv.TLSConfig)
+		scheme = "https"
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			warnf("Metrics server stopped: %v", err)
+		}
+	}()
+	infof("Metrics server listening on %s (%s)", lis.Addr(), scheme)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			_ = srv.Close()
+		}
+		<-done
+	}, nil
+}
+
+// Identity labels, set with --metrics-identity, record who sends each
+// request: "name" uses the client certificate's identity and "hash" a
+// short SHA-256 of it, for clusters where names should not reach the
+// metrics backend. They need mTLS, the only way the agent learns who a
+// client is.
+const (
+	identityOff  = ""
+	identityName = "name"
+	identityHash = "hash"
+)
+
+// Identities beyond maxMetricsIdentities (--metrics-max-identities)
+// share the "other" label, bounding the series a long-lived agent creates.
+var (
+	metricsIdentity      = identityOff
+	maxMetricsIdentities = 50
+)
+
+var identityRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "pulsaar_agent_requests_by_identity_total",
+	Help: "Requests handled by the agent per method, status code and client identity.",
+}, []string{"method", "code", "identity"})
+
+var (
+	identitiesMu   sync.Mutex
+	seenIdentities = map[string]bool{}
+)
+
+// initIdentityMetrics reads the identity label settings and reports whether
+// requests should be counted per identity.
+func initIdentityMetrics(mtls bool) bool {
+	if metricsIdentity == identityOff {
+		return false
+	}
+	if !mtls {
+		warnf("--metrics-identity is set but client certificates are not verified; set --tls-ca-file to label metrics by identity")
+		return false
+	}
+	prometheus.MustRegister(identityRequests)
+	return true
+}
+
+// peerIdentity names the client certificate on ctx by its common name, or
+// its full subject or URI when it has no common name. It is "" for clients
+// that did not present one.
+func peerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return ""
+	}
+	cert := tlsInfo.State.PeerCertificates[0]
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if subject := cert.Subject.String(); subject != "" {
+		return subject
+	}
+	if len(cert.URIs) > 0 {
+		// SPIFFE and similar workload certificates carry only a URI.
+		return cert.URIs[0].String()
+	}
+	return ""
+}
+
+// identityLabel turns a client identity into a metric label value.
+func identityLabel(identity string) string {
+	if identity == "" {
+		return "anonymous"
+	}
+	if metricsIdentity == identityHash {
+		sum := sha256.Sum256([]byte(identity))
+		identity = hex.EncodeToString(sum[:6])
+	}
+	identitiesMu.Lock()
+	defer identitiesMu.Unlock()
+	if !seenIdentities[identity] {
+		if len(seenIdentities) >= maxMetricsIdentities {
+			return "other"
+		}
+		seenIdentities[identity] = true
+	}
+	return identity
+}
+
+func countIdentityRequest(ctx context.Context, fullMethod string, err error) {
+	identityRequests.WithLabelValues(path.Base(fullMethod), status.Code(err).String(), identityLabel(peerIdentity(ctx))).Inc()
+}
+
+func identityUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	countIdentityRequest(ctx, info.FullMethod, err)
+	return resp, err
+}
+
+func identityStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	countIdentityRequest(ss.Context(), info.FullMethod, err)
+	return err
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func setIdentityMetrics(t *testing.T, mode string, max int) {
+	origMode, origMax := metricsIdentity, maxMetricsIdentities
+	metricsIdentity, maxMetricsIdentities = mode, max
+	seenIdentities = map[string]bool{}
+	identityRequests.Reset()
+	t.Cleanup(func() {
+		metricsIdentity, maxMetricsIdentities = origMode, origMax
+		seenIdentities = map[string]bool{}
+	})
+}
+
+// certContext is a request context from a client presenting a certificate
+// for commonName.
+func certContext(commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 4242},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+	})
+}
+
+func TestIdentityLabel(t *testing.T) {
+	setIdentityMetrics(t, identityName, 2)
+	if got := identityLabel(""); got != "anonymous" {
+		t.Errorf("expected anonymous for a client without a certificate, got %q", got)
+	}
+	for _, id := range []string{"alice", "bob", "alice"} {
+		if got := identityLabel(id); got != id {
+			t.Errorf("identityLabel(%q) = %q", id, got)
+		}
+	}
+	if got := identityLabel("carol"); got != "other" {
+		t.Errorf("expected identities past the limit to be labelled other, got %q", got)
+	}
+
+	setIdentityMetrics(t, identityHash, 2)
+	got := identityLabel("alice")
+	if got == "alice" || len(got) != 12 || identityLabel("alice") != got {
+		t.Errorf("expected a stable 12-character hash, got %q", got)
+	}
+}
+
+func TestIdentityInterceptors(t *testing.T) {
+	setIdentityMetrics(t, identityName, 10)
+	info := &grpc.UnaryServerInfo{FullMethod: api.PulsaarAgent_ReadFile_FullMethodName}
+	ctx := certContext("alice")
+
+	_, _ = identityUnaryInterceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		return &api.ReadResponse{}, nil
+	})
+	_, _ = identityUnaryInterceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.PermissionDenied, "denied")
+	})
+	if n := testutil.ToFloat64(identityRequests.WithLabelValues("ReadFile", "OK", "alice")); n != 1 {
+		t.Errorf("expected one successful read by alice, got %v", n)
+	}
+	if n := testutil.ToFloat64(identityRequests.WithLabelValues("ReadFile", "PermissionDenied", "alice")); n != 1 {
+		t.Errorf("expected one denied read by alice, got %v", n)
+	}
+
+	if newAuditEvent(ctx, "ReadFile", "/etc/hosts").User != "alice" {
+		t.Error("expected the audit event to carry the certificate's common name")
+	}
+}
+
+func TestMetricsServer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	stop, err := startMetricsServer(addr, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]int{"/metrics": http.StatusOK, "/debug/pprof/": http.StatusNotFound} {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+	stop()
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Error("expected the server to be shut down")
+	}
+
+	if _, err := startMetricsServer("no-port", nil, ""); err == nil {
+		t.Error("expected an address without a port to fail")
+	}
+	stop, err = startMetricsServer(metricsOff, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+}
+
+func TestMetricsServerAuth(t *testing.T) {
+	cert, err := selfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+	getCert := func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &cert, nil }
+	stop, err := startMetricsServer(addr, getCert, tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	get := func(path, token string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "https://"+addr+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get("/metrics", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected a request without a token to be refused, got %d", code)
+	}
+	if code := get("/metrics", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong token to be refused, got %d", code)
+	}
+	if code := get("/metrics", "s3cret"); code != http.StatusOK {
+		t.Errorf("expected the token to be accepted, got %d", code)
+	}
+	if code := get("/readyz", ""); code == http.StatusUnauthorized {
+		t.Error("expected /readyz to stay open for probes")
+	}
+	if resp, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Error("expected plain HTTP to be refused")
+		}
+	}
+
+	// A rotated token is picked up; a missing file refuses everything.
+	if err := os.WriteFile(tokenFile, []byte("rotated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code := get("/metrics", "rotated"); code != http.StatusOK {
+		t.Errorf("expected the rotated token to be accepted, got %d", code)
+	}
+	_ = os.Remove(tokenFile)
+	if code := get("/metrics", "rotated"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected requests to be refused without a token file, got %d", code)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+)
+
+// peerKey is the rate limiter key for a client address: its IP without the
+// port or an IPv6 zone, so every connection from a client shares a limiter.
+// IPv4 clients reaching a dual-stack socket appear as IPv4-mapped IPv6
+// addresses and are keyed by the IPv4 address. Addresses that are not IP
+// addresses, such as in-memory test listeners, are keyed as they are.
+func peerKey(addr net.Addr) string {
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		if ip, err := netip.ParseAddr(addr.String()); err == nil {
+			return ip.Unmap().WithZone("").String()
+		}
+		return addr.String()
+	}
+	return ap.Addr().Unmap().WithZone("").String()
+}
+
+// peerString is the client address as audited, with IPv4-mapped addresses
+// shown as IPv4.
+func peerString(addr net.Addr) string {
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil || !ap.Addr().Is4In6() {
+		return addr.String()
+	}
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port()).String()
+}
+
+// listen listens on addr. A wildcard host, empty, 0.0.0.0 or ::, listens on
+// every address of both IP families where the host has them, so the agent
+// serves IPv4, IPv6 and dual-stack pods alike.
+func listen(addr string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && ip.IsUnspecified() {
+		addr = net.JoinHostPort("", port)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// certIPs are the IP SANs of the self-signed certificate: both loopback
+// addresses, for port-forwards, and the pod's own addresses of either
+// family, for direct connections.
+func certIPs() []net.IP {
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ips
+	}
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if ok && ipNet.IP.IsGlobalUnicast() {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return ips
+}
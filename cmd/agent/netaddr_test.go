@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/x509"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestPeerKey(t *testing.T) {
+	for _, tt := range []struct {
+		addr net.Addr
+		want string
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 40000}, "10.0.0.7"},
+		{&net.TCPAddr{IP: net.ParseIP("::ffff:10.0.0.7"), Port: 40001}, "10.0.0.7"},
+		{&net.TCPAddr{IP: net.ParseIP("fd00::7"), Port: 40002}, "fd00::7"},
+		{&net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 40003, Zone: "eth0"}, "fe80::1"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8:0:0::1"), Port: 40004}, "2001:db8::1"},
+		{&net.UnixAddr{Name: "bufconn", Net: "unix"}, "bufconn"},
+	} {
+		if got := peerKey(tt.addr); got != tt.want {
+			t.Errorf("peerKey(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestPeerString(t *testing.T) {
+	if got := peerString(&net.TCPAddr{IP: net.ParseIP("::ffff:10.0.0.7"), Port: 40000}); got != "10.0.0.7:40000" {
+		t.Errorf("expected the IPv4 address, got %q", got)
+	}
+	if got := peerString(&net.TCPAddr{IP: net.ParseIP("fd00::7"), Port: 40000}); got != "[fd00::7]:40000" {
+		t.Errorf("expected the IPv6 address, got %q", got)
+	}
+}
+
+func TestListenDualStack(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", "[::]:0", ":0"} {
+		lis, err := listen(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		port := lis.Addr().(*net.TCPAddr).Port
+		for _, host := range []string{"127.0.0.1", "::1"} {
+			conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+			if err != nil {
+				if host == "::1" {
+					t.Logf("%s: skipping IPv6, which this host lacks: %v", addr, err)
+					continue
+				}
+				t.Errorf("%s: expected %s to connect, got %v", addr, host, err)
+				continue
+			}
+			_ = conn.Close()
+		}
+		_ = lis.Close()
+	}
+	if _, err := listen("50051"); err == nil {
+		t.Error("expected an address without a port separator to be rejected")
+	}
+}
+
+func TestSelfSignedCertIPv6(t *testing.T) {
+	cert, err := selfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"127.0.0.1", "::1", "localhost"} {
+		if err := leaf.VerifyHostname(host); err != nil {
+			t.Errorf("expected the certificate to cover %s: %v", host, err)
+		}
+	}
+}
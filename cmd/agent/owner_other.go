@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package main
+
+import "io/fs"
+
+func fileOwner(_ string, _ fs.FileInfo) string {
+	return ""
+}
+
+func hardLinks(_ fs.FileInfo) int64 {
+	return 0
+}
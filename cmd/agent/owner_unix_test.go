@@ -0,0 +1,55 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestFileOwner(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "owned")
+	if err := os.WriteFile(p, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()); fileOwner(p, info) != want {
+		t.Errorf("fileOwner() = %q; want %q", fileOwner(p, info), want)
+	}
+}
+
+func TestListingLinks(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(p, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Skipf("link: %v", err)
+	}
+	if err := os.Symlink("app.log", filepath.Join(dir, "current")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&server{}).ListDirectory(context.Background(), &api.ListRequest{Path: dir, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]*api.FileInfo{}
+	for _, e := range resp.Entries {
+		got[e.Name] = e
+	}
+	if got["app.log"].HardLinks != 2 || got["app.log"].LinkTarget != "" {
+		t.Errorf("expected app.log to have 2 hard links and no target, got %v", got["app.log"])
+	}
+	if got["current"].LinkTarget != "app.log" {
+		t.Errorf("expected the symlink target app.log, got %q", got["current"].LinkTarget)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package main
+
+import (
+	"io/fs"
+
+	"golang.org/x/sys/windows"
+)
+
+// fileOwner returns the owner account as DOMAIN\user, or its SID when the
+// account cannot be resolved.
+func fileOwner(path string, _ fs.FileInfo) string {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return ""
+	}
+	sid, _, err := sd.Owner()
+	if err != nil || sid == nil {
+		return ""
+	}
+	account, domain, _, err := sid.LookupAccount("")
+	if err != nil {
+		return sid.String()
+	}
+	if domain == "" {
+		return account
+	}
+	return domain + `\` + account
+}
+
+// hardLinks is 0: FileInfo.Sys on Windows does not carry the link count.
+func hardLinks(_ fs.FileInfo) int64 {
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path"
+	"runtime"
+	"strings"
+)
+
+// normalizePath returns p in the form used for allowed-roots matching. On
+// Windows, separators become "/" and the path is lowercased so "C:\Logs" and
+// "c:/logs" compare equal. UNC, device and alternate-data-stream paths
+// return "" and are never allowed.
+func normalizePath(p string, windows bool) string {
+	if !windows {
+		return path.Clean(p)
+	}
+	p = strings.ReplaceAll(p, `\`, "/")
+	if strings.HasPrefix(p, "//") {
+		return ""
+	}
+	drive := ""
+	if len(p) >= 2 && p[1] == ':' && isDriveLetter(p[0]) {
+		drive, p = strings.ToLower(p[:2]), p[2:]
+	}
+	if strings.Contains(p, ":") || (drive != "" && !strings.HasPrefix(p, "/")) {
+		return ""
+	}
+	return drive + strings.ToLower(path.Clean(p))
+}
+
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// isPathAllowed reports whether p lies under one of allowedRoots, using the
+// path rules of the OS the agent runs on.
+func isPathAllowed(p string, allowedRoots []string) bool {
+	return isPathAllowedOS(p, allowedRoots, runtime.GOOS == "windows")
+}
+
+func isPathAllowedOS(p string, allowedRoots []string, windows bool) bool {
+	cleanPath := normalizePath(p, windows)
+	if cleanPath == "" {
+		return false
+	}
+	for _, root := range allowedRoots {
+		cleanRoot := normalizePath(root, windows)
+		if cleanRoot == "" {
+			continue
+		}
+		if cleanRoot == "/" {
+			return true
+		}
+		cleanRoot = strings.TrimSuffix(cleanRoot, "/")
+		if cleanPath == cleanRoot || strings.HasPrefix(cleanPath, cleanRoot+"/") {
+			return true
+		}
+	}
+	return false
+}
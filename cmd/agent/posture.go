@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+// securityPosture describes what the agent runs with and may do, for
+// Health.
+func securityPosture() *api.SecurityPosture {
+	layers, message := sandboxStatus()
+	posture := &api.SecurityPosture{
+		Uid:                  int64(os.Geteuid()),
+		Gid:                  int64(os.Getegid()),
+		Sandbox:              layers,
+		SandboxMessage:       message,
+		MaxDecompressedBytes: maxDecompressedSize,
+		RpcTimeoutMs:         rpcTimeout.Milliseconds(),
+		MaxStreamDurationMs:  maxStreamDuration.Milliseconds(),
+		RequestsPerSecond:    requestsPerSecond,
+		Gomaxprocs:           int32(runtime.GOMAXPROCS(0)),
+	}
+	// The roots are set before the configuration problem is cleared.
+	if getConfigProblem() == "" {
+		posture.AllowedRoots = configuredAllowedRoots
+	}
+	if caps, ok := effectiveCapabilities(); ok {
+		posture.LinuxCapabilities = privilege.CapabilityNames(caps)
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		posture.MemoryLimitBytes = limit
+	}
+	return posture
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestHealthPosture(t *testing.T) {
+	setSetting(t, &configuredAllowedRoots, []string{"/var/log"})
+	setSetting(t, &rpcTimeout, 30*time.Second)
+	resp, err := (&server{}).Health(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := resp.Posture
+	if p.Uid != int64(os.Geteuid()) || p.Gid != int64(os.Getegid()) {
+		t.Errorf("expected the process's IDs, got %d:%d", p.Uid, p.Gid)
+	}
+	if !slices.Equal(p.AllowedRoots, []string{"/var/log"}) || p.RpcTimeoutMs != 30000 || p.RequestsPerSecond != requestsPerSecond || p.Gomaxprocs < 1 {
+		t.Errorf("unexpected posture %v", p)
+	}
+	if len(p.Sandbox) != 0 || p.SandboxMessage != "not applied until the agent is ready" {
+		t.Errorf("expected the sandbox to be pending, got %v %q", p.Sandbox, p.SandboxMessage)
+	}
+
+	setSetting(t, &settings.sandbox, false)
+	if resp, _ := (&server{}).Health(context.Background(), &emptypb.Empty{}); resp.Posture.SandboxMessage != "turned off" {
+		t.Errorf("expected a turned off sandbox, got %q", resp.Posture.SandboxMessage)
+	}
+
+	setConfigProblem("no certificate")
+	t.Cleanup(func() { setConfigProblem("") })
+	if resp, _ := (&server{}).Health(context.Background(), &emptypb.Empty{}); resp.Posture.AllowedRoots != nil {
+		t.Errorf("expected no roots before the agent is ready, got %v", resp.Posture.AllowedRoots)
+	}
+}
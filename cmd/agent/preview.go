@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// defaultPreviewBytes is the head size when a request asks for neither head
+// nor tail.
+const defaultPreviewBytes int64 = 4 * 1024
+
+// Preview returns a file's metadata with its first and last bytes in one
+// call. The tail never overlaps the head. Directories, pipes, sockets and
+// devices get only their metadata.
+func (s *server) Preview(ctx context.Context, req *api.PreviewRequest) (*api.PreviewResponse, error) {
+	if !getLimiterForIP(ctx).Allow() {
+		return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
+	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
+	if !isPathAllowed(req.Path, allowedRoots) {
+		return nil, status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
+	}
+	headLen, tailLen := req.HeadBytes, req.TailBytes
+	if headLen < 0 || tailLen < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Preview sizes must not be negative")
+	}
+	if headLen > maxReadSize || tailLen > maxReadSize {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested preview size exceeds the maximum allowed size of %d bytes", maxReadSize)
+	}
+	if headLen == 0 && tailLen == 0 {
+		headLen = defaultPreviewBytes
+	}
+
+	info, err := statFile(req.Path)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+	resp := &api.PreviewResponse{Info: describeFile(req.Path, filepath.Base(req.Path), info)}
+	if !info.Mode().IsRegular() {
+		return resp, nil
+	}
+
+	file, err := openFile(req.Path, allowedRoots)
+	if err != nil {
+		return nil, openError("Unable to open file '%s' for reading: %v", req.Path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	size := info.Size()
+	head := make([]byte, min(headLen, size))
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, status.Errorf(codes.Internal, "Unable to read file '%s': %v", req.Path, err)
+	}
+	resp.Head = head[:n]
+
+	if start := max(int64(n), size-tailLen); tailLen > 0 && start < size {
+		tail := make([]byte, size-start)
+		n, err := file.ReadAt(tail, start)
+		if err != nil && err != io.EOF {
+			return nil, status.Errorf(codes.Internal, "Unable to read file '%s': %v", req.Path, err)
+		}
+		resp.Tail = tail[:n]
+	}
+	return resp, nil
+}
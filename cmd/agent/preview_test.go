@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestPreview(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(p, []byte("0123456789abcdefghij"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{}
+	ctx := context.Background()
+	roots := []string{dir}
+
+	tests := []struct {
+		head, tail int64
+		wantHead   string
+		wantTail   string
+	}{
+		{4, 4, "0123", "ghij"},
+		{0, 0, "0123456789abcdefghij", ""},
+		{0, 3, "", "hij"},
+		{15, 10, "0123456789abcde", "fghij"},
+		{30, 30, "0123456789abcdefghij", ""},
+	}
+	for _, tt := range tests {
+		resp, err := s.Preview(ctx, &api.PreviewRequest{Path: p, HeadBytes: tt.head, TailBytes: tt.tail, AllowedRoots: roots})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(resp.Head) != tt.wantHead || string(resp.Tail) != tt.wantTail {
+			t.Errorf("Preview(head=%d, tail=%d) = %q, %q; want %q, %q", tt.head, tt.tail, resp.Head, resp.Tail, tt.wantHead, tt.wantTail)
+		}
+		if resp.Info.Name != "app.log" || resp.Info.SizeBytes != 20 {
+			t.Errorf("unexpected info: %v", resp.Info)
+		}
+	}
+
+	resp, err := s.Preview(ctx, &api.PreviewRequest{Path: dir, AllowedRoots: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Info.IsDir || len(resp.Head) != 0 {
+		t.Errorf("expected directory metadata only, got %v", resp)
+	}
+}
+
+func TestPreviewRejectsBadRequests(t *testing.T) {
+	dir := t.TempDir()
+	s := &server{}
+	ctx := context.Background()
+	tests := []struct {
+		req  *api.PreviewRequest
+		code codes.Code
+	}{
+		{&api.PreviewRequest{Path: "/etc/passwd", AllowedRoots: []string{dir}}, codes.PermissionDenied},
+		{&api.PreviewRequest{Path: dir, HeadBytes: -1, AllowedRoots: []string{dir}}, codes.InvalidArgument},
+		{&api.PreviewRequest{Path: dir, TailBytes: maxReadSize + 1, AllowedRoots: []string{dir}}, codes.InvalidArgument},
+		{&api.PreviewRequest{Path: filepath.Join(dir, "missing"), AllowedRoots: []string{dir}}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.Preview(ctx, tt.req); status.Code(err) != tt.code {
+			t.Errorf("Preview(%s): expected %s, got %v", strings.TrimPrefix(tt.req.Path, dir), tt.code, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+// effectivePrivilege is processPrivilege. Tests replace it.
+var effectivePrivilege = processPrivilege
+
+// checkPrivilege makes sure the agent runs with exactly the privilege it
+// is configured for, so a pod that grants more than intended, or less than
+// needed, is caught at startup instead of when files are read.
+func checkPrivilege(effective string) error {
+	if err := privilege.Validate(settings.privilege); err != nil {
+		return err
+	}
+	if effective == "" || effective == settings.privilege {
+		return nil
+	}
+	switch settings.privilege {
+	case privilege.NonRoot:
+		return fmt.Errorf("the agent runs with %s privilege but is configured for %s; run it as a non-root user without capabilities (runAsNonRoot, capabilities.drop: [ALL]), or set --privilege to what it needs", effective, settings.privilege)
+	case privilege.DACReadSearch:
+		return fmt.Errorf("the agent runs with %s privilege but is configured for %s; run %s as a non-root user with capabilities.add: [DAC_READ_SEARCH] and privilege escalation allowed", effective, settings.privilege, privilege.DACReadSearchBinary)
+	}
+	return fmt.Errorf("the agent runs with %s privilege but is configured for %s; set runAsUser: 0", effective, settings.privilege)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+// processPrivilege names what the agent process may read, from its
+// effective user ID and capabilities.
+func processPrivilege() string {
+	caps, ok := effectiveCapabilities()
+	if !ok {
+		return ""
+	}
+	return privilege.Of(os.Geteuid(), caps)
+}
+
+// effectiveCapabilities returns the agent's effective capability set from
+// /proc.
+func effectiveCapabilities() (uint64, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "CapEff:"); ok {
+			caps, err := strconv.ParseUint(strings.TrimSpace(v), 16, 64)
+			return caps, err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+// processPrivilege is only known on Linux; Windows agents run as the
+// container's user and are not checked.
+func processPrivilege() string {
+	return ""
+}
+
+// effectiveCapabilities is only known on Linux.
+func effectiveCapabilities() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+func TestCheckPrivilege(t *testing.T) {
+	for _, c := range []struct {
+		configured, effective, want string
+	}{
+		{privilege.NonRoot, privilege.NonRoot, ""},
+		{privilege.NonRoot, "", ""},
+		{privilege.NonRoot, privilege.Root, "runs with root privilege but is configured for non-root"},
+		{privilege.NonRoot, privilege.DACReadSearch, "runs with dac-read-search privilege"},
+		{privilege.DACReadSearch, privilege.DACReadSearch, ""},
+		{privilege.DACReadSearch, privilege.NonRoot, "capabilities.add: [DAC_READ_SEARCH]"},
+		{privilege.Root, privilege.Root, ""},
+		{privilege.Root, privilege.Elevated, "set runAsUser: 0"},
+		{"admin", privilege.Root, `invalid agent privilege "admin"`},
+	} {
+		setSetting(t, &settings.privilege, c.configured)
+		err := checkPrivilege(c.effective)
+		if c.want == "" && err != nil || c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)) {
+			t.Errorf("%s agent running as %q: expected %q, got %v", c.configured, c.effective, c.want, err)
+		}
+	}
+}
+
+func TestProcessPrivilege(t *testing.T) {
+	got := processPrivilege()
+	if got != "" && privilege.Validate(got) != nil && got != privilege.Elevated {
+		t.Errorf("unexpected privilege %q", got)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// maxReadRanges caps ReadRequest.ranges.
+const maxReadRanges = 64
+
+// readRanges serves a ReadRequest with ranges: each range is read in turn
+// into its own part. The lengths together may not exceed maxReadSize.
+func readRanges(req *api.ReadRequest, allowedRoots []string) (*api.ReadResponse, error) {
+	if len(req.Ranges) > maxReadRanges {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested %d ranges; at most %d are allowed", len(req.Ranges), maxReadRanges)
+	}
+	if req.Decompress {
+		return nil, status.Errorf(codes.InvalidArgument, "Ranges cannot be combined with decompress")
+	}
+	var total int64
+	for _, rg := range req.Ranges {
+		if rg.Length == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Each range needs a positive length")
+		}
+		total += rg.Length
+	}
+	if total > maxReadSize {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested ranges total %d bytes, exceeding the maximum allowed size of %d bytes", total, maxReadSize)
+	}
+
+	file, err := openFile(req.Path, allowedRoots)
+	if err != nil {
+		return nil, openError("Unable to open file '%s' for reading: %v", req.Path, err)
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+
+	resp := &api.ReadResponse{}
+	for _, rg := range req.Ranges {
+		offset := rg.Offset
+		if offset < 0 {
+			offset = max(0, info.Size()+offset)
+		}
+		data := make([]byte, rg.Length)
+		n, err := file.ReadAt(data, offset)
+		if err != nil && err != io.EOF {
+			return nil, status.Errorf(codes.Internal, "Unable to read file '%s': %v", req.Path, err)
+		}
+		resp.Parts = append(resp.Parts, &api.ReadPart{Offset: offset, Data: data[:n], Eof: int64(n) < rg.Length || err == io.EOF})
+	}
+	return resp, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestReadRanges(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(p, []byte("HEADER....middle....FOOTER"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{}
+	ctx := context.Background()
+	roots := []string{dir}
+
+	resp, err := s.ReadFile(ctx, &api.ReadRequest{Path: p, AllowedRoots: roots, Ranges: []*api.ByteRange{
+		{Offset: 0, Length: 6},
+		{Offset: -6, Length: 6},
+		{Offset: 10, Length: 6},
+		{Offset: 24, Length: 10},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		offset int64
+		data   string
+		eof    bool
+	}{{0, "HEADER", false}, {20, "FOOTER", false}, {10, "middle", false}, {24, "ER", true}}
+	if len(resp.Parts) != len(want) || len(resp.Data) != 0 {
+		t.Fatalf("expected %d parts and no data, got %v", len(want), resp)
+	}
+	for i, w := range want {
+		got := resp.Parts[i]
+		if got.Offset != w.offset || string(got.Data) != w.data || got.Eof != w.eof {
+			t.Errorf("part %d = {%d %q %t}; want {%d %q %t}", i, got.Offset, got.Data, got.Eof, w.offset, w.data, w.eof)
+		}
+	}
+	if n := servedBytes(resp); n != 20 {
+		t.Errorf("expected 20 bytes to be audited, got %d", n)
+	}
+
+	for name, ranges := range map[string][]*api.ByteRange{
+		"zero length":  {{Offset: 0, Length: 0}},
+		"over the max": {{Offset: 0, Length: maxReadSize}, {Offset: 0, Length: 1}},
+	} {
+		_, err := s.ReadFile(ctx, &api.ReadRequest{Path: p, AllowedRoots: roots, Ranges: ranges})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: expected InvalidArgument, got %v", name, err)
+		}
+	}
+}
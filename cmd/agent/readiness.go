@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// configRetryInterval is how often an invalid configuration is checked
+// again, e.g. while a certificate Secret is not mounted yet or the API
+// server is unreachable.
+var configRetryInterval = 10 * time.Second
+
+// configProblem explains why the configuration has not been applied yet;
+// it is empty once the agent is ready. Until then only Health,
+// Capabilities and Shutdown are served.
+var (
+	configMu      sync.Mutex
+	configProblem string
+)
+
+// healthServer is the standard gRPC health service, NOT_SERVING until the
+// configuration is applied.
+var healthServer = health.NewServer()
+
+func setConfigProblem(problem string) {
+	configMu.Lock()
+	configProblem = problem
+	configMu.Unlock()
+	st := healthpb.HealthCheckResponse_SERVING
+	if problem != "" {
+		st = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	healthServer.SetServingStatus("", st)
+	healthServer.SetServingStatus(api.PulsaarAgent_ServiceDesc.ServiceName, st)
+}
+
+func getConfigProblem() string {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return configProblem
+}
+
+// readyStatus reports whether the agent can serve requests, and why not.
+func readyStatus() (bool, string) {
+	if problem := getConfigProblem(); problem != "" {
+		return false, "Configuration invalid: " + problem
+	}
+	if n := stuckRPCs.Load(); n >= maxStuckRPCs {
+		return false, fmt.Sprintf("%d requests are stuck on an unresponsive filesystem", n)
+	}
+	return true, "Agent ready"
+}
+
+// agentConfig is what the settings refer to, loaded and checked.
+type agentConfig struct {
+	allowedRoots []string
+	tls          *tls.Config
+	auditTLS     *tls.Config
+	auditSpool   *auditSpool
+	auditFile    *os.File
+}
+
+// loadConfig loads the roots, certificates and audit settings, reporting
+// every problem at once so one fix makes the agent ready.
+func loadConfig() (*agentConfig, error) {
+	var problems []string
+	cfg := &agentConfig{}
+	if err := checkPrivilege(effectivePrivilege()); err != nil {
+		problems = append(problems, err.Error())
+	}
+	roots, err := loadAllowedRoots()
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	cfg.allowedRoots = roots
+
+	cert, err := loadOrGenerateCert()
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("failed to load the TLS certificate: %v", err))
+	}
+	pool, err := loadCACertPool()
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("failed to load the client CA: %v", err))
+	}
+	cfg.tls = serverTLSConfig(cert, pool)
+
+	if err := checkAuditAddrs(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	cfg.auditTLS, err = loadAuditClientTLSConfig()
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("invalid audit TLS settings: %v", err))
+	}
+	if dir := settings.auditSpoolDir; dir != "" && len(problems) == 0 {
+		cfg.auditSpool, err = openAuditSpool(dir, settings.auditSpoolMaxBytes)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("failed to open the audit spool: %v", err))
+		}
+	}
+	if path := settings.auditFile; path != "" && len(problems) == 0 {
+		cfg.auditFile, err = openAuditFile(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("failed to open the audit file: %v", err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return cfg, nil
+}
+
+// checkAuditAddrs makes sure audit events have somewhere to go, rather than
+// failing on every event once requests arrive.
+func checkAuditAddrs() error {
+	if addr := settings.auditGRPCAddr; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid --audit-grpc-addr %q: %v", addr, err)
+		}
+	}
+	if raw := settings.auditURL; raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid --audit-url %q: must be an http or https URL", raw)
+		}
+	}
+	if raw := settings.auditOTLPURL; raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid --audit-otlp-url %q: must be an http or https URL", raw)
+		}
+	}
+	if settings.auditFailClosed && settings.auditURL == "" && settings.auditGRPCAddr == "" &&
+		settings.auditOTLPURL == "" && settings.auditFile == "" && !settings.auditStdout {
+		return fmt.Errorf("--audit-fail-closed needs an audit output")
+	}
+	return nil
+}
+
+// serverTLSConfig serves cert and, when clientCAs is set, requires client
+// certificates signed by it.
+func serverTLSConfig(cert tls.Certificate, clientCAs *x509.CertPool) *tls.Config {
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2"},
+	}
+	if clientCAs != nil {
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
+
+// awaitConfig loads the configuration until it is valid, then applies it
+// and marks the agent ready.
+func awaitConfig(apply func(*agentConfig)) {
+	for {
+		cfg, err := loadConfig()
+		if err == nil {
+			apply(cfg)
+			setConfigProblem("")
+			infof("Configuration valid; agent ready")
+			return
+		}
+		setConfigProblem(err.Error())
+		warnf("Configuration invalid, retrying in %s: %v", configRetryInterval, err)
+		time.Sleep(configRetryInterval)
+	}
+}
+
+// servedWhileNotReady are the RPCs that report on or stop an agent whose
+// configuration is not applied yet.
+var servedWhileNotReady = map[string]bool{
+	api.PulsaarAgent_Health_FullMethodName:       true,
+	api.PulsaarAgent_Capabilities_FullMethodName: true,
+	api.PulsaarAgent_Shutdown_FullMethodName:     true,
+}
+
+func checkReady(method string) error {
+	if !strings.HasPrefix(method, "/"+api.PulsaarAgent_ServiceDesc.ServiceName+"/") || servedWhileNotReady[method] {
+		return nil
+	}
+	if problem := getConfigProblem(); problem != "" {
+		return status.Errorf(codes.Unavailable, "Agent is not ready: %s", problem)
+	}
+	return nil
+}
+
+func readyUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := checkReady(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func readyStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := checkReady(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// handleReadyz answers kubelet readiness probes, which cannot speak the
+// agent's TLS, on the metrics port.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ready, message := readyStatus()
+	if !ready {
+		http.Error(w, message, http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = fmt.Fprintln(w, message)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+func TestLoadConfig(t *testing.T) {
+	setSetting(t, &effectivePrivilege, func() string { return privilege.NonRoot })
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("expected the defaults to be valid, got %v", err)
+	}
+	if len(cfg.allowedRoots) != 1 || cfg.allowedRoots[0] != "/" {
+		t.Errorf("expected / without configured roots, got %v", cfg.allowedRoots)
+	}
+
+	setSetting(t, &settings.allowedRoots, []string{"/var/log", "tmp"})
+	setSetting(t, &settings.tlsCertFile, "/etc/pulsaar/tls.crt")
+	setSetting(t, &settings.auditURL, "aggregator:8080")
+	_, err = loadConfig()
+	if err == nil {
+		t.Fatal("expected an invalid configuration")
+	}
+	for _, want := range []string{`"tmp"`, "--tls-key-file", "--audit-url"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q among the problems, got %v", want, err)
+		}
+	}
+}
+
+func TestNotReady(t *testing.T) {
+	setConfigProblem("failed to load the TLS certificate")
+	t.Cleanup(func() { setConfigProblem("") })
+
+	if err := checkReady(api.PulsaarAgent_ReadFile_FullMethodName); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected reads to be refused, got %v", err)
+	}
+	for _, method := range []string{api.PulsaarAgent_Health_FullMethodName, "/grpc.health.v1.Health/Check"} {
+		if err := checkReady(method); err != nil {
+			t.Errorf("expected %s to be served, got %v", method, err)
+		}
+	}
+
+	resp, err := (&server{}).Health(context.Background(), &emptypb.Empty{})
+	if err != nil || resp.Ready || !strings.Contains(resp.StatusMessage, "TLS certificate") {
+		t.Errorf("expected Health to explain the problem, got %v, %v", resp, err)
+	}
+	check, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || check.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expected NOT_SERVING, got %v, %v", check, err)
+	}
+	rec := httptest.NewRecorder()
+	handleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected /readyz to fail, got %d", rec.Code)
+	}
+
+	setConfigProblem("")
+	if err := checkReady(api.PulsaarAgent_ReadFile_FullMethodName); err != nil {
+		t.Errorf("expected reads once ready, got %v", err)
+	}
+}
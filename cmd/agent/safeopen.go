@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errNotRegular   = errors.New("not a regular file")
+	errNotDir       = errors.New("not a directory")
+	errOutsideRoots = errors.New("resolves outside the allowed roots")
+)
+
+// openFile opens the regular file p for reading. The allowed-roots check on
+// a request path is lexical, so the open must not be redirected by a
+// symlink or a file swapped in after that check: p is opened without
+// following a final symlink and without blocking on a pipe, and the opened
+// descriptor itself must be a regular file whose real path lies within
+// allowedRoots. A final symlink, as in ConfigMap volumes, is resolved once
+// and its target opened the same way.
+func openFile(p string, allowedRoots []string) (*os.File, error) {
+	return openChecked(p, allowedRoots, false)
+}
+
+// openDir is openFile for directories.
+func openDir(p string, allowedRoots []string) (*os.File, error) {
+	return openChecked(p, allowedRoots, true)
+}
+
+func openChecked(p string, allowedRoots []string, dir bool) (*os.File, error) {
+	f, err := openNoFollow(p)
+	if isSymlinkErr(err) {
+		target, rerr := filepath.EvalSymlinks(p)
+		if rerr != nil {
+			return nil, rerr
+		}
+		if !isPathAllowed(target, allowedRoots) && !isPathAllowed(target, resolvedRoots(allowedRoots)) {
+			return nil, errOutsideRoots
+		}
+		f, err = openNoFollow(target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOpened(f, allowedRoots, dir); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// openNoFollow opens p read-only without following a final symlink or
+// blocking. In host and target mode, os.Root already keeps symlinks inside
+// the root; it follows them there.
+func openNoFollow(p string) (*os.File, error) {
+	root := fileRoot()
+	if root == nil {
+		return os.OpenFile(p, os.O_RDONLY|noFollowFlag|nonBlockFlag, 0)
+	}
+	return root.OpenFile(hostRelative(p), os.O_RDONLY|nonBlockFlag, 0)
+}
+
+// checkOpened checks the type and real location of an opened file. The
+// location is checked where the OS reports it, on Linux through /proc, and
+// not in target mode, whose paths are only meaningful inside the targeted
+// container.
+func checkOpened(f *os.File, allowedRoots []string, dir bool) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	switch {
+	case dir && !info.IsDir():
+		return errNotDir
+	case !dir && !info.Mode().IsRegular():
+		return errNotRegular
+	}
+	if targetRoot != nil {
+		return nil
+	}
+	real, ok := fdPath(f)
+	if !ok {
+		return nil
+	}
+	if hostRoot != nil {
+		prefix, err := filepath.EvalSymlinks(hostRoot.Name())
+		if err != nil {
+			return err
+		}
+		if prefix, err = filepath.Abs(prefix); err != nil {
+			return err
+		}
+		prefix = strings.TrimSuffix(prefix, "/")
+		if real != prefix && !strings.HasPrefix(real, prefix+"/") {
+			return errOutsideRoots
+		}
+		real = "/" + strings.TrimPrefix(strings.TrimPrefix(real, prefix), "/")
+	}
+	if !isPathAllowed(real, allowedRoots) && !isPathAllowed(real, resolvedRoots(allowedRoots)) {
+		return errOutsideRoots
+	}
+	return nil
+}
+
+// resolvedRoots returns allowedRoots with symlinks resolved, so a root that
+// is itself a symlink allows the files it leads to.
+func resolvedRoots(allowedRoots []string) []string {
+	if fileRoot() != nil {
+		return nil
+	}
+	var resolved []string
+	for _, root := range allowedRoots {
+		if r, err := filepath.EvalSymlinks(root); err == nil && r != root {
+			resolved = append(resolved, r)
+		}
+	}
+	return resolved
+}
+
+// openError converts an openFile or openDir error into a status, with msg,
+// a format taking the path and the error, for failures other than a
+// refused open.
+func openError(msg, p string, err error) error {
+	switch {
+	case errors.Is(err, errOutsideRoots):
+		return status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed: it resolves outside the allowed roots", p)
+	case errors.Is(err, errNotRegular):
+		return status.Errorf(codes.FailedPrecondition, "'%s' is not a regular file", p)
+	case errors.Is(err, errNotDir):
+		return status.Errorf(codes.FailedPrecondition, "'%s' is not a directory", p)
+	}
+	return status.Errorf(fileErrorCode(err), msg, p, err)
+}
+
+// fileErrorCode is the status code for a failed open or stat: NotFound
+// for a missing path, PermissionDenied when the agent may not read it, and
+// Internal otherwise.
+func fileErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return codes.NotFound
+	case errors.Is(err, os.ErrPermission):
+		return codes.PermissionDenied
+	}
+	return codes.Internal
+}
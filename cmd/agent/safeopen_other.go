@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+// Opens follow symlinks and may block where O_NOFOLLOW and O_NONBLOCK do
+// not exist; the checks on the opened file still apply.
+const (
+	noFollowFlag = 0
+	nonBlockFlag = 0
+)
+
+func isSymlinkErr(err error) bool {
+	return false
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	noFollowFlag = syscall.O_NOFOLLOW
+	nonBlockFlag = syscall.O_NONBLOCK
+)
+
+// isSymlinkErr reports whether an O_NOFOLLOW open failed because the path
+// is a symlink. FreeBSD reports EMLINK rather than ELOOP.
+func isSymlinkErr(err error) bool {
+	return errors.Is(err, syscall.ELOOP) || errors.Is(err, syscall.EMLINK)
+}
@@ -0,0 +1,85 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestSafeOpen(t *testing.T) {
+	base := t.TempDir()
+	allowed := filepath.Join(base, "app")
+	outside := filepath.Join(base, "secret")
+	for _, d := range []string{filepath.Join(allowed, "..data"), outside} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(allowed, "..data", "config.yaml"): "debug: true",
+		filepath.Join(outside, "token"):                 "hunter2",
+	}
+	for p, data := range files {
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"config.yaml": "..data/config.yaml", // as in ConfigMap volumes
+		"token":       outside + "/token",
+		"sub":         outside,
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(allowed, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := syscall.Mkfifo(filepath.Join(allowed, "fifo"), 0o644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+
+	s := &server{}
+	roots := []string{allowed}
+	read := func(p string) (*api.ReadResponse, error) {
+		return s.ReadFile(context.Background(), &api.ReadRequest{Path: filepath.Join(allowed, p), AllowedRoots: roots})
+	}
+	if resp, err := read("config.yaml"); err != nil || string(resp.Data) != "debug: true" {
+		t.Errorf("expected a symlink within the roots to be followed, got %v, %v", resp, err)
+	}
+	for _, p := range []string{"token", "sub/token"} {
+		if _, err := read(p); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: expected PermissionDenied, got %v", p, err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := read("fifo")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected FailedPrecondition for a pipe, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("opening a pipe blocked")
+	}
+	if _, err := read("."); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition for a directory, got %v", err)
+	}
+
+	if _, err := s.ListDirectory(context.Background(), &api.ListRequest{Path: filepath.Join(allowed, "sub"), AllowedRoots: roots}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected listing through a symlink out of the roots to be refused, got %v", err)
+	}
+}
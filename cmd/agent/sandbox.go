@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The sandbox layers in force and why it is incomplete, for Health.
+var (
+	sandboxMu      sync.Mutex
+	sandboxLayers  []string
+	sandboxMessage = "not applied until the agent is ready"
+)
+
+// sandboxSystemPaths are what the agent reads besides request paths once
+// it is sandboxed: name resolution, time zones, system CAs for audit
+// delivery, the ServiceAccount token, and its own /proc and cgroup entries
+// for Health, metrics and the Go runtime. Paths a node lacks are skipped.
+var sandboxSystemPaths = []string{
+	"/etc/hosts",
+	"/etc/resolv.conf",
+	"/etc/nsswitch.conf",
+	"/etc/services",
+	"/etc/localtime",
+	"/usr/share/zoneinfo",
+	"/etc/ssl",
+	"/etc/pki",
+	"/etc/ca-certificates",
+	"/var/run/secrets/kubernetes.io/serviceaccount",
+	"/proc/self",
+	"/proc/stat",
+	"/sys/fs/cgroup",
+}
+
+// sandboxPaths returns the paths the sandboxed agent may still read: the
+// allowed roots where requests resolve them and the system paths above.
+// The metrics token is reread for every scrape, so its directory is
+// included; a rotated Secret replaces the file.
+func sandboxPaths(roots []string) []string {
+	var paths []string
+	for _, root := range roots {
+		switch {
+		case hostRoot != nil:
+			paths = append(paths, filepath.Join(settings.hostRoot, root))
+		case targetRoot != nil:
+			paths = append(paths, filepath.Join(targetProcRoot, root))
+		default:
+			paths = append(paths, root)
+		}
+	}
+	if settings.metricsTokenFile != "" {
+		paths = append(paths, filepath.Dir(settings.metricsTokenFile))
+	}
+	return append(paths, sandboxSystemPaths...)
+}
+
+// applySandbox restricts the agent, once its configuration is applied and
+// its audit files are open, to reading roots and the paths it needs to
+// keep running. It is defense in depth beneath the path checks: a bug
+// that lets a request past them still cannot read elsewhere, write or
+// execute anything. Layers the kernel does not support are skipped with a
+// warning.
+func applySandbox(roots []string) {
+	if !settings.sandbox {
+		return
+	}
+	applied, err := sandboxProcess(sandboxPaths(roots))
+	message := ""
+	if err != nil {
+		message = err.Error()
+		warnf("Sandbox incomplete: %v", err)
+	}
+	if len(applied) > 0 {
+		infof("Sandboxed with %s", strings.Join(applied, " and "))
+	}
+	sandboxMu.Lock()
+	sandboxLayers, sandboxMessage = applied, message
+	sandboxMu.Unlock()
+}
+
+// sandboxStatus returns the sandbox layers in force and why the sandbox
+// is incomplete.
+func sandboxStatus() ([]string, string) {
+	if !settings.sandbox {
+		return nil, "turned off"
+	}
+	sandboxMu.Lock()
+	defer sandboxMu.Unlock()
+	return sandboxLayers, sandboxMessage
+}
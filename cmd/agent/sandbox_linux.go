@@ -0,0 +1,217 @@
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxProcess confines every thread of the agent with Landlock, to
+// reading paths, and with a seccomp filter that refuses execution and
+// syscalls that change files. It returns the layers it applied.
+func sandboxProcess(paths []string) ([]string, error) {
+	var applied, problems []string
+	if err := landlock(paths); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		applied = append(applied, "Landlock")
+	}
+	if err := seccomp(); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		applied = append(applied, "seccomp")
+	}
+	if len(problems) > 0 {
+		return applied, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return applied, nil
+}
+
+// landlockHandled returns the filesystem rights Landlock ABI version abi
+// can restrict. Handling all of them denies every write, and executing,
+// outside the rules.
+func landlockHandled(abi int) uint64 {
+	handled := uint64(unix.LANDLOCK_ACCESS_FS_MAKE_SYM<<1 - 1)
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		handled |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return handled
+}
+
+func landlock(paths []string) error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return fmt.Errorf("Landlock is not available: %v", errno)
+	}
+	attr := unix.LandlockRulesetAttr{Access_fs: landlockHandled(int(abi))}
+	ruleset, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create a Landlock ruleset: %v", errno)
+	}
+	defer func() { _ = unix.Close(int(ruleset)) }()
+
+	for _, p := range paths {
+		if err := landlockAllowRead(int(ruleset), p); err != nil {
+			return err
+		}
+	}
+
+	// Landlock applies per thread; restrict them all, which the Go runtime
+	// can only do without cgo.
+	if _, _, errno := syscall.AllThreadsSyscall6(unix.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0, 0); errno != 0 {
+		return landlockThreadsError(errno)
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, ruleset, 0, 0); errno != 0 {
+		return landlockThreadsError(errno)
+	}
+	return nil
+}
+
+// landlockAllowRead adds a rule reading p, and the files under it when it
+// is a directory. Paths that do not exist are skipped; they stay
+// unreadable if they appear later.
+func landlockAllowRead(ruleset int, p string) error {
+	fd, err := unix.Open(p, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open %s for the Landlock ruleset: %v", p, err)
+	}
+	defer func() { _ = unix.Close(fd) }()
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return fmt.Errorf("failed to stat %s for the Landlock ruleset: %v", p, err)
+	}
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: unix.LANDLOCK_ACCESS_FS_READ_FILE, Parent_fd: int32(fd)}
+	if st.Mode&unix.S_IFMT == unix.S_IFDIR {
+		rule.Allowed_access |= unix.LANDLOCK_ACCESS_FS_READ_DIR
+	}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to allow reading %s with Landlock: %v", p, errno)
+	}
+	return nil
+}
+
+func landlockThreadsError(errno syscall.Errno) error {
+	if errno == syscall.ENOTSUP {
+		return fmt.Errorf("Landlock needs an agent built with CGO_ENABLED=0 to restrict all threads")
+	}
+	return fmt.Errorf("failed to apply the Landlock ruleset: %v", errno)
+}
+
+// deniedSyscalls change files, mounts or other processes, or execute
+// programs. The agent makes none of them once it serves requests.
+var deniedSyscalls = []uintptr{
+	unix.SYS_EXECVE, unix.SYS_EXECVEAT,
+	unix.SYS_UNLINKAT, unix.SYS_RENAMEAT, unix.SYS_RENAMEAT2,
+	unix.SYS_MKDIRAT, unix.SYS_MKNODAT, unix.SYS_LINKAT, unix.SYS_SYMLINKAT,
+	unix.SYS_FCHMOD, unix.SYS_FCHMODAT, unix.SYS_FCHMODAT2,
+	unix.SYS_FCHOWN, unix.SYS_FCHOWNAT,
+	unix.SYS_TRUNCATE, unix.SYS_UTIMENSAT,
+	unix.SYS_SETXATTR, unix.SYS_LSETXATTR, unix.SYS_FSETXATTR, unix.SYS_SETXATTRAT,
+	unix.SYS_REMOVEXATTR, unix.SYS_LREMOVEXATTR, unix.SYS_FREMOVEXATTR, unix.SYS_REMOVEXATTRAT,
+	unix.SYS_MOUNT, unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_CHROOT,
+	// Opens files by handle rather than path, past Landlock, with
+	// CAP_DAC_READ_SEARCH.
+	unix.SYS_OPEN_BY_HANDLE_AT,
+	// Its requests are not seen by seccomp.
+	unix.SYS_IO_URING_SETUP,
+	unix.SYS_PTRACE,
+}
+
+// openSyscall is a syscall opening files, with the index of its flags
+// argument.
+type openSyscall struct {
+	nr       uintptr
+	flagsArg uint32
+}
+
+// writeOpenFlags open a file for writing or create it.
+const writeOpenFlags = unix.O_WRONLY | unix.O_RDWR | unix.O_CREAT | unix.O_TRUNC | unix.O_APPEND
+
+// Offsets into struct seccomp_data. Arguments are 64 bits; on little
+// endian architectures the lower half, holding open flags, comes first.
+const (
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArgs = 16
+)
+
+// seccompFilter returns the filter program: syscalls of other
+// architectures, the denied syscalls and opens for writing fail with
+// EPERM, and openat2, whose flags a filter cannot read, with ENOSYS so
+// callers fall back to openat.
+func seccompFilter() []unix.SockFilter {
+	deny := func(errno syscall.Errno) unix.SockFilter {
+		return bpfStmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|uint32(errno))
+	}
+	allow := bpfStmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW)
+	loadNr := bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNr)
+
+	prog := []unix.SockFilter{
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArch),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, seccompAuditArch, 1, 0),
+		deny(unix.EPERM),
+		loadNr,
+	}
+	if seccompForeignSyscalls != 0 {
+		prog = append(prog, bpfJump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, seccompForeignSyscalls, 0, 1), deny(unix.EPERM))
+	}
+	for _, nr := range append(deniedSyscalls, archDeniedSyscalls...) {
+		prog = append(prog, bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), 0, 1), deny(unix.EPERM))
+	}
+	prog = append(prog, bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_OPENAT2, 0, 1), deny(unix.ENOSYS))
+	for _, open := range append([]openSyscall{{unix.SYS_OPENAT, 2}}, archOpenSyscalls...) {
+		prog = append(prog,
+			bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(open.nr), 0, 4),
+			bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArgs+8*open.flagsArg),
+			bpfJump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, writeOpenFlags, 0, 1),
+			deny(unix.EPERM),
+			allow,
+		)
+	}
+	return append(prog, allow)
+}
+
+func bpfStmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// seccomp installs seccompFilter on every thread. Files already open for
+// writing, such as the audit file and spool, keep working.
+func seccomp() error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	// Synchronizing the filter sets no_new_privs on the other threads.
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %v", err)
+	}
+	prog := seccompFilter()
+	fprog := unix.SockFprog{Len: uint16(len(prog)), Filter: &prog[0]}
+	tid, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&fprog)))
+	switch {
+	case errno != 0:
+		return fmt.Errorf("failed to install the seccomp filter: %v", errno)
+	case tid != 0:
+		return fmt.Errorf("failed to install the seccomp filter: thread %d could not be synchronized", tid)
+	}
+	return nil
+}
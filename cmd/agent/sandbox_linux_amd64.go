@@ -0,0 +1,20 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+const seccompAuditArch = unix.AUDIT_ARCH_X86_64
+
+// seccompForeignSyscalls marks x32 syscalls, which share the x86-64 audit
+// architecture.
+const seccompForeignSyscalls = 0x40000000
+
+// archDeniedSyscalls are the older forms of deniedSyscalls that x86-64
+// still has.
+var archDeniedSyscalls = []uintptr{
+	unix.SYS_UNLINK, unix.SYS_RMDIR, unix.SYS_RENAME,
+	unix.SYS_MKDIR, unix.SYS_MKNOD, unix.SYS_LINK, unix.SYS_SYMLINK,
+	unix.SYS_CHMOD, unix.SYS_CHOWN, unix.SYS_LCHOWN, unix.SYS_CREAT,
+	unix.SYS_UTIME, unix.SYS_UTIMES, unix.SYS_FUTIMESAT,
+}
+
+var archOpenSyscalls = []openSyscall{{unix.SYS_OPEN, 1}}
@@ -0,0 +1,13 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+const seccompAuditArch = unix.AUDIT_ARCH_AARCH64
+
+// seccompForeignSyscalls is zero: arm64 has one syscall table.
+const seccompForeignSyscalls = 0
+
+// archDeniedSyscalls is empty: arm64 only has the *at forms.
+var archDeniedSyscalls []uintptr
+
+var archOpenSyscalls []openSyscall
@@ -0,0 +1,92 @@
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestHelperSandbox sandboxes a child test process, which cannot be undone,
+// and checks what it can still do.
+func TestHelperSandbox(t *testing.T) {
+	dir := os.Getenv("PULSAAR_SANDBOX_HELPER")
+	if dir == "" {
+		return
+	}
+	fail := func(format string, args ...any) {
+		fmt.Printf(format+"\n", args...)
+		os.Exit(1)
+	}
+	audit, err := os.OpenFile(filepath.Join(dir, "audit.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		fail("%v", err)
+	}
+	applied, err := sandboxProcess([]string{filepath.Join(dir, "allowed"), filepath.Join(dir, "missing")})
+	fmt.Printf("applied %s: %v\n", strings.Join(applied, ","), err)
+	if !slices.Contains(applied, "seccomp") {
+		fail("expected the seccomp filter to be installed")
+	}
+
+	if _, err := os.ReadFile(filepath.Join(dir, "allowed", "app.log")); err != nil {
+		fail("expected an allowed root to stay readable: %v", err)
+	}
+	if _, err := os.ReadDir(filepath.Join(dir, "allowed")); err != nil {
+		fail("expected an allowed root to stay listable: %v", err)
+	}
+	if slices.Contains(applied, "Landlock") {
+		if _, err := os.ReadFile(filepath.Join(dir, "secret")); err == nil {
+			fail("expected a file outside the allowed roots to be unreadable")
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "allowed", "new"), nil, 0o600); err == nil {
+		fail("expected creating a file to fail")
+	}
+	if f, err := os.OpenFile(filepath.Join(dir, "allowed", "app.log"), os.O_WRONLY, 0); err == nil {
+		_ = f.Close()
+		fail("expected opening a file for writing to fail")
+	}
+	if err := os.Remove(filepath.Join(dir, "allowed", "app.log")); err == nil {
+		fail("expected removing a file to fail")
+	}
+	if err := exec.Command(os.Args[0], "-test.run=^$").Run(); err == nil {
+		fail("expected executing a program to fail")
+	}
+	if _, err := audit.WriteString("event\n"); err != nil {
+		fail("expected a file opened before sandboxing to stay writable: %v", err)
+	}
+	os.Exit(0)
+}
+
+func TestSandboxProcess(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "allowed"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"allowed/app.log", "secret"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperSandbox$")
+	cmd.Env = append(os.Environ(), "PULSAAR_SANDBOX_HELPER="+dir)
+	out, err := cmd.CombinedOutput()
+	if strings.Contains(string(out), "failed to set no_new_privs") || strings.Contains(string(out), "failed to install the seccomp filter") {
+		t.Skipf("cannot sandbox here: %s", out)
+	}
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	t.Logf("%s", out)
+	if data, err := os.ReadFile(filepath.Join(dir, "audit.log")); err != nil || string(data) != "event\n" {
+		t.Errorf("expected the audit file to be written, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "allowed", "app.log")); err != nil {
+		t.Errorf("expected the file to survive: %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux || !(amd64 || arm64)
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// sandboxProcess is only implemented for Linux on amd64 and arm64.
+func sandboxProcess([]string) ([]string, error) {
+	return nil, fmt.Errorf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSandboxPaths(t *testing.T) {
+	setSetting(t, &settings.metricsTokenFile, "/etc/pulsaar/metrics/token")
+	paths := sandboxPaths([]string{"/var/log", "/app"})
+	if paths[0] != "/var/log" || paths[1] != "/app" || paths[2] != "/etc/pulsaar/metrics" || !slices.Contains(paths, "/proc/self") {
+		t.Errorf("unexpected paths %v", paths)
+	}
+}
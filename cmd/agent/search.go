@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// Search limits keep a single request from scanning a whole volume.
+const (
+	defaultSearchMatches       = 100
+	maxSearchMatches           = 1000
+	maxSearchBytes       int64 = 64 * 1024 * 1024
+	maxSearchFiles             = 10000
+	maxSearchLineText          = 512
+)
+
+var errSearchLimit = errors.New("search limit reached")
+
+// searcher walks a directory tree with the agent's file helpers, so host
+// mode applies, and records matching lines. Only regular files are read;
+// symlinks, pipes, sockets and devices are never opened.
+type searcher struct {
+	re         *regexp.Regexp
+	roots      []string
+	maxMatches int
+	scanned    int64
+	files      int64
+	matches    []*api.SearchMatch
+	truncated  bool
+}
+
+func (s *searcher) walk(ctx context.Context, p string, isDir bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !isDir {
+		return s.searchFile(p)
+	}
+	entries, err := readDir(p, s.roots)
+	if err != nil {
+		return nil // unreadable directories are skipped
+	}
+	for _, entry := range entries {
+		child := path.Join(p, entry.Name())
+		switch {
+		case entry.IsDir():
+			err = s.walk(ctx, child, true)
+		case entry.Type().IsRegular():
+			err = s.walk(ctx, child, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *searcher) searchFile(p string) error {
+	if s.files >= maxSearchFiles || s.scanned >= maxSearchBytes {
+		s.truncated = true
+		return errSearchLimit
+	}
+	f, err := openFile(p, s.roots)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+	s.files++
+
+	r := bufio.NewReader(io.LimitReader(f, maxSearchBytes-s.scanned))
+	if head, _ := r.Peek(512); bytes.IndexByte(head, 0) >= 0 {
+		return nil // binary
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), int(maxReadSize))
+	var line int64
+	for scanner.Scan() {
+		line++
+		text := scanner.Bytes()
+		s.scanned += int64(len(text)) + 1
+		if !s.re.Match(text) {
+			continue
+		}
+		if len(s.matches) >= s.maxMatches {
+			s.truncated = true
+			return errSearchLimit
+		}
+		if len(text) > maxSearchLineText {
+			text = text[:maxSearchLineText]
+		}
+		s.matches = append(s.matches, &api.SearchMatch{Path: p, Line: line, Text: string(text)})
+	}
+	return nil
+}
+
+func (s *server) Search(ctx context.Context, req *api.SearchRequest) (*api.SearchResponse, error) {
+	if !getLimiterForIP(ctx).Allow() {
+		return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
+	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
+	if !isPathAllowed(req.Path, allowedRoots) {
+		return nil, status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", req.Path, allowedRoots)
+	}
+	if req.Pattern == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "A search pattern is required.")
+	}
+	re, err := regexp.Compile(req.Pattern)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid search pattern: %v", err)
+	}
+	maxMatches := int(req.MaxMatches)
+	if maxMatches <= 0 {
+		maxMatches = defaultSearchMatches
+	}
+	if maxMatches > maxSearchMatches {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested max matches (%d) exceeds the maximum of %d", maxMatches, maxSearchMatches)
+	}
+
+	info, err := statFile(req.Path)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+	sr := &searcher{re: re, roots: allowedRoots, maxMatches: maxMatches}
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		// Pipes, sockets and devices are skipped as they are in a walk.
+		return &api.SearchResponse{}, nil
+	}
+	if err := sr.walk(ctx, path.Clean(req.Path), info.IsDir()); err != nil && !errors.Is(err, errSearchLimit) {
+		return nil, status.FromContextError(err).Err()
+	}
+	return &api.SearchResponse{Matches: sr.matches, Truncated: sr.truncated, FilesSearched: sr.files}, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func writeSearchFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.log":          "start\nOOMKilled: worker 1\nok\n",
+		"old/app.log.1":    "OOMKilled: worker 2\n",
+		"data.bin":         "OOMKilled\x00binary",
+		"other/notes.txt":  "nothing here\n",
+		"old/long.log":     strings.Repeat("x", 2000) + " OOMKilled\n",
+		"old/empty.log":    "",
+		"other/README.txt": "oomkilled in lower case\n",
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("/etc", filepath.Join(dir, "etc-link")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSearch(t *testing.T) {
+	dir := writeSearchFixture(t)
+	s := &server{}
+	ctx := context.Background()
+
+	resp, err := s.Search(ctx, &api.SearchRequest{Path: dir, Pattern: "OOMKilled", AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Matches) != 3 || resp.Truncated {
+		t.Fatalf("expected 3 matches, got %v (truncated=%t)", resp.Matches, resp.Truncated)
+	}
+	first := resp.Matches[0]
+	if first.Path != filepath.Join(dir, "app.log") || first.Line != 2 || first.Text != "OOMKilled: worker 1" {
+		t.Errorf("unexpected first match: %v", first)
+	}
+	for _, m := range resp.Matches {
+		if len(m.Text) > maxSearchLineText {
+			t.Errorf("expected long lines to be cut to %d bytes, got %d", maxSearchLineText, len(m.Text))
+		}
+	}
+
+	resp, err = s.Search(ctx, &api.SearchRequest{Path: dir, Pattern: "(?i)oomkilled", MaxMatches: 2, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Matches) != 2 || !resp.Truncated {
+		t.Errorf("expected 2 matches and truncation, got %d (truncated=%t)", len(resp.Matches), resp.Truncated)
+	}
+
+	resp, err = s.Search(ctx, &api.SearchRequest{Path: filepath.Join(dir, "app.log"), Pattern: "^ok$", AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Matches) != 1 || resp.FilesSearched != 1 {
+		t.Errorf("expected one match in a single file, got %v", resp.Matches)
+	}
+}
+
+func TestSearchRejectsBadRequests(t *testing.T) {
+	dir := writeSearchFixture(t)
+	s := &server{}
+	ctx := context.Background()
+
+	tests := []struct {
+		req  *api.SearchRequest
+		code codes.Code
+	}{
+		{&api.SearchRequest{Path: "/etc", Pattern: "root", AllowedRoots: []string{dir}}, codes.PermissionDenied},
+		{&api.SearchRequest{Path: dir, AllowedRoots: []string{dir}}, codes.InvalidArgument},
+		{&api.SearchRequest{Path: dir, Pattern: "(", AllowedRoots: []string{dir}}, codes.InvalidArgument},
+		{&api.SearchRequest{Path: dir, Pattern: "x", MaxMatches: maxSearchMatches + 1, AllowedRoots: []string{dir}}, codes.InvalidArgument},
+		{&api.SearchRequest{Path: filepath.Join(dir, "missing"), Pattern: "x", AllowedRoots: []string{dir}}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.Search(ctx, tt.req); status.Code(err) != tt.code {
+			t.Errorf("Search(%v): expected %s, got %v", tt.req, tt.code, err)
+		}
+	}
+}
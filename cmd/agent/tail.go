@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+const (
+	// tailBatchSize is how much matching output is sent per message.
+	tailBatchSize = 32 * 1024
+	// tailScanWindow bounds each read, and the lines inspected when looking
+	// for a timestamp.
+	tailScanWindow = 64 * 1024
+)
+
+// defaultTailPollInterval is how often a followed file is checked for new
+// data unless the server sets its own interval.
+const defaultTailPollInterval = 500 * time.Millisecond
+
+// logTimeLayouts are timestamp prefixes recognised for --since, with the
+// length of the prefix each one parses.
+var logTimeLayouts = []struct {
+	layout string
+	length int
+}{
+	{"2006-01-02T15:04:05.999999999Z07:00", 0}, // RFC 3339, up to the first space
+	{"2006-01-02 15:04:05", 19},
+	{"2006-01-02T15:04:05", 19},
+	{"2006/01/02 15:04:05", 19},        // Go log package
+	{"02/Jan/2006:15:04:05 -0700", 26}, // common log format
+	{"Jan _2 15:04:05", 15},            // syslog, no year
+}
+
+// parseLogTime reads the timestamp at the start of line, after an optional
+// '['. Times without a zone are taken as local time.
+func parseLogTime(line []byte, now time.Time) (time.Time, bool) {
+	line = bytes.TrimPrefix(line, []byte("["))
+	for _, l := range logTimeLayouts {
+		prefix := line
+		if l.length == 0 {
+			if i := bytes.IndexAny(line, " ]\t"); i >= 0 {
+				prefix = line[:i]
+			}
+		} else if len(line) >= l.length {
+			prefix = line[:l.length]
+		} else {
+			continue
+		}
+		t, err := time.ParseInLocation(l.layout, string(prefix), time.Local)
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// lineStart returns the offset of the first line starting at or after off.
+func lineStart(f io.ReaderAt, size, off int64) int64 {
+	if off == 0 {
+		return 0
+	}
+	buf := make([]byte, tailScanWindow)
+	for pos := off - 1; pos < size; pos += int64(len(buf)) {
+		n, _ := f.ReadAt(buf, pos)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return pos + int64(i) + 1
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return size
+}
+
+// timeAfter returns the timestamp of the first timestamped line starting at
+// or after off, looking no further than tailScanWindow.
+func timeAfter(f io.ReaderAt, size, off int64, now time.Time) (time.Time, bool) {
+	start := lineStart(f, size, off)
+	buf := make([]byte, min(tailScanWindow, size-start))
+	n, _ := f.ReadAt(buf, start)
+	for _, line := range bytes.Split(buf[:n], []byte("\n")) {
+		if t, ok := parseLogTime(line, now); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// findSinceOffset binary-searches a log with ascending timestamps for the
+// first line at or after since. The result is approximate when lines are
+// out of order or some lack timestamps.
+func findSinceOffset(f io.ReaderAt, size int64, since, now time.Time) (int64, error) {
+	if _, ok := timeAfter(f, size, 0, now); !ok {
+		return 0, status.Errorf(codes.FailedPrecondition, "No recognised timestamps at the start of the file; --since needs timestamped log lines")
+	}
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if t, ok := timeAfter(f, size, mid, now); ok && !t.Before(since) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lineStart(f, size, lo), nil
+}
+
+// maxTailFiles caps how many files one TailFile call follows.
+const maxTailFiles = 32
+
+// TailFile streams lines of a log, optionally starting at a time window and
+// keeping only lines that match a pattern, then follows appended data until
+// the client cancels. A file that shrinks is read again from the start.
+// Each message reports the offset it read up to, so a client whose stream
+// broke can resume there.
+//
+// With several paths, or glob patterns, each message carries the path it
+// came from. Globs are matched again at every poll, and files that appear
+// later are read from the start.
+func (s *server) TailFile(req *api.TailRequest, stream api.PulsaarAgent_TailFileServer) error {
+	ctx := stream.Context()
+	if !getLimiterForIP(ctx).Allow() {
+		return status.Errorf(codes.ResourceExhausted, "Rate limit exceeded. Please wait before retrying.")
+	}
+	patterns := req.Paths
+	if req.Path != "" {
+		patterns = append([]string{req.Path}, patterns...)
+	}
+	if len(patterns) == 0 {
+		return status.Errorf(codes.InvalidArgument, "No path to tail")
+	}
+	allowedRoots := effectiveRoots(req.AllowedRoots)
+	multi := len(patterns) > 1
+	for _, p := range patterns {
+		if !isPathAllowed(p, allowedRoots) {
+			return status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", p, allowedRoots)
+		}
+		if isGlob(p) {
+			multi = true
+		}
+	}
+	if req.SinceSeconds < 0 {
+		return status.Errorf(codes.InvalidArgument, "since_seconds must not be negative")
+	}
+	for p, off := range req.ResumeOffsets {
+		if off < 0 {
+			return status.Errorf(codes.InvalidArgument, "resume offset for '%s' must not be negative", p)
+		}
+	}
+	var re *regexp.Regexp
+	if req.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(req.Pattern); err != nil {
+			return status.Errorf(codes.InvalidArgument, "Invalid tail pattern: %v", err)
+		}
+	}
+
+	paths, err := expandTailPaths(patterns, allowedRoots)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return status.Errorf(codes.NotFound, "No files match %s", strings.Join(patterns, ", "))
+	}
+	tailers := make(map[string]*tailer)
+	var order []string
+	for _, p := range paths {
+		offset, err := tailStart(p, allowedRoots, req, multi)
+		if err != nil {
+			return err
+		}
+		tailers[p] = &tailer{path: p, roots: allowedRoots, re: re, stream: stream, offset: offset, tagged: multi}
+		order = append(order, p)
+	}
+
+	for {
+		for _, p := range order {
+			t := tailers[p]
+			if err := t.readAvailable(!req.Follow); err != nil {
+				// A matched file may be deleted while it is followed.
+				if multi && status.Code(err) == codes.NotFound {
+					delete(tailers, p)
+					continue
+				}
+				return err
+			}
+		}
+		if !req.Follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.pollInterval()):
+		}
+		if multi {
+			paths, err := expandTailPaths(patterns, allowedRoots)
+			if err != nil {
+				return err
+			}
+			order = order[:0]
+			for _, p := range paths {
+				if tailers[p] == nil {
+					tailers[p] = &tailer{path: p, roots: allowedRoots, re: re, stream: stream, offset: req.ResumeOffsets[p], tagged: true}
+				}
+				order = append(order, p)
+			}
+		}
+	}
+}
+
+func (s *server) pollInterval() time.Duration {
+	if s.tailPollInterval > 0 {
+		return s.tailPollInterval
+	}
+	return defaultTailPollInterval
+}
+
+// tailStart returns the offset a tail of p begins at: where a resumed tail
+// left off, or else where the request asks. With tagged set, errors name
+// the file.
+func tailStart(p string, allowedRoots []string, req *api.TailRequest, tagged bool) (int64, error) {
+	if off, ok := req.ResumeOffsets[p]; ok {
+		// readAvailable starts again from 0 if the file has since shrunk.
+		return off, nil
+	}
+	file, err := openFile(p, allowedRoots)
+	if err != nil {
+		return 0, openError("Unable to open file '%s' for tailing: %v", p, err)
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", p, err)
+	}
+	switch {
+	case req.SinceSeconds > 0:
+		now := time.Now()
+		offset, err := findSinceOffset(file, info.Size(), now.Add(-time.Duration(req.SinceSeconds)*time.Second), now)
+		if err != nil && tagged {
+			return 0, status.Errorf(status.Code(err), "%s: %s", p, status.Convert(err).Message())
+		}
+		return offset, err
+	case req.FromStart:
+		return 0, nil
+	}
+	return info.Size(), nil
+}
+
+func isGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// expandTailPaths returns the allowed regular files matching patterns, in
+// order and without duplicates. Paths without glob characters are returned
+// as they are, so a missing file is reported when it is opened.
+func expandTailPaths(patterns, allowedRoots []string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if isGlob(pattern) {
+			var err error
+			if matches, err = globFiles(pattern); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid glob pattern '%s': %v", pattern, err)
+			}
+		}
+		for _, m := range matches {
+			if seen[m] || !isPathAllowed(m, allowedRoots) {
+				continue
+			}
+			if isGlob(pattern) {
+				if info, err := statFile(m); err != nil || !info.Mode().IsRegular() {
+					continue
+				}
+			}
+			seen[m] = true
+			paths = append(paths, m)
+		}
+	}
+	if len(paths) > maxTailFiles {
+		return nil, status.Errorf(codes.InvalidArgument, "%d files match; at most %d can be tailed at once", len(paths), maxTailFiles)
+	}
+	return paths, nil
+}
+
+// tailer reads whole lines from offset and sends the ones that match. When
+// tagged, messages carry the path.
+type tailer struct {
+	path   string
+	roots  []string
+	re     *regexp.Regexp
+	stream api.PulsaarAgent_TailFileServer
+	offset int64
+	batch  []byte
+	tagged bool
+}
+
+// readAvailable sends everything up to the last complete line. With final
+// set, a trailing partial line is sent too.
+func (t *tailer) readAvailable(final bool) error {
+	file, err := openFile(t.path, t.roots)
+	if err != nil {
+		return openError("Unable to open file '%s' for tailing: %v", t.path, err)
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", t.path, err)
+	}
+	if info.Size() < t.offset {
+		t.offset = 0
+	}
+
+	buf := make([]byte, tailScanWindow)
+	for t.offset < info.Size() {
+		n, err := file.ReadAt(buf, t.offset)
+		if err != nil && err != io.EOF {
+			return status.Errorf(codes.Internal, "Unable to read file '%s' during tailing: %v", t.path, err)
+		}
+		data := buf[:n]
+		end := bytes.LastIndexByte(data, '\n') + 1
+		switch {
+		case end > 0:
+			data = data[:end]
+		case n == len(buf) || final:
+			// A line longer than the window, or the last line of the file.
+		default:
+			return t.flush()
+		}
+		for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+			if len(line) > 0 && (t.re == nil || t.re.Match(bytes.TrimSuffix(line, []byte("\n")))) {
+				t.batch = append(t.batch, line...)
+			}
+		}
+		t.offset += int64(len(data))
+		if len(t.batch) >= tailBatchSize {
+			if err := t.flush(); err != nil {
+				return err
+			}
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return t.flush()
+}
+
+func (t *tailer) flush() error {
+	if len(t.batch) == 0 {
+		return nil
+	}
+	resp := &api.ReadResponse{Data: t.batch, Offset: t.offset}
+	if t.tagged {
+		resp.Path = t.path
+	}
+	err := t.stream.Send(resp)
+	t.batch = nil
+	return err
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	pulsaartesting "github.com/VrushankPatel/pulsaar/pkg/testing"
+)
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{"2026-03-01T11:50:00Z INFO ready", time.Date(2026, 3, 1, 11, 50, 0, 0, time.UTC), true},
+		{"2026-03-01T11:50:00.123+01:00 ready", time.Date(2026, 3, 1, 11, 50, 0, 123000000, time.FixedZone("", 3600)), true},
+		{"2026-03-01 11:50:00,123 ERROR boom", time.Date(2026, 3, 1, 11, 50, 0, 0, time.Local), true},
+		{"2026/03/01 11:50:00 listening", time.Date(2026, 3, 1, 11, 50, 0, 0, time.Local), true},
+		{"[2026-03-01T11:50:00Z] started", time.Date(2026, 3, 1, 11, 50, 0, 0, time.UTC), true},
+		{"[01/Mar/2026:11:50:00 +0000] GET /", time.Date(2026, 3, 1, 11, 50, 0, 0, time.UTC), true},
+		{"Mar  1 11:50:00 host sshd[1]: ok", time.Date(2026, 3, 1, 11, 50, 0, 0, time.Local), true},
+		{"Dec 31 23:00:00 host cron: ran", time.Date(2025, 12, 31, 23, 0, 0, 0, time.Local), true},
+		{"panic: runtime error", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLogTime([]byte(tt.line), now)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseLogTime(%q) = %v, %t; want %v, %t", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// minuteLog has one line a minute for the hour before now, with a few
+// untimestamped continuation lines.
+func minuteLog(now time.Time) []byte {
+	var b bytes.Buffer
+	for i := 60; i > 0; i-- {
+		fmt.Fprintf(&b, "%s INFO tick %d\n", now.Add(-time.Duration(i)*time.Minute).Format(time.RFC3339), i)
+		if i%7 == 0 {
+			b.WriteString("  continuation line\n")
+		}
+	}
+	return b.Bytes()
+}
+
+func TestFindSinceOffset(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	data := minuteLog(now)
+	r := bytes.NewReader(data)
+
+	off, err := findSinceOffset(r, int64(len(data)), now.Add(-10*time.Minute), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data[off:], []byte(now.Add(-10*time.Minute).Format(time.RFC3339)+" INFO tick 10\n")) {
+		t.Errorf("expected the tick 10 line, got %q", data[off:min(off+40, int64(len(data)))])
+	}
+
+	if off, _ := findSinceOffset(r, int64(len(data)), now.Add(-2*time.Hour), now); off != 0 {
+		t.Errorf("expected a window before the file to start at 0, got %d", off)
+	}
+	if off, _ := findSinceOffset(r, int64(len(data)), now.Add(time.Minute), now); off != int64(len(data)) {
+		t.Errorf("expected a window after the file to start at its end, got %d", off)
+	}
+
+	plain := []byte("no timestamps\nhere\n")
+	if _, err := findSinceOffset(bytes.NewReader(plain), int64(len(plain)), now, now); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition without timestamps, got %v", err)
+	}
+}
+
+func tailClient(t *testing.T) api.PulsaarAgentClient {
+	t.Helper()
+	return serveClient(t, &server{})
+}
+
+// serveClient serves s in process and returns a client connected to it.
+func serveClient(t *testing.T, s *server) api.PulsaarAgentClient {
+	t.Helper()
+	// In-process connections share one rate limiter; keep tests within it.
+	limiters.Store("bufconn", rate.NewLimiter(rate.Inf, 1))
+	t.Cleanup(func() { limiters.Delete("bufconn") })
+	srv := pulsaartesting.Serve(s)
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return api.NewPulsaarAgentClient(conn)
+}
+
+func receiveAll(t *testing.T, stream api.PulsaarAgent_TailFileClient) string {
+	t.Helper()
+	var out strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return out.String()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Write(resp.Data)
+	}
+}
+
+func TestTailFileSinceAndPattern(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	data := append(minuteLog(now), []byte(now.Format(time.RFC3339)+" ERROR disk full\n")...)
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := tailClient(t)
+
+	stream, err := c.TailFile(context.Background(), &api.TailRequest{Path: p, SinceSeconds: 210, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, stream); strings.Count(got, "\n") != 4 || !strings.Contains(got, "tick 3\n") || strings.Contains(got, "tick 4\n") {
+		t.Errorf("expected the last three and a half minutes, got:\n%s", got)
+	}
+
+	stream, err = c.TailFile(context.Background(), &api.TailRequest{Path: p, FromStart: true, Pattern: "ERROR|tick 5\\b", AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, stream); !strings.HasSuffix(got, "INFO tick 5\n"+now.Format(time.RFC3339)+" ERROR disk full\n") || strings.Count(got, "\n") != 2 {
+		t.Errorf("expected only matching lines, got:\n%s", got)
+	}
+
+	stream, err = c.TailFile(context.Background(), &api.TailRequest{Path: p, Pattern: "(", AllowedRoots: []string{dir}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a bad pattern, got %v", err)
+	}
+}
+
+func TestTailFileFollow(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(p, []byte("old ERROR\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := serveClient(t, &server{tailPollInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.TailFile(ctx, &api.TailRequest{Path: p, Follow: true, Pattern: "ERROR", AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	time.Sleep(50 * time.Millisecond)
+	// The partial line is held back until its newline arrives.
+	_, _ = f.WriteString("INFO skip\nnew ERR")
+	time.Sleep(50 * time.Millisecond)
+	_, _ = f.WriteString("OR one\n")
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Data) != "new ERROR one\n" {
+		t.Errorf("expected only the new matching line, got %q", resp.Data)
+	}
+}
+
+func TestTailFileResume(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(p, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := tailClient(t)
+	stream, err := c.TailFile(context.Background(), &api.TailRequest{Path: p, FromStart: true, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Offset != 8 {
+		t.Fatalf("expected the message to report offset 8, got %d", resp.Offset)
+	}
+
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("three\n")
+	_ = f.Close()
+	stream, err = c.TailFile(context.Background(), &api.TailRequest{Path: p, FromStart: true, ResumeOffsets: map[string]int64{p: resp.Offset}, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, stream); got != "three\n" {
+		t.Errorf("expected to resume after the data already sent, got %q", got)
+	}
+
+	// An offset past the end means the file was replaced.
+	stream, err = c.TailFile(context.Background(), &api.TailRequest{Path: p, ResumeOffsets: map[string]int64{p: 100}, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, stream); got != "one\ntwo\nthree\n" {
+		t.Errorf("expected a shrunken file to be read from the start, got %q", got)
+	}
+}
+
+func TestTailFileGlob(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"api.log": "api started\n", "worker.log": "worker started\n", "notes.txt": "not a log\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "old.log"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := serveClient(t, &server{tailPollInterval: 10 * time.Millisecond})
+
+	stream, err := c.TailFile(context.Background(), &api.TailRequest{Paths: []string{filepath.Join(dir, "*.log"), filepath.Join(dir, "notes.txt")}, FromStart: true, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.Base(resp.Path)+": "+string(resp.Data))
+	}
+	if want := []string{"api.log: api started\n", "worker.log: worker started\n", "notes.txt: not a log\n"}; strings.Join(got, "") != strings.Join(want, "") {
+		t.Errorf("expected each file tagged with its path, got %q", got)
+	}
+
+	// Files that start matching while following are read from the start.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err = c.TailFile(ctx, &api.TailRequest{Paths: []string{filepath.Join(dir, "*.log")}, Follow: true, AllowedRoots: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "cron.log"), []byte("cron ran\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(resp.Path) != "cron.log" || string(resp.Data) != "cron ran\n" {
+		t.Errorf("expected the new file, got %s: %q", resp.Path, resp.Data)
+	}
+}
+
+func TestTailFilePathsErrors(t *testing.T) {
+	dir := t.TempDir()
+	c := tailClient(t)
+	for _, tt := range []struct {
+		paths []string
+		code  codes.Code
+	}{
+		{nil, codes.InvalidArgument},
+		{[]string{filepath.Join(dir, "*.log")}, codes.NotFound},
+		{[]string{filepath.Join(dir, "[.log")}, codes.InvalidArgument},
+		{[]string{filepath.Join(dir, "*.log"), "/etc/*"}, codes.PermissionDenied},
+	} {
+		stream, err := c.TailFile(context.Background(), &api.TailRequest{Paths: tt.paths, AllowedRoots: []string{dir}})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != tt.code {
+			t.Errorf("%v: expected %v, got %v", tt.paths, tt.code, err)
+		}
+	}
+
+	for i := 0; i <= maxTailFiles; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%02d.log", i)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream, err := c.TailFile(context.Background(), &api.TailRequest{Paths: []string{filepath.Join(dir, "*.log")}, AllowedRoots: []string{dir}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "at most 32") {
+		t.Errorf("expected too many files to be refused, got %v", err)
+	}
+}
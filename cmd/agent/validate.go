@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// maxPathLength is the longest path a request may carry, Linux's PATH_MAX.
+const maxPathLength = 4096
+
+// violations collects what is wrong with a request, one entry per field,
+// and becomes an InvalidArgument status with a BadRequest detail so
+// clients can tell which field to fix.
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field, format string, args ...any) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Description: fmt.Sprintf(format, args...)})
+}
+
+// path checks a path before it reaches the OS: it must be absolute, short
+// enough, and free of NUL bytes, which would otherwise truncate it.
+func (v *violations) path(field, p string) {
+	switch {
+	case p == "":
+		v.add(field, "path is required")
+	case strings.ContainsRune(p, 0):
+		v.add(field, "path contains a NUL byte")
+	case len(p) > maxPathLength:
+		v.add(field, "path is %d bytes long; the maximum is %d", len(p), maxPathLength)
+	case !filepath.IsAbs(p):
+		v.add(field, "path '%s' is not absolute", p)
+	}
+}
+
+// roots checks requested allowed roots, which are only compared with
+// paths, for the same corruption as a path.
+func (v *violations) roots(roots []string) {
+	for i, root := range roots {
+		field := fmt.Sprintf("allowed_roots[%d]", i)
+		switch {
+		case strings.ContainsRune(root, 0):
+			v.add(field, "path contains a NUL byte")
+		case len(root) > maxPathLength:
+			v.add(field, "path is %d bytes long; the maximum is %d", len(root), maxPathLength)
+		}
+	}
+}
+
+func (v *violations) nonNegative(field string, n int64) {
+	if n < 0 {
+		v.add(field, "must not be negative, got %d", n)
+	}
+}
+
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	descs := make([]string, len(v))
+	for i, fv := range v {
+		descs[i] = fv.Field + ": " + fv.Description
+	}
+	st := status.Newf(codes.InvalidArgument, "Invalid request: %s", strings.Join(descs, "; "))
+	if withDetails, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// validateRequest checks the fields every handler would otherwise pass to
+// the OS unchecked. Handler-specific limits, such as maxReadSize, stay in
+// the handlers.
+func validateRequest(req any) error {
+	var v violations
+	switch r := req.(type) {
+	case *api.ListRequest:
+		v.path("path", r.Path)
+		v.roots(r.AllowedRoots)
+		v.nonNegative("page_size", int64(r.PageSize))
+		v.nonNegative("min_size", r.MinSize)
+	case *api.StatRequest:
+		v.path("path", r.Path)
+		v.roots(r.AllowedRoots)
+	case *api.ReadRequest:
+		v.path("path", r.Path)
+		v.roots(r.AllowedRoots)
+		v.nonNegative("offset", r.Offset)
+		v.nonNegative("length", r.Length)
+		for i, rg := range r.Ranges {
+			v.nonNegative(fmt.Sprintf("ranges[%d].length", i), rg.Length)
+		}
+	case *api.StreamRequest:
+		v.path("path", r.Path)
+		v.roots(r.AllowedRoots)
+		v.nonNegative("chunk_size", r.ChunkSize)
+	case *api.SearchRequest:
+		v.path("path", r.Path)
+		v.roots(r.AllowedRoots)
+		v.nonNegative("max_matches", int64(r.MaxMatches))
+	case *api.PreviewRequest:
+		v.path("path", r.Path)
+		v.roots(r.AllowedRoots)
+		v.nonNegative("head_bytes", r.HeadBytes)
+		v.nonNegative("tail_bytes", r.TailBytes)
+	case *api.TailRequest:
+		// Either field may carry the paths, or glob patterns, to tail.
+		if r.Path != "" || len(r.Paths) == 0 {
+			v.path("path", r.Path)
+		}
+		for i, p := range r.Paths {
+			v.path(fmt.Sprintf("paths[%d]", i), p)
+		}
+		v.roots(r.AllowedRoots)
+		v.nonNegative("since_seconds", r.SinceSeconds)
+	case *api.ChecksumRequest:
+		v.path("path", r.Path)
+		v.roots(r.AllowedRoots)
+		v.nonNegative("length", r.Length)
+	case *api.FindRequest:
+		v.path("path", r.Path)
+		v.roots(r.AllowedRoots)
+		v.nonNegative("min_size", r.MinSize)
+		v.nonNegative("max_results", int64(r.MaxResults))
+	case *api.ShutdownRequest:
+		v.nonNegative("grace_seconds", r.GraceSeconds)
+	}
+	return v.err()
+}
+
+func validateUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func validateStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validatingStream{ServerStream: ss})
+}
+
+// validatingStream validates each request message as the handler receives
+// it; the agent's streaming RPCs take a single request.
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validateRequest(m)
+}
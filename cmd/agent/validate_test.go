@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// violatedFields returns the fields named in err's BadRequest detail.
+func violatedFields(t *testing.T, err error) []string {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			var fields []string
+			for _, fv := range br.FieldViolations {
+				fields = append(fields, fv.Field)
+			}
+			return fields
+		}
+	}
+	t.Fatalf("expected a BadRequest detail in %v", err)
+	return nil
+}
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  any
+		want string
+	}{
+		{"empty path", &api.StatRequest{}, "path"},
+		{"relative path", &api.StatRequest{Path: "etc/passwd"}, "path"},
+		{"NUL byte", &api.ReadRequest{Path: "/etc/passwd\x00.log"}, "path"},
+		{"long path", &api.ListRequest{Path: "/" + strings.Repeat("a", maxPathLength)}, "path"},
+		{"negative offset", &api.ReadRequest{Path: "/tmp/a", Offset: -1}, "offset"},
+		{"negative length", &api.ReadRequest{Path: "/tmp/a", Length: -1}, "length"},
+		{"negative chunk size", &api.StreamRequest{Path: "/tmp/a", ChunkSize: -1}, "chunk_size"},
+		{"negative page size", &api.ListRequest{Path: "/tmp", PageSize: -1}, "page_size"},
+		{"negative head", &api.PreviewRequest{Path: "/tmp/a", HeadBytes: -1}, "head_bytes"},
+		{"relative tail path", &api.TailRequest{Paths: []string{"/tmp/a", "b.log"}}, "paths[1]"},
+		{"NUL in root", &api.StatRequest{Path: "/tmp", AllowedRoots: []string{"/tmp\x00"}}, "allowed_roots[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := violatedFields(t, validateRequest(tt.req))
+			if len(fields) != 1 || fields[0] != tt.want {
+				t.Errorf("expected a violation of %s, got %v", tt.want, fields)
+			}
+		})
+	}
+
+	valid := []any{
+		&api.ReadRequest{Path: "/tmp/a", Offset: 10, Length: 100},
+		&api.TailRequest{Paths: []string{"/var/log/*.log"}},
+		&api.ListRequest{Path: "/", AllowedRoots: []string{"/"}},
+	}
+	for _, req := range valid {
+		if err := validateRequest(req); err != nil {
+			t.Errorf("validateRequest(%v) = %v; want nil", req, err)
+		}
+	}
+
+	// Every offending field is reported at once.
+	fields := violatedFields(t, validateRequest(&api.ReadRequest{Path: "rel", Offset: -1, Length: -1}))
+	if len(fields) != 3 {
+		t.Errorf("expected three violations, got %v", fields)
+	}
+}
+
+func TestValidateInterceptors(t *testing.T) {
+	called := false
+	_, err := validateUnaryInterceptor(context.Background(), &api.StatRequest{Path: "rel"}, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	})
+	if status.Code(err) != codes.InvalidArgument || called {
+		t.Errorf("expected the request to be rejected before the handler, got %v (called %v)", err, called)
+	}
+
+	ss := &validatingStream{ServerStream: &recvStream{msg: &api.StreamRequest{Path: "/tmp/a", ChunkSize: -5}}}
+	var req api.StreamRequest
+	if err := ss.RecvMsg(&req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument from RecvMsg, got %v", err)
+	}
+}
+
+// recvStream is a server stream whose single request is msg.
+type recvStream struct {
+	grpc.ServerStream
+	msg *api.StreamRequest
+}
+
+func (s *recvStream) RecvMsg(m any) error {
+	*m.(*api.StreamRequest) = api.StreamRequest{Path: s.msg.Path, ChunkSize: s.msg.ChunkSize}
+	return nil
+}
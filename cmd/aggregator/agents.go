@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxTrackedAgents bounds memory when many short-lived pods report;
+	// beyond it the agent seen least recently is forgotten.
+	maxTrackedAgents = 10000
+	// maxAgentPrefixes bounds the distinct path prefixes counted per agent;
+	// later ones are counted under otherPrefix.
+	maxAgentPrefixes  = 100
+	otherPrefix       = "(other)"
+	agentPrefixDepth  = 2
+	defaultAgentsTopN = 5
+)
+
+// agentKey identifies an agent in one namespace. A host agent serves every
+// namespace of its node and gets an entry per namespace, so namespace-scoped
+// tokens only see the accesses in their namespaces.
+type agentKey struct {
+	agentID, namespace string
+}
+
+type agentRecord struct {
+	pod, node  string
+	firstSeen  time.Time
+	lastSeen   time.Time
+	events     int64
+	operations map[string]int64
+	prefixes   map[string]int64
+}
+
+// agentStats keeps a running inventory of the agents reporting to the
+// aggregator: when each was first and last seen, how many events it sent
+// and what it was asked to do. Unlike the history it covers every event
+// since the aggregator started.
+type agentStats struct {
+	mu     sync.Mutex
+	agents map[agentKey]*agentRecord
+	now    func() time.Time
+}
+
+var agentInventory = newAgentStats()
+
+func newAgentStats() *agentStats {
+	return &agentStats{agents: map[agentKey]*agentRecord{}, now: time.Now}
+}
+
+// pathPrefix is the directory an access falls under, cut to
+// agentPrefixDepth components: /var/log/app/x.log counts under /var/log.
+func pathPrefix(p string) string {
+	dir := path.Dir(path.Clean("/" + p))
+	parts := strings.SplitN(strings.TrimPrefix(dir, "/"), "/", agentPrefixDepth+1)
+	if len(parts) > agentPrefixDepth {
+		parts = parts[:agentPrefixDepth]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+func (s *agentStats) Add(a AuditLog) {
+	if a.AgentID == "" {
+		return
+	}
+	seen := s.now()
+	if t, err := time.Parse(time.RFC3339Nano, a.Timestamp); err == nil {
+		seen = t
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := agentKey{a.AgentID, a.Namespace}
+	rec, ok := s.agents[key]
+	if !ok {
+		if len(s.agents) >= maxTrackedAgents {
+			s.evictLocked()
+		}
+		rec = &agentRecord{firstSeen: seen, operations: map[string]int64{}, prefixes: map[string]int64{}}
+		s.agents[key] = rec
+	}
+	if seen.Before(rec.firstSeen) {
+		rec.firstSeen = seen
+	}
+	if !seen.Before(rec.lastSeen) {
+		rec.lastSeen = seen
+		if a.Pod != "" {
+			rec.pod = a.Pod
+		}
+		if a.Node != "" {
+			rec.node = a.Node
+		}
+	}
+	n := max(a.Count, 1)
+	rec.events += n
+	rec.operations[a.Operation] += n
+	prefix := pathPrefix(a.Path)
+	if _, ok := rec.prefixes[prefix]; !ok && len(rec.prefixes) >= maxAgentPrefixes {
+		prefix = otherPrefix
+	}
+	rec.prefixes[prefix] += n
+}
+
+// evictLocked forgets the agent seen least recently. Callers must hold
+// s.mu.
+func (s *agentStats) evictLocked() {
+	var oldest agentKey
+	var oldestSeen time.Time
+	first := true
+	for key, rec := range s.agents {
+		if first || rec.lastSeen.Before(oldestSeen) {
+			oldest, oldestSeen, first = key, rec.lastSeen, false
+		}
+	}
+	delete(s.agents, oldest)
+}
+
+// agentSummary is one entry of the /agents response.
+type agentSummary struct {
+	AgentID         string       `json:"agent_id"`
+	Namespace       string       `json:"namespace,omitempty"`
+	Pod             string       `json:"pod,omitempty"`
+	Node            string       `json:"node,omitempty"`
+	FirstSeen       string       `json:"first_seen"`
+	LastSeen        string       `json:"last_seen"`
+	Events          int64        `json:"events"`
+	TopOperations   []countEntry `json:"top_operations"`
+	TopPathPrefixes []countEntry `json:"top_path_prefixes"`
+}
+
+// Summaries returns the agents f allows, most recently seen first, with
+// their top n operations and path prefixes.
+func (s *agentStats) Summaries(f eventFilter, n int) []agentSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []agentSummary{}
+	for key, rec := range s.agents {
+		if !f.allowsNamespace(key.namespace) || (f.AgentID != "" && key.agentID != f.AgentID) {
+			continue
+		}
+		out = append(out, agentSummary{
+			AgentID:         key.agentID,
+			Namespace:       key.namespace,
+			Pod:             rec.pod,
+			Node:            rec.node,
+			FirstSeen:       rec.firstSeen.UTC().Format(time.RFC3339),
+			LastSeen:        rec.lastSeen.UTC().Format(time.RFC3339),
+			Events:          rec.events,
+			TopOperations:   topOfCounts(rec.operations, n),
+			TopPathPrefixes: topOfCounts(rec.prefixes, n),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].LastSeen != out[j].LastSeen {
+			return out[i].LastSeen > out[j].LastSeen
+		}
+		if out[i].AgentID != out[j].AgentID {
+			return out[i].AgentID < out[j].AgentID
+		}
+		return out[i].Namespace < out[j].Namespace
+	})
+	return out
+}
+
+// topOfCounts is topCounts over counts already tallied.
+func topOfCounts(counts map[string]int64, n int) []countEntry {
+	entries := make([]countEntry, 0, len(counts))
+	for k, c := range counts {
+		entries = append(entries, countEntry{Key: k, Count: int(c)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// handleAgents serves GET /agents, the inventory of agents that have sent
+// events, limited to the namespaces the caller's token allows.
+func handleAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scope, ok := scopeForRequest(r)
+	if !ok {
+		http.Error(w, "Valid query token required", http.StatusUnauthorized)
+		return
+	}
+	filter, ok := scopedFilter(filterFromQuery(r), scope)
+	if !ok {
+		http.Error(w, "Token does not grant access to this namespace", http.StatusForbidden)
+		return
+	}
+	top := defaultAgentsTopN
+	if v := r.URL.Query().Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid top", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"agents": agentInventory.Summaries(filter, top)}); err != nil {
+		log.Printf("Error encoding agents response: %v", err)
+	}
+}
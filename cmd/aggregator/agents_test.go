@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPathPrefix(t *testing.T) {
+	for p, want := range map[string]string{
+		"/var/log/app/x.log": "/var/log",
+		"/etc/hosts":         "/etc",
+		"/app":               "/",
+		"relative/file":      "/relative",
+		"/a/../b/c":          "/b",
+	} {
+		if got := pathPrefix(p); got != want {
+			t.Errorf("pathPrefix(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestAgentStats(t *testing.T) {
+	s := newAgentStats()
+	s.Add(AuditLog{Timestamp: "2024-01-01T00:05:00Z", AgentID: "web-0", Namespace: "shop", Pod: "web-0", Operation: "ReadFile", Path: "/var/log/a.log"})
+	s.Add(AuditLog{Timestamp: "2024-01-01T00:01:00Z", AgentID: "web-0", Namespace: "shop", Pod: "web-0", Operation: "ListDirectory", Path: "/var/log/"})
+	s.Add(AuditLog{Timestamp: "2024-01-01T00:09:00Z", AgentID: "web-0", Namespace: "shop", Pod: "web-0", Operation: "ReadFile", Path: "/etc/hosts", Count: 4})
+	s.Add(AuditLog{Timestamp: "2024-01-01T00:02:00Z", AgentID: "node-a", Namespace: "billing", Node: "node-a", Operation: "Stat", Path: "/var/log/syslog"})
+	s.Add(AuditLog{Operation: "Stat", Path: "/no/agent"})
+
+	got := s.Summaries(eventFilter{}, 1)
+	if len(got) != 2 || got[0].AgentID != "web-0" || got[1].AgentID != "node-a" {
+		t.Fatalf("expected both agents, most recent first, got %+v", got)
+	}
+	web := got[0]
+	if web.FirstSeen != "2024-01-01T00:01:00Z" || web.LastSeen != "2024-01-01T00:09:00Z" || web.Events != 6 || web.Pod != "web-0" {
+		t.Errorf("unexpected summary %+v", web)
+	}
+	if !reflect.DeepEqual(web.TopOperations, []countEntry{{"ReadFile", 5}}) || !reflect.DeepEqual(web.TopPathPrefixes, []countEntry{{"/etc", 4}}) {
+		t.Errorf("unexpected top entries %+v %+v", web.TopOperations, web.TopPathPrefixes)
+	}
+
+	if got := s.Summaries(eventFilter{Namespaces: map[string]bool{"billing": true}}, 5); len(got) != 1 || got[0].Node != "node-a" {
+		t.Errorf("expected only the billing agent, got %+v", got)
+	}
+}
+
+func TestAgentStatsBounds(t *testing.T) {
+	s := newAgentStats()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	for i := 0; i < maxAgentPrefixes+10; i++ {
+		s.Add(AuditLog{AgentID: "busy", Operation: "ReadFile", Path: "/data/" + strconv.Itoa(i) + "/f"})
+	}
+	rec := s.agents[agentKey{"busy", ""}]
+	if len(rec.prefixes) != maxAgentPrefixes+1 || rec.prefixes[otherPrefix] != 10 {
+		t.Errorf("expected prefixes capped with the rest under %s, got %d distinct", otherPrefix, len(rec.prefixes))
+	}
+
+	for i := 0; i < maxTrackedAgents; i++ {
+		now = now.Add(time.Second)
+		s.Add(AuditLog{AgentID: "pod-" + strconv.Itoa(i), Operation: "Stat", Path: "/"})
+	}
+	if len(s.agents) != maxTrackedAgents {
+		t.Errorf("expected %d agents tracked, got %d", maxTrackedAgents, len(s.agents))
+	}
+	if _, ok := s.agents[agentKey{"busy", ""}]; ok {
+		t.Error("expected the agent seen least recently to be forgotten")
+	}
+}
+
+func TestHandleAgentsScopesToToken(t *testing.T) {
+	withTenantHistory(t)
+	original := agentInventory
+	t.Cleanup(func() { agentInventory = original })
+	agentInventory = newAgentStats()
+	agentInventory.Add(AuditLog{AgentID: "pay-0", Namespace: "payments", Operation: "ReadFile", Path: "/a"})
+	agentInventory.Add(AuditLog{AgentID: "search-0", Namespace: "search", Operation: "ReadFile", Path: "/b"})
+
+	get := func(token, query string) (int, []agentSummary) {
+		req := httptest.NewRequest(http.MethodGet, "/agents"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handleAgents(w, req)
+		var resp struct {
+			Agents []agentSummary `json:"agents"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Agents
+	}
+	if code, agents := get("pay-secret", ""); code != http.StatusOK || len(agents) != 1 || agents[0].AgentID != "pay-0" {
+		t.Errorf("expected only the payments agent, got %d %+v", code, agents)
+	}
+	if _, agents := get("sec-secret", "?agent=search-0"); len(agents) != 1 || agents[0].Namespace != "search" {
+		t.Errorf("expected the agent filter to apply, got %+v", agents)
+	}
+	if code, _ := get("sec-secret", "?top=0"); code != http.StatusBadRequest {
+		t.Errorf("expected an invalid top to be rejected, got %d", code)
+	}
+}
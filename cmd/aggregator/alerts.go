@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAlertCooldown = 5 * time.Minute
+	pagerDutyEventsURL   = "https://events.pagerduty.com/v2/enqueue"
+)
+
+// alertConfig is the JSON document referenced by PULSAAR_ALERT_RULES_FILE.
+type alertConfig struct {
+	Rules     []alertRule      `json:"rules"`
+	Notifiers []notifierConfig `json:"notifiers"`
+}
+
+// alertRule matches when every condition it sets holds for an audit event.
+type alertRule struct {
+	Name         string        `json:"name"`
+	PathRegex    string        `json:"path_regex,omitempty"`
+	Operations   []string      `json:"operations,omitempty"`
+	Namespaces   []string      `json:"namespaces,omitempty"`
+	Volume       *volumeRule   `json:"volume,omitempty"`
+	OutsideHours *workingHours `json:"outside_hours,omitempty"`
+	Cooldown     string        `json:"cooldown,omitempty"`
+	Notify       []string      `json:"notify,omitempty"`
+
+	pathRe   *regexp.Regexp
+	cooldown time.Duration
+	window   time.Duration
+}
+
+// volumeRule fires when more than Count matching events arrive for the same
+// GroupBy key (user, agent_id or namespace) within Window.
+type volumeRule struct {
+	Count   int    `json:"count"`
+	Window  string `json:"window"`
+	GroupBy string `json:"group_by,omitempty"`
+}
+
+// workingHours describes the allowed access window; events outside it match.
+type workingHours struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Weekends bool   `json:"weekends,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+
+	loc *time.Location
+}
+
+type notifierConfig struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	URL        string `json:"url,omitempty"`
+	RoutingKey string `json:"routing_key,omitempty"`
+}
+
+type alert struct {
+	Rule    string   `json:"rule"`
+	Message string   `json:"message"`
+	Event   AuditLog `json:"event"`
+}
+
+type alertEngine struct {
+	rules     []*alertRule
+	notifiers map[string]notifierConfig
+	client    *http.Client
+
+	mu        sync.Mutex
+	lastFired map[string]time.Time
+	volume    map[string][]time.Time
+	now       func() time.Time
+
+	// in-flight NotifyAsync deliveries
+	pending sync.WaitGroup
+}
+
+var alerts *alertEngine
+
+func initAlerts() error {
+	path := os.Getenv("PULSAAR_ALERT_RULES_FILE")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read alert rules file: %v", err)
+	}
+	var cfg alertConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse alert rules file: %v", err)
+	}
+	engine, err := newAlertEngine(cfg)
+	if err != nil {
+		return err
+	}
+	alerts = engine
+	log.Printf("Loaded %d alert rules and %d notifiers", len(engine.rules), len(engine.notifiers))
+	return nil
+}
+
+func newAlertEngine(cfg alertConfig) (*alertEngine, error) {
+	e := &alertEngine{
+		notifiers: map[string]notifierConfig{},
+		client:    &http.Client{Timeout: 10 * time.Second},
+		lastFired: map[string]time.Time{},
+		volume:    map[string][]time.Time{},
+		now:       time.Now,
+	}
+	for _, n := range cfg.Notifiers {
+		switch n.Type {
+		case "webhook", "slack":
+			if n.URL == "" {
+				return nil, fmt.Errorf("notifier %q of type %s requires a url", n.Name, n.Type)
+			}
+		case "pagerduty":
+			if n.RoutingKey == "" {
+				return nil, fmt.Errorf("notifier %q of type pagerduty requires a routing_key", n.Name)
+			}
+		default:
+			return nil, fmt.Errorf("notifier %q has unknown type %q. Supported types: webhook, slack, pagerduty", n.Name, n.Type)
+		}
+		e.notifiers[n.Name] = n
+	}
+
+	for i := range cfg.Rules {
+		r := cfg.Rules[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("alert rule %d has no name", i)
+		}
+		if r.PathRegex != "" {
+			re, err := regexp.Compile(r.PathRegex)
+			if err != nil {
+				return nil, fmt.Errorf("alert rule %q has invalid path_regex: %v", r.Name, err)
+			}
+			r.pathRe = re
+		}
+		r.cooldown = defaultAlertCooldown
+		if r.Cooldown != "" {
+			d, err := time.ParseDuration(r.Cooldown)
+			if err != nil {
+				return nil, fmt.Errorf("alert rule %q has invalid cooldown: %v", r.Name, err)
+			}
+			r.cooldown = d
+		}
+		if r.Volume != nil {
+			d, err := time.ParseDuration(r.Volume.Window)
+			if err != nil || d <= 0 || r.Volume.Count <= 0 {
+				return nil, fmt.Errorf("alert rule %q needs a positive volume count and window", r.Name)
+			}
+			switch r.Volume.GroupBy {
+			case "", "user", "agent_id", "namespace":
+			default:
+				return nil, fmt.Errorf("alert rule %q has unknown group_by %q", r.Name, r.Volume.GroupBy)
+			}
+			r.window = d
+		}
+		if r.OutsideHours != nil {
+			h := r.OutsideHours
+			if h.Start < 0 || h.Start > 23 || h.End < 0 || h.End > 24 {
+				return nil, fmt.Errorf("alert rule %q has invalid working hours", r.Name)
+			}
+			h.loc = time.UTC
+			if h.Timezone != "" {
+				loc, err := time.LoadLocation(h.Timezone)
+				if err != nil {
+					return nil, fmt.Errorf("alert rule %q has invalid timezone: %v", r.Name, err)
+				}
+				h.loc = loc
+			}
+		}
+		for _, name := range r.Notify {
+			if _, ok := e.notifiers[name]; !ok {
+				return nil, fmt.Errorf("alert rule %q references unknown notifier %q", r.Name, name)
+			}
+		}
+		e.rules = append(e.rules, &r)
+	}
+	return e, nil
+}
+
+// Evaluate checks the event against every rule and returns the alerts that
+// fired. Rules that fired within their cooldown are suppressed.
+func (e *alertEngine) Evaluate(audit AuditLog) []alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	var fired []alert
+	for _, r := range e.rules {
+		if !r.matches(audit, now) {
+			continue
+		}
+		key := r.Name
+		message := fmt.Sprintf("Pulsaar alert %q: %s %s", r.Name, audit.Operation, audit.Path)
+		if r.Volume != nil {
+			group := groupKey(audit, r.Volume.GroupBy)
+			key = r.Name + "/" + group
+			// A deduplicated record counts as the events it stands for,
+			// as many as it takes to exceed the rule.
+			n := int(min(max(audit.Count, 1), int64(r.Volume.Count)+1))
+			events := append(pruneBefore(e.volume[key], now.Add(-r.window)), slices.Repeat([]time.Time{now}, n)...)
+			e.volume[key] = events
+			if len(events) <= r.Volume.Count {
+				continue
+			}
+			message = fmt.Sprintf("Pulsaar alert %q: %d accesses within %s by %s", r.Name, len(events), r.window, group)
+		}
+		if last, ok := e.lastFired[key]; ok && now.Sub(last) < r.cooldown {
+			continue
+		}
+		e.lastFired[key] = now
+		fired = append(fired, alert{Rule: r.Name, Message: message, Event: audit})
+	}
+	return fired
+}
+
+func (r *alertRule) matches(audit AuditLog, now time.Time) bool {
+	if r.pathRe != nil && !r.pathRe.MatchString(audit.Path) {
+		return false
+	}
+	if len(r.Operations) > 0 && !containsString(r.Operations, audit.Operation) {
+		return false
+	}
+	if len(r.Namespaces) > 0 && !containsString(r.Namespaces, audit.Namespace) {
+		return false
+	}
+	if r.OutsideHours != nil {
+		t := now
+		if ts, err := time.Parse(time.RFC3339, audit.Timestamp); err == nil {
+			t = ts
+		}
+		if r.OutsideHours.contains(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *workingHours) contains(t time.Time) bool {
+	t = t.In(h.loc)
+	if !h.Weekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return false
+	}
+	return t.Hour() >= h.Start && t.Hour() < h.End
+}
+
+func groupKey(audit AuditLog, groupBy string) string {
+	switch groupBy {
+	case "user":
+		return audit.User
+	case "agent_id":
+		return audit.AgentID
+	case "namespace":
+		return audit.Namespace
+	}
+	return "all"
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify delivers a fired alert to the rule's notifiers, or to every notifier
+// when the rule does not name any.
+func (e *alertEngine) Notify(a alert) {
+	var targets []notifierConfig
+	for _, r := range e.rules {
+		if r.Name != a.Rule {
+			continue
+		}
+		for _, name := range r.Notify {
+			targets = append(targets, e.notifiers[name])
+		}
+		if len(r.Notify) == 0 {
+			for _, n := range e.notifiers {
+				targets = append(targets, n)
+			}
+		}
+	}
+	for _, n := range targets {
+		if err := e.send(n, a); err != nil {
+			log.Printf("Failed to deliver alert %q to notifier %q: %v", a.Rule, n.Name, err)
+		}
+	}
+}
+
+// NotifyAsync delivers a in the background; Wait blocks until every such
+// delivery has finished.
+func (e *alertEngine) NotifyAsync(a alert) {
+	e.pending.Add(1)
+	go func() {
+		defer e.pending.Done()
+		e.Notify(a)
+	}()
+}
+
+func (e *alertEngine) Wait() {
+	e.pending.Wait()
+}
+
+func (e *alertEngine) send(n notifierConfig, a alert) error {
+	url := n.URL
+	var payload any
+	switch n.Type {
+	case "webhook":
+		payload = a
+	case "slack":
+		payload = map[string]string{"text": a.Message}
+	case "pagerduty":
+		if url == "" {
+			url = pagerDutyEventsURL
+		}
+		payload = map[string]any{
+			"routing_key":  n.RoutingKey,
+			"event_action": "trigger",
+			"dedup_key":    "pulsaar-" + a.Rule,
+			"payload": map[string]any{
+				"summary":        a.Message,
+				"source":         "pulsaar-aggregator",
+				"severity":       "warning",
+				"custom_details": a.Event,
+			},
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notifier returned status %s", resp.Status)
+	}
+	return nil
+}
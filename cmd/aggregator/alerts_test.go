@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAlertPathRule(t *testing.T) {
+	e, err := newAlertEngine(alertConfig{
+		Rules: []alertRule{{Name: "secrets", PathRegex: `^/var/run/secrets/`, Operations: []string{"ReadFile"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := e.Evaluate(AuditLog{Operation: "ReadFile", Path: "/var/run/secrets/kubernetes.io/token"}); len(got) != 1 {
+		t.Errorf("expected alert for secrets read, got %v", got)
+	}
+	if got := e.Evaluate(AuditLog{Operation: "ReadFile", Path: "/var/run/secrets/other"}); len(got) != 0 {
+		t.Errorf("expected cooldown to suppress repeat alert, got %v", got)
+	}
+	if got := e.Evaluate(AuditLog{Operation: "Stat", Path: "/app"}); len(got) != 0 {
+		t.Errorf("expected no alert for unrelated access, got %v", got)
+	}
+}
+
+func TestAlertVolumeRule(t *testing.T) {
+	e, err := newAlertEngine(alertConfig{
+		Rules: []alertRule{{Name: "bulk", Volume: &volumeRule{Count: 2, Window: "1m", GroupBy: "user"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	var fired int
+	for i := 0; i < 3; i++ {
+		fired += len(e.Evaluate(AuditLog{User: "alice", Operation: "ReadFile"}))
+	}
+	fired += len(e.Evaluate(AuditLog{User: "bob", Operation: "ReadFile"}))
+	if fired != 1 {
+		t.Errorf("expected exactly one volume alert, got %d", fired)
+	}
+}
+
+func TestAlertOutsideHours(t *testing.T) {
+	e, err := newAlertEngine(alertConfig{
+		Rules: []alertRule{{Name: "after-hours", OutsideHours: &workingHours{Start: 9, End: 17}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Monday 10:00 UTC is within working hours.
+	if got := e.Evaluate(AuditLog{Timestamp: "2024-01-01T10:00:00Z"}); len(got) != 0 {
+		t.Errorf("expected no alert during working hours, got %v", got)
+	}
+	// Saturday is outside working hours when weekends are excluded.
+	if got := e.Evaluate(AuditLog{Timestamp: "2024-01-06T10:00:00Z"}); len(got) != 1 {
+		t.Errorf("expected alert on weekend, got %v", got)
+	}
+}
+
+func TestNewAlertEngineValidation(t *testing.T) {
+	cases := []alertConfig{
+		{Rules: []alertRule{{Name: "bad", PathRegex: "("}}},
+		{Rules: []alertRule{{Name: "missing", Notify: []string{"nope"}}}},
+		{Notifiers: []notifierConfig{{Name: "x", Type: "email"}}},
+	}
+	for i, cfg := range cases {
+		if _, err := newAlertEngine(cfg); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestAlertNotifySlack(t *testing.T) {
+	received := make(chan map[string]string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer srv.Close()
+
+	e, err := newAlertEngine(alertConfig{
+		Rules:     []alertRule{{Name: "any", Notify: []string{"team"}}},
+		Notifiers: []notifierConfig{{Name: "team", Type: "slack", URL: srv.URL}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, a := range e.Evaluate(AuditLog{Operation: "ReadFile", Path: "/etc/shadow"}) {
+		e.Notify(a)
+	}
+
+	select {
+	case body := <-received:
+		if body["text"] == "" {
+			t.Error("expected slack text payload")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("notifier was not called")
+	}
+}
+
+func TestAlertNotifyAsyncWait(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer srv.Close()
+
+	e, err := newAlertEngine(alertConfig{
+		Rules:     []alertRule{{Name: "any"}},
+		Notifiers: []notifierConfig{{Name: "hook", Type: "webhook", URL: srv.URL}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, a := range e.Evaluate(AuditLog{Operation: "ReadFile", Path: "/etc/hosts"}) {
+		e.NotifyAsync(a)
+	}
+	e.Wait()
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("expected Wait to return after delivery, got %d calls", calls)
+	}
+}
+
+func TestAlertVolumeCountsDeduplicatedEvents(t *testing.T) {
+	engine, err := newAlertEngine(alertConfig{Rules: []alertRule{{Name: "polling", Volume: &volumeRule{Count: 5, Window: "1m", GroupBy: "user"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired := engine.Evaluate(AuditLog{User: "alice", Count: 4}); len(fired) != 0 {
+		t.Errorf("expected 4 reads to stay under the rule, got %+v", fired)
+	}
+	if fired := engine.Evaluate(AuditLog{User: "alice", Count: 2}); len(fired) != 1 {
+		t.Errorf("expected 6 reads to fire the rule, got %+v", fired)
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultArchiveInterval   = 15 * time.Minute
+	defaultArchiveMaxBatchMB = 64
+	maxPendingArchiveBatches = 16
+)
+
+// objectUploader stores one archived batch under key.
+type objectUploader interface {
+	Upload(ctx context.Context, key string, data []byte) error
+}
+
+// archiver collects audit events and periodically uploads them to object
+// storage as gzip-compressed JSON lines. Objects are named
+// <prefix>/year=YYYY/month=MM/day=DD/audit-<host>-<timestamp>.jsonl.gz so
+// that bucket lifecycle rules can match on date prefixes and query engines
+// can treat the layout as partitions.
+type archiver struct {
+	uploader objectUploader
+	prefix   string
+	hostname string
+	interval time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	pending []archiveBatch
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type archiveBatch struct {
+	key  string
+	data []byte
+}
+
+var archive *archiver
+
+// initArchive configures archival from PULSAAR_ARCHIVE_URL, which selects the
+// backend by scheme: s3://bucket/prefix, gs://bucket/prefix or
+// azblob://account/container/prefix.
+func initArchive() error {
+	rawURL := os.Getenv("PULSAAR_ARCHIVE_URL")
+	if rawURL == "" {
+		return nil
+	}
+
+	uploader, prefix, err := newObjectUploader(rawURL)
+	if err != nil {
+		return err
+	}
+	interval, err := envDuration("PULSAAR_ARCHIVE_INTERVAL")
+	if err != nil {
+		return err
+	}
+	if interval == 0 {
+		interval = defaultArchiveInterval
+	}
+	maxBatchMB, err := envPositiveInt("PULSAAR_ARCHIVE_MAX_BATCH_MB", defaultArchiveMaxBatchMB)
+	if err != nil {
+		return err
+	}
+	if p := os.Getenv("PULSAAR_ARCHIVE_PREFIX"); p != "" {
+		prefix = p
+	}
+
+	archive = newArchiver(uploader, prefix, interval, maxBatchMB*1024*1024)
+	log.Printf("Archiving audit events to %s every %s", rawURL, interval)
+	return nil
+}
+
+func newArchiver(uploader objectUploader, prefix string, interval time.Duration, maxBatch int) *archiver {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "aggregator"
+	}
+	a := &archiver{
+		uploader: uploader,
+		prefix:   strings.Trim(prefix, "/"),
+		hostname: hostname,
+		interval: interval,
+		maxBatch: maxBatch,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Add appends one JSON-encoded audit event to the current batch. A batch
+// that grows past the size limit is sealed immediately and uploaded on the
+// next tick.
+func (a *archiver) Add(body []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.buf.Write(body)
+	a.buf.WriteByte('\n')
+	if a.buf.Len() >= a.maxBatch {
+		a.sealLocked(time.Now())
+	}
+}
+
+// Close uploads everything still buffered and stops the background loop.
+func (a *archiver) Close() {
+	close(a.stop)
+	<-a.done
+}
+
+func (a *archiver) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.flush()
+		case <-a.stop:
+			a.flush()
+			return
+		}
+	}
+}
+
+// sealLocked compresses the current buffer into a pending batch. Callers
+// must hold a.mu.
+func (a *archiver) sealLocked(now time.Time) {
+	if a.buf.Len() == 0 {
+		return
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = zw.Write(a.buf.Bytes())
+	_ = zw.Close()
+	a.buf.Reset()
+
+	if len(a.pending) >= maxPendingArchiveBatches {
+		log.Printf("Archive backlog full, dropping batch %s", a.pending[0].key)
+		a.pending = a.pending[1:]
+	}
+	a.pending = append(a.pending, archiveBatch{key: a.objectKey(now), data: gz.Bytes()})
+}
+
+// flush uploads sealed batches in order. Failed uploads stay pending and are
+// retried on the next tick.
+func (a *archiver) flush() {
+	a.mu.Lock()
+	a.sealLocked(time.Now())
+	batches := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+
+	for i, b := range batches {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := a.uploader.Upload(ctx, b.key, b.data)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to archive audit batch %s: %v", b.key, err)
+			a.mu.Lock()
+			a.pending = append(batches[i:], a.pending...)
+			if len(a.pending) > maxPendingArchiveBatches {
+				a.pending = a.pending[len(a.pending)-maxPendingArchiveBatches:]
+			}
+			a.mu.Unlock()
+			return
+		}
+		log.Printf("Archived audit batch %s (%d bytes)", b.key, len(b.data))
+	}
+}
+
+func (a *archiver) objectKey(t time.Time) string {
+	t = t.UTC()
+	key := fmt.Sprintf("year=%04d/month=%02d/day=%02d/audit-%s-%s.jsonl.gz",
+		t.Year(), t.Month(), t.Day(), a.hostname, t.Format("20060102T150405.000000000Z"))
+	if a.prefix != "" {
+		key = a.prefix + "/" + key
+	}
+	return key
+}
+
+func newObjectUploader(rawURL string) (objectUploader, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid archive URL: %v", err)
+	}
+	if u.Host == "" {
+		return nil, "", fmt.Errorf("archive URL %q must include a bucket", rawURL)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	client := &http.Client{Timeout: 5 * time.Minute}
+
+	switch u.Scheme {
+	case "s3":
+		region := firstEnv("PULSAAR_ARCHIVE_REGION", "AWS_REGION", "AWS_DEFAULT_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint := os.Getenv("PULSAAR_ARCHIVE_ENDPOINT")
+		pathStyle := endpoint != ""
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+		up := &s3Uploader{
+			endpoint:     strings.TrimRight(endpoint, "/"),
+			bucket:       u.Host,
+			region:       region,
+			pathStyle:    pathStyle,
+			accessKey:    firstEnv("PULSAAR_ARCHIVE_ACCESS_KEY", "AWS_ACCESS_KEY_ID"),
+			secretKey:    firstEnv("PULSAAR_ARCHIVE_SECRET_KEY", "AWS_SECRET_ACCESS_KEY"),
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			client:       client,
+		}
+		if up.accessKey == "" || up.secretKey == "" {
+			return nil, "", fmt.Errorf("s3 archive requires PULSAAR_ARCHIVE_ACCESS_KEY/PULSAAR_ARCHIVE_SECRET_KEY or AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY")
+		}
+		return up, prefix, nil
+	case "gs":
+		// Google Cloud Storage accepts SigV4-signed requests made with HMAC
+		// keys through its XML API.
+		endpoint := os.Getenv("PULSAAR_ARCHIVE_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		up := &s3Uploader{
+			endpoint:  strings.TrimRight(endpoint, "/"),
+			bucket:    u.Host,
+			region:    "auto",
+			pathStyle: true,
+			accessKey: os.Getenv("PULSAAR_ARCHIVE_ACCESS_KEY"),
+			secretKey: os.Getenv("PULSAAR_ARCHIVE_SECRET_KEY"),
+			client:    client,
+		}
+		if up.accessKey == "" || up.secretKey == "" {
+			return nil, "", fmt.Errorf("gs archive requires HMAC keys in PULSAAR_ARCHIVE_ACCESS_KEY and PULSAAR_ARCHIVE_SECRET_KEY")
+		}
+		return up, prefix, nil
+	case "azblob":
+		parts := strings.SplitN(prefix, "/", 2)
+		if parts[0] == "" {
+			return nil, "", fmt.Errorf("azblob archive URL must be azblob://account/container[/prefix]")
+		}
+		container := parts[0]
+		prefix = ""
+		if len(parts) == 2 {
+			prefix = parts[1]
+		}
+		endpoint := os.Getenv("PULSAAR_ARCHIVE_ENDPOINT")
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", u.Host)
+		}
+		sas := strings.TrimPrefix(firstEnv("PULSAAR_ARCHIVE_AZURE_SAS_TOKEN", "AZURE_STORAGE_SAS_TOKEN"), "?")
+		if sas == "" {
+			return nil, "", fmt.Errorf("azblob archive requires PULSAAR_ARCHIVE_AZURE_SAS_TOKEN")
+		}
+		return &azureBlobUploader{
+			endpoint:  strings.TrimRight(endpoint, "/"),
+			container: container,
+			sasToken:  sas,
+			client:    client,
+		}, prefix, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported archive scheme %q. Supported schemes: s3, gs, azblob", u.Scheme)
+	}
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// s3Uploader writes objects with AWS Signature Version 4. It also serves
+// S3-compatible stores such as MinIO and the GCS XML API.
+type s3Uploader struct {
+	endpoint     string
+	bucket       string
+	region       string
+	pathStyle    bool
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+}
+
+func (s *s3Uploader) Upload(ctx context.Context, key string, data []byte) error {
+	base, err := url.Parse(s.endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid archive endpoint: %v", err)
+	}
+	host := base.Host
+	path := "/" + awsEscapePath(key)
+	if s.pathStyle {
+		path = "/" + awsEscapePath(s.bucket) + path
+	} else {
+		host = s.bucket + "." + host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, base.Scheme+"://"+host+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	s.sign(req, host, path, data, time.Now())
+
+	return doUpload(s.client, req)
+}
+
+func (s *s3Uploader) sign(req *http.Request, host, path string, payload []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{"host": host, "x-amz-content-sha256": payloadHash, "x-amz-date": amzDate}
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+		headers = append(headers, "x-amz-security-token")
+		values["x-amz-security-token"] = s.sessionToken
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h + ":" + values[h] + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+	canonicalRequest := strings.Join([]string{req.Method, path, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// azureBlobUploader writes block blobs using a shared access signature.
+type azureBlobUploader struct {
+	endpoint  string
+	container string
+	sasToken  string
+	client    *http.Client
+}
+
+func (a *azureBlobUploader) Upload(ctx context.Context, key string, data []byte) error {
+	target := a.endpoint + "/" + awsEscapePath(a.container) + "/" + awsEscapePath(key) + "?" + a.sasToken
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", "2021-08-06")
+	req.Header.Set("Content-Type", "application/gzip")
+
+	return doUpload(a.client, req)
+}
+
+func doUpload(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// awsEscapePath percent-encodes every byte outside the RFC 3986 unreserved
+// set, leaving '/' separators intact, as SigV4 canonical URIs require.
+func awsEscapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
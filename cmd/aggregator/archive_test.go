@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeUploader struct {
+	mu      sync.Mutex
+	fail    bool
+	objects map[string][]byte
+}
+
+func (f *fakeUploader) Upload(_ context.Context, key string, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("unavailable")
+	}
+	if f.objects == nil {
+		f.objects = map[string][]byte{}
+	}
+	f.objects[key] = data
+	return nil
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid gzip: %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("invalid gzip: %v", err)
+	}
+	return string(out)
+}
+
+func TestArchiverUploadsOnClose(t *testing.T) {
+	up := &fakeUploader{}
+	a := newArchiver(up, "/audit/prod/", time.Hour, 1024*1024)
+	a.Add([]byte(`{"path":"/etc/hosts"}`))
+	a.Add([]byte(`{"path":"/tmp"}`))
+	a.Close()
+
+	if len(up.objects) != 1 {
+		t.Fatalf("expected 1 archived object, got %d", len(up.objects))
+	}
+	for key, data := range up.objects {
+		if !strings.HasPrefix(key, "audit/prod/year=") || !strings.HasSuffix(key, ".jsonl.gz") {
+			t.Errorf("unexpected object key %q", key)
+		}
+		if got := gunzip(t, data); got != "{\"path\":\"/etc/hosts\"}\n{\"path\":\"/tmp\"}\n" {
+			t.Errorf("unexpected archive contents %q", got)
+		}
+	}
+}
+
+func TestArchiverRetriesFailedBatches(t *testing.T) {
+	up := &fakeUploader{fail: true}
+	a := newArchiver(up, "", time.Hour, 1024*1024)
+	defer a.Close()
+
+	a.Add([]byte(`{"path":"/a"}`))
+	a.flush()
+	if len(a.pending) != 1 {
+		t.Fatalf("expected failed batch to stay pending, got %d", len(a.pending))
+	}
+
+	up.mu.Lock()
+	up.fail = false
+	up.mu.Unlock()
+	a.flush()
+	if len(a.pending) != 0 || len(up.objects) != 1 {
+		t.Errorf("expected pending batch to be uploaded, pending=%d objects=%d", len(a.pending), len(up.objects))
+	}
+}
+
+func TestArchiverObjectKey(t *testing.T) {
+	a := &archiver{prefix: "pulsaar", hostname: "agg-0"}
+	key := a.objectKey(time.Date(2024, 3, 7, 9, 30, 0, 0, time.UTC))
+	want := "pulsaar/year=2024/month=03/day=07/audit-agg-0-20240307T093000.000000000Z.jsonl.gz"
+	if key != want {
+		t.Errorf("expected %q, got %q", want, key)
+	}
+}
+
+func TestS3UploaderSignsRequest(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		if r.Method != http.MethodPut || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	up := &s3Uploader{endpoint: srv.URL, bucket: "audit", region: "eu-west-1", pathStyle: true,
+		accessKey: "AKID", secretKey: "secret", client: srv.Client()}
+	if err := up.Upload(context.Background(), "year=2024/a.jsonl.gz", []byte("data")); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if gotPath != "/audit/year%3D2024/a.jsonl.gz" {
+		t.Errorf("unexpected request path %q", gotPath)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(gotAuth, "/eu-west-1/s3/aws4_request") {
+		t.Errorf("unexpected Authorization header %q", gotAuth)
+	}
+}
+
+func TestAzureBlobUploader(t *testing.T) {
+	var gotQuery, gotType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		gotType = r.Header.Get("x-ms-blob-type")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	up := &azureBlobUploader{endpoint: srv.URL, container: "audit", sasToken: "sv=2021&sig=abc", client: srv.Client()}
+	if err := up.Upload(context.Background(), "a.jsonl.gz", []byte("data")); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if gotQuery != "sv=2021&sig=abc" || gotType != "BlockBlob" {
+		t.Errorf("unexpected request query=%q blob-type=%q", gotQuery, gotType)
+	}
+}
+
+func TestNewObjectUploader(t *testing.T) {
+	t.Setenv("PULSAAR_ARCHIVE_ACCESS_KEY", "key")
+	t.Setenv("PULSAAR_ARCHIVE_SECRET_KEY", "secret")
+	t.Setenv("PULSAAR_ARCHIVE_AZURE_SAS_TOKEN", "?sig=abc")
+
+	if _, prefix, err := newObjectUploader("s3://bucket/audit/"); err != nil || prefix != "audit" {
+		t.Errorf("s3: prefix=%q err=%v", prefix, err)
+	}
+	up, prefix, err := newObjectUploader("azblob://account/container/audit")
+	if err != nil || prefix != "audit" {
+		t.Fatalf("azblob: prefix=%q err=%v", prefix, err)
+	}
+	if az := up.(*azureBlobUploader); az.container != "container" || az.sasToken != "sig=abc" {
+		t.Errorf("unexpected azure uploader %+v", az)
+	}
+	if _, _, err := newObjectUploader("ftp://bucket"); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+}
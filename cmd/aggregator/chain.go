@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCheckpointEvery = 1000
+
+// chainRecord is one line of a hash-chained audit log. Hash covers the
+// previous record's hash, the sequence number and the event itself, so
+// modifying, reordering or removing any record breaks every later hash.
+type chainRecord struct {
+	Seq      uint64          `json:"seq"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+	Event    json.RawMessage `json:"event"`
+}
+
+// chainCheckpoint signs the chain head so that truncating the log after the
+// checkpoint, or rewriting the whole chain, can be detected with the public
+// key alone.
+type chainCheckpoint struct {
+	Seq       uint64 `json:"seq"`
+	Hash      string `json:"hash"`
+	Time      string `json:"time"`
+	Signature string `json:"signature"`
+}
+
+// chainLine is the union of both line types, used when reading a log back.
+type chainLine struct {
+	chainRecord
+	Checkpoint *chainCheckpoint `json:"checkpoint,omitempty"`
+}
+
+type hashChain struct {
+	mu              sync.Mutex
+	seq             uint64
+	head            string
+	signer          ed25519.PrivateKey
+	checkpointEvery uint64
+	sinceCheckpoint uint64
+}
+
+var chain *hashChain
+
+// initHashChain enables hash chaining when PULSAAR_AUDIT_HASH_CHAIN is true
+// and resumes the chain from the existing audit log. Checkpoints are written
+// every PULSAAR_AUDIT_CHECKPOINT_EVERY records when PULSAAR_AUDIT_SIGNING_KEY
+// points at a PEM-encoded Ed25519 private key.
+func initHashChain(auditLogPath string) error {
+	if os.Getenv("PULSAAR_AUDIT_HASH_CHAIN") != "true" {
+		return nil
+	}
+
+	c := &hashChain{}
+	if keyFile := os.Getenv("PULSAAR_AUDIT_SIGNING_KEY"); keyFile != "" {
+		key, err := loadSigningKey(keyFile)
+		if err != nil {
+			return err
+		}
+		c.signer = key
+		every, err := envPositiveInt("PULSAAR_AUDIT_CHECKPOINT_EVERY", defaultCheckpointEvery)
+		if err != nil {
+			return err
+		}
+		c.checkpointEvery = uint64(every)
+	}
+
+	seq, head, err := lastChainHead(auditLogPath)
+	if err != nil {
+		return fmt.Errorf("failed to resume audit hash chain: %v", err)
+	}
+	c.seq, c.head = seq, head
+	chain = c
+	log.Printf("Audit hash chain enabled at seq %d (signed checkpoints=%t)", seq, c.signer != nil)
+	return nil
+}
+
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit signing key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("audit signing key %s is not PEM encoded", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse audit signing key: %v", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("audit signing key must be an Ed25519 key")
+	}
+	return edKey, nil
+}
+
+// Append chains body onto the log and returns the line to write, followed
+// by a checkpoint line when one is due.
+func (c *hashChain) Append(body []byte) ([]string, error) {
+	var event bytes.Buffer
+	if err := json.Compact(&event, body); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec := chainRecord{Seq: c.seq + 1, PrevHash: c.head, Event: event.Bytes()}
+	rec.Hash = chainHash(rec.PrevHash, rec.Seq, rec.Event)
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	c.seq, c.head = rec.Seq, rec.Hash
+
+	lines := []string{string(line)}
+	c.sinceCheckpoint++
+	if c.signer != nil && c.sinceCheckpoint >= c.checkpointEvery {
+		lines = append(lines, c.checkpointLocked())
+	}
+	return lines, nil
+}
+
+// Checkpoint returns a signed checkpoint for the current head, or "" when
+// signing is disabled or nothing was written since the last one.
+func (c *hashChain) Checkpoint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.signer == nil || c.sinceCheckpoint == 0 {
+		return ""
+	}
+	return c.checkpointLocked()
+}
+
+func (c *hashChain) checkpointLocked() string {
+	cp := chainCheckpoint{Seq: c.seq, Hash: c.head, Time: time.Now().UTC().Format(time.RFC3339)}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(c.signer, checkpointMessage(cp)))
+	c.sinceCheckpoint = 0
+	line, _ := json.Marshal(struct {
+		Checkpoint chainCheckpoint `json:"checkpoint"`
+	}{cp})
+	return string(line)
+}
+
+// writeFinalCheckpoint signs the chain head on shutdown so the tail of the
+// log is covered too.
+func writeFinalCheckpoint() {
+	if chain == nil || auditFile == nil {
+		return
+	}
+	if cp := chain.Checkpoint(); cp != "" {
+		if _, err := auditFile.WriteString(cp + "\n"); err != nil {
+			log.Printf("Failed to write audit checkpoint: %v", err)
+		}
+	}
+}
+
+func chainHash(prev string, seq uint64, event []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prev))
+	h.Write([]byte("\n" + strconv.FormatUint(seq, 10) + "\n"))
+	h.Write(event)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func checkpointMessage(cp chainCheckpoint) []byte {
+	return []byte(fmt.Sprintf("pulsaar-audit-checkpoint:%d:%s:%s", cp.Seq, cp.Hash, cp.Time))
+}
+
+// lastChainHead finds the most recent record in the active audit log, or in
+// the newest rotated segment when the active log has none yet.
+func lastChainHead(path string) (uint64, string, error) {
+	seq, head, err := lastChainHeadInFile(path)
+	if err != nil || seq > 0 {
+		return seq, head, err
+	}
+
+	matches, _ := filepath.Glob(path + ".*")
+	if len(matches) == 0 {
+		return 0, "", nil
+	}
+	// Rotated segment names sort chronologically.
+	newest := matches[0]
+	for _, m := range matches {
+		if strings.TrimSuffix(m, ".gz") > strings.TrimSuffix(newest, ".gz") {
+			newest = m
+		}
+	}
+	return lastChainHeadInFile(newest)
+}
+
+func lastChainHeadInFile(path string) (uint64, string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, "", err
+		}
+		defer func() { _ = zr.Close() }()
+		r = zr
+	}
+
+	var seq uint64
+	var head string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line chainLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.Hash == "" {
+			continue
+		}
+		seq, head = line.Seq, line.Hash
+	}
+	return seq, head, scanner.Err()
+}
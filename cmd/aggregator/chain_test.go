@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeChainedLog(t *testing.T, c *hashChain, events ...string) string {
+	t.Helper()
+	var lines []string
+	for _, ev := range events {
+		out, err := c.Append([]byte(ev))
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		lines = append(lines, out...)
+	}
+	if cp := c.Checkpoint(); cp != "" {
+		lines = append(lines, cp)
+	}
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writePublicKey(t *testing.T, pub ed25519.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "audit.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHashChainVerifies(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	c := &hashChain{signer: priv, checkpointEvery: 2}
+	path := writeChainedLog(t, c, `{"path":"/a"}`, `{"path": "/b"}`, `{"path":"/c"}`)
+
+	var out bytes.Buffer
+	if code := runVerify([]string{"-public-key", writePublicKey(t, pub), path}, &out); code != 0 {
+		t.Fatalf("expected verification to pass, got %d: %s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "OK: 3 records (seq 1-3), 2 checkpoints") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
+
+func TestHashChainDetectsTampering(t *testing.T) {
+	c := &hashChain{}
+	path := writeChainedLog(t, c, `{"path":"/a"}`, `{"path":"/b"}`, `{"path":"/c"}`)
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	modified := strings.Replace(string(data), `"/b"`, `"/x"`, 1)
+	removed := lines[0] + "\n" + lines[2] + "\n"
+	for name, content := range map[string]string{"modified": modified, "removed": removed} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if code := runVerify([]string{path}, &out); code != 1 {
+			t.Errorf("%s: expected verification to fail, got %d: %s", name, code, out.String())
+		}
+	}
+}
+
+func TestHashChainDetectsForgedCheckpoint(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	path := writeChainedLog(t, &hashChain{signer: priv, checkpointEvery: 10}, `{"path":"/a"}`)
+
+	var out bytes.Buffer
+	if code := runVerify([]string{"-public-key", writePublicKey(t, otherPub), path}, &out); code != 1 {
+		t.Errorf("expected signature check to fail, got %d: %s", code, out.String())
+	}
+}
+
+func TestLastChainHeadResumes(t *testing.T) {
+	c := &hashChain{}
+	path := writeChainedLog(t, c, `{"path":"/a"}`, `{"path":"/b"}`)
+
+	seq, head, err := lastChainHead(path)
+	if err != nil {
+		t.Fatalf("lastChainHead: %v", err)
+	}
+	if seq != 2 || head != c.head {
+		t.Errorf("expected seq 2 head %s, got seq %d head %s", c.head, seq, head)
+	}
+
+	// After rotation the active log is empty and the head comes from the
+	// newest rotated segment.
+	if err := os.Rename(path, path+".20240101T000000.000000000"); err != nil {
+		t.Fatal(err)
+	}
+	if seq, _, err := lastChainHead(path); err != nil || seq != 2 {
+		t.Errorf("expected to resume from rotated segment, got seq %d err %v", seq, err)
+	}
+}
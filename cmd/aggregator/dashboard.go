@@ -0,0 +1,126 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+const dashboardTopN = 10
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Pulsaar Audit</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; font-size: 0.9em; }
+th { background: #f0f0f0; }
+.summary { display: flex; gap: 2em; flex-wrap: wrap; }
+form input { margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>Pulsaar Audit</h1>
+<form method="get">
+{{if .Token}}<input type="hidden" name="token" value="{{.Token}}">{{end}}
+<label>Namespace <input name="namespace" value="{{.Filter.Namespace}}"></label>
+<label>User <input name="user" value="{{.Filter.User}}"></label>
+<label>Agent <input name="agent" value="{{.Filter.AgentID}}"></label>
+<label>Operation <input name="operation" value="{{.Filter.Operation}}"></label>
+<label>Path prefix <input name="path_prefix" value="{{.Filter.PathPrefix}}"></label>
+<button type="submit">Filter</button>
+</form>
+<p>{{len .Events}} matching events in the last {{.HistorySize}} received per namespace{{if .Scope}} visible to {{.Scope}}{{end}}.</p>
+<div class="summary">
+{{range .Summaries}}
+<table>
+<tr><th>{{.Title}}</th><th>Count</th></tr>
+{{range .Entries}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{else}}<tr><td colspan="2">none</td></tr>
+{{end}}
+</table>
+{{end}}
+</div>
+<h2>Recent accesses</h2>
+<table>
+<tr><th>Time</th><th>Namespace</th><th>Pod</th><th>User</th><th>Client</th><th>Operation</th><th>Path</th><th>Result</th><th>Bytes</th></tr>
+{{range .Recent}}<tr><td>{{.Timestamp}}</td><td>{{.Namespace}}</td><td>{{.Pod}}</td><td>{{.User}}</td><td>{{.ClientAddr}}</td><td>{{.Operation}}</td><td>{{.Path}}</td><td>{{.Result}}</td><td>{{.BytesRead}}</td></tr>
+{{else}}<tr><td colspan="9">No audit events recorded yet.</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+type dashboardSummary struct {
+	Title   string
+	Entries []countEntry
+}
+
+type dashboardData struct {
+	Filter      eventFilter
+	Token       string
+	Scope       string
+	HistorySize int
+	Events      []AuditLog
+	Recent      []AuditLog
+	Summaries   []dashboardSummary
+}
+
+func filterFromQuery(r *http.Request) eventFilter {
+	q := r.URL.Query()
+	return eventFilter{
+		Namespace:  q.Get("namespace"),
+		User:       q.Get("user"),
+		AgentID:    q.Get("agent"),
+		Operation:  q.Get("operation"),
+		PathPrefix: q.Get("path_prefix"),
+	}
+}
+
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scope, ok := scopeForRequest(r)
+	if !ok {
+		http.Error(w, "Valid query token required", http.StatusUnauthorized)
+		return
+	}
+	filter, ok := scopedFilter(filterFromQuery(r), scope)
+	if !ok {
+		http.Error(w, "Token does not grant access to this namespace", http.StatusForbidden)
+		return
+	}
+
+	events := history.Recent(filter, 0)
+	recent := events
+	if len(recent) > 100 {
+		recent = recent[:100]
+	}
+
+	data := dashboardData{
+		Filter:      filter,
+		Token:       r.URL.Query().Get("token"),
+		Scope:       scope.Name,
+		HistorySize: history.Size(),
+		Events:      events,
+		Recent:      recent,
+		Summaries: []dashboardSummary{
+			{Title: "Top paths", Entries: topCounts(events, func(a AuditLog) string { return a.Path }, dashboardTopN)},
+			{Title: "Top users", Entries: topCounts(events, func(a AuditLog) string { return a.User }, dashboardTopN)},
+			{Title: "Top agents", Entries: topCounts(events, func(a AuditLog) string { return a.AgentID }, dashboardTopN)},
+			{Title: "Namespaces", Entries: topCounts(events, func(a AuditLog) string { return a.Namespace }, 0)},
+		},
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, data); err != nil {
+		log.Printf("Error rendering dashboard: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEventStoreRecent(t *testing.T) {
+	s := newEventStore(3)
+	for _, p := range []string{"/a", "/b", "/c", "/d"} {
+		s.Add(AuditLog{Path: p, Namespace: "team-a"})
+	}
+
+	got := s.Recent(eventFilter{}, 0)
+	if len(got) != 3 || got[0].Path != "/d" || got[2].Path != "/b" {
+		t.Errorf("expected newest-first window of 3, got %+v", got)
+	}
+	if got := s.Recent(eventFilter{PathPrefix: "/c"}, 0); len(got) != 1 {
+		t.Errorf("expected 1 match for prefix filter, got %d", len(got))
+	}
+	if got := s.Recent(eventFilter{}, 1); len(got) != 1 {
+		t.Errorf("expected limit to apply, got %d", len(got))
+	}
+}
+
+func TestTopCounts(t *testing.T) {
+	events := []AuditLog{{Path: "/x"}, {Path: "/y"}, {Path: "/x"}, {Path: ""}}
+	top := topCounts(events, func(a AuditLog) string { return a.Path }, 1)
+	if len(top) != 1 || top[0].Key != "/x" || top[0].Count != 2 {
+		t.Errorf("unexpected top counts: %+v", top)
+	}
+
+	// A deduplicated event stands for every event it collapsed.
+	events = append(events, AuditLog{Path: "/y", Count: 5})
+	top = topCounts(events, func(a AuditLog) string { return a.Path }, 1)
+	if len(top) != 1 || top[0].Key != "/y" || top[0].Count != 6 {
+		t.Errorf("expected collapsed events to be weighed by their count, got %+v", top)
+	}
+}
+
+func TestHandleDashboard(t *testing.T) {
+	original := history
+	history = newEventStore(10)
+	defer func() { history = original }()
+
+	history.Add(AuditLog{Timestamp: "2023-01-01T00:00:00Z", Operation: "ReadFile", Path: "/etc/<passwd>", Namespace: "payments", User: "alice"})
+	history.Add(AuditLog{Timestamp: "2023-01-01T00:00:01Z", Operation: "Stat", Path: "/tmp", Namespace: "search", User: "bob"})
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard?namespace=payments", nil)
+	w := httptest.NewRecorder()
+	handleDashboard(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "/etc/&lt;passwd&gt;") {
+		t.Error("expected escaped path in dashboard")
+	}
+	if strings.Contains(body, "bob") {
+		t.Error("expected namespace filter to exclude other namespaces")
+	}
+}
+
+func TestEventStorePartitionsByNamespace(t *testing.T) {
+	s := newEventStore(2)
+	s.Add(AuditLog{Path: "/quiet", Namespace: "quiet"})
+	for _, p := range []string{"/1", "/2", "/3"} {
+		s.Add(AuditLog{Path: p, Namespace: "busy"})
+	}
+
+	if got := s.Recent(eventFilter{Namespace: "quiet"}, 0); len(got) != 1 {
+		t.Errorf("expected busy namespace not to evict quiet history, got %+v", got)
+	}
+	got := s.Recent(eventFilter{}, 0)
+	if len(got) != 3 || got[0].Path != "/3" || got[2].Path != "/quiet" {
+		t.Errorf("expected events merged newest first, got %+v", got)
+	}
+	scoped := s.Recent(eventFilter{Namespaces: map[string]bool{"busy": true}}, 0)
+	if len(scoped) != 2 {
+		t.Errorf("expected scope to hide other namespaces, got %+v", scoped)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxDedupGroups bounds the events held back for deduplication; beyond it
+// every group is written early.
+const maxDedupGroups = 10000
+
+// dedupKey identifies repeats of one access. Events by different users or
+// with different results are never merged, so the trail still shows who
+// accessed what and whether it was allowed.
+type dedupKey struct {
+	agent, operation, path, user, result string
+}
+
+// dedupGroup is the events of one key within a window.
+type dedupGroup struct {
+	key     dedupKey
+	first   auditRecord
+	last    string // timestamp of the latest event
+	count   int64
+	bytes   int64
+	elapsed int64
+	expires time.Time
+}
+
+// auditDeduplicator collapses identical events within a window into one
+// record with a count, so an agent polling a file in a tight loop writes
+// one line per window instead of one per read. Events are held until their
+// window ends and written in the order their windows began.
+type auditDeduplicator struct {
+	window time.Duration
+	write  func([]auditRecord)
+	now    func() time.Time
+
+	mu     sync.Mutex
+	groups map[dedupKey]*dedupGroup
+	order  []*dedupGroup // by expiry
+
+	// writeMu keeps writes in order when an overflowing Add and the
+	// flusher write at once.
+	writeMu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newAuditDeduplicator(window time.Duration, write func([]auditRecord)) *auditDeduplicator {
+	d := &auditDeduplicator{
+		window: window,
+		write:  write,
+		now:    time.Now,
+		groups: map[dedupKey]*dedupGroup{},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// newAuditDeduplicatorFromEnv returns the deduplicator set up by
+// PULSAAR_AUDIT_DEDUP_WINDOW, or nil when it is unset or 0.
+func newAuditDeduplicatorFromEnv(write func([]auditRecord)) (*auditDeduplicator, error) {
+	window, err := envDuration("PULSAAR_AUDIT_DEDUP_WINDOW")
+	if err != nil || window == 0 {
+		return nil, err
+	}
+	return newAuditDeduplicator(window, write), nil
+}
+
+// Add takes a batch of events in place of writeAuditBatch.
+func (d *auditDeduplicator) Add(batch []auditRecord) {
+	d.mu.Lock()
+	now := d.now()
+	for _, record := range batch {
+		a := record.audit
+		key := dedupKey{a.AgentID, a.Operation, a.Path, a.User, a.Result}
+		if g, ok := d.groups[key]; ok {
+			g.count += max(a.Count, 1)
+			g.bytes += a.BytesRead
+			g.elapsed += a.DurationMs
+			g.last = a.Timestamp
+			continue
+		}
+		g := &dedupGroup{
+			key:     key,
+			first:   record,
+			last:    a.Timestamp,
+			count:   max(a.Count, 1),
+			bytes:   a.BytesRead,
+			elapsed: a.DurationMs,
+			expires: now.Add(d.window),
+		}
+		d.groups[key] = g
+		d.order = append(d.order, g)
+	}
+	overflow := len(d.order) > maxDedupGroups
+	d.mu.Unlock()
+	if overflow {
+		log.Printf("More than %d distinct audit events within %s; writing them early", maxDedupGroups, d.window)
+		d.flush(time.Time{})
+	}
+}
+
+// flush writes the groups whose window ended by cutoff, or every group for
+// a zero cutoff.
+func (d *auditDeduplicator) flush(cutoff time.Time) {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	d.mu.Lock()
+	n := 0
+	for n < len(d.order) && (cutoff.IsZero() || !d.order[n].expires.After(cutoff)) {
+		n++
+	}
+	expired := d.order[:n:n]
+	d.order = d.order[n:]
+	for _, g := range expired {
+		delete(d.groups, g.key)
+	}
+	d.mu.Unlock()
+
+	if len(expired) == 0 {
+		return
+	}
+	records := make([]auditRecord, len(expired))
+	for i, g := range expired {
+		records[i] = g.record()
+	}
+	d.write(records)
+}
+
+// record is the one record written for the group: its first event, or for
+// repeats the first event with the count, the latest timestamp and the
+// bytes and time of them all.
+func (g *dedupGroup) record() auditRecord {
+	if g.count == 1 {
+		return g.first
+	}
+	a := g.first.audit
+	a.Count = g.count
+	a.LastTimestamp = g.last
+	a.BytesRead = g.bytes
+	a.DurationMs = g.elapsed
+	body, err := json.Marshal(a)
+	if err != nil {
+		log.Printf("Failed to encode deduplicated audit record: %v", err)
+		return g.first
+	}
+	return auditRecord{audit: a, body: body}
+}
+
+func (d *auditDeduplicator) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(max(d.window/4, 10*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.flush(d.now())
+		}
+	}
+}
+
+// Close writes every held event. Call it after the ingest queue is closed,
+// so nothing is added afterwards.
+func (d *auditDeduplicator) Close() {
+	close(d.stop)
+	<-d.done
+	d.flush(time.Time{})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuditDeduplicator(t *testing.T) {
+	var mu sync.Mutex
+	var written []auditRecord
+	d := newAuditDeduplicator(time.Hour, func(batch []auditRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, batch...)
+	})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	read := func(ts, user string) auditRecord {
+		a := AuditLog{Timestamp: ts, AgentID: "web-0", Operation: "ReadFile", Path: "/app/status", User: user, Result: "allowed", BytesRead: 10, DurationMs: 1}
+		body, _ := json.Marshal(a)
+		return auditRecord{audit: a, body: body}
+	}
+	d.Add([]auditRecord{read("t1", "alice"), read("t2", "alice"), read("t3", "bob")})
+	d.Add([]auditRecord{read("t4", "alice")})
+
+	// Nothing is written before the window ends.
+	d.flush(now.Add(time.Minute))
+	if len(written) != 0 {
+		t.Fatalf("expected events to be held for the window, got %d", len(written))
+	}
+	d.flush(now.Add(time.Hour))
+	if len(written) != 2 {
+		t.Fatalf("expected one record per user, got %+v", written)
+	}
+	alice, bob := written[0], written[1]
+	if alice.audit.Count != 3 || alice.audit.Timestamp != "t1" || alice.audit.LastTimestamp != "t4" || alice.audit.BytesRead != 30 {
+		t.Errorf("expected alice's reads collapsed into one record, got %+v", alice.audit)
+	}
+	var decoded AuditLog
+	if err := json.Unmarshal(alice.body, &decoded); err != nil || decoded.Count != 3 {
+		t.Errorf("expected the count in the written record, got %s", alice.body)
+	}
+	if bob.audit.Count != 0 || string(bob.body) != string(read("t3", "bob").body) {
+		t.Errorf("expected a single event to be written unchanged, got %s", bob.body)
+	}
+
+	// A new window starts after the last one was written, and Close
+	// writes what is still held.
+	d.Add([]auditRecord{read("t5", "alice")})
+	d.Close()
+	if len(written) != 3 || written[2].audit.Timestamp != "t5" {
+		t.Errorf("expected Close to write held events, got %+v", written)
+	}
+}
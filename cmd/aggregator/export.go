@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const parquetContentType = "application/vnd.apache.parquet"
+
+// exportFlushRows is how often a CSV export is flushed to the client.
+const exportFlushRows = 1000
+
+// exportRange is the period of an export: events at or after from and
+// before to. A zero bound is open.
+type exportRange struct {
+	from, to time.Time
+}
+
+func parseExportRange(r *http.Request) (exportRange, error) {
+	var rng exportRange
+	for _, bound := range []struct {
+		param string
+		t     *time.Time
+	}{{"from", &rng.from}, {"to", &rng.to}} {
+		v := r.URL.Query().Get(bound.param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return rng, fmt.Errorf("invalid %s %q; use an RFC 3339 time such as 2024-01-01T00:00:00Z", bound.param, v)
+		}
+		*bound.t = t
+	}
+	if !rng.from.IsZero() && !rng.to.IsZero() && !rng.from.Before(rng.to) {
+		return rng, fmt.Errorf("from must be before to")
+	}
+	return rng, nil
+}
+
+// contains reports whether the event's timestamp falls in the range.
+// Events without a readable timestamp are only in an unbounded export.
+func (rng exportRange) contains(a AuditLog) bool {
+	if rng.from.IsZero() && rng.to.IsZero() {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339Nano, a.Timestamp)
+	if err != nil {
+		return false
+	}
+	return !t.Before(rng.from) && (rng.to.IsZero() || t.Before(rng.to))
+}
+
+// auditLogFiles returns the audit log and its rotated segments, oldest
+// first, that may hold events in rng: a segment rotated before rng.from
+// holds only earlier events, and those after the first segment rotated
+// after rng.to only later ones. A segment still being compressed is read
+// uncompressed.
+func auditLogFiles(path string, rng exportRange) []string {
+	matches, _ := filepath.Glob(path + ".*")
+	present := map[string]bool{}
+	for _, m := range matches {
+		present[m] = true
+	}
+	var files []string
+	for _, m := range matches {
+		name := strings.TrimSuffix(m, ".gz")
+		if m != name && present[name] {
+			continue
+		}
+		rotated, err := time.Parse(rotatedTimeFormat, strings.TrimPrefix(name, path+"."))
+		if err != nil {
+			continue
+		}
+		if !rng.from.IsZero() && rotated.Before(rng.from) {
+			continue
+		}
+		files = append(files, m)
+	}
+	// Rotated segment names sort chronologically.
+	sort.Slice(files, func(i, j int) bool {
+		return strings.TrimSuffix(files[i], ".gz") < strings.TrimSuffix(files[j], ".gz")
+	})
+	for i, f := range files {
+		rotated, _ := time.Parse(rotatedTimeFormat, strings.TrimPrefix(strings.TrimSuffix(f, ".gz"), path+"."))
+		if !rng.to.IsZero() && !rotated.Before(rng.to) {
+			return files[:i+1]
+		}
+	}
+	return append(files, path)
+}
+
+// scanAuditFile calls fn with each event in an audit log file, plain or
+// hash-chained and optionally gzipped, skipping checkpoints and lines that
+// are not events, such as one still being written.
+func scanAuditFile(path string, fn func(AuditLog) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// Pruned or compressed since it was listed.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		defer func() { _ = zr.Close() }()
+		r = zr
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line chainLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.Checkpoint != nil {
+			continue
+		}
+		event := scanner.Bytes()
+		if line.Hash != "" {
+			event = line.Event
+		}
+		var a AuditLog
+		if err := json.Unmarshal(event, &a); err != nil || a.Operation == "" {
+			continue
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// csvSafe keeps a value from being read as a formula when the export is
+// opened in a spreadsheet, since paths and user names come from clients.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// handleExport serves GET /audit/export?format=csv|parquet&from=&to=,
+// streaming the events of a period from the audit log and its rotated
+// segments, oldest first, for compliance review in spreadsheet or data
+// tools. The query token and the filters of /api/v1/audit narrow the
+// export as they narrow a query.
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scope, ok := scopeForRequest(r)
+	if !ok {
+		http.Error(w, "Valid query token required", http.StatusUnauthorized)
+		return
+	}
+	filter, ok := scopedFilter(filterFromQuery(r), scope)
+	if !ok {
+		http.Error(w, "Token does not grant access to this namespace", http.StatusForbidden)
+		return
+	}
+	rng, err := parseExportRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "parquet" {
+		http.Error(w, "Invalid format; use csv or parquet", http.StatusBadRequest)
+		return
+	}
+	if auditFile == nil {
+		http.Error(w, "Audit log is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The export ends with the events on disk now; ask for later ones in
+	// the next period.
+	if err := auditFile.Sync(); err != nil {
+		log.Printf("Failed to sync audit log before export: %v", err)
+	}
+	files := auditLogFiles(auditFile.path, rng)
+
+	name := "audit"
+	if !rng.from.IsZero() {
+		name += "-" + rng.from.UTC().Format("20060102T150405Z")
+	}
+	if !rng.to.IsZero() {
+		name += "-to-" + rng.to.UTC().Format("20060102T150405Z")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	flusher, _ := w.(http.Flusher)
+
+	var write func(AuditLog) error
+	var finish func() error
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		header := make([]string, len(auditColumns))
+		for i, col := range auditColumns {
+			header[i] = col.name
+		}
+		_ = cw.Write(header)
+		rows := 0
+		row := make([]string, len(auditColumns))
+		write = func(a AuditLog) error {
+			for i, col := range auditColumns {
+				if col.num != nil {
+					row[i] = strconv.FormatInt(col.num(a), 10)
+				} else {
+					row[i] = csvSafe(col.str(a))
+				}
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+			if rows++; rows%exportFlushRows == 0 && flusher != nil {
+				cw.Flush()
+				flusher.Flush()
+			}
+			return cw.Error()
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case "parquet":
+		w.Header().Set("Content-Type", parquetContentType)
+		pw, err := newParquetWriter(w, "pulsaar-aggregator version "+version)
+		if err != nil {
+			log.Printf("Audit export failed: %v", err)
+			return
+		}
+		write = func(a AuditLog) error {
+			n := pw.total
+			if err := pw.Write(a); err != nil {
+				return err
+			}
+			if pw.total != n && flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		}
+		finish = pw.Close
+	}
+
+	for _, path := range files {
+		err := scanAuditFile(path, func(a AuditLog) error {
+			if !rng.contains(a) || !filter.Match(a) {
+				return nil
+			}
+			return write(a)
+		})
+		if err != nil {
+			// Too late for an error status; the truncated file shows it.
+			log.Printf("Audit export failed: %v", err)
+			return
+		}
+	}
+	if err := finish(); err != nil {
+		log.Printf("Audit export failed: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// withExportLog points the aggregator at an audit log with a gzipped
+// segment rotated at 01:00 and an active, hash-chained log.
+func withExportLog(t *testing.T) {
+	t.Helper()
+	withTenantHistory(t)
+	original := auditFile
+	t.Cleanup(func() { auditFile = original })
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	rotated, _ := time.Parse(time.RFC3339, "2024-01-01T01:00:00Z")
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = fmt.Fprintln(zw, `{"timestamp":"2024-01-01T00:10:00Z","operation":"ReadFile","path":"/etc/passwd","namespace":"payments","user":"alice","bytes_read":42}`)
+	_, _ = fmt.Fprintln(zw, `{"timestamp":"2024-01-01T00:20:00Z","operation":"Stat","path":"=cmd|' /C calc'!A0","namespace":"search"}`)
+	_ = zw.Close()
+	if err := os.WriteFile(path+"."+rotated.Format(rotatedTimeFormat)+".gz", gz.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := openRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	for _, line := range []string{
+		`{"seq":1,"prev_hash":"","hash":"h1","event":{"timestamp":"2024-01-01T01:30:00Z","operation":"ReadFile","path":"/app/config.yaml","namespace":"payments","result":"denied","count":3}}`,
+		`{"checkpoint":{"seq":1,"hash":"h1","time":"2024-01-01T01:30:01Z","signature":"c2ln"}}`,
+		`{"timestamp":"2024-01-01T02:30:00Z","operation":"ReadFile","path":"/late","namespace":"payments"}`,
+		`{"timestamp":"2024-01-01T02:40:00Z","operation":"Rea`,
+	} {
+		_, _ = f.WriteString(line + "\n")
+	}
+	auditFile = f
+}
+
+func export(t *testing.T, token, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/audit/export?"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handleExport(w, req)
+	return w
+}
+
+func TestHandleExportCSV(t *testing.T) {
+	withExportLog(t)
+
+	w := export(t, "sec-secret", "format=csv&to=2024-01-01T02:00:00Z")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="audit-to-20240101T020000Z.csv"` {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][0] != "timestamp" || rows[0][2] != "path" {
+		t.Fatalf("expected a header and three events, got %q", rows)
+	}
+	var paths []string
+	for _, row := range rows[1:] {
+		paths = append(paths, row[2])
+	}
+	if want := []string{"/etc/passwd", `'=cmd|' /C calc'!A0`, "/app/config.yaml"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got paths %q, want %q", paths, want)
+	}
+	if rows[1][13] != "42" || rows[3][15] != "3" || rows[1][15] != "1" {
+		t.Errorf("unexpected numeric columns %q", rows)
+	}
+
+	// A namespace-scoped token exports only its namespaces, and a period
+	// after the rotation skips the rotated segment.
+	w = export(t, "pay-secret", "from=2024-01-01T01:00:00Z")
+	rows, _ = csv.NewReader(w.Body).ReadAll()
+	if len(rows) != 3 || rows[1][2] != "/app/config.yaml" || rows[2][2] != "/late" {
+		t.Errorf("unexpected scoped export %q", rows)
+	}
+}
+
+func TestHandleExportErrors(t *testing.T) {
+	withExportLog(t)
+	for query, code := range map[string]int{
+		"format=xlsx":    http.StatusBadRequest,
+		"from=yesterday": http.StatusBadRequest,
+		"from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z": http.StatusBadRequest,
+		"namespace=search": http.StatusForbidden,
+	} {
+		if w := export(t, "pay-secret", query); w.Code != code {
+			t.Errorf("%s: expected %d, got %d", query, code, w.Code)
+		}
+	}
+}
+
+func TestHandleExportParquet(t *testing.T) {
+	withExportLog(t)
+
+	w := export(t, "sec-secret", "format=parquet")
+	if w.Header().Get("Content-Type") != parquetContentType {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	columns := readParquet(t, w.Body.Bytes())
+	if want := []any{"/etc/passwd", `=cmd|' /C calc'!A0`, "/app/config.yaml", "/late"}; !reflect.DeepEqual(columns["path"], want) {
+		t.Errorf("got paths %v, want %v", columns["path"], want)
+	}
+	if want := []any{int64(42), int64(0), int64(0), int64(0)}; !reflect.DeepEqual(columns["bytes_read"], want) {
+		t.Errorf("got bytes_read %v, want %v", columns["bytes_read"], want)
+	}
+}
+
+func TestParquetWriterRowGroups(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := newParquetWriter(&buf, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := parquetRowGroupRows + 5
+	for i := 0; i < n; i++ {
+		if err := pw.Write(AuditLog{Operation: "Stat", Path: fmt.Sprintf("/f%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	paths := readParquet(t, buf.Bytes())["path"]
+	if len(paths) != n || paths[n-1] != fmt.Sprintf("/f%d", n-1) {
+		t.Errorf("expected %d paths across two row groups, got %d", n, len(paths))
+	}
+
+	buf.Reset()
+	pw, _ = newParquetWriter(&buf, "test")
+	_ = pw.Close()
+	if columns := readParquet(t, buf.Bytes()); len(columns["path"]) != 0 {
+		t.Errorf("expected an empty file, got %v", columns)
+	}
+}
+
+// readParquet decodes a file written by parquetWriter into its column
+// values, reading the footer and pages as a Parquet reader would.
+func readParquet(t *testing.T, data []byte) map[string][]any {
+	t.Helper()
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("missing Parquet magic")
+	}
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := (&thriftReader{b: data[len(data)-8-n : len(data)-8]}).readStruct()
+
+	schema := meta[2].([]any)
+	types := map[string]int64{}
+	for _, el := range schema[1:] {
+		s := el.(map[int16]any)
+		types[string(s[4].([]byte))] = s[1].(int64)
+	}
+	if int(schema[0].(map[int16]any)[5].(int64)) != len(schema)-1 {
+		t.Fatalf("root schema element has the wrong number of children")
+	}
+
+	columns := map[string][]any{}
+	groups, _ := meta[4].([]any)
+	var rows int64
+	for _, g := range groups {
+		group := g.(map[int16]any)
+		rows += group[3].(int64)
+		for _, c := range group[1].([]any) {
+			cm := c.(map[int16]any)[3].(map[int16]any)
+			name := string(cm[3].([]any)[0].([]byte))
+			if cm[4].(int64) != parquetGzip {
+				t.Fatalf("%s: unexpected codec %d", name, cm[4])
+			}
+			r := &thriftReader{b: data[cm[9].(int64):]}
+			header := r.readStruct()
+			page := data[cm[9].(int64)+int64(r.pos):][:header[3].(int64)]
+			zr, err := gzip.NewReader(bytes.NewReader(page))
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			values, _ := io.ReadAll(zr)
+			if int64(len(values)) != header[2].(int64) {
+				t.Fatalf("%s: page is %d bytes, header says %d", name, len(values), header[2])
+			}
+			count := header[5].(map[int16]any)[1].(int64)
+			for i := int64(0); i < count; i++ {
+				if types[name] == parquetInt64 {
+					columns[name] = append(columns[name], int64(binary.LittleEndian.Uint64(values)))
+					values = values[8:]
+					continue
+				}
+				l := binary.LittleEndian.Uint32(values)
+				columns[name] = append(columns[name], string(values[4:4+l]))
+				values = values[4+l:]
+			}
+		}
+	}
+	if rows != meta[3].(int64) {
+		t.Fatalf("row groups hold %d rows, footer says %d", rows, meta[3])
+	}
+	return columns
+}
+
+// thriftReader decodes the Thrift compact protocol types parquetWriter
+// uses. Structs become maps by field id, integers int64 and binaries
+// []byte.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		u := r.uvarint()
+		return int64(u>>1) ^ -int64(u&1)
+	case thriftBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return r.b[r.pos-n : r.pos]
+	case thriftList:
+		h := r.b[r.pos]
+		r.pos++
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(h & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unexpected thrift type %d", typ))
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := map[int16]any{}
+	var id int16
+	for {
+		h := r.b[r.pos]
+		r.pos++
+		if h == 0 {
+			return fields
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			u := r.uvarint()
+			id = int16(int64(u>>1) ^ -int64(u&1))
+		}
+		fields[id] = r.value(h & 0x0f)
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	for in, want := range map[string]string{"/etc": "/etc", "=1+1": "'=1+1", "@SUM(A1)": "'@SUM(A1)", "": ""} {
+		if got := csvSafe(in); got != want {
+			t.Errorf("csvSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
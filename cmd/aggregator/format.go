@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output formats accepted by PULSAAR_SYSLOG_FORMAT and forwarding
+// destinations.
+const (
+	formatJSON    = "json"
+	formatRFC5424 = "rfc5424"
+	formatCEF     = "cef"
+	formatLEEF    = "leef"
+)
+
+const (
+	siemVendor  = "Pulsaar"
+	siemProduct = "Pulsaar"
+)
+
+// parseOutputFormat validates the format named by env var name against the
+// formats a sink supports, returning def when it is unset.
+func parseOutputFormat(name, def string, allowed ...string) (string, error) {
+	v := strings.ToLower(os.Getenv(name))
+	if v == "" {
+		return def, nil
+	}
+	for _, a := range allowed {
+		if v == a {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid %s %q. Supported formats: %s", name, v, strings.Join(allowed, ", "))
+}
+
+// auditSeverity maps the outcome of an access to a 0-10 SIEM severity.
+func auditSeverity(audit AuditLog) int {
+	switch audit.Result {
+	case "denied":
+		return 7
+	case "error":
+		return 5
+	default:
+		return 3
+	}
+}
+
+func auditEpochMillis(audit AuditLog) string {
+	t, err := time.Parse(time.RFC3339, audit.Timestamp)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// clientIP returns the host part of ClientAddr when it is an IP address.
+func clientIP(audit AuditLog) string {
+	host, _, err := net.SplitHostPort(audit.ClientAddr)
+	if err != nil {
+		host = audit.ClientAddr
+	}
+	if net.ParseIP(host) == nil {
+		return ""
+	}
+	return host
+}
+
+// formatCEFEvent renders an audit event in ArcSight Common Event Format.
+func formatCEFEvent(audit AuditLog) string {
+	header := strings.Join([]string{
+		"CEF:0",
+		escapeCEFHeader(siemVendor),
+		escapeCEFHeader(siemProduct),
+		escapeCEFHeader(version),
+		escapeCEFHeader(audit.Operation),
+		escapeCEFHeader("Pulsaar " + audit.Operation),
+		strconv.Itoa(auditSeverity(audit)),
+	}, "|")
+
+	var ext []string
+	add := func(key, value string) {
+		if value != "" {
+			ext = append(ext, key+"="+escapeCEFExtension(value))
+		}
+	}
+	labeled := func(key, label, value string) {
+		if value != "" {
+			add(key+"Label", label)
+			add(key, value)
+		}
+	}
+
+	add("rt", auditEpochMillis(audit))
+	add("act", audit.Operation)
+	add("filePath", audit.Path)
+	add("suser", audit.User)
+	add("src", clientIP(audit))
+	add("dvchost", audit.AgentID)
+	add("outcome", audit.Result)
+	add("reason", audit.ErrorCode)
+	add("externalId", audit.RequestID)
+	labeled("cs1", "namespace", audit.Namespace)
+	labeled("cs2", "pod", audit.Pod)
+	labeled("cs3", "container", audit.Container)
+	labeled("cs4", "node", audit.Node)
+	if audit.BytesRead > 0 {
+		add("out", strconv.FormatInt(audit.BytesRead, 10))
+	}
+	if audit.DurationMs > 0 {
+		labeled("cn1", "durationMs", strconv.FormatInt(audit.DurationMs, 10))
+	}
+	if audit.Count > 1 {
+		add("cnt", strconv.FormatInt(audit.Count, 10))
+	}
+	return header + "|" + strings.Join(ext, " ")
+}
+
+// formatLEEFEvent renders an audit event in IBM QRadar Log Event Extended
+// Format 1.0 with tab-separated attributes.
+func formatLEEFEvent(audit AuditLog) string {
+	header := strings.Join([]string{
+		"LEEF:1.0",
+		escapeLEEFHeader(siemVendor),
+		escapeLEEFHeader(siemProduct),
+		escapeLEEFHeader(version),
+		escapeLEEFHeader(audit.Operation),
+	}, "|")
+
+	var attrs []string
+	add := func(key, value string) {
+		if value != "" {
+			attrs = append(attrs, key+"="+escapeLEEFValue(value))
+		}
+	}
+
+	add("devTime", auditEpochMillis(audit))
+	add("cat", audit.Operation)
+	add("sev", strconv.Itoa(auditSeverity(audit)))
+	add("resource", audit.Path)
+	add("usrName", audit.User)
+	add("src", clientIP(audit))
+	add("identHostName", audit.AgentID)
+	add("namespace", audit.Namespace)
+	add("pod", audit.Pod)
+	add("container", audit.Container)
+	add("node", audit.Node)
+	add("result", audit.Result)
+	add("reason", audit.ErrorCode)
+	add("requestId", audit.RequestID)
+	if audit.BytesRead > 0 {
+		add("dstBytes", strconv.FormatInt(audit.BytesRead, 10))
+	}
+	if audit.DurationMs > 0 {
+		add("durationMs", strconv.FormatInt(audit.DurationMs, 10))
+	}
+	if audit.Count > 1 {
+		add("count", strconv.FormatInt(audit.Count, 10))
+	}
+	return header + "|" + strings.Join(attrs, "\t")
+}
+
+func escapeCEFHeader(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ").Replace(v)
+}
+
+func escapeCEFExtension(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`).Replace(v)
+}
+
+func escapeLEEFHeader(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\t", " ", "\r", " ", "\n", " ").Replace(v)
+}
+
+func escapeLEEFValue(v string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(v)
+}
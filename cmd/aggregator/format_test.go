@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+var siemAudit = AuditLog{
+	Timestamp:  "2023-01-01T00:00:00Z",
+	Operation:  "ReadFile",
+	Path:       `/etc/a=b|c`,
+	AgentID:    "agent-1",
+	User:       "alice",
+	Namespace:  "payments",
+	Result:     "denied",
+	ClientAddr: "10.0.0.7:51234",
+	BytesRead:  42,
+}
+
+func TestFormatCEFEvent(t *testing.T) {
+	msg := formatCEFEvent(siemAudit)
+
+	if !strings.HasPrefix(msg, "CEF:0|Pulsaar|Pulsaar|dev|ReadFile|Pulsaar ReadFile|7|") {
+		t.Errorf("unexpected CEF header: %s", msg)
+	}
+	for _, want := range []string{
+		"rt=1672531200000", `filePath=/etc/a\=b|c`, "suser=alice", "src=10.0.0.7",
+		"cs1Label=namespace cs1=payments", "outcome=denied", "out=42",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in %s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "cs2Label") {
+		t.Errorf("expected empty pod to be omitted: %s", msg)
+	}
+}
+
+func TestFormatLEEFEvent(t *testing.T) {
+	msg := formatLEEFEvent(siemAudit)
+
+	if !strings.HasPrefix(msg, "LEEF:1.0|Pulsaar|Pulsaar|dev|ReadFile|devTime=1672531200000\t") {
+		t.Errorf("unexpected LEEF header: %s", msg)
+	}
+	for _, want := range []string{"\tsev=7\t", "\tresource=/etc/a=b|c\t", "\tusrName=alice\t", "\tdstBytes=42"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in %q", want, msg)
+		}
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	t.Setenv("PULSAAR_SYSLOG_FORMAT", "CEF")
+	if f, err := parseOutputFormat("PULSAAR_SYSLOG_FORMAT", formatRFC5424, formatRFC5424, formatCEF); err != nil || f != formatCEF {
+		t.Errorf("expected cef, got %q, %v", f, err)
+	}
+	t.Setenv("PULSAAR_SYSLOG_FORMAT", "xml")
+	if _, err := parseOutputFormat("PULSAAR_SYSLOG_FORMAT", formatRFC5424, formatRFC5424, formatCEF); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
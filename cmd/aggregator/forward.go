@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultForwardQueueSize = 1000
+	// forwardCloseTimeout bounds how long shutdown waits for destinations
+	// to take the events still queued for them.
+	forwardCloseTimeout = 10 * time.Second
+)
+
+// forwardConfig is the JSON document referenced by
+// PULSAAR_FORWARD_CONFIG_FILE.
+type forwardConfig struct {
+	Destinations []forwardDestination `json:"destinations"`
+}
+
+// forwardDestination is an HTTP endpoint that receives a copy of each audit
+// event its filters match, one POST per event. Filters that are set must all
+// match; a destination without filters receives every event.
+type forwardDestination struct {
+	Name         string            `json:"name"`
+	URL          string            `json:"url"`
+	Format       string            `json:"format,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Namespaces   []string          `json:"namespaces,omitempty"`
+	Operations   []string          `json:"operations,omitempty"`
+	PathPrefixes []string          `json:"path_prefixes,omitempty"`
+	Results      []string          `json:"results,omitempty"`
+	QueueSize    int               `json:"queue_size,omitempty"`
+}
+
+func (d *forwardDestination) Match(a AuditLog) bool {
+	if len(d.Namespaces) > 0 && !slices.Contains(d.Namespaces, a.Namespace) {
+		return false
+	}
+	if len(d.Operations) > 0 && !slices.Contains(d.Operations, a.Operation) {
+		return false
+	}
+	if len(d.Results) > 0 && !slices.Contains(d.Results, a.Result) {
+		return false
+	}
+	if len(d.PathPrefixes) > 0 && !slices.ContainsFunc(d.PathPrefixes, func(p string) bool { return strings.HasPrefix(a.Path, p) }) {
+		return false
+	}
+	return true
+}
+
+// forwardSink delivers to one destination from its own queue, so a slow or
+// unreachable destination neither delays the audit log nor the others.
+type forwardSink struct {
+	dest   forwardDestination
+	client *http.Client
+	queue  chan auditRecord
+	done   chan struct{}
+}
+
+// forwarder fans audit events out to every destination whose filters
+// match.
+type forwarder struct {
+	sinks []*forwardSink
+}
+
+var forwarding *forwarder
+
+// initForwarding loads the destinations in PULSAAR_FORWARD_CONFIG_FILE.
+// PULSAAR_EXTERNAL_LOG_URL, which predates it, adds one more destination
+// without filters.
+func initForwarding() error {
+	var cfg forwardConfig
+	if path := os.Getenv("PULSAAR_FORWARD_CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read forwarding config file: %v", err)
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("failed to parse forwarding config file: %v", err)
+		}
+	}
+	if externalURL := os.Getenv("PULSAAR_EXTERNAL_LOG_URL"); externalURL != "" {
+		format, err := parseOutputFormat("PULSAAR_EXTERNAL_LOG_FORMAT", formatJSON, formatJSON, formatCEF, formatLEEF)
+		if err != nil {
+			return err
+		}
+		cfg.Destinations = append(cfg.Destinations, forwardDestination{Name: "external", URL: externalURL, Format: format})
+	}
+	if len(cfg.Destinations) == 0 {
+		return nil
+	}
+	f, err := newForwarder(cfg)
+	if err != nil {
+		return err
+	}
+	forwarding = f
+	for _, s := range f.sinks {
+		log.Printf("Forwarding audit events to %s (%s) as %s", s.dest.Name, redactURL(s.dest.URL), s.dest.Format)
+	}
+	return nil
+}
+
+func newForwarder(cfg forwardConfig) (*forwarder, error) {
+	f := &forwarder{}
+	names := map[string]bool{}
+	for _, d := range cfg.Destinations {
+		if d.Name == "" {
+			return nil, fmt.Errorf("forwarding destination %q requires a name", d.URL)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("duplicate forwarding destination %q", d.Name)
+		}
+		names[d.Name] = true
+		u, err := url.Parse(d.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("forwarding destination %q requires an http:// or https:// url", d.Name)
+		}
+		switch d.Format = strings.ToLower(d.Format); d.Format {
+		case "":
+			d.Format = formatJSON
+		case formatJSON, formatCEF, formatLEEF:
+		default:
+			return nil, fmt.Errorf("forwarding destination %q has invalid format %q. Supported formats: json, cef, leef", d.Name, d.Format)
+		}
+		if d.QueueSize < 0 {
+			return nil, fmt.Errorf("forwarding destination %q has a negative queue_size", d.Name)
+		}
+		if d.QueueSize == 0 {
+			d.QueueSize = defaultForwardQueueSize
+		}
+		s := &forwardSink{
+			dest:   d,
+			client: &http.Client{Timeout: 10 * time.Second},
+			queue:  make(chan auditRecord, d.QueueSize),
+			done:   make(chan struct{}),
+		}
+		go s.run()
+		f.sinks = append(f.sinks, s)
+	}
+	return f, nil
+}
+
+// redactURL drops credentials and the query, which may hold a token, from a
+// URL for logging.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.User, u.RawQuery = nil, ""
+	return u.String()
+}
+
+// Send queues each event for the destinations it matches, dropping it for
+// a destination whose queue is full; the audit log still has it.
+func (f *forwarder) Send(batch []auditRecord) {
+	for _, s := range f.sinks {
+		dropped := 0
+		for _, record := range batch {
+			if !s.dest.Match(record.audit) {
+				continue
+			}
+			select {
+			case s.queue <- record:
+			default:
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			log.Printf("Forwarding queue for %s is full, dropped %d audit events", s.dest.Name, dropped)
+		}
+	}
+}
+
+// Close delivers what is queued, giving up after forwardCloseTimeout.
+func (f *forwarder) Close() {
+	for _, s := range f.sinks {
+		close(s.queue)
+	}
+	deadline := time.After(forwardCloseTimeout)
+	for _, s := range f.sinks {
+		select {
+		case <-s.done:
+		case <-deadline:
+			log.Printf("Gave up forwarding the remaining audit events to %s", s.dest.Name)
+		}
+	}
+}
+
+func (s *forwardSink) run() {
+	defer close(s.done)
+	for record := range s.queue {
+		if err := s.post(record); err != nil {
+			log.Printf("Failed to forward audit event to %s: %v", s.dest.Name, err)
+		}
+	}
+}
+
+func (s *forwardSink) post(record auditRecord) error {
+	contentType, payload := "application/json", record.body
+	switch s.dest.Format {
+	case formatCEF:
+		contentType, payload = "text/plain", []byte(formatCEFEvent(record.audit))
+	case formatLEEF:
+		contentType, payload = "text/plain", []byte(formatLEEFEvent(record.audit))
+	}
+	req, err := http.NewRequest(http.MethodPost, s.dest.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range s.dest.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
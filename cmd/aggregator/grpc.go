@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// auditSinkServer accepts audit events from agents over a long-lived client
+// stream and feeds them into the same pipeline as POST /audit.
+type auditSinkServer struct {
+	api.UnimplementedAuditSinkServer
+}
+
+func (s *auditSinkServer) StreamAudit(stream api.AuditSink_StreamAuditServer) error {
+	source := ""
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		source = sourceFromAddr(p.Addr.String())
+	}
+
+	var received int64
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&api.AuditAck{Received: received})
+		}
+		if err != nil {
+			return err
+		}
+
+		if !ingestLimiter.Allow(source) {
+			return status.Errorf(codes.ResourceExhausted, "Rate limit exceeded after %d events. Retry later.", received)
+		}
+
+		audit := auditFromEvent(ev)
+		if err := validateAudit(audit); err != nil {
+			// Ending the stream would only have the agent send the event
+			// again; drop it and carry on.
+			log.Printf("Rejected audit event from %s: %v", source, err)
+			received++
+			continue
+		}
+		body, err := json.Marshal(audit)
+		if err != nil {
+			return status.Errorf(codes.Internal, "Unable to encode audit event: %v", err)
+		}
+		if !submitAudit(auditRecord{audit: audit, body: body}) {
+			return status.Errorf(codes.ResourceExhausted, "Audit queue is full after %d events. Retry later.", received)
+		}
+		received++
+	}
+}
+
+func auditFromEvent(ev *api.AuditEvent) AuditLog {
+	return AuditLog{
+		Timestamp:  ev.Timestamp,
+		Operation:  ev.Operation,
+		Path:       ev.Path,
+		AgentID:    ev.AgentId,
+		User:       ev.User,
+		Namespace:  ev.Namespace,
+		Pod:        ev.Pod,
+		Container:  ev.Container,
+		Node:       ev.Node,
+		Result:     ev.Result,
+		BytesRead:  ev.BytesRead,
+		DurationMs: ev.DurationMs,
+		RequestID:  ev.RequestId,
+		ClientAddr: ev.ClientAddr,
+		ErrorCode:  ev.ErrorCode,
+	}
+}
+
+func startGRPCServer(tlsConfig *tls.Config) (*grpc.Server, error) {
+	port := os.Getenv("PULSAAR_AGGREGATOR_GRPC_PORT")
+	if port == "" {
+		port = "8081"
+	}
+
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(int(maxAuditBodyBytes))}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	s := grpc.NewServer(opts...)
+	api.RegisterAuditSinkServer(s, &auditSinkServer{})
+
+	go func() {
+		log.Printf("Audit sink gRPC server listening on :%s (tls=%t)", port, tlsConfig != nil)
+		if err := s.Serve(lis); err != nil {
+			log.Printf("gRPC audit sink stopped: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// stopGRPCServer waits for open audit streams to finish until ctx expires,
+// then closes them. Agents reconnect and resend whatever was not received.
+func stopGRPCServer(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Closing remaining audit streams")
+		s.Stop()
+		<-done
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestStreamAudit(t *testing.T) {
+	original := history
+	history = newEventStore(10)
+	defer func() { history = original }()
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	api.RegisterAuditSinkServer(s, &auditSinkServer{})
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	stream, err := api.NewAuditSinkClient(conn).StreamAudit(context.Background())
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	for _, p := range []string{"/etc/hosts", "/var/log/app.log"} {
+		if err := stream.Send(&api.AuditEvent{Timestamp: "2023-01-01T00:00:00Z", Operation: "ReadFile", Path: p, AgentId: "agent-1"}); err != nil {
+			t.Fatalf("send failed: %v", err)
+		}
+	}
+	ack, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if ack.Received != 2 {
+		t.Errorf("expected 2 events acknowledged, got %d", ack.Received)
+	}
+
+	events := history.Recent(eventFilter{AgentID: "agent-1"}, 0)
+	if len(events) != 2 || events[0].Path != "/var/log/app.log" {
+		t.Errorf("expected streamed events in history, got %+v", events)
+	}
+}
+
+func TestStopGRPCServerClosesOpenStreams(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	api.RegisterAuditSinkServer(s, &auditSinkServer{})
+	go func() { _ = s.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	stream, err := api.NewAuditSinkClient(conn).StreamAudit(context.Background())
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	if err := stream.Send(&api.AuditEvent{Operation: "Stat", Path: "/tmp"}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		stopGRPCServer(ctx, s)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stopGRPCServer did not return after its deadline")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIngestQueueSize     = 10000
+	defaultIngestBatchSize     = 100
+	defaultIngestFlushInterval = 200 * time.Millisecond
+)
+
+type auditRecord struct {
+	audit AuditLog
+	body  []byte
+}
+
+// ingestQueue decouples HTTP ingestion from disk and exporter writes. Events
+// are buffered in a bounded channel and written in batches by a single
+// worker; when the buffer is full Enqueue reports false so the handler can
+// push back on the agent instead of blocking it.
+type ingestQueue struct {
+	records       chan auditRecord
+	batchSize     int
+	flushInterval time.Duration
+	write         func([]auditRecord)
+
+	closeOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{}
+}
+
+var ingest *ingestQueue
+
+func newIngestQueue(size, batchSize int, flushInterval time.Duration, write func([]auditRecord)) *ingestQueue {
+	q := &ingestQueue{
+		records:       make(chan auditRecord, size),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		write:         write,
+		done:          make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func newIngestQueueFromEnv(write func([]auditRecord)) (*ingestQueue, error) {
+	size, err := envPositiveInt("PULSAAR_INGEST_QUEUE_SIZE", defaultIngestQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := envPositiveInt("PULSAAR_INGEST_BATCH_SIZE", defaultIngestBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := envDuration("PULSAAR_INGEST_FLUSH_INTERVAL")
+	if err != nil {
+		return nil, err
+	}
+	if flushInterval == 0 {
+		flushInterval = defaultIngestFlushInterval
+	}
+	return newIngestQueue(size, batchSize, flushInterval, write), nil
+}
+
+// Enqueue adds a record without blocking. It returns false when the queue is
+// full or has been closed.
+func (q *ingestQueue) Enqueue(record auditRecord) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.records <- record:
+		return true
+	default:
+		return false
+	}
+}
+
+// Len reports the number of records waiting to be written.
+func (q *ingestQueue) Len() int {
+	return len(q.records)
+}
+
+// Close stops accepting records and waits until everything already queued
+// has been written.
+func (q *ingestQueue) Close() {
+	q.closeOnce.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		close(q.records)
+		q.mu.Unlock()
+	})
+	<-q.done
+}
+
+func (q *ingestQueue) run() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]auditRecord, 0, q.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		q.write(batch)
+		batch = make([]auditRecord, 0, q.batchSize)
+	}
+
+	for {
+		select {
+		case record, ok := <-q.records:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= q.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func envPositiveInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a positive integer", name, v)
+	}
+	return n, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIngestQueueBatches(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]auditRecord
+	q := newIngestQueue(10, 2, time.Hour, func(batch []auditRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, batch)
+	})
+
+	for i := 0; i < 3; i++ {
+		if !q.Enqueue(auditRecord{audit: AuditLog{Operation: "Stat"}}) {
+			t.Fatalf("enqueue %d rejected", i)
+		}
+	}
+	q.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Errorf("unexpected batching: %v", batches)
+	}
+	if q.Enqueue(auditRecord{}) {
+		t.Error("expected enqueue after close to be rejected")
+	}
+}
+
+func TestHandleAuditQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	q := newIngestQueue(1, 1, time.Hour, func([]auditRecord) { <-block })
+	ingest = q
+	defer func() {
+		close(block)
+		q.Close()
+		ingest = nil
+	}()
+
+	auditData := `{"timestamp":"2023-01-01T00:00:00Z","operation":"ReadFile","path":"/etc/passwd"}`
+	var codes []int
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/audit", bytes.NewBufferString(auditData))
+		w := httptest.NewRecorder()
+		handleAudit(w, req)
+		codes = append(codes, w.Code)
+	}
+
+	last := codes[len(codes)-1]
+	if last != http.StatusTooManyRequests {
+		t.Errorf("expected 429 once the queue is full, got %v", codes)
+	}
+}
+
+func TestHandleAuditBatch(t *testing.T) {
+	var mu sync.Mutex
+	var received []auditRecord
+	q := newIngestQueue(10, 10, time.Millisecond, func(batch []auditRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, batch...)
+	})
+	ingest = q
+	defer func() { ingest = nil }()
+
+	batch := `[
+  {"timestamp": "2023-01-01T00:00:00Z", "operation": "ReadFile", "path": "/etc/hosts", "result": "allowed"},
+  {"timestamp": "2023-01-01T00:00:01Z", "operation": "Stat", "path": "/etc/shadow", "result": "denied"}
+]`
+	w := httptest.NewRecorder()
+	handleAudit(w, httptest.NewRequest(http.MethodPost, "/audit", bytes.NewBufferString(batch)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	q.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[1].audit.Result != "denied" {
+		t.Fatalf("expected both events in order, got %v", received)
+	}
+	if got := string(received[0].body); got != `{"timestamp":"2023-01-01T00:00:00Z","operation":"ReadFile","path":"/etc/hosts","result":"allowed"}` {
+		t.Errorf("expected each event compacted onto one line, got %s", got)
+	}
+}
+
+func TestHandleAuditBatchQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	q := newIngestQueue(1, 1, time.Hour, func([]auditRecord) { <-block })
+	ingest = q
+	defer func() {
+		close(block)
+		q.Close()
+		ingest = nil
+	}()
+
+	// The worker holds the first event and the queue the second.
+	batch := `[{"timestamp":"2023-01-01T00:00:00Z","operation":"Stat","path":"/a"},{"timestamp":"2023-01-01T00:00:00Z","operation":"Stat","path":"/b"},{"timestamp":"2023-01-01T00:00:00Z","operation":"Stat","path":"/c"},{"timestamp":"2023-01-01T00:00:00Z","operation":"Stat","path":"/d"}]`
+	w := httptest.NewRecorder()
+	handleAudit(w, httptest.NewRequest(http.MethodPost, "/audit", bytes.NewBufferString(batch)))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if n := w.Header().Get(acceptedEventsHeader); n == "" || n == "0" || n == "4" {
+		t.Errorf("expected part of the batch to be accepted, got %q", n)
+	}
+}
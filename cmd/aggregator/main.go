@@ -2,15 +2,26 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+const defaultShutdownTimeout = 20 * time.Second
+
 var (
 	version = "dev"
 	commit  = "none"
@@ -18,19 +29,41 @@ var (
 )
 
 type AuditLog struct {
-	Timestamp string `json:"timestamp"`
-	Operation string `json:"operation"`
-	Path      string `json:"path"`
-	AgentID   string `json:"agent_id,omitempty"`
+	Timestamp  string `json:"timestamp"`
+	Operation  string `json:"operation"`
+	Path       string `json:"path"`
+	AgentID    string `json:"agent_id,omitempty"`
+	User       string `json:"user,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Pod        string `json:"pod,omitempty"`
+	Container  string `json:"container,omitempty"`
+	Node       string `json:"node,omitempty"`
+	Result     string `json:"result,omitempty"` // allowed, denied or error
+	BytesRead  int64  `json:"bytes_read,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	ClientAddr string `json:"client_addr,omitempty"`
+	ErrorCode  string `json:"error_code,omitempty"` // gRPC code of a denied or failed request
+	// Count is how many identical events a deduplicated record stands
+	// for, from Timestamp to LastTimestamp; absent for a single event.
+	Count         int64  `json:"count,omitempty"`
+	LastTimestamp string `json:"last_timestamp,omitempty"`
+	// SchemaVersion is the audit event schema the sender wrote; absent
+	// means version 1.
+	SchemaVersion int `json:"schema_version,omitempty"`
 }
 
-var auditFile *os.File
+var auditFile *rotatingFile
 
-func initAuditFile() error {
-	auditLogPath := os.Getenv("PULSAAR_AUDIT_LOG_PATH")
-	if auditLogPath == "" {
-		auditLogPath = "/var/log/pulsaar/audit.log"
+func auditLogPath() string {
+	if path := os.Getenv("PULSAAR_AUDIT_LOG_PATH"); path != "" {
+		return path
 	}
+	return "/var/log/pulsaar/audit.log"
+}
+
+func initAuditFile() error {
+	auditLogPath := auditLogPath()
 
 	// Ensure directory exists
 	dir := filepath.Dir(auditLogPath)
@@ -38,11 +71,19 @@ func initAuditFile() error {
 		return fmt.Errorf("failed to create audit log directory: %v", err)
 	}
 
-	var err error
-	auditFile, err = os.OpenFile(auditLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	f, err := openRotatingFile(auditLogPath)
 	if err != nil {
 		return fmt.Errorf("failed to open audit log file: %v", err)
 	}
+	if err := configureRotation(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("invalid audit log rotation settings: %v", err)
+	}
+	if err := initHashChain(auditLogPath); err != nil {
+		_ = f.Close()
+		return err
+	}
+	auditFile = f
 
 	return nil
 }
@@ -53,44 +94,173 @@ func handleAudit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
+	if !ingestLimiter.Allow(sourceFromAddr(r.RemoteAddr)) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAuditBodyBytes))
 	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "Audit event too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Failed to read body", http.StatusBadRequest)
 		return
 	}
 
-	var audit AuditLog
-	if err := json.Unmarshal(body, &audit); err != nil {
+	records, err := parseAuditBody(body)
+	if err != nil {
+		rejectedAuditEvents.WithLabelValues(rejectInvalidJSON).Inc()
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	// Log to stdout
-	log.Printf("Received audit: %+v", audit)
+	// Events that do not match the schema are rejected and the rest of a
+	// batch accepted, since the agent does not send a rejected batch again.
+	var rejected []string
+	for i, record := range records {
+		if err := validateAudit(record.audit); err != nil {
+			if len(records) > 1 {
+				err = fmt.Errorf("event %d: %v", i, err)
+			}
+			rejected = append(rejected, err.Error())
+			continue
+		}
+		if !submitAudit(record) {
+			// Tell the agent how much of a batch was taken, so a retry
+			// does not duplicate it.
+			w.Header().Set(acceptedEventsHeader, strconv.Itoa(i))
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Audit queue is full", http.StatusTooManyRequests)
+			return
+		}
+	}
+	if len(rejected) > 0 {
+		log.Printf("Rejected %d of %d audit events from %s: %s", len(rejected), len(records), r.RemoteAddr, strings.Join(rejected, "; "))
+		http.Error(w, "Invalid audit event: "+strings.Join(rejected, "; "), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// acceptedEventsHeader counts the leading events of a refused batch that
+// were accepted anyway.
+const acceptedEventsHeader = "Pulsaar-Accepted-Events"
 
-	// Write to file
-	if auditFile != nil {
-		if _, err := auditFile.WriteString(string(body) + "\n"); err != nil {
-			log.Printf("Failed to write to audit log file: %v", err)
+// parseAuditBody decodes one audit event, or a JSON array of them as agents
+// send in batches. Each record keeps its own JSON for the audit file.
+func parseAuditBody(body []byte) ([]auditRecord, error) {
+	var raws []json.RawMessage
+	trimmed := bytes.TrimSpace(body)
+	batch := len(trimmed) > 0 && trimmed[0] == '['
+	if batch {
+		if err := json.Unmarshal(trimmed, &raws); err != nil {
+			return nil, err
+		}
+	} else {
+		raws = []json.RawMessage{body}
+	}
+	records := make([]auditRecord, len(raws))
+	for i, raw := range raws {
+		if err := json.Unmarshal(raw, &records[i].audit); err != nil {
+			return nil, err
+		}
+		records[i].body = raw
+		if batch {
+			// One line per event in the audit file.
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err != nil {
+				return nil, err
+			}
+			records[i].body = compact.Bytes()
 		}
+	}
+	return records, nil
+}
+
+// submitAudit hands a record to the ingestion queue, or writes it directly
+// when no queue is running. It returns false if the queue is full.
+func submitAudit(record auditRecord) bool {
+	if ingest != nil {
+		return ingest.Enqueue(record)
+	}
+	writeAuditBatch([]auditRecord{record})
+	return true
+}
+
+// writeAuditBatch persists a batch of audit events to the audit file and
+// forwards each one to the configured downstream sinks. The audit file is
+// synced once per batch.
+func writeAuditBatch(batch []auditRecord) {
+	for _, record := range batch {
+		// Log to stdout
+		log.Printf("Received audit: %+v", record.audit)
+		history.Add(record.audit)
+		agentInventory.Add(record.audit)
+
+		if alerts != nil {
+			for _, a := range alerts.Evaluate(record.audit) {
+				log.Printf("Alert triggered: %s", a.Message)
+				alerts.NotifyAsync(a)
+			}
+		}
+
+		// Write to file
+		if auditFile != nil {
+			lines := []string{string(record.body)}
+			if chain != nil {
+				chained, err := chain.Append(record.body)
+				if err != nil {
+					log.Printf("Failed to hash-chain audit record: %v", err)
+				} else {
+					lines = chained
+				}
+			}
+			for _, line := range lines {
+				if _, err := auditFile.WriteString(line + "\n"); err != nil {
+					log.Printf("Failed to write to audit log file: %v", err)
+				}
+			}
+		}
+
+		if partitions != nil {
+			if err := partitions.Write(record.audit, string(record.body)); err != nil {
+				log.Printf("Failed to write to audit partition: %v", err)
+			}
+		}
+
+		if archive != nil {
+			archive.Add(record.body)
+		}
+	}
+	if auditFile != nil {
 		if err := auditFile.Sync(); err != nil {
 			log.Printf("Failed to sync audit log file: %v", err)
 		}
 	}
+	if partitions != nil {
+		partitions.Sync()
+	}
 
-	// Send to external system if configured
-	if externalURL := os.Getenv("PULSAAR_EXTERNAL_LOG_URL"); externalURL != "" {
-		resp, err := http.Post(externalURL, "application/json", bytes.NewBuffer(body))
-		if err != nil {
-			log.Printf("Failed to send to external log: %v", err)
-		} else {
-			if err := resp.Body.Close(); err != nil {
-				log.Printf("Error closing response body: %v", err)
+	if otlpSink != nil {
+		otlpSink.Send(batch)
+	}
+	if forwarding != nil {
+		forwarding.Send(batch)
+	}
+
+	for _, record := range batch {
+		// Forward to syslog if configured
+		if syslogSink != nil {
+			if err := syslogSink.Send(record.audit, record.body); err != nil {
+				log.Printf("Failed to send to syslog: %v", err)
 			}
 		}
 	}
-
-	w.WriteHeader(http.StatusOK)
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -102,6 +272,10 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(runVerify(os.Args[2:], os.Stdout))
+	}
+
 	if err := initAuditFile(); err != nil {
 		log.Fatalf("Failed to initialize audit file: %v", err)
 	}
@@ -110,17 +284,155 @@ func main() {
 			log.Printf("Error closing audit file: %v", err)
 		}
 	}()
+	defer writeFinalCheckpoint()
+
+	if err := initNamespacePartitions(); err != nil {
+		log.Fatalf("Failed to initialize audit partitions: %v", err)
+	}
+	if partitions != nil {
+		defer partitions.Close()
+	}
+
+	if err := initQueryTokens(); err != nil {
+		log.Fatalf("Invalid query token settings: %v", err)
+	}
+
+	if err := initSchemaValidation(); err != nil {
+		log.Fatalf("Invalid audit schema settings: %v", err)
+	}
+
+	if err := initForwarding(); err != nil {
+		log.Fatalf("Invalid forwarding settings: %v", err)
+	}
+	if forwarding != nil {
+		defer forwarding.Close()
+	}
+
+	if err := initSyslog(); err != nil {
+		log.Fatalf("Failed to initialize syslog output: %v", err)
+	}
+	if syslogSink != nil {
+		defer func() {
+			if err := syslogSink.Close(); err != nil {
+				log.Printf("Error closing syslog connection: %v", err)
+			}
+		}()
+	}
+
+	if err := initOTLP(); err != nil {
+		log.Fatalf("Failed to initialize OTLP log export: %v", err)
+	}
+	if otlpSink != nil {
+		defer otlpSink.Close()
+	}
+
+	if err := initAlerts(); err != nil {
+		log.Fatalf("Failed to load alert rules: %v", err)
+	}
+	if alerts != nil {
+		defer alerts.Wait()
+	}
+
+	if err := initArchive(); err != nil {
+		log.Fatalf("Failed to initialize audit archival: %v", err)
+	}
+	if archive != nil {
+		defer archive.Close()
+	}
+
+	if err := initIngestLimits(); err != nil {
+		log.Fatalf("Invalid ingestion limits: %v", err)
+	}
+
+	historySize, err := envPositiveInt("PULSAAR_AUDIT_HISTORY_SIZE", defaultHistorySize)
+	if err != nil {
+		log.Fatalf("Invalid audit history settings: %v", err)
+	}
+	history = newEventStore(historySize)
+
+	write := writeAuditBatch
+	dedup, err := newAuditDeduplicatorFromEnv(writeAuditBatch)
+	if err != nil {
+		log.Fatalf("Invalid audit deduplication settings: %v", err)
+	}
+	if dedup != nil {
+		// Closed after the queue below has drained into it.
+		defer dedup.Close()
+		write = dedup.Add
+	}
+
+	queue, err := newIngestQueueFromEnv(write)
+	if err != nil {
+		log.Fatalf("Invalid ingestion queue settings: %v", err)
+	}
+	ingest = queue
+	defer ingest.Close()
 
 	port := os.Getenv("PULSAAR_AGGREGATOR_PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	http.HandleFunc("/audit", handleAudit)
+	shutdownTimeout, err := envDuration("PULSAAR_SHUTDOWN_TIMEOUT")
+	if err != nil {
+		log.Fatalf("Invalid shutdown settings: %v", err)
+	}
+	if shutdownTimeout == 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+
+	tlsConfig, err := loadServerTLSConfig()
+	if err != nil {
+		log.Fatalf("Invalid aggregator TLS settings: %v", err)
+	}
+
+	http.HandleFunc("/audit", requireClientCert(tlsConfig, handleAudit))
 	http.HandleFunc("/health", handleHealth)
+	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/dashboard", requireClientCert(tlsConfig, handleDashboard))
+	http.HandleFunc("/api/v1/audit", requireClientCert(tlsConfig, handleQuery))
+	http.HandleFunc("/audit/search", requireClientCert(tlsConfig, handleSearch))
+	http.HandleFunc("/audit/export", requireClientCert(tlsConfig, handleExport))
+	http.HandleFunc("/agents", requireClientCert(tlsConfig, handleAgents))
+
+	grpcServer, err := startGRPCServer(tlsConfig)
+	if err != nil {
+		log.Fatalf("Failed to start gRPC audit sink: %v", err)
+	}
+
+	server := &http.Server{
+		Addr:      ":" + port,
+		TLSConfig: httpTLSConfig(tlsConfig),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	log.Printf("Audit aggregator listening on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	serveErr := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			log.Printf("Audit aggregator listening on :%s with TLS", port)
+			serveErr <- server.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Audit aggregator listening on :%s", port)
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
 		log.Fatalf("Failed to start server: %v", err)
+	case <-ctx.Done():
+	}
+
+	// Stop accepting events, then let the deferred calls drain the ingest
+	// queue, flush the archive and sinks, and fsync and close the audit file.
+	log.Printf("Shutting down audit aggregator (timeout %s)", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP shutdown did not complete: %v", err)
 	}
+	stopGRPCServer(shutdownCtx, grpcServer)
+	log.Printf("Stopped accepting audit events, flushing %d queued events", ingest.Len())
 }
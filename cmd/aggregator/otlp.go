@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxPendingOTLPBatches = 16
+	otlpExportAttempts    = 3
+)
+
+// OTLP severity numbers for INFO and WARN.
+const (
+	otlpSeverityInfo = 9
+	otlpSeverityWarn = 13
+)
+
+// otlpExporter sends audit events as OpenTelemetry logs to an OTLP/HTTP
+// endpoint, such as a collector that forwards them to a vendor backend.
+// Batches are encoded as the ingest queue writes them and posted in the
+// background, so a slow collector never holds up the audit file.
+type otlpExporter struct {
+	url     string
+	headers http.Header
+	cluster string
+	client  *http.Client
+
+	batches chan []byte
+	done    chan struct{}
+}
+
+var otlpSink *otlpExporter
+
+// initOTLP configures the exporter from PULSAAR_OTLP_LOGS_URL.
+func initOTLP() error {
+	rawURL := os.Getenv("PULSAAR_OTLP_LOGS_URL")
+	if rawURL == "" {
+		return nil
+	}
+	headers, err := parseOTLPHeaders(os.Getenv("PULSAAR_OTLP_HEADERS"))
+	if err != nil {
+		return err
+	}
+	e, err := newOTLPExporter(rawURL, headers, os.Getenv("PULSAAR_CLUSTER_NAME"), os.Getenv("PULSAAR_OTLP_CA_FILE"))
+	if err != nil {
+		return err
+	}
+	otlpSink = e
+	log.Printf("Exporting audit events as OTLP logs to %s", rawURL)
+	return nil
+}
+
+func newOTLPExporter(rawURL string, headers http.Header, cluster, caFile string) (*otlpExporter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP logs URL: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("OTLP logs URL %q must be an http:// or https:// URL, such as http://otel-collector:4318/v1/logs", rawURL)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OTLP CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse OTLP CA certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	e := &otlpExporter{
+		url:     rawURL,
+		headers: headers,
+		cluster: cluster,
+		client:  &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		batches: make(chan []byte, maxPendingOTLPBatches),
+		done:    make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// parseOTLPHeaders reads headers in the OTEL_EXPORTER_OTLP_HEADERS form,
+// key=value pairs separated by commas with URL-encoded values, so a
+// vendor's API key can be sent without a collector in between.
+func parseOTLPHeaders(raw string) (http.Header, error) {
+	headers := http.Header{}
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid PULSAAR_OTLP_HEADERS entry %q; use key=value", pair)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PULSAAR_OTLP_HEADERS value for %s: %v", key, err)
+		}
+		headers.Add(key, value)
+	}
+	return headers, nil
+}
+
+// Send queues a batch for export. When the collector has fallen too far
+// behind the batch is dropped; the audit file still has it.
+func (e *otlpExporter) Send(batch []auditRecord) {
+	body, err := encodeOTLPLogs(batch, e.cluster)
+	if err != nil {
+		log.Printf("Failed to encode OTLP logs: %v", err)
+		return
+	}
+	select {
+	case e.batches <- body:
+	default:
+		log.Printf("OTLP export backlog full, dropping %d audit events", len(batch))
+	}
+}
+
+// Close exports the queued batches and stops the exporter.
+func (e *otlpExporter) Close() {
+	close(e.batches)
+	<-e.done
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.done)
+	for body := range e.batches {
+		var err error
+		for attempt := 0; attempt < otlpExportAttempts; attempt++ {
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * time.Second)
+			}
+			var retry bool
+			if retry, err = e.post(body); err == nil || !retry {
+				break
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to export audit events as OTLP logs: %v", err)
+		}
+	}
+}
+
+// post sends one export request and reports whether a failure is worth
+// retrying, as the OTLP specification allows for 429, 502, 503 and 504.
+func (e *otlpExporter) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range e.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, err
+	}
+	return false, err
+}
+
+// The OTLP/HTTP JSON encoding of a logs export request, limited to the
+// fields audit events use.
+type (
+	otlpLogsRequest struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeLogs struct {
+		Scope      otlpScope       `json:"scope"`
+		LogRecords []otlpLogRecord `json:"logRecords"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpLogRecord struct {
+		TimeUnixNano         string         `json:"timeUnixNano,omitempty"`
+		ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+		SeverityNumber       int            `json:"severityNumber"`
+		SeverityText         string         `json:"severityText"`
+		Body                 otlpAnyValue   `json:"body"`
+		Attributes           []otlpKeyValue `json:"attributes"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+)
+
+func otlpStringValue(v string) otlpAnyValue {
+	return otlpAnyValue{StringValue: &v}
+}
+
+// otlpAttrs appends the non-empty string attributes in pairs of key and
+// value.
+func otlpAttrs(attrs []otlpKeyValue, pairs ...string) []otlpKeyValue {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			attrs = append(attrs, otlpKeyValue{Key: pairs[i], Value: otlpStringValue(pairs[i+1])})
+		}
+	}
+	return attrs
+}
+
+func otlpInt(key string, v int64) otlpKeyValue {
+	s := strconv.FormatInt(v, 10)
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{IntValue: &s}}
+}
+
+func otlpUnixNano(timestamp string) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpResourceKey is the Kubernetes object an event was about; events for
+// the same pod share one resource, as OpenTelemetry backends expect.
+type otlpResourceKey struct {
+	namespace, pod, container, node string
+}
+
+// encodeOTLPLogs renders a batch as an OTLP logs export request. The pod the
+// event was about and the cluster become resource attributes; allowed
+// requests are INFO records and denied or failed ones WARN, with the rest
+// of the event as attributes, using the OpenTelemetry names where there is
+// one.
+func encodeOTLPLogs(batch []auditRecord, cluster string) ([]byte, error) {
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+	var resources []otlpResourceLogs
+	index := map[otlpResourceKey]int{}
+	for _, record := range batch {
+		a := record.audit
+		key := otlpResourceKey{a.Namespace, a.Pod, a.Container, a.Node}
+		i, ok := index[key]
+		if !ok {
+			i = len(resources)
+			index[key] = i
+			resources = append(resources, otlpResourceLogs{
+				Resource: otlpResource{Attributes: otlpAttrs([]otlpKeyValue{{Key: "service.name", Value: otlpStringValue("pulsaar")}},
+					"k8s.cluster.name", cluster,
+					"k8s.namespace.name", a.Namespace,
+					"k8s.pod.name", a.Pod,
+					"k8s.container.name", a.Container,
+					"k8s.node.name", a.Node,
+				)},
+				ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: "pulsaar.audit", Version: version}}},
+			})
+		}
+
+		r := otlpLogRecord{
+			TimeUnixNano:         otlpUnixNano(a.Timestamp),
+			ObservedTimeUnixNano: observed,
+			SeverityNumber:       otlpSeverityInfo,
+			SeverityText:         "INFO",
+			Body:                 otlpStringValue(strings.TrimSpace(a.Operation + " " + a.Path + " " + a.Result)),
+			Attributes: otlpAttrs(nil,
+				"pulsaar.operation", a.Operation,
+				"pulsaar.path", a.Path,
+				"pulsaar.result", a.Result,
+				"pulsaar.error_code", a.ErrorCode,
+				"pulsaar.agent_id", a.AgentID,
+				"pulsaar.request_id", a.RequestID,
+				"enduser.id", a.User,
+				"client.address", a.ClientAddr,
+			),
+		}
+		if a.Result != "" && a.Result != "allowed" {
+			r.SeverityNumber, r.SeverityText = otlpSeverityWarn, "WARN"
+		}
+		if a.BytesRead > 0 {
+			r.Attributes = append(r.Attributes, otlpInt("pulsaar.bytes_read", a.BytesRead))
+		}
+		if a.DurationMs > 0 {
+			r.Attributes = append(r.Attributes, otlpInt("pulsaar.duration_ms", a.DurationMs))
+		}
+		if a.Count > 1 {
+			r.Attributes = append(r.Attributes, otlpInt("pulsaar.count", a.Count))
+			r.Attributes = otlpAttrs(r.Attributes, "pulsaar.last_timestamp", a.LastTimestamp)
+		}
+		scope := &resources[i].ScopeLogs[0]
+		scope.LogRecords = append(scope.LogRecords, r)
+	}
+	return json.Marshal(otlpLogsRequest{ResourceLogs: resources})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan *http.Request, 4)
+	bodies := make(chan []byte, 4)
+	failures := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	headers, err := parseOTLPHeaders("api-key=s%3Dcret, x-tenant = ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := newOTLPExporter(srv.URL+"/v1/logs", headers, "prod-eu", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Send([]auditRecord{
+		{audit: AuditLog{Timestamp: "2024-01-01T00:00:00Z", Operation: "ReadFile", Path: "/etc/hosts", Namespace: "shop", Pod: "web-0", Node: "node-1", User: "alice", Result: "allowed", BytesRead: 12}},
+		{audit: AuditLog{Timestamp: "2024-01-01T00:00:01Z", Operation: "ReadFile", Path: "/etc/shadow", Namespace: "shop", Pod: "web-0", Node: "node-1", User: "alice", Result: "denied", Count: 3, LastTimestamp: "2024-01-01T00:00:04Z"}},
+		{audit: AuditLog{Timestamp: "2024-01-01T00:00:02Z", Operation: "Stat", Path: "/", Namespace: "billing", Pod: "api-0", Result: "allowed"}},
+	})
+	e.Close()
+
+	if len(requests) != 1 {
+		t.Fatalf("expected the batch exported once after a retry, got %d requests", len(requests))
+	}
+	r := <-requests
+	if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected request %s with content type %q", r.URL.Path, r.Header.Get("Content-Type"))
+	}
+	if r.Header.Get("Api-Key") != "s=cret" || r.Header.Get("X-Tenant") != "ops" {
+		t.Errorf("expected the configured headers, got %v", r.Header)
+	}
+
+	var req otlpLogsRequest
+	if err := json.Unmarshal(<-bodies, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.ResourceLogs) != 2 {
+		t.Fatalf("expected one resource per pod, got %d", len(req.ResourceLogs))
+	}
+	shop := req.ResourceLogs[0]
+	attrs := map[string]string{}
+	for _, kv := range shop.Resource.Attributes {
+		attrs[kv.Key] = *kv.Value.StringValue
+	}
+	for key, want := range map[string]string{"service.name": "pulsaar", "k8s.cluster.name": "prod-eu", "k8s.namespace.name": "shop", "k8s.pod.name": "web-0", "k8s.node.name": "node-1"} {
+		if attrs[key] != want {
+			t.Errorf("expected resource attribute %s=%q, got %q", key, want, attrs[key])
+		}
+	}
+	records := shop.ScopeLogs[0].LogRecords
+	if len(records) != 2 || shop.ScopeLogs[0].Scope.Name != "pulsaar.audit" {
+		t.Fatalf("expected both shop events under the pulsaar.audit scope, got %+v", shop.ScopeLogs)
+	}
+	if records[0].SeverityText != "INFO" || records[0].TimeUnixNano != "1704067200000000000" {
+		t.Errorf("unexpected allowed record %+v", records[0])
+	}
+	denied := map[string]otlpAnyValue{}
+	for _, kv := range records[1].Attributes {
+		denied[kv.Key] = kv.Value
+	}
+	if records[1].SeverityText != "WARN" || *denied["enduser.id"].StringValue != "alice" || *denied["pulsaar.count"].IntValue != "3" {
+		t.Errorf("unexpected denied record %+v", records[1])
+	}
+	if len(req.ResourceLogs[1].ScopeLogs[0].LogRecords) != 1 {
+		t.Errorf("expected the billing event under its own resource")
+	}
+}
+
+func TestNewOTLPExporterInvalid(t *testing.T) {
+	if _, err := newOTLPExporter("grpc://collector:4317", nil, "", ""); err == nil {
+		t.Error("expected error for a non-HTTP URL")
+	}
+	if _, err := parseOTLPHeaders("api-key"); err == nil {
+		t.Error("expected error for a header without a value")
+	}
+}
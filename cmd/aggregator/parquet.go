@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// Parquet format constants used by the writer; see parquet.thrift in the
+// Apache Parquet format specification.
+const (
+	parquetMagic = "PAR1"
+
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired    = 0
+	parquetUTF8        = 0
+	parquetPlain       = 0
+	parquetRLE         = 3
+	parquetGzip        = 2
+	parquetDataPage    = 0
+	parquetFileVersion = 1
+
+	// parquetRowGroupRows is how many events each row group holds, which
+	// bounds the memory an export buffers.
+	parquetRowGroupRows = 10000
+)
+
+// auditColumn is one column of an audit export: a string or an integer
+// field of the event.
+type auditColumn struct {
+	name string
+	str  func(AuditLog) string
+	num  func(AuditLog) int64
+}
+
+var auditColumns = []auditColumn{
+	{name: "timestamp", str: func(a AuditLog) string { return a.Timestamp }},
+	{name: "operation", str: func(a AuditLog) string { return a.Operation }},
+	{name: "path", str: func(a AuditLog) string { return a.Path }},
+	{name: "result", str: func(a AuditLog) string { return a.Result }},
+	{name: "error_code", str: func(a AuditLog) string { return a.ErrorCode }},
+	{name: "user", str: func(a AuditLog) string { return a.User }},
+	{name: "client_addr", str: func(a AuditLog) string { return a.ClientAddr }},
+	{name: "namespace", str: func(a AuditLog) string { return a.Namespace }},
+	{name: "pod", str: func(a AuditLog) string { return a.Pod }},
+	{name: "container", str: func(a AuditLog) string { return a.Container }},
+	{name: "node", str: func(a AuditLog) string { return a.Node }},
+	{name: "agent_id", str: func(a AuditLog) string { return a.AgentID }},
+	{name: "request_id", str: func(a AuditLog) string { return a.RequestID }},
+	{name: "bytes_read", num: func(a AuditLog) int64 { return a.BytesRead }},
+	{name: "duration_ms", num: func(a AuditLog) int64 { return a.DurationMs }},
+	{name: "count", num: func(a AuditLog) int64 { return max(a.Count, 1) }},
+	{name: "last_timestamp", str: func(a AuditLog) string { return a.LastTimestamp }},
+}
+
+// parquetWriter writes audit events as a Parquet file with one required
+// column per auditColumns entry, PLAIN-encoded and gzip-compressed. Row
+// groups are written as they fill, so a long export streams instead of
+// being held in memory; the file is only readable once Close has written
+// the footer.
+type parquetWriter struct {
+	w         *countingWriter
+	createdBy string
+	rows      []AuditLog
+	total     int64
+	groups    []parquetRowGroup
+}
+
+type parquetRowGroup struct {
+	rows    int64
+	size    int64
+	columns []parquetColumnChunk
+}
+
+type parquetColumnChunk struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newParquetWriter(w io.Writer, createdBy string) (*parquetWriter, error) {
+	cw := &countingWriter{w: w}
+	if _, err := io.WriteString(cw, parquetMagic); err != nil {
+		return nil, err
+	}
+	return &parquetWriter{w: cw, createdBy: createdBy}, nil
+}
+
+// Write adds an event, writing a row group once enough are buffered.
+func (p *parquetWriter) Write(a AuditLog) error {
+	p.rows = append(p.rows, a)
+	if len(p.rows) >= parquetRowGroupRows {
+		return p.flush()
+	}
+	return nil
+}
+
+// Close writes the last row group and the footer.
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	footer := p.footer()
+	var trailer [4]byte
+	binary.LittleEndian.PutUint32(trailer[:], uint32(len(footer)))
+	for _, b := range [][]byte{footer, trailer[:], []byte(parquetMagic)} {
+		if _, err := p.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes the buffered events as a row group of one data page per
+// column.
+func (p *parquetWriter) flush() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: int64(len(p.rows))}
+	for _, col := range auditColumns {
+		var values bytes.Buffer
+		for _, a := range p.rows {
+			if col.num != nil {
+				_ = binary.Write(&values, binary.LittleEndian, col.num(a))
+				continue
+			}
+			s := col.str(a)
+			_ = binary.Write(&values, binary.LittleEndian, uint32(len(s)))
+			values.WriteString(s)
+		}
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		_, _ = zw.Write(values.Bytes())
+		if err := zw.Close(); err != nil {
+			return err
+		}
+
+		var header thriftWriter
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(values.Len()))
+		header.i32(3, int32(compressed.Len()))
+		header.structBegin(5)
+		header.i32(1, int32(len(p.rows)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.structEnd()
+		header.stop()
+
+		chunk := parquetColumnChunk{
+			offset:       p.w.n,
+			uncompressed: int64(len(header.buf) + values.Len()),
+			compressed:   int64(len(header.buf) + compressed.Len()),
+		}
+		if _, err := p.w.Write(header.buf); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(compressed.Bytes()); err != nil {
+			return err
+		}
+		group.size += chunk.uncompressed
+		group.columns = append(group.columns, chunk)
+	}
+	p.groups = append(p.groups, group)
+	p.total += group.rows
+	p.rows = p.rows[:0]
+	return nil
+}
+
+// footer encodes the FileMetaData.
+func (p *parquetWriter) footer() []byte {
+	var t thriftWriter
+	t.i32(1, parquetFileVersion)
+
+	t.listBegin(2, thriftStruct, len(auditColumns)+1)
+	t.elemBegin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(auditColumns)))
+	t.elemEnd()
+	for _, col := range auditColumns {
+		t.elemBegin()
+		if col.num != nil {
+			t.i32(1, parquetInt64)
+		} else {
+			t.i32(1, parquetByteArray)
+		}
+		t.i32(3, parquetRequired)
+		t.binary(4, col.name)
+		if col.num == nil {
+			t.i32(6, parquetUTF8)
+		}
+		t.elemEnd()
+	}
+
+	t.i64(3, p.total)
+
+	t.listBegin(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		t.elemBegin()
+		t.listBegin(1, thriftStruct, len(g.columns))
+		for i, c := range g.columns {
+			col := auditColumns[i]
+			typ := int32(parquetByteArray)
+			if col.num != nil {
+				typ = parquetInt64
+			}
+			t.elemBegin()
+			t.i64(2, c.offset)
+			t.structBegin(3)
+			t.i32(1, typ)
+			t.listBegin(2, thriftI32, 1)
+			t.varint(uint64(zigzag(parquetPlain)))
+			t.listBegin(3, thriftBinary, 1)
+			t.rawBinary(col.name)
+			t.i32(4, parquetGzip)
+			t.i64(5, g.rows)
+			t.i64(6, c.uncompressed)
+			t.i64(7, c.compressed)
+			t.i64(9, c.offset)
+			t.structEnd()
+			t.elemEnd()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.elemEnd()
+	}
+
+	t.binary(6, p.createdBy)
+	t.stop()
+	return t.buf
+}
+
+// Thrift compact protocol type ids.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol, which
+// Parquet uses for its page headers and footer. Fields must be written in
+// increasing id order within each struct.
+type thriftWriter struct {
+	buf  []byte
+	last []int16 // prev of each enclosing struct
+	prev int16   // id of the last field written in the open struct
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func zigzag(v int64) int64 {
+	return (v << 1) ^ (v >> 63)
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.prev; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(uint64(zigzag(int64(id))))
+	}
+	t.prev = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(uint64(zigzag(int64(v))))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(uint64(zigzag(v)))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.rawBinary(s)
+}
+
+// rawBinary writes a string without a field header, as a list element.
+func (t *thriftWriter) rawBinary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftWriter) listBegin(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elem)
+	t.varint(uint64(n))
+}
+
+// structBegin opens a struct field; elemBegin opens a struct list element.
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) elemBegin() {
+	t.last = append(t.last, t.prev)
+	t.prev = 0
+}
+
+func (t *thriftWriter) structEnd() { t.elemEnd() }
+
+func (t *thriftWriter) elemEnd() {
+	t.stop()
+	t.prev = t.last[len(t.last)-1]
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf = append(t.buf, 0)
+}
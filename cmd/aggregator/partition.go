@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+const (
+	unknownNamespacePartition  = "_unknown"
+	overflowNamespacePartition = "_overflow"
+)
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// namespaceLogs writes a copy of each audit event to
+// <dir>/<namespace>/audit.log so per-tenant data can be retained, exported or
+// handed to a team without exposing other namespaces. Each partition rotates
+// with the same settings as the main audit log.
+type namespaceLogs struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]*rotatingFile
+	dirty map[string]bool
+}
+
+var partitions *namespaceLogs
+
+func initNamespacePartitions() error {
+	if os.Getenv("PULSAAR_AUDIT_PARTITION_BY_NAMESPACE") != "true" {
+		return nil
+	}
+	dir := os.Getenv("PULSAAR_AUDIT_PARTITION_DIR")
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(auditLogPath()), "namespaces")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create audit partition directory: %v", err)
+	}
+	partitions = &namespaceLogs{dir: dir, files: map[string]*rotatingFile{}, dirty: map[string]bool{}}
+	log.Printf("Partitioning audit logs by namespace under %s", dir)
+	return nil
+}
+
+// partitionName maps a namespace to a safe directory name. Values that are
+// not valid Kubernetes namespace names never become path components.
+func partitionName(ns string) string {
+	if !namespacePattern.MatchString(ns) {
+		return unknownNamespacePartition
+	}
+	return ns
+}
+
+func (p *namespaceLogs) Write(audit AuditLog, line string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := partitionName(audit.Namespace)
+	f, ok := p.files[name]
+	if !ok {
+		if len(p.files) >= maxHistoryPartitions {
+			name = overflowNamespacePartition
+			f = p.files[name]
+		}
+		if f == nil {
+			var err error
+			if f, err = p.open(name); err != nil {
+				return err
+			}
+			p.files[name] = f
+		}
+	}
+	p.dirty[name] = true
+	_, err := f.WriteString(line + "\n")
+	return err
+}
+
+func (p *namespaceLogs) open(name string) (*rotatingFile, error) {
+	dir := filepath.Join(p.dir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := openRotatingFile(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		return nil, err
+	}
+	if err := configureRotation(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Sync flushes every partition written since the last Sync.
+func (p *namespaceLogs) Sync() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name := range p.dirty {
+		if err := p.files[name].Sync(); err != nil {
+			log.Printf("Failed to sync audit partition %s: %v", name, err)
+		}
+	}
+	p.dirty = map[string]bool{}
+}
+
+func (p *namespaceLogs) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, f := range p.files {
+		if err := f.Close(); err != nil {
+			log.Printf("Error closing audit partition %s: %v", name, err)
+		}
+	}
+	p.files = map[string]*rotatingFile{}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPartitionName(t *testing.T) {
+	for ns, want := range map[string]string{
+		"payments":    "payments",
+		"":            unknownNamespacePartition,
+		"../etc":      unknownNamespacePartition,
+		"Team-A":      unknownNamespacePartition,
+		"kube-system": "kube-system",
+	} {
+		if got := partitionName(ns); got != want {
+			t.Errorf("partitionName(%q) = %q, want %q", ns, got, want)
+		}
+	}
+}
+
+func TestNamespaceLogsWrite(t *testing.T) {
+	dir := t.TempDir()
+	p := &namespaceLogs{dir: dir, files: map[string]*rotatingFile{}, dirty: map[string]bool{}}
+
+	for _, a := range []AuditLog{{Namespace: "payments", Path: "/a"}, {Namespace: "search", Path: "/b"}, {Namespace: "payments", Path: "/c"}} {
+		if err := p.Write(a, `{"path":"`+a.Path+`"}`); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	p.Sync()
+	p.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "payments", "audit.log"))
+	if err != nil {
+		t.Fatalf("expected payments partition: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || strings.Contains(string(data), "/b") {
+		t.Errorf("expected only payments events in partition, got %q", data)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultIngestRateLimit   = 100 // events per second per source
+	defaultIngestRateBurst   = 200
+	defaultMaxAuditBodyBytes = 64 * 1024
+)
+
+// sourceRateLimiter applies a token bucket per ingestion source (the remote
+// IP of the agent) so that one agent cannot starve the others or fill the
+// audit disk. A nil limiter allows everything.
+type sourceRateLimiter struct {
+	limit    rate.Limit
+	burst    int
+	limiters sync.Map // map[string]*rate.Limiter
+}
+
+var (
+	ingestLimiter     *sourceRateLimiter
+	maxAuditBodyBytes int64 = defaultMaxAuditBodyBytes
+)
+
+func newSourceRateLimiter(perSecond, burst int) *sourceRateLimiter {
+	return &sourceRateLimiter{limit: rate.Limit(perSecond), burst: burst}
+}
+
+// Allow reports whether source may submit one more audit event.
+func (l *sourceRateLimiter) Allow(source string) bool {
+	if l == nil {
+		return true
+	}
+	limiter, ok := l.limiters.Load(source)
+	if !ok {
+		limiter, _ = l.limiters.LoadOrStore(source, rate.NewLimiter(l.limit, l.burst))
+	}
+	return limiter.(*rate.Limiter).Allow()
+}
+
+// initIngestLimits reads PULSAAR_INGEST_RATE_LIMIT, PULSAAR_INGEST_RATE_BURST
+// and PULSAAR_INGEST_MAX_BODY_BYTES. A rate limit of 0 disables per-source
+// limiting.
+func initIngestLimits() error {
+	perSecond := defaultIngestRateLimit
+	if v := os.Getenv("PULSAAR_INGEST_RATE_LIMIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid PULSAAR_INGEST_RATE_LIMIT %q: expected a non-negative integer", v)
+		}
+		perSecond = n
+	}
+	burst, err := envPositiveInt("PULSAAR_INGEST_RATE_BURST", defaultIngestRateBurst)
+	if err != nil {
+		return err
+	}
+	maxBody, err := envPositiveInt("PULSAAR_INGEST_MAX_BODY_BYTES", defaultMaxAuditBodyBytes)
+	if err != nil {
+		return err
+	}
+
+	ingestLimiter = nil
+	if perSecond > 0 {
+		ingestLimiter = newSourceRateLimiter(perSecond, burst)
+	}
+	maxAuditBodyBytes = int64(maxBody)
+	return nil
+}
+
+// sourceFromAddr strips the port from a remote address so all connections
+// from one agent share a bucket.
+func sourceFromAddr(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSourceRateLimiter(t *testing.T) {
+	l := newSourceRateLimiter(1, 2)
+	if !l.Allow("10.0.0.1") || !l.Allow("10.0.0.1") {
+		t.Fatal("expected burst of 2 to be allowed")
+	}
+	if l.Allow("10.0.0.1") {
+		t.Error("expected third event from the same source to be limited")
+	}
+	if !l.Allow("10.0.0.2") {
+		t.Error("expected a different source to have its own bucket")
+	}
+
+	var disabled *sourceRateLimiter
+	if !disabled.Allow("10.0.0.1") {
+		t.Error("expected nil limiter to allow everything")
+	}
+}
+
+func TestSourceFromAddr(t *testing.T) {
+	if got := sourceFromAddr("10.0.0.1:53422"); got != "10.0.0.1" {
+		t.Errorf("expected port to be stripped, got %q", got)
+	}
+	if got := sourceFromAddr("bufconn"); got != "bufconn" {
+		t.Errorf("expected address without port to be kept, got %q", got)
+	}
+}
+
+func TestInitIngestLimits(t *testing.T) {
+	defer func() { ingestLimiter, maxAuditBodyBytes = nil, defaultMaxAuditBodyBytes }()
+
+	t.Setenv("PULSAAR_INGEST_RATE_LIMIT", "0")
+	t.Setenv("PULSAAR_INGEST_MAX_BODY_BYTES", "1024")
+	if err := initIngestLimits(); err != nil {
+		t.Fatalf("initIngestLimits: %v", err)
+	}
+	if ingestLimiter != nil || maxAuditBodyBytes != 1024 {
+		t.Errorf("expected limiter disabled and 1024 byte cap, got %v and %d", ingestLimiter, maxAuditBodyBytes)
+	}
+
+	t.Setenv("PULSAAR_INGEST_RATE_LIMIT", "-1")
+	if err := initIngestLimits(); err == nil {
+		t.Error("expected error for negative rate limit")
+	}
+}
+
+func TestHandleAuditRateLimited(t *testing.T) {
+	defer func() { ingestLimiter = nil }()
+	ingestLimiter = newSourceRateLimiter(1, 1)
+
+	auditData := `{"timestamp":"2023-01-01T00:00:00Z","operation":"ReadFile","path":"/etc/hosts"}`
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/audit", bytes.NewBufferString(auditData))
+		req.RemoteAddr = "10.0.0.9:40000"
+		w := httptest.NewRecorder()
+		handleAudit(w, req)
+		if w.Code != want {
+			t.Errorf("request %d: expected status %d, got %d", i, want, w.Code)
+		}
+	}
+}
+
+func TestHandleAuditBodyTooLarge(t *testing.T) {
+	defer func() { maxAuditBodyBytes = defaultMaxAuditBodyBytes }()
+	maxAuditBodyBytes = 64
+
+	auditData := `{"operation":"ReadFile","path":"/` + strings.Repeat("a", 128) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/audit", bytes.NewBufferString(auditData))
+	w := httptest.NewRecorder()
+	handleAudit(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", w.Code)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// rotatingFile is an append-only audit log that rotates on size or age,
+// optionally gzips rotated segments and prunes them according to the
+// configured retention limits.
+type rotatingFile struct {
+	path         string
+	maxSize      int64
+	interval     time.Duration
+	maxFiles     int
+	maxAge       time.Duration
+	maxTotalSize int64
+	compress     bool
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// background compression and pruning of rotated segments; maintenance
+	// runs them one rotation at a time so pruning never removes a segment
+	// that is still being compressed
+	wg          sync.WaitGroup
+	maintenance sync.Mutex
+}
+
+func openRotatingFile(path string) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, compress: true}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	r.openedAt = time.Now()
+	return nil
+}
+
+func (r *rotatingFile) WriteString(s string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.shouldRotate(int64(len(s))) {
+		if err := r.rotate(); err != nil {
+			log.Printf("Failed to rotate audit log: %v", err)
+		}
+	}
+	n, err := r.file.WriteString(s)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.file.Sync()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Sync()
+		if cerr := r.file.Close(); err == nil {
+			err = cerr
+		}
+		r.file = nil
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+func (r *rotatingFile) shouldRotate(next int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.maxSize > 0 && r.size+next > r.maxSize {
+		return true
+	}
+	return r.interval > 0 && time.Since(r.openedAt) >= r.interval
+}
+
+// rotate must be called with r.mu held.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	rotated := r.path + "." + time.Now().UTC().Format(rotatedTimeFormat)
+	if err := os.Rename(r.path, rotated); err != nil {
+		// Keep writing to the current file rather than losing events.
+		if openErr := r.open(); openErr != nil {
+			return fmt.Errorf("rename failed (%v) and reopen failed: %v", err, openErr)
+		}
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.maintenance.Lock()
+		defer r.maintenance.Unlock()
+		if r.compress {
+			// An earlier rotation's pruning may already have removed it.
+			if err := gzipFile(rotated); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to compress rotated audit log %s: %v", rotated, err)
+			}
+		}
+		if err := r.prune(); err != nil {
+			log.Printf("Failed to apply audit log retention: %v", err)
+		}
+	}()
+	return nil
+}
+
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = gz.Close()
+		_ = out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// rotatedSegments returns rotated audit log files, newest first.
+func (r *rotatingFile) rotatedSegments() ([]os.FileInfo, error) {
+	dir := filepath.Dir(r.path)
+	prefix := filepath.Base(r.path) + "."
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, info)
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Name() > segments[j].Name()
+	})
+	return segments, nil
+}
+
+func (r *rotatingFile) prune() error {
+	if r.maxFiles <= 0 && r.maxAge <= 0 && r.maxTotalSize <= 0 {
+		return nil
+	}
+	segments, err := r.rotatedSegments()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(r.path)
+	var total int64
+	for i, info := range segments {
+		total += info.Size()
+		expired := r.maxAge > 0 && time.Since(info.ModTime()) > r.maxAge
+		tooMany := r.maxFiles > 0 && i >= r.maxFiles
+		tooLarge := r.maxTotalSize > 0 && total > r.maxTotalSize
+		if expired || tooMany || tooLarge {
+			if err := os.Remove(filepath.Join(dir, info.Name())); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove expired audit log %s: %v", info.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// configureRotation applies the PULSAAR_AUDIT_LOG_* rotation and retention
+// settings to r.
+func configureRotation(r *rotatingFile) error {
+	var err error
+	if r.maxSize, err = envMegabytes("PULSAAR_AUDIT_LOG_MAX_SIZE_MB"); err != nil {
+		return err
+	}
+	if r.maxTotalSize, err = envMegabytes("PULSAAR_AUDIT_LOG_MAX_TOTAL_SIZE_MB"); err != nil {
+		return err
+	}
+	if r.interval, err = envDuration("PULSAAR_AUDIT_LOG_ROTATE_INTERVAL"); err != nil {
+		return err
+	}
+	if r.maxAge, err = envDuration("PULSAAR_AUDIT_LOG_MAX_AGE"); err != nil {
+		return err
+	}
+	if v := os.Getenv("PULSAAR_AUDIT_LOG_MAX_FILES"); v != "" {
+		if r.maxFiles, err = strconv.Atoi(v); err != nil {
+			return fmt.Errorf("invalid PULSAAR_AUDIT_LOG_MAX_FILES %q: %v", v, err)
+		}
+	}
+	if v := os.Getenv("PULSAAR_AUDIT_LOG_COMPRESS"); v != "" {
+		if r.compress, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("invalid PULSAAR_AUDIT_LOG_COMPRESS %q: %v", v, err)
+		}
+	}
+	return nil
+}
+
+func envMegabytes(name string) (int64, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	mb, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || mb < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a non-negative number of megabytes", name, v)
+	}
+	return mb * 1024 * 1024, nil
+}
+
+func envDuration(name string) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a duration such as 24h", name, v)
+	}
+	return d, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileRotatesOnSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+
+	r, err := openRotatingFile(path)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	r.maxSize = 32
+
+	line := strings.Repeat("a", 20) + "\n"
+	for i := 0; i < 3; i++ {
+		if _, err := r.WriteString(line); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	segments, err := filepath.Glob(path + ".*.gz")
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 compressed segments, got %v", segments)
+	}
+
+	f, err := os.Open(segments[0])
+	if err != nil {
+		t.Fatalf("open segment failed: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("segment is not gzip: %v", err)
+	}
+	data, _ := io.ReadAll(gz)
+	if string(data) != line {
+		t.Errorf("unexpected segment content %q", data)
+	}
+
+	current, _ := os.ReadFile(path)
+	if string(current) != line {
+		t.Errorf("unexpected current file content %q", current)
+	}
+}
+
+func TestRotatingFileRetentionWhileCompressing(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+
+	r, err := openRotatingFile(path)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	r.maxSize = 32
+	r.maxFiles = 2
+
+	line := strings.Repeat("a", 20) + "\n"
+	for i := 0; i < 50; i++ {
+		if _, err := r.WriteString(line); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	segments, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 segments to be kept, got %v", segments)
+	}
+	for _, segment := range segments {
+		f, err := os.Open(segment)
+		if err != nil {
+			t.Fatalf("open segment failed: %v", err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("segment %s is not gzip: %v", segment, err)
+		}
+		if data, err := io.ReadAll(gz); err != nil || string(data) != line {
+			t.Errorf("unexpected content %q in %s (%v)", data, segment, err)
+		}
+		_ = f.Close()
+	}
+}
+
+func TestRotatingFileRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+
+	old := time.Now().Add(-48 * time.Hour)
+	for i, name := range []string{"20230101T000000.000000000", "20230102T000000.000000000", "20230103T000000.000000000"} {
+		p := path + "." + name + ".gz"
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		if i == 0 {
+			_ = os.Chtimes(p, old, old)
+		}
+	}
+
+	r := &rotatingFile{path: path, maxFiles: 1}
+	if err := r.prune(); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	remaining, _ := filepath.Glob(path + ".*")
+	if len(remaining) != 1 || !strings.Contains(remaining[0], "20230103") {
+		t.Errorf("expected only newest segment to remain, got %v", remaining)
+	}
+}
+
+func TestConfigureRotationInvalid(t *testing.T) {
+	t.Setenv("PULSAAR_AUDIT_LOG_MAX_SIZE_MB", "lots")
+	if err := configureRotation(&rotatingFile{}); err == nil {
+		t.Error("expected error for invalid max size")
+	}
+}
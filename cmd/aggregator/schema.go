@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// auditSchemaVersion is the version of the audit event schema the
+// aggregator accepts. Events without schema_version are version 1, as sent
+// by every agent so far.
+const auditSchemaVersion = 1
+
+// auditOperations are the agent RPCs that produce audit events.
+var auditOperations = map[string]bool{
+	"ListDirectory":       true,
+	"ListDirectoryStream": true,
+	"Stat":                true,
+	"ReadFile":            true,
+	"StreamFile":          true,
+	"TailFile":            true,
+	"Search":              true,
+	"Find":                true,
+	"Preview":             true,
+	"Checksum":            true,
+	"Shutdown":            true,
+}
+
+var auditResults = map[string]bool{"": true, "allowed": true, "denied": true, "error": true}
+
+// Reasons an event is rejected, used as the reason label of
+// rejectedAuditEvents.
+const (
+	rejectInvalidJSON        = "invalid_json"
+	rejectUnsupportedVersion = "unsupported_version"
+	rejectMissingField       = "missing_field"
+	rejectInvalidTimestamp   = "invalid_timestamp"
+	rejectUnknownOperation   = "unknown_operation"
+	rejectInvalidResult      = "invalid_result"
+	rejectNegativeValue      = "negative_value"
+)
+
+var rejectedAuditEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "pulsaar_aggregator_rejected_audit_events_total",
+	Help: "Audit events rejected by schema validation, by reason.",
+}, []string{"reason"})
+
+func init() {
+	prometheus.MustRegister(rejectedAuditEvents)
+}
+
+// validateSchema reports whether incoming events are checked against the
+// schema; PULSAAR_AUDIT_SCHEMA_VALIDATION=false turns it off.
+var validateSchema = true
+
+func initSchemaValidation() error {
+	validateSchema = true
+	if v := os.Getenv("PULSAAR_AUDIT_SCHEMA_VALIDATION"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid PULSAAR_AUDIT_SCHEMA_VALIDATION %q: %v", v, err)
+		}
+		validateSchema = b
+	}
+	return nil
+}
+
+// schemaError describes why an event does not match the schema.
+type schemaError struct {
+	reason string
+	msg    string
+}
+
+func (e *schemaError) Error() string { return e.msg }
+
+// validateAudit checks an event against auditSchemaVersion and counts it
+// in rejectedAuditEvents if it does not match.
+func validateAudit(a AuditLog) error {
+	if !validateSchema {
+		return nil
+	}
+	if err := checkAuditSchema(a); err != nil {
+		rejectedAuditEvents.WithLabelValues(err.reason).Inc()
+		return err
+	}
+	return nil
+}
+
+func checkAuditSchema(a AuditLog) *schemaError {
+	if a.SchemaVersion != 0 && a.SchemaVersion != auditSchemaVersion {
+		return &schemaError{rejectUnsupportedVersion, fmt.Sprintf("unsupported schema_version %d; this aggregator accepts %d", a.SchemaVersion, auditSchemaVersion)}
+	}
+	if a.Timestamp == "" {
+		return &schemaError{rejectMissingField, "timestamp is required"}
+	}
+	if a.Operation == "" {
+		return &schemaError{rejectMissingField, "operation is required"}
+	}
+	for _, ts := range []struct{ name, value string }{{"timestamp", a.Timestamp}, {"last_timestamp", a.LastTimestamp}} {
+		if ts.value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339Nano, ts.value); err != nil {
+			return &schemaError{rejectInvalidTimestamp, fmt.Sprintf("%s %q is not an RFC 3339 time", ts.name, ts.value)}
+		}
+	}
+	if !auditOperations[a.Operation] {
+		return &schemaError{rejectUnknownOperation, fmt.Sprintf("unknown operation %q", a.Operation)}
+	}
+	if !auditResults[a.Result] {
+		return &schemaError{rejectInvalidResult, fmt.Sprintf("result %q is not allowed, denied or error", a.Result)}
+	}
+	if a.BytesRead < 0 || a.DurationMs < 0 || a.Count < 0 {
+		return &schemaError{rejectNegativeValue, "bytes_read, duration_ms and count must not be negative"}
+	}
+	return nil
+}
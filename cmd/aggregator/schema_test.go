@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCheckAuditSchema(t *testing.T) {
+	valid := AuditLog{Timestamp: "2024-01-01T00:00:00Z", Operation: "ReadFile", Path: "/etc/hosts", Result: "allowed"}
+	if err := checkAuditSchema(valid); err != nil {
+		t.Fatalf("expected a valid event, got %v", err)
+	}
+
+	for reason, mutate := range map[string]func(*AuditLog){
+		rejectUnsupportedVersion: func(a *AuditLog) { a.SchemaVersion = 2 },
+		rejectMissingField:       func(a *AuditLog) { a.Timestamp = "" },
+		rejectInvalidTimestamp:   func(a *AuditLog) { a.Timestamp = "01/01/2024 00:00" },
+		rejectUnknownOperation:   func(a *AuditLog) { a.Operation = "DeleteFile" },
+		rejectInvalidResult:      func(a *AuditLog) { a.Result = "ok" },
+		rejectNegativeValue:      func(a *AuditLog) { a.BytesRead = -1 },
+	} {
+		a := valid
+		mutate(&a)
+		err := checkAuditSchema(a)
+		if err == nil || err.reason != reason {
+			t.Errorf("expected %s, got %v", reason, err)
+		}
+	}
+
+	valid.SchemaVersion = auditSchemaVersion
+	valid.Count, valid.LastTimestamp = 3, "2024-01-01T00:00:05.5Z"
+	if err := checkAuditSchema(valid); err != nil {
+		t.Errorf("expected a deduplicated version 1 event to be valid, got %v", err)
+	}
+}
+
+func TestHandleAuditRejectsInvalidEvents(t *testing.T) {
+	var mu sync.Mutex
+	var received []auditRecord
+	q := newIngestQueue(10, 10, time.Millisecond, func(batch []auditRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, batch...)
+	})
+	ingest = q
+	defer func() { ingest = nil }()
+
+	before := testutil.ToFloat64(rejectedAuditEvents.WithLabelValues(rejectUnknownOperation))
+	batch := `[
+  {"timestamp": "2024-01-01T00:00:00Z", "operation": "ReadFile", "path": "/etc/hosts"},
+  {"timestamp": "2024-01-01T00:00:01Z", "operation": "rm -rf", "path": "/"},
+  {"timestamp": "2024-01-01T00:00:02Z", "operation": "Stat", "path": "/tmp"}
+]`
+	w := httptest.NewRecorder()
+	handleAudit(w, httptest.NewRequest(http.MethodPost, "/audit", bytes.NewBufferString(batch)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `event 1: unknown operation "rm -rf"`) {
+		t.Fatalf("expected the invalid event to be reported, got %d %q", w.Code, w.Body.String())
+	}
+	q.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0].audit.Path != "/etc/hosts" || received[1].audit.Path != "/tmp" {
+		t.Errorf("expected the valid events to be accepted, got %v", received)
+	}
+	if n := testutil.ToFloat64(rejectedAuditEvents.WithLabelValues(rejectUnknownOperation)) - before; n != 1 {
+		t.Errorf("expected one rejection counted, got %v", n)
+	}
+}
+
+func TestHandleAuditSchemaValidationDisabled(t *testing.T) {
+	t.Setenv("PULSAAR_AUDIT_SCHEMA_VALIDATION", "false")
+	if err := initSchemaValidation(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { validateSchema = true }()
+
+	w := httptest.NewRecorder()
+	handleAudit(w, httptest.NewRequest(http.MethodPost, "/audit", bytes.NewBufferString(`{"operation":"Custom"}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 with validation off, got %d", w.Code)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// searchFields are the event fields free text is matched against, by the
+// name a query can qualify a term with, as in user:alice.
+var searchFields = map[string]func(AuditLog) string{
+	"path":       func(a AuditLog) string { return a.Path },
+	"user":       func(a AuditLog) string { return a.User },
+	"agent":      func(a AuditLog) string { return a.AgentID },
+	"namespace":  func(a AuditLog) string { return a.Namespace },
+	"pod":        func(a AuditLog) string { return a.Pod },
+	"container":  func(a AuditLog) string { return a.Container },
+	"node":       func(a AuditLog) string { return a.Node },
+	"operation":  func(a AuditLog) string { return a.Operation },
+	"result":     func(a AuditLog) string { return a.Result },
+	"error_code": func(a AuditLog) string { return a.ErrorCode },
+	"client":     func(a AuditLog) string { return a.ClientAddr },
+	"request_id": func(a AuditLog) string { return a.RequestID },
+}
+
+// searchFieldOrder fixes the order fields are joined in for indexing.
+var searchFieldOrder = func() []string {
+	names := make([]string, 0, len(searchFields))
+	for name := range searchFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}()
+
+// searchTerm is one word or quoted phrase of a query, matched as a
+// case-insensitive substring of field, or of any field when field is empty.
+type searchTerm struct {
+	field string
+	text  string
+}
+
+// parseSearchQuery splits q into terms on spaces, keeping "quoted phrases"
+// whole. A term may be qualified with a field name, as in path:/etc or
+// user:"jane doe".
+func parseSearchQuery(q string) ([]searchTerm, error) {
+	var terms []searchTerm
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimLeftFunc(q, unicode.IsSpace) {
+		var field string
+		if i := strings.IndexAny(q, ": \t\""); i > 0 && q[i] == ':' {
+			if _, ok := searchFields[strings.ToLower(q[:i])]; ok {
+				field, q = strings.ToLower(q[:i]), q[i+1:]
+			}
+		}
+		var text string
+		if strings.HasPrefix(q, `"`) {
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in query")
+			}
+			text, q = q[1:end+1], q[end+2:]
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				end = len(q)
+			}
+			text, q = q[:end], q[end:]
+		}
+		if text == "" {
+			continue
+		}
+		terms = append(terms, searchTerm{field: field, text: strings.ToLower(text)})
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	return terms, nil
+}
+
+// matchTerms reports whether every term is found in a.
+func matchTerms(terms []searchTerm, a AuditLog) bool {
+	for _, t := range terms {
+		if t.field != "" {
+			if !strings.Contains(strings.ToLower(searchFields[t.field](a)), t.text) {
+				return false
+			}
+			continue
+		}
+		found := false
+		for _, name := range searchFieldOrder {
+			if strings.Contains(strings.ToLower(searchFields[name](a)), t.text) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type trigram [3]byte
+
+// searchIndex is a trigram index over the events in the history, so a
+// search reads only the events containing the rarest three-byte sequence
+// of its terms instead of every event held. Postings of evicted events are
+// dropped lazily and the index is rebuilt once they outnumber the live
+// events.
+type searchIndex struct {
+	docs  map[uint64]AuditLog
+	grams map[trigram][]uint64 // seqs in ascending order
+	dead  int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{docs: map[uint64]AuditLog{}, grams: map[trigram][]uint64{}}
+}
+
+// searchText is the lowercased text of a's fields, separated by a byte
+// no query contains so trigrams never span two fields.
+func searchText(a AuditLog) string {
+	var b strings.Builder
+	for _, name := range searchFieldOrder {
+		b.WriteString(strings.ToLower(searchFields[name](a)))
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func trigrams(s string) map[trigram]struct{} {
+	grams := map[trigram]struct{}{}
+	for i := 0; i+3 <= len(s); i++ {
+		grams[trigram{s[i], s[i+1], s[i+2]}] = struct{}{}
+	}
+	return grams
+}
+
+func (x *searchIndex) add(seq uint64, a AuditLog) {
+	x.docs[seq] = a
+	for g := range trigrams(searchText(a)) {
+		x.grams[g] = append(x.grams[g], seq)
+	}
+}
+
+func (x *searchIndex) remove(seq uint64) {
+	if _, ok := x.docs[seq]; !ok {
+		return
+	}
+	delete(x.docs, seq)
+	x.dead++
+	if x.dead > len(x.docs) && x.dead > 1024 {
+		x.rebuild()
+	}
+}
+
+func (x *searchIndex) rebuild() {
+	seqs := make([]uint64, 0, len(x.docs))
+	for seq := range x.docs {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	docs := x.docs
+	x.docs, x.grams, x.dead = map[uint64]AuditLog{}, map[trigram][]uint64{}, 0
+	for _, seq := range seqs {
+		x.add(seq, docs[seq])
+	}
+}
+
+// candidates returns the seqs that may match terms: the shortest posting
+// list among the trigrams of the terms, or every event when no term is
+// three bytes long.
+func (x *searchIndex) candidates(terms []searchTerm) []uint64 {
+	var best []uint64
+	indexed := false
+	for _, t := range terms {
+		for g := range trigrams(t.text) {
+			postings := x.grams[g]
+			if !indexed || len(postings) < len(best) {
+				best, indexed = postings, true
+			}
+		}
+	}
+	if indexed {
+		return best
+	}
+	all := make([]uint64, 0, len(x.docs))
+	for seq := range x.docs {
+		all = append(all, seq)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	return all
+}
+
+// Search returns up to limit events matching terms and f, newest first.
+func (s *eventStore) Search(terms []searchTerm, f eventFilter, limit int) []AuditLog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []AuditLog
+	seqs := s.index.candidates(terms)
+	for i := len(seqs) - 1; i >= 0; i-- {
+		if seqs[i] <= f.After {
+			break
+		}
+		a, ok := s.index.docs[seqs[i]]
+		if !ok || !f.Match(a) || !matchTerms(terms, a) {
+			continue
+		}
+		out = append(out, a)
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out
+}
+
+// handleSearch serves GET /audit/search?q=, finding events in the history
+// by free text within the namespaces the caller's token allows. Every term
+// of q must appear in the event, in any field or the one it names.
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scope, ok := scopeForRequest(r)
+	if !ok {
+		http.Error(w, "Valid query token required", http.StatusUnauthorized)
+		return
+	}
+	filter, ok := scopedFilter(filterFromQuery(r), scope)
+	if !ok {
+		http.Error(w, "Token does not grant access to this namespace", http.StatusForbidden)
+		return
+	}
+	terms, err := parseSearchQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(w, "Invalid q: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := defaultQueryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxQueryLimit)
+	}
+
+	events := history.Search(terms, filter, limit)
+	if events == nil {
+		events = []AuditLog{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"events": events}); err != nil {
+		log.Printf("Error encoding search response: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	terms, err := parseSearchQuery(`Shadow user:"Jane Doe" path:/etc  nofield:x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []searchTerm{{"", "shadow"}, {"user", "jane doe"}, {"path", "/etc"}, {"", "nofield:x"}}
+	if !reflect.DeepEqual(terms, want) {
+		t.Errorf("got %+v, want %+v", terms, want)
+	}
+	for _, q := range []string{"", "   ", `path:"/etc`} {
+		if _, err := parseSearchQuery(q); err == nil {
+			t.Errorf("expected %q to be rejected", q)
+		}
+	}
+}
+
+func TestEventStoreSearch(t *testing.T) {
+	s := newEventStore(2)
+	s.Add(AuditLog{Path: "/etc/shadow", User: "alice", Namespace: "shop"})
+	s.Add(AuditLog{Path: "/var/log/app.log", User: "bob", Namespace: "shop", Node: "node-shadow"})
+	s.Add(AuditLog{Path: "/etc/shadow", User: "bob", Namespace: "billing"})
+
+	search := func(q string, f eventFilter) []string {
+		terms, err := parseSearchQuery(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, a := range s.Search(terms, f, 0) {
+			got = append(got, a.Namespace+":"+a.User+":"+a.Path)
+		}
+		return got
+	}
+	if got := search("SHADOW", eventFilter{}); !reflect.DeepEqual(got, []string{"billing:bob:/etc/shadow", "shop:bob:/var/log/app.log", "shop:alice:/etc/shadow"}) {
+		t.Errorf("free text: got %v", got)
+	}
+	if got := search("path:shadow bob", eventFilter{}); !reflect.DeepEqual(got, []string{"billing:bob:/etc/shadow"}) {
+		t.Errorf("qualified terms: got %v", got)
+	}
+	if got := search("bo", eventFilter{Namespaces: map[string]bool{"shop": true}}); !reflect.DeepEqual(got, []string{"shop:bob:/var/log/app.log"}) {
+		t.Errorf("short term within a tenant: got %v", got)
+	}
+
+	// Evicted events leave the index.
+	s.Add(AuditLog{Path: "/tmp/x", Namespace: "shop"})
+	if got := search("alice", eventFilter{}); got != nil {
+		t.Errorf("expected the evicted event not to be found, got %v", got)
+	}
+}
+
+func TestSearchIndexRebuild(t *testing.T) {
+	s := newEventStore(10)
+	for i := 0; i < 5000; i++ {
+		s.Add(AuditLog{Path: fmt.Sprintf("/data/file-%d", i), Namespace: "shop"})
+	}
+	if len(s.index.docs) != 10 || s.index.dead > 1024 {
+		t.Errorf("expected the index to hold only live events, got %d docs and %d dead", len(s.index.docs), s.index.dead)
+	}
+	terms, _ := parseSearchQuery("file-4995")
+	if got := s.Search(terms, eventFilter{}, 0); len(got) != 1 {
+		t.Errorf("expected one match after rebuilds, got %v", got)
+	}
+}
+
+func TestHandleSearchScopesToToken(t *testing.T) {
+	withTenantHistory(t)
+
+	search := func(token, q string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/audit/search?q="+url.QueryEscape(q), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handleSearch(w, req)
+		return w
+	}
+	if paths := queryPaths(t, search("pay-secret", "readfile")); !reflect.DeepEqual(paths, []string{"/a"}) {
+		t.Errorf("expected only the payments event, got %v", paths)
+	}
+	if paths := queryPaths(t, search("sec-secret", "namespace:search")); !reflect.DeepEqual(paths, []string{"/b"}) {
+		t.Errorf("expected the search namespace event, got %v", paths)
+	}
+	if w := search("sec-secret", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an empty query to be rejected, got %d", w.Code)
+	}
+	if w := search("wrong", "readfile"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected an unknown token to be rejected, got %d", w.Code)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultHistorySize = 1000
+	// maxHistoryPartitions bounds memory when agents report many distinct
+	// namespaces; events for namespaces beyond the limit share one partition.
+	maxHistoryPartitions = 1024
+	overflowPartition    = "\x00overflow"
+)
+
+// eventStore keeps the most recent audit events in memory so they can be
+// browsed and summarized without re-reading the audit file. Events are
+// partitioned by namespace and each namespace keeps its own window of the
+// most recent events, so a busy tenant cannot push another tenant's history
+// out of memory.
+type eventStore struct {
+	mu    sync.RWMutex
+	size  int
+	seq   uint64
+	parts map[string]*eventRing
+	index *searchIndex
+}
+
+type storedEvent struct {
+	seq   uint64
+	audit AuditLog
+}
+
+type eventRing struct {
+	events []storedEvent
+	next   int
+}
+
+var history = newEventStore(defaultHistorySize)
+
+func newEventStore(size int) *eventStore {
+	return &eventStore{size: size, parts: map[string]*eventRing{}, index: newSearchIndex()}
+}
+
+// Size is the maximum number of events the store retains per namespace.
+func (s *eventStore) Size() int {
+	return s.size
+}
+
+func (s *eventStore) Add(audit AuditLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size == 0 {
+		return
+	}
+	key := audit.Namespace
+	ring, ok := s.parts[key]
+	if !ok {
+		if len(s.parts) >= maxHistoryPartitions {
+			key = overflowPartition
+			ring = s.parts[key]
+		}
+		if ring == nil {
+			ring = &eventRing{}
+			s.parts[key] = ring
+		}
+	}
+
+	s.seq++
+	ev := storedEvent{seq: s.seq, audit: audit}
+	s.index.add(ev.seq, audit)
+	if len(ring.events) < s.size {
+		ring.events = append(ring.events, ev)
+		return
+	}
+	s.index.remove(ring.events[ring.next].seq)
+	ring.events[ring.next] = ev
+	ring.next = (ring.next + 1) % s.size
+}
+
+// Recent returns up to limit events matching f across the namespaces f
+// allows, newest first. A limit of zero returns every matching event.
+func (s *eventStore) Recent(f eventFilter, limit int) []AuditLog {
+	events, _ := s.Query(f, limit)
+	return events
+}
+
+// Query is Recent that also returns a cursor: passing it back as
+// eventFilter.After returns only events stored since this call.
+func (s *eventStore) Query(f eventFilter, limit int) ([]AuditLog, uint64) {
+	s.mu.RLock()
+	cursor := s.seq
+	var matched []storedEvent
+	for key, ring := range s.parts {
+		if key != overflowPartition && !f.allowsNamespace(key) {
+			continue
+		}
+		n := 0
+		for i := 0; i < len(ring.events); i++ {
+			idx := (ring.next - 1 - i + 2*len(ring.events)) % len(ring.events)
+			if ring.events[idx].seq <= f.After {
+				break
+			}
+			if !f.Match(ring.events[idx].audit) {
+				continue
+			}
+			matched = append(matched, ring.events[idx])
+			n++
+			if limit > 0 && n >= limit {
+				break
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool { return matched[i].seq > matched[j].seq })
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+	out := make([]AuditLog, len(matched))
+	for i, ev := range matched {
+		out[i] = ev.audit
+	}
+	return out, cursor
+}
+
+type eventFilter struct {
+	Namespace  string
+	User       string
+	AgentID    string
+	Operation  string
+	PathPrefix string
+	// Namespaces restricts results to a tenant's namespaces; nil allows all.
+	Namespaces map[string]bool
+	// After skips events at or before this cursor (see eventStore.Query).
+	After uint64
+}
+
+func (f eventFilter) allowsNamespace(ns string) bool {
+	if f.Namespace != "" && ns != f.Namespace {
+		return false
+	}
+	return f.Namespaces == nil || f.Namespaces[ns]
+}
+
+func (f eventFilter) Match(a AuditLog) bool {
+	if !f.allowsNamespace(a.Namespace) {
+		return false
+	}
+	if f.User != "" && a.User != f.User {
+		return false
+	}
+	if f.AgentID != "" && a.AgentID != f.AgentID {
+		return false
+	}
+	if f.Operation != "" && a.Operation != f.Operation {
+		return false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(a.Path, f.PathPrefix) {
+		return false
+	}
+	return true
+}
+
+type countEntry struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// topCounts tallies key(event) across events and returns the n most frequent
+// values. Empty keys are ignored; a deduplicated event counts for every
+// event it collapsed.
+func topCounts(events []AuditLog, key func(AuditLog) string, n int) []countEntry {
+	counts := map[string]int{}
+	for _, e := range events {
+		if k := key(e); k != "" {
+			counts[k] += int(max(e.Count, 1))
+		}
+	}
+	entries := make([]countEntry, 0, len(counts))
+	for k, c := range counts {
+		entries = append(entries, countEntry{Key: k, Count: c})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	syslogFacilityAudit = 13 // log audit
+	syslogSeverityInfo  = 6
+	syslogAppName       = "pulsaar"
+	syslogMsgID         = "audit"
+	syslogSDID          = "pulsaar@32473"
+)
+
+// syslogWriter forwards audit events to a remote syslog receiver using the
+// RFC 5424 message format. UDP sends one message per datagram; TCP and TLS
+// use octet-counted framing (RFC 6587 / RFC 5425).
+type syslogWriter struct {
+	network   string
+	addr      string
+	tlsConfig *tls.Config
+	hostname  string
+	format    string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+var syslogSink *syslogWriter
+
+func initSyslog() error {
+	rawURL := os.Getenv("PULSAAR_SYSLOG_URL")
+	if rawURL == "" {
+		return nil
+	}
+
+	format, err := parseOutputFormat("PULSAAR_SYSLOG_FORMAT", formatRFC5424, formatRFC5424, formatCEF, formatLEEF)
+	if err != nil {
+		return err
+	}
+	w, err := newSyslogWriter(rawURL, os.Getenv("PULSAAR_SYSLOG_CA_FILE"))
+	if err != nil {
+		return err
+	}
+	w.format = format
+	syslogSink = w
+	return nil
+}
+
+func newSyslogWriter(rawURL, caFile string) (*syslogWriter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog URL: %v", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("syslog URL %q must include host:port", rawURL)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	w := &syslogWriter{addr: u.Host, hostname: hostname, format: formatRFC5424}
+	switch u.Scheme {
+	case "udp", "tcp":
+		w.network = u.Scheme
+	case "tls":
+		w.network = "tcp"
+		w.tlsConfig = &tls.Config{
+			ServerName: u.Hostname(),
+			MinVersion: tls.VersionTLS12,
+		}
+		if caFile != "" {
+			caCert, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read syslog CA file: %v", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caCert) {
+				return nil, fmt.Errorf("failed to parse syslog CA certificate")
+			}
+			w.tlsConfig.RootCAs = pool
+		}
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme %q. Supported schemes: udp, tcp, tls", u.Scheme)
+	}
+	return w, nil
+}
+
+func (w *syslogWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if w.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, w.network, w.addr, w.tlsConfig)
+	}
+	return dialer.Dial(w.network, w.addr)
+}
+
+// Send formats the audit event as an RFC 5424 message and writes it to the
+// receiver. With the cef or leef format the message body carries the SIEM
+// payload instead of structured data and JSON. A broken stream connection is
+// re-dialed once before giving up.
+func (w *syslogWriter) Send(audit AuditLog, body []byte) error {
+	var msg string
+	switch w.format {
+	case formatCEF:
+		msg = syslogHeader(audit, w.hostname) + " - " + formatCEFEvent(audit)
+	case formatLEEF:
+		msg = syslogHeader(audit, w.hostname) + " - " + formatLEEFEvent(audit)
+	default:
+		msg = formatSyslogMessage(audit, w.hostname, body)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			w.conn, err = w.dial()
+			if err != nil {
+				return fmt.Errorf("failed to connect to syslog receiver %s: %v", w.addr, err)
+			}
+		}
+
+		frame := msg
+		if w.network == "tcp" {
+			frame = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+		_ = w.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err = w.conn.Write([]byte(frame)); err == nil {
+			return nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return fmt.Errorf("failed to write to syslog receiver %s: %v", w.addr, err)
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// syslogHeader returns the RFC 5424 header up to and including MSGID.
+func syslogHeader(audit AuditLog, hostname string) string {
+	pri := syslogFacilityAudit*8 + syslogSeverityInfo
+
+	timestamp := "-"
+	if t, err := time.Parse(time.RFC3339, audit.Timestamp); err == nil {
+		timestamp = t.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s", pri, timestamp, hostname, syslogAppName, os.Getpid(), syslogMsgID)
+}
+
+func formatSyslogMessage(audit AuditLog, hostname string, body []byte) string {
+	sd := fmt.Sprintf(`[%s operation="%s" path="%s"`, syslogSDID, escapeSDParam(audit.Operation), escapeSDParam(audit.Path))
+	for _, param := range []struct{ name, value string }{
+		{"agent_id", audit.AgentID},
+		{"user", audit.User},
+		{"namespace", audit.Namespace},
+		{"pod", audit.Pod},
+		{"node", audit.Node},
+		{"result", audit.Result},
+		{"error_code", audit.ErrorCode},
+		{"client_addr", audit.ClientAddr},
+		{"request_id", audit.RequestID},
+	} {
+		if param.value != "" {
+			sd += fmt.Sprintf(` %s="%s"`, param.name, escapeSDParam(param.value))
+		}
+	}
+	sd += "]"
+
+	return fmt.Sprintf("%s %s %s", syslogHeader(audit, hostname), sd, strings.TrimSpace(string(body)))
+}
+
+// escapeSDParam escapes the characters RFC 5424 section 6.3.3 requires inside
+// structured data parameter values.
+func escapeSDParam(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	return r.Replace(v)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatSyslogMessage(t *testing.T) {
+	audit := AuditLog{
+		Timestamp: "2023-01-01T00:00:00Z",
+		Operation: "ReadFile",
+		Path:      `/etc/"odd"]\path`,
+		AgentID:   "agent-1",
+	}
+	msg := formatSyslogMessage(audit, "aggregator-0", []byte(`{"operation":"ReadFile"}`+"\n"))
+
+	if !strings.HasPrefix(msg, "<110>1 2023-01-01T00:00:00.000000Z aggregator-0 pulsaar ") {
+		t.Errorf("unexpected header: %s", msg)
+	}
+	if !strings.Contains(msg, `[pulsaar@32473 operation="ReadFile" path="/etc/\"odd\"\]\\path" agent_id="agent-1"]`) {
+		t.Errorf("unexpected structured data: %s", msg)
+	}
+	if !strings.HasSuffix(msg, `{"operation":"ReadFile"}`) {
+		t.Errorf("expected JSON body as message, got %s", msg)
+	}
+}
+
+func TestNewSyslogWriterInvalidScheme(t *testing.T) {
+	if _, err := newSyslogWriter("http://localhost:514", ""); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+	if _, err := newSyslogWriter("udp://", ""); err == nil {
+		t.Error("expected error for missing host")
+	}
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	w, err := newSyslogWriter("udp://"+pc.LocalAddr().String(), "")
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	if err := w.Send(AuditLog{Operation: "Stat", Path: "/tmp"}, []byte(`{}`)); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !strings.Contains(string(buf[:n]), `operation="Stat"`) {
+		t.Errorf("unexpected datagram: %s", buf[:n])
+	}
+}
+
+func TestSyslogWriterTCPFraming(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer func() { _ = lis.Close() }()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		line, _ := bufio.NewReader(conn).ReadString('}')
+		received <- line
+	}()
+
+	w, err := newSyslogWriter("tcp://"+lis.Addr().String(), "")
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	if err := w.Send(AuditLog{Operation: "ListDirectory", Path: "/"}, []byte(`{}`)); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	select {
+	case frame := <-received:
+		sp := strings.IndexByte(frame, ' ')
+		if sp <= 0 || !strings.HasPrefix(frame[sp+1:], "<110>1 ") {
+			t.Errorf("expected octet-counted frame, got %q", frame)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for syslog frame")
+	}
+}
+
+func TestSyslogWriterCEF(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	w, err := newSyslogWriter("udp://"+pc.LocalAddr().String(), "")
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+	w.format = formatCEF
+
+	if err := w.Send(AuditLog{Operation: "Stat", Path: "/tmp"}, []byte(`{}`)); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !strings.Contains(string(buf[:n]), " audit - CEF:0|Pulsaar|Pulsaar|") {
+		t.Errorf("expected CEF payload after syslog header, got %s", buf[:n])
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// queryTokenConfig is one entry of PULSAAR_QUERY_TOKENS_FILE. Tokens are
+// stored as SHA-256 hex digests so the file does not hold usable secrets.
+// A namespace of "*" grants access to every namespace.
+type queryTokenConfig struct {
+	Name        string   `json:"name"`
+	TokenSHA256 string   `json:"token_sha256"`
+	Namespaces  []string `json:"namespaces"`
+}
+
+// tenantScope is what a presented query token grants.
+type tenantScope struct {
+	Name       string
+	Namespaces map[string]bool // nil means all namespaces
+}
+
+// Allows reports whether the scope may read events from namespace ns.
+func (s *tenantScope) Allows(ns string) bool {
+	return s.Namespaces == nil || s.Namespaces[ns]
+}
+
+type tokenEntry struct {
+	digest []byte
+	scope  *tenantScope
+}
+
+// queryTokens is nil when query authentication is disabled.
+var queryTokens []tokenEntry
+
+func initQueryTokens() error {
+	path := os.Getenv("PULSAAR_QUERY_TOKENS_FILE")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read query tokens file: %v", err)
+	}
+	var cfg struct {
+		Tokens []queryTokenConfig `json:"tokens"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse query tokens file: %v", err)
+	}
+	entries, err := newTokenEntries(cfg.Tokens)
+	if err != nil {
+		return err
+	}
+	queryTokens = entries
+	log.Printf("Loaded %d scoped query tokens", len(entries))
+	return nil
+}
+
+func newTokenEntries(tokens []queryTokenConfig) ([]tokenEntry, error) {
+	entries := make([]tokenEntry, 0, len(tokens))
+	for _, t := range tokens {
+		digest, err := hex.DecodeString(t.TokenSHA256)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("query token %q: token_sha256 must be a hex SHA-256 digest", t.Name)
+		}
+		if len(t.Namespaces) == 0 {
+			return nil, fmt.Errorf("query token %q must list at least one namespace", t.Name)
+		}
+		scope := &tenantScope{Name: t.Name, Namespaces: map[string]bool{}}
+		for _, ns := range t.Namespaces {
+			if ns == "*" {
+				scope.Namespaces = nil
+				break
+			}
+			scope.Namespaces[ns] = true
+		}
+		entries = append(entries, tokenEntry{digest: digest, scope: scope})
+	}
+	return entries, nil
+}
+
+// scopeForRequest resolves the bearer token on r. When query tokens are not
+// configured every request gets an unrestricted scope.
+func scopeForRequest(r *http.Request) (*tenantScope, bool) {
+	if queryTokens == nil {
+		return &tenantScope{}, true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		// Browsers opening the dashboard cannot set headers.
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return nil, false
+	}
+	sum := sha256.Sum256([]byte(token))
+	for _, e := range queryTokens {
+		if subtle.ConstantTimeCompare(sum[:], e.digest) == 1 {
+			return e.scope, true
+		}
+	}
+	return nil, false
+}
+
+// scopedFilter narrows f to the namespaces scope may read. It returns false
+// when f asks for a namespace outside the scope.
+func scopedFilter(f eventFilter, scope *tenantScope) (eventFilter, bool) {
+	if f.Namespace != "" && !scope.Allows(f.Namespace) {
+		return f, false
+	}
+	f.Namespaces = scope.Namespaces
+	return f, true
+}
+
+// handleQuery serves GET /api/v1/audit, returning recent events visible to
+// the caller's token as JSON, newest first.
+func handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scope, ok := scopeForRequest(r)
+	if !ok {
+		http.Error(w, "Valid query token required", http.StatusUnauthorized)
+		return
+	}
+	filter, ok := scopedFilter(filterFromQuery(r), scope)
+	if !ok {
+		http.Error(w, "Token does not grant access to this namespace", http.StatusForbidden)
+		return
+	}
+
+	limit := defaultQueryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxQueryLimit)
+	}
+	if v := r.URL.Query().Get("after"); v != "" {
+		after, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid after cursor", http.StatusBadRequest)
+			return
+		}
+		filter.After = after
+	}
+
+	events, cursor := history.Query(filter, limit)
+	if events == nil {
+		events = []AuditLog{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"events": events, "cursor": cursor}); err != nil {
+		log.Printf("Error encoding query response: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func tokenDigest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func withTenantHistory(t *testing.T) {
+	t.Helper()
+	originalHistory, originalTokens := history, queryTokens
+	t.Cleanup(func() { history, queryTokens = originalHistory, originalTokens })
+
+	history = newEventStore(10)
+	history.Add(AuditLog{Operation: "ReadFile", Path: "/a", Namespace: "payments"})
+	history.Add(AuditLog{Operation: "ReadFile", Path: "/b", Namespace: "search"})
+
+	entries, err := newTokenEntries([]queryTokenConfig{
+		{Name: "payments-team", TokenSHA256: tokenDigest("pay-secret"), Namespaces: []string{"payments"}},
+		{Name: "security", TokenSHA256: tokenDigest("sec-secret"), Namespaces: []string{"*"}},
+	})
+	if err != nil {
+		t.Fatalf("newTokenEntries: %v", err)
+	}
+	queryTokens = entries
+}
+
+func queryPaths(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var resp struct {
+		Events []AuditLog `json:"events"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+	var paths []string
+	for _, e := range resp.Events {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
+
+func TestHandleQueryScopesToToken(t *testing.T) {
+	withTenantHistory(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil)
+	req.Header.Set("Authorization", "Bearer pay-secret")
+	w := httptest.NewRecorder()
+	handleQuery(w, req)
+	if paths := queryPaths(t, w); len(paths) != 1 || paths[0] != "/a" {
+		t.Errorf("expected only the payments event, got %v", paths)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil)
+	req.Header.Set("Authorization", "Bearer sec-secret")
+	w = httptest.NewRecorder()
+	handleQuery(w, req)
+	if paths := queryPaths(t, w); len(paths) != 2 {
+		t.Errorf("expected wildcard token to see every namespace, got %v", paths)
+	}
+}
+
+func TestHandleQueryCursor(t *testing.T) {
+	withTenantHistory(t)
+
+	query := func(url string) ([]string, uint64) {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer sec-secret")
+		w := httptest.NewRecorder()
+		handleQuery(w, req)
+		var resp struct {
+			Cursor uint64 `json:"cursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+		}
+		return queryPaths(t, w), resp.Cursor
+	}
+
+	_, cursor := query("/api/v1/audit")
+	if cursor != 2 {
+		t.Fatalf("expected cursor 2, got %d", cursor)
+	}
+	history.Add(AuditLog{Operation: "Stat", Path: "/c", Namespace: "payments"})
+	paths, next := query("/api/v1/audit?after=2")
+	if len(paths) != 1 || paths[0] != "/c" || next != 3 {
+		t.Errorf("expected only the new event and cursor 3, got %v cursor %d", paths, next)
+	}
+	if paths, _ := query("/api/v1/audit?after=3"); len(paths) != 0 {
+		t.Errorf("expected no events after latest cursor, got %v", paths)
+	}
+}
+
+func TestHandleQueryRejects(t *testing.T) {
+	withTenantHistory(t)
+
+	for _, tc := range []struct {
+		name, url, auth string
+		want            int
+	}{
+		{"no token", "/api/v1/audit", "", http.StatusUnauthorized},
+		{"wrong token", "/api/v1/audit", "Bearer nope", http.StatusUnauthorized},
+		{"other namespace", "/api/v1/audit?namespace=search", "Bearer pay-secret", http.StatusForbidden},
+		{"bad limit", "/api/v1/audit?limit=-1", "Bearer pay-secret", http.StatusBadRequest},
+		{"bad cursor", "/api/v1/audit?after=x", "Bearer pay-secret", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		w := httptest.NewRecorder()
+		handleQuery(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
+
+func TestHandleDashboardScopedByToken(t *testing.T) {
+	withTenantHistory(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard?token=pay-secret", nil)
+	w := httptest.NewRecorder()
+	handleDashboard(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "payments") || strings.Contains(body, "search") {
+		t.Errorf("expected dashboard limited to payments: %s", body)
+	}
+}
+
+func TestNewTokenEntriesValidation(t *testing.T) {
+	if _, err := newTokenEntries([]queryTokenConfig{{Name: "x", TokenSHA256: "abc", Namespaces: []string{"a"}}}); err == nil {
+		t.Error("expected error for malformed digest")
+	}
+	if _, err := newTokenEntries([]queryTokenConfig{{Name: "x", TokenSHA256: tokenDigest("t")}}); err == nil {
+		t.Error("expected error for token without namespaces")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// loadServerTLSConfig builds the TLS configuration shared by the HTTP and
+// gRPC listeners from PULSAAR_AGGREGATOR_TLS_CERT/KEY. When
+// PULSAAR_AGGREGATOR_TLS_CA is also set, clients must present a certificate
+// signed by that CA. It returns nil when TLS is not configured.
+func loadServerTLSConfig() (*tls.Config, error) {
+	certFile := os.Getenv("PULSAAR_AGGREGATOR_TLS_CERT")
+	keyFile := os.Getenv("PULSAAR_AGGREGATOR_TLS_KEY")
+	caFile := os.Getenv("PULSAAR_AGGREGATOR_TLS_CA")
+
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, fmt.Errorf("PULSAAR_AGGREGATOR_TLS_CA requires PULSAAR_AGGREGATOR_TLS_CERT and PULSAAR_AGGREGATOR_TLS_KEY")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both PULSAAR_AGGREGATOR_TLS_CERT and PULSAAR_AGGREGATOR_TLS_KEY must be set")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aggregator certificate: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse client CA certificate")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// httpTLSConfig relaxes client certificate verification for the HTTP
+// listener so kubelet probes can reach /health; handlers that carry audit
+// data are wrapped with requireClientCert instead.
+func httpTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil || config.ClientAuth != tls.RequireAndVerifyClientCert {
+		return config
+	}
+	httpConfig := config.Clone()
+	httpConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return httpConfig
+}
+
+// requireClientCert rejects requests without a verified client certificate
+// when mTLS is enabled.
+func requireClientCert(config *tls.Config, next http.HandlerFunc) http.HandlerFunc {
+	if config == nil || config.ClientAuth != tls.RequireAndVerifyClientCert {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "Client certificate required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
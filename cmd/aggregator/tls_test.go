@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "pulsaar-aggregator"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestLoadServerTLSConfigUnset(t *testing.T) {
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_CERT", "")
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_KEY", "")
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_CA", "")
+
+	config, err := loadServerTLSConfig()
+	if err != nil || config != nil {
+		t.Fatalf("expected nil config without TLS settings, got %v, %v", config, err)
+	}
+}
+
+func TestLoadServerTLSConfigErrors(t *testing.T) {
+	certFile, _ := writeTestCert(t, t.TempDir())
+
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_CERT", certFile)
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_KEY", "")
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_CA", "")
+	if _, err := loadServerTLSConfig(); err == nil {
+		t.Error("expected error when only the certificate is set")
+	}
+
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_CERT", "")
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_CA", certFile)
+	if _, err := loadServerTLSConfig(); err == nil {
+		t.Error("expected error when only the CA is set")
+	}
+}
+
+func TestLoadServerTLSConfigMutual(t *testing.T) {
+	certFile, keyFile := writeTestCert(t, t.TempDir())
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_CERT", certFile)
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_KEY", keyFile)
+	t.Setenv("PULSAAR_AGGREGATOR_TLS_CA", certFile)
+
+	config, err := loadServerTLSConfig()
+	if err != nil {
+		t.Fatalf("loadServerTLSConfig: %v", err)
+	}
+	if config.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("expected client certificates to be required, got %v", config.ClientAuth)
+	}
+	if httpTLSConfig(config).ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Error("expected HTTP listener to accept connections without a client certificate")
+	}
+}
+
+func TestRequireClientCert(t *testing.T) {
+	config := &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert}
+	handler := requireClientCert(config, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("POST", "/audit", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a client certificate, got %d", w.Code)
+	}
+
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 with a verified client certificate, got %d", w.Code)
+	}
+}
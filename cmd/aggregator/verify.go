@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// chainVerifier walks hash-chained audit log lines in order and records the
+// first integrity violation it finds.
+type chainVerifier struct {
+	publicKey ed25519.PublicKey
+
+	records     uint64
+	checkpoints uint64
+	firstSeq    uint64
+	seq         uint64
+	head        string
+	// records written after the most recent checkpoint
+	unsigned uint64
+}
+
+// runVerify implements `pulsaar-aggregator verify [-public-key file] log...`.
+// Files must be given oldest first; rotated .gz segments are read directly.
+func runVerify(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(out)
+	publicKeyFile := fs.String("public-key", "", "PEM-encoded Ed25519 public key used to verify checkpoint signatures")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(out, "Usage: pulsaar-aggregator verify [-public-key file] audit.log.<oldest>[.gz] ... audit.log")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	v := &chainVerifier{}
+	if *publicKeyFile != "" {
+		key, err := loadVerifyKey(*publicKeyFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(out, "FAIL: %v\n", err)
+			return 1
+		}
+		v.publicKey = key
+	}
+
+	for _, path := range fs.Args() {
+		if err := v.verifyFile(path); err != nil {
+			_, _ = fmt.Fprintf(out, "FAIL: %v\n", err)
+			return 1
+		}
+	}
+
+	_, _ = fmt.Fprintf(out, "OK: %d records (seq %d-%d), %d checkpoints, head %s\n",
+		v.records, v.firstSeq, v.seq, v.checkpoints, v.head)
+	if v.firstSeq > 1 {
+		_, _ = fmt.Fprintf(out, "WARNING: chain starts at seq %d; earlier segments were not provided or have been pruned\n", v.firstSeq)
+	}
+	if v.publicKey == nil && v.checkpoints > 0 {
+		_, _ = fmt.Fprintln(out, "WARNING: checkpoint signatures not verified; pass -public-key")
+	}
+	if v.unsigned > 0 {
+		_, _ = fmt.Fprintf(out, "WARNING: %d records after the last checkpoint; truncation of these records cannot be detected\n", v.unsigned)
+	}
+	return 0
+}
+
+func (v *chainVerifier) verifyFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		defer func() { _ = zr.Close() }()
+		r = zr
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		if err := v.verifyLine(scanner.Bytes()); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func (v *chainVerifier) verifyLine(data []byte) error {
+	var line chainLine
+	if err := json.Unmarshal(data, &line); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	if cp := line.Checkpoint; cp != nil {
+		if cp.Seq != v.seq || cp.Hash != v.head {
+			return fmt.Errorf("checkpoint for seq %d does not match chain head seq %d", cp.Seq, v.seq)
+		}
+		if v.publicKey != nil {
+			sig, err := base64.StdEncoding.DecodeString(cp.Signature)
+			if err != nil || !ed25519.Verify(v.publicKey, checkpointMessage(*cp), sig) {
+				return fmt.Errorf("invalid signature on checkpoint for seq %d", cp.Seq)
+			}
+		}
+		v.checkpoints++
+		v.unsigned = 0
+		return nil
+	}
+
+	if line.Hash == "" {
+		return fmt.Errorf("not a hash-chained record")
+	}
+	if v.records == 0 {
+		if line.Seq == 1 && line.PrevHash != "" {
+			return fmt.Errorf("first record has a previous hash")
+		}
+		v.firstSeq = line.Seq
+	} else {
+		if line.Seq != v.seq+1 {
+			return fmt.Errorf("expected seq %d, found %d (records missing or reordered)", v.seq+1, line.Seq)
+		}
+		if line.PrevHash != v.head {
+			return fmt.Errorf("seq %d does not chain to seq %d", line.Seq, v.seq)
+		}
+	}
+	if chainHash(line.PrevHash, line.Seq, line.Event) != line.Hash {
+		return fmt.Errorf("hash mismatch at seq %d (record modified)", line.Seq)
+	}
+
+	v.records++
+	v.unsigned++
+	v.seq, v.head = line.Seq, line.Hash
+	return nil
+}
+
+func loadVerifyKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("public key %s is not PEM encoded", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key must be an Ed25519 key")
+	}
+	return edKey, nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/VrushankPatel/pulsaar/pkg/access"
+)
+
+// accessPollInterval is how often request --wait checks for a decision.
+var accessPollInterval = 2 * time.Second
+
+// accessClients returns the clients the access commands use. It is a
+// variable so tests can stand in for the cluster.
+var accessClients = func() (dynamic.Interface, kubernetes.Interface, error) {
+	config, err := kubeConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to connect to Kubernetes cluster. Please check your kubeconfig or in-cluster configuration. Error: %w", err)
+	}
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dyn, clientset, nil
+}
+
+func newAccessCmd() *cobra.Command {
+	accessCmd := &cobra.Command{
+		Use:   "access",
+		Short: "Request, approve and list just-in-time access to pods",
+		Long: `Agents started with --require-approval only serve callers holding an
+approved PulsaarAccessRequest for their pod. Request access, have someone
+else approve it, and use the CLI as usual until the approval expires.`,
+	}
+	accessCmd.PersistentFlags().String("namespace", "default", "Namespace")
+
+	requestCmd := &cobra.Command{
+		Use:   "request",
+		Short: "Ask for access to a pod for a limited time",
+		Args:  cobra.NoArgs,
+		RunE:  runAccessRequest,
+	}
+	requestCmd.Flags().String("pod", "", "Pod to access")
+	requestCmd.Flags().StringSlice("path", nil, "Directory or file the access is limited to; repeat for several (default every allowed path)")
+	requestCmd.Flags().Duration("duration", time.Hour, "How long the access lasts once approved, at most 24h")
+	requestCmd.Flags().String("reason", "", "Why the access is needed, shown to approvers")
+	requestCmd.Flags().String("user", "", "Identity the agent sees: the client certificate's common name or the identity token's ServiceAccount (default read from $PULSAAR_CLIENT_CERT_FILE or --identity-token-file)")
+	requestCmd.Flags().Duration("wait", 0, "Wait this long for the request to be decided, e.g. 10m")
+	for _, name := range []string{"pod", "reason"} {
+		if err := requestCmd.MarkFlagRequired(name); err != nil {
+			panic(err)
+		}
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List access requests",
+		Args:  cobra.NoArgs,
+		RunE:  runAccessList,
+	}
+	listCmd.Flags().BoolP("all-namespaces", "A", false, "List requests in every namespace")
+
+	approveCmd := &cobra.Command{
+		Use:   "approve NAME",
+		Short: "Approve a pending access request",
+		Args:  cobra.ExactArgs(1),
+		RunE:  func(cmd *cobra.Command, args []string) error { return runAccessDecide(cmd, args[0], true) },
+	}
+	denyCmd := &cobra.Command{
+		Use:   "deny NAME",
+		Short: "Deny a pending access request",
+		Args:  cobra.ExactArgs(1),
+		RunE:  func(cmd *cobra.Command, args []string) error { return runAccessDecide(cmd, args[0], false) },
+	}
+	for _, c := range []*cobra.Command{approveCmd, denyCmd} {
+		c.Flags().String("message", "", "Note recorded with the decision")
+	}
+
+	accessCmd.AddCommand(requestCmd, listCmd, approveCmd, denyCmd)
+	return accessCmd
+}
+
+// certIdentity names a client certificate the way the agent does.
+func certIdentity(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("%s holds no PEM certificate", file)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName, nil
+	}
+	if subject := cert.Subject.String(); subject != "" {
+		return subject, nil
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String(), nil
+	}
+	return "", fmt.Errorf("the certificate in %s has no subject", file)
+}
+
+// tokenIdentity reads the subject of a ServiceAccount token, which is the
+// username the agent's TokenReview returns. The token is not verified;
+// the agent does that.
+func tokenIdentity(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(strings.TrimSpace(string(data)), ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%s does not hold a JWT", file)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("%s does not hold a JWT: %v", file, err)
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return "", fmt.Errorf("the token in %s has no subject", file)
+	}
+	return claims.Subject, nil
+}
+
+// requesterIdentity returns --user, else the identity of the credentials
+// the CLI would present to the agent.
+func requesterIdentity(cmd *cobra.Command) (string, error) {
+	if user, _ := cmd.Flags().GetString("user"); user != "" {
+		return user, nil
+	}
+	if file := os.Getenv("PULSAAR_CLIENT_CERT_FILE"); file != "" {
+		return certIdentity(file)
+	}
+	if file, _ := cmd.Flags().GetString("identity-token-file"); file != "" {
+		return tokenIdentity(file)
+	}
+	return "", fmt.Errorf("cannot tell which identity the agent will see; pass --user, or set PULSAAR_CLIENT_CERT_FILE or --identity-token-file")
+}
+
+// kubernetesUser returns who the API server authenticates the CLI as.
+func kubernetesUser(ctx context.Context, clientset kubernetes.Interface) (string, error) {
+	review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up your Kubernetes identity: %v", err)
+	}
+	if review.Status.UserInfo.Username == "" {
+		return "", fmt.Errorf("the API server did not report your Kubernetes identity")
+	}
+	return review.Status.UserInfo.Username, nil
+}
+
+func runAccessRequest(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	pod, _ := cmd.Flags().GetString("pod")
+	paths, _ := cmd.Flags().GetStringSlice("path")
+	duration, _ := cmd.Flags().GetDuration("duration")
+	reason, _ := cmd.Flags().GetString("reason")
+	wait, _ := cmd.Flags().GetDuration("wait")
+
+	user, err := requesterIdentity(cmd)
+	if err != nil {
+		return err
+	}
+	r, err := access.NewRequest(namespace, access.Spec{User: user, Pod: pod, Paths: paths, Duration: duration.String(), Reason: reason})
+	if err != nil {
+		return err
+	}
+	dyn, _, err := accessClients()
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	created, err := access.Create(ctx, dyn, r)
+	if err != nil {
+		return fmt.Errorf("failed to create access request: %v", err)
+	}
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(out, "Created access request %s for %s on pod %s/%s.\n", created.Name, user, namespace, pod)
+	_, _ = fmt.Fprintf(out, "An approver can run: pulsaar access approve %s --namespace %s\n", created.Name, namespace)
+	if wait <= 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(wait)
+	for created.Phase() == access.PhasePending {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("access request %s was not decided within %s", created.Name, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(accessPollInterval):
+		}
+		if created, err = access.Get(ctx, dyn, namespace, created.Name); err != nil {
+			return fmt.Errorf("failed to read access request: %v", err)
+		}
+	}
+	if created.Phase() != access.PhaseApproved {
+		return fmt.Errorf("access request %s was %s by %s%s", created.Name, strings.ToLower(created.Phase()), created.Status.DecidedBy, decisionMessage(created))
+	}
+	_, _ = fmt.Fprintf(out, "Approved by %s until %s.\n", created.Status.DecidedBy, created.Status.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+func decisionMessage(r *access.Request) string {
+	if r.Status.Message == "" {
+		return ""
+	}
+	return ": " + r.Status.Message
+}
+
+func runAccessList(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	if all, _ := cmd.Flags().GetBool("all-namespaces"); all {
+		namespace = ""
+	}
+	dyn, _, err := accessClients()
+	if err != nil {
+		return err
+	}
+	requests, err := access.List(cmd.Context(), dyn, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list access requests: %v", err)
+	}
+	printAccessRequests(cmd.OutOrStdout(), requests, namespace == "")
+	return nil
+}
+
+func printAccessRequests(w io.Writer, requests []access.Request, withNamespace bool) {
+	if len(requests) == 0 {
+		_, _ = fmt.Fprintln(w, "No access requests.")
+		return
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreationTimestamp.After(requests[j].CreationTimestamp.Time)
+	})
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := "NAME\tUSER\tPOD\tPATHS\tDURATION\tPHASE\tDECIDED BY\tEXPIRES\tREASON"
+	if withNamespace {
+		header = "NAMESPACE\t" + header
+	}
+	_, _ = fmt.Fprintln(tw, header)
+	for _, r := range requests {
+		paths, expires := strings.Join(r.Spec.Paths, ","), ""
+		if paths == "" {
+			paths = "*"
+		}
+		if r.Status.ExpiresAt != nil {
+			expires = r.Status.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		row := strings.Join([]string{r.Name, r.Spec.User, r.Spec.Pod, paths, r.Spec.Duration, r.Phase(), r.Status.DecidedBy, expires, r.Spec.Reason}, "\t")
+		if withNamespace {
+			row = r.Namespace + "\t" + row
+		}
+		_, _ = fmt.Fprintln(tw, row)
+	}
+	_ = tw.Flush()
+}
+
+func runAccessDecide(cmd *cobra.Command, name string, approve bool) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	message, _ := cmd.Flags().GetString("message")
+	dyn, clientset, err := accessClients()
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	decider, err := kubernetesUser(ctx, clientset)
+	if err != nil {
+		return err
+	}
+	r, err := access.Get(ctx, dyn, namespace, name)
+	if err != nil {
+		return fmt.Errorf("failed to read access request: %v", err)
+	}
+	if err := r.Decide(decider, approve, message, time.Now()); err != nil {
+		return err
+	}
+	if _, err := access.UpdateStatus(ctx, dyn, r); err != nil {
+		return fmt.Errorf("failed to record the decision: %v", err)
+	}
+	out := cmd.OutOrStdout()
+	if approve {
+		_, _ = fmt.Fprintf(out, "Approved %s: %s may access pod %s/%s until %s.\n", name, r.Spec.User, namespace, r.Spec.Pod, r.Status.ExpiresAt.UTC().Format(time.RFC3339))
+	} else {
+		_, _ = fmt.Fprintf(out, "Denied %s.\n", name)
+	}
+	return nil
+}
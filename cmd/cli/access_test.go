@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/VrushankPatel/pulsaar/pkg/access"
+)
+
+// withAccessCluster stands in for a cluster where the CLI is authenticated
+// as user.
+func withAccessCluster(t *testing.T, user *string) dynamic.Interface {
+	t.Helper()
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{access.GVR: access.Kind + "List"})
+	// The fake does not fill in generated names.
+	dyn.PrependReactor("create", access.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.CreateAction).GetObject().(interface {
+			GetName() string
+			GetGenerateName() string
+			SetName(string)
+		})
+		if obj.GetName() == "" {
+			obj.SetName(obj.GetGenerateName() + "x7k2p")
+		}
+		return false, nil, nil
+	})
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("create", "selfsubjectreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := &authenticationv1.SelfSubjectReview{}
+		review.Status.UserInfo.Username = *user
+		return true, review, nil
+	})
+	original := accessClients
+	accessClients = func() (dynamic.Interface, kubernetes.Interface, error) { return dyn, clientset, nil }
+	t.Cleanup(func() { accessClients = original })
+	return dyn
+}
+
+func runAccess(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := newAccessCmd()
+	cmd.PersistentFlags().String("identity-token-file", "", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func TestAccessRequestApproveList(t *testing.T) {
+	t.Setenv("PULSAAR_CLIENT_CERT_FILE", "")
+	user := "alice"
+	dyn := withAccessCluster(t, &user)
+
+	out, err := runAccess(t, "request", "--namespace", "shop", "--pod", "web-0", "--path", "/var/log", "--duration", "30m", "--reason", "INC-42", "--user", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Created access request web-0-x7k2p for alice on pod shop/web-0.") {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	if _, err := runAccess(t, "approve", "web-0-x7k2p", "--namespace", "shop"); err == nil || !strings.Contains(err.Error(), "cannot be approved by the user") {
+		t.Errorf("expected alice to be unable to approve their own request, got %v", err)
+	}
+	user = "bob"
+	if out, err := runAccess(t, "approve", "web-0-x7k2p", "--namespace", "shop"); err != nil || !strings.Contains(out, "Approved web-0-x7k2p: alice may access pod shop/web-0 until") {
+		t.Fatalf("unexpected approval %q: %v", out, err)
+	}
+
+	r, err := access.Get(context.Background(), dyn, "shop", "web-0-x7k2p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Grants("alice", "web-0", []string{"/var/log/syslog"}, time.Now()) || r.Grants("alice", "web-0", []string{"/var/log"}, time.Now().Add(31*time.Minute)) {
+		t.Errorf("unexpected grant %+v", r.Status)
+	}
+
+	out, err = runAccess(t, "list", "--namespace", "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "NAME") || !strings.Contains(lines[1], "Approved") || !strings.Contains(lines[1], "bob") {
+		t.Errorf("unexpected listing %q", out)
+	}
+
+	if _, err := runAccess(t, "deny", "web-0-x7k2p", "--namespace", "shop"); err == nil {
+		t.Error("expected a decided request to stay decided")
+	}
+}
+
+func TestAccessRequestWaitForDenial(t *testing.T) {
+	user := "bob"
+	dyn := withAccessCluster(t, &user)
+	original := accessPollInterval
+	accessPollInterval = time.Millisecond
+	t.Cleanup(func() { accessPollInterval = original })
+
+	// Deny the request as soon as it shows up.
+	go func() {
+		for {
+			requests, _ := access.List(context.Background(), dyn, "shop")
+			if len(requests) == 1 {
+				r := &requests[0]
+				_ = r.Decide("bob", false, "use the runbook", time.Now())
+				_, _ = access.UpdateStatus(context.Background(), dyn, r)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	_, err := runAccess(t, "request", "--namespace", "shop", "--pod", "web-0", "--reason", "debug", "--user", "alice", "--wait", "5s")
+	if err == nil || !strings.Contains(err.Error(), "was denied by bob: use the runbook") {
+		t.Errorf("expected the denial to be reported, got %v", err)
+	}
+}
+
+func TestRequesterIdentityFromToken(t *testing.T) {
+	t.Setenv("PULSAAR_CLIENT_CERT_FILE", "")
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"system:serviceaccount:ops:debugger"}`))
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("e30."+payload+".sig\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := newAccessCmd()
+	cmd.PersistentFlags().String("identity-token-file", "", "")
+	request, _, _ := cmd.Find([]string{"request"})
+	// Merge the parent's persistent flags the way Execute would.
+	request.InheritedFlags()
+	if err := cmd.PersistentFlags().Set("identity-token-file", file); err != nil {
+		t.Fatal(err)
+	}
+	user, err := requesterIdentity(request)
+	if err != nil || user != "system:serviceaccount:ops:debugger" {
+		t.Errorf("expected the token's ServiceAccount, got %q (%v)", user, err)
+	}
+}
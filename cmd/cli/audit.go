@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// auditEvent mirrors the aggregator's JSON audit record.
+type auditEvent struct {
+	Timestamp string `json:"timestamp"`
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	User      string `json:"user,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Result    string `json:"result,omitempty"`
+	Count     int64  `json:"count,omitempty"`
+}
+
+type auditQueryResponse struct {
+	Events []auditEvent `json:"events"`
+	Cursor uint64       `json:"cursor"`
+}
+
+// auditQuery calls the aggregator's GET /api/v1/audit, or the endpoint at
+// path when set.
+type auditQuery struct {
+	baseURL string
+	path    string
+	token   string
+	params  url.Values
+	client  *http.Client
+}
+
+func newAuditQuery(cmd *cobra.Command) (*auditQuery, error) {
+	baseURL, _ := cmd.Flags().GetString("aggregator")
+	if baseURL == "" {
+		baseURL = os.Getenv("PULSAAR_AGGREGATOR_URL")
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("no aggregator address. Pass --aggregator or set PULSAAR_AGGREGATOR_URL (e.g. https://pulsaar-aggregator.pulsaar:8080)")
+	}
+	token, _ := cmd.Flags().GetString("token")
+	if token == "" {
+		token = os.Getenv("PULSAAR_QUERY_TOKEN")
+	}
+
+	params := url.Values{}
+	for flag, param := range map[string]string{"namespace": "namespace", "path-prefix": "path_prefix", "user": "user", "operation": "operation"} {
+		if v, _ := cmd.Flags().GetString(flag); v != "" {
+			params.Set(param, v)
+		}
+	}
+	if limit, err := cmd.Flags().GetInt("limit"); err == nil {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	tlsConfig, err := createTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TLS configuration. Check your certificate files and environment variables (PULSAAR_CLIENT_CERT_FILE, PULSAAR_CLIENT_KEY_FILE, PULSAAR_CA_FILE). Error: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &auditQuery{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		params:  params,
+		client:  &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (q *auditQuery) fetch(ctx context.Context, after uint64) (*auditQueryResponse, error) {
+	params := url.Values{}
+	for k, v := range q.params {
+		params[k] = v
+	}
+	if after > 0 {
+		params.Set("after", strconv.FormatUint(after, 10))
+	}
+	body, err := q.open(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+
+	var out auditQueryResponse
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid response from aggregator: %v", err)
+	}
+	return &out, nil
+}
+
+// open sends the request and returns the body of a successful response.
+func (q *auditQuery) open(ctx context.Context, params url.Values) (io.ReadCloser, error) {
+	path := q.path
+	if path == "" {
+		path = "/api/v1/audit"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, q.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if q.token != "" {
+		req.Header.Set("Authorization", "Bearer "+q.token)
+	}
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach aggregator at %s. Error: %w", q.baseURL, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp.Body, nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	msg := strings.TrimSpace(string(body))
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("aggregator rejected the query token (%s). Pass --token or set PULSAAR_QUERY_TOKEN", msg)
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("query token does not grant access to namespace %q (%s)", q.params.Get("namespace"), msg)
+	default:
+		return nil, fmt.Errorf("aggregator returned %s: %s", resp.Status, msg)
+	}
+}
+
+// printAuditEvents writes events oldest first, one per line.
+func printAuditEvents(w io.Writer, events []auditEvent) {
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		target := e.Namespace
+		if e.Pod != "" {
+			target += "/" + e.Pod
+		}
+		repeats := ""
+		if e.Count > 1 {
+			repeats = fmt.Sprintf(" (x%d)", e.Count)
+		}
+		_, _ = fmt.Fprintf(w, "%s %s %s %s %s %s%s\n", e.Timestamp, orDash(e.User), orDash(target), e.Operation, e.Path, orDash(e.Result), repeats)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func newAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Review file access history recorded by the audit aggregator",
+	}
+	auditCmd.PersistentFlags().String("aggregator", "", "Aggregator base URL (default $PULSAAR_AGGREGATOR_URL)")
+	auditCmd.PersistentFlags().String("token", "", "Query token (default $PULSAAR_QUERY_TOKEN)")
+	auditCmd.PersistentFlags().String("namespace", "", "Only events from this namespace")
+	auditCmd.PersistentFlags().String("path-prefix", "", "Only events for paths under this prefix")
+	auditCmd.PersistentFlags().String("user", "", "Only events by this user")
+	auditCmd.PersistentFlags().String("operation", "", "Only this operation (e.g. ReadFile)")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List recent audit events, oldest first",
+		RunE:  runAuditList,
+	}
+	listCmd.Flags().Int("limit", 100, "Maximum number of events (at most 1000)")
+
+	tailCmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Print recent audit events and follow new ones",
+		RunE:  runAuditTail,
+	}
+	tailCmd.Flags().Int("limit", 10, "Number of recent events to print before following")
+	tailCmd.Flags().Duration("interval", 2*time.Second, "Polling interval")
+
+	searchCmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Find audit events by free text, oldest first",
+		Long: `Find audit events held by the aggregator whose fields contain every word
+of the query, ignoring case. Quote a phrase to match it whole, and name a
+field to match only that field: path, user, agent, namespace, pod,
+container, node, operation, result, error_code, client or request_id.`,
+		Example: `  pulsaar audit search shadow
+  pulsaar audit search 'path:/etc user:"jane doe"'`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runAuditSearch,
+	}
+	searchCmd.Flags().Int("limit", 100, "Maximum number of events (at most 1000)")
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Download a period of audit events as CSV or Parquet",
+		Long: `Download the audit events of a period from the aggregator's audit log,
+including rotated segments, oldest first. The export streams, so long
+periods do not need to fit in memory.`,
+		Example: `  pulsaar audit export --from 2024-01-01T00:00:00Z --to 2024-02-01T00:00:00Z -o january.csv
+  pulsaar audit export --format parquet --namespace payments -o payments.parquet`,
+		Args: cobra.NoArgs,
+		RunE: runAuditExport,
+	}
+	exportCmd.Flags().String("format", "csv", "Output format: csv or parquet")
+	exportCmd.Flags().String("from", "", "Only events at or after this RFC 3339 time")
+	exportCmd.Flags().String("to", "", "Only events before this RFC 3339 time")
+	exportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+
+	auditCmd.AddCommand(listCmd, tailCmd, searchCmd, exportCmd)
+	return auditCmd
+}
+
+func runAuditList(cmd *cobra.Command, args []string) error {
+	q, err := newAuditQuery(cmd)
+	if err != nil {
+		return err
+	}
+	resp, err := q.fetch(cmd.Context(), 0)
+	if err != nil {
+		return err
+	}
+	printAuditEvents(cmd.OutOrStdout(), resp.Events)
+	return nil
+}
+
+func runAuditSearch(cmd *cobra.Command, args []string) error {
+	q, err := newAuditQuery(cmd)
+	if err != nil {
+		return err
+	}
+	q.path = "/audit/search"
+	q.params.Set("q", strings.Join(args, " "))
+	resp, err := q.fetch(cmd.Context(), 0)
+	if err != nil {
+		return err
+	}
+	printAuditEvents(cmd.OutOrStdout(), resp.Events)
+	return nil
+}
+
+func runAuditExport(cmd *cobra.Command, args []string) error {
+	q, err := newAuditQuery(cmd)
+	if err != nil {
+		return err
+	}
+	q.path = "/audit/export"
+	for _, flag := range []string{"format", "from", "to"} {
+		if v, _ := cmd.Flags().GetString(flag); v != "" {
+			q.params.Set(flag, v)
+		}
+	}
+	// An export may take longer than a query; only an interrupt ends it.
+	q.client.Timeout = 0
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	body, err := q.open(ctx, q.params)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = body.Close() }()
+
+	out := cmd.OutOrStdout()
+	if path, _ := cmd.Flags().GetString("output"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		return fmt.Errorf("export interrupted: %v", err)
+	}
+	return nil
+}
+
+func runAuditTail(cmd *cobra.Command, args []string) error {
+	q, err := newAuditQuery(cmd)
+	if err != nil {
+		return err
+	}
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	return tailAudit(ctx, q, interval, cmd.OutOrStdout())
+}
+
+// tailAudit prints the most recent events, then polls for events newer than
+// the aggregator's cursor until ctx is cancelled.
+func tailAudit(ctx context.Context, q *auditQuery, interval time.Duration, w io.Writer) error {
+	resp, err := q.fetch(ctx, 0)
+	if err != nil {
+		return err
+	}
+	printAuditEvents(w, resp.Events)
+	cursor := resp.Cursor
+	q.params.Set("limit", "1000")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		resp, err := q.fetch(ctx, cursor)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		printAuditEvents(w, resp.Events)
+		// A restarted aggregator starts counting again from zero.
+		if resp.Cursor < cursor {
+			cursor = 0
+			continue
+		}
+		cursor = resp.Cursor
+	}
+}
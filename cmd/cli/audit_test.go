@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuditList(t *testing.T) {
+	var gotQuery, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery, gotAuth = r.URL.RawQuery, r.Header.Get("Authorization")
+		_ = json.NewEncoder(w).Encode(auditQueryResponse{Cursor: 2, Events: []auditEvent{
+			{Timestamp: "t2", Operation: "Stat", Path: "/etc/hosts", User: "bob", Namespace: "shop", Pod: "web-0", Result: "allowed", Count: 3},
+			{Timestamp: "t1", Operation: "ReadFile", Path: "/etc/passwd", Namespace: "shop", Result: "denied"},
+		}})
+	}))
+	defer srv.Close()
+
+	cmd := newAuditCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"list", "--aggregator", srv.URL + "/", "--token", "secret", "--namespace", "shop", "--path-prefix", "/etc"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"namespace=shop", "path_prefix=%2Fetc", "limit=100"} {
+		if !strings.Contains(gotQuery, want) {
+			t.Errorf("expected %q in query %q", want, gotQuery)
+		}
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("expected bearer token, got %q", gotAuth)
+	}
+	want := "t1 - shop ReadFile /etc/passwd denied\nt2 bob shop/web-0 Stat /etc/hosts allowed (x3)\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestAuditSearch(t *testing.T) {
+	var gotPath, gotQ string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQ = r.URL.Path, r.URL.Query().Get("q")
+		_ = json.NewEncoder(w).Encode(auditQueryResponse{Events: []auditEvent{
+			{Timestamp: "t1", Operation: "ReadFile", Path: "/etc/shadow", User: "alice", Namespace: "shop", Result: "denied"},
+		}})
+	}))
+	defer srv.Close()
+
+	cmd := newAuditCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"search", "--aggregator", srv.URL, "shadow", `user:"alice"`})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/audit/search" || gotQ != `shadow user:"alice"` {
+		t.Errorf("unexpected request %s?q=%s", gotPath, gotQ)
+	}
+	if want := "t1 alice shop ReadFile /etc/shadow denied\n"; out.String() != want {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestAuditExport(t *testing.T) {
+	var gotPath, gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		_, _ = w.Write([]byte("timestamp,operation\n"))
+	}))
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "audit.csv")
+	cmd := newAuditCmd()
+	cmd.SetArgs([]string{"export", "--aggregator", srv.URL, "--from", "2024-01-01T00:00:00Z", "--namespace", "shop", "-o", output})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/audit/export" || gotQuery != "format=csv&from=2024-01-01T00%3A00%3A00Z&namespace=shop" {
+		t.Errorf("unexpected request %s?%s", gotPath, gotQuery)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "timestamp,operation\n" {
+		t.Errorf("unexpected export %q", data)
+	}
+}
+
+func TestAuditListErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Valid query token required", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	cmd := newAuditCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"list", "--aggregator", srv.URL})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "query token") {
+		t.Errorf("expected token error, got %v", err)
+	}
+
+	t.Setenv("PULSAAR_AGGREGATOR_URL", "")
+	cmd = newAuditCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"list"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "PULSAAR_AGGREGATOR_URL") {
+		t.Errorf("expected missing aggregator error, got %v", err)
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTailAuditFollowsCursor(t *testing.T) {
+	var mu sync.Mutex
+	var afters []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		afters = append(afters, r.URL.Query().Get("after"))
+		n := len(afters)
+		mu.Unlock()
+		resp := auditQueryResponse{Cursor: 5}
+		switch n {
+		case 1:
+			resp.Events = []auditEvent{{Timestamp: "t5", Operation: "Stat", Path: "/old"}}
+		case 2:
+			resp.Cursor = 6
+			resp.Events = []auditEvent{{Timestamp: "t6", Operation: "ReadFile", Path: "/new"}}
+		default:
+			resp.Cursor = 6
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	q := &auditQuery{baseURL: srv.URL, params: map[string][]string{"limit": {"10"}}, client: srv.Client()}
+	ctx, cancel := context.WithCancel(context.Background())
+	var out lockedBuffer
+	done := make(chan error, 1)
+	go func() { done <- tailAudit(ctx, q, 10*time.Millisecond, &out) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "/new") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if got := out.String(); got != "t5 - - Stat /old -\nt6 - - ReadFile /new -\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if afters[0] != "" || afters[1] != "5" {
+		t.Errorf("expected first poll without cursor then after=5, got %v", afters)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestGenerateServiceCerts(t *testing.T) {
+	now := time.Now()
+	names := serviceDNSNames("pulsaar-webhook", "ops")
+	certs, err := generateServiceCerts(names, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tls.X509KeyPair(certs.Cert, certs.Key); err != nil {
+		t.Fatalf("certificate and key do not match: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(certs.CA) {
+		t.Fatal("CA does not parse")
+	}
+	block, _ := pem.Decode(certs.Cert)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{DNSName: "pulsaar-webhook.ops.svc", Roots: pool}); err != nil {
+		t.Errorf("serving certificate does not verify against the CA: %v", err)
+	}
+
+	if !reusableCerts(certs, names, now) {
+		t.Error("fresh certificates should be reusable")
+	}
+	if reusableCerts(certs, names, now.Add(certValidity-certRenewBefore+time.Hour)) {
+		t.Error("certificates close to expiry should be replaced")
+	}
+	if reusableCerts(certs, serviceDNSNames("pulsaar-webhook", "other"), now) {
+		t.Error("certificates for another namespace should be replaced")
+	}
+	if reusableCerts(&serviceCerts{Cert: certs.Cert}, names, now) {
+		t.Error("certificates without a CA or key should be replaced")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// maxClipboardBytes keeps accidental copies of large files off the clipboard.
+const maxClipboardBytes = 256 * 1024
+
+// clipboardCommands lists the tools that can set the clipboard on goos, in
+// order of preference.
+func clipboardCommands(goos string) [][]string {
+	switch goos {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	default:
+		return [][]string{
+			{"wl-copy"},
+			{"xclip", "-selection", "clipboard"},
+			{"xsel", "--clipboard", "--input"},
+			// WSL
+			{"clip.exe"},
+		}
+	}
+}
+
+// copyToClipboard places data on the system clipboard using the first
+// available clipboard tool. Tests replace it.
+var copyToClipboard = func(data []byte) error {
+	commands := clipboardCommands(runtime.GOOS)
+	for _, c := range commands {
+		if _, err := exec.LookPath(c[0]); err != nil {
+			continue
+		}
+		copyCmd := exec.Command(c[0], c[1:]...)
+		copyCmd.Stdin = bytes.NewReader(data)
+		if out, err := copyCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s failed: %v %s", c[0], err, bytes.TrimSpace(out))
+		}
+		return nil
+	}
+	var names []string
+	for _, c := range commands {
+		names = append(names, c[0])
+	}
+	return fmt.Errorf("no clipboard tool found; install one of %v", names)
+}
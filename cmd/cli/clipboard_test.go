@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadClipboard(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{
+		"etc/app.conf": {Data: []byte("port = 8080\n")},
+		"var/big.log":  {Data: bytes.Repeat([]byte("x"), maxClipboardBytes+1)},
+		"bin/tool":     {Data: []byte{0, 1, 2, 3}},
+	})
+	var copied []byte
+	original := copyToClipboard
+	t.Cleanup(func() { copyToClipboard = original })
+	copyToClipboard = func(data []byte) error {
+		copied = append([]byte(nil), data...)
+		return nil
+	}
+
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/etc/app.conf"})
+	cmd.Flags().Bool("clipboard", true, "")
+	var errOut bytes.Buffer
+	cmd.SetErr(&errOut)
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if string(copied) != "port = 8080\n" || !strings.Contains(errOut.String(), "Copied 12 bytes") {
+		t.Errorf("unexpected clipboard copy %q, output %q", copied, errOut.String())
+	}
+
+	for p, want := range map[string]string{"/var/big.log": "clipboard limit", "/bin/tool": "appears to be binary"} {
+		cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": p})
+		cmd.Flags().Bool("clipboard", true, "")
+		if err := runRead(cmd, nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", p, want, err)
+		}
+	}
+}
+
+func TestClipboardCommands(t *testing.T) {
+	if got := clipboardCommands("darwin"); len(got) != 1 || got[0][0] != "pbcopy" {
+		t.Errorf("unexpected darwin commands %v", got)
+	}
+	if got := clipboardCommands("linux"); got[0][0] != "wl-copy" || got[1][0] != "xclip" {
+		t.Errorf("unexpected linux commands %v", got)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func newCompareCmd() *cobra.Command {
+	compareCmd := &cobra.Command{
+		Use:   "compare",
+		Short: "List a directory in several pods side by side",
+		Long: `List the same directory in several pods at once, one column per pod, to
+compare replicas. Each pod gets its own connection and the listings are
+fetched concurrently. Rows marked with * differ in type, size or mode
+between pods, or are missing from some; modification times are shown but
+not compared, as they usually differ between replicas.`,
+		Example: `  pulsaar compare --pods web-0,web-1 --path /app/config
+  pulsaar compare -l app=web --path /etc/nginx --diff-only`,
+		RunE: runCompare,
+	}
+	compareCmd.Flags().StringSlice("pods", nil, "Pods to compare, e.g. web-0,web-1")
+	compareCmd.Flags().StringP("selector", "l", "", "Compare the running pods matching this label selector")
+	compareCmd.Flags().String("namespace", "default", "Namespace")
+	compareCmd.Flags().String("path", "/", "Directory to compare")
+	compareCmd.Flags().Bool("diff-only", false, "Only print entries that differ between pods")
+	compareCmd.MarkFlagsMutuallyExclusive("pods", "selector")
+	compareCmd.MarkFlagsOneRequired("pods", "selector")
+	return compareCmd
+}
+
+type podListing struct {
+	pod     string
+	entries map[string]*api.FileInfo
+	err     error
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	pods, _ := cmd.Flags().GetStringSlice("pods")
+	selector, _ := cmd.Flags().GetString("selector")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	path, _ := cmd.Flags().GetString("path")
+	diffOnly, _ := cmd.Flags().GetBool("diff-only")
+
+	ctx := context.Background()
+	if selector != "" {
+		var err error
+		if pods, err = listPods(ctx, namespace, selector); err != nil {
+			return err
+		}
+		slices.Sort(pods)
+	}
+	if len(pods) < 2 {
+		return fmt.Errorf("compare needs at least two pods, got %d", len(pods))
+	}
+
+	listings := make([]podListing, len(pods))
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			listings[i] = listPod(ctx, cmd, namespace, pod, path)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, l := range listings {
+		if l.err != nil {
+			failed++
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", l.pod, l.err)
+		}
+	}
+	if failed == len(listings) {
+		return fmt.Errorf("failed to list '%s' in every pod", path)
+	}
+	printComparison(cmd.OutOrStdout(), listings, diffOnly)
+	if failed > 0 {
+		return fmt.Errorf("listing failed on %d of %d pods", failed, len(pods))
+	}
+	return nil
+}
+
+func listPod(ctx context.Context, cmd *cobra.Command, namespace, pod, path string) podListing {
+	c, err := connectToAgent(cmd, pod, namespace)
+	if err != nil {
+		return podListing{pod: pod, err: err}
+	}
+	defer func() { _ = c.Close() }()
+	entries, err := c.List(ctx, path)
+	if err != nil {
+		return podListing{pod: pod, err: fmt.Errorf("failed to list '%s': %v", path, err)}
+	}
+	return podListing{pod: pod, entries: listingByName(entries)}
+}
+
+// printComparison prints one row per entry name and one column per pod
+// that was listed, marking rows that differ with *.
+func printComparison(w io.Writer, listings []podListing, diffOnly bool) {
+	listings = slices.DeleteFunc(slices.Clone(listings), func(l podListing) bool { return l.err != nil })
+	seen := map[string]bool{}
+	var names []string
+	for _, l := range listings {
+		for name := range l.entries {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+
+	header := append([]string{"NAME"}, make([]string, len(listings))...)
+	for i, l := range listings {
+		header[i+1] = l.pod
+	}
+	rows := [][]string{header}
+	var marks []bool
+	for _, name := range names {
+		row := []string{name}
+		differs := false
+		var first *api.FileInfo
+		for i, l := range listings {
+			e := l.entries[name]
+			row = append(row, compareCell(e))
+			if i == 0 {
+				first = e
+			} else if !sameEntry(first, e) {
+				differs = true
+			}
+		}
+		if diffOnly && !differs {
+			continue
+		}
+		rows = append(rows, row)
+		marks = append(marks, differs)
+	}
+
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	for r, row := range rows {
+		mark := " "
+		if r > 0 && marks[r-1] {
+			mark = "*"
+		}
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		_, _ = fmt.Fprintln(w, strings.TrimRight(mark+" "+strings.Join(cells, "  "), " "))
+	}
+}
+
+// compareCell describes an entry in one pod's column.
+func compareCell(e *api.FileInfo) string {
+	switch {
+	case e == nil:
+		return "-"
+	case e.IsDir:
+		return "dir " + e.Mode
+	}
+	kind := fileKind(e)
+	size := strconv.FormatInt(e.SizeBytes, 10)
+	if kind != "file" {
+		size = kind
+	}
+	return size + " " + e.Mode + " " + e.Mtime.AsTime().Format("2006-01-02 15:04")
+}
+
+// sameEntry reports whether two pods hold the same kind of entry with the
+// same size and mode. Directory sizes depend on the file system, so only
+// their modes are compared.
+func sameEntry(a, b *api.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if fileKind(a) != fileKind(b) || a.Mode != b.Mode {
+		return false
+	}
+	return a.IsDir || a.SizeBytes == b.SizeBytes
+}
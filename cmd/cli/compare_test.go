@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCompareAcrossPods(t *testing.T) {
+	mtime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	withFakePods(t, map[string]fstest.MapFS{
+		"web-0": {
+			"app/config/app.yaml":  {Data: []byte("port: 8080\n"), ModTime: mtime},
+			"app/config/feature":   {Data: []byte("on\n"), ModTime: mtime},
+			"app/config/only-here": {Data: []byte("x"), ModTime: mtime},
+		},
+		"web-1": {
+			"app/config/app.yaml": {Data: []byte("port: 8080\n"), ModTime: mtime.Add(time.Hour)},
+			"app/config/feature":  {Data: []byte("off\n"), ModTime: mtime},
+		},
+	}, "web-1", "web-0")
+
+	cmd := newCompareCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"-l", "app=web", "--path", "/app/config"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], "web-0") || strings.Index(lines[0], "web-0") > strings.Index(lines[0], "web-1") {
+		t.Fatalf("expected a header with web-0 then web-1 and three rows, got %q", out.String())
+	}
+	for _, want := range []string{"  app.yaml ", "* feature ", "* only-here "} {
+		found := false
+		for _, line := range lines[1:] {
+			found = found || strings.HasPrefix(line, want)
+		}
+		if !found {
+			t.Errorf("expected a row starting %q, got %q", want, out.String())
+		}
+	}
+
+	out.Reset()
+	cmd = newCompareCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--pods", "web-0,web-1", "--path", "/app/config", "--diff-only"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "app.yaml") || !strings.Contains(out.String(), "feature") {
+		t.Errorf("expected only differing rows, got %q", out.String())
+	}
+}
+
+func TestCompareReportsFailedPods(t *testing.T) {
+	withFakePods(t, map[string]fstest.MapFS{
+		"web-0": {"app/config/app.yaml": {Data: []byte("port: 8080\n")}},
+	})
+
+	cmd := newCompareCmd()
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"--pods", "web-0,web-9", "--path", "/app/config"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 pods") {
+		t.Errorf("expected a partial failure, got %v", err)
+	}
+	if !strings.Contains(errOut.String(), "web-9: ") || !strings.Contains(out.String(), "app.yaml") {
+		t.Errorf("expected the reachable pod to be listed and the other reported, got %q, %q", out.String(), errOut.String())
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+// cliConfig is the optional CLI config file. Flags and PULSAAR_*
+// environment variables take precedence over it.
+type cliConfig struct {
+	// AgentImage is injected into Linux pods.
+	AgentImage string `json:"agentImage,omitempty"`
+	// WindowsAgentImage is injected into Windows pods.
+	WindowsAgentImage string `json:"windowsAgentImage,omitempty"`
+}
+
+// configPath is PULSAAR_CONFIG, or config.yaml under the user config
+// directory, e.g. ~/.config/pulsaar/config.yaml.
+func configPath() string {
+	if p := os.Getenv("PULSAAR_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pulsaar", "config.yaml")
+}
+
+// loadConfig reads the config file. A missing file is an empty config
+// unless PULSAAR_CONFIG names it.
+func loadConfig() (*cliConfig, error) {
+	cfg := &cliConfig{}
+	p := configPath()
+	if p == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) && os.Getenv("PULSAAR_CONFIG") == "" {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", p, err)
+	}
+	for _, image := range []string{cfg.AgentImage, cfg.WindowsAgentImage} {
+		if image == "" {
+			continue
+		}
+		if err := client.ValidateImage(image); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", p, err)
+		}
+	}
+	return cfg, nil
+}
+
+// agentImages returns --agent-image and, for pods without an environment
+// override, the images from the config file.
+func agentImages(cmd *cobra.Command) (string, client.AgentImages, error) {
+	image, _ := cmd.Flags().GetString("agent-image")
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", client.AgentImages{}, err
+	}
+	var defaults client.AgentImages
+	if os.Getenv("PULSAAR_AGENT_IMAGE") == "" {
+		defaults.Linux = cfg.AgentImage
+	}
+	if os.Getenv("PULSAAR_AGENT_WINDOWS_IMAGE") == "" {
+		defaults.Windows = cfg.WindowsAgentImage
+	}
+	return image, defaults, nil
+}
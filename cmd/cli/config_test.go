@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/cobra"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func writeConfig(t *testing.T, content string) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PULSAAR_CONFIG", p)
+}
+
+func TestLoadConfig(t *testing.T) {
+	writeConfig(t, "agentImage: registry.internal/pulsaar/agent@"+testDigest+"\nwindowsAgentImage: registry.internal/pulsaar/agent:win\n")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AgentImage != "registry.internal/pulsaar/agent@"+testDigest || cfg.WindowsAgentImage != "registry.internal/pulsaar/agent:win" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	for content, want := range map[string]string{
+		"agentImages: x\n":                      "unknown field",
+		"agentImage: pulsaar/agent@sha256:12\n": "invalid digest",
+	} {
+		writeConfig(t, content)
+		if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected %q, got %v", content, want, err)
+		}
+	}
+
+	t.Setenv("PULSAAR_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected an explicit PULSAAR_CONFIG to be required")
+	}
+}
+
+func TestAgentImages(t *testing.T) {
+	writeConfig(t, "agentImage: registry.internal/agent:v1\nwindowsAgentImage: registry.internal/agent:win\n")
+	t.Setenv("PULSAAR_AGENT_IMAGE", "")
+	t.Setenv("PULSAAR_AGENT_WINDOWS_IMAGE", "env/agent:win")
+	cmd := &cobra.Command{}
+	cmd.Flags().String("agent-image", "", "")
+
+	image, defaults, err := agentImages(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image != "" || defaults.Linux != "registry.internal/agent:v1" || defaults.Windows != "" {
+		t.Errorf("expected the config for Linux and the environment for Windows, got %q %+v", image, defaults)
+	}
+
+	_ = cmd.Flags().Set("agent-image", "registry.internal/agent@"+testDigest)
+	if image, _, _ := agentImages(cmd); image != "registry.internal/agent@"+testDigest {
+		t.Errorf("expected --agent-image, got %q", image)
+	}
+}
+
+func TestRunWithAgentImage(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("x")}})
+	writeConfig(t, "agentImage: registry.internal/agent:v1\n")
+	t.Setenv("PULSAAR_AGENT_IMAGE", "")
+
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml"})
+	cmd.Flags().String("agent-image", "registry.internal/agent:v2", "")
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.AgentImage != "registry.internal/agent:v2" || lastOptions.DefaultAgentImages.Linux != "registry.internal/agent:v1" {
+		t.Errorf("unexpected image options %+v", lastOptions)
+	}
+}
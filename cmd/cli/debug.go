@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+var debugClientset = policyClientset
+
+const (
+	// maxDebugLogs is how many of the most recently modified log files are
+	// shown.
+	maxDebugLogs = 5
+	// maxDebugListing bounds the core dump and config file listings.
+	maxDebugListing = 50
+	// debugTailBytes is read from the end of each log for its last lines.
+	debugTailBytes = 16 * 1024
+)
+
+// Where debug looks for artifacts, besides the container's volume mounts
+// and working directory.
+var (
+	debugLogDirs    = []string{"/var/log", "/tmp", "/logs", "/app/logs"}
+	debugCoreDirs   = []string{"/", "/tmp", "/var/crash", "/var/lib/systemd/coredump"}
+	debugConfigDirs = []string{"/etc/config", "/config", "/app/config"}
+)
+
+func newDebugCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Report why a container is crashing, with its logs, core dumps and config files",
+		Long: `Summarise a crashing container's restarts and last exit, print the output of
+its previous run, then inject the agent targeting the container and report
+the last lines of its log files, any core dumps and its config files.
+
+Without --container the first container in CrashLoopBackOff, or that last
+exited with an error, is chosen. The agent reads the container's filesystem
+through its main process, so it can only start while the container is
+running between restarts; the summary and previous output are printed
+either way.`,
+		Example: `  pulsaar debug --pod web-0
+  pulsaar debug --pod web-0 --container app --lines 50`,
+		Args: cobra.NoArgs,
+		RunE: runDebug,
+	}
+	cmd.Flags().String("pod", "", "Pod name")
+	cmd.Flags().String("namespace", "default", "Namespace")
+	cmd.Flags().StringP("container", "c", "", "Container to debug (default: the crashed one)")
+	cmd.Flags().Int64("lines", 20, "Lines to show from the previous run's output and from each log file")
+	_ = cmd.MarkFlagRequired("pod")
+	return cmd
+}
+
+func runDebug(cmd *cobra.Command, args []string) error {
+	podName, _ := cmd.Flags().GetString("pod")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	container, _ := cmd.Flags().GetString("container")
+	lines, _ := cmd.Flags().GetInt64("lines")
+	if lines < 1 {
+		return &usageError{fmt.Errorf("--lines must be at least 1")}
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := cmd.OutOrStdout()
+
+	clientset, err := debugClientset()
+	if err != nil {
+		return err
+	}
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pod %s/%s. Error: %w", namespace, podName, err)
+	}
+	crashed, err := crashedContainer(pod, container)
+	if err != nil {
+		return err
+	}
+	printCrashSummary(out, pod, crashed)
+
+	_, _ = fmt.Fprintf(out, "\n== Output of the previous run (last %d lines) ==\n", lines)
+	logs, err := clientset.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: crashed.Name,
+		Previous:  true,
+		TailLines: &lines,
+	}).DoRaw(ctx)
+	switch {
+	case err != nil:
+		_, _ = fmt.Fprintf(out, "(unavailable: %v)\n", err)
+	case len(logs) == 0:
+		_, _ = fmt.Fprintln(out, "(empty)")
+	default:
+		writeSection(out, logs)
+	}
+
+	opts, err := agentOptions(cmd, podName, namespace)
+	if err != nil {
+		return err
+	}
+	if !opts.SkipInjection {
+		opts.TargetContainer = crashed.Name
+	}
+	c, err := connect(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to start the agent in container %s, which may not be running between restarts; retry while it runs. Error: %w", crashed.Name, err)
+	}
+	defer func() { _ = c.Close() }()
+	if err := checkAgentVersion(ctx, cmd, c, describeTarget(cmd, namespace, podName)); err != nil {
+		return err
+	}
+
+	spec := containerSpec(pod, crashed.Name)
+	r := &debugReport{ctx: ctx, c: c, out: out}
+	r.printLogs(debugDirs(spec, debugLogDirs, writableVolume(pod), true), lines)
+	r.printCoreDumps(debugDirs(spec, debugCoreDirs, nil, true))
+	r.printConfig(debugDirs(spec, debugConfigDirs, configVolume(pod), false))
+	if len(r.denied) > 0 {
+		_, _ = fmt.Fprintf(out, "\nSkipped locations outside the pod's allowed roots: %s\n", strings.Join(r.denied, ", "))
+	}
+	return nil
+}
+
+// crashedContainer returns the status of the named container or, if name
+// is empty, of the first one crash looping or last terminated with an
+// error.
+func crashedContainer(pod *corev1.Pod, name string) (corev1.ContainerStatus, error) {
+	if name != "" {
+		for _, s := range pod.Status.ContainerStatuses {
+			if s.Name == name {
+				return s, nil
+			}
+		}
+		if containerSpec(pod, name) == nil {
+			return corev1.ContainerStatus{}, fmt.Errorf("container %q not found in pod %s/%s", name, pod.Namespace, pod.Name)
+		}
+		return corev1.ContainerStatus{Name: name}, nil
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.State.Waiting != nil && s.State.Waiting.Reason == "CrashLoopBackOff" {
+			return s, nil
+		}
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		if t := lastTermination(s); t != nil && t.ExitCode != 0 {
+			return s, nil
+		}
+	}
+	return corev1.ContainerStatus{}, fmt.Errorf("pod %s/%s has no crashed containers; pass --container to inspect one anyway", pod.Namespace, pod.Name)
+}
+
+// lastTermination is how the container last exited, if it has.
+func lastTermination(s corev1.ContainerStatus) *corev1.ContainerStateTerminated {
+	if s.State.Terminated != nil {
+		return s.State.Terminated
+	}
+	return s.LastTerminationState.Terminated
+}
+
+func containerSpec(pod *corev1.Pod, name string) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+func printCrashSummary(w io.Writer, pod *corev1.Pod, s corev1.ContainerStatus) {
+	_, _ = fmt.Fprintf(w, "Pod %s/%s, container %s\n", pod.Namespace, pod.Name, s.Name)
+	switch {
+	case s.State.Waiting != nil:
+		state := "waiting: " + s.State.Waiting.Reason
+		if s.State.Waiting.Message != "" {
+			state += " (" + s.State.Waiting.Message + ")"
+		}
+		_, _ = fmt.Fprintf(w, "  State:     %s\n", state)
+	case s.State.Running != nil:
+		_, _ = fmt.Fprintf(w, "  State:     running since %s\n", s.State.Running.StartedAt.Format(time.RFC3339))
+	case s.State.Terminated != nil:
+		_, _ = fmt.Fprintln(w, "  State:     terminated")
+	}
+	_, _ = fmt.Fprintf(w, "  Restarts:  %d\n", s.RestartCount)
+	t := lastTermination(s)
+	if t == nil {
+		return
+	}
+	exit := fmt.Sprintf("code %d", t.ExitCode)
+	if t.Reason != "" {
+		exit += " (" + t.Reason + ")"
+	}
+	if t.Signal != 0 {
+		exit += fmt.Sprintf(", signal %d", t.Signal)
+	}
+	if !t.FinishedAt.IsZero() {
+		exit += " at " + t.FinishedAt.Format(time.RFC3339)
+		if !t.StartedAt.IsZero() {
+			exit += fmt.Sprintf(" after %s", t.FinishedAt.Sub(t.StartedAt.Time))
+		}
+	}
+	_, _ = fmt.Fprintf(w, "  Last exit: %s\n", exit)
+	if t.Reason == "OOMKilled" {
+		if spec := containerSpec(pod, s.Name); spec != nil {
+			if limit, ok := spec.Resources.Limits[corev1.ResourceMemory]; ok {
+				_, _ = fmt.Fprintf(w, "  Memory:    limit %s\n", limit.String())
+			}
+		}
+	}
+	if msg := strings.TrimSpace(t.Message); msg != "" {
+		_, _ = fmt.Fprintf(w, "  Message:   %s\n", msg)
+	}
+}
+
+// debugDirs returns defaults plus the container's working directory (if
+// withWorkingDir) and the mount paths of volumes selected by volumes, in
+// order and without duplicates.
+func debugDirs(spec *corev1.Container, defaults []string, volumes func(string) bool, withWorkingDir bool) []string {
+	dirs := slices.Clone(defaults)
+	if spec != nil {
+		if withWorkingDir && spec.WorkingDir != "" {
+			dirs = append(dirs, spec.WorkingDir)
+		}
+		for _, m := range spec.VolumeMounts {
+			if volumes != nil && volumes(m.Name) {
+				dirs = append(dirs, m.MountPath)
+			}
+		}
+	}
+	var unique []string
+	for _, d := range dirs {
+		if d = path.Clean(d); !slices.Contains(unique, d) {
+			unique = append(unique, d)
+		}
+	}
+	return unique
+}
+
+// writableVolume selects volumes an application may write logs to.
+func writableVolume(pod *corev1.Pod) func(string) bool {
+	return volumeMatcher(pod, func(v corev1.Volume) bool {
+		return v.EmptyDir != nil || v.HostPath != nil || v.PersistentVolumeClaim != nil
+	})
+}
+
+// configVolume selects volumes that hold configuration.
+func configVolume(pod *corev1.Pod) func(string) bool {
+	return volumeMatcher(pod, func(v corev1.Volume) bool {
+		return v.ConfigMap != nil || v.Secret != nil || v.Projected != nil
+	})
+}
+
+func volumeMatcher(pod *corev1.Pod, match func(corev1.Volume) bool) func(string) bool {
+	return func(name string) bool {
+		for _, v := range pod.Spec.Volumes {
+			if v.Name == name {
+				return match(v)
+			}
+		}
+		return false
+	}
+}
+
+// debugReport prints artifacts found through the agent.
+type debugReport struct {
+	ctx    context.Context
+	c      *client.Client
+	out    io.Writer
+	denied []string
+}
+
+type foundFile struct {
+	path string
+	info *api.FileInfo
+}
+
+// find returns files under dirs, up to depth levels of subdirectories
+// deep, whose names match. Missing directories are skipped, and ones
+// outside the allowed roots are noted.
+func (r *debugReport) find(dirs []string, depth int, match func(name string) bool) []foundFile {
+	var found []foundFile
+	seen := map[string]bool{}
+	var walk func(dir string, depth int, top bool)
+	walk = func(dir string, depth int, top bool) {
+		entries, err := r.c.List(r.ctx, dir)
+		if err != nil {
+			if top && status.Code(err) == codes.PermissionDenied && !slices.Contains(r.denied, dir) {
+				r.denied = append(r.denied, dir)
+			}
+			return
+		}
+		for _, e := range entries {
+			// Skip ConfigMap and Secret volume internals such as ..data.
+			if strings.HasPrefix(e.Name, "..") {
+				continue
+			}
+			p := path.Join(dir, e.Name)
+			if e.IsDir {
+				if depth > 0 {
+					walk(p, depth-1, false)
+				}
+				continue
+			}
+			// Reading a pipe or device would hang or never end.
+			if e.IsSpecial() {
+				continue
+			}
+			if match(e.Name) && !seen[p] {
+				seen[p] = true
+				found = append(found, foundFile{path: p, info: e})
+			}
+		}
+	}
+	for _, dir := range dirs {
+		walk(dir, depth, true)
+	}
+	return found
+}
+
+// isDebugLog matches uncompressed log and output files.
+func isDebugLog(name string) bool {
+	for _, ext := range []string{".gz", ".xz", ".zst", ".bz2"} {
+		if strings.HasSuffix(name, ext) {
+			return false
+		}
+	}
+	return strings.HasSuffix(name, ".log") || strings.Contains(name, ".log.") || strings.HasSuffix(name, ".out") || strings.HasSuffix(name, ".err")
+}
+
+// isCoreDump matches the default core file names: core, core.<pid> and
+// <name>.core.
+func isCoreDump(name string) bool {
+	return name == "core" || strings.HasPrefix(name, "core.") || strings.HasSuffix(name, ".core")
+}
+
+func (r *debugReport) printLogs(dirs []string, lines int64) {
+	_, _ = fmt.Fprintf(r.out, "\n== Log files ==\n")
+	logs := r.find(dirs, 2, isDebugLog)
+	if len(logs) == 0 {
+		_, _ = fmt.Fprintf(r.out, "(none found in %s)\n", strings.Join(dirs, ", "))
+		return
+	}
+	slices.SortFunc(logs, func(a, b foundFile) int {
+		return b.info.Mtime.AsTime().Compare(a.info.Mtime.AsTime())
+	})
+	if len(logs) > maxDebugLogs {
+		_, _ = fmt.Fprintf(r.out, "(showing the %d most recently modified of %d)\n", maxDebugLogs, len(logs))
+		logs = logs[:maxDebugLogs]
+	}
+	for _, f := range logs {
+		_, _ = fmt.Fprintf(r.out, "--> %s (%d bytes, modified %s)\n", f.path, f.info.SizeBytes, f.info.Mtime.AsTime().Format("2006-01-02 15:04:05"))
+		resp, err := r.c.Preview(r.ctx, f.path, 0, debugTailBytes)
+		switch {
+		case err != nil:
+			_, _ = fmt.Fprintf(r.out, "(unreadable: %v)\n", status.Convert(err).Message())
+		case isBinary(resp.Tail):
+			_, _ = fmt.Fprintln(r.out, "(binary content not shown)")
+		case len(resp.Tail) == 0:
+			_, _ = fmt.Fprintln(r.out, "(empty)")
+		default:
+			writeSection(r.out, lastLines(resp.Tail, lines))
+		}
+	}
+}
+
+// lastLines returns the last n lines of data.
+func lastLines(data []byte, n int64) []byte {
+	end := bytes.TrimRight(data, "\n")
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] == '\n' {
+			if n--; n == 0 {
+				return data[i+1:]
+			}
+		}
+	}
+	return data
+}
+
+func (r *debugReport) printCoreDumps(dirs []string) {
+	_, _ = fmt.Fprintf(r.out, "\n== Core dumps ==\n")
+	cores := r.find(dirs, 0, isCoreDump)
+	if len(cores) == 0 {
+		_, _ = fmt.Fprintf(r.out, "(none found in %s; the node's kernel.core_pattern decides where dumps go)\n", strings.Join(dirs, ", "))
+		return
+	}
+	r.printListing(cores)
+}
+
+func (r *debugReport) printConfig(dirs []string) {
+	_, _ = fmt.Fprintf(r.out, "\n== Config files ==\n")
+	files := r.find(dirs, 2, func(string) bool { return true })
+	if len(files) == 0 {
+		_, _ = fmt.Fprintf(r.out, "(none found in %s)\n", strings.Join(dirs, ", "))
+		return
+	}
+	r.printListing(files)
+}
+
+func (r *debugReport) printListing(files []foundFile) {
+	for i, f := range files {
+		if i == maxDebugListing {
+			_, _ = fmt.Fprintf(r.out, "... and %d more\n", len(files)-i)
+			return
+		}
+		_, _ = fmt.Fprintf(r.out, "%-50s %10d  %s\n", f.path, f.info.SizeBytes, f.info.Mtime.AsTime().Format("2006-01-02 15:04:05"))
+	}
+}
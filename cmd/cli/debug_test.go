@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func crashingPod() *corev1.Pod {
+	started := metav1.NewTime(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	finished := metav1.NewTime(started.Add(3 * time.Second))
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "shop"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "envoy"},
+				{
+					Name:       "app",
+					WorkingDir: "/srv",
+					Resources:  corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "settings", MountPath: "/etc/app"},
+						{Name: "scratch", MountPath: "/data"},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{Name: "settings", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}},
+				{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "envoy", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: started}}},
+				{
+					Name:         "app",
+					RestartCount: 7,
+					State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 5m0s"}},
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						ExitCode: 137, Reason: "OOMKilled", StartedAt: started, FinishedAt: finished,
+					}},
+				},
+			},
+		},
+	}
+}
+
+func withDebugClientset(t *testing.T, pod *corev1.Pod) {
+	t.Helper()
+	clientset := fake.NewClientset(pod)
+	original := debugClientset
+	t.Cleanup(func() { debugClientset = original })
+	debugClientset = func() (kubernetes.Interface, error) { return clientset, nil }
+}
+
+func runDebugCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := newDebugCmd()
+	// Declared on the root command in main.
+	cmd.Flags().Bool("no-inject", false, "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestDebugReport(t *testing.T) {
+	t.Setenv("PULSAAR_CONFIG", "")
+	withDebugClientset(t, crashingPod())
+	var appLog strings.Builder
+	for i := 1; i <= 30; i++ {
+		appLog.WriteString("line " + strings.Repeat("x", i%3) + string(rune('a'+i%26)) + "\n")
+	}
+	agent := withFakeAgent(t, fstest.MapFS{
+		"var/log/app/app.log":           {Data: []byte(appLog.String()), ModTime: time.Now()},
+		"var/log/app/app.log.1.gz":      {Data: []byte{0x1f, 0x8b}},
+		"data/worker.out":               {Data: []byte("worker started\nworker died\n"), ModTime: time.Now().Add(-time.Hour)},
+		"srv/core.4242":                 {Data: make([]byte, 2048)},
+		"etc/app/config.yaml":           {Data: []byte("debug: true\n")},
+		"etc/app/..data/config.yaml":    {Data: []byte("debug: true\n")},
+		"etc/config/feature-flags.json": {Data: []byte("{}")},
+	})
+	agent.AllowedRoots = []string{"/var", "/data", "/srv", "/etc"}
+
+	out, err := runDebugCmd(t, "--pod", "web-0", "--namespace", "shop", "--lines", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.TargetContainer != "app" || lastOptions.Pod != "web-0" {
+		t.Errorf("expected the agent to target the crashed container, got %+v", lastOptions)
+	}
+	for _, want := range []string{
+		"Pod shop/web-0, container app",
+		"State:     waiting: CrashLoopBackOff (back-off 5m0s)",
+		"Restarts:  7",
+		"Last exit: code 137 (OOMKilled) at 2026-10-16T09:00:03Z after 3s",
+		"Memory:    limit 256Mi",
+		"== Output of the previous run (last 3 lines) ==\nfake logs",
+		"--> /var/log/app/app.log",
+		"line xc\nline xxd\nline e\n",
+		"--> /data/worker.out",
+		"== Core dumps ==\n/srv/core.4242",
+		"/etc/app/config.yaml",
+		"/etc/config/feature-flags.json",
+		"Skipped locations outside the pod's allowed roots: /tmp, /logs, /app/logs",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "app.log.1.gz") || strings.Contains(out, "..data") || strings.Contains(out, "line b\n") {
+		t.Errorf("unexpected content in:\n%s", out)
+	}
+	if strings.Index(out, "/var/log/app/app.log") > strings.Index(out, "/data/worker.out") {
+		t.Error("expected the most recently modified log first")
+	}
+}
+
+func TestCrashedContainer(t *testing.T) {
+	pod := crashingPod()
+	if s, err := crashedContainer(pod, ""); err != nil || s.Name != "app" {
+		t.Errorf("expected app, got %q, %v", s.Name, err)
+	}
+	if s, err := crashedContainer(pod, "envoy"); err != nil || s.Name != "envoy" {
+		t.Errorf("expected --container to pick envoy, got %q, %v", s.Name, err)
+	}
+	if _, err := crashedContainer(pod, "db"); err == nil || !strings.Contains(err.Error(), `container "db" not found`) {
+		t.Errorf("expected a missing container error, got %v", err)
+	}
+
+	// A container that exited with an error but is not yet backing off.
+	pod.Status.ContainerStatuses[1].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	if s, err := crashedContainer(pod, ""); err != nil || s.Name != "app" {
+		t.Errorf("expected app from its last termination, got %q, %v", s.Name, err)
+	}
+
+	pod.Status.ContainerStatuses[1].LastTerminationState = corev1.ContainerState{}
+	if _, err := crashedContainer(pod, ""); err == nil || !strings.Contains(err.Error(), "no crashed containers; pass --container") {
+		t.Errorf("expected no crashed containers, got %v", err)
+	}
+}
+
+func TestDebugWithoutInjection(t *testing.T) {
+	t.Setenv("PULSAAR_CONFIG", "")
+	withDebugClientset(t, crashingPod())
+	withFakeAgent(t, fstest.MapFS{})
+
+	out, err := runDebugCmd(t, "--pod", "web-0", "--namespace", "shop", "--no-inject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.TargetContainer != "" {
+		t.Errorf("an agent that is not injected cannot target a container, got %q", lastOptions.TargetContainer)
+	}
+	if !strings.Contains(out, "Restarts:  7") || !strings.Contains(out, "(none found in /var/log, /tmp, /logs, /app/logs, /srv, /data)") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		n    int64
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\n", 5, "a\nb\n"},
+		{"partial line\nlast\n", 1, "last\n"},
+	} {
+		if got := string(lastLines([]byte(tt.data), tt.n)); got != tt.want {
+			t.Errorf("lastLines(%q, %d) = %q, want %q", tt.data, tt.n, got, tt.want)
+		}
+	}
+}
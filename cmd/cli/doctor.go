@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+// doctorClientset loads the kubeconfig, returning the clientset and the
+// API server address.
+var doctorClientset = func() (kubernetes.Interface, string, error) {
+	config, err := kubeConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, "", err
+	}
+	return clientset, config.Host, nil
+}
+
+func newDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the kubeconfig, RBAC, cluster features, TLS settings and agent health Pulsaar needs",
+		Long: `Run the checks behind a Pulsaar connection and print each result with a fix
+for anything that fails. With --pod, the pod, its node's copy of the agent
+image and a running agent's health are checked too. Doctor never injects an
+agent. It exits non-zero if any check fails.`,
+		Args: cobra.NoArgs,
+		RunE: runDoctor,
+	}
+	cmd.Flags().String("pod", "", "Pod to check; without it only cluster-wide checks run")
+	cmd.Flags().String("namespace", "default", "Namespace")
+	cmd.Flags().String("connection-method", client.PortForward, "Connection method to check: port-forward or apiserver-proxy")
+	return cmd
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	pod, _ := cmd.Flags().GetString("pod")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	opts, err := agentOptions(cmd, pod, namespace)
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := cmd.OutOrStdout()
+
+	clientset, kubeconfig := diagnoseKubeConfig()
+	results := []client.CheckResult{kubeconfig, client.DiagnoseTLS(time.Now())}
+	agentRunning := false
+	if clientset != nil {
+		var cluster []client.CheckResult
+		cluster, agentRunning = client.DiagnoseCluster(ctx, clientset, opts)
+		results = append(results, cluster...)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Status == client.CheckFail {
+			failed++
+		}
+	}
+	if pod != "" {
+		results = append(results, doctorHealth(ctx, opts, agentRunning, failed > 0))
+		if results[len(results)-1].Status == client.CheckFail {
+			failed++
+		}
+	}
+
+	printChecks(out, results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// diagnoseKubeConfig loads the kubeconfig and contacts the API server,
+// returning a nil clientset if either fails.
+func diagnoseKubeConfig() (kubernetes.Interface, client.CheckResult) {
+	result := client.CheckResult{Name: "kubeconfig", Status: client.CheckFail, Hint: "check $KUBECONFIG or ~/.kube/config and try: kubectl cluster-info"}
+	clientset, host, err := doctorClientset()
+	if err != nil {
+		result.Detail = err.Error()
+		return nil, result
+	}
+	info, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		result.Detail = fmt.Sprintf("cannot reach %s: %v", host, err)
+		return nil, result
+	}
+	return clientset, client.CheckResult{Name: "kubeconfig", Status: client.CheckPass, Detail: fmt.Sprintf("%s (Kubernetes %s)", host, info.GitVersion)}
+}
+
+// doctorHealth asks an already running agent for its health.
+func doctorHealth(ctx context.Context, opts client.Options, agentRunning, failed bool) client.CheckResult {
+	result := client.CheckResult{Name: "agent-health", Status: client.CheckSkip}
+	switch {
+	case failed:
+		result.Detail = "skipped until the failures above are fixed"
+		return result
+	case !agentRunning:
+		result.Detail = "no agent is running in the pod yet; one is injected on first use"
+		return result
+	}
+
+	opts.SkipInjection = true
+	opts.SessionTTL = 0
+	opts.Progress = nil
+	c, err := connect(ctx, opts)
+	if err != nil {
+		result.Status = client.CheckFail
+		result.Detail = err.Error()
+		result.Hint = "see docs/TROUBLESHOOTING.md, \"CLI cannot connect to agent\""
+		return result
+	}
+	defer func() { _ = c.Close() }()
+	resp, err := c.Health(ctx)
+	switch {
+	case err != nil:
+		result.Status = client.CheckFail
+		result.Detail = err.Error()
+		result.Hint = "check the agent logs: kubectl logs " + opts.Pod + " -c pulsaar-agent -n " + opts.Namespace
+	case !resp.Ready:
+		result.Status = client.CheckFail
+		result.Detail = fmt.Sprintf("agent %s is not ready: %s", resp.Version, resp.StatusMessage)
+		result.Hint = "check the agent logs: kubectl logs " + opts.Pod + " -c pulsaar-agent -n " + opts.Namespace
+	default:
+		result.Status = client.CheckPass
+		result.Detail = fmt.Sprintf("agent %s is ready", resp.Version)
+	}
+	return result
+}
+
+// checkColors colors the status tags printChecks writes to a terminal.
+var checkColors = map[client.CheckStatus]string{
+	client.CheckPass: colorGreen,
+	client.CheckWarn: colorYellow,
+	client.CheckFail: colorRed,
+}
+
+func printChecks(w io.Writer, results []client.CheckResult) {
+	for _, r := range results {
+		tag := "[" + string(r.Status) + "]"
+		if color, ok := checkColors[r.Status]; ok {
+			tag = colorize(w, color, tag)
+		}
+		_, _ = fmt.Fprintf(w, "%s %s: %s\n", tag, r.Name, r.Detail)
+		if r.Hint != "" && (r.Status == client.CheckFail || r.Status == client.CheckWarn) {
+			_, _ = fmt.Fprintf(w, "       fix: %s\n", r.Hint)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func withDoctorClientset(t *testing.T, clientset kubernetes.Interface, err error) {
+	t.Helper()
+	original := doctorClientset
+	t.Cleanup(func() { doctorClientset = original })
+	doctorClientset = func() (kubernetes.Interface, string, error) {
+		return clientset, "https://cluster.example:6443", err
+	}
+}
+
+func runDoctorCmd(args ...string) (string, error) {
+	cmd := newDoctorCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestDoctorChecksSidecarAgentHealth(t *testing.T) {
+	t.Setenv("PULSAAR_CLIENT_CERT_FILE", "")
+	t.Setenv("PULSAAR_CA_FILE", "")
+	withFakeAgent(t, fstest.MapFS{})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "shop"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "pulsaar-agent", Image: "pulsaar/agent:1.2.0"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	clientset := fake.NewClientset(pod)
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = true
+		return true, review, nil
+	})
+	clientset.Resources = []*metav1.APIResourceList{{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods/ephemeralcontainers"}}}}
+	withDoctorClientset(t, clientset, nil)
+
+	out, err := runDoctorCmd("--pod", "web-0", "--namespace", "shop")
+	if err != nil {
+		t.Fatalf("expected all checks to pass, got %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"[PASS] kubeconfig: https://cluster.example:6443",
+		"[WARN] tls: no CA or client certificate configured",
+		"[PASS] rbac: get pods in shop",
+		"[PASS] rbac: update pods/ephemeralcontainers in shop",
+		"[PASS] ephemeral-containers: pods/ephemeralcontainers is served",
+		"[PASS] rbac: create pods/portforward in shop",
+		"[PASS] pod: shop/web-0 is Running",
+		"[PASS] agent-image: agent runs as a sidecar (pulsaar/agent:1.2.0)",
+		"[PASS] agent-health: agent test is ready",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if !lastOptions.SkipInjection || lastOptions.Pod != "web-0" {
+		t.Errorf("doctor must not inject an agent, got %+v", lastOptions)
+	}
+}
+
+func TestDoctorReportsUnreachableCluster(t *testing.T) {
+	withDoctorClientset(t, nil, errors.New("invalid configuration: no configuration has been provided"))
+
+	out, err := runDoctorCmd("--pod", "web-0")
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 checks failed") {
+		t.Errorf("expected a failure count, got %v", err)
+	}
+	for _, want := range []string{
+		"[FAIL] kubeconfig: invalid configuration",
+		"       fix: check $KUBECONFIG",
+		"[SKIP] agent-health: skipped until the failures above are fixed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newEditCmd() *cobra.Command {
+	editCmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Open a copy of a pod file in your local editor",
+		Long: `Download a file from a pod to a temporary file and open it in $VISUAL or
+$EDITOR (vi, or notepad on Windows, if neither is set). Pulsaar is read-only:
+changes made in the editor are never pushed back to the pod, and the copy is
+deleted when the editor exits unless --keep is set.`,
+		Example: `  pulsaar edit --read-only --pod web-0 --path /etc/app.conf
+  VISUAL="code --wait" pulsaar edit --pod web-0 --path /etc/nginx/nginx.conf --keep`,
+		RunE: runEdit,
+	}
+	editCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
+	editCmd.Flags().String("namespace", "default", "Namespace")
+	editCmd.Flags().String("path", "", "Path to file")
+	editCmd.Flags().Bool("read-only", true, "Open a local copy; edits are not pushed back (the only supported mode)")
+	editCmd.Flags().Bool("keep", false, "Keep the local copy after the editor exits")
+	if err := editCmd.MarkFlagRequired("path"); err != nil {
+		panic(err)
+	}
+	requireTarget(editCmd)
+	return editCmd
+}
+
+// editorCommand returns the user's editor command line, split on spaces so
+// values like "code --wait" work.
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// runEditor opens file with the editor attached to the terminal. Tests
+// replace it.
+var runEditor = func(editor []string, file string) error {
+	editorCmd := exec.Command(editor[0], append(editor[1:], file)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	return editorCmd.Run()
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	pod, _ := cmd.Flags().GetString("pod")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	filePath, _ := cmd.Flags().GetString("path")
+	readOnly, _ := cmd.Flags().GetBool("read-only")
+	keep, _ := cmd.Flags().GetBool("keep")
+	if !readOnly {
+		return fmt.Errorf("pulsaar never writes to pods; only --read-only editing is supported")
+	}
+
+	c, err := connectToAgent(cmd, pod, namespace)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	var content bytes.Buffer
+	if err := c.Stream(context.Background(), filePath, 0, &content); err != nil {
+		return fmt.Errorf("failed to download '%s' from %s. Check the path exists and is within allowed paths. Error: %w", filePath, describeTarget(cmd, namespace, pod), err)
+	}
+	if isBinary(content.Bytes()[:min(content.Len(), 8192)]) {
+		warnf(cmd.ErrOrStderr(), "This file appears to be binary. Your editor may not display it correctly.")
+	}
+
+	dir, err := os.MkdirTemp("", "pulsaar-edit-")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary directory: %v", err)
+	}
+	// Keep the base name so editors pick the right syntax highlighting.
+	local := filepath.Join(dir, path.Base(filePath))
+	if !keep {
+		defer func() {
+			// Windows will not delete a read-only file.
+			_ = os.Chmod(local, 0o600)
+			_ = os.RemoveAll(dir)
+		}()
+	}
+	if err := os.WriteFile(local, content.Bytes(), 0o400); err != nil {
+		return fmt.Errorf("failed to write local copy: %v", err)
+	}
+
+	if err := runEditor(editorCommand(), local); err != nil {
+		return fmt.Errorf("editor failed: %v", err)
+	}
+
+	edited, err := os.ReadFile(local)
+	if err == nil && !bytes.Equal(edited, content.Bytes()) {
+		warnf(cmd.ErrOrStderr(), "changes to the local copy were not pushed back to '%s' in %s.", filePath, describeTarget(cmd, namespace, pod))
+	}
+	if keep {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Local copy kept at %s\n", local)
+	}
+	return nil
+}
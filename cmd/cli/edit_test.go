@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func withFakeEditor(t *testing.T, edit func(file string)) *[]string {
+	t.Helper()
+	var got []string
+	original := runEditor
+	t.Cleanup(func() { runEditor = original })
+	runEditor = func(editor []string, file string) error {
+		got = append(append([]string(nil), editor...), file)
+		if edit != nil {
+			edit(file)
+		}
+		return nil
+	}
+	return &got
+}
+
+func runEditCmd(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+	cmd := newEditCmd()
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs(append([]string{"--pod", "web-0", "--path", "/etc/app.conf"}, args...))
+	err := cmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func TestEdit(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"etc/app.conf": {Data: []byte("port = 8080\n")}})
+	t.Setenv("VISUAL", "code --wait")
+	t.Setenv("EDITOR", "nano")
+
+	var seen string
+	got := withFakeEditor(t, func(file string) {
+		data, _ := os.ReadFile(file)
+		seen = string(data)
+	})
+	_, errOut, err := runEditCmd(t, "--read-only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*got) != 3 || (*got)[0] != "code" || (*got)[1] != "--wait" || filepath.Base((*got)[2]) != "app.conf" {
+		t.Errorf("unexpected editor invocation %v", *got)
+	}
+	if seen != "port = 8080\n" {
+		t.Errorf("expected the editor to see the file contents, got %q", seen)
+	}
+	if errOut != "" {
+		t.Errorf("expected no warning for an unchanged file, got %q", errOut)
+	}
+	if _, err := os.Stat((*got)[2]); !os.IsNotExist(err) {
+		t.Errorf("expected the local copy to be removed, got %v", err)
+	}
+}
+
+func TestEditWarnsAboutChanges(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"etc/app.conf": {Data: []byte("port = 8080\n")}})
+	got := withFakeEditor(t, func(file string) {
+		_ = os.Chmod(file, 0o600)
+		_ = os.WriteFile(file, []byte("port = 9090\n"), 0o600)
+	})
+
+	out, errOut, err := runEditCmd(t, "--keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(filepath.Dir((*got)[len(*got)-1])) })
+	if !strings.Contains(errOut, "not pushed back to '/etc/app.conf'") {
+		t.Errorf("expected a not-pushed-back warning, got %q", errOut)
+	}
+	if !strings.Contains(out, "Local copy kept at ") {
+		t.Errorf("expected the kept path, got %q", out)
+	}
+}
+
+func TestEditRejectsWriteBack(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"etc/app.conf": {Data: []byte("x")}})
+	withFakeEditor(t, nil)
+	if _, _, err := runEditCmd(t, "--read-only=false"); err == nil || !strings.Contains(err.Error(), "only --read-only") {
+		t.Errorf("expected write-back to be refused, got %v", err)
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "nano -w")
+	if got := editorCommand(); len(got) != 2 || got[0] != "nano" {
+		t.Errorf("expected $EDITOR when $VISUAL is empty, got %v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// mutuallyExclusiveAnnotation is where cobra records
+// MarkFlagsMutuallyExclusive groups, as space-separated flag names.
+const mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
+// flagEnv returns the environment variable for a flag, e.g.
+// PULSAAR_CHUNK_SIZE for --chunk-size.
+func flagEnv(name string) string {
+	return "PULSAAR_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// bindFlagEnv sets every flag of cmd that was not given on the command line
+// from its PULSAAR_* environment variable, so the CLI can be configured
+// where flags are awkward, e.g. in containers and CI. List flags take
+// comma-separated values. A flag given on the command line also wins over
+// the variables of the flags it excludes, so PULSAAR_POD does not clash
+// with --node.
+func bindFlagEnv(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	given := map[string]bool{}
+	flags.Visit(func(f *pflag.Flag) { given[f.Name] = true })
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || given[f.Name] || f.Name == "help" || excludedByGivenFlag(f, given) {
+			return
+		}
+		env := flagEnv(f.Name)
+		v := os.Getenv(env)
+		if v == "" {
+			return
+		}
+		if list, isList := f.Value.(pflag.SliceValue); isList {
+			err = list.Replace(strings.Split(v, ","))
+		} else {
+			err = f.Value.Set(v)
+		}
+		if err != nil {
+			err = &usageError{fmt.Errorf("invalid %s %q: %v", env, v, err)}
+			return
+		}
+		f.Changed = true
+	})
+	return err
+}
+
+// excludedByGivenFlag reports whether a flag that shares a mutually
+// exclusive group with f was given on the command line.
+func excludedByGivenFlag(f *pflag.Flag, given map[string]bool) bool {
+	for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+		for _, name := range strings.Fields(group) {
+			if name != f.Name && given[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
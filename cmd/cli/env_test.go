@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func envTestCmd(args ...string) *cobra.Command {
+	cmd := &cobra.Command{Use: "stream"}
+	cmd.Flags().String("pod", "", "")
+	cmd.Flags().String("namespace", "default", "")
+	cmd.Flags().String("connection-method", "port-forward", "")
+	cmd.Flags().Int64("chunk-size", 64*1024, "")
+	cmd.Flags().StringArray("as-group", nil, "")
+	requireTarget(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		panic(err)
+	}
+	return cmd
+}
+
+func TestFlagEnv(t *testing.T) {
+	if got := flagEnv("connection-method"); got != "PULSAAR_CONNECTION_METHOD" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestBindFlagEnv(t *testing.T) {
+	t.Setenv("PULSAAR_NAMESPACE", "prod")
+	t.Setenv("PULSAAR_CONNECTION_METHOD", "apiserver-proxy")
+	t.Setenv("PULSAAR_CHUNK_SIZE", "4096")
+	t.Setenv("PULSAAR_AS_GROUP", "dev,ops")
+	cmd := envTestCmd("--namespace", "staging")
+	if err := bindFlagEnv(cmd); err != nil {
+		t.Fatal(err)
+	}
+	namespace, _ := cmd.Flags().GetString("namespace")
+	method, _ := cmd.Flags().GetString("connection-method")
+	chunkSize, _ := cmd.Flags().GetInt64("chunk-size")
+	groups, _ := cmd.Flags().GetStringArray("as-group")
+	if namespace != "staging" {
+		t.Errorf("expected the flag to win over the environment, got %s", namespace)
+	}
+	if method != "apiserver-proxy" || chunkSize != 4096 || !slices.Equal(groups, []string{"dev", "ops"}) {
+		t.Errorf("expected values from the environment, got %s, %d, %v", method, chunkSize, groups)
+	}
+	if !cmd.Flags().Changed("chunk-size") {
+		t.Error("expected a flag set from the environment to count as given")
+	}
+}
+
+func TestBindFlagEnvSkipsExcludedFlags(t *testing.T) {
+	t.Setenv("PULSAAR_POD", "web-0")
+	cmd := envTestCmd("--node", "node-a")
+	if err := bindFlagEnv(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		t.Errorf("expected --node to override PULSAAR_POD, got %v", err)
+	}
+	if pod, _ := cmd.Flags().GetString("pod"); pod != "" {
+		t.Errorf("expected no pod, got %s", pod)
+	}
+}
+
+func TestBindFlagEnvRejectsInvalidValues(t *testing.T) {
+	t.Setenv("PULSAAR_CHUNK_SIZE", "big")
+	err := bindFlagEnv(envTestCmd())
+	var usage *usageError
+	if !errors.As(err, &usage) {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+// Exit codes, stable so scripts can branch on the failure type.
+const (
+	exitError       = 1
+	exitUsage       = 2
+	exitAuthFailed  = 3
+	exitRBACDenied  = 4
+	exitPathDenied  = 5
+	exitNotFound    = 6
+	exitRateLimited = 7
+	exitConnection  = 8
+	// exitUpdateAvailable is not a failure: pulsaar update --check found a
+	// newer release.
+	exitUpdateAvailable = 9
+)
+
+// Values of --error-format.
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// errorTypes names each exit code in JSON error output.
+var errorTypes = map[int]string{
+	exitError:           "error",
+	exitUsage:           "usage",
+	exitAuthFailed:      "auth_failed",
+	exitRBACDenied:      "rbac_denied",
+	exitPathDenied:      "path_denied",
+	exitNotFound:        "not_found",
+	exitRateLimited:     "rate_limited",
+	exitConnection:      "connection_failed",
+	exitUpdateAvailable: "update_available",
+}
+
+// usageError marks a command-line parsing error.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// exitCode classifies err by the classes pkg/client attaches, the gRPC
+// status returned by the agent, or the network error underneath.
+func exitCode(err error) int {
+	var usage *usageError
+	if errors.As(err, &usage) {
+		return exitUsage
+	}
+	switch {
+	case errors.Is(err, errUpdateAvailable):
+		return exitUpdateAvailable
+	case errors.Is(err, client.ErrUnauthenticated):
+		return exitAuthFailed
+	case errors.Is(err, client.ErrAccessDenied):
+		return exitRBACDenied
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrConnection):
+		return exitConnection
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		switch s.Code() {
+		case codes.Unauthenticated:
+			return exitAuthFailed
+		case codes.PermissionDenied:
+			return exitPathDenied
+		case codes.NotFound:
+			return exitNotFound
+		case codes.ResourceExhausted:
+			return exitRateLimited
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return exitConnection
+		}
+		return exitError
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return exitConnection
+	}
+	return exitError
+}
+
+// reportError prints err in the format chosen by --error-format and returns
+// the exit code for it.
+func reportError(format string, err error, w io.Writer) int {
+	code := exitCode(err)
+	if format != errorFormatJSON {
+		log.New(w, "", log.LstdFlags).Print(err)
+		return code
+	}
+	_ = json.NewEncoder(w).Encode(struct {
+		Error    string `json:"error"`
+		Type     string `json:"type"`
+		ExitCode int    `json:"exit_code"`
+	}{err.Error(), errorTypes[code], code})
+	return code
+}
+
+// setupErrorFormat validates --error-format and, for JSON, stops cobra
+// printing its own error text and usage.
+func setupErrorFormat(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("error-format")
+	switch format {
+	case "", errorFormatText:
+	case errorFormatJSON:
+		cmd.Root().SilenceErrors = true
+		cmd.Root().SilenceUsage = true
+	default:
+		return &usageError{fmt.Errorf("unsupported --error-format %q; use text or json", format)}
+	}
+	return nil
+}
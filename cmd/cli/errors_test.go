@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func TestExitCode(t *testing.T) {
+	wrap := func(err error) error { return fmt.Errorf("failed to read file '/x'. Error: %w", err) }
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitError},
+		{&usageError{errors.New("unknown flag: --bogus")}, exitUsage},
+		{wrap(client.ErrUnauthenticated), exitAuthFailed},
+		{wrap(client.ErrAccessDenied), exitRBACDenied},
+		{wrap(client.ErrNotFound), exitNotFound},
+		{wrap(client.ErrConnection), exitConnection},
+		{wrap(status.Error(codes.Unauthenticated, "no client certificate")), exitAuthFailed},
+		{wrap(status.Error(codes.PermissionDenied, "Access to path '/etc' is not allowed.")), exitPathDenied},
+		{wrap(status.Error(codes.ResourceExhausted, "Rate limit exceeded.")), exitRateLimited},
+		{wrap(status.Error(codes.Unavailable, "connection refused")), exitConnection},
+		{wrap(status.Error(codes.NotFound, "Unable to open file '/x' for reading: open /x: no such file or directory")), exitNotFound},
+		{wrap(status.Error(codes.Internal, "Unable to read file '/x': is a directory")), exitError},
+		{wrap(&net.OpError{Op: "dial", Err: errors.New("connection refused")}), exitConnection},
+	} {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestReportErrorJSON(t *testing.T) {
+	var out bytes.Buffer
+	code := reportError(errorFormatJSON, fmt.Errorf("denied: %w", client.ErrAccessDenied), &out)
+	var got struct {
+		Error    string `json:"error"`
+		Type     string `json:"type"`
+		ExitCode int    `json:"exit_code"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if code != exitRBACDenied || got.ExitCode != exitRBACDenied || got.Type != "rbac_denied" || got.Error != "denied: access denied" {
+		t.Errorf("unexpected report %d %+v", code, got)
+	}
+
+	out.Reset()
+	if code := reportError(errorFormatText, errors.New("boom"), &out); code != exitError || !strings.HasSuffix(out.String(), " boom\n") {
+		t.Errorf("unexpected text report %d %q", code, out.String())
+	}
+}
+
+func TestErrorsFromFakeAgent(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"etc/secret": {Data: []byte("x")}})
+
+	err := runRead(fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/missing"}), nil)
+	if got := exitCode(err); got != exitNotFound {
+		t.Errorf("expected exit code %d for a missing file, got %d (%v)", exitNotFound, got, err)
+	}
+
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/etc/secret"})
+	c, err := connectToAgent(cmd, "web-0", "default", "/var/log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Read(context.Background(), "/etc/secret", 0, 0)
+	if got := exitCode(fmt.Errorf("read: %w", err)); got != exitPathDenied {
+		t.Errorf("expected exit code %d outside the allowed roots, got %d (%v)", exitPathDenied, got, err)
+	}
+}
+
+func TestSetupErrorFormat(t *testing.T) {
+	root := &cobra.Command{Use: "pulsaar"}
+	root.PersistentFlags().String("error-format", errorFormatText, "")
+	if err := root.ParseFlags([]string{"--error-format", "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setupErrorFormat(root); exitCode(err) != exitUsage {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+	if err := root.ParseFlags([]string{"--error-format", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setupErrorFormat(root); err != nil || !root.SilenceErrors || !root.SilenceUsage {
+		t.Errorf("expected JSON errors to silence cobra, got %v", err)
+	}
+}
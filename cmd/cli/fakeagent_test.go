@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+	pulsaartesting "github.com/VrushankPatel/pulsaar/pkg/testing"
+)
+
+// lastOptions holds the options of the latest fake connect call.
+var lastOptions client.Options
+
+// withFakeAgent routes connectToAgent to an in-memory agent serving fsys.
+func withFakeAgent(t *testing.T, fsys fstest.MapFS) *pulsaartesting.Agent {
+	t.Helper()
+	agent := pulsaartesting.NewAgent(fsys)
+	srv := pulsaartesting.Serve(agent)
+	t.Cleanup(srv.Close)
+
+	original := connect
+	t.Cleanup(func() { connect = original })
+	connect = func(ctx context.Context, opts client.Options) (*client.Client, error) {
+		lastOptions = opts
+		conn, err := srv.Dial()
+		if err != nil {
+			return nil, err
+		}
+		c := client.New(conn, opts.AllowedRoots...)
+		t.Cleanup(func() { _ = conn.Close() })
+		return c, nil
+	}
+	return agent
+}
+
+func fakeAgentCmd(flags map[string]string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("pod", "", "")
+	cmd.Flags().String("namespace", "default", "")
+	cmd.Flags().String("path", "", "")
+	cmd.Flags().Int64("chunk-size", 64*1024, "")
+	// The connection flags main declares on the root command.
+	cmd.Flags().Bool("no-inject", false, "")
+	cmd.Flags().Int("agent-port", 0, "")
+	cmd.Flags().Duration("inject-timeout", defaultInjectTimeout, "")
+	cmd.Flags().String("agent-cpu", "", "")
+	cmd.Flags().String("agent-memory", "", "")
+	cmd.Flags().String("agent-privilege", "", "")
+	cmd.Flags().String("proxy", "", "")
+	cmd.Flags().Bool("strict-version", false, "")
+	requireTarget(cmd)
+	for k, v := range flags {
+		_ = cmd.Flags().Set(k, v)
+	}
+	if err := bindFlagEnv(cmd); err != nil {
+		panic(err)
+	}
+	return cmd
+}
+
+func TestRunCommandsWithFakeAgent(t *testing.T) {
+	agent := withFakeAgent(t, fstest.MapFS{
+		"app/config.yaml": {Data: []byte("debug: true\n")},
+	})
+	flags := map[string]string{"pod": "web-0", "path": "/app/config.yaml"}
+
+	for name, run := range map[string]func(*cobra.Command, []string) error{
+		"read": runRead, "stream": runStream, "stat": runStat, "health": runHealth,
+	} {
+		if err := run(fakeAgentCmd(flags), nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if err := runExplore(fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app"}), nil); err != nil {
+		t.Errorf("explore: %v", err)
+	}
+
+	for _, req := range agent.Requests() {
+		if req.Operation == "Stat" && (len(req.AllowedRoots) != 1 || req.AllowedRoots[0] != "/") {
+			t.Errorf("stat should send allowed roots [/], got %v", req.AllowedRoots)
+		}
+	}
+	// stream also asks for the checksum it verifies against.
+	if got := len(agent.Requests()); got != 5 {
+		t.Errorf("expected 5 file requests, got %d", got)
+	}
+}
+
+func TestRunStreamWarnsWithoutChecksum(t *testing.T) {
+	agent := withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("debug: true\n")}})
+	agent.Supported = []string{"StreamFile"}
+
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml"})
+	var stderr strings.Builder
+	cmd.SetErr(&stderr)
+	if err := runStream(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr.String(), "will not be verified") {
+		t.Errorf("expected a warning that the copy is unverified, got %q", stderr.String())
+	}
+}
+
+func TestRunReadWithFakeAgentReportsMissingFile(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{})
+	err := runRead(fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/missing"}), nil)
+	if err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestRunWithNodeTarget(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"var/log/syslog": {Data: []byte("boot\n")}})
+
+	if err := runRead(fakeAgentCmd(map[string]string{"node": "node-a", "path": "/var/log/syslog"}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.Node != "node-a" || lastOptions.Pod != "" || lastOptions.Namespace != "" {
+		t.Errorf("expected a node target in the host agent namespace, got %+v", lastOptions)
+	}
+
+	if err := runRead(fakeAgentCmd(map[string]string{"node": "node-a", "namespace": "ops", "path": "/var/log/syslog"}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.Namespace != "ops" {
+		t.Errorf("expected an explicit --namespace to be kept, got %q", lastOptions.Namespace)
+	}
+
+	err := runRead(fakeAgentCmd(map[string]string{"node": "node-a", "path": "/var/log/missing"}), nil)
+	if err == nil || !strings.Contains(err.Error(), "in node node-a") {
+		t.Errorf("expected the error to name the node, got %v", err)
+	}
+}
+
+func TestRunWithNoInject(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("x")}})
+	t.Setenv("PULSAAR_NO_INJECT", "1")
+	t.Setenv("PULSAAR_AGENT_PORT", "9443")
+
+	if err := runRead(fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml"}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if !lastOptions.SkipInjection || lastOptions.AgentPort != 9443 {
+		t.Errorf("expected injection to be skipped on port 9443, got %+v", lastOptions)
+	}
+}
+
+func TestRunWithInjectTimeout(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("x")}})
+	withTerminal(t, true)
+	t.Setenv("PULSAAR_INJECT_TIMEOUT", "3m")
+
+	if err := runRead(fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml"}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.InjectTimeout != 3*time.Minute || lastOptions.Progress == nil {
+		t.Errorf("expected a 3m inject timeout with progress output, got %+v", lastOptions)
+	}
+}
+
+func TestRequireTarget(t *testing.T) {
+	for _, args := range [][]string{{}, {"--pod", "web-0", "--node", "node-a"}} {
+		cmd := &cobra.Command{Use: "read", RunE: func(*cobra.Command, []string) error { return nil }}
+		cmd.Flags().String("pod", "", "")
+		requireTarget(cmd)
+		cmd.SetArgs(args)
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected %v to be rejected", args)
+		}
+	}
+}
+
+func TestRunWithTargetContainer(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("x")}})
+	t.Setenv("PULSAAR_NO_INJECT", "")
+	targeted := func(flags map[string]string) *cobra.Command {
+		cmd := fakeAgentCmd(flags)
+		cmd.Flags().String("target-container", "app", "")
+		return cmd
+	}
+
+	if err := runRead(targeted(map[string]string{"pod": "web-0", "path": "/app/config.yaml"}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.TargetContainer != "app" {
+		t.Errorf("expected the agent to target app, got %+v", lastOptions)
+	}
+
+	t.Setenv("PULSAAR_NO_INJECT", "1")
+	err := runRead(targeted(map[string]string{"pod": "web-0", "path": "/app/config.yaml"}), nil)
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--target-container needs an injected agent") {
+		t.Errorf("expected a usage error with --no-inject, got %v", err)
+	}
+}
+
+func TestRunWithAgentLimits(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("x")}})
+	t.Setenv("PULSAAR_AGENT_CPU", "2")
+	t.Setenv("PULSAAR_AGENT_MEMORY", "256Mi")
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml", "agent-cpu": "500m"})
+
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.AgentCPU != "500m" || lastOptions.AgentMemory != "256Mi" {
+		t.Errorf("expected --agent-cpu to override the environment, got %q and %q", lastOptions.AgentCPU, lastOptions.AgentMemory)
+	}
+}
+
+func TestRunWithAgentPrivilege(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("x")}})
+	t.Setenv("PULSAAR_AGENT_PRIVILEGE", "dac-read-search")
+	if err := runRead(fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml"}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.AgentPrivilege != "dac-read-search" {
+		t.Errorf("expected the privilege from the environment, got %q", lastOptions.AgentPrivilege)
+	}
+
+	t.Setenv("PULSAAR_AGENT_PRIVILEGE", "admin")
+	err := runRead(fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml"}), nil)
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), `invalid agent privilege "admin"`) {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+}
+
+func TestRunWithProxy(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("x")}})
+	t.Setenv("PULSAAR_PROXY", "http://proxy.corp:3128")
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml", "proxy": "socks5://localhost:1080"})
+
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastOptions.Proxy != "socks5://localhost:1080" {
+		t.Errorf("expected --proxy to override the environment, got %q", lastOptions.Proxy)
+	}
+
+	t.Setenv("PULSAAR_PROXY", "proxy.corp:3128")
+	err := runRead(fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app/config.yaml"}), nil)
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "invalid proxy URL") {
+		t.Errorf("expected a usage error for a proxy without a scheme, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	pathpkg "path"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func newFindCmd() *cobra.Command {
+	findCmd := &cobra.Command{
+		Use:   "find",
+		Short: "Find files in a pod by name, size, age and type",
+		Long: `Walk a directory tree in a pod and print the entries that pass every filter,
+like find(1). The agent walks and filters, so only matching entries are
+transferred. Symlinks are listed but not followed.`,
+		Example: `  pulsaar find --pod web-0 --path /var/log --newer-than 1h --larger-than 100M --type f
+  pulsaar find --pod web-0 --path /app --name '*.yaml' -o csv`,
+		RunE: runFind,
+	}
+	findCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
+	findCmd.Flags().String("namespace", "default", "Namespace")
+	findCmd.Flags().String("path", "/", "Directory to search")
+	findCmd.Flags().String("name", "", "Only entries whose name matches this glob, e.g. '*.log'")
+	findCmd.Flags().Duration("newer-than", 0, "Only entries modified within this window, e.g. 1h")
+	findCmd.Flags().String("larger-than", "", "Only entries of at least this size, e.g. 500, 100M or 1Gi")
+	findCmd.Flags().String("type", "", "Only entries of this type: f (file), d (directory), l (symlink), p (pipe), s (socket), b or c (device)")
+	findCmd.Flags().Int32("max-results", 0, "Maximum entries to return (default 1000, at most 10000)")
+	findCmd.Flags().StringP("output", "o", "text", "Output format: text, csv or tsv")
+	requireTarget(findCmd)
+	return findCmd
+}
+
+// findTypes maps --type letters, as in find(1), to file types.
+var findTypes = map[string]api.FileType{
+	"f": api.FileType_FILE_TYPE_REGULAR,
+	"d": api.FileType_FILE_TYPE_DIRECTORY,
+	"l": api.FileType_FILE_TYPE_SYMLINK,
+	"p": api.FileType_FILE_TYPE_NAMED_PIPE,
+	"s": api.FileType_FILE_TYPE_SOCKET,
+	"b": api.FileType_FILE_TYPE_BLOCK_DEVICE,
+	"c": api.FileType_FILE_TYPE_CHAR_DEVICE,
+}
+
+// findOptions reads the filter flags before any connection is made.
+func findOptions(cmd *cobra.Command) (client.FindOptions, error) {
+	var opts client.FindOptions
+	opts.NameGlob, _ = cmd.Flags().GetString("name")
+	if _, err := pathpkg.Match(opts.NameGlob, ""); err != nil {
+		return opts, &usageError{fmt.Errorf("invalid --name pattern %q: %w", opts.NameGlob, err)}
+	}
+	if size, _ := cmd.Flags().GetString("larger-than"); size != "" {
+		q, err := resource.ParseQuantity(size)
+		if err != nil || q.Sign() < 0 {
+			return opts, &usageError{fmt.Errorf("invalid --larger-than %q; use a byte count such as 500, 100M or 1Gi", size)}
+		}
+		opts.MinSize = q.Value()
+	}
+	if newer, _ := cmd.Flags().GetDuration("newer-than"); newer > 0 {
+		opts.ModifiedSince = time.Now().Add(-newer)
+	}
+	if t, _ := cmd.Flags().GetString("type"); t != "" {
+		fileType, ok := findTypes[t]
+		if !ok {
+			return opts, &usageError{fmt.Errorf("invalid --type %q; use f, d, l, p, s, b or c", t)}
+		}
+		opts.Type = fileType
+	}
+	opts.MaxResults, _ = cmd.Flags().GetInt32("max-results")
+	if opts.MaxResults < 0 {
+		return opts, &usageError{fmt.Errorf("--max-results must not be negative")}
+	}
+	return opts, nil
+}
+
+func runFind(cmd *cobra.Command, args []string) error {
+	pod, _ := cmd.Flags().GetString("pod")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	path, _ := cmd.Flags().GetString("path")
+	output, _ := cmd.Flags().GetString("output")
+	if err := validateListingFormat(output); err != nil {
+		return err
+	}
+	opts, err := findOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	c, err := connectToAgent(cmd, pod, namespace)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	resp, err := c.Find(context.Background(), path, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find files under '%s' in %s. Check the path is a directory within allowed paths. Error: %w", path, describeTarget(cmd, namespace, pod), err)
+	}
+	// List each match under its full path.
+	entries := make([]*api.FileInfo, len(resp.Matches))
+	for i, m := range resp.Matches {
+		info := proto.Clone(m.Info).(*api.FileInfo)
+		info.Name = m.Path
+		entries[i] = info
+	}
+	if err := writeListing(cmd.OutOrStdout(), output, entries); err != nil {
+		return err
+	}
+	if resp.Truncated {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Results truncated after %d entries scanned; narrow --path or the filters, or raise --max-results\n", resp.EntriesScanned)
+	}
+	return nil
+}
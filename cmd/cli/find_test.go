@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFind(t *testing.T) {
+	now := time.Now()
+	agent := withFakeAgent(t, fstest.MapFS{
+		"var/log/app.log":         {Data: make([]byte, 2000), ModTime: now},
+		"var/log/old/app.log.1":   {Data: make([]byte, 5000), ModTime: now.Add(-48 * time.Hour)},
+		"var/log/nginx/error.log": {Data: []byte("x"), ModTime: now},
+	})
+
+	cmd := newFindCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--pod", "web-0", "--path", "/var/log", "--newer-than", "1h", "--larger-than", "1k", "--type", "f"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], " /var/log/app.log 2000 ") {
+		t.Errorf("expected only the recent large log, got %q", out.String())
+	}
+	requests := agent.Requests()
+	if last := requests[len(requests)-1]; last.Operation != "Find" {
+		t.Errorf("expected the agent to do the filtering, got a %s request", last.Operation)
+	}
+}
+
+func TestFindRejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--type", "x"},
+		{"--larger-than", "lots"},
+		{"--name", "["},
+	} {
+		cmd := newFindCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(append([]string{"--pod", "web-0"}, args...))
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected %v to be rejected", args)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/webhook"
+)
+
+// listAgentPods returns the running pods in namespace that run the agent,
+// as a sidecar or an injected ephemeral container. Replaced in tests.
+var listAgentPods = func(ctx context.Context, namespace string) ([]string, error) {
+	clientset, err := policyClientset()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods in namespace %s: %v", namespace, err)
+	}
+	var names []string
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && runsAgent(&pod) {
+			names = append(names, pod.Name)
+		}
+	}
+	return names, nil
+}
+
+func runsAgent(pod *corev1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == webhook.ContainerName {
+			return true
+		}
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Name == webhook.ContainerName {
+			return true
+		}
+	}
+	return false
+}
+
+// addFleetHealthFlags lets health check many agents instead of one pod's.
+func addFleetHealthFlags(healthCmd *cobra.Command) {
+	healthCmd.Flags().StringP("selector", "l", "", "Check the agents of the running pods matching this label selector")
+	healthCmd.Flags().Bool("all", false, "Check every running pod in the namespace that runs the agent")
+	healthCmd.Flags().Int("concurrency", 5, "Agents checked at once with --selector or --all")
+	healthCmd.Flags().Duration("timeout", 10*time.Second, "How long to wait for each agent with --selector or --all")
+	healthCmd.MarkFlagsMutuallyExclusive("pod", "node", "selector", "all")
+	healthCmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if isFleetHealth(cmd) {
+			return nil
+		}
+		return ensureTarget(cmd, args)
+	}
+}
+
+func isFleetHealth(cmd *cobra.Command) bool {
+	selector, _ := cmd.Flags().GetString("selector")
+	all, _ := cmd.Flags().GetBool("all")
+	return selector != "" || all
+}
+
+type agentHealth struct {
+	pod     string
+	resp    *api.HealthResponse
+	latency time.Duration
+	err     error
+}
+
+// runFleetHealth checks the agents of many pods concurrently and prints
+// one row per pod. It fails unless every agent is ready, so it can gate a
+// rollout of a new agent image. Agents are never injected.
+func runFleetHealth(cmd *cobra.Command, namespace string) error {
+	selector, _ := cmd.Flags().GetString("selector")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx := context.Background()
+	var pods []string
+	var err error
+	if selector != "" {
+		pods, err = listPods(ctx, namespace, selector)
+	} else {
+		pods, err = listAgentPods(ctx, namespace)
+	}
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		if selector != "" {
+			return fmt.Errorf("no running pods match '%s' in namespace %s", selector, namespace)
+		}
+		return fmt.Errorf("no running pods in namespace %s run the agent", namespace)
+	}
+	slices.Sort(pods)
+
+	results := make([]agentHealth, len(pods))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = checkAgentHealth(ctx, cmd, namespace, pod, timeout)
+		}()
+	}
+	wg.Wait()
+
+	printFleetHealth(cmd.OutOrStdout(), results)
+	notReady := 0
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			notReady++
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", r.pod, r.err)
+		case !r.resp.Ready:
+			notReady++
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", r.pod, r.resp.StatusMessage)
+		}
+	}
+	if notReady > 0 {
+		return fmt.Errorf("%d of %d agents are not ready", notReady, len(pods))
+	}
+	return nil
+}
+
+func checkAgentHealth(ctx context.Context, cmd *cobra.Command, namespace, pod string, timeout time.Duration) agentHealth {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	opts, err := agentOptions(cmd, pod, namespace)
+	if err != nil {
+		return agentHealth{pod: pod, err: err}
+	}
+	opts.SkipInjection, opts.Progress = true, nil
+	c, err := connect(ctx, opts)
+	if err != nil {
+		return agentHealth{pod: pod, err: err}
+	}
+	defer func() { _ = c.Close() }()
+	start := time.Now()
+	resp, err := c.Health(ctx)
+	if err != nil {
+		return agentHealth{pod: pod, err: fmt.Errorf("failed to get health: %w", err)}
+	}
+	return agentHealth{pod: pod, resp: resp, latency: time.Since(start)}
+}
+
+// printFleetHealth prints one row per pod; pods whose agent could not be
+// reached show dashes.
+func printFleetHealth(w io.Writer, results []agentHealth) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "POD\tREADY\tVERSION\tCOMMIT\tLATENCY")
+	for _, r := range results {
+		row := []string{r.pod, "-", "-", "-", "-"}
+		if r.resp != nil {
+			row = []string{r.pod, fmt.Sprint(r.resp.Ready), r.resp.Version, r.resp.Commit, formatLatency(r.latency)}
+		}
+		_, _ = fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	_ = tw.Flush()
+}
+
+// formatLatency prints a round trip in milliseconds with one decimal.
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func fleetHealthCmd(args ...string) (*cobra.Command, *bytes.Buffer, *bytes.Buffer) {
+	cmd := &cobra.Command{Use: "health", RunE: runHealth, SilenceUsage: true, SilenceErrors: true}
+	cmd.Flags().String("pod", "", "")
+	cmd.Flags().String("namespace", "default", "")
+	requireTarget(cmd)
+	addFleetHealthFlags(cmd)
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs(args)
+	return cmd, &out, &errOut
+}
+
+func TestFleetHealth(t *testing.T) {
+	withFakePods(t, map[string]fstest.MapFS{"web-0": {}, "web-1": {}}, "web-1", "web-0")
+
+	cmd, out, _ := fleetHealthCmd("-l", "app=web")
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "POD") || !strings.HasPrefix(lines[1], "web-0") || !strings.HasPrefix(lines[2], "web-1") {
+		t.Fatalf("expected a header and a row per pod sorted by name, got:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 4 || fields[1] != "true" || fields[2] != "test" || !strings.HasSuffix(fields[3], "ms") {
+		t.Errorf("expected ready, version and latency, got %q", lines[1])
+	}
+}
+
+func TestFleetHealthReportsUnreachableAgents(t *testing.T) {
+	withFakePods(t, map[string]fstest.MapFS{"web-0": {}}, "web-0", "web-1")
+
+	cmd, out, errOut := fleetHealthCmd("-l", "app=web")
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 agents are not ready") {
+		t.Errorf("expected a failure for web-1, got %v", err)
+	}
+	if !strings.Contains(out.String(), "web-1  -") || !strings.Contains(errOut.String(), "web-1: port-forward failed") {
+		t.Errorf("expected web-1 to show dashes and its error, got %q and %q", out.String(), errOut.String())
+	}
+}
+
+func TestFleetHealthAll(t *testing.T) {
+	withFakePods(t, map[string]fstest.MapFS{"web-0": {}})
+	original := listAgentPods
+	t.Cleanup(func() { listAgentPods = original })
+	var namespace string
+	listAgentPods = func(ctx context.Context, ns string) ([]string, error) {
+		namespace = ns
+		return []string{"web-0"}, nil
+	}
+
+	cmd, out, _ := fleetHealthCmd("--all", "--namespace", "prod")
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if namespace != "prod" || !strings.Contains(out.String(), "web-0") {
+		t.Errorf("expected the agents in prod to be checked, got %q for %q", out.String(), namespace)
+	}
+
+	listAgentPods = func(ctx context.Context, ns string) ([]string, error) { return nil, nil }
+	cmd, _, _ = fleetHealthCmd("--all")
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "run the agent") {
+		t.Errorf("expected an error when no pod runs the agent, got %v", err)
+	}
+}
+
+func TestFleetHealthExcludesPod(t *testing.T) {
+	cmd, _, _ := fleetHealthCmd("--pod", "web-0", "--all")
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected --pod and --all to be rejected together")
+	}
+}
+
+func TestRunsAgent(t *testing.T) {
+	sidecar := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "pulsaar-agent"}}}}
+	injected := &corev1.Pod{Spec: corev1.PodSpec{
+		Containers:          []corev1.Container{{Name: "app"}},
+		EphemeralContainers: []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "pulsaar-agent"}}},
+	}}
+	plain := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+	if !runsAgent(sidecar) || !runsAgent(injected) || runsAgent(plain) {
+		t.Errorf("expected sidecar and injected agents to be found and a plain pod not")
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+	"github.com/VrushankPatel/pulsaar/pkg/webhook"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// podTemplatePaths locate the pod spec the webhook would see in each kind
+// that creates pods. An empty path is the object itself.
+var podTemplatePaths = map[string][]string{
+	"Pod":                   nil,
+	"Deployment":            {"spec", "template"},
+	"StatefulSet":           {"spec", "template"},
+	"DaemonSet":             {"spec", "template"},
+	"ReplicaSet":            {"spec", "template"},
+	"ReplicationController": {"spec", "template"},
+	"Job":                   {"spec", "template"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template"},
+}
+
+func newInjectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inject --dry-run -f FILE",
+		Short: "Render manifests as the injection webhook would mutate them",
+		Long: `Print the manifests in FILE with the agent sidecar, TLS volume and environment
+the webhook adds, so the mutation can be reviewed before the webhook is
+enabled. Pods and the pod templates of Deployments, StatefulSets, DaemonSets,
+ReplicaSets, ReplicationControllers, Jobs and CronJobs annotated or labelled
+with pulsaar.io/inject-agent=true are rendered; other documents are printed
+unchanged. Nothing is sent to the cluster, so namespace labels and the
+webhook's --inject-by-default are not taken into account.
+
+Agent images are resolved as pulsaar install webhook passes them to the
+webhook: --agent-image, the config file, then $PULSAAR_AGENT_IMAGE and
+$PULSAAR_AGENT_WINDOWS_IMAGE.`,
+		Example: `  pulsaar inject --dry-run -f deploy.yaml
+  kustomize build overlays/prod | pulsaar inject --dry-run -f -`,
+		Args: cobra.NoArgs,
+		RunE: runInject,
+	}
+	cmd.Flags().StringP("filename", "f", "", "Manifest file to render, or - for stdin")
+	cmd.Flags().Bool("dry-run", false, "Render the mutation locally; required, as the webhook injects into live pods")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+func runInject(cmd *cobra.Command, args []string) error {
+	filename, _ := cmd.Flags().GetString("filename")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if !dryRun {
+		return &usageError{errors.New("inject only renders manifests; pass --dry-run. Running pods get the agent from the webhook or on connect")}
+	}
+	override, defaults, err := agentImages(cmd)
+	if err != nil {
+		return err
+	}
+	images := webhook.Images{Linux: firstNonEmpty(override, defaults.Linux), Windows: defaults.Windows}
+	for _, image := range []string{images.Linux, images.Windows} {
+		if image != "" {
+			if err := client.ValidateImage(image); err != nil {
+				return &usageError{err}
+			}
+		}
+	}
+
+	var in io.Reader = cmd.InOrStdin()
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read manifests. Error: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+
+	out := cmd.OutOrStdout()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(in))
+	written := 0
+	for {
+		data, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read manifests. Error: %w", err)
+		}
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse manifest %d. Error: %w", written+1, err)
+		}
+		if doc == nil {
+			continue
+		}
+		injected, err := renderInjection(doc, images)
+		if err != nil {
+			return fmt.Errorf("failed to render %s. Error: %w", describeManifest(doc), err)
+		}
+		if _, ok := podTemplatePaths[kindOf(doc)]; ok && !injected {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Note: %s is not annotated with %s=true; the webhook leaves it unchanged\n", describeManifest(doc), webhook.InjectAnnotation)
+		}
+		rendered, err := yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if written > 0 {
+			_, _ = fmt.Fprintln(out, "---")
+		}
+		if _, err := out.Write(rendered); err != nil {
+			return err
+		}
+		written++
+	}
+	return nil
+}
+
+// renderInjection applies the webhook's mutation to the pod or pod
+// template in doc, reporting whether it injected the agent.
+func renderInjection(doc map[string]interface{}, images webhook.Images) (bool, error) {
+	path, ok := podTemplatePaths[kindOf(doc)]
+	if !ok {
+		return false, nil
+	}
+	template := doc
+	if len(path) > 0 {
+		var found bool
+		var err error
+		template, found, err = unstructured.NestedMap(doc, path...)
+		if err != nil || !found {
+			return false, err
+		}
+	}
+	pod := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(template, pod); err != nil {
+		return false, err
+	}
+	if !webhook.ShouldInject(pod) {
+		return false, nil
+	}
+
+	sidecar, volume := webhook.Sidecar(pod, images)
+	for field, value := range map[string]interface{}{"containers": &sidecar, "volumes": &volume} {
+		// An already injected manifest is rendered unchanged, as the
+		// webhook would admit it.
+		if field == "containers" && webhook.HasContainer(pod) || field == "volumes" && webhook.HasVolume(pod) {
+			continue
+		}
+		item, err := runtime.DefaultUnstructuredConverter.ToUnstructured(value)
+		if err != nil {
+			return false, err
+		}
+		items, _, err := unstructured.NestedSlice(template, "spec", field)
+		if err != nil {
+			return false, err
+		}
+		if err := unstructured.SetNestedSlice(template, append(items, item), "spec", field); err != nil {
+			return false, err
+		}
+	}
+	if len(path) > 0 {
+		if err := unstructured.SetNestedMap(doc, template, path...); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func kindOf(doc map[string]interface{}) string {
+	kind, _, _ := unstructured.NestedString(doc, "kind")
+	return kind
+}
+
+// describeManifest names doc as Kind namespace/name, as install reports
+// objects.
+func describeManifest(doc map[string]interface{}) string {
+	name, _, _ := unstructured.NestedString(doc, "metadata", "name")
+	if namespace, _, _ := unstructured.NestedString(doc, "metadata", "namespace"); namespace != "" {
+		name = namespace + "/" + name
+	}
+	return kindOf(doc) + " " + name
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const injectManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  replicas: 2
+  template:
+    metadata:
+      annotations:
+        pulsaar.io/inject-agent: "true"
+    spec:
+      containers:
+      - name: app
+        image: nginx
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: batch
+  namespace: shop
+spec:
+  containers:
+  - name: app
+    image: busybox
+`
+
+func runInjectCmd(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	t.Setenv("PULSAAR_CONFIG", "")
+	cmd := newInjectCmd()
+	var out, errOut bytes.Buffer
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func TestInjectDryRun(t *testing.T) {
+	t.Setenv("PULSAAR_AGENT_IMAGE", "registry.internal/pulsaar/agent:1.2.0")
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(file, []byte(injectManifests), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, stderr, err := runInjectCmd(t, "", "--dry-run", "-f", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(out, "---\n")
+	if len(docs) != 3 {
+		t.Fatalf("expected 3 documents, got %d:\n%s", len(docs), out)
+	}
+
+	var deploy appsv1.Deployment
+	if err := yaml.Unmarshal([]byte(docs[0]), &deploy); err != nil {
+		t.Fatal(err)
+	}
+	spec := deploy.Spec.Template.Spec
+	if len(spec.Containers) != 2 || spec.Containers[0].Name != "app" {
+		t.Fatalf("expected the agent to follow the app container, got %+v", spec.Containers)
+	}
+	agent := spec.Containers[1]
+	if agent.Name != "pulsaar-agent" || agent.Image != "registry.internal/pulsaar/agent:1.2.0" {
+		t.Errorf("unexpected agent container %s %s", agent.Name, agent.Image)
+	}
+	if env := agent.Env[3]; env.Name != "PULSAAR_NAMESPACE" || env.ValueFrom == nil || env.ValueFrom.FieldRef.FieldPath != "metadata.namespace" {
+		t.Errorf("expected the pod namespace from the downward API, got %+v", env)
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].Secret == nil || spec.Volumes[0].Secret.SecretName != "pulsaar-tls" {
+		t.Errorf("expected the TLS volume, got %+v", spec.Volumes)
+	}
+	if deploy.Spec.Replicas == nil || *deploy.Spec.Replicas != 2 {
+		t.Error("fields outside the pod template should be kept")
+	}
+
+	if !strings.Contains(docs[1], "kind: Service") || strings.Contains(docs[2], "pulsaar-agent") {
+		t.Errorf("expected the Service and unannotated Pod unchanged, got:\n%s", out)
+	}
+	if !strings.Contains(stderr, "Pod shop/batch is not annotated with pulsaar.io/inject-agent=true") || strings.Contains(stderr, "Service") {
+		t.Errorf("unexpected notes: %s", stderr)
+	}
+}
+
+func TestInjectDryRunStdinWindows(t *testing.T) {
+	t.Setenv("PULSAAR_AGENT_WINDOWS_IMAGE", "registry.internal/pulsaar/agent:1.2.0-windows")
+	pod := `apiVersion: v1
+kind: Pod
+metadata:
+  name: iis
+  annotations:
+    pulsaar.io/inject-agent: "true"
+spec:
+  nodeSelector:
+    kubernetes.io/os: windows
+  containers:
+  - name: app
+    image: iis
+`
+	out, _, err := runInjectCmd(t, pod, "--dry-run", "-f", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rendered corev1.Pod
+	if err := yaml.Unmarshal([]byte(out), &rendered); err != nil {
+		t.Fatal(err)
+	}
+	agent := rendered.Spec.Containers[1]
+	if agent.Image != "registry.internal/pulsaar/agent:1.2.0-windows" || agent.VolumeMounts[0].MountPath != `C:\etc\pulsaar\tls` {
+		t.Errorf("expected the Windows image and paths, got %s at %s", agent.Image, agent.VolumeMounts[0].MountPath)
+	}
+	if env := agent.Env[2]; env.Name != "PULSAAR_POD_NAME" || env.ValueFrom == nil || env.ValueFrom.FieldRef.FieldPath != "metadata.name" {
+		t.Errorf("expected the pod name from the downward API, got %+v", env)
+	}
+}
+
+func TestInjectRequiresDryRun(t *testing.T) {
+	_, _, err := runInjectCmd(t, "", "-f", "-")
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--dry-run") {
+		t.Errorf("expected a usage error asking for --dry-run, got %v", err)
+	}
+}
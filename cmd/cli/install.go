@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+	"github.com/VrushankPatel/pulsaar/pkg/webhook"
+)
+
+const (
+	defaultInstallNamespace = "pulsaar-system"
+	webhookName             = "pulsaar-webhook"
+	aggregatorName          = "pulsaar-aggregator"
+)
+
+// installClientset is the cluster install applies to.
+var installClientset = policyClientset
+
+// installComponent is a server-side piece pulsaar install can deploy.
+type installComponent struct {
+	name         string
+	defaultImage string
+	// objects returns the manifests for the component, in apply order.
+	objects func(cfg installConfig) []runtime.Object
+	// done is printed after a successful install.
+	done string
+}
+
+// installConfig is what the manifests are rendered from.
+type installConfig struct {
+	namespace   string
+	image       string
+	replicas    int32
+	certs       *serviceCerts
+	agentImages [2]string // Linux and Windows agent images for the webhook
+	// verifyEnv configures agent image signature verification in the
+	// webhook.
+	verifyEnv []corev1.EnvVar
+	// injectDefault injects pods that neither they nor their namespace
+	// decide for.
+	injectDefault bool
+}
+
+var installComponents = map[string]installComponent{
+	"webhook": {
+		name:         webhookName,
+		defaultImage: "vrushankpatel/pulsaar-webhook:latest",
+		objects:      webhookObjects,
+		done:         "Annotate pods, or label their namespaces, with pulsaar.io/inject-agent=true to inject the agent sidecar, and =false to opt out. Their namespace needs a pulsaar-tls Secret with the agent's tls.crt and tls.key.",
+	},
+	"aggregator": {
+		name:         aggregatorName,
+		defaultImage: "vrushankpatel/pulsaar-aggregator:latest",
+		objects:      aggregatorObjects,
+		done:         "Point agents at it with PULSAAR_AUDIT_AGGREGATOR_URL=https://" + aggregatorName + ".<namespace>.svc:8080/audit and trust ca.crt from its TLS Secret.",
+	},
+}
+
+func newInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install webhook|aggregator",
+		Short: "Deploy the sidecar injection webhook or the audit aggregator to the cluster",
+		Long: `Create or update the Namespace, ServiceAccount, TLS Secret, Deployment and
+Service for a server-side component, plus the MutatingWebhookConfiguration for
+the webhook. A CA and serving certificate are generated for the Service; an
+existing TLS Secret is kept until its certificate is within 30 days of expiry
+or --rotate-certs is given.
+
+Neither component calls the Kubernetes API, so their ServiceAccounts are bound
+to no roles. The identity running install needs to create these objects.`,
+		ValidArgs: []string{"webhook", "aggregator"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE:      runInstall,
+	}
+	cmd.Flags().String("namespace", defaultInstallNamespace, "Namespace to install into")
+	cmd.Flags().String("image", "", "Component image (default vrushankpatel/pulsaar-<component>:latest)")
+	cmd.Flags().Int32("replicas", 1, "Deployment replicas")
+	cmd.Flags().Bool("dry-run", false, "Print the manifests as YAML instead of applying them")
+	cmd.Flags().Bool("rotate-certs", false, "Replace the TLS Secret even if its certificate is still valid")
+	cmd.Flags().Bool("inject-by-default", false, "Webhook: inject every pod unless it or its namespace sets pulsaar.io/inject-agent=false")
+	cmd.Flags().String("cosign-key", "", "Webhook: only inject agent images signed with this cosign public key file")
+	cmd.Flags().String("cosign-identity", "", "Webhook: only inject agent images signed keyless by an identity matching this regular expression")
+	cmd.Flags().String("cosign-issuer", "", "Webhook: OIDC issuer the keyless signer's identity must come from")
+	cmd.Flags().String("cosign-fulcio-root", "", "Webhook: Fulcio root certificates file for keyless verification")
+	cmd.Flags().String("cosign-rekor-key", "", "Webhook: Rekor public key file for keyless verification")
+	return cmd
+}
+
+// verifyEnv reads the --cosign-* flags into the webhook's PULSAAR_COSIGN_*
+// variables, checking them as the webhook will. Key files are passed as
+// their PEM contents, so nothing needs mounting.
+func verifyEnv(cmd *cobra.Command) ([]corev1.EnvVar, error) {
+	var cfg webhook.VerifyConfig
+	var env []corev1.EnvVar
+	for _, f := range []struct {
+		flag, env string
+		dst       *[]byte
+	}{
+		{"cosign-key", "PULSAAR_COSIGN_KEY", &cfg.Key},
+		{"cosign-fulcio-root", "PULSAAR_COSIGN_FULCIO_ROOT", &cfg.FulcioRoots},
+		{"cosign-rekor-key", "PULSAAR_COSIGN_REKOR_KEY", &cfg.RekorKey},
+	} {
+		path, _ := cmd.Flags().GetString(f.flag)
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, &usageError{fmt.Errorf("--%s: %w", f.flag, err)}
+		}
+		*f.dst = data
+		env = append(env, corev1.EnvVar{Name: f.env, Value: string(data)})
+	}
+	cfg.Identity, _ = cmd.Flags().GetString("cosign-identity")
+	cfg.Issuer, _ = cmd.Flags().GetString("cosign-issuer")
+	if _, err := webhook.NewImageVerifier(cfg); err != nil {
+		return nil, &usageError{err}
+	}
+	if cfg.Identity != "" {
+		env = append(env, corev1.EnvVar{Name: "PULSAAR_COSIGN_IDENTITY", Value: cfg.Identity})
+	}
+	if cfg.Issuer != "" {
+		env = append(env, corev1.EnvVar{Name: "PULSAAR_COSIGN_ISSUER", Value: cfg.Issuer})
+	}
+	return env, nil
+}
+
+func runInstall(cmd *cobra.Command, args []string) error {
+	component := installComponents[args[0]]
+	namespace, _ := cmd.Flags().GetString("namespace")
+	image, _ := cmd.Flags().GetString("image")
+	replicas, _ := cmd.Flags().GetInt32("replicas")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	rotate, _ := cmd.Flags().GetBool("rotate-certs")
+	if image == "" {
+		image = component.defaultImage
+	}
+	if replicas < 1 {
+		return &usageError{fmt.Errorf("--replicas must be at least 1")}
+	}
+	cfg := installConfig{namespace: namespace, image: image, replicas: replicas}
+	if args[0] == "webhook" {
+		override, defaults, err := agentImages(cmd)
+		if err != nil {
+			return err
+		}
+		// The webhook reads the same variables, so pass on what is set here.
+		cfg.agentImages = [2]string{
+			firstNonEmpty(override, defaults.Linux, os.Getenv("PULSAAR_AGENT_IMAGE")),
+			firstNonEmpty(defaults.Windows, os.Getenv("PULSAAR_AGENT_WINDOWS_IMAGE")),
+		}
+		for _, image := range cfg.agentImages {
+			if image != "" {
+				if err := client.ValidateImage(image); err != nil {
+					return &usageError{err}
+				}
+			}
+		}
+		if cfg.verifyEnv, err = verifyEnv(cmd); err != nil {
+			return err
+		}
+		cfg.injectDefault, _ = cmd.Flags().GetBool("inject-by-default")
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var clientset kubernetes.Interface
+	if !dryRun {
+		var err error
+		clientset, err = installClientset()
+		if err != nil {
+			return err
+		}
+		if !rotate {
+			cfg.certs = installedCerts(ctx, clientset, namespace, component.name)
+		}
+	}
+	dnsNames := serviceDNSNames(component.name, namespace)
+	if cfg.certs == nil || !reusableCerts(cfg.certs, dnsNames, time.Now()) {
+		certs, err := generateServiceCerts(dnsNames, time.Now())
+		if err != nil {
+			return err
+		}
+		cfg.certs = certs
+	}
+
+	objects := component.objects(cfg)
+	if dryRun {
+		return writeManifests(cmd.OutOrStdout(), objects)
+	}
+	out := cmd.OutOrStdout()
+	for _, obj := range objects {
+		if err := applyObject(ctx, clientset, obj, out); err != nil {
+			return fmt.Errorf("failed to install %s. Error: %w", args[0], err)
+		}
+	}
+	_, _ = fmt.Fprintln(out, component.done)
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// installedCerts returns the certificates in the component's existing TLS
+// Secret, or nil.
+func installedCerts(ctx context.Context, clientset kubernetes.Interface, namespace, name string) *serviceCerts {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name+"-tls", metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	return &serviceCerts{CA: secret.Data["ca.crt"], Cert: secret.Data[corev1.TLSCertKey], Key: secret.Data[corev1.TLSPrivateKeyKey]}
+}
+
+// writeManifests prints objects as a multi-document YAML stream.
+func writeManifests(w io.Writer, objects []runtime.Object) error {
+	for i, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			_, _ = fmt.Fprintln(w, "---")
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func componentLabels(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/part-of":    "pulsaar",
+		"app.kubernetes.io/managed-by": "pulsaar-cli",
+	}
+}
+
+func objectMeta(name, namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: componentLabels(name)}
+}
+
+// baseObjects are the Namespace, ServiceAccount and TLS Secret every
+// component needs.
+func baseObjects(name string, cfg installConfig) []runtime.Object {
+	return []runtime.Object{
+		&corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: cfg.namespace},
+		},
+		&corev1.ServiceAccount{
+			TypeMeta:                     metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta:                   objectMeta(name, cfg.namespace),
+			AutomountServiceAccountToken: new(bool),
+		},
+		&corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: objectMeta(name+"-tls", cfg.namespace),
+			Type:       corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				"ca.crt":                cfg.certs.CA,
+				corev1.TLSCertKey:       cfg.certs.Cert,
+				corev1.TLSPrivateKeyKey: cfg.certs.Key,
+			},
+		},
+	}
+}
+
+// deployment runs container with the TLS Secret mounted at /etc/pulsaar/tls.
+// The components load their certificates at start, so the pod template
+// carries the certificate hash to roll the pods when it changes.
+func deployment(name string, cfg installConfig, container corev1.Container) *appsv1.Deployment {
+	labels := componentLabels(name)
+	certHash := sha256.Sum256(cfg.certs.Cert)
+	selector := map[string]string{"app.kubernetes.io/name": name}
+	container.Image = cfg.image
+	container.VolumeMounts = []corev1.VolumeMount{{Name: "tls", MountPath: "/etc/pulsaar/tls", ReadOnly: true}}
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: objectMeta(name, cfg.namespace),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &cfg.replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{"pulsaar.io/tls-cert-sha256": hex.EncodeToString(certHash[:])},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: name,
+					Containers:         []corev1.Container{container},
+					Volumes: []corev1.Volume{{
+						Name:         "tls",
+						VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: name + "-tls"}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func service(name string, cfg installConfig, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: objectMeta(name, cfg.namespace),
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app.kubernetes.io/name": name},
+			Ports:    ports,
+		},
+	}
+}
+
+func httpsProbe(port int) *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/health", Port: intstr.FromInt32(int32(port)), Scheme: corev1.URISchemeHTTPS},
+		},
+		PeriodSeconds: 10,
+	}
+}
+
+func webhookObjects(cfg installConfig) []runtime.Object {
+	env := []corev1.EnvVar{
+		{Name: "TLS_CERT_FILE", Value: "/etc/pulsaar/tls/tls.crt"},
+		{Name: "TLS_KEY_FILE", Value: "/etc/pulsaar/tls/tls.key"},
+	}
+	if cfg.agentImages[0] != "" {
+		env = append(env, corev1.EnvVar{Name: "PULSAAR_AGENT_IMAGE", Value: cfg.agentImages[0]})
+	}
+	if cfg.agentImages[1] != "" {
+		env = append(env, corev1.EnvVar{Name: "PULSAAR_AGENT_WINDOWS_IMAGE", Value: cfg.agentImages[1]})
+	}
+	env = append(env, cfg.verifyEnv...)
+	if cfg.injectDefault {
+		env = append(env, corev1.EnvVar{Name: "PULSAAR_INJECT_DEFAULT", Value: "true"})
+	}
+	failurePolicy := admissionregistrationv1.Ignore
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	reinvocation := admissionregistrationv1.IfNeededReinvocationPolicy
+	timeout := int32(5)
+
+	// One webhook per value of the namespace's pulsaar.io/inject-agent
+	// label, so the server learns the label from the path it is called on
+	// without reading namespaces.
+	var webhooks []admissionregistrationv1.MutatingWebhook
+	for _, ns := range []struct {
+		name, path string
+		label      metav1.LabelSelectorRequirement
+	}{
+		{"pulsaar-agent-injector.pulsaar.io", "/mutate", metav1.LabelSelectorRequirement{Key: webhook.InjectAnnotation, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"true", "false"}}},
+		{"enabled.pulsaar-agent-injector.pulsaar.io", "/mutate/namespace/true", metav1.LabelSelectorRequirement{Key: webhook.InjectAnnotation, Operator: metav1.LabelSelectorOpIn, Values: []string{"true"}}},
+		{"disabled.pulsaar-agent-injector.pulsaar.io", "/mutate/namespace/false", metav1.LabelSelectorRequirement{Key: webhook.InjectAnnotation, Operator: metav1.LabelSelectorOpIn, Values: []string{"false"}}},
+	} {
+		path := ns.path
+		webhooks = append(webhooks, admissionregistrationv1.MutatingWebhook{
+			Name: ns.name,
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service:  &admissionregistrationv1.ServiceReference{Name: webhookName, Namespace: cfg.namespace, Path: &path},
+				CABundle: cfg.certs.CA,
+			},
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+				Rule:       admissionregistrationv1.Rule{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"pods"}},
+			}},
+			// Never block pod creation on the webhook, and keep it away
+			// from its own pods and the control plane.
+			FailurePolicy: &failurePolicy,
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "kubernetes.io/metadata.name",
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   []string{"kube-system", cfg.namespace},
+			}, ns.label}},
+			SideEffects:             &sideEffects,
+			AdmissionReviewVersions: []string{"v1"},
+			TimeoutSeconds:          &timeout,
+			ReinvocationPolicy:      &reinvocation,
+		})
+	}
+
+	return append(baseObjects(webhookName, cfg),
+		deployment(webhookName, cfg, corev1.Container{
+			Name:           "webhook",
+			Ports:          []corev1.ContainerPort{{Name: "https", ContainerPort: 8443}},
+			Env:            env,
+			ReadinessProbe: httpsProbe(8443),
+		}),
+		service(webhookName, cfg, corev1.ServicePort{Name: "https", Port: 443, TargetPort: intstr.FromInt32(8443)}),
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "admissionregistration.k8s.io/v1", Kind: "MutatingWebhookConfiguration"},
+			ObjectMeta: metav1.ObjectMeta{Name: webhookName, Labels: componentLabels(webhookName)},
+			Webhooks:   webhooks,
+		},
+	)
+}
+
+func aggregatorObjects(cfg installConfig) []runtime.Object {
+	return append(baseObjects(aggregatorName, cfg),
+		deployment(aggregatorName, cfg, corev1.Container{
+			Name: "aggregator",
+			Ports: []corev1.ContainerPort{
+				{Name: "https", ContainerPort: 8080},
+				{Name: "grpc", ContainerPort: 8081},
+			},
+			Env: []corev1.EnvVar{
+				{Name: "PULSAAR_AGGREGATOR_PORT", Value: "8080"},
+				{Name: "PULSAAR_AGGREGATOR_GRPC_PORT", Value: "8081"},
+				{Name: "PULSAAR_AGGREGATOR_TLS_CERT", Value: "/etc/pulsaar/tls/tls.crt"},
+				{Name: "PULSAAR_AGGREGATOR_TLS_KEY", Value: "/etc/pulsaar/tls/tls.key"},
+			},
+			ReadinessProbe: httpsProbe(8080),
+		}),
+		service(aggregatorName, cfg,
+			corev1.ServicePort{Name: "https", Port: 8080, TargetPort: intstr.FromInt32(8080)},
+			corev1.ServicePort{Name: "grpc", Port: 8081, TargetPort: intstr.FromInt32(8081)},
+		),
+	)
+}
+
+// applyObject creates obj, or updates it in place if it exists, and prints
+// what it did.
+func applyObject(ctx context.Context, clientset kubernetes.Interface, obj runtime.Object, out io.Writer) error {
+	var action string
+	var err error
+	switch o := obj.(type) {
+	case *corev1.Namespace:
+		// Leave an existing namespace and its labels alone.
+		_, err = clientset.CoreV1().Namespaces().Get(ctx, o.Name, metav1.GetOptions{})
+		action = "unchanged"
+		if apierrors.IsNotFound(err) {
+			_, err = clientset.CoreV1().Namespaces().Create(ctx, o, metav1.CreateOptions{})
+			action = "created"
+		}
+	case *corev1.ServiceAccount:
+		c := clientset.CoreV1().ServiceAccounts(o.Namespace)
+		action, err = createOrUpdate(ctx, o, c.Get, c.Create, c.Update, nil)
+	case *corev1.Secret:
+		c := clientset.CoreV1().Secrets(o.Namespace)
+		action, err = createOrUpdate(ctx, o, c.Get, c.Create, c.Update, nil)
+	case *corev1.Service:
+		c := clientset.CoreV1().Services(o.Namespace)
+		action, err = createOrUpdate(ctx, o, c.Get, c.Create, c.Update, func(existing *corev1.Service) {
+			// The cluster IP is immutable.
+			o.Spec.ClusterIP, o.Spec.ClusterIPs = existing.Spec.ClusterIP, existing.Spec.ClusterIPs
+		})
+	case *appsv1.Deployment:
+		c := clientset.AppsV1().Deployments(o.Namespace)
+		action, err = createOrUpdate(ctx, o, c.Get, c.Create, c.Update, nil)
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		c := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations()
+		action, err = createOrUpdate(ctx, o, c.Get, c.Create, c.Update, nil)
+	default:
+		return fmt.Errorf("cannot apply %T", obj)
+	}
+	if err != nil {
+		return err
+	}
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	meta, _ := obj.(metav1.Object)
+	name := meta.GetName()
+	if meta.GetNamespace() != "" {
+		name = meta.GetNamespace() + "/" + name
+	}
+	_, _ = fmt.Fprintf(out, "%s %s %s\n", kind, name, action)
+	return nil
+}
+
+// createOrUpdate creates obj, or replaces the existing object of the same
+// name. prepare, if set, copies fields that must be kept from the existing
+// object.
+func createOrUpdate[T metav1.Object](
+	ctx context.Context,
+	obj T,
+	get func(context.Context, string, metav1.GetOptions) (T, error),
+	create func(context.Context, T, metav1.CreateOptions) (T, error),
+	update func(context.Context, T, metav1.UpdateOptions) (T, error),
+	prepare func(existing T),
+) (string, error) {
+	existing, err := get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = create(ctx, obj, metav1.CreateOptions{})
+		return "created", err
+	}
+	if err != nil {
+		return "", err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	if prepare != nil {
+		prepare(existing)
+	}
+	_, err = update(ctx, obj, metav1.UpdateOptions{})
+	return "configured", err
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func withInstallClientset(t *testing.T) *fake.Clientset {
+	t.Helper()
+	clientset := fake.NewClientset()
+	original := installClientset
+	t.Cleanup(func() { installClientset = original })
+	installClientset = func() (kubernetes.Interface, error) { return clientset, nil }
+	return clientset
+}
+
+func runInstallCmd(t *testing.T, args ...string) string {
+	t.Helper()
+	cmd := newInstallCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestInstallWebhook(t *testing.T) {
+	t.Setenv("PULSAAR_CONFIG", "")
+	t.Setenv("PULSAAR_AGENT_IMAGE", "registry.internal/pulsaar/agent:1.2.0")
+	t.Setenv("PULSAAR_AGENT_WINDOWS_IMAGE", "")
+	clientset := withInstallClientset(t)
+
+	out := runInstallCmd(t, "webhook", "--namespace", "ops")
+	for _, want := range []string{
+		"Namespace ops created",
+		"ServiceAccount ops/pulsaar-webhook created",
+		"Secret ops/pulsaar-webhook-tls created",
+		"Deployment ops/pulsaar-webhook created",
+		"Service ops/pulsaar-webhook created",
+		"MutatingWebhookConfiguration pulsaar-webhook created",
+		"label their namespaces, with pulsaar.io/inject-agent=true",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	ctx := context.Background()
+	secret, err := clientset.CoreV1().Secrets("ops").Get(ctx, "pulsaar-webhook-tls", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reusableCerts(installedCerts(ctx, clientset, "ops", webhookName), serviceDNSNames(webhookName, "ops"), time.Now()) {
+		t.Error("expected a valid serving certificate for the webhook service")
+	}
+	mwc, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, webhookName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mwc.Webhooks) != 3 || *mwc.Webhooks[1].ClientConfig.Service.Path != "/mutate/namespace/true" || *mwc.Webhooks[2].ClientConfig.Service.Path != "/mutate/namespace/false" {
+		t.Errorf("expected a webhook per namespace label value, got %+v", mwc.Webhooks)
+	}
+	hook := mwc.Webhooks[0]
+	if !bytes.Equal(hook.ClientConfig.CABundle, secret.Data["ca.crt"]) || hook.ClientConfig.Service.Namespace != "ops" {
+		t.Errorf("webhook should trust the generated CA and call the ops service, got %+v", hook.ClientConfig)
+	}
+	deploy, err := clientset.AppsV1().Deployments("ops").Get(ctx, webhookName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := deploy.Spec.Template.Spec.Containers[0].Env
+	if len(env) != 3 || env[2] != (corev1.EnvVar{Name: "PULSAAR_AGENT_IMAGE", Value: "registry.internal/pulsaar/agent:1.2.0"}) {
+		t.Errorf("expected the agent image to be passed to the webhook, got %+v", env)
+	}
+
+	// Installing again updates in place and keeps the certificates.
+	out = runInstallCmd(t, "webhook", "--namespace", "ops", "--image", "vrushankpatel/pulsaar-webhook:1.2.0")
+	if !strings.Contains(out, "Namespace ops unchanged") || !strings.Contains(out, "Deployment ops/pulsaar-webhook configured") {
+		t.Errorf("unexpected reinstall output:\n%s", out)
+	}
+	again, _ := clientset.CoreV1().Secrets("ops").Get(ctx, "pulsaar-webhook-tls", metav1.GetOptions{})
+	if !bytes.Equal(again.Data["tls.crt"], secret.Data["tls.crt"]) {
+		t.Error("reinstalling should keep a valid certificate")
+	}
+	deploy, _ = clientset.AppsV1().Deployments("ops").Get(ctx, webhookName, metav1.GetOptions{})
+	if got := deploy.Spec.Template.Spec.Containers[0].Image; got != "vrushankpatel/pulsaar-webhook:1.2.0" {
+		t.Errorf("expected the image to be updated, got %s", got)
+	}
+
+	runInstallCmd(t, "webhook", "--namespace", "ops", "--rotate-certs")
+	rotated, _ := clientset.CoreV1().Secrets("ops").Get(ctx, "pulsaar-webhook-tls", metav1.GetOptions{})
+	if bytes.Equal(rotated.Data["tls.crt"], secret.Data["tls.crt"]) {
+		t.Error("--rotate-certs should replace the certificate")
+	}
+	rolled, _ := clientset.AppsV1().Deployments("ops").Get(ctx, webhookName, metav1.GetOptions{})
+	if rolled.Spec.Template.Annotations["pulsaar.io/tls-cert-sha256"] == deploy.Spec.Template.Annotations["pulsaar.io/tls-cert-sha256"] {
+		t.Error("rotating the certificate should roll the webhook pods")
+	}
+}
+
+func TestInstallAggregatorDryRun(t *testing.T) {
+	original := installClientset
+	t.Cleanup(func() { installClientset = original })
+	installClientset = func() (kubernetes.Interface, error) {
+		t.Fatal("dry run must not contact the cluster")
+		return nil, nil
+	}
+
+	out := runInstallCmd(t, "aggregator", "--dry-run")
+	if got := strings.Count(out, "\n---\n"); got != 4 {
+		t.Errorf("expected 5 documents, got %d separators:\n%s", got+1, out)
+	}
+	for _, want := range []string{
+		"kind: Namespace",
+		"kind: Secret",
+		"type: kubernetes.io/tls",
+		"name: PULSAAR_AGGREGATOR_TLS_CERT",
+		"image: vrushankpatel/pulsaar-aggregator:latest",
+		"kind: Service",
+		"namespace: pulsaar-system",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "MutatingWebhookConfiguration") {
+		t.Error("the aggregator has no webhook configuration")
+	}
+}
+
+func TestInstallRejectsUnknownComponent(t *testing.T) {
+	cmd := newInstallCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"controller"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "invalid argument") {
+		t.Errorf("expected an invalid argument error, got %v", err)
+	}
+}
+
+func TestInstallWebhookImageVerification(t *testing.T) {
+	t.Setenv("PULSAAR_CONFIG", "")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyFile := filepath.Join(t.TempDir(), "cosign.pub")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runInstallCmd(t, "webhook", "--dry-run", "--cosign-key", keyFile)
+	if !strings.Contains(out, "name: PULSAAR_COSIGN_KEY") || !strings.Contains(out, "BEGIN PUBLIC KEY") {
+		t.Errorf("expected the cosign key to be passed to the webhook:\n%s", out)
+	}
+
+	cmd := newInstallCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"webhook", "--dry-run", "--cosign-identity", ".*@example.com"})
+	err = cmd.Execute()
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "Fulcio root") {
+		t.Errorf("expected keyless verification without trust roots to be a usage error, got %v", err)
+	}
+}
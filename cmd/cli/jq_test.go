@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func TestReadJQ(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{
+		"var/log/app.jsonl": {Data: []byte("{\"level\":\"warn\",\"msg\":\"slow\",\"took_ms\":912}\n{\"level\":\"info\",\"msg\":\"ok\"}\n")},
+	})
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/var/log/app.jsonl"})
+	cmd.Flags().String("jq", ".msg, .took_ms", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"slow\"\n912\n\"ok\"\nnull\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestReadJQUsage(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{})
+	lastOptions = client.Options{}
+	for _, tt := range []struct {
+		filter    string
+		clipboard bool
+		want      string
+	}{
+		{".msg | length", false, "invalid filter"},
+		{".msg", true, "--jq cannot be combined with --clipboard"},
+	} {
+		cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/var/log/app.jsonl"})
+		cmd.Flags().String("jq", tt.filter, "")
+		cmd.Flags().Bool("clipboard", tt.clipboard, "")
+		err := runRead(cmd, nil)
+		if exitCode(err) != exitUsage || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected a usage error containing %q, got %v", tt.filter, tt.want, err)
+		}
+	}
+	if lastOptions.Pod != "" {
+		t.Error("expected usage errors before connecting")
+	}
+}
@@ -3,31 +3,26 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"os/signal"
+	pathpkg "path"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/protobuf/types/known/emptypb"
-	authenticationv1 "k8s.io/api/authentication/v1"
-	authorizationv1 "k8s.io/api/authorization/v1"
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
-	api "github.com/VrushankPatel/pulsaar/api"
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+	"github.com/VrushankPatel/pulsaar/pkg/jq"
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
 )
 
 var (
@@ -50,254 +45,181 @@ func isBinary(data []byte) bool {
 	return ratio > 0.05
 }
 
-func getConfig() (*rest.Config, error) {
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		kubeconfig := os.Getenv("KUBECONFIG")
-		if kubeconfig == "" {
-			kubeconfig = filepath.Join(os.Getenv("HOME"), ".kube", "config")
-		}
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return config, nil
+func createTLSConfig() (*tls.Config, error) {
+	return client.TLSConfigFromEnv()
 }
 
-func getClientset() (*kubernetes.Clientset, error) {
-	config, err := getConfig()
-	if err != nil {
-		return nil, err
-	}
-	return kubernetes.NewForConfig(config)
-}
+// connect is replaced in tests to reach an in-memory agent.
+var connect = client.Connect
 
-func getProxyURL(namespace, podName string) (string, error) {
-	config, err := getConfig()
-	if err != nil {
-		return "", err
-	}
-	return config.Host + "/api/v1/namespaces/" + namespace + "/pods/" + podName + "/proxy/", nil
+// requireTarget adds --node to a command that takes --pod. At most one may
+// be given; with neither, a pod picker runs in interactive terminals.
+func requireTarget(cmd *cobra.Command) {
+	cmd.Flags().String("node", "", "Node name; targets the node's host-mode agent instead of a pod")
+	cmd.MarkFlagsMutuallyExclusive("pod", "node")
+	cmd.PreRunE = ensureTarget
 }
 
-func checkUserAccess(namespace, pod string) error {
-	config, err := getConfig()
-	if err != nil {
-		return fmt.Errorf("unable to connect to Kubernetes cluster. Please check your kubeconfig or in-cluster configuration. Error: %v", err)
+// describeTarget names the pod or node a command works on, for messages.
+func describeTarget(cmd *cobra.Command, namespace, pod string) string {
+	if node, _ := cmd.Flags().GetString("node"); node != "" {
+		return "node " + node
 	}
+	return fmt.Sprintf("pod %s/%s", namespace, pod)
+}
 
-	token := config.BearerToken
-	if token == "" {
-		return fmt.Errorf("RBAC enforcement requires token-based authentication. Ensure you are using a token-based auth method (e.g., not client certs)")
-	}
+const (
+	// defaultAccessCacheTTL bounds how long a successful RBAC check is reused.
+	defaultAccessCacheTTL = time.Minute
+	// defaultInjectTimeout matches the client's wait for an injected agent.
+	defaultInjectTimeout = 30 * time.Second
+)
 
-	clientset, err := kubernetes.NewForConfig(config)
+func connectToAgent(cmd *cobra.Command, pod, namespace string, allowedRoots ...string) (*client.Client, error) {
+	opts, err := agentOptions(cmd, pod, namespace, allowedRoots...)
 	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client. Verify your cluster connection and credentials. Error: %v", err)
+		return nil, err
 	}
-
-	// TokenReview
-	tr := &authenticationv1.TokenReview{
-		Spec: authenticationv1.TokenReviewSpec{
-			Token: token,
-		},
+	// Tie the port-forward to the command so it stops with it.
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	result, err := clientset.AuthenticationV1().TokenReviews().Create(context.TODO(), tr, metav1.CreateOptions{})
+	c, err := connect(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("failed to validate authentication token. Check your token and cluster connectivity. Error: %v", err)
+		return nil, err
 	}
-	if !result.Status.Authenticated {
-		return fmt.Errorf("token authentication failed. Please verify your token is valid and not expired")
+	if err := checkAgentVersion(ctx, cmd, c, describeTarget(cmd, namespace, pod)); err != nil {
+		_ = c.Close()
+		return nil, err
 	}
+	return c, nil
+}
 
-	user := result.Status.User.Username
+// proxySetting is the --proxy flag or $PULSAAR_PROXY, for Kubernetes
+// clients built outside a connection.
+var proxySetting string
 
-	// SubjectAccessReview
-	sar := &authorizationv1.SubjectAccessReview{
-		Spec: authorizationv1.SubjectAccessReviewSpec{
-			ResourceAttributes: &authorizationv1.ResourceAttributes{
-				Namespace: namespace,
-				Verb:      "get",
-				Resource:  "pods",
-				Name:      pod,
-			},
-			User:   user,
-			Groups: result.Status.User.Groups,
-		},
-	}
-	sarResult, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to check RBAC permissions. Ensure you have the necessary permissions to access pods. Error: %v", err)
+func setupProxy(cmd *cobra.Command) error {
+	proxySetting, _ = cmd.Flags().GetString("proxy")
+	if proxySetting == "" {
+		return nil
 	}
-	if !sarResult.Status.Allowed {
-		return fmt.Errorf("access denied to pod %s/%s. Check your RBAC permissions for 'get' verb on pods in namespace %s", namespace, pod, namespace)
+	if _, err := client.ParseProxy(proxySetting); err != nil {
+		return &usageError{err}
 	}
-
 	return nil
 }
 
-func injectEphemeralContainer(podName, namespace string) error {
-	clientset, err := getClientset()
-	if err != nil {
-		return fmt.Errorf("failed to create k8s client: %v", err)
-	}
-
-	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to get pod: %v", err)
-	}
-
-	// Check if already has pulsaar-agent container
-	for _, c := range pod.Spec.Containers {
-		if c.Name == "pulsaar-agent" {
-			return nil
-		}
-	}
-	for _, ec := range pod.Spec.EphemeralContainers {
-		if ec.Name == "pulsaar-agent" {
-			return nil
-		}
-	}
-
-	// Add ephemeral container
-	image := os.Getenv("PULSAAR_AGENT_IMAGE")
-	if image == "" {
-		image = "pulsaar/agent:latest"
-	}
-
-	ephemeralContainer := corev1.EphemeralContainer{
-		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
-			Name:  "pulsaar-agent",
-			Image: image,
-			Ports: []corev1.ContainerPort{
-				{
-					ContainerPort: 50051,
-					Name:          "grpc",
-				},
-			},
-		},
-	}
-
-	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, ephemeralContainer)
-
-	// Patch the pod
-	_, err = clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(context.TODO(), podName, pod, metav1.UpdateOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to update ephemeral containers: %v", err)
-	}
+// asSetting and asGroupsSetting are --as and --as-group, for Kubernetes
+// clients built outside a connection.
+var (
+	asSetting       string
+	asGroupsSetting []string
+)
 
-	// Wait for the container to be running
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	err = wait.PollUntilContextTimeout(ctx, 1*time.Second, 30*time.Second, true, func(ctx context.Context) (bool, error) {
-		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
-		if err != nil {
-			return false, err
-		}
-		for _, status := range pod.Status.EphemeralContainerStatuses {
-			if status.Name == "pulsaar-agent" && status.State.Running != nil {
-				return true, nil
-			}
-		}
-		return false, nil
-	})
-	if err != nil {
-		return fmt.Errorf("failed to wait for ephemeral container: %v", err)
+func setupImpersonation(cmd *cobra.Command) error {
+	asSetting, _ = cmd.Flags().GetString("as")
+	asGroupsSetting, _ = cmd.Flags().GetStringArray("as-group")
+	if asSetting == "" && len(asGroupsSetting) > 0 {
+		return &usageError{fmt.Errorf("--as-group needs --as")}
 	}
-
 	return nil
 }
 
-func createTLSConfig() (*tls.Config, error) {
-	config := &tls.Config{
-		InsecureSkipVerify: true, // Default for MVP port-forward
-	}
-
-	clientCertFile := os.Getenv("PULSAAR_CLIENT_CERT_FILE")
-	clientKeyFile := os.Getenv("PULSAAR_CLIENT_KEY_FILE")
-	caFile := os.Getenv("PULSAAR_CA_FILE")
+// kubeNamespace is client.KubeNamespace. Tests replace it.
+var kubeNamespace = client.KubeNamespace
 
-	if clientCertFile != "" && clientKeyFile != "" {
-		cert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load client cert: %v", err)
-		}
-		config.Certificates = []tls.Certificate{cert}
-		config.InsecureSkipVerify = false // Use proper verification if client cert provided
+// setupNamespace makes the kubeconfig context's namespace the default of a
+// command's --namespace flag, as kubectl does. Flags whose default is not
+// "default", such as filters and the install namespace, keep theirs.
+func setupNamespace(cmd *cobra.Command) {
+	flag := cmd.Flags().Lookup("namespace")
+	if flag == nil || flag.Changed || flag.DefValue != "default" {
+		return
 	}
-
-	if caFile != "" {
-		caCert, err := os.ReadFile(caFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read CA file: %v", err)
-		}
-		caCertPool := x509.NewCertPool()
-		if !caCertPool.AppendCertsFromPEM(caCert) {
-			return nil, fmt.Errorf("failed to parse CA certificate")
-		}
-		config.RootCAs = caCertPool
-		config.InsecureSkipVerify = false
-	}
-
-	return config, nil
+	_ = flag.Value.Set(kubeNamespace())
 }
 
-func connectToAgent(cmd *cobra.Command, pod, namespace string) (*grpc.ClientConn, func(), error) {
-	connectionMethod, _ := cmd.Flags().GetString("connection-method")
-	tlsConfig, err := createTLSConfig()
+// kubeConfig is client.KubeConfig, routed through proxySetting and acting
+// as asSetting when set.
+func kubeConfig() (*rest.Config, error) {
+	config, err := client.KubeConfig()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create TLS configuration. Check your certificate files and environment variables (PULSAAR_CLIENT_CERT_FILE, PULSAAR_CLIENT_KEY_FILE, PULSAAR_CA_FILE). Error: %v", err)
+		return nil, err
 	}
-
-	// Inject ephemeral container if needed
-	err = injectEphemeralContainer(pod, namespace)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to inject Pulsaar agent into pod %s/%s. Ensure the pod supports ephemeral containers and you have permissions to update pods. Error: %v", namespace, pod, err)
+	if config, err = client.WithProxy(config, proxySetting); err != nil {
+		return nil, err
 	}
+	return client.WithImpersonation(config, asSetting, asGroupsSetting)
+}
 
-	switch connectionMethod {
-	case "port-forward":
-		// Find a free local port
-		lis, err := net.Listen("tcp", ":0")
-		if err != nil {
-			return nil, nil, fmt.Errorf("unable to find a free local port for port-forwarding. This may indicate too many open connections. Error: %v", err)
-		}
-		localPort := lis.Addr().(*net.TCPAddr).Port
-		if err := lis.Close(); err != nil {
-			return nil, nil, fmt.Errorf("failed to close temporary listener. Error: %v", err)
-		}
-
-		// Start kubectl port-forward
-		kubectlCmd := exec.Command("kubectl", "port-forward", fmt.Sprintf("%s/%s", namespace, pod), fmt.Sprintf("%d:50051", localPort))
-		err = kubectlCmd.Start()
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to start kubectl port-forward. Ensure kubectl is installed, accessible, and you have permissions to port-forward to the pod. Error: %v", err)
-		}
-
-		// Wait for port-forward to be ready
-		time.Sleep(2 * time.Second)
-
-		conn, err := grpc.NewClient(fmt.Sprintf("localhost:%d", localPort), grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
-		if err != nil {
-			_ = kubectlCmd.Process.Kill()
-			return nil, nil, fmt.Errorf("failed to establish gRPC connection via port-forward. Check TLS configuration and agent availability. Error: %v", err)
-		}
-
-		return conn, func() { _ = kubectlCmd.Process.Kill() }, nil
-	case "apiserver-proxy":
-		proxyURL, err := getProxyURL(namespace, pod)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to construct apiserver proxy URL. Verify cluster configuration. Error: %v", err)
+// agentOptions resolves the connection flags, environment and config file
+// into client options for pod.
+func agentOptions(cmd *cobra.Command, pod, namespace string, allowedRoots ...string) (client.Options, error) {
+	connectionMethod, _ := cmd.Flags().GetString("connection-method")
+	ttl, _ := cmd.Flags().GetDuration("session-ttl")
+	accessTTL, _ := cmd.Flags().GetDuration("access-cache-ttl")
+	skipAccessCheck, _ := cmd.Flags().GetBool("skip-access-check")
+	noInject, _ := cmd.Flags().GetBool("no-inject")
+	agentPort, _ := cmd.Flags().GetInt("agent-port")
+	agentImage, defaultImages, err := agentImages(cmd)
+	if err != nil {
+		return client.Options{}, err
+	}
+	injectTimeout, _ := cmd.Flags().GetDuration("inject-timeout")
+	var progress io.Writer
+	if format, _ := cmd.Flags().GetString("error-format"); format != errorFormatJSON && showProgress(cmd.ErrOrStderr()) {
+		progress = cmd.ErrOrStderr()
+	}
+	node, _ := cmd.Flags().GetString("node")
+	targetContainer, _ := cmd.Flags().GetString("target-container")
+	agentCPU, _ := cmd.Flags().GetString("agent-cpu")
+	agentMemory, _ := cmd.Flags().GetString("agent-memory")
+	agentPrivilege, _ := cmd.Flags().GetString("agent-privilege")
+	if agentPrivilege != "" {
+		if err := privilege.Validate(agentPrivilege); err != nil {
+			return client.Options{}, &usageError{err}
 		}
-		conn, err := grpc.NewClient(proxyURL, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to establish gRPC connection via apiserver proxy. Check TLS configuration and agent availability. Error: %v", err)
+	}
+	identityTokenFile, _ := cmd.Flags().GetString("identity-token-file")
+	proxy, _ := cmd.Flags().GetString("proxy")
+	if proxy != "" {
+		if _, err := client.ParseProxy(proxy); err != nil {
+			return client.Options{}, &usageError{err}
 		}
-		return conn, func() {}, nil
-	default:
-		return nil, nil, fmt.Errorf("unknown connection method '%s'. Supported methods: port-forward, apiserver-proxy", connectionMethod)
 	}
+	if targetContainer != "" && (noInject || node != "") {
+		return client.Options{}, &usageError{fmt.Errorf("--target-container needs an injected agent; it cannot be used with --no-inject or --node")}
+	}
+	if node != "" && !cmd.Flags().Changed("namespace") {
+		// Let the client use the host agents' namespace.
+		namespace = ""
+	}
+	return client.Options{
+		Namespace:          namespace,
+		Pod:                pod,
+		Node:               node,
+		ConnectionMethod:   connectionMethod,
+		AllowedRoots:       allowedRoots,
+		SkipAccessCheck:    skipAccessCheck,
+		SkipInjection:      noInject,
+		TargetContainer:    targetContainer,
+		AgentCPU:           agentCPU,
+		AgentMemory:        agentMemory,
+		AgentPrivilege:     agentPrivilege,
+		AgentPort:          agentPort,
+		AgentImage:         agentImage,
+		DefaultAgentImages: defaultImages,
+		InjectTimeout:      injectTimeout,
+		Progress:           progress,
+		AccessCacheTTL:     accessTTL,
+		SessionTTL:         ttl,
+		IdentityTokenFile:  identityTokenFile,
+		Proxy:              proxy,
+		As:                 asSetting,
+		AsGroups:           asGroupsSetting,
+	}, nil
 }
 
 func main() {
@@ -312,12 +234,22 @@ func main() {
 		RunE:  runExplore,
 	}
 
-	exploreCmd.Flags().String("pod", "", "Pod name")
+	exploreCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
 	exploreCmd.Flags().String("namespace", "default", "Namespace")
 	exploreCmd.Flags().String("path", "/", "Path to explore")
-	if err := exploreCmd.MarkFlagRequired("pod"); err != nil {
-		panic(err)
-	}
+	exploreCmd.Flags().StringP("output", "o", "text", "Output format: text, csv or tsv")
+	exploreCmd.Flags().BoolP("long", "l", false, "Long text listing like ls -l, with hard links, owner, group and symlink targets")
+	exploreCmd.Flags().Bool("unsorted", false, "Print entries in directory order as the agent reads them, for huge directories")
+	exploreCmd.Flags().String("sort", "name", "Sort entries by name, size or mtime")
+	exploreCmd.Flags().Bool("reverse", false, "Reverse the sort order")
+	exploreCmd.Flags().String("name", "", "Only list entries whose name matches this glob, e.g. '*.log'")
+	exploreCmd.Flags().String("min-size", "", "Only list entries of at least this size, e.g. 500, 10Ki or 1Gi")
+	exploreCmd.Flags().Duration("since", 0, "Only list entries modified within this window, e.g. 24h")
+	exploreCmd.Flags().Bool("watch", false, "Keep listing the directory and print entries that were added (+), removed (-) or changed (~)")
+	exploreCmd.Flags().Duration("interval", 5*time.Second, "How often --watch lists the directory")
+	exploreCmd.MarkFlagsMutuallyExclusive("unsorted", "sort")
+	exploreCmd.MarkFlagsMutuallyExclusive("unsorted", "reverse")
+	requireTarget(exploreCmd)
 
 	readCmd := &cobra.Command{
 		Use:   "read",
@@ -325,12 +257,15 @@ func main() {
 		RunE:  runRead,
 	}
 
-	readCmd.Flags().String("pod", "", "Pod name")
+	readCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
 	readCmd.Flags().String("namespace", "default", "Namespace")
 	readCmd.Flags().String("path", "", "Path to file")
-	if err := readCmd.MarkFlagRequired("pod"); err != nil {
-		panic(err)
-	}
+	readCmd.Flags().Bool("clipboard", false, "Copy the contents to the system clipboard instead of printing them (text files up to 256KB)")
+	readCmd.Flags().Bool("decompress", false, "Decompress gzip, zstd or bzip2 files on the agent")
+	readCmd.Flags().String("jq", "", "Filter each line of a JSON-lines file on the agent, e.g. '.level,.msg' or '{level, msg}'")
+	readCmd.Flags().StringArray("range", nil, "Read only OFFSET:LENGTH, e.g. 0:4Ki or -4Ki:4Ki for the last 4KiB; repeat to sample several ranges in one call")
+	readCmd.Flags().Bool("raw", false, rawUsage)
+	requireTarget(readCmd)
 	if err := readCmd.MarkFlagRequired("path"); err != nil {
 		panic(err)
 	}
@@ -341,13 +276,14 @@ func main() {
 		RunE:  runStream,
 	}
 
-	streamCmd.Flags().String("pod", "", "Pod name")
+	streamCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
 	streamCmd.Flags().String("namespace", "default", "Namespace")
 	streamCmd.Flags().String("path", "", "Path to file")
 	streamCmd.Flags().Int64("chunk-size", 64*1024, "Chunk size in bytes")
-	if err := streamCmd.MarkFlagRequired("pod"); err != nil {
-		panic(err)
-	}
+	streamCmd.Flags().Bool("decompress", false, "Decompress gzip, zstd or bzip2 files on the agent")
+	streamCmd.Flags().Bool("no-verify", false, "Skip comparing the SHA-256 of the streamed bytes with the agent's checksum of the file")
+	streamCmd.Flags().Bool("raw", false, rawUsage)
+	requireTarget(streamCmd)
 	if err := streamCmd.MarkFlagRequired("path"); err != nil {
 		panic(err)
 	}
@@ -358,12 +294,10 @@ func main() {
 		RunE:  runStat,
 	}
 
-	statCmd.Flags().String("pod", "", "Pod name")
+	statCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
 	statCmd.Flags().String("namespace", "default", "Namespace")
 	statCmd.Flags().String("path", "", "Path to file or directory")
-	if err := statCmd.MarkFlagRequired("pod"); err != nil {
-		panic(err)
-	}
+	requireTarget(statCmd)
 	if err := statCmd.MarkFlagRequired("path"); err != nil {
 		panic(err)
 	}
@@ -371,20 +305,38 @@ func main() {
 	healthCmd := &cobra.Command{
 		Use:   "health",
 		Short: "Check health of a Pulsaar agent",
-		RunE:  runHealth,
+		Example: `  pulsaar health --pod web-0
+  pulsaar health --selector app=web
+  pulsaar health --all --namespace prod`,
+		RunE: runHealth,
 	}
 
-	healthCmd.Flags().String("pod", "", "Pod name")
+	healthCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
 	healthCmd.Flags().String("namespace", "default", "Namespace")
-	if err := healthCmd.MarkFlagRequired("pod"); err != nil {
-		panic(err)
-	}
+	requireTarget(healthCmd)
+	addFleetHealthFlags(healthCmd)
 
 	rootCmd.AddCommand(exploreCmd)
 	rootCmd.AddCommand(readCmd)
 	rootCmd.AddCommand(streamCmd)
 	rootCmd.AddCommand(statCmd)
 	rootCmd.AddCommand(healthCmd)
+	rootCmd.AddCommand(newAuditCmd())
+	rootCmd.AddCommand(newPolicyCmd())
+	rootCmd.AddCommand(newAccessCmd())
+	rootCmd.AddCommand(newSessionCmd())
+	rootCmd.AddCommand(newSearchCmd())
+	rootCmd.AddCommand(newFindCmd())
+	rootCmd.AddCommand(newCompareCmd())
+	rootCmd.AddCommand(newPreviewCmd())
+	rootCmd.AddCommand(newTailCmd())
+	rootCmd.AddCommand(newEditCmd())
+	rootCmd.AddCommand(newSyncCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newInstallCmd())
+	rootCmd.AddCommand(newInjectCmd())
+	rootCmd.AddCommand(newDebugCmd())
+	rootCmd.AddCommand(newUpdateCmd())
 
 	completionCmd := &cobra.Command{
 		Use:   "completion [bash|zsh|fish|powershell]",
@@ -483,10 +435,49 @@ PowerShell:
 
 	rootCmd.AddCommand(versionCmd)
 
-	rootCmd.Flags().String("connection-method", "port-forward", "Connection method: port-forward or apiserver-proxy")
+	rootCmd.PersistentFlags().Duration("access-cache-ttl", defaultAccessCacheTTL, "Reuse a successful RBAC check for this long; 0 checks every time (default $PULSAAR_ACCESS_CACHE_TTL or 1m)")
+	rootCmd.PersistentFlags().Bool("skip-access-check", false, "Skip the SelfSubjectAccessReview check, e.g. for automation accounts already scoped by RBAC")
+	rootCmd.PersistentFlags().Bool("no-inject", false, "Connect to an agent already running in the pod, e.g. a webhook-injected sidecar, instead of injecting one (default $PULSAAR_NO_INJECT)")
+	rootCmd.PersistentFlags().String("agent-image", "", "Agent image to inject, e.g. registry.internal/pulsaar/agent@sha256:<digest> (default from the config file, $PULSAAR_AGENT_IMAGE or pulsaar/agent:latest)")
+	rootCmd.PersistentFlags().String("agent-cpu", "", "CPU cap for an injected agent, rounded up to whole cores; 0 for none (default $PULSAAR_AGENT_CPU or "+client.DefaultAgentCPU+")")
+	rootCmd.PersistentFlags().String("agent-memory", "", "Soft memory limit for an injected agent, e.g. 256Mi; 0 for none (default $PULSAAR_AGENT_MEMORY or "+client.DefaultAgentMemory+")")
+	rootCmd.PersistentFlags().String("agent-privilege", "", "What an injected agent may read on Linux: non-root, dac-read-search to bypass file permissions, or root (default $PULSAAR_AGENT_PRIVILEGE or non-root)")
+	rootCmd.PersistentFlags().String("target-container", "", "Inject the agent into this container's process namespace and read its filesystem instead of the agent image's (Linux pods)")
+	rootCmd.PersistentFlags().Duration("inject-timeout", defaultInjectTimeout, "How long to wait for an injected agent to start, e.g. 2m for slow image pulls (default $PULSAAR_INJECT_TIMEOUT or 30s)")
+	rootCmd.PersistentFlags().Int("agent-port", 0, "Agent port in the pod (default $PULSAAR_AGENT_PORT or 50051)")
+	rootCmd.PersistentFlags().Bool("strict-version", false, "Refuse to use an agent whose major version differs from the CLI's instead of warning (default $PULSAAR_STRICT_VERSION)")
+	rootCmd.PersistentFlags().String("identity-token-file", "", "ServiceAccount token issued for the \"pulsaar\" audience, sent so the agent audits requests under the ServiceAccount's name (default $PULSAAR_IDENTITY_TOKEN_FILE)")
+	rootCmd.PersistentFlags().Duration("session-ttl", 0, "Keep the agent connection open and reuse it for this long, e.g. 10m (default $PULSAAR_SESSION_TTL)")
+	rootCmd.PersistentFlags().String("proxy", "", "HTTP, HTTPS or SOCKS5 proxy for Kubernetes API calls and agent connections, e.g. socks5://localhost:1080 (default $PULSAAR_PROXY, else $HTTPS_PROXY)")
+	rootCmd.PersistentFlags().String("as", "", "Act as this user for Kubernetes API calls and the access check, like kubectl --as, to see what they could reach")
+	rootCmd.PersistentFlags().StringArray("as-group", nil, "Group to act as alongside --as; repeat for several")
+	rootCmd.PersistentFlags().String("connection-method", "port-forward", "Connection method: port-forward or apiserver-proxy")
+
+	rootCmd.PersistentFlags().String("error-format", errorFormatText, "Error output: text, or json for one {\"error\",\"type\",\"exit_code\"} object on stderr")
+	rootCmd.PersistentFlags().Bool("no-color", false, "Never color output; it is only colored on terminals, and not when NO_COLOR is set")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := bindFlagEnv(cmd); err != nil {
+			_ = setupErrorFormat(cmd)
+			return err
+		}
+		if err := setupErrorFormat(cmd); err != nil {
+			return err
+		}
+		setupColor(cmd)
+		if err := setupImpersonation(cmd); err != nil {
+			return err
+		}
+		setupNamespace(cmd)
+		return setupProxy(cmd)
+	}
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		_ = setupErrorFormat(cmd)
+		return &usageError{err}
+	})
 
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+		format, _ := rootCmd.PersistentFlags().GetString("error-format")
+		os.Exit(reportError(format, err, os.Stderr))
 	}
 }
 
@@ -494,122 +485,246 @@ func runExplore(cmd *cobra.Command, args []string) error {
 	pod, _ := cmd.Flags().GetString("pod")
 	namespace, _ := cmd.Flags().GetString("namespace")
 	path, _ := cmd.Flags().GetString("path")
-
-	err := checkUserAccess(namespace, pod)
+	output, _ := cmd.Flags().GetString("output")
+	if err := validateListingFormat(output); err != nil {
+		return err
+	}
+	opts, err := listOptions(cmd)
 	if err != nil {
 		return err
 	}
+	long, _ := cmd.Flags().GetBool("long")
+	if long && output != "" && output != "text" {
+		return fmt.Errorf("--long is a text listing; -o %s already includes the owner", output)
+	}
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if watch && interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	if watch && output != "" && output != "text" {
+		return fmt.Errorf("--watch prints a text diff; it cannot be combined with -o %s", output)
+	}
 
-	conn, cleanup, err := connectToAgent(cmd, pod, namespace)
+	c, err := connectToAgent(cmd, pod, namespace)
 	if err != nil {
 		return err
 	}
-	defer cleanup()
-	defer func() { _ = conn.Close() }()
-
-	client := api.NewPulsaarAgentClient(conn)
-
-	resp, err := client.ListDirectory(context.Background(), &api.ListRequest{
-		Path:         path,
-		AllowedRoots: []string{},
+	defer func() { _ = c.Close() }()
+
+	list := c.ListPages
+	if unsorted, _ := cmd.Flags().GetBool("unsorted"); unsorted {
+		list = c.ListStream
+	}
+	if watch {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		err = watchListing(ctx, func(ctx context.Context) ([]*api.FileInfo, error) {
+			var entries []*api.FileInfo
+			err := list(ctx, path, opts, func(page []*api.FileInfo) error {
+				entries = append(entries, page...)
+				return nil
+			})
+			return entries, err
+		}, interval, cmd.OutOrStdout())
+		if err != nil {
+			return fmt.Errorf("failed to watch directory '%s' in %s. Error: %w", path, describeTarget(cmd, namespace, pod), err)
+		}
+		return nil
+	}
+	header := true
+	err = list(context.Background(), path, opts, func(entries []*api.FileInfo) error {
+		if long {
+			return writeLongListing(cmd.OutOrStdout(), entries)
+		}
+		err := writeListingPage(cmd.OutOrStdout(), output, entries, header)
+		header = false
+		return err
 	})
 	if err != nil {
-		return fmt.Errorf("failed to list directory '%s' in pod %s/%s. This may be due to permission restrictions, invalid path, or agent connectivity issues. Error: %v", path, namespace, pod, err)
+		return fmt.Errorf("failed to list directory '%s' in %s. This may be due to permission restrictions, invalid path, or agent connectivity issues. Error: %w", path, describeTarget(cmd, namespace, pod), err)
 	}
+	return nil
+}
 
-	for _, entry := range resp.Entries {
-		fmt.Printf("%s %s %d %s\n", entry.Mode, entry.Name, entry.SizeBytes, entry.Mtime.AsTime().Format("2006-01-02 15:04:05"))
+// listOptions returns the sorting and filtering flags of explore.
+func listOptions(cmd *cobra.Command) (client.ListOptions, error) {
+	var opts client.ListOptions
+	opts.SortBy, _ = cmd.Flags().GetString("sort")
+	switch opts.SortBy {
+	case "", "name":
+		opts.SortBy = ""
+	case "size", "mtime":
+	default:
+		return opts, &usageError{fmt.Errorf("invalid --sort %q; use name, size or mtime", opts.SortBy)}
 	}
-
-	return nil
+	opts.Descending, _ = cmd.Flags().GetBool("reverse")
+	opts.NameGlob, _ = cmd.Flags().GetString("name")
+	if _, err := pathpkg.Match(opts.NameGlob, ""); err != nil {
+		return opts, &usageError{fmt.Errorf("invalid --name pattern %q: %w", opts.NameGlob, err)}
+	}
+	if minSize, _ := cmd.Flags().GetString("min-size"); minSize != "" {
+		q, err := resource.ParseQuantity(minSize)
+		if err != nil || q.Sign() < 0 {
+			return opts, &usageError{fmt.Errorf("invalid --min-size %q; use a byte count such as 500, 10Ki or 1Gi", minSize)}
+		}
+		opts.MinSize = q.Value()
+	}
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		opts.ModifiedSince = time.Now().Add(-since)
+	}
+	return opts, nil
 }
 
 func runRead(cmd *cobra.Command, args []string) error {
 	pod, _ := cmd.Flags().GetString("pod")
 	namespace, _ := cmd.Flags().GetString("namespace")
 	path, _ := cmd.Flags().GetString("path")
-
-	err := checkUserAccess(namespace, pod)
+	filter, _ := cmd.Flags().GetString("jq")
+	clipboard, _ := cmd.Flags().GetBool("clipboard")
+	decompress, _ := cmd.Flags().GetBool("decompress")
+	if filter != "" {
+		if clipboard {
+			return &usageError{errors.New("--jq cannot be combined with --clipboard")}
+		}
+		if _, err := jq.Parse(filter); err != nil {
+			return &usageError{err}
+		}
+	}
+	ranges, err := rangeFlags(cmd)
 	if err != nil {
 		return err
 	}
+	if len(ranges) > 0 && (filter != "" || clipboard || decompress) {
+		return &usageError{errors.New("--range cannot be combined with --jq, --clipboard or --decompress")}
+	}
 
-	conn, cleanup, err := connectToAgent(cmd, pod, namespace)
+	c, err := connectToAgent(cmd, pod, namespace)
 	if err != nil {
 		return err
 	}
-	defer cleanup()
-	defer func() { _ = conn.Close() }()
+	defer func() { _ = c.Close() }()
 
-	client := api.NewPulsaarAgentClient(conn)
+	if len(ranges) > 0 {
+		parts, err := c.ReadRanges(context.Background(), path, ranges)
+		if err != nil {
+			return fmt.Errorf("failed to read ranges of file '%s' in %s. Check if the file exists, is within allowed paths, and the ranges total at most 1MB. Error: %w", path, describeTarget(cmd, namespace, pod), err)
+		}
+		if rawOutput(cmd) {
+			return writeRawParts(cmd.OutOrStdout(), parts)
+		}
+		return writeParts(cmd.OutOrStdout(), cmd.ErrOrStderr(), parts)
+	}
 
-	resp, err := client.ReadFile(context.Background(), &api.ReadRequest{
-		Path:         path,
-		Offset:       0,
-		Length:       0, // read up to max
-		AllowedRoots: []string{},
-	})
+	if filter != "" {
+		if err := c.StreamFiltered(context.Background(), path, filter, decompress, cmd.OutOrStdout()); err != nil {
+			return fmt.Errorf("failed to filter file '%s' in %s. Check that it is a JSON-lines file within allowed paths. Error: %w", path, describeTarget(cmd, namespace, pod), err)
+		}
+		return nil
+	}
+
+	read := c.Read
+	if decompress {
+		read = c.ReadDecompressed
+	}
+	resp, err := read(context.Background(), path, 0, 0) // read up to max
 	if err != nil {
-		return fmt.Errorf("failed to read file '%s' in pod %s/%s. Check if the file exists, is within allowed paths, and you have read permissions. Error: %v", path, namespace, pod, err)
+		return fmt.Errorf("failed to read file '%s' in %s. Check if the file exists, is within allowed paths, and you have read permissions. Error: %w", path, describeTarget(cmd, namespace, pod), err)
 	}
 
-	if isBinary(resp.Data) {
-		fmt.Println("Warning: This file appears to be binary. Output may be corrupted.")
+	if clipboard {
+		return readToClipboard(cmd, path, resp)
+	}
+
+	raw := rawOutput(cmd)
+	if isBinary(resp.Data) && !raw {
+		warnf(cmd.ErrOrStderr(), "This file appears to be binary. Output may be corrupted.")
+	}
+	if _, err := cmd.OutOrStdout().Write(resp.Data); err != nil {
+		return err
 	}
-	fmt.Print(string(resp.Data))
-	if !resp.Eof {
-		fmt.Println("\n... (file truncated)")
+	switch {
+	case resp.Eof:
+	case raw:
+		// Stdout holds only file bytes, so say on stderr that they stop
+		// short of the end.
+		warnf(cmd.ErrOrStderr(), "only the first %d bytes of '%s' were read; use pulsaar stream for the whole file.", len(resp.Data), path)
+	default:
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "\n... (file truncated)")
 	}
 
 	return nil
 }
 
+// readToClipboard copies a text file that fits within maxClipboardBytes.
+func readToClipboard(cmd *cobra.Command, path string, resp *api.ReadResponse) error {
+	if !resp.Eof || len(resp.Data) > maxClipboardBytes {
+		return fmt.Errorf("'%s' is larger than the %dKB clipboard limit; use read without --clipboard or preview instead", path, maxClipboardBytes/1024)
+	}
+	if isBinary(resp.Data) {
+		return fmt.Errorf("'%s' appears to be binary and was not copied to the clipboard", path)
+	}
+	if err := copyToClipboard(resp.Data); err != nil {
+		return fmt.Errorf("failed to copy to the clipboard: %v", err)
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Copied %d bytes from '%s' to the clipboard.\n", len(resp.Data), path)
+	return nil
+}
+
 func runStream(cmd *cobra.Command, args []string) error {
 	pod, _ := cmd.Flags().GetString("pod")
 	namespace, _ := cmd.Flags().GetString("namespace")
 	path, _ := cmd.Flags().GetString("path")
 	chunkSize, _ := cmd.Flags().GetInt64("chunk-size")
 
-	err := checkUserAccess(namespace, pod)
+	c, err := connectToAgent(cmd, pod, namespace)
 	if err != nil {
 		return err
 	}
+	defer func() { _ = c.Close() }()
 
-	conn, cleanup, err := connectToAgent(cmd, pod, namespace)
+	ctx := context.Background()
+	var out io.Writer = cmd.OutOrStdout()
+	if !rawOutput(cmd) {
+		out = &binaryWarningWriter{w: out, errOut: cmd.ErrOrStderr()}
+	}
+	stream := c.StreamVerified
+	if decompress, _ := cmd.Flags().GetBool("decompress"); decompress {
+		// The agent's checksum covers the file as stored, not decompressed.
+		stream = c.StreamDecompressed
+	} else if noVerify, _ := cmd.Flags().GetBool("no-verify"); noVerify {
+		stream = c.Stream
+	}
+	err = stream(ctx, path, chunkSize, out)
+	if errors.Is(err, client.ErrChecksum) {
+		warnf(cmd.ErrOrStderr(), "%v; '%s' will not be verified.", err, path)
+		err = c.Stream(ctx, path, chunkSize, out)
+	}
+	var mismatch *client.MismatchError
+	if errors.As(err, &mismatch) {
+		return fmt.Errorf("verification failed: the streamed copy of '%s' in %s does not match the source and must not be trusted; the file may have changed while it was read. %w", path, describeTarget(cmd, namespace, pod), err)
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to stream file '%s' in %s. Ensure the file is readable and within size limits. Error: %w", path, describeTarget(cmd, namespace, pod), err)
 	}
-	defer cleanup()
-	defer func() { _ = conn.Close() }()
 
-	client := api.NewPulsaarAgentClient(conn)
+	return nil
+}
 
-	stream, err := client.StreamFile(context.Background(), &api.StreamRequest{
-		Path:         path,
-		ChunkSize:    chunkSize,
-		AllowedRoots: []string{},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to stream file '%s' in pod %s/%s. Ensure the file is readable and within size limits. Error: %v", path, namespace, pod, err)
-	}
+// binaryWarningWriter prints the binary-content warning to errOut before
+// the first chunk that looks binary.
+type binaryWarningWriter struct {
+	w      io.Writer
+	errOut io.Writer
+	warned bool
+}
 
-	warned := false
-	for {
-		resp, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("error while streaming file '%s': %v", path, err)
-		}
-		if !warned && isBinary(resp.Data) {
-			fmt.Println("Warning: This file appears to be binary. Output may be corrupted.")
-			warned = true
-		}
-		fmt.Print(string(resp.Data))
+func (b *binaryWarningWriter) Write(p []byte) (int, error) {
+	if !b.warned && isBinary(p) {
+		warnf(b.errOut, "This file appears to be binary. Output may be corrupted.")
+		b.warned = true
 	}
-
-	return nil
+	return b.w.Write(p)
 }
 
 func runStat(cmd *cobra.Command, args []string) error {
@@ -617,33 +732,28 @@ func runStat(cmd *cobra.Command, args []string) error {
 	namespace, _ := cmd.Flags().GetString("namespace")
 	path, _ := cmd.Flags().GetString("path")
 
-	err := checkUserAccess(namespace, pod)
+	c, err := connectToAgent(cmd, pod, namespace, "/")
 	if err != nil {
 		return err
 	}
+	defer func() { _ = c.Close() }()
 
-	conn, cleanup, err := connectToAgent(cmd, pod, namespace)
+	info, err := c.Stat(context.Background(), path)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get info for path '%s' in %s. Verify the path exists and is accessible. Error: %w", path, describeTarget(cmd, namespace, pod), err)
 	}
-	defer cleanup()
-	defer func() { _ = conn.Close() }()
-
-	client := api.NewPulsaarAgentClient(conn)
 
-	resp, err := client.Stat(context.Background(), &api.StatRequest{
-		Path:         path,
-		AllowedRoots: []string{"/"},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to get info for path '%s' in pod %s/%s. Verify the path exists and is accessible. Error: %v", path, namespace, pod, err)
+	fmt.Printf("Name: %s\n", info.Name)
+	fmt.Printf("IsDir: %t\n", info.IsDir)
+	if info.FileType != api.FileType_FILE_TYPE_UNSPECIFIED {
+		fmt.Printf("Type: %s\n", fileKind(info))
 	}
-
-	fmt.Printf("Name: %s\n", resp.Info.Name)
-	fmt.Printf("IsDir: %t\n", resp.Info.IsDir)
-	fmt.Printf("Size: %d bytes\n", resp.Info.SizeBytes)
-	fmt.Printf("Mode: %s\n", resp.Info.Mode)
-	fmt.Printf("Modified: %s\n", resp.Info.Mtime.AsTime().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Size: %d bytes\n", info.SizeBytes)
+	fmt.Printf("Mode: %s\n", info.Mode)
+	if info.Owner != "" {
+		fmt.Printf("Owner: %s\n", info.Owner)
+	}
+	fmt.Printf("Modified: %s\n", info.Mtime.AsTime().Format("2006-01-02 15:04:05"))
 
 	return nil
 }
@@ -651,24 +761,19 @@ func runStat(cmd *cobra.Command, args []string) error {
 func runHealth(cmd *cobra.Command, args []string) error {
 	pod, _ := cmd.Flags().GetString("pod")
 	namespace, _ := cmd.Flags().GetString("namespace")
-
-	err := checkUserAccess(namespace, pod)
-	if err != nil {
-		return err
+	if isFleetHealth(cmd) {
+		return runFleetHealth(cmd, namespace)
 	}
 
-	conn, cleanup, err := connectToAgent(cmd, pod, namespace)
+	c, err := connectToAgent(cmd, pod, namespace)
 	if err != nil {
 		return err
 	}
-	defer cleanup()
-	defer func() { _ = conn.Close() }()
+	defer func() { _ = c.Close() }()
 
-	client := api.NewPulsaarAgentClient(conn)
-
-	resp, err := client.Health(context.Background(), &emptypb.Empty{})
+	resp, err := c.Health(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to get health from pod %s/%s. Error: %v", namespace, pod, err)
+		return fmt.Errorf("failed to get health from %s. Error: %w", describeTarget(cmd, namespace, pod), err)
 	}
 
 	fmt.Printf("Ready: %t\n", resp.Ready)
@@ -676,10 +781,81 @@ func runHealth(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Status: %s\n", resp.StatusMessage)
 	fmt.Printf("Commit: %s\n", resp.Commit)
 	fmt.Printf("Date: %s\n", resp.Date)
+	if resp.Privilege != "" {
+		fmt.Printf("Privilege: %s\n", resp.Privilege)
+	}
+	for _, line := range postureLines(resp.Posture) {
+		fmt.Println(line)
+	}
+	caps, err := c.AgentCapabilities(context.Background())
+	if err != nil {
+		return nil
+	}
+	if names := slices.Concat(caps.Rpcs, caps.Features); len(names) > 0 {
+		fmt.Printf("Capabilities: %s\n", strings.Join(names, ", "))
+	}
+	if caps.MaxReadSize > 0 {
+		fmt.Printf("Compression: %s\n", strings.Join(caps.CompressionCodecs, ", "))
+		fmt.Printf("Max read size: %d bytes\n", caps.MaxReadSize)
+		fmt.Printf("Auth modes: %s\n", strings.Join(caps.AuthModes, ", "))
+	}
 
 	return nil
 }
 
+// postureLines describes how an agent is confined, for pulsaar health.
+func postureLines(p *api.SecurityPosture) []string {
+	if p == nil {
+		return nil
+	}
+	var lines []string
+	if p.Uid >= 0 {
+		lines = append(lines, fmt.Sprintf("User: %d, group %d", p.Uid, p.Gid))
+	}
+	if p.Uid >= 0 || len(p.LinuxCapabilities) > 0 {
+		lines = append(lines, "Linux capabilities: "+joinOr(p.LinuxCapabilities, "none"))
+	}
+	sandbox := joinOr(p.Sandbox, "none")
+	if p.SandboxMessage != "" {
+		sandbox += " (" + p.SandboxMessage + ")"
+	}
+	lines = append(lines, "Sandbox: "+sandbox)
+	if len(p.AllowedRoots) > 0 {
+		lines = append(lines, "Allowed roots: "+strings.Join(p.AllowedRoots, ", "))
+	}
+	limit := func(n int64, format string) string {
+		if n == 0 {
+			return "none"
+		}
+		return fmt.Sprintf(format, n)
+	}
+	lines = append(lines,
+		fmt.Sprintf("Request limits: %s per client, %s per request, %s per stream, %s decompressed",
+			limit(int64(p.RequestsPerSecond), "%d/s"),
+			durationLimit(p.RpcTimeoutMs),
+			durationLimit(p.MaxStreamDurationMs),
+			limit(p.MaxDecompressedBytes, "%d bytes")),
+		fmt.Sprintf("Runtime limits: GOMAXPROCS %s, memory %s", limit(int64(p.Gomaxprocs), "%d"), limit(p.MemoryLimitBytes, "%d bytes")),
+	)
+	return lines
+}
+
+// durationLimit formats a limit in milliseconds, 0 meaning none.
+func durationLimit(ms int64) string {
+	if ms == 0 {
+		return "none"
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// joinOr joins values with commas, or returns none when there are none.
+func joinOr(values []string, none string) string {
+	if len(values) == 0 {
+		return none
+	}
+	return strings.Join(values, ", ")
+}
+
 func runMan(cmd *cobra.Command, args []string) error {
 	header := &doc.GenManHeader{
 		Title:   "PULSAAR",
@@ -25,7 +25,7 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
-	api "github.com/VrushankPatel/pulsaar/api"
+	api "github.com/VrushankPatel/pulsaar/api/v1"
 )
 
 func loadOrGenerateCert() (tls.Certificate, error) {
@@ -630,6 +630,41 @@ func TestHealthEndToEnd(t *testing.T) {
 	}
 }
 
+func TestPostureLines(t *testing.T) {
+	lines := postureLines(&api.SecurityPosture{
+		Uid:                  65532,
+		Gid:                  65532,
+		LinuxCapabilities:    []string{"CAP_DAC_READ_SEARCH"},
+		Sandbox:              []string{"seccomp"},
+		SandboxMessage:       "Landlock is not available: function not implemented",
+		AllowedRoots:         []string{"/var/log", "/app"},
+		MaxDecompressedBytes: 1 << 30,
+		RpcTimeoutMs:         30000,
+		RequestsPerSecond:    10,
+		Gomaxprocs:           1,
+		MemoryLimitBytes:     128 << 20,
+	})
+	want := []string{
+		"User: 65532, group 65532",
+		"Linux capabilities: CAP_DAC_READ_SEARCH",
+		"Sandbox: seccomp (Landlock is not available: function not implemented)",
+		"Allowed roots: /var/log, /app",
+		"Request limits: 10/s per client, 30s per request, none per stream, 1073741824 bytes decompressed",
+		"Runtime limits: GOMAXPROCS 1, memory 134217728 bytes",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected posture:\n%s", strings.Join(lines, "\n"))
+	}
+
+	windows := postureLines(&api.SecurityPosture{Uid: -1, Gid: -1, SandboxMessage: "not supported on windows/amd64"})
+	if windows[0] != "Sandbox: none (not supported on windows/amd64)" {
+		t.Errorf("expected no user or capabilities on Windows, got %v", windows)
+	}
+	if postureLines(nil) != nil {
+		t.Error("expected nothing from agents that predate the posture")
+	}
+}
+
 func TestCreateTLSConfig(t *testing.T) {
 	// Test default config
 	config, err := createTLSConfig()
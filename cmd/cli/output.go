@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// listingHeader names the columns of CSV and TSV listings.
+var listingHeader = []string{"name", "type", "size_bytes", "mode", "modified", "owner"}
+
+// validateListingFormat checks an -o value before any connection is made.
+func validateListingFormat(format string) error {
+	switch format {
+	case "", "text", "csv", "tsv":
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q; use text, csv or tsv", format)
+}
+
+// fileKinds names each file_type in the type column of listings.
+var fileKinds = map[api.FileType]string{
+	api.FileType_FILE_TYPE_REGULAR:      "file",
+	api.FileType_FILE_TYPE_DIRECTORY:    "dir",
+	api.FileType_FILE_TYPE_SYMLINK:      "symlink",
+	api.FileType_FILE_TYPE_NAMED_PIPE:   "pipe",
+	api.FileType_FILE_TYPE_SOCKET:       "socket",
+	api.FileType_FILE_TYPE_BLOCK_DEVICE: "blockdev",
+	api.FileType_FILE_TYPE_CHAR_DEVICE:  "chardev",
+	api.FileType_FILE_TYPE_IRREGULAR:    "irregular",
+}
+
+// fileKind names the type of entry. Agents that predate file_type only
+// distinguish directories from files.
+func fileKind(entry *api.FileInfo) string {
+	if kind, ok := fileKinds[entry.FileType]; ok {
+		return kind
+	}
+	if entry.IsDir {
+		return "dir"
+	}
+	return "file"
+}
+
+func listingRow(entry *api.FileInfo) []string {
+	return []string{
+		entry.Name,
+		fileKind(entry),
+		strconv.FormatInt(entry.SizeBytes, 10),
+		entry.Mode,
+		entry.Mtime.AsTime().UTC().Format(time.RFC3339),
+		entry.Owner,
+	}
+}
+
+// tsvEscaper keeps each TSV record on one line with one field per column.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writeListing prints directory entries as text, CSV (RFC 4180) or TSV with
+// a header row. TSV escapes backslash, tab and newline as \\, \t and \n.
+func writeListing(w io.Writer, format string, entries []*api.FileInfo) error {
+	return writeListingPage(w, format, entries, true)
+}
+
+// writeListingPage is writeListing for one page of a paged listing, where
+// only the first page has the header.
+func writeListingPage(w io.Writer, format string, entries []*api.FileInfo, header bool) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if header {
+			_ = cw.Write(listingHeader)
+		}
+		for _, entry := range entries {
+			_ = cw.Write(listingRow(entry))
+		}
+		cw.Flush()
+		return cw.Error()
+	case "tsv":
+		var rows [][]string
+		if header {
+			rows = append(rows, slices.Clone(listingHeader))
+		}
+		for _, entry := range entries {
+			rows = append(rows, listingRow(entry))
+		}
+		for _, row := range rows {
+			for i := range row {
+				row[i] = tsvEscaper.Replace(row[i])
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, entry := range entries {
+		if _, err := fmt.Fprintln(w, textListingLine(entry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// textListingLine formats entry as a line of the text listing.
+func textListingLine(entry *api.FileInfo) string {
+	return fmt.Sprintf("%s %s %d %s", entry.Mode, entry.Name, entry.SizeBytes, entry.Mtime.AsTime().Format("2006-01-02 15:04:05"))
+}
+
+// writeLongListing prints entries like ls -l: mode, hard links, owner,
+// group, size, modification time and name, with " -> target" after
+// symlinks. Owners are the agent's "uid:gid"; fields an agent does not
+// report are shown as "-".
+func writeLongListing(w io.Writer, entries []*api.FileInfo) error {
+	rows := make([][]string, len(entries))
+	var widths [5]int
+	for i, entry := range entries {
+		links := "-"
+		if entry.HardLinks > 0 {
+			links = strconv.FormatInt(entry.HardLinks, 10)
+		}
+		owner, group, ok := strings.Cut(entry.Owner, ":")
+		if owner == "" {
+			owner = "-"
+		}
+		if !ok {
+			group = "-"
+		}
+		rows[i] = []string{entry.Mode, links, owner, group, strconv.FormatInt(entry.SizeBytes, 10)}
+		for j, cell := range rows[i] {
+			widths[j] = max(widths[j], len(cell))
+		}
+	}
+	for i, entry := range entries {
+		r := rows[i]
+		name := entry.Name
+		if entry.LinkTarget != "" {
+			name += " -> " + entry.LinkTarget
+		}
+		if _, err := fmt.Fprintf(w, "%-*s %*s %-*s %-*s %*s %s %s\n",
+			widths[0], r[0], widths[1], r[1], widths[2], r[2], widths[3], r[3], widths[4], r[4],
+			entry.Mtime.AsTime().Format("2006-01-02 15:04"), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func testEntries() []*api.FileInfo {
+	mtime := timestamppb.New(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	return []*api.FileInfo{
+		{Name: "conf.d", IsDir: true, SizeBytes: 4096, Mode: "drwxr-xr-x", Mtime: mtime, Owner: "0:0"},
+		{Name: "odd, \"name\"\twith tab", SizeBytes: 12, Mode: "-rw-r--r--", Mtime: mtime},
+	}
+}
+
+func TestWriteListingCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeListing(&out, "csv", testEntries()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != "name,type,size_bytes,mode,modified,owner" {
+		t.Fatalf("unexpected records %q", records)
+	}
+	if strings.Join(records[1], ",") != "conf.d,dir,4096,drwxr-xr-x,2026-03-01T12:00:00Z,0:0" {
+		t.Errorf("unexpected row %q", records[1])
+	}
+	if records[2][0] != "odd, \"name\"\twith tab" || records[2][1] != "file" {
+		t.Errorf("expected the name to round-trip, got %q", records[2])
+	}
+}
+
+func TestWriteLongListing(t *testing.T) {
+	mtime := timestamppb.New(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	var out bytes.Buffer
+	err := writeLongListing(&out, []*api.FileInfo{
+		{Name: "app.log", SizeBytes: 1234, Mode: "-rw-r--r--", Mtime: mtime, Owner: "1000:1000", HardLinks: 2},
+		{Name: "current", SizeBytes: 7, Mode: "Lrwxrwxrwx", Mtime: mtime, Owner: "0:0", HardLinks: 1, LinkTarget: "app.log"},
+		{Name: "old", SizeBytes: 5, Mode: "-rw-r--r--", Mtime: mtime},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-rw-r--r-- 2 1000 1000 1234 2026-03-01 12:00 app.log\n" +
+		"Lrwxrwxrwx 1 0    0       7 2026-03-01 12:00 current -> app.log\n" +
+		"-rw-r--r-- - -    -       5 2026-03-01 12:00 old\n"
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteListingTSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeListing(&out, "tsv", testEntries()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", lines)
+	}
+	for _, line := range lines {
+		if n := strings.Count(line, "\t"); n != 5 {
+			t.Errorf("expected 6 fields in %q, got %d", line, n+1)
+		}
+	}
+	if !strings.HasPrefix(lines[2], `odd, "name"\twith tab`+"\tfile\t12\t") {
+		t.Errorf("expected an escaped tab, got %q", lines[2])
+	}
+}
+
+func TestExploreOutputFormat(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"app/config.yaml": {Data: []byte("debug: true\n")}})
+
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app"})
+	cmd.Flags().StringP("output", "o", "csv", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := runExplore(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "name,type,size_bytes,mode,modified,owner\nconfig.yaml,file,12,") {
+		t.Errorf("unexpected CSV listing %q", out.String())
+	}
+
+	cmd = fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/app"})
+	cmd.Flags().StringP("output", "o", "json", "")
+	if err := runExplore(cmd, nil); err == nil || !strings.Contains(err.Error(), "unsupported output format") {
+		t.Errorf("expected an unsupported format error, got %v", err)
+	}
+}
+
+func TestExploreLargeDirectory(t *testing.T) {
+	files := fstest.MapFS{}
+	for i := 0; i < 2500; i++ {
+		files[fmt.Sprintf("spool/msg-%05d", i)] = &fstest.MapFile{}
+	}
+	agent := withFakeAgent(t, files)
+
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/spool"})
+	cmd.Flags().StringP("output", "o", "tsv", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := runExplore(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2501 || strings.Count(out.String(), "name\ttype") != 1 || !strings.HasPrefix(lines[2500], "msg-02499\t") {
+		t.Errorf("expected one header and every entry, got %d lines", len(lines))
+	}
+	var lists int
+	for _, r := range agent.Requests() {
+		if r.Operation == "ListDirectory" {
+			lists++
+		}
+	}
+	if lists != 3 {
+		t.Errorf("expected the listing in 3 pages, got %d calls", lists)
+	}
+}
+
+func TestExploreUnsorted(t *testing.T) {
+	agent := withFakeAgent(t, fstest.MapFS{"spool/a": {}, "spool/b": {}})
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/spool"})
+	cmd.Flags().StringP("output", "o", "csv", "")
+	cmd.Flags().Bool("unsorted", true, "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := runExplore(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out.String(), "\n") != 3 || agent.Requests()[len(agent.Requests())-1].Operation != "ListDirectoryStream" {
+		t.Errorf("expected a streamed listing, got %q", out.String())
+	}
+}
+
+func TestExploreSortAndFilter(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{
+		"logs/a.log": {Data: make([]byte, 2048)},
+		"logs/b.log": {Data: make([]byte, 4096)},
+		"logs/c.log": {Data: make([]byte, 10)},
+		"logs/d.txt": {Data: make([]byte, 8192)},
+	})
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/logs"})
+	cmd.Flags().StringP("output", "o", "csv", "")
+	cmd.Flags().String("sort", "size", "")
+	cmd.Flags().Bool("reverse", true, "")
+	cmd.Flags().String("name", "*.log", "")
+	cmd.Flags().String("min-size", "1Ki", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := runExplore(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "b.log,") || !strings.HasPrefix(lines[2], "a.log,") {
+		t.Errorf("expected b.log then a.log, got %q", out.String())
+	}
+
+	for flag, value := range map[string]string{"sort": "owner", "min-size": "lots", "name": "[a"} {
+		cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/logs"})
+		cmd.Flags().StringP("output", "o", "text", "")
+		cmd.Flags().String(flag, value, "")
+		if err := runExplore(cmd, nil); exitCode(err) != exitUsage {
+			t.Errorf("--%s %s: expected a usage error, got %v", flag, value, err)
+		}
+	}
+}
+
+func TestExploreFileKinds(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{
+		"run/app.log":  {Data: []byte("x")},
+		"run/app.sock": {Mode: fs.ModeSocket},
+		"run/fifo":     {Mode: fs.ModeNamedPipe},
+		"run/sub/a":    {},
+	})
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/run"})
+	cmd.Flags().StringP("output", "o", "csv", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := runExplore(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]string)
+	for _, r := range records[1:] {
+		kinds[r[0]] = r[1]
+	}
+	want := map[string]string{"app.log": "file", "app.sock": "socket", "fifo": "pipe", "sub": "dir"}
+	if fmt.Sprint(kinds) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, kinds)
+	}
+
+	// Entries from agents that predate file_type fall back to is_dir.
+	if kind := fileKind(&api.FileInfo{IsDir: true}); kind != "dir" {
+		t.Errorf("expected dir, got %s", kind)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// maxPickerRows bounds how many pods the picker lists at once.
+const maxPickerRows = 20
+
+type podSummary struct {
+	Name   string
+	Ready  string
+	Status string
+	Age    string
+}
+
+// stdinIsTerminal reports whether the picker can prompt. Replaced in tests.
+var stdinIsTerminal = func() bool { return term.IsTerminal(int(os.Stdin.Fd())) }
+
+// listPodSummaries returns the pods in namespace for the picker. Replaced in
+// tests.
+var listPodSummaries = func(ctx context.Context, namespace string) ([]podSummary, error) {
+	clientset, err := policyClientset()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods in namespace %s: %v", namespace, err)
+	}
+	now := time.Now()
+	summaries := make([]podSummary, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		summaries = append(summaries, summarizePod(&pod, now))
+	}
+	return summaries, nil
+}
+
+func summarizePod(pod *corev1.Pod, now time.Time) podSummary {
+	ready := 0
+	for _, st := range pod.Status.ContainerStatuses {
+		if st.Ready {
+			ready++
+		}
+	}
+	return podSummary{
+		Name:   pod.Name,
+		Ready:  fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers)),
+		Status: string(pod.Status.Phase),
+		Age:    duration.HumanDuration(now.Sub(pod.CreationTimestamp.Time)),
+	}
+}
+
+// ensureTarget fills in --pod from the picker when neither --pod nor --node
+// was given and the CLI reads from and prompts on a terminal.
+func ensureTarget(cmd *cobra.Command, args []string) error {
+	pod, _ := cmd.Flags().GetString("pod")
+	node, _ := cmd.Flags().GetString("node")
+	if pod != "" || node != "" {
+		return nil
+	}
+	if !stdinIsTerminal() || !isTerminal(cmd.ErrOrStderr()) {
+		return fmt.Errorf("either --pod or --node is required")
+	}
+	namespace, _ := cmd.Flags().GetString("namespace")
+	pods, err := listPodSummaries(context.Background(), namespace)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no pods in namespace %s", namespace)
+	}
+	picked, err := pickPod(cmd.InOrStdin(), cmd.ErrOrStderr(), pods)
+	if err != nil {
+		return err
+	}
+	return cmd.Flags().Set("pod", picked)
+}
+
+// pickPod lists pods and reads either a row number or a filter. A filter
+// that leaves one pod selects it; an empty line clears the filter, or
+// cancels when none is set.
+func pickPod(r io.Reader, w io.Writer, pods []podSummary) (string, error) {
+	in := bufio.NewScanner(r)
+	filter := ""
+	for {
+		candidates := fuzzyFilter(pods, filter)
+		if len(candidates) == 1 && filter != "" {
+			_, _ = fmt.Fprintf(w, "Using pod %s\n", candidates[0].Name)
+			return candidates[0].Name, nil
+		}
+		if len(candidates) == 0 {
+			_, _ = fmt.Fprintf(w, "No pods match %q.\n", filter)
+			filter, candidates = "", pods
+		}
+		printCandidates(w, candidates)
+		_, _ = fmt.Fprint(w, "Pod number or filter (empty to cancel): ")
+		if filter != "" {
+			_, _ = fmt.Fprintf(w, "[%s] ", filter)
+		}
+		if !in.Scan() {
+			return "", fmt.Errorf("no pod selected")
+		}
+		answer := strings.TrimSpace(in.Text())
+		switch n, err := strconv.Atoi(answer); {
+		case answer == "" && filter == "":
+			return "", fmt.Errorf("no pod selected")
+		case answer == "":
+			filter = ""
+		case err == nil && n >= 1 && n <= min(len(candidates), maxPickerRows):
+			return candidates[n-1].Name, nil
+		default:
+			filter = answer
+		}
+	}
+}
+
+func printCandidates(w io.Writer, pods []podSummary) {
+	width := len("NAME")
+	for _, p := range pods[:min(len(pods), maxPickerRows)] {
+		width = max(width, len(p.Name))
+	}
+	_, _ = fmt.Fprintf(w, "     %-*s  READY  STATUS     AGE\n", width, "NAME")
+	for i, p := range pods[:min(len(pods), maxPickerRows)] {
+		_, _ = fmt.Fprintf(w, "%3d) %-*s  %-5s  %-9s  %s\n", i+1, width, p.Name, p.Ready, p.Status, p.Age)
+	}
+	if len(pods) > maxPickerRows {
+		_, _ = fmt.Fprintf(w, "     ... %d more; type to filter\n", len(pods)-maxPickerRows)
+	}
+}
+
+// fuzzyFilter keeps pods whose name contains the letters of filter in
+// order, case-insensitively, best matches first: the tightest span, then
+// the earliest start, then by name.
+func fuzzyFilter(pods []podSummary, filter string) []podSummary {
+	type scored struct {
+		pod         podSummary
+		span, start int
+	}
+	var matches []scored
+	for _, p := range pods {
+		if start, span, ok := fuzzyMatch(strings.ToLower(p.Name), strings.ToLower(filter)); ok {
+			matches = append(matches, scored{p, span, start})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.span != b.span {
+			return a.span < b.span
+		}
+		if a.start != b.start {
+			return a.start < b.start
+		}
+		return a.pod.Name < b.pod.Name
+	})
+	result := make([]podSummary, len(matches))
+	for i, m := range matches {
+		result[i] = m.pod
+	}
+	return result
+}
+
+// fuzzyMatch finds the shortest window of s containing pattern as a
+// subsequence.
+func fuzzyMatch(s, pattern string) (start, span int, ok bool) {
+	if pattern == "" {
+		return 0, 0, true
+	}
+	best := -1
+	for i := 0; i < len(s); i++ {
+		if s[i] != pattern[0] {
+			continue
+		}
+		j, k := i, 0
+		for ; j < len(s) && k < len(pattern); j++ {
+			if s[j] == pattern[k] {
+				k++
+			}
+		}
+		if k == len(pattern) && (best < 0 || j-i < span) {
+			best, start, span = i, i, j-i
+		}
+	}
+	return start, span, best >= 0
+}
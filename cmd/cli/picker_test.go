@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var pickerPods = []podSummary{
+	{Name: "api-7d9f-abcde", Ready: "1/1", Status: "Running", Age: "3d"},
+	{Name: "web-0", Ready: "2/2", Status: "Running", Age: "5h"},
+	{Name: "web-1", Ready: "1/2", Status: "Running", Age: "5h"},
+	{Name: "worker-batch", Ready: "0/1", Status: "Pending", Age: "2m"},
+}
+
+func names(pods []podSummary) string {
+	var out []string
+	for _, p := range pods {
+		out = append(out, p.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	tests := map[string]string{
+		"":     "api-7d9f-abcde,web-0,web-1,worker-batch",
+		"web":  "web-0,web-1,worker-batch",
+		"wb":   "web-0,web-1,worker-batch",
+		"W1":   "web-1",
+		"abc":  "api-7d9f-abcde",
+		"zzz":  "",
+		"wkbt": "worker-batch",
+	}
+	for filter, want := range tests {
+		if got := names(fuzzyFilter(pickerPods, filter)); got != want {
+			t.Errorf("fuzzyFilter(%q) = %s; want %s", filter, got, want)
+		}
+	}
+}
+
+func TestPickPod(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2\n", "web-0"},
+		{"web\n2\n", "web-1"},
+		{"batch\n", "worker-batch"},
+		{"zzz\n1\n", "api-7d9f-abcde"},
+		{"web\n\n4\n", "worker-batch"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, err := pickPod(strings.NewReader(tt.input), &out, pickerPods)
+		if err != nil || got != tt.want {
+			t.Errorf("pickPod(%q) = %s, %v; want %s", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"\n", ""} {
+		if _, err := pickPod(strings.NewReader(input), &bytes.Buffer{}, pickerPods); err == nil {
+			t.Errorf("expected %q to cancel", input)
+		}
+	}
+}
+
+func TestPickPodOutput(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := pickPod(strings.NewReader("1\n"), &out, pickerPods); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "  2) web-0           2/2    Running    5h") {
+		t.Errorf("expected aligned rows with ready state and age, got:\n%s", out.String())
+	}
+}
+
+func TestEnsureTargetPicksPod(t *testing.T) {
+	originalTerm, originalList := stdinIsTerminal, listPodSummaries
+	t.Cleanup(func() { stdinIsTerminal, listPodSummaries = originalTerm, originalList })
+	stdinIsTerminal = func() bool { return true }
+	withTerminal(t, true)
+	listPodSummaries = func(ctx context.Context, namespace string) ([]podSummary, error) {
+		return pickerPods, nil
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("pod", "", "")
+	cmd.Flags().String("namespace", "default", "")
+	requireTarget(cmd)
+	cmd.SetIn(strings.NewReader("api\n"))
+	cmd.SetErr(&bytes.Buffer{})
+	if err := ensureTarget(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if pod, _ := cmd.Flags().GetString("pod"); pod != "api-7d9f-abcde" {
+		t.Errorf("expected the picked pod in --pod, got %q", pod)
+	}
+}
+
+func TestSummarizePod(t *testing.T) {
+	now := time.Now()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-0", CreationTimestamp: metav1.NewTime(now.Add(-90 * time.Minute))},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "proxy"}}},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Ready: true}, {Name: "proxy"}},
+		},
+	}
+	got := summarizePod(pod, now)
+	if got.Ready != "1/2" || got.Status != "Running" || got.Age != "90m" {
+		t.Errorf("unexpected summary: %+v", got)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func newPolicyCmd() *cobra.Command {
+	policyCmd := &cobra.Command{
+		Use:   "policy",
+		Short: "View or change which paths Pulsaar agents may access",
+	}
+	policyCmd.PersistentFlags().String("namespace", "default", "Namespace")
+	policyCmd.PersistentFlags().String("pod", "", "Pod name; without it the namespace-wide pulsaar-config ConfigMap is used")
+
+	getCmd := &cobra.Command{
+		Use:   "get",
+		Short: "Show the allowed roots for a namespace or pod",
+		RunE:  runPolicyGet,
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Set the allowed roots for a namespace or pod",
+		RunE:  runPolicySet,
+	}
+	setCmd.Flags().String("allowed-roots", "", "Comma-separated absolute paths, e.g. /var/log,/app/config")
+	setCmd.Flags().Bool("dry-run", false, "Validate and print the change without applying it")
+	if err := setCmd.MarkFlagRequired("allowed-roots"); err != nil {
+		panic(err)
+	}
+
+	policyCmd.AddCommand(getCmd, setCmd)
+	return policyCmd
+}
+
+func policyClientset() (kubernetes.Interface, error) {
+	config, err := kubeConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Kubernetes cluster. Please check your kubeconfig or in-cluster configuration. Error: %w", err)
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+func formatRoots(roots []string) string {
+	if roots == nil {
+		return "(not set)"
+	}
+	if len(roots) == 0 {
+		return "(empty: no paths allowed)"
+	}
+	return strings.Join(roots, ",")
+}
+
+func printPolicy(w io.Writer, p *client.Policy) {
+	_, _ = fmt.Fprintf(w, "Namespace %s (ConfigMap %s): %s\n", p.Namespace, client.ConfigMapName, formatRoots(p.ConfigMapRoots))
+	if p.Pod != "" {
+		_, _ = fmt.Fprintf(w, "Pod %s (annotation %s): %s\n", p.Pod, client.AllowedRootsAnnotation, formatRoots(p.PodRoots))
+	}
+	if roots, source, ok := p.Effective(); ok {
+		_, _ = fmt.Fprintf(w, "Effective: %s (from %s)\n", formatRoots(roots), source)
+	} else {
+		_, _ = fmt.Fprintln(w, "Effective: agent default (PULSAAR_ALLOWED_ROOTS, or / when unset)")
+	}
+}
+
+func runPolicyGet(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	pod, _ := cmd.Flags().GetString("pod")
+
+	clientset, err := policyClientset()
+	if err != nil {
+		return err
+	}
+	p, err := client.GetPolicy(cmd.Context(), clientset, namespace, pod)
+	if err != nil {
+		return err
+	}
+	printPolicy(cmd.OutOrStdout(), p)
+	return nil
+}
+
+func runPolicySet(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	pod, _ := cmd.Flags().GetString("pod")
+	rootsFlag, _ := cmd.Flags().GetString("allowed-roots")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	roots, err := client.ValidateAllowedRoots(strings.Split(rootsFlag, ","))
+	if err != nil {
+		return fmt.Errorf("invalid --allowed-roots: %v", err)
+	}
+	out := cmd.OutOrStdout()
+	target := fmt.Sprintf("ConfigMap %s/%s", namespace, client.ConfigMapName)
+	if pod != "" {
+		target = fmt.Sprintf("pod %s/%s", namespace, pod)
+	}
+	for _, root := range roots {
+		if root == "/" {
+			warnf(cmd.ErrOrStderr(), "allowing / exposes the entire container filesystem.")
+		}
+	}
+	if dryRun {
+		_, _ = fmt.Fprintf(out, "Would set allowed roots on %s to %s\n", target, strings.Join(roots, ","))
+		return nil
+	}
+
+	clientset, err := policyClientset()
+	if err != nil {
+		return err
+	}
+	if err := client.SetAllowedRoots(cmd.Context(), clientset, namespace, pod, roots); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "Set allowed roots on %s to %s\n", target, strings.Join(roots, ","))
+	_, _ = fmt.Fprintln(out, "Agents apply the new scope when they next start.")
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func TestPolicySetDryRun(t *testing.T) {
+	cmd := newPolicyCmd()
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"set", "--namespace", "shop", "--allowed-roots", "/var/log/,/", "--dry-run"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "Would set allowed roots on ConfigMap shop/pulsaar-config to /var/log,/") {
+		t.Errorf("unexpected dry-run output: %q", got)
+	}
+	if !strings.Contains(errOut.String(), "Warning: allowing /") {
+		t.Errorf("expected warning for / on stderr, got %q", errOut.String())
+	}
+}
+
+func TestPolicySetRejectsInvalidRoots(t *testing.T) {
+	cmd := newPolicyCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"set", "--allowed-roots", "var/log", "--dry-run"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "absolute path") {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestPrintPolicy(t *testing.T) {
+	var out bytes.Buffer
+	printPolicy(&out, &client.Policy{Namespace: "shop", ConfigMapRoots: []string{"/var/log"}, Pod: "web-0"})
+	want := "Namespace shop (ConfigMap pulsaar-config): /var/log\n" +
+		"Pod web-0 (annotation pulsaar.io/allowed-roots): (not set)\n" +
+		"Effective: /var/log (from ConfigMap pulsaar-config)\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func newPreviewCmd() *cobra.Command {
+	previewCmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Show file info with the start (and end, for logs) of its content",
+		Example: `  pulsaar preview --pod web-0 --path /etc/app/config.yaml
+  pulsaar preview --pod web-0 --path /var/log/app.log --head-kb 1 --tail-kb 8`,
+		RunE: runPreview,
+	}
+	previewCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
+	previewCmd.Flags().String("namespace", "default", "Namespace")
+	previewCmd.Flags().String("path", "", "Path to file")
+	previewCmd.Flags().Int64("head-kb", 4, "KB to show from the start of the file")
+	previewCmd.Flags().Int64("tail-kb", -1, "KB to show from the end of the file (default 4 for log files, otherwise 0)")
+	if err := previewCmd.MarkFlagRequired("path"); err != nil {
+		panic(err)
+	}
+	requireTarget(previewCmd)
+	return previewCmd
+}
+
+// isLogPath guesses whether p is a log, whose end matters most.
+func isLogPath(p string) bool {
+	base := path.Base(p)
+	return strings.HasPrefix(p, "/var/log/") || strings.HasSuffix(base, ".log") || strings.Contains(base, ".log.")
+}
+
+func runPreview(cmd *cobra.Command, args []string) error {
+	pod, _ := cmd.Flags().GetString("pod")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	filePath, _ := cmd.Flags().GetString("path")
+	headKB, _ := cmd.Flags().GetInt64("head-kb")
+	tailKB, _ := cmd.Flags().GetInt64("tail-kb")
+	if tailKB < 0 {
+		tailKB = 0
+		if isLogPath(filePath) {
+			tailKB = 4
+		}
+	}
+	if headKB < 0 {
+		return fmt.Errorf("--head-kb must not be negative")
+	}
+	if headKB == 0 && tailKB == 0 {
+		return fmt.Errorf("nothing to preview: --head-kb and --tail-kb are both 0")
+	}
+
+	c, err := connectToAgent(cmd, pod, namespace)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	resp, err := c.Preview(context.Background(), filePath, headKB*1024, tailKB*1024)
+	if err != nil {
+		return fmt.Errorf("failed to preview '%s' in %s. Check the path exists and is within allowed paths. Error: %w", filePath, describeTarget(cmd, namespace, pod), err)
+	}
+	printPreview(cmd.OutOrStdout(), filePath, resp)
+	return nil
+}
+
+func printPreview(w io.Writer, filePath string, resp *api.PreviewResponse) {
+	info := resp.Info
+	_, _ = fmt.Fprintf(w, "==> %s <==\n", filePath)
+	_, _ = fmt.Fprintf(w, "Size: %d bytes  Mode: %s  Modified: %s", info.SizeBytes, info.Mode, info.Mtime.AsTime().Format("2006-01-02 15:04:05"))
+	if info.Owner != "" {
+		_, _ = fmt.Fprintf(w, "  Owner: %s", info.Owner)
+	}
+	_, _ = fmt.Fprintln(w)
+	if info.IsDir {
+		_, _ = fmt.Fprintln(w, "(directory; use explore to list it)")
+		return
+	}
+	if info.IsSpecial() {
+		_, _ = fmt.Fprintf(w, "(%s; content not shown)\n", fileKind(info))
+		return
+	}
+	if isBinary(resp.Head) || isBinary(resp.Tail) {
+		_, _ = fmt.Fprintln(w, "(binary content not shown)")
+		return
+	}
+
+	shown := int64(len(resp.Head) + len(resp.Tail))
+	if len(resp.Head) > 0 {
+		_, _ = fmt.Fprintf(w, "--- first %d bytes ---\n", len(resp.Head))
+		writeSection(w, resp.Head)
+	}
+	if skipped := info.SizeBytes - shown; skipped > 0 && len(resp.Tail) > 0 {
+		_, _ = fmt.Fprintf(w, "... %d bytes skipped ...\n", skipped)
+	}
+	if len(resp.Tail) > 0 {
+		_, _ = fmt.Fprintf(w, "--- last %d bytes ---\n", len(resp.Tail))
+		writeSection(w, resp.Tail)
+	}
+	if shown < info.SizeBytes && len(resp.Tail) == 0 {
+		_, _ = fmt.Fprintf(w, "... (%d more bytes)\n", info.SizeBytes-shown)
+	}
+}
+
+// writeSection prints data and ends it with a newline if it lacks one.
+func writeSection(w io.Writer, data []byte) {
+	_, _ = w.Write(data)
+	if data[len(data)-1] != '\n' {
+		_, _ = fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func runPreviewCmd(t *testing.T, args ...string) string {
+	t.Helper()
+	cmd := newPreviewCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs(append([]string{"--pod", "web-0"}, args...))
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestPreview(t *testing.T) {
+	log := strings.Repeat("line\n", 2000)
+	withFakeAgent(t, fstest.MapFS{
+		"var/log/app.log":  {Data: []byte(log)},
+		"etc/app.conf":     {Data: []byte("port = 8080\n")},
+		"opt/app/blob.bin": {Data: []byte{0, 1, 2}},
+	})
+
+	out := runPreviewCmd(t, "--path", "/var/log/app.log", "--head-kb", "1")
+	for _, want := range []string{"==> /var/log/app.log <==", "Size: 10000 bytes", "--- first 1024 bytes ---", "... 4880 bytes skipped ...", "--- last 4096 bytes ---"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log preview:\n%s", want, out)
+		}
+	}
+
+	out = runPreviewCmd(t, "--path", "/etc/app.conf")
+	if !strings.Contains(out, "--- first 12 bytes ---\nport = 8080\n") || strings.Contains(out, "last") {
+		t.Errorf("expected only the head of a config file:\n%s", out)
+	}
+
+	out = runPreviewCmd(t, "--path", "/opt/app/blob.bin")
+	if !strings.Contains(out, "(binary content not shown)") {
+		t.Errorf("expected binary content to be hidden:\n%s", out)
+	}
+
+	out = runPreviewCmd(t, "--path", "/etc")
+	if !strings.Contains(out, "(directory; use explore to list it)") {
+		t.Errorf("expected a directory note:\n%s", out)
+	}
+}
+
+func TestIsLogPath(t *testing.T) {
+	for p, want := range map[string]bool{
+		"/var/log/syslog":     true,
+		"/app/logs/app.log":   true,
+		"/app/logs/app.log.1": true,
+		"/etc/app.conf":       false,
+		"/app/catalog.json":   false,
+	} {
+		if got := isLogPath(p); got != want {
+			t.Errorf("isLogPath(%s) = %t; want %t", p, got, want)
+		}
+	}
+}
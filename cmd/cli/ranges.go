@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// rangeFlags parses the read --range flags. Offsets and lengths take the
+// same suffixes as find --larger-than; a negative offset counts back from
+// the end of the file.
+func rangeFlags(cmd *cobra.Command) ([]*api.ByteRange, error) {
+	values, _ := cmd.Flags().GetStringArray("range")
+	ranges := make([]*api.ByteRange, 0, len(values))
+	for _, v := range values {
+		offset, length, ok := strings.Cut(v, ":")
+		o, errOffset := resource.ParseQuantity(offset)
+		l, errLength := resource.ParseQuantity(length)
+		if !ok || errOffset != nil || errLength != nil || l.Sign() <= 0 {
+			return nil, &usageError{fmt.Errorf("invalid --range %q; use OFFSET:LENGTH with a positive length, e.g. 0:4Ki or -4Ki:4Ki", v)}
+		}
+		ranges = append(ranges, &api.ByteRange{Offset: o.Value(), Length: l.Value()})
+	}
+	return ranges, nil
+}
+
+// writeRawParts writes the bytes of each part back to back, for --raw.
+func writeRawParts(w io.Writer, parts []*api.ReadPart) error {
+	for _, p := range parts {
+		if _, err := w.Write(p.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeParts prints the parts of a ranged read. Several parts are each
+// headed by the bytes they hold, like head(1) with several files. Binary
+// warnings go to errOut.
+func writeParts(w, errOut io.Writer, parts []*api.ReadPart) error {
+	for i, p := range parts {
+		if len(parts) > 1 {
+			if i > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+			_, _ = fmt.Fprintf(w, "==> bytes %d-%d <==\n", p.Offset, p.Offset+int64(len(p.Data)))
+		}
+		if isBinary(p.Data) {
+			warnf(errOut, "This range appears to be binary. Output may be corrupted.")
+		}
+		if _, err := w.Write(p.Data); err != nil {
+			return err
+		}
+		if len(parts) > 1 && len(p.Data) > 0 && p.Data[len(p.Data)-1] != '\n' {
+			_, _ = fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
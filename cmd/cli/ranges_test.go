@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func TestReadRanges(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"var/log/app.log": {Data: []byte("first line\n" + strings.Repeat(".", 100) + "\nlast line\n")}})
+	withTerminal(t, true)
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/var/log/app.log"})
+	cmd.Flags().StringArray("range", []string{"0:11", "-10:10"}, "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "==> bytes 0-11 <==\nfirst line\n\n==> bytes 112-122 <==\nlast line\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestReadRangesUsage(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{})
+	lastOptions = client.Options{}
+	for _, tt := range []struct {
+		rng  string
+		jq   string
+		want string
+	}{
+		{"100", "", "invalid --range"},
+		{"0:0", "", "invalid --range"},
+		{"x:1Ki", "", "invalid --range"},
+		{"0:1Ki", ".msg", "cannot be combined"},
+	} {
+		cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/var/log/app.log"})
+		cmd.Flags().StringArray("range", []string{tt.rng}, "")
+		cmd.Flags().String("jq", tt.jq, "")
+		err := runRead(cmd, nil)
+		if exitCode(err) != exitUsage || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("--range %s: expected a usage error containing %q, got %v", tt.rng, tt.want, err)
+		}
+	}
+	if lastOptions.Pod != "" {
+		t.Error("expected usage errors before connecting")
+	}
+}
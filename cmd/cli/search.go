@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// listPods returns the running pods matching selector. Replaced in tests.
+var listPods = func(ctx context.Context, namespace, selector string) ([]string, error) {
+	clientset, err := policyClientset()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods matching '%s' in namespace %s: %v", selector, namespace, err)
+	}
+	var names []string
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning {
+			names = append(names, pod.Name)
+		}
+	}
+	return names, nil
+}
+
+func newSearchCmd() *cobra.Command {
+	searchCmd := &cobra.Command{
+		Use:   "search",
+		Short: "Search file contents across all pods matching a selector",
+		Example: `  pulsaar search --selector app=web --path /var/log --pattern 'OOMKilled'
+  pulsaar search -l app=web --path /app/logs --pattern '(?i)timeout' --max-matches 20`,
+		RunE: runSearch,
+	}
+	searchCmd.Flags().StringP("selector", "l", "", "Label selector for pods, e.g. app=web")
+	searchCmd.Flags().String("namespace", "default", "Namespace")
+	searchCmd.Flags().String("path", "", "File or directory to search")
+	searchCmd.Flags().String("pattern", "", "Regular expression (RE2 syntax) to match lines against")
+	searchCmd.Flags().Int32("max-matches", 100, "Maximum matches per pod")
+	searchCmd.Flags().Int("concurrency", 5, "Pods searched at once")
+	for _, name := range []string{"selector", "path", "pattern"} {
+		if err := searchCmd.MarkFlagRequired(name); err != nil {
+			panic(err)
+		}
+	}
+	return searchCmd
+}
+
+type podSearchResult struct {
+	pod  string
+	resp *api.SearchResponse
+	err  error
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	selector, _ := cmd.Flags().GetString("selector")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	path, _ := cmd.Flags().GetString("path")
+	pattern, _ := cmd.Flags().GetString("pattern")
+	maxMatches, _ := cmd.Flags().GetInt32("max-matches")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx := context.Background()
+	pods, err := listPods(ctx, namespace, selector)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no running pods match '%s' in namespace %s", selector, namespace)
+	}
+
+	results := make([]podSearchResult, len(pods))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = searchPod(ctx, cmd, namespace, pod, path, pattern, maxMatches)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].pod < results[j].pod })
+	out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			_, _ = fmt.Fprintf(errOut, "%s: %v\n", r.pod, r.err)
+			continue
+		}
+		for _, m := range r.resp.Matches {
+			_, _ = fmt.Fprintf(out, "%s:%s:%d: %s\n", r.pod, m.Path, m.Line, m.Text)
+		}
+		if r.resp.Truncated {
+			_, _ = fmt.Fprintf(errOut, "%s: results truncated; narrow --path or raise --max-matches\n", r.pod)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("search failed on %d of %d pods", failed, len(pods))
+	}
+	return nil
+}
+
+func searchPod(ctx context.Context, cmd *cobra.Command, namespace, pod, path, pattern string, maxMatches int32) podSearchResult {
+	c, err := connectToAgent(cmd, pod, namespace)
+	if err != nil {
+		return podSearchResult{pod: pod, err: err}
+	}
+	defer func() { _ = c.Close() }()
+	resp, err := c.Search(ctx, path, pattern, maxMatches)
+	if err != nil {
+		return podSearchResult{pod: pod, err: fmt.Errorf("failed to search '%s': %v", path, err)}
+	}
+	return podSearchResult{pod: pod, resp: resp}
+}
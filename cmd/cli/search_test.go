@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+	pulsaartesting "github.com/VrushankPatel/pulsaar/pkg/testing"
+)
+
+// withFakePods serves each pod from its own in-memory agent. Pods without
+// a file system fail to connect.
+func withFakePods(t *testing.T, pods map[string]fstest.MapFS, names ...string) {
+	t.Helper()
+	servers := map[string]*pulsaartesting.Server{}
+	for pod, fsys := range pods {
+		srv := pulsaartesting.Serve(pulsaartesting.NewAgent(fsys))
+		t.Cleanup(srv.Close)
+		servers[pod] = srv
+	}
+
+	originalConnect, originalList := connect, listPods
+	t.Cleanup(func() { connect, listPods = originalConnect, originalList })
+	listPods = func(ctx context.Context, namespace, selector string) ([]string, error) {
+		return names, nil
+	}
+	connect = func(ctx context.Context, opts client.Options) (*client.Client, error) {
+		srv, ok := servers[opts.Pod]
+		if !ok {
+			return nil, errors.New("port-forward failed")
+		}
+		conn, err := srv.Dial()
+		if err != nil {
+			return nil, err
+		}
+		return client.New(conn, opts.AllowedRoots...), nil
+	}
+}
+
+func TestSearchAcrossPods(t *testing.T) {
+	withFakePods(t, map[string]fstest.MapFS{
+		"web-0": {"var/log/app.log": {Data: []byte("ok\nOOMKilled worker\n")}},
+		"web-1": {"var/log/app.log": {Data: []byte("OOMKilled main\n")}},
+		"web-2": {"var/log/app.log": {Data: []byte("fine\n")}},
+	}, "web-2", "web-1", "web-0")
+
+	cmd := newSearchCmd()
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs([]string{"-l", "app=web", "--path", "/var/log", "--pattern", "OOMKilled"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := "web-0:/var/log/app.log:2: OOMKilled worker\nweb-1:/var/log/app.log:1: OOMKilled main\n"
+	if out.String() != want {
+		t.Errorf("expected merged results sorted by pod:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestSearchReportsFailedPods(t *testing.T) {
+	withFakePods(t, map[string]fstest.MapFS{
+		"web-0": {"var/log/app.log": {Data: []byte("OOMKilled\n")}},
+	}, "web-0", "web-1")
+
+	cmd := newSearchCmd()
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	cmd.SetArgs([]string{"-l", "app=web", "--path", "/var/log", "--pattern", "OOMKilled"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 pods") {
+		t.Errorf("expected a partial failure, got %v", err)
+	}
+	if !strings.Contains(out.String(), "web-0:") || !strings.Contains(errOut.String(), "web-1: port-forward failed") {
+		t.Errorf("expected results from web-0 and an error for web-1, got %q and %q", out.String(), errOut.String())
+	}
+}
+
+func TestSearchNoPods(t *testing.T) {
+	withFakePods(t, nil)
+	cmd := newSearchCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"-l", "app=none", "--path", "/", "--pattern", "x"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected an error when no pods match")
+	}
+}
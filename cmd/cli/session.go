@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func newSessionCmd() *cobra.Command {
+	sessionCmd := &cobra.Command{
+		Use:   "session",
+		Short: "Manage agent connections kept open by --session-ttl",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List open sessions",
+		RunE:  runSessionList,
+	}
+
+	closeCmd := &cobra.Command{
+		Use:   "close",
+		Short: "Close sessions and stop their port-forwards",
+		RunE:  runSessionClose,
+	}
+	closeCmd.Flags().String("namespace", "", "Only sessions in this namespace")
+	closeCmd.Flags().String("pod", "", "Only sessions for this pod")
+
+	sessionCmd.AddCommand(listCmd, closeCmd)
+	return sessionCmd
+}
+
+func runSessionList(cmd *cobra.Command, args []string) error {
+	sessions, err := client.ListSessions(client.DefaultSessionDir())
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(sessions) == 0 {
+		_, _ = fmt.Fprintln(out, "No open sessions.")
+		return nil
+	}
+	now := time.Now()
+	for _, s := range sessions {
+		via := s.ConnectionMethod
+		if s.LocalPort != 0 {
+			via = fmt.Sprintf("%s localhost:%d", via, s.LocalPort)
+		}
+		_, _ = fmt.Fprintf(out, "%s/%s via %s, expires in %s\n", s.Namespace, s.Pod, via, s.Expires.Sub(now).Round(time.Second))
+	}
+	return nil
+}
+
+func runSessionClose(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	pod, _ := cmd.Flags().GetString("pod")
+	closed, err := client.CloseSessions(client.DefaultSessionDir(), namespace, pod)
+	if err != nil {
+		return fmt.Errorf("failed to close sessions: %v", err)
+	}
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Closed %d session(s).\n", closed)
+	return nil
+}
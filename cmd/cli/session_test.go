@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSessionListEmpty(t *testing.T) {
+	t.Setenv("PULSAAR_SESSION_DIR", t.TempDir())
+	cmd := newSessionCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"list"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No open sessions.") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestAgentOptionsFromEnvironment(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().Duration("session-ttl", 0, "")
+	cmd.Flags().Duration("access-cache-ttl", defaultAccessCacheTTL, "")
+	cmd.Flags().Bool("no-inject", false, "")
+	cmd.Flags().Int("agent-port", 0, "")
+	cmd.Flags().String("agent-cpu", "", "")
+
+	t.Setenv("PULSAAR_SESSION_TTL", "10m")
+	t.Setenv("PULSAAR_NO_INJECT", "true")
+	t.Setenv("PULSAAR_AGENT_PORT", "9443")
+	t.Setenv("PULSAAR_AGENT_CPU", "2")
+	if err := cmd.ParseFlags([]string{"--agent-port", "50052"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bindFlagEnv(cmd); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := agentOptions(cmd, "web-0", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.SessionTTL != 10*time.Minute || !opts.SkipInjection || opts.AgentCPU != "2" {
+		t.Errorf("expected settings from the environment, got %s, %t, %q", opts.SessionTTL, opts.SkipInjection, opts.AgentCPU)
+	}
+	if opts.AgentPort != 50052 {
+		t.Errorf("expected the flag to override the environment, got %d", opts.AgentPort)
+	}
+	if opts.AccessCacheTTL != time.Minute {
+		t.Errorf("expected the 1m default, got %s", opts.AccessCacheTTL)
+	}
+}
+
+func TestImpersonationSettings(t *testing.T) {
+	t.Cleanup(func() { asSetting, asGroupsSetting = "", nil })
+	cmd := &cobra.Command{}
+	cmd.Flags().String("as", "", "")
+	cmd.Flags().StringArray("as-group", nil, "")
+	_ = cmd.Flags().Set("as-group", "dev")
+	if err := setupImpersonation(cmd); err == nil {
+		t.Error("expected --as-group without --as to be rejected")
+	}
+
+	_ = cmd.Flags().Set("as", "alice")
+	_ = cmd.Flags().Set("as-group", "oncall")
+	if err := setupImpersonation(cmd); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := agentOptions(cmd, "web-0", "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.As != "alice" || strings.Join(opts.AsGroups, ",") != "dev,oncall" {
+		t.Errorf("expected the connection to impersonate alice in dev and oncall, got %q %v", opts.As, opts.AsGroups)
+	}
+}
+
+func TestNamespaceFromKubeconfig(t *testing.T) {
+	original := kubeNamespace
+	kubeNamespace = func() string { return "payments" }
+	t.Cleanup(func() { kubeNamespace = original })
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("namespace", "default", "")
+	setupNamespace(cmd)
+	if ns, _ := cmd.Flags().GetString("namespace"); ns != "payments" || cmd.Flags().Changed("namespace") {
+		t.Errorf("expected the context's namespace as an unchanged default, got %q", ns)
+	}
+
+	cmd = &cobra.Command{}
+	cmd.Flags().String("namespace", "default", "")
+	_ = cmd.Flags().Set("namespace", "shop")
+	setupNamespace(cmd)
+	if ns, _ := cmd.Flags().GetString("namespace"); ns != "shop" {
+		t.Errorf("expected --namespace to win, got %q", ns)
+	}
+
+	cmd = &cobra.Command{}
+	cmd.Flags().String("namespace", "", "")
+	setupNamespace(cmd)
+	if ns, _ := cmd.Flags().GetString("namespace"); ns != "" {
+		t.Errorf("expected a filter flag to keep its empty default, got %q", ns)
+	}
+}
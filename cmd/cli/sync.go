@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func newSyncCmd() *cobra.Command {
+	syncCmd := &cobra.Command{
+		Use:   "sync LOCAL_DIR",
+		Short: "Mirror a pod directory into a local directory, copying only changed files",
+		Long: `Copy a file or directory tree from a pod into LOCAL_DIR, like rsync. Files
+whose size and modification time match the local copy are skipped; files of
+the same size with a different time are compared by SHA-256 on both sides
+and only copied if their contents differ. Copies are verified against the
+agent's checksum and get the pod's modification time, so repeated
+snapshots of a configuration directory only transfer what changed.
+Symlinks, pipes, sockets and devices are skipped.`,
+		Example: `  pulsaar sync --pod web-0 --path /app/config ./local-config
+  pulsaar sync --pod web-0 --path /etc/nginx ./nginx --checksum --delete`,
+		Args: cobra.ExactArgs(1),
+		RunE: runSync,
+	}
+	syncCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
+	syncCmd.Flags().String("namespace", "default", "Namespace")
+	syncCmd.Flags().String("path", "", "Path to the file or directory to mirror")
+	syncCmd.Flags().Bool("checksum", false, "Compare every file by SHA-256, even when size and modification time match")
+	syncCmd.Flags().Bool("delete", false, "Remove local files and directories that are not in the pod")
+	if err := syncCmd.MarkFlagRequired("path"); err != nil {
+		panic(err)
+	}
+	requireTarget(syncCmd)
+	return syncCmd
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	pod, _ := cmd.Flags().GetString("pod")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	remote, _ := cmd.Flags().GetString("path")
+	checksum, _ := cmd.Flags().GetBool("checksum")
+	del, _ := cmd.Flags().GetBool("delete")
+
+	c, err := connectToAgent(cmd, pod, namespace)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	s := &syncer{c: c, checksum: checksum, delete: del, out: cmd.OutOrStdout(), errOut: cmd.ErrOrStderr()}
+	if err := s.sync(context.Background(), remote, args[0]); err != nil {
+		return fmt.Errorf("failed to sync '%s' from %s to %s. Check the path exists and is within allowed paths. Error: %w", remote, describeTarget(cmd, namespace, pod), args[0], err)
+	}
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Synced '%s' to %s: %d copied (%d bytes), %d unchanged, %d deleted, %d skipped\n",
+		remote, args[0], s.copied, s.bytes, s.unchanged, s.deleted, s.skipped)
+	return nil
+}
+
+// syncer mirrors pod files into a local directory and counts what it did.
+type syncer struct {
+	c        *client.Client
+	checksum bool
+	delete   bool
+	// out lists copied files; errOut gets warnings.
+	out, errOut io.Writer
+	// noVerify is set once the agent turns out to predate checksums.
+	noVerify bool
+
+	copied, unchanged, deleted, skipped int
+	bytes                               int64
+}
+
+// sync mirrors remote into localDir: a directory's contents, or a file as
+// localDir/<name>.
+func (s *syncer) sync(ctx context.Context, remote, localDir string) error {
+	info, err := s.c.Stat(ctx, remote)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return err
+	}
+	if !info.IsDir {
+		info.Name = path.Base(remote)
+		return s.syncEntry(ctx, remote, localDir, info)
+	}
+	return s.syncDir(ctx, remote, localDir)
+}
+
+func (s *syncer) syncDir(ctx context.Context, remote, local string) error {
+	entries, err := s.c.List(ctx, remote)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		// Names come from the agent; never let one escape local.
+		if !filepath.IsLocal(e.Name) || filepath.Base(e.Name) != e.Name {
+			s.skipped++
+			continue
+		}
+		seen[e.Name] = true
+		if err := s.syncEntry(ctx, path.Join(remote, e.Name), local, e); err != nil {
+			return err
+		}
+	}
+	if s.delete {
+		return s.prune(local, seen)
+	}
+	return nil
+}
+
+func (s *syncer) syncEntry(ctx context.Context, remote, localDir string, e *api.FileInfo) error {
+	local := filepath.Join(localDir, e.Name)
+	switch {
+	case e.IsDir:
+		if err := os.MkdirAll(local, 0o755); err != nil {
+			return err
+		}
+		return s.syncDir(ctx, remote, local)
+	case e.FileType == api.FileType_FILE_TYPE_UNSPECIFIED, e.FileType == api.FileType_FILE_TYPE_REGULAR:
+		return s.syncFile(ctx, remote, local, e)
+	default:
+		s.skipped++
+		return nil
+	}
+}
+
+// syncFile copies remote to local unless the local copy is already the same.
+func (s *syncer) syncFile(ctx context.Context, remote, local string, e *api.FileInfo) error {
+	mtime := e.Mtime.AsTime()
+	same, err := s.upToDate(ctx, remote, local, e)
+	if err != nil {
+		return err
+	}
+	if same {
+		s.unchanged++
+		// Record the pod's time so the next sync can skip the checksum.
+		return os.Chtimes(local, mtime, mtime)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(local), ".pulsaar-sync-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	n, err := s.download(ctx, remote, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), mtime, mtime); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), local); err != nil {
+		return err
+	}
+	s.copied++
+	s.bytes += n
+	_, _ = fmt.Fprintf(s.out, "%s\n", remote)
+	return nil
+}
+
+// upToDate reports whether local already holds remote: the same size and
+// modification time, or the same SHA-256 when the times differ or
+// --checksum is set.
+func (s *syncer) upToDate(ctx context.Context, remote, local string, e *api.FileInfo) (bool, error) {
+	info, err := os.Lstat(local)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() != e.SizeBytes {
+		return false, nil
+	}
+	if !s.checksum && info.ModTime().Truncate(time.Second).Equal(e.Mtime.AsTime().Truncate(time.Second)) {
+		return true, nil
+	}
+	if s.noVerify {
+		return false, nil
+	}
+	resp, err := s.c.Checksum(ctx, remote, 0)
+	if errors.Is(err, client.ErrChecksum) {
+		s.warnUnverified(err)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	digest, err := fileDigest(local)
+	if err != nil {
+		return false, err
+	}
+	return resp.SizeBytes == info.Size() && resp.Digest == digest, nil
+}
+
+// download streams remote into w, verified against the agent's checksum
+// when it has one, and returns the bytes written.
+func (s *syncer) download(ctx context.Context, remote string, w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	if !s.noVerify {
+		err := s.c.StreamVerified(ctx, remote, 0, cw)
+		if !errors.Is(err, client.ErrChecksum) {
+			return cw.n, err
+		}
+		s.warnUnverified(err)
+	}
+	err := s.c.Stream(ctx, remote, 0, cw)
+	return cw.n, err
+}
+
+func (s *syncer) warnUnverified(err error) {
+	warnf(s.errOut, "%v; files are compared by size and time only and copies are not verified.", err)
+	s.noVerify = true
+}
+
+// prune removes entries of local that are not in seen.
+func (s *syncer) prune(local string, seen map[string]bool) error {
+	entries, err := os.ReadDir(local)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if seen[e.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(local, e.Name())); err != nil {
+			return err
+		}
+		s.deleted++
+	}
+	return nil
+}
+
+// fileDigest returns the hex SHA-256 of the file at p.
+func fileDigest(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
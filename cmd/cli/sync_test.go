@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func runSyncCmd(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+	cmd := newSyncCmd()
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	cmd.SetArgs(append([]string{"--pod", "web-0", "--path", "/app/config"}, args...))
+	err := cmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func TestSync(t *testing.T) {
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"app/config/app.yaml":          {Data: []byte("port: 8080\n"), ModTime: mtime},
+		"app/config/tls/ca.pem":        {Data: []byte("-----BEGIN CERTIFICATE-----\n"), ModTime: mtime},
+		"app/config/current.yaml":      {Data: []byte("app.yaml"), Mode: os.ModeSymlink, ModTime: mtime},
+		"app/config/tls/expired/.keep": {Data: nil, ModTime: mtime},
+	}
+	agent := withFakeAgent(t, fsys)
+	local := filepath.Join(t.TempDir(), "mirror")
+
+	out, _, err := runSyncCmd(t, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "3 copied") || !strings.Contains(out, "1 skipped") {
+		t.Errorf("expected three files copied and the symlink skipped, got %q", out)
+	}
+	data, err := os.ReadFile(filepath.Join(local, "tls", "ca.pem"))
+	if err != nil || string(data) != "-----BEGIN CERTIFICATE-----\n" {
+		t.Fatalf("expected the nested file to be mirrored, got %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(local, "app.yaml")); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("expected the copy to keep the pod's modification time, got %v, %v", info, err)
+	}
+
+	// Nothing changed: no file is transferred or even hashed.
+	before := len(agent.Requests())
+	if out, _, err = runSyncCmd(t, local); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "0 copied") || !strings.Contains(out, "3 unchanged") {
+		t.Errorf("expected nothing to be copied, got %q", out)
+	}
+	for _, req := range agent.Requests()[before:] {
+		if req.Operation == "StreamFile" || req.Operation == "Checksum" {
+			t.Errorf("expected an unchanged mirror to need no %s request", req.Operation)
+		}
+	}
+
+	// A touched file with the same content is compared by checksum and
+	// not copied; a changed file of the same size is.
+	later := mtime.Add(time.Hour)
+	fsys["app/config/tls/ca.pem"].ModTime = later
+	fsys["app/config/app.yaml"].Data = []byte("port: 9090\n")
+	fsys["app/config/app.yaml"].ModTime = later
+	if err := os.WriteFile(filepath.Join(local, "stale.yaml"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, _, err = runSyncCmd(t, local, "--delete"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "/app/config/app.yaml\n") || !strings.Contains(out, "1 copied") || !strings.Contains(out, "1 deleted") {
+		t.Errorf("expected only app.yaml to be copied and stale.yaml deleted, got %q", out)
+	}
+	if data, _ := os.ReadFile(filepath.Join(local, "app.yaml")); string(data) != "port: 9090\n" {
+		t.Errorf("expected the changed file to be updated, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(local, "stale.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected --delete to remove stale.yaml, got %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(local, "tls", "ca.pem")); err != nil || !info.ModTime().Equal(later) {
+		t.Errorf("expected the unchanged file to take the pod's new time, got %v, %v", info, err)
+	}
+}
+
+func TestSyncWithoutChecksum(t *testing.T) {
+	agent := withFakeAgent(t, fstest.MapFS{"app/config/app.yaml": {Data: []byte("port: 8080\n")}})
+	agent.Supported = []string{"Stat", "ListDirectory", "StreamFile"}
+
+	out, errOut, err := runSyncCmd(t, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "1 copied") || !strings.Contains(errOut, "not verified") {
+		t.Errorf("expected an unverified copy with a warning, got %q, %q", out, errOut)
+	}
+}
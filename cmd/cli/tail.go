@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+func newTailCmd() *cobra.Command {
+	tailCmd := &cobra.Command{
+		Use:   "tail [path...]",
+		Short: "Follow files in a pod, optionally filtered by time and pattern",
+		Long: `Follow files in a pod, printing lines as they are appended.
+
+--since starts at the first line timestamped within the window; the agent
+finds it by binary search, so it suits large timestamped logs. --pattern is a
+regular expression applied by the agent, so only matching lines are sent.
+
+Several paths, or glob patterns matched by the agent, are followed in one
+stream. Output from each file is introduced by a "==> path <==" header, as
+with tail -f. Quote globs so your local shell does not expand them; files
+that start matching later are followed too.
+
+When the connection drops while following, for example because the
+port-forward or the agent restarted, tail reconnects for up to
+--reconnect-timeout and carries on after the last line it printed.`,
+		Example: `  pulsaar tail --pod web-0 --path /var/log/app.log
+  pulsaar tail --pod web-0 --path /var/log/app.log --since 10m --pattern ERROR
+  pulsaar tail --pod web-0 --path /var/log/app.log --from-start --no-follow --pattern 'timeout|refused'
+  pulsaar tail --pod web-0 '/var/log/app/*.log' /var/log/nginx/error.log`,
+		RunE: runTail,
+	}
+	tailCmd.Flags().String("pod", "", "Pod name (omit to pick one interactively)")
+	tailCmd.Flags().String("namespace", "default", "Namespace")
+	tailCmd.Flags().String("path", "", "Path to file (or pass paths and globs as arguments)")
+	tailCmd.Flags().Duration("since", 0, "Start at lines timestamped within this window, e.g. 10m")
+	tailCmd.Flags().String("pattern", "", "Only print lines matching this regular expression")
+	tailCmd.Flags().Bool("from-start", false, "Print the whole file before following")
+	tailCmd.Flags().Bool("no-follow", false, "Exit after printing the existing contents")
+	tailCmd.Flags().Duration("reconnect-timeout", time.Minute, "How long to keep reconnecting after the connection drops while following; 0 exits on the first error")
+	tailCmd.MarkFlagsMutuallyExclusive("since", "from-start")
+	requireTarget(tailCmd)
+	return tailCmd
+}
+
+func runTail(cmd *cobra.Command, args []string) error {
+	pod, _ := cmd.Flags().GetString("pod")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	filePath, _ := cmd.Flags().GetString("path")
+	since, _ := cmd.Flags().GetDuration("since")
+	pattern, _ := cmd.Flags().GetString("pattern")
+	fromStart, _ := cmd.Flags().GetBool("from-start")
+	noFollow, _ := cmd.Flags().GetBool("no-follow")
+	reconnect, _ := cmd.Flags().GetDuration("reconnect-timeout")
+	if reconnect < 0 {
+		return fmt.Errorf("--reconnect-timeout must not be negative")
+	}
+	if since < 0 {
+		return fmt.Errorf("--since must not be negative")
+	}
+	if since > 0 && since < time.Second {
+		return fmt.Errorf("--since must be at least 1s")
+	}
+	paths := args
+	if filePath != "" {
+		paths = append([]string{filePath}, paths...)
+	}
+	if len(paths) == 0 {
+		return &usageError{errors.New("no file to tail; pass --path or paths as arguments")}
+	}
+
+	c, err := connectToAgent(cmd, pod, namespace)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	opts := client.TailOptions{
+		FromStart:    fromStart,
+		Since:        since,
+		Pattern:      pattern,
+		NoFollow:     noFollow,
+		ReconnectFor: reconnect,
+		OnReconnect: func(err error) {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Connection to %s lost (%s); reconnecting...\n", describeTarget(cmd, namespace, pod), status.Convert(err).Message())
+		},
+	}
+	if len(paths) == 1 && !strings.ContainsAny(paths[0], "*?[") {
+		err = c.Tail(ctx, paths[0], opts, cmd.OutOrStdout())
+	} else {
+		err = c.TailFiles(ctx, paths, opts, (&tailPrinter{w: cmd.OutOrStdout()}).print)
+	}
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to tail '%s' in %s. Check the path exists and is within allowed paths. Error: %w", strings.Join(paths, "', '"), describeTarget(cmd, namespace, pod), err)
+	}
+	return nil
+}
+
+// tailPrinter writes data from several files with a "==> path <==" header
+// whenever the file changes, as tail does.
+type tailPrinter struct {
+	w    io.Writer
+	last string
+}
+
+func (p *tailPrinter) print(path string, data []byte) error {
+	if path != p.last {
+		sep := "\n"
+		if p.last == "" {
+			sep = ""
+		}
+		if _, err := fmt.Fprintf(p.w, "%s==> %s <==\n", sep, path); err != nil {
+			return err
+		}
+		p.last = path
+	}
+	_, err := p.w.Write(data)
+	return err
+}
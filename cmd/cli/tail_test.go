@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTail(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{
+		"var/log/app.log": {Data: []byte("INFO start\nERROR disk full\nINFO retry\nERROR disk still full\n")},
+	})
+
+	cmd := newTailCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--pod", "web-0", "--path", "/var/log/app.log", "--from-start", "--no-follow", "--pattern", "^ERROR"})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "ERROR disk full\nERROR disk still full\n" {
+		t.Errorf("expected only the ERROR lines, got %q", out.String())
+	}
+}
+
+func TestTailErrors(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"var/log/app.log": {Data: []byte("x\n")}})
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--since", "10m", "--from-start"}, "none of the others can be"},
+		{[]string{"--since", "100ms"}, "--since must be at least 1s"},
+		{[]string{"--pattern", "(", "--no-follow"}, "Invalid tail pattern"},
+		{[]string{"--reconnect-timeout", "-1s"}, "--reconnect-timeout must not be negative"},
+	} {
+		cmd := newTailCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(append([]string{"--pod", "web-0", "--path", "/var/log/app.log"}, tt.args...))
+		err := cmd.ExecuteContext(context.Background())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
+
+func TestTailSeveralFiles(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{
+		"var/log/app/api.log":     {Data: []byte("GET /health\nGET /orders\n")},
+		"var/log/app/worker.log":  {Data: []byte("job 1 done\n")},
+		"var/log/app/gc.txt":      {Data: []byte("not matched\n")},
+		"var/log/nginx/error.log": {Data: []byte("upstream timed out\n")},
+	})
+
+	cmd := newTailCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--pod", "web-0", "--from-start", "--no-follow", "/var/log/app/*.log", "/var/log/nginx/error.log"})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "==> /var/log/app/api.log <==\nGET /health\nGET /orders\n\n" +
+		"==> /var/log/app/worker.log <==\njob 1 done\n\n" +
+		"==> /var/log/nginx/error.log <==\nupstream timed out\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestTailRequiresPath(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{})
+	cmd := newTailCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--pod", "web-0"})
+	err := cmd.ExecuteContext(context.Background())
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "pass --path or paths as arguments") {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+}
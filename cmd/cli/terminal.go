@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// ANSI colors used for status tags, diffs and warnings.
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+)
+
+// isTerminal reports whether w writes to a terminal. Replaced in tests.
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// noColor is --no-color, or NO_COLOR set to anything (https://no-color.org).
+var noColor bool
+
+func setupColor(cmd *cobra.Command) {
+	noColor, _ = cmd.Flags().GetBool("no-color")
+	if os.Getenv("NO_COLOR") != "" {
+		noColor = true
+	}
+}
+
+// colorEnabled reports whether w may be colored: it is a terminal that
+// supports colors and neither --no-color nor NO_COLOR is set. Piped and
+// redirected output is never colored.
+func colorEnabled(w io.Writer) bool {
+	return !noColor && os.Getenv("TERM") != "dumb" && isTerminal(w)
+}
+
+// colorize wraps s in color when w may be colored.
+func colorize(w io.Writer, color, s string) string {
+	if !colorEnabled(w) {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// warnf prints a warning line to w, which should be stderr so warnings
+// never mix with data on stdout.
+func warnf(w io.Writer, format string, args ...any) {
+	_, _ = fmt.Fprintf(w, colorize(w, colorYellow, "Warning:")+" "+format+"\n", args...)
+}
+
+const rawUsage = "Write the bytes verbatim, without binary warnings, range headers or notes on stdout (default when stdout is not a terminal)"
+
+// rawOutput reports whether read and stream write file bytes verbatim:
+// with --raw, or when stdout is not a terminal, so a redirected copy such
+// as pulsaar stream ... > dump.bin holds nothing but the file.
+func rawOutput(cmd *cobra.Command) bool {
+	if f := cmd.Flags().Lookup("raw"); f != nil && f.Changed {
+		raw, _ := cmd.Flags().GetBool("raw")
+		return raw
+	}
+	return !isTerminal(cmd.OutOrStdout())
+}
+
+// showProgress reports whether progress lines may be written to w: only
+// people watching a terminal want them, not logs or pipes.
+func showProgress(w io.Writer) bool {
+	return isTerminal(w)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/cobra"
+
+	pulsaartesting "github.com/VrushankPatel/pulsaar/pkg/testing"
+)
+
+// withTerminal makes every writer look like a terminal, or none.
+func withTerminal(t *testing.T, terminal bool) {
+	t.Helper()
+	original := isTerminal
+	t.Cleanup(func() { isTerminal = original })
+	isTerminal = func(io.Writer) bool { return terminal }
+}
+
+func TestColorize(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+	original := noColor
+	t.Cleanup(func() { noColor = original })
+	noColor = false
+	var w bytes.Buffer
+
+	withTerminal(t, false)
+	if got := colorize(&w, colorRed, "x"); got != "x" {
+		t.Errorf("expected piped output to stay plain, got %q", got)
+	}
+	withTerminal(t, true)
+	if got := colorize(&w, colorRed, "x"); got != "\x1b[31mx\x1b[0m" {
+		t.Errorf("expected a colored terminal, got %q", got)
+	}
+	t.Setenv("TERM", "dumb")
+	if got := colorize(&w, colorRed, "x"); got != "x" {
+		t.Errorf("expected a dumb terminal to stay plain, got %q", got)
+	}
+	t.Setenv("TERM", "xterm")
+	t.Setenv("NO_COLOR", "1")
+	setupColor(fakeAgentCmd(nil))
+	if got := colorize(&w, colorRed, "x"); got != "x" {
+		t.Errorf("expected NO_COLOR to turn colors off, got %q", got)
+	}
+}
+
+func TestWarnf(t *testing.T) {
+	withTerminal(t, false)
+	var w bytes.Buffer
+	warnf(&w, "%d files skipped.", 2)
+	if got := w.String(); got != "Warning: 2 files skipped.\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestStreamWarnsOnStderr(t *testing.T) {
+	withFakeAgent(t, fstest.MapFS{"bin/app": {Data: []byte{0, 1, 2}}})
+	withTerminal(t, true)
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "path": "/bin/app"})
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	if err := runStream(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(errOut.Bytes(), []byte("This file appears to be binary")) {
+		t.Errorf("expected the binary warning on stderr, got %q", errOut.String())
+	}
+	if !bytes.Equal(out.Bytes(), []byte{0, 1, 2}) {
+		t.Errorf("expected only the file on stdout, got %q", out.String())
+	}
+}
+
+func TestRawOutput(t *testing.T) {
+	// Larger than one read: stream copies it all, read stops at the cap.
+	data := bytes.Repeat([]byte{0, 1, 2, 3}, 300*1024)
+	agent := withFakeAgent(t, fstest.MapFS{"data/dump.bin": {Data: data}})
+	withTerminal(t, false)
+
+	cmd := rawTestCmd("--path", "/data/dump.bin")
+	var out, errOut bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	if err := runStream(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) || errOut.Len() != 0 {
+		t.Errorf("expected stream to copy the file verbatim when piped, got %d bytes and %q", out.Len(), errOut.String())
+	}
+
+	reads := len(agent.Requests())
+	cmd = rawTestCmd("--path", "/data/dump.bin")
+	out.Reset()
+	errOut.Reset()
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data[:pulsaartesting.MaxReadSize]) {
+		t.Errorf("expected read to write its first %d bytes verbatim, got %d", pulsaartesting.MaxReadSize, out.Len())
+	}
+	if got := len(agent.Requests()) - reads; got != 1 {
+		t.Errorf("expected a single read, got %d", got)
+	}
+	if bytes.Contains(errOut.Bytes(), []byte("binary")) || !bytes.Contains(errOut.Bytes(), []byte("use pulsaar stream")) {
+		t.Errorf("expected only a pointer to stream on stderr, got %q", errOut.String())
+	}
+
+	cmd = rawTestCmd("--path", "/data/dump.bin", "--range", "0:2", "--range", "4:2")
+	out.Reset()
+	cmd.SetOut(&out)
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), []byte{0, 1, 0, 1}) {
+		t.Errorf("expected raw ranges without headers, got %q", out.String())
+	}
+
+	cmd = rawTestCmd("--path", "/data/dump.bin", "--raw=false")
+	errOut.Reset()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&errOut)
+	if err := runRead(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(errOut.Bytes(), []byte("binary")) || !bytes.Contains(errOut.Bytes(), []byte("file truncated")) {
+		t.Errorf("expected --raw=false to warn and note the truncation, got %q", errOut.String())
+	}
+}
+
+func rawTestCmd(args ...string) *cobra.Command {
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0"})
+	cmd.Flags().Bool("raw", false, "")
+	cmd.Flags().StringArray("range", nil, "")
+	if err := cmd.ParseFlags(args); err != nil {
+		panic(err)
+	}
+	return cmd
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// releaseURL is the GitHub API address of the latest release.
+var releaseURL = "https://api.github.com/repos/VrushankPatel/pulsaar/releases/latest"
+
+// releaseKeyFingerprint pins the GPG key that signs checksums.txt, as a
+// fingerprint or long key ID. Release builds set it with
+// -ldflags "-X main.releaseKeyFingerprint=..."; when it is empty any valid
+// signature from a key in the user's keyring is accepted.
+var releaseKeyFingerprint = ""
+
+var updateHTTPClient = &http.Client{Timeout: 5 * time.Minute}
+
+// executablePath is the binary pulsaar update replaces.
+var executablePath = func() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// maxReleaseAsset bounds downloads so a bad release cannot fill the disk.
+const maxReleaseAsset = 256 << 20
+
+// errUpdateAvailable is returned by pulsaar update --check when a newer
+// release exists.
+var errUpdateAvailable = errors.New("update available")
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r *githubRelease) assetURL(name string) (string, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL, true
+		}
+	}
+	return "", false
+}
+
+func newUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Replace this binary with the latest GitHub release",
+		Long: `Download the latest release for this platform, verify it against the
+release's checksums.txt and the GPG signature of that file, and replace the
+running binary.
+
+Signature verification runs gpg, which needs the release signing key in your
+keyring. --check only reports whether a newer release exists, exiting with
+code 9 (update_available) if it does, for CI notifications.`,
+		Args: cobra.NoArgs,
+		RunE: runUpdate,
+	}
+	cmd.Flags().Bool("check", false, "Only report whether a newer release exists; exits 9 if it does")
+	cmd.Flags().Bool("skip-signature", false, "Trust checksums.txt without verifying its GPG signature")
+	cmd.Flags().Bool("force", false, "Replace the binary even if it is a development build or looks managed by a package manager")
+	return cmd
+}
+
+func runUpdate(cmd *cobra.Command, args []string) error {
+	check, _ := cmd.Flags().GetBool("check")
+	skipSignature, _ := cmd.Flags().GetBool("skip-signature")
+	force, _ := cmd.Flags().GetBool("force")
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := cmd.OutOrStdout()
+
+	release, err := latestRelease(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check for updates. Error: %w", err)
+	}
+	newer, comparable := newerVersion(release.TagName, version)
+	if comparable && !newer {
+		_, _ = fmt.Fprintf(out, "pulsaar %s is up to date\n", version)
+		return nil
+	}
+	if check {
+		_, _ = fmt.Fprintf(out, "pulsaar %s is available (current %s): %s\n", release.TagName, version, release.HTMLURL)
+		return fmt.Errorf("%w: %s", errUpdateAvailable, release.TagName)
+	}
+	if !comparable && !force {
+		return &usageError{fmt.Errorf("cannot compare development build %q with %s; pass --force to install the release anyway", version, release.TagName)}
+	}
+
+	exe, err := executablePath()
+	if err != nil {
+		return fmt.Errorf("failed to locate the pulsaar binary. Error: %w", err)
+	}
+	if managed := packageManaged(exe); managed != "" && !force {
+		return fmt.Errorf("%s looks installed by %s; upgrade it there, or pass --force", exe, managed)
+	}
+
+	binary, err := downloadRelease(ctx, release, skipSignature)
+	if err != nil {
+		return fmt.Errorf("failed to download %s. Error: %w", release.TagName, err)
+	}
+	if err := replaceExecutable(exe, binary); err != nil {
+		return fmt.Errorf("failed to replace %s. Error: %w", exe, err)
+	}
+	_, _ = fmt.Fprintf(out, "Updated %s from %s to %s\n", exe, version, release.TagName)
+	return nil
+}
+
+func latestRelease(ctx context.Context) (*githubRelease, error) {
+	body, err := fetch(ctx, releaseURL)
+	if err != nil {
+		return nil, err
+	}
+	var release githubRelease
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("invalid release response: %v", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release response has no tag")
+	}
+	return &release, nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// An optional token lifts GitHub's anonymous rate limit in CI.
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.HasPrefix(url, "https://api.github.com/") {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := updateHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxReleaseAsset+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxReleaseAsset {
+		return nil, fmt.Errorf("GET %s: response larger than %d bytes", url, maxReleaseAsset)
+	}
+	return body, nil
+}
+
+// releaseArchiveName is the GoReleaser archive for goos and goarch.
+func releaseArchiveName(goos, goarch string) string {
+	arch := goarch
+	if goarch == "amd64" {
+		arch = "x86_64"
+	}
+	return fmt.Sprintf("pulsaar_%s%s_%s.tar.gz", strings.ToUpper(goos[:1]), goos[1:], arch)
+}
+
+// downloadRelease fetches the archive for this platform, verifies it and
+// returns the CLI binary inside.
+func downloadRelease(ctx context.Context, release *githubRelease, skipSignature bool) ([]byte, error) {
+	archiveName := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+	archiveURL, ok := release.assetURL(archiveName)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s", release.TagName, archiveName)
+	}
+	checksumsURL, ok := release.assetURL("checksums.txt")
+	if !ok {
+		return nil, fmt.Errorf("release %s has no checksums.txt", release.TagName)
+	}
+	checksums, err := fetch(ctx, checksumsURL)
+	if err != nil {
+		return nil, err
+	}
+	if !skipSignature {
+		sigURL, ok := release.assetURL("checksums.txt.sig")
+		if !ok {
+			return nil, fmt.Errorf("release %s has no checksums.txt.sig; pass --skip-signature to rely on the checksum alone", release.TagName)
+		}
+		sig, err := fetch(ctx, sigURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyChecksumsSignature(checksums, sig); err != nil {
+			return nil, err
+		}
+	}
+	want, err := checksumFor(checksums, archiveName)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := fetch(ctx, archiveURL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %s, want %s", archiveName, got, want)
+	}
+	binaryName := "pulsaar-cli"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	return extractFile(archive, binaryName)
+}
+
+// checksumFor finds name in a sha256sum-style checksums file.
+func checksumFor(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("checksums.txt has no entry for %s", name)
+}
+
+// verifySignature checks a detached GPG signature over data, returning the
+// fingerprint of the signing key.
+var verifySignature = func(data, sig []byte) (string, error) {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		return "", fmt.Errorf("gpg is needed to verify the release signature; install it or pass --skip-signature")
+	}
+	dir, err := os.MkdirTemp("", "pulsaar-update-")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	dataFile, sigFile := filepath.Join(dir, "checksums.txt"), filepath.Join(dir, "checksums.txt.sig")
+	if err := os.WriteFile(dataFile, data, 0600); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(sigFile, sig, 0600); err != nil {
+		return "", err
+	}
+	output, err := exec.Command(gpg, "--batch", "--status-fd", "1", "--verify", sigFile, dataFile).Output()
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 3 && fields[0] == "[GNUPG:]" && fields[1] == "VALIDSIG" {
+			return fields[2], nil
+		}
+	}
+	if err == nil {
+		err = errors.New("no valid signature")
+	}
+	return "", fmt.Errorf("gpg could not verify checksums.txt (is the release signing key imported?): %v", err)
+}
+
+func verifyChecksumsSignature(checksums, sig []byte) error {
+	fingerprint, err := verifySignature(checksums, sig)
+	if err != nil {
+		return err
+	}
+	want := strings.ToUpper(strings.ReplaceAll(releaseKeyFingerprint, " ", ""))
+	// A long key ID is the tail of the fingerprint.
+	if want != "" && !strings.HasSuffix(strings.ToUpper(fingerprint), want) {
+		return fmt.Errorf("checksums.txt is signed by key %s, not the release key %s", fingerprint, want)
+	}
+	return nil
+}
+
+// extractFile returns the regular file named name, at any depth, from a
+// gzipped tar archive.
+func extractFile(archive []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive has no %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == name {
+			return io.ReadAll(io.LimitReader(tr, maxReleaseAsset))
+		}
+	}
+}
+
+// replaceExecutable swaps exe for binary, keeping its permissions. The new
+// file is written next to exe so the final rename stays on one filesystem.
+func replaceExecutable(exe string, binary []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".pulsaar-update-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(binary); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		// A running executable cannot be overwritten on Windows, but it
+		// can be renamed.
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+// packageManaged names the package manager that appears to own exe, or
+// returns "".
+func packageManaged(exe string) string {
+	switch {
+	case strings.Contains(exe, "/Cellar/") || strings.Contains(exe, "/homebrew/"):
+		return "Homebrew"
+	case strings.HasPrefix(exe, "/usr/bin/") || strings.HasPrefix(exe, "/usr/sbin/"):
+		return "the system package manager"
+	}
+	return ""
+}
+
+// newerVersion reports whether latest is newer than current. comparable is
+// false if either is not a semantic version, e.g. a "dev" build.
+func newerVersion(latest, current string) (newer, comparable bool) {
+	l, lok := parseSemver(latest)
+	c, cok := parseSemver(current)
+	if !lok || !cok {
+		return false, false
+	}
+	for i := range l.core {
+		if l.core[i] != c.core[i] {
+			return l.core[i] > c.core[i], true
+		}
+	}
+	// A release is newer than its own pre-releases.
+	return c.pre != "" && (l.pre == "" || l.pre > c.pre), true
+}
+
+type semver struct {
+	core [3]int
+	pre  string
+}
+
+func parseSemver(v string) (semver, bool) {
+	var s semver
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, s.pre = v[:i], v[i+1:]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return s, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return s, false
+		}
+		s.core[i] = n
+	}
+	return s, true
+}
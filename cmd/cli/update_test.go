@@ -0,0 +1,241 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeRelease serves a GitHub release whose archive holds binary.
+type fakeRelease struct {
+	tag       string
+	checksums []byte
+	server    *httptest.Server
+}
+
+func newFakeRelease(t *testing.T, tag string, binary []byte) *fakeRelease {
+	t.Helper()
+	name := "pulsaar-cli"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for file, content := range map[string][]byte{"README.md": []byte("readme"), name: binary} {
+		if err := tw.WriteHeader(&tar.Header{Name: file, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+	archiveName := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+	sum := sha256.Sum256(archive)
+
+	r := &fakeRelease{tag: tag}
+	r.checksums = []byte(fmt.Sprintf("%s  %s\n%s  pulsaar_Plan9_x86_64.tar.gz\n", hex.EncodeToString(sum[:]), archiveName, strings.Repeat("0", 64)))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, _ *http.Request) {
+		release := map[string]any{
+			"tag_name": r.tag,
+			"html_url": "https://github.com/VrushankPatel/pulsaar/releases/tag/" + r.tag,
+			"assets": []map[string]string{
+				{"name": archiveName, "browser_download_url": r.server.URL + "/archive"},
+				{"name": "checksums.txt", "browser_download_url": r.server.URL + "/checksums.txt"},
+				{"name": "checksums.txt.sig", "browser_download_url": r.server.URL + "/checksums.txt.sig"},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(release)
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(archive) })
+	mux.HandleFunc("/checksums.txt", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(r.checksums) })
+	mux.HandleFunc("/checksums.txt.sig", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("signature")) })
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+
+	originalURL, originalVerify := releaseURL, verifySignature
+	t.Cleanup(func() { releaseURL, verifySignature = originalURL, originalVerify })
+	releaseURL = r.server.URL + "/latest"
+	verifySignature = func(data, sig []byte) (string, error) {
+		if !bytes.Equal(data, r.checksums) || string(sig) != "signature" {
+			return "", errors.New("bad signature")
+		}
+		return "0123456789ABCDEF0123456789ABCDEF01234567", nil
+	}
+	return r
+}
+
+// withExecutable points pulsaar update at a fake installed binary.
+func withExecutable(t *testing.T) string {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "pulsaar")
+	if err := os.WriteFile(exe, []byte("old"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	original := executablePath
+	t.Cleanup(func() { executablePath = original })
+	executablePath = func() (string, error) { return exe, nil }
+	return exe
+}
+
+func runUpdateCmd(args ...string) (string, error) {
+	cmd := newUpdateCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestUpdateReplacesBinary(t *testing.T) {
+	newFakeRelease(t, "v1.4.0", []byte("new"))
+	exe := withExecutable(t)
+	withVersion(t, "1.3.2")
+
+	out, err := runUpdateCmd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "from 1.3.2 to v1.4.0") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("expected the binary to be replaced, got %q, %v", data, err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(exe); info.Mode().Perm() != 0750 {
+			t.Errorf("expected the mode to be kept, got %v", info.Mode())
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 && runtime.GOOS != "windows" {
+		t.Errorf("expected no temporary files to remain, got %d entries", len(entries))
+	}
+}
+
+func TestUpdateCheck(t *testing.T) {
+	newFakeRelease(t, "v1.4.0", []byte("new"))
+	exe := withExecutable(t)
+
+	withVersion(t, "v1.3.2")
+	out, err := runUpdateCmd("--check")
+	if exitCode(err) != exitUpdateAvailable {
+		t.Fatalf("expected exit code %d, got %d (%v)", exitUpdateAvailable, exitCode(err), err)
+	}
+	if !strings.Contains(out, "pulsaar v1.4.0 is available (current v1.3.2): https://github.com/VrushankPatel/pulsaar/releases/tag/v1.4.0") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Error("--check must not replace the binary")
+	}
+
+	withVersion(t, "v1.4.0")
+	out, err = runUpdateCmd("--check")
+	if err != nil || !strings.Contains(out, "up to date") {
+		t.Errorf("expected an up to date report, got %q, %v", out, err)
+	}
+}
+
+func TestUpdateRejectsBadChecksum(t *testing.T) {
+	release := newFakeRelease(t, "v1.4.0", []byte("new"))
+	exe := withExecutable(t)
+	withVersion(t, "1.3.2")
+	release.checksums = bytes.Replace(release.checksums, release.checksums[:8], []byte("deadbeef"), 1)
+
+	_, err := runUpdateCmd()
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Error("a failed update must leave the binary alone")
+	}
+}
+
+func TestUpdateVerifiesSigningKey(t *testing.T) {
+	newFakeRelease(t, "v1.4.0", []byte("new"))
+	exe := withExecutable(t)
+	withVersion(t, "1.3.2")
+	original := releaseKeyFingerprint
+	t.Cleanup(func() { releaseKeyFingerprint = original })
+
+	releaseKeyFingerprint = "FFFFFFFFFFFFFFFF"
+	if _, err := runUpdateCmd(); err == nil || !strings.Contains(err.Error(), "not the release key") {
+		t.Fatalf("expected an untrusted key error, got %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Error("an untrusted signature must leave the binary alone")
+	}
+
+	// The release workflow pins the long key ID, the fingerprint's tail.
+	releaseKeyFingerprint = "89ABCDEF01234567"
+	if _, err := runUpdateCmd(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateRefusesDevelopmentAndManagedBuilds(t *testing.T) {
+	newFakeRelease(t, "v1.4.0", []byte("new"))
+	exe := withExecutable(t)
+
+	withVersion(t, "dev")
+	if _, err := runUpdateCmd(); exitCode(err) != exitUsage {
+		t.Errorf("expected a usage error for a development build, got %v", err)
+	}
+	if _, err := runUpdateCmd("--force"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new" {
+		t.Error("--force should install over a development build")
+	}
+
+	if got := packageManaged("/opt/homebrew/Cellar/pulsaar-cli/1.3.2/bin/pulsaar-cli"); got != "Homebrew" {
+		t.Errorf("expected Homebrew, got %q", got)
+	}
+	if got := packageManaged(exe); got != "" {
+		t.Errorf("expected %s not to be package managed, got %q", exe, got)
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest, current   string
+		newer, comparable bool
+	}{
+		{"v1.4.0", "1.3.9", true, true},
+		{"v1.4.0", "v1.4.0", false, true},
+		{"v1.3.0", "v1.4.0", false, true},
+		{"v2.0.0", "v1.10.0", true, true},
+		{"v1.4.0", "v1.4.0-rc.1", true, true},
+		{"v1.4.0-rc.2", "v1.4.0-rc.1", true, true},
+		{"v1.4.0-rc.1", "v1.4.0", false, true},
+		{"v1.4.0", "dev", false, false},
+	}
+	for _, tt := range tests {
+		newer, comparable := newerVersion(tt.latest, tt.current)
+		if newer != tt.newer || comparable != tt.comparable {
+			t.Errorf("newerVersion(%q, %q) = %v, %v; want %v, %v", tt.latest, tt.current, newer, comparable, tt.newer, tt.comparable)
+		}
+	}
+}
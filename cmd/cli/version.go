@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+// checkAgentVersion warns when the agent at target is not compatible with
+// this CLI's version, or fails under --strict-version.
+func checkAgentVersion(ctx context.Context, cmd *cobra.Command, c *client.Client, target string) error {
+	strict, _ := cmd.Flags().GetBool("strict-version")
+	info, err := c.AgentInfo(ctx)
+	if err != nil {
+		if strict {
+			return fmt.Errorf("failed to check the agent version in %s. Error: %w", target, err)
+		}
+		return nil
+	}
+	skew := client.VersionSkew(version, info.Version)
+	if skew == nil {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("%v in %s. Use a matching CLI or upgrade the agent", skew, target)
+	}
+	if format, _ := cmd.Flags().GetString("error-format"); format != errorFormatJSON {
+		warnf(cmd.ErrOrStderr(), "%v in %s. Newer operations may fail; pass --strict-version to refuse mismatches.", skew, target)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func withVersion(t *testing.T, v string) {
+	t.Helper()
+	original := version
+	t.Cleanup(func() { version = original })
+	version = v
+}
+
+func TestConnectWarnsOnVersionSkew(t *testing.T) {
+	t.Setenv("PULSAAR_STRICT_VERSION", "")
+	withVersion(t, "v2.0.0")
+	agent := withFakeAgent(t, fstest.MapFS{})
+	agent.Version = "v1.3.0"
+
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0", "namespace": "shop"})
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+	c, err := connectToAgent(cmd, "web-0", "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Close()
+	if !strings.Contains(stderr.String(), "Warning: CLI version v2.0.0 is not compatible with agent version v1.3.0 in pod shop/web-0") {
+		t.Errorf("expected a skew warning, got %q", stderr.String())
+	}
+
+	_ = cmd.Flags().Set("strict-version", "true")
+	_, err = connectToAgent(cmd, "web-0", "shop")
+	if err == nil || !strings.Contains(err.Error(), "upgrade the agent") {
+		t.Errorf("expected --strict-version to refuse the agent, got %v", err)
+	}
+}
+
+func TestCheckAgentVersionCompatible(t *testing.T) {
+	withVersion(t, "v1.4.0")
+	agent := withFakeAgent(t, fstest.MapFS{})
+	agent.Version = "1.2.0"
+
+	cmd := fakeAgentCmd(map[string]string{"pod": "web-0"})
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+	c, err := connect(context.Background(), lastOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkAgentVersion(context.Background(), cmd, c, "pod default/web-0"); err != nil || stderr.Len() != 0 {
+		t.Errorf("expected no warning for matching major versions, got %v %q", err, stderr.String())
+	}
+}
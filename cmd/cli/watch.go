@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// watchListing prints the listing returned by list, then lists again every
+// interval and prints what changed until ctx is cancelled. Each batch of
+// changes is headed by the time it was seen.
+func watchListing(ctx context.Context, list func(context.Context) ([]*api.FileInfo, error), interval time.Duration, w io.Writer) error {
+	entries, err := list(ctx)
+	if err != nil {
+		return err
+	}
+	if err := writeListing(w, "text", entries); err != nil {
+		return err
+	}
+	before := listingByName(entries)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		entries, err := list(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		after := listingByName(entries)
+		if lines := diffListings(before, after); len(lines) > 0 {
+			_, _ = fmt.Fprintf(w, "--- %s\n", time.Now().Format("15:04:05"))
+			for _, line := range lines {
+				if _, err := fmt.Fprintln(w, colorize(w, diffColors[line[0]], line)); err != nil {
+					return err
+				}
+			}
+		}
+		before = after
+	}
+}
+
+// diffColors colors the lines diffListings marks when w is a terminal.
+var diffColors = map[byte]string{'+': colorGreen, '-': colorRed, '~': colorYellow}
+
+func listingByName(entries []*api.FileInfo) map[string]*api.FileInfo {
+	m := make(map[string]*api.FileInfo, len(entries))
+	for _, e := range entries {
+		m[e.Name] = e
+	}
+	return m
+}
+
+// diffListings returns text listing lines, in name order, marked "+" for
+// entries only in after, "-" for entries only in before and "~" for entries
+// whose type, size, mode or modification time changed.
+func diffListings(before, after map[string]*api.FileInfo) []string {
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var lines []string
+	for _, name := range names {
+		old, hadOld := before[name]
+		cur, hasCur := after[name]
+		switch {
+		case !hadOld:
+			lines = append(lines, "+ "+textListingLine(cur))
+		case !hasCur:
+			lines = append(lines, "- "+textListingLine(old))
+		case entryChanged(old, cur):
+			lines = append(lines, "~ "+textListingLine(cur))
+		}
+	}
+	return lines
+}
+
+func entryChanged(old, cur *api.FileInfo) bool {
+	return fileKind(old) != fileKind(cur) || old.SizeBytes != cur.SizeBytes || old.Mode != cur.Mode ||
+		!old.Mtime.AsTime().Equal(cur.Mtime.AsTime())
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func TestDiffListings(t *testing.T) {
+	mtime := timestamppb.New(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	later := timestamppb.New(time.Date(2026, 3, 1, 12, 0, 5, 0, time.UTC))
+	before := listingByName([]*api.FileInfo{
+		{Name: "a.part", SizeBytes: 10, Mode: "-rw-r--r--", Mtime: mtime},
+		{Name: "b.done", SizeBytes: 20, Mode: "-rw-r--r--", Mtime: mtime},
+		{Name: "c.done", SizeBytes: 30, Mode: "-rw-r--r--", Mtime: mtime},
+	})
+	after := listingByName([]*api.FileInfo{
+		{Name: "a.part", SizeBytes: 15, Mode: "-rw-r--r--", Mtime: later},
+		{Name: "c.done", SizeBytes: 30, Mode: "-rw-r--r--", Mtime: mtime},
+		{Name: "d.part", SizeBytes: 1, Mode: "-rw-r--r--", Mtime: later},
+	})
+
+	got := diffListings(before, after)
+	want := []string{
+		"~ -rw-r--r-- a.part 15 " + later.AsTime().Format("2006-01-02 15:04:05"),
+		"- -rw-r--r-- b.done 20 " + mtime.AsTime().Format("2006-01-02 15:04:05"),
+		"+ -rw-r--r-- d.part 1 " + later.AsTime().Format("2006-01-02 15:04:05"),
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if lines := diffListings(after, after); len(lines) != 0 {
+		t.Errorf("expected no changes, got %v", lines)
+	}
+}
+
+func TestWatchListing(t *testing.T) {
+	mtime := timestamppb.New(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	var mu sync.Mutex
+	calls := 0
+	list := func(context.Context) ([]*api.FileInfo, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		entries := []*api.FileInfo{{Name: "upload-1", SizeBytes: 10, Mode: "-rw-r--r--", Mtime: mtime}}
+		if calls > 1 {
+			entries = append(entries, &api.FileInfo{Name: "upload-2", SizeBytes: 5, Mode: "-rw-r--r--", Mtime: mtime})
+		}
+		return entries, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out lockedBuffer
+	done := make(chan error, 1)
+	go func() { done <- watchListing(ctx, list, 10*time.Millisecond, &out) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "+ -rw-r--r-- upload-2") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	if !strings.HasPrefix(got, "-rw-r--r-- upload-1 10 ") {
+		t.Errorf("expected the initial listing first, got %q", got)
+	}
+	if strings.Count(got, "upload-2") != 1 || strings.Count(got, "--- ") != 1 {
+		t.Errorf("expected the new entry to be reported once, got %q", got)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/VrushankPatel/pulsaar/pkg/access"
+)
+
+// settleAccessRequests applies access.Request.Settle to every
+// PulsaarAccessRequest: approvals expire after their duration, approvals
+// made with kubectl get an expiry, and requesters cannot approve their own
+// requests. Nothing is done while the CRD is not installed.
+func (c *agentController) settleAccessRequests(ctx context.Context) error {
+	if c.dynamic == nil {
+		return nil
+	}
+	requests, err := access.List(ctx, c.dynamic, "")
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list access requests: %v", err)
+	}
+	now := c.now()
+	for i := range requests {
+		r := &requests[i]
+		if !r.Settle(now) {
+			continue
+		}
+		if _, err := access.UpdateStatus(ctx, c.dynamic, r); err != nil {
+			log.Printf("Failed to update access request %s/%s: %v", r.Namespace, r.Name, err)
+			continue
+		}
+		switch r.Phase() {
+		case access.PhaseExpired:
+			log.Printf("Access request %s/%s for %s on pod %s expired", r.Namespace, r.Name, r.Spec.User, r.Spec.Pod)
+		case access.PhaseDenied:
+			log.Printf("Denied access request %s/%s: %s", r.Namespace, r.Name, r.Status.Message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	agentContainerName = "pulsaar-agent"
+	// ttlAnnotation overrides the default TTL for agents injected into a pod.
+	ttlAnnotation = "pulsaar.io/agent-ttl"
+	// expiresAnnotation records when the agent in a pod expires. The
+	// controller sets it from the TTL when it first finds the agent; changing
+	// it extends or shortens the agent's access.
+	expiresAnnotation = "pulsaar.io/agent-expires-at"
+	statusDataKey     = "agents.json"
+)
+
+var (
+	activeAgents = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pulsaar_active_agents",
+		Help: "Running injected Pulsaar agents per namespace.",
+	}, []string{"namespace"})
+	expiredAgents = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pulsaar_expired_agents",
+		Help: "Running injected Pulsaar agents past their TTL per namespace.",
+	}, []string{"namespace"})
+	shutdownRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pulsaar_agent_shutdown_requests_total",
+		Help: "Shutdown requests sent to expired agents.",
+	}, []string{"namespace", "result"})
+	agentEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pulsaar_agent_evictions_total",
+		Help: "Pods evicted because an expired agent ignored shutdown requests.",
+	}, []string{"namespace"})
+)
+
+func init() {
+	prometheus.MustRegister(activeAgents, expiredAgents, shutdownRequests, agentEvictions)
+}
+
+// agentStatus describes one running injected agent.
+type agentStatus struct {
+	Namespace     string    `json:"namespace"`
+	Pod           string    `json:"pod"`
+	Node          string    `json:"node,omitempty"`
+	PodIP         string    `json:"pod_ip,omitempty"`
+	Image         string    `json:"image"`
+	StartedAt     time.Time `json:"started_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Expired       bool      `json:"expired"`
+	Notifications int       `json:"notifications"`
+	Evicted       bool      `json:"evicted,omitempty"`
+
+	// marked reports whether the pod already carries expiresAnnotation.
+	marked bool
+}
+
+func (a agentStatus) key() string {
+	return a.Namespace + "/" + a.Pod
+}
+
+// notice tracks the shutdown requests sent to one expired agent.
+type notice struct {
+	count   int
+	last    time.Time
+	evicted bool
+}
+
+// agentController enforces agent TTLs. Expired agents are asked to exit
+// through the Shutdown RPC, re-notified every renotify interval and, when
+// evictAfter is set, their pod is evicted after that many ignored requests.
+// When dynamic is set it also settles PulsaarAccessRequests.
+type agentController struct {
+	clientset       kubernetes.Interface
+	dynamic         dynamic.Interface
+	ttl             time.Duration
+	grace           time.Duration
+	renotify        time.Duration
+	evictAfter      int
+	statusNamespace string
+	statusName      string
+	shutdown        func(ctx context.Context, a agentStatus, grace time.Duration) (bool, error)
+	now             func() time.Time
+
+	mu      sync.Mutex
+	notices map[string]*notice
+	agents  []agentStatus
+}
+
+// findAgents returns the running pulsaar-agent ephemeral containers in pods.
+// The expiry is taken from the pod's expiresAnnotation when valid, else the
+// TTL from its ttlAnnotation or defaultTTL after the agent started.
+func findAgents(pods []corev1.Pod, defaultTTL time.Duration, now time.Time) []agentStatus {
+	var agents []agentStatus
+	for _, pod := range pods {
+		image := ""
+		for _, ec := range pod.Spec.EphemeralContainers {
+			if ec.Name == agentContainerName {
+				image = ec.Image
+			}
+		}
+		if image == "" {
+			continue
+		}
+		for _, st := range pod.Status.EphemeralContainerStatuses {
+			if st.Name != agentContainerName || st.State.Running == nil {
+				continue
+			}
+			ttl := defaultTTL
+			if v, ok := pod.Annotations[ttlAnnotation]; ok {
+				if d, err := time.ParseDuration(v); err == nil && d > 0 {
+					ttl = d
+				} else {
+					log.Printf("Ignoring invalid %s annotation %q on pod %s/%s", ttlAnnotation, v, pod.Namespace, pod.Name)
+				}
+			}
+			started := st.State.Running.StartedAt.Time
+			expires, marked := started.Add(ttl), false
+			if v, ok := pod.Annotations[expiresAnnotation]; ok {
+				if t, err := time.Parse(time.RFC3339, v); err == nil {
+					expires, marked = t, true
+				} else {
+					log.Printf("Ignoring invalid %s annotation %q on pod %s/%s", expiresAnnotation, v, pod.Namespace, pod.Name)
+				}
+			}
+			agents = append(agents, agentStatus{
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Node:      pod.Spec.NodeName,
+				PodIP:     pod.Status.PodIP,
+				Image:     image,
+				StartedAt: started,
+				ExpiresAt: expires,
+				Expired:   !now.Before(expires),
+				marked:    marked,
+			})
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].key() < agents[j].key() })
+	return agents
+}
+
+// reconcile runs one pass: settle access requests, discover agents, retire
+// expired ones, publish metrics and write the status ConfigMap.
+func (c *agentController) reconcile(ctx context.Context) error {
+	if err := c.settleAccessRequests(ctx); err != nil {
+		log.Printf("%v", err)
+	}
+
+	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %v", err)
+	}
+	now := c.now()
+	agents := findAgents(pods.Items, c.ttl, now)
+
+	c.mu.Lock()
+	seen := make(map[string]bool, len(agents))
+	for i := range agents {
+		a := &agents[i]
+		seen[a.key()] = true
+		if !a.marked {
+			c.mark(ctx, a)
+		}
+		if !a.Expired {
+			continue
+		}
+		n := c.notices[a.key()]
+		if n == nil {
+			n = &notice{}
+			c.notices[a.key()] = n
+		}
+		c.retire(ctx, a, n, now)
+		a.Notifications, a.Evicted = n.count, n.evicted
+	}
+	for key := range c.notices {
+		if !seen[key] {
+			delete(c.notices, key)
+		}
+	}
+	c.agents = agents
+	c.mu.Unlock()
+
+	updateMetrics(agents)
+	return c.writeStatus(ctx, agents, now)
+}
+
+// mark sets expiresAnnotation on the agent's pod, so its expiry shows on
+// the pod and survives controller restarts and TTL changes.
+func (c *agentController) mark(ctx context.Context, a *agentStatus) {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{expiresAnnotation: a.ExpiresAt.UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return
+	}
+	if _, err := c.clientset.CoreV1().Pods(a.Namespace).Patch(ctx, a.Pod, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		log.Printf("Failed to annotate pod %s with its agent expiry: %v", a.key(), err)
+		return
+	}
+	a.marked = true
+}
+
+func (c *agentController) retire(ctx context.Context, a *agentStatus, n *notice, now time.Time) {
+	if n.evicted {
+		return
+	}
+	if c.evictAfter > 0 && n.count >= c.evictAfter {
+		eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: a.Pod, Namespace: a.Namespace}}
+		if err := c.clientset.PolicyV1().Evictions(a.Namespace).Evict(ctx, eviction); err != nil {
+			log.Printf("Failed to evict pod %s after %d ignored shutdown requests: %v", a.key(), n.count, err)
+			return
+		}
+		n.evicted = true
+		agentEvictions.WithLabelValues(a.Namespace).Inc()
+		log.Printf("Evicted pod %s: agent expired at %s and ignored %d shutdown requests", a.key(), a.ExpiresAt.Format(time.RFC3339), n.count)
+		return
+	}
+	if !n.last.IsZero() && now.Sub(n.last) < c.renotify {
+		return
+	}
+
+	n.count++
+	n.last = now
+	accepted, err := c.shutdown(ctx, *a, c.grace)
+	switch {
+	case err != nil:
+		shutdownRequests.WithLabelValues(a.Namespace, "error").Inc()
+		log.Printf("Failed to send shutdown to expired agent in %s: %v", a.key(), err)
+	case accepted:
+		shutdownRequests.WithLabelValues(a.Namespace, "accepted").Inc()
+		log.Printf("Agent in %s expired at %s; shutdown requested", a.key(), a.ExpiresAt.Format(time.RFC3339))
+	default:
+		shutdownRequests.WithLabelValues(a.Namespace, "in_progress").Inc()
+		log.Printf("Agent in %s is still running after shutdown request %d", a.key(), n.count)
+	}
+}
+
+func updateMetrics(agents []agentStatus) {
+	activeAgents.Reset()
+	expiredAgents.Reset()
+	for _, a := range agents {
+		activeAgents.WithLabelValues(a.Namespace).Inc()
+		if a.Expired {
+			expiredAgents.WithLabelValues(a.Namespace).Inc()
+		}
+	}
+}
+
+// writeStatus records the current agents in the status ConfigMap, creating
+// it on first use.
+func (c *agentController) writeStatus(ctx context.Context, agents []agentStatus, now time.Time) error {
+	if agents == nil {
+		agents = []agentStatus{}
+	}
+	data, err := json.Marshal(agents)
+	if err != nil {
+		return err
+	}
+	values := map[string]string{
+		statusDataKey: string(data),
+		"updated_at":  now.UTC().Format(time.RFC3339),
+	}
+
+	configMaps := c.clientset.CoreV1().ConfigMaps(c.statusNamespace)
+	cm, err := configMaps.Get(ctx, c.statusName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.statusName,
+				Namespace: c.statusNamespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "pulsaar-controller"},
+			},
+			Data: values,
+		}
+		if _, err := configMaps.Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create status ConfigMap: %v", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get status ConfigMap: %v", err)
+	}
+	cm.Data = values
+	if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update status ConfigMap: %v", err)
+	}
+	return nil
+}
+
+// Agents returns the agents seen by the last reconcile.
+func (c *agentController) Agents() []agentStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.agents
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/VrushankPatel/pulsaar/pkg/access"
+)
+
+func agentPod(name string, started time.Time, annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Annotations: annotations},
+		Spec: corev1.PodSpec{
+			EphemeralContainers: []corev1.EphemeralContainer{{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: agentContainerName, Image: "pulsaar/agent:test"},
+			}},
+		},
+		Status: corev1.PodStatus{
+			PodIP: "10.0.0.1",
+			EphemeralContainerStatuses: []corev1.ContainerStatus{{
+				Name:  agentContainerName,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(started)}},
+			}},
+		},
+	}
+}
+
+func TestFindAgents(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	pods := []corev1.Pod{
+		*agentPod("old", now.Add(-2*time.Hour), nil),
+		*agentPod("fresh", now.Add(-10*time.Minute), nil),
+		*agentPod("long", now.Add(-2*time.Hour), map[string]string{ttlAnnotation: "4h"}),
+		{ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "shop"}},
+	}
+	stopped := agentPod("stopped", now.Add(-2*time.Hour), nil)
+	stopped.Status.EphemeralContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+	pods = append(pods, *stopped)
+
+	agents := findAgents(pods, time.Hour, now)
+	if len(agents) != 3 {
+		t.Fatalf("expected 3 running agents, got %d: %+v", len(agents), agents)
+	}
+	expired := map[string]bool{}
+	for _, a := range agents {
+		expired[a.Pod] = a.Expired
+	}
+	if !expired["old"] || expired["fresh"] || expired["long"] {
+		t.Errorf("unexpected expiry: %v", expired)
+	}
+}
+
+func newTestController(pods ...*corev1.Pod) (*agentController, *fake.Clientset, *[]string) {
+	objs := make([]runtime.Object, 0, len(pods))
+	for _, p := range pods {
+		objs = append(objs, p)
+	}
+	clientset := fake.NewClientset(objs...)
+	var calls []string
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := &agentController{
+		clientset:       clientset,
+		ttl:             time.Hour,
+		grace:           30 * time.Second,
+		renotify:        5 * time.Minute,
+		evictAfter:      2,
+		statusNamespace: "pulsaar",
+		statusName:      "pulsaar-agent-status",
+		shutdown: func(ctx context.Context, a agentStatus, grace time.Duration) (bool, error) {
+			calls = append(calls, a.key())
+			return true, nil
+		},
+		now:     func() time.Time { return now },
+		notices: map[string]*notice{},
+	}
+	return c, clientset, &calls
+}
+
+func TestReconcileRetiresExpiredAgents(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c, clientset, calls := newTestController(
+		agentPod("old", now.Add(-2*time.Hour), nil),
+		agentPod("fresh", now.Add(-time.Minute), nil),
+	)
+	ctx := context.Background()
+
+	if err := c.reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 || (*calls)[0] != "shop/old" {
+		t.Fatalf("expected one shutdown for shop/old, got %v", *calls)
+	}
+
+	// Within the renotify interval nothing is sent again.
+	if err := c.reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("expected no re-notification yet, got %v", *calls)
+	}
+
+	// After the interval the agent is re-notified, then evicted.
+	later := now.Add(6 * time.Minute)
+	c.now = func() time.Time { return later }
+	if err := c.reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 2 {
+		t.Errorf("expected re-notification, got %v", *calls)
+	}
+	if err := c.reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	evicted := false
+	for _, action := range clientset.Actions() {
+		if action.GetSubresource() == "eviction" {
+			evicted = true
+			if ns := action.GetNamespace(); ns != "shop" {
+				t.Errorf("eviction in wrong namespace %q", ns)
+			}
+		}
+	}
+	if !evicted {
+		t.Error("expected pod eviction after evictAfter ignored requests")
+	}
+
+	cm, err := clientset.CoreV1().ConfigMaps("pulsaar").Get(ctx, "pulsaar-agent-status", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var agents []agentStatus
+	if err := json.Unmarshal([]byte(cm.Data[statusDataKey]), &agents); err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 2 {
+		t.Fatalf("expected 2 agents in status, got %d", len(agents))
+	}
+	for _, a := range agents {
+		if a.Pod == "old" && (!a.Expired || a.Notifications != 2) {
+			t.Errorf("unexpected status for expired agent: %+v", a)
+		}
+	}
+}
+
+func TestReconcileForgetsRemovedAgents(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c, clientset, _ := newTestController(agentPod("old", now.Add(-2*time.Hour), nil))
+	ctx := context.Background()
+	if err := c.reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientset.CoreV1().Pods("shop").Delete(ctx, "old", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.notices) != 0 || len(c.Agents()) != 0 {
+		t.Errorf("expected removed agent to be forgotten, notices=%v agents=%v", c.notices, c.Agents())
+	}
+}
+
+func TestReconcileMarksAgentExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c, clientset, calls := newTestController(
+		agentPod("fresh", now.Add(-10*time.Minute), nil),
+		agentPod("extended", now.Add(-2*time.Hour), map[string]string{expiresAnnotation: "2024-01-01T14:00:00Z"}),
+		agentPod("cut", now.Add(-time.Minute), map[string]string{expiresAnnotation: "2024-01-01T11:59:00Z"}),
+	)
+	ctx := context.Background()
+	if err := c.reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	pod, err := clientset.CoreV1().Pods("shop").Get(ctx, "fresh", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pod.Annotations[expiresAnnotation]; got != "2024-01-01T12:50:00Z" {
+		t.Errorf("expected the pod to be marked with its expiry, got %q", got)
+	}
+	// The annotation overrides the TTL either way.
+	if len(*calls) != 1 || (*calls)[0] != "shop/cut" {
+		t.Errorf("expected only shop/cut to be shut down, got %v", *calls)
+	}
+	patches := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "patch" {
+			patches++
+		}
+	}
+	if patches != 1 {
+		t.Errorf("expected only the unmarked pod to be patched, got %d patches", patches)
+	}
+}
+
+func TestReconcileSettlesAccessRequests(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c, _, _ := newTestController()
+	c.dynamic = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{access.GVR: access.Kind + "List"})
+	ctx := context.Background()
+
+	for name, status := range map[string]access.Status{
+		"expired":  {Phase: access.PhaseApproved, DecidedBy: "bob", ExpiresAt: &metav1.Time{Time: now.Add(-time.Minute)}},
+		"self":     {Phase: access.PhaseApproved, DecidedBy: "alice"},
+		"current":  {Phase: access.PhaseApproved, DecidedBy: "bob", ExpiresAt: &metav1.Time{Time: now.Add(time.Minute)}},
+		"untimely": {Phase: access.PhaseApproved, DecidedBy: "bob"},
+	} {
+		r, err := access.NewRequest("shop", access.Spec{User: "alice", Pod: "web-0", Duration: "1h"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Name, r.Status = name, status
+		if _, err := access.Create(ctx, c.dynamic, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := access.List(ctx, c.dynamic, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases := map[string]string{}
+	for _, r := range requests {
+		phases[r.Name] = r.Phase()
+		if r.Name == "untimely" && (r.Status.ExpiresAt == nil || !r.Status.ExpiresAt.Time.Equal(now.Add(time.Hour))) {
+			t.Errorf("expected an expiry an hour from now, got %+v", r.Status)
+		}
+	}
+	want := map[string]string{"expired": access.PhaseExpired, "self": access.PhaseDenied, "current": access.PhaseApproved, "untimely": access.PhaseApproved}
+	for name, phase := range want {
+		if phases[name] != phase {
+			t.Errorf("%s: expected %s, got %s", name, phase, phases[name])
+		}
+	}
+}
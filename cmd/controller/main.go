@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/VrushankPatel/pulsaar/pkg/client"
+)
+
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+const (
+	defaultAgentTTL          = time.Hour
+	defaultReconcileInterval = 30 * time.Second
+	defaultShutdownGrace     = 30 * time.Second
+	defaultRenotifyInterval  = 5 * time.Minute
+	agentPort                = "50051"
+	shutdownCallTimeout      = 10 * time.Second
+)
+
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", name, v)
+	}
+	return d, nil
+}
+
+// shutdownAgent dials the agent directly on the pod IP and calls Shutdown.
+func shutdownAgent(ctx context.Context, a agentStatus, grace time.Duration) (bool, error) {
+	if a.PodIP == "" {
+		return false, fmt.Errorf("pod has no IP")
+	}
+	tlsConfig, err := client.TLSConfigFromEnv()
+	if err != nil {
+		return false, err
+	}
+	conn, err := grpc.NewClient(net.JoinHostPort(a.PodIP, agentPort), grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithTimeout(ctx, shutdownCallTimeout)
+	defer cancel()
+	return client.New(conn).Shutdown(ctx, fmt.Sprintf("TTL expired at %s", a.ExpiresAt.Format(time.RFC3339)), grace)
+}
+
+func main() {
+	ttl, err := envDuration("PULSAAR_AGENT_TTL", defaultAgentTTL)
+	if err != nil {
+		log.Fatalf("Invalid controller settings: %v", err)
+	}
+	interval, err := envDuration("PULSAAR_CONTROLLER_INTERVAL", defaultReconcileInterval)
+	if err != nil {
+		log.Fatalf("Invalid controller settings: %v", err)
+	}
+	grace, err := envDuration("PULSAAR_AGENT_SHUTDOWN_GRACE", defaultShutdownGrace)
+	if err != nil {
+		log.Fatalf("Invalid controller settings: %v", err)
+	}
+	renotify, err := envDuration("PULSAAR_AGENT_RENOTIFY_INTERVAL", defaultRenotifyInterval)
+	if err != nil {
+		log.Fatalf("Invalid controller settings: %v", err)
+	}
+	evictAfter := 0
+	if v := os.Getenv("PULSAAR_AGENT_EVICT_AFTER"); v != "" {
+		if evictAfter, err = strconv.Atoi(v); err != nil || evictAfter < 0 {
+			log.Fatalf("Invalid controller settings: PULSAAR_AGENT_EVICT_AFTER must be a non-negative integer, got %q", v)
+		}
+	}
+	statusNamespace := os.Getenv("PULSAAR_STATUS_NAMESPACE")
+	if statusNamespace == "" {
+		statusNamespace = os.Getenv("POD_NAMESPACE")
+	}
+	if statusNamespace == "" {
+		statusNamespace = "default"
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatalf("Failed to load in-cluster config: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	}
+
+	ctrl := &agentController{
+		clientset:       clientset,
+		dynamic:         dynamicClient,
+		ttl:             ttl,
+		grace:           grace,
+		renotify:        renotify,
+		evictAfter:      evictAfter,
+		statusNamespace: statusNamespace,
+		statusName:      "pulsaar-agent-status",
+		shutdown:        shutdownAgent,
+		now:             time.Now,
+		notices:         map[string]*notice{},
+	}
+
+	port := os.Getenv("PULSAAR_CONTROLLER_PORT")
+	if port == "" {
+		port = "8080"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status": "ok", "version": "%s", "commit": "%s", "date": "%s"}`, version, commit, date)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"agents": ctrl.Agents()}); err != nil {
+			log.Printf("Error encoding status: %v", err)
+		}
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+	go func() {
+		log.Printf("Controller HTTP server listening on :%s", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("HTTP server failed: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	log.Printf("Pulsaar agent controller started (ttl %s, interval %s, evict after %d notifications)", ttl, interval, evictAfter)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := ctrl.reconcile(ctx); err != nil {
+			log.Printf("Reconcile failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = server.Shutdown(shutdownCtx)
+			cancel()
+			log.Printf("Pulsaar agent controller stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/VrushankPatel/pulsaar/pkg/webhook"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -24,15 +27,39 @@ var (
 	version       = "dev"
 	commit        = "none"
 	date          = "unknown"
+
+	// verifier checks agent image signatures before injection; nil when
+	// no PULSAAR_COSIGN_* variable is set.
+	verifier *webhook.ImageVerifier
+	// injectDefault, from PULSAAR_INJECT_DEFAULT, injects pods that
+	// neither they nor their namespace decide for.
+	injectDefault bool
 )
 
+// namespacePath prefixes the paths the MutatingWebhookConfiguration sends
+// pods of labelled namespaces to, e.g. /mutate/namespace/false. The
+// webhook does not call the Kubernetes API, so it learns the label from
+// which namespaceSelector matched.
+const namespacePath = "/mutate/namespace/"
+
 func init() {
 	_ = corev1.AddToScheme(runtimeScheme)
 	_ = v1.AddToScheme(runtimeScheme)
 }
 
 func main() {
+	var err error
+	if verifier, err = webhook.ImageVerifierFromEnv(); err != nil {
+		log.Fatalf("Invalid image verification settings: %v", err)
+	}
+	if value := os.Getenv("PULSAAR_INJECT_DEFAULT"); value != "" {
+		if injectDefault, err = strconv.ParseBool(value); err != nil {
+			log.Fatalf("Invalid PULSAAR_INJECT_DEFAULT %q: %v", value, err)
+		}
+	}
+
 	http.HandleFunc("/mutate", handleMutate)
+	http.HandleFunc(namespacePath, handleMutate)
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -76,9 +103,17 @@ func handleMutate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if admissionReview.Request == nil {
+		http.Error(w, "AdmissionReview has no request", http.StatusBadRequest)
+		return
+	}
 	response := &v1.AdmissionResponse{
 		UID: admissionReview.Request.UID,
 	}
+	policy := webhook.Policy{Default: injectDefault}
+	if label, ok := strings.CutPrefix(r.URL.Path, namespacePath); ok {
+		policy.Namespace = label
+	}
 
 	if admissionReview.Request.Kind.Kind == "Pod" {
 		pod := &corev1.Pod{}
@@ -86,8 +121,20 @@ func handleMutate(w http.ResponseWriter, r *http.Request) {
 			response.Result = &metav1.Status{
 				Message: err.Error(),
 			}
+		} else if admissionReview.Request.Operation != v1.Create {
+			// Containers cannot be added to an existing pod, so updates
+			// are admitted unchanged.
+			response.Allowed = true
+			if policy.ShouldInject(pod) && !webhook.HasContainer(pod) {
+				response.Warnings = []string{fmt.Sprintf("%s=true takes effect when the pod is created; recreate pod %s/%s to inject the agent", webhook.InjectAnnotation, pod.Namespace, pod.Name)}
+			}
+		} else if images, err := verifiedImages(r, pod, policy); err != nil {
+			response.Result = &metav1.Status{
+				Code:    http.StatusForbidden,
+				Message: err.Error(),
+			}
 		} else {
-			patch, err := mutatePod(pod)
+			patch, err := policy.Mutate(pod, images)
 			if err != nil {
 				response.Result = &metav1.Status{
 					Message: err.Error(),
@@ -116,79 +163,17 @@ func handleMutate(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(respBytes)
 }
 
-func mutatePod(pod *corev1.Pod) ([]byte, error) {
-	// Check for annotation to enable injection
-	if pod.Annotations["pulsaar.io/inject-agent"] != "true" {
-		return nil, nil
+// verifiedImages checks the signature of the agent image pod would get and
+// returns it pinned to the verified digest. Without a verifier, or when no
+// agent will be added, the default images are returned unchecked.
+func verifiedImages(r *http.Request, pod *corev1.Pod, policy webhook.Policy) (webhook.Images, error) {
+	if verifier == nil || !policy.ShouldInject(pod) || webhook.HasContainer(pod) {
+		return webhook.Images{}, nil
 	}
-
-	// Inject sidecar container
-	image := os.Getenv("PULSAAR_AGENT_IMAGE")
-	if image == "" {
-		image = "pulsaar/agent:latest"
-	}
-	sidecar := corev1.Container{
-		Name:  "pulsaar-agent",
-		Image: image,
-		Ports: []corev1.ContainerPort{
-			{
-				ContainerPort: 50051,
-				Name:          "grpc",
-			},
-		},
-		Env: []corev1.EnvVar{
-			{
-				Name:  "PULSAAR_TLS_CERT_FILE",
-				Value: "/etc/pulsaar/tls/tls.crt",
-			},
-			{
-				Name:  "PULSAAR_TLS_KEY_FILE",
-				Value: "/etc/pulsaar/tls/tls.key",
-			},
-			{
-				Name:  "PULSAAR_POD_NAME",
-				Value: pod.Name,
-			},
-			{
-				Name:  "PULSAAR_NAMESPACE",
-				Value: pod.Namespace,
-			},
-		},
-		VolumeMounts: []corev1.VolumeMount{
-			{
-				Name:      "pulsaar-tls",
-				MountPath: "/etc/pulsaar/tls",
-				ReadOnly:  true,
-			},
-		},
-	}
-
-	pod.Spec.Containers = append(pod.Spec.Containers, sidecar)
-
-	// Inject volume for TLS certs
-	volume := corev1.Volume{
-		Name: "pulsaar-tls",
-		VolumeSource: corev1.VolumeSource{
-			Secret: &corev1.SecretVolumeSource{
-				SecretName: "pulsaar-tls",
-			},
-		},
-	}
-	pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
-
-	// Create JSON patch
-	patch := []map[string]interface{}{
-		{
-			"op":    "add",
-			"path":  "/spec/containers/-",
-			"value": sidecar,
-		},
-		{
-			"op":    "add",
-			"path":  "/spec/volumes/-",
-			"value": volume,
-		},
+	sidecar, _ := webhook.Sidecar(pod, webhook.Images{})
+	pinned, err := verifier.Verify(r.Context(), sidecar.Image)
+	if err != nil {
+		return webhook.Images{}, fmt.Errorf("refusing to inject an unverified agent image: %v", err)
 	}
-
-	return json.Marshal(patch)
+	return webhook.Images{Linux: pinned, Windows: pinned}, nil
 }
@@ -1,65 +1,175 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/VrushankPatel/pulsaar/pkg/webhook"
+	v1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
-func TestMutatePod(t *testing.T) {
-	tests := []struct {
-		name     string
-		pod      *corev1.Pod
-		expected bool // true if patch is generated
-	}{
-		{
-			name: "no annotation",
-			pod: &corev1.Pod{
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{Name: "app", Image: "nginx"},
-					},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "with annotation",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{
-						"pulsaar.io/inject-agent": "true",
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{Name: "app", Image: "nginx"},
-					},
-				},
-			},
-			expected: true,
+func admit(t *testing.T, op v1.Operation, pod *corev1.Pod) *v1.AdmissionResponse {
+	t.Helper()
+	return admitAt(t, "/mutate", op, pod)
+}
+
+// admitAt sends pod to the webhook path a namespace's label selects.
+func admitAt(t *testing.T, path string, op v1.Operation, pod *corev1.Pod) *v1.AdmissionResponse {
+	t.Helper()
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review := v1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &v1.AdmissionRequest{
+			UID:       "1234",
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Operation: op,
+			Object:    runtime.RawExtension{Raw: raw},
 		},
 	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handleMutate(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+	}
+	var resp v1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Response
+}
+
+func TestHandleMutate(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"pulsaar.io/inject-agent": "true"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+	}
+	resp := admit(t, v1.Create, pod)
+	if !resp.Allowed || resp.UID != "1234" || resp.PatchType == nil || len(resp.Patch) == 0 {
+		t.Errorf("expected an allowed response with a patch, got %+v", resp)
+	}
+
+	pod.Annotations = nil
+	resp = admit(t, v1.Create, pod)
+	if !resp.Allowed || resp.Patch != nil {
+		t.Errorf("expected unannotated pods to be allowed unchanged, got %+v", resp)
+	}
+}
+
+func TestHandleMutateReinvocation(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "shop", Annotations: map[string]string{"pulsaar.io/inject-agent": "true"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+	}
+	resp := admit(t, v1.Create, pod)
+	var patch []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(resp.Patch, &patch); err != nil || len(patch) != 2 || patch[1].Path != "/spec/volumes" {
+		t.Fatalf("expected the container and a new volumes array, got %s, %v", resp.Patch, err)
+	}
 
-	for _, tt := range tests {
+	// Admitting the mutated pod again, as on reinvocation, changes nothing.
+	var sidecar corev1.Container
+	var volumes []corev1.Volume
+	_ = json.Unmarshal(patch[0].Value, &sidecar)
+	_ = json.Unmarshal(patch[1].Value, &volumes)
+	pod.Spec.Containers = append(pod.Spec.Containers, sidecar)
+	pod.Spec.Volumes = volumes
+	if resp := admit(t, v1.Create, pod); !resp.Allowed || resp.Patch != nil {
+		t.Errorf("expected an injected pod to be admitted unchanged, got %+v", resp)
+	}
+
+	// Updates never add containers, which are immutable.
+	pod.Spec.Containers, pod.Spec.Volumes = pod.Spec.Containers[:1], nil
+	resp = admit(t, v1.Update, pod)
+	if !resp.Allowed || resp.Patch != nil || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "recreate pod shop/web-0") {
+		t.Errorf("expected an update to be admitted unchanged with a warning, got %+v", resp)
+	}
+}
+
+func TestHandleMutateRejectsUnverifiedImage(t *testing.T) {
+	registry := httptest.NewTLSServer(http.NotFoundHandler())
+	defer registry.Close()
+	t.Setenv("PULSAAR_AGENT_IMAGE", strings.TrimPrefix(registry.URL, "https://")+"/pulsaar/agent:v1")
+	t.Setenv("PULSAAR_COSIGN_KEY", "")
+	t.Setenv("PULSAAR_COSIGN_IDENTITY", "ci@example.com")
+	t.Setenv("PULSAAR_COSIGN_FULCIO_ROOT", "")
+	if _, err := webhook.ImageVerifierFromEnv(); err == nil {
+		t.Fatal("expected keyless settings without trust roots to be refused")
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	v, err := webhook.NewImageVerifier(webhook.VerifyConfig{Key: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Client = registry.Client()
+	verifier = v
+	defer func() { verifier = nil }()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{webhook.InjectAnnotation: "true"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+	}
+	resp := admit(t, v1.Create, pod)
+	if resp.Allowed || resp.Result == nil || resp.Result.Code != http.StatusForbidden || !strings.Contains(resp.Result.Message, "unverified agent image") {
+		t.Errorf("expected the pod to be refused, got %+v", resp)
+	}
+
+	// Pods not asking for the agent are not held up by verification.
+	pod.Annotations = nil
+	if resp := admit(t, v1.Create, pod); !resp.Allowed {
+		t.Errorf("expected a pod without the annotation to be admitted, got %+v", resp)
+	}
+}
+
+func TestHandleMutatePrecedence(t *testing.T) {
+	pod := func(annotation string) *corev1.Pod {
+		p := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}}}
+		if annotation != "" {
+			p.Annotations = map[string]string{webhook.InjectAnnotation: annotation}
+		}
+		return p
+	}
+	for _, tt := range []struct {
+		name, path, annotation string
+		clusterDefault, inject bool
+	}{
+		{"namespace enabled", "/mutate/namespace/true", "", false, true},
+		{"pod opts out of an enabled namespace", "/mutate/namespace/true", "false", false, false},
+		{"pod opts in to a disabled namespace", "/mutate/namespace/false", "true", true, true},
+		{"namespace opts out of the cluster default", "/mutate/namespace/false", "", true, false},
+		{"cluster default", "/mutate", "", true, true},
+		{"pod opts out of the cluster default", "/mutate", "false", true, false},
+	} {
 		t.Run(tt.name, func(t *testing.T) {
-			patch, err := mutatePod(tt.pod)
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
-			if (patch != nil) != tt.expected {
-				t.Errorf("expected patch %v, got %v", tt.expected, patch != nil)
-			}
-			if patch != nil {
-				var operations []map[string]interface{}
-				if err := json.Unmarshal(patch, &operations); err != nil {
-					t.Fatalf("invalid patch: %v", err)
-				}
-				if len(operations) != 2 {
-					t.Errorf("expected 2 operations, got %d", len(operations))
-				}
+			injectDefault = tt.clusterDefault
+			defer func() { injectDefault = false }()
+			resp := admitAt(t, tt.path, v1.Create, pod(tt.annotation))
+			if !resp.Allowed || (resp.Patch != nil) != tt.inject {
+				t.Errorf("expected injection %v, got %+v", tt.inject, resp)
 			}
 		})
 	}
@@ -0,0 +1,274 @@
+// Package access implements just-in-time access approval. A user files a
+// PulsaarAccessRequest for a pod and a set of paths, an approver approves or
+// denies it, and agents started with --require-approval serve the user only
+// within the approved paths until the approval expires.
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// The PulsaarAccessRequest custom resource.
+const (
+	Group    = "pulsaar.io"
+	Version  = "v1alpha1"
+	Kind     = "PulsaarAccessRequest"
+	Resource = "pulsaaraccessrequests"
+)
+
+// GVR addresses PulsaarAccessRequests through a dynamic client.
+var GVR = schema.GroupVersionResource{Group: Group, Version: Version, Resource: Resource}
+
+// Phases of a request. A request is Pending until decided, and an approved
+// one becomes Expired once its duration has passed.
+const (
+	PhasePending  = "Pending"
+	PhaseApproved = "Approved"
+	PhaseDenied   = "Denied"
+	PhaseExpired  = "Expired"
+)
+
+// MaxDuration is the longest access a request may ask for.
+const MaxDuration = 24 * time.Hour
+
+// ErrSelfApproval is returned when a user tries to approve their own
+// request.
+var ErrSelfApproval = errors.New("a request cannot be approved by the user it grants access to")
+
+// Request is a PulsaarAccessRequest.
+type Request struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              Spec   `json:"spec"`
+	Status            Status `json:"status,omitempty"`
+}
+
+// Spec is what the requester asks for.
+type Spec struct {
+	// User is the caller as the agent identifies it: the subject of the
+	// client certificate, or the ServiceAccount of the identity token.
+	User string `json:"user"`
+	Pod  string `json:"pod"`
+	// Paths limits the access to these directories and the files under
+	// them; empty allows every path the agent's allowed roots do.
+	Paths    []string `json:"paths,omitempty"`
+	Duration string   `json:"duration"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+// Status is the decision on a request. Only approvers should be allowed to
+// update the status subresource.
+type Status struct {
+	Phase     string       `json:"phase,omitempty"`
+	DecidedBy string       `json:"decidedBy,omitempty"`
+	DecidedAt *metav1.Time `json:"decidedAt,omitempty"`
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	Message   string       `json:"message,omitempty"`
+}
+
+// Phase returns the request's phase, Pending until one is set.
+func (r *Request) Phase() string {
+	if r.Status.Phase == "" {
+		return PhasePending
+	}
+	return r.Status.Phase
+}
+
+// NewRequest returns a request for spec in namespace after checking it.
+// The API server names it after the pod.
+func NewRequest(namespace string, spec Spec) (*Request, error) {
+	if spec.User == "" {
+		return nil, fmt.Errorf("the user to grant access to is required")
+	}
+	if spec.Pod == "" {
+		return nil, fmt.Errorf("the pod to access is required")
+	}
+	d, err := time.ParseDuration(spec.Duration)
+	if err != nil || d <= 0 || d > MaxDuration {
+		return nil, fmt.Errorf("invalid duration %q: expected a positive duration of at most %s, e.g. 1h", spec.Duration, MaxDuration)
+	}
+	for i, p := range spec.Paths {
+		if !path.IsAbs(p) {
+			return nil, fmt.Errorf("invalid path %q: must be absolute", p)
+		}
+		spec.Paths[i] = path.Clean(p)
+	}
+	return &Request{
+		TypeMeta:   metav1.TypeMeta{APIVersion: Group + "/" + Version, Kind: Kind},
+		ObjectMeta: metav1.ObjectMeta{GenerateName: spec.Pod + "-", Namespace: namespace},
+		Spec:       spec,
+	}, nil
+}
+
+// Grants reports whether the request lets user access every one of paths
+// on pod at now.
+func (r *Request) Grants(user, pod string, paths []string, now time.Time) bool {
+	if r.Phase() != PhaseApproved || r.Spec.User != user || r.Spec.Pod != pod {
+		return false
+	}
+	if r.Status.ExpiresAt == nil || !now.Before(r.Status.ExpiresAt.Time) {
+		return false
+	}
+	for _, p := range paths {
+		if !within(p, r.Spec.Paths) {
+			return false
+		}
+	}
+	return true
+}
+
+// within reports whether p is one of scope or under one of them. TailFile
+// patterns are matched by the directory they name, so /var/log/*.log is
+// within /var/log.
+func within(p string, scope []string) bool {
+	if len(scope) == 0 {
+		return true
+	}
+	p = path.Clean("/" + p)
+	for _, s := range scope {
+		if s == "/" || p == s || strings.HasPrefix(p, s+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Settle applies the rules the controller enforces: an approval by the
+// requester is turned into a denial, an approval without an expiry gets one
+// from its duration, and an approval past its expiry expires. It reports
+// whether the status changed.
+func (r *Request) Settle(now time.Time) bool {
+	if r.Phase() != PhaseApproved {
+		return false
+	}
+	if r.Status.DecidedBy == r.Spec.User {
+		r.Status.Phase, r.Status.ExpiresAt = PhaseDenied, nil
+		r.Status.Message = ErrSelfApproval.Error()
+		return true
+	}
+	changed := false
+	if r.Status.ExpiresAt == nil {
+		d, err := time.ParseDuration(r.Spec.Duration)
+		if err != nil || d <= 0 || d > MaxDuration {
+			r.Status.Phase = PhaseDenied
+			r.Status.Message = fmt.Sprintf("invalid duration %q", r.Spec.Duration)
+			return true
+		}
+		decided := now
+		if r.Status.DecidedAt != nil {
+			decided = r.Status.DecidedAt.Time
+		} else {
+			r.Status.DecidedAt = &metav1.Time{Time: now}
+		}
+		r.Status.ExpiresAt = &metav1.Time{Time: decided.Add(d)}
+		changed = true
+	}
+	if !now.Before(r.Status.ExpiresAt.Time) {
+		r.Status.Phase = PhaseExpired
+		return true
+	}
+	return changed
+}
+
+// Decide approves or denies a pending request on behalf of decider. An
+// approval lasts the requested duration from now.
+func (r *Request) Decide(decider string, approve bool, message string, now time.Time) error {
+	if r.Phase() != PhasePending {
+		return fmt.Errorf("request %s is already %s", r.Name, strings.ToLower(r.Phase()))
+	}
+	decided := &metav1.Time{Time: now}
+	if !approve {
+		r.Status = Status{Phase: PhaseDenied, DecidedBy: decider, DecidedAt: decided, Message: message}
+		return nil
+	}
+	if decider == r.Spec.User {
+		return ErrSelfApproval
+	}
+	d, err := time.ParseDuration(r.Spec.Duration)
+	if err != nil || d <= 0 || d > MaxDuration {
+		return fmt.Errorf("request %s has an invalid duration %q", r.Name, r.Spec.Duration)
+	}
+	r.Status = Status{Phase: PhaseApproved, DecidedBy: decider, DecidedAt: decided, ExpiresAt: &metav1.Time{Time: now.Add(d)}, Message: message}
+	return nil
+}
+
+// FromUnstructured converts an object read through a dynamic client.
+func FromUnstructured(u *unstructured.Unstructured) (*Request, error) {
+	var r Request
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &r); err != nil {
+		return nil, fmt.Errorf("invalid %s %s/%s: %v", Kind, u.GetNamespace(), u.GetName(), err)
+	}
+	return &r, nil
+}
+
+func (r *Request) toUnstructured() (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(r)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// Create files r.
+func Create(ctx context.Context, c dynamic.Interface, r *Request) (*Request, error) {
+	u, err := r.toUnstructured()
+	if err != nil {
+		return nil, err
+	}
+	created, err := c.Resource(GVR).Namespace(r.Namespace).Create(ctx, u, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return FromUnstructured(created)
+}
+
+// Get returns the named request.
+func Get(ctx context.Context, c dynamic.Interface, namespace, name string) (*Request, error) {
+	u, err := c.Resource(GVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return FromUnstructured(u)
+}
+
+// List returns the requests in namespace, or in every namespace when it is
+// empty.
+func List(ctx context.Context, c dynamic.Interface, namespace string) ([]Request, error) {
+	list, err := c.Resource(GVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]Request, 0, len(list.Items))
+	for i := range list.Items {
+		r, err := FromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *r)
+	}
+	return requests, nil
+}
+
+// UpdateStatus writes r's status.
+func UpdateStatus(ctx context.Context, c dynamic.Interface, r *Request) (*Request, error) {
+	u, err := r.toUnstructured()
+	if err != nil {
+		return nil, err
+	}
+	updated, err := c.Resource(GVR).Namespace(r.Namespace).UpdateStatus(ctx, u, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return FromUnstructured(updated)
+}
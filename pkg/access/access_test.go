@@ -0,0 +1,133 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func approved(t *testing.T, paths ...string) *Request {
+	t.Helper()
+	r, err := NewRequest("shop", Spec{User: "alice", Pod: "web-0", Paths: paths, Duration: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Decide("bob", true, "", now); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestNewRequestValidates(t *testing.T) {
+	for _, spec := range []Spec{
+		{Pod: "web-0", Duration: "1h"},
+		{User: "alice", Duration: "1h"},
+		{User: "alice", Pod: "web-0", Duration: "forever"},
+		{User: "alice", Pod: "web-0", Duration: "48h"},
+		{User: "alice", Pod: "web-0", Duration: "1h", Paths: []string{"var/log"}},
+	} {
+		if _, err := NewRequest("shop", spec); err == nil {
+			t.Errorf("expected %+v to be rejected", spec)
+		}
+	}
+	r, err := NewRequest("shop", Spec{User: "alice", Pod: "web-0", Duration: "30m", Paths: []string{"/var/log/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Spec.Paths[0] != "/var/log" || r.GenerateName != "web-0-" || r.Phase() != PhasePending {
+		t.Errorf("unexpected request %+v", r)
+	}
+}
+
+func TestGrants(t *testing.T) {
+	r := approved(t, "/var/log", "/app/config.yaml")
+	for _, c := range []struct {
+		user, pod string
+		paths     []string
+		at        time.Time
+		want      bool
+	}{
+		{"alice", "web-0", []string{"/var/log/app.log"}, now, true},
+		{"alice", "web-0", []string{"/app/config.yaml", "/var/log/*.log"}, now.Add(59 * time.Minute), true},
+		{"alice", "web-0", []string{"/var/logs"}, now, false},
+		{"alice", "web-0", []string{"/var/log/../../etc/shadow"}, now, false},
+		{"alice", "web-0", []string{"/var/log/app.log"}, now.Add(time.Hour), false},
+		{"mallory", "web-0", []string{"/var/log/app.log"}, now, false},
+		{"alice", "web-1", []string{"/var/log/app.log"}, now, false},
+	} {
+		if got := r.Grants(c.user, c.pod, c.paths, c.at); got != c.want {
+			t.Errorf("Grants(%s, %s, %v, %s) = %v, want %v", c.user, c.pod, c.paths, c.at, got, c.want)
+		}
+	}
+	if !approved(t).Grants("alice", "web-0", []string{"/etc/hosts"}, now) {
+		t.Error("expected a request without paths to grant every path")
+	}
+}
+
+func TestDecide(t *testing.T) {
+	r, _ := NewRequest("shop", Spec{User: "alice", Pod: "web-0", Duration: "1h"})
+	if err := r.Decide("alice", true, "", now); !errors.Is(err, ErrSelfApproval) || r.Phase() != PhasePending {
+		t.Errorf("expected self-approval to be refused, got %v with phase %s", err, r.Phase())
+	}
+	if err := r.Decide("bob", false, "not during the freeze", now); err != nil || r.Phase() != PhaseDenied {
+		t.Fatalf("expected a denial, got %v with phase %s", err, r.Phase())
+	}
+	if err := r.Decide("carol", true, "", now); err == nil {
+		t.Error("expected a decided request to stay decided")
+	}
+}
+
+func TestSettle(t *testing.T) {
+	r := approved(t)
+	if r.Settle(now.Add(time.Minute)) {
+		t.Error("expected a current approval to be left alone")
+	}
+	if !r.Settle(now.Add(time.Hour)) || r.Phase() != PhaseExpired {
+		t.Errorf("expected the approval to expire, got %s", r.Phase())
+	}
+
+	// Approvals written with kubectl get their expiry, or are refused when
+	// the requester approved them.
+	r, _ = NewRequest("shop", Spec{User: "alice", Pod: "web-0", Duration: "2h"})
+	r.Status = Status{Phase: PhaseApproved, DecidedBy: "bob"}
+	if !r.Settle(now) || !r.Status.ExpiresAt.Time.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("expected an expiry two hours from now, got %+v", r.Status)
+	}
+	r.Status = Status{Phase: PhaseApproved, DecidedBy: "alice"}
+	if !r.Settle(now) || r.Phase() != PhaseDenied {
+		t.Errorf("expected a self-approval to be denied, got %+v", r.Status)
+	}
+}
+
+func TestCreateAndUpdateStatus(t *testing.T) {
+	c := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{GVR: Kind + "List"})
+	ctx := context.Background()
+
+	r, _ := NewRequest("shop", Spec{User: "alice", Pod: "web-0", Duration: "1h", Paths: []string{"/var/log"}})
+	r.Name = "web-0-abcde"
+	created, err := Create(ctx, c, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := created.Decide("bob", true, "", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateStatus(ctx, c, created); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := List(ctx, c, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || !requests[0].Grants("alice", "web-0", []string{"/var/log/app.log"}, now) {
+		t.Errorf("expected the approved request back, got %+v", requests)
+	}
+}
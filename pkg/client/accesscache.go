@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// accessCacheFile names the record of a successful access check. The key
+// covers the cluster, a hash of the credentials and the pod, so other
+// credentials or another pod are checked again; the credentials themselves
+// are never written.
+func accessCacheFile(dir string, config *rest.Config, namespace, pod string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{config.Host, credentialKey(config), namespace, pod}, "\x00")))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16]))
+}
+
+// credentialKey identifies whoever config authenticates as: its token,
+// client certificate, basic auth user, or the auth provider or exec plugin
+// that produces them.
+func credentialKey(config *rest.Config) string {
+	parts := []string{config.BearerToken, config.BearerTokenFile, config.Username, config.CertFile, string(config.CertData), config.Impersonate.UserName}
+	parts = append(parts, config.Impersonate.Groups...)
+	if config.AuthProvider != nil {
+		parts = append(parts, config.AuthProvider.Name, config.AuthProvider.Config["client-id"], config.AuthProvider.Config["id-token"])
+	}
+	if config.ExecProvider != nil {
+		parts = append(parts, config.ExecProvider.Command)
+		parts = append(parts, config.ExecProvider.Args...)
+		for _, env := range config.ExecProvider.Env {
+			parts = append(parts, env.Name+"="+env.Value)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// accessCached reports whether file records a check that has not expired.
+func accessCached(file string, now time.Time) bool {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	expires, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil || !now.Before(expires) {
+		_ = os.Remove(file)
+		return false
+	}
+	return true
+}
+
+func recordAccess(file string, expires time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(expires.Format(time.RFC3339Nano)), 0o600)
+}
+
+// CheckAccessCached runs CheckAccess unless the same check succeeded within
+// ttl, and remembers successes in dir. Denials are never cached. A zero ttl
+// always checks.
+func CheckAccessCached(ctx context.Context, config *rest.Config, namespace, pod string, ttl time.Duration, dir string) error {
+	if ttl <= 0 {
+		return CheckAccess(ctx, config, namespace, pod)
+	}
+	if dir == "" {
+		dir = filepath.Join(DefaultCacheDir(), "access")
+	}
+	file := accessCacheFile(dir, config, namespace, pod)
+	now := time.Now()
+	if accessCached(file, now) {
+		return nil
+	}
+	if err := CheckAccess(ctx, config, namespace, pod); err != nil {
+		return err
+	}
+	_ = recordAccess(file, now.Add(ttl))
+	return nil
+}
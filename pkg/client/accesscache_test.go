@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// fakeReviewServer answers SelfSubjectAccessReviews and counts the calls.
+func fakeReviewServer(t *testing.T, allowed *atomic.Bool) (*rest.Config, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/selfsubjectaccessreviews"):
+			if allowed.Load() {
+				_, _ = w.Write([]byte(`{"apiVersion":"authorization.k8s.io/v1","kind":"SelfSubjectAccessReview","status":{"allowed":true}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"apiVersion":"authorization.k8s.io/v1","kind":"SelfSubjectAccessReview","status":{"allowed":false}}`))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &rest.Config{Host: srv.URL, BearerToken: "token-a"}, &calls
+}
+
+func TestCheckAccessCached(t *testing.T) {
+	var allowed atomic.Bool
+	allowed.Store(true)
+	config, calls := fakeReviewServer(t, &allowed)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := CheckAccessCached(ctx, config, "shop", "web-0", time.Minute, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected one review, got %d calls", got)
+	}
+
+	other := *config
+	other.BearerToken = "token-b"
+	if err := CheckAccessCached(ctx, &other, "shop", "web-0", time.Minute, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckAccessCached(ctx, config, "shop", "web-1", time.Minute, dir); err != nil {
+		t.Fatal(err)
+	}
+	cert := *config
+	cert.BearerToken, cert.CertData = "", []byte("client certificate")
+	if err := CheckAccessCached(ctx, &cert, "shop", "web-0", time.Minute, dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.Load(); got != 4 {
+		t.Errorf("expected new credentials and a new pod to be checked again, got %d calls", got)
+	}
+
+	allowed.Store(false)
+	for i := 0; i < 2; i++ {
+		if err := CheckAccessCached(ctx, config, "shop", "web-2", time.Minute, dir); err == nil {
+			t.Fatal("expected access to be denied")
+		}
+	}
+	if got := calls.Load(); got != 6 {
+		t.Errorf("expected denials not to be cached, got %d calls", got)
+	}
+
+	if err := CheckAccessCached(ctx, config, "shop", "web-0", 0, dir); err == nil {
+		t.Error("expected a zero TTL to bypass the cache")
+	}
+}
+
+func TestAccessCacheExpiry(t *testing.T) {
+	file := accessCacheFile(t.TempDir(), &rest.Config{Host: "https://cluster", BearerToken: "t"}, "shop", "web-0")
+	now := time.Now()
+	if err := recordAccess(file, now.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if !accessCached(file, now) {
+		t.Error("expected a fresh record to be used")
+	}
+	if accessCached(file, now.Add(2*time.Second)) {
+		t.Error("expected an expired record to be ignored")
+	}
+	if accessCached(file, now) {
+		t.Error("expected the expired record to be removed")
+	}
+}
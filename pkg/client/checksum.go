@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// ErrChecksum is returned for a checksum against an agent that predates
+// the Checksum RPC.
+var ErrChecksum = errors.New("the agent predates checksums (Checksum); upgrade the agent to verify copies")
+
+// MismatchError reports a copy whose digest differs from the agent's digest
+// of the same bytes of the source.
+type MismatchError struct {
+	Path        string
+	Size        int64
+	LocalDigest string
+	AgentDigest string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for '%s' (%d bytes): received sha256 %s, agent reports sha256 %s", e.Path, e.Size, e.LocalDigest, e.AgentDigest)
+}
+
+// Checksum returns the agent's SHA-256 of the file at path, or of its first
+// length bytes when length is positive.
+func (c *Client) Checksum(ctx context.Context, path string, length int64) (*api.ChecksumResponse, error) {
+	if !c.Supports(ctx, "Checksum") {
+		return nil, ErrChecksum
+	}
+	return c.agent.Checksum(ctx, &api.ChecksumRequest{Path: path, Length: length, AllowedRoots: c.allowedRoots})
+}
+
+// StreamVerified is Stream followed by a check that the bytes written to w
+// hash to the agent's digest of the same length of the source. A file that
+// changed while it was streamed fails the check too. It returns ErrChecksum,
+// before streaming, when the agent cannot compute checksums, and a
+// *MismatchError when the digests differ.
+func (c *Client) StreamVerified(ctx context.Context, path string, chunkSize int64, w io.Writer) error {
+	if !c.Supports(ctx, "Checksum") {
+		return ErrChecksum
+	}
+	h := &hashWriter{h: sha256.New()}
+	if err := c.Stream(ctx, path, chunkSize, io.MultiWriter(w, h)); err != nil {
+		return err
+	}
+	return c.verify(ctx, path, h)
+}
+
+// verify compares the digest of the bytes hashed so far with the agent's
+// digest of as many bytes of path. An empty copy asks for the whole file,
+// which must be empty too.
+func (c *Client) verify(ctx context.Context, path string, h *hashWriter) error {
+	resp, err := c.Checksum(ctx, path, h.n)
+	if err != nil {
+		return err
+	}
+	local := hex.EncodeToString(h.h.Sum(nil))
+	if resp.SizeBytes != h.n || resp.Digest != local {
+		return &MismatchError{Path: path, Size: h.n, LocalDigest: local, AgentDigest: resp.Digest}
+	}
+	return nil
+}
+
+// hashWriter hashes and counts the bytes written to it.
+type hashWriter struct {
+	h hash.Hash
+	n int64
+}
+
+func (w *hashWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return w.h.Write(p)
+}
@@ -0,0 +1,603 @@
+// Package client provides programmatic access to Pulsaar agents. It wraps
+// the steps the pulsaar CLI performs — RBAC checks, ephemeral agent
+// injection, port-forwarding or apiserver proxying, and TLS — behind a small
+// API so other tools and operators can read files from pods the same way.
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+const defaultTailInterval = time.Second
+
+// Client is a connection to the Pulsaar agent in one pod.
+type Client struct {
+	agent        api.PulsaarAgentClient
+	allowedRoots []string
+	closeFn      func() error
+	// redial restores a lost connection, such as a port-forward whose
+	// kubectl exited, before a broken stream is opened again. It may be nil.
+	redial func(ctx context.Context) error
+
+	infoMu sync.Mutex
+	info   *api.HealthResponse
+	capsMu sync.Mutex
+	caps   *api.CapabilitiesResponse
+}
+
+// New wraps an existing gRPC connection to an agent. Callers own conn and
+// must close it themselves; use Connect to let the Client manage it.
+func New(conn grpc.ClientConnInterface, allowedRoots ...string) *Client {
+	return &Client{
+		agent:        api.NewPulsaarAgentClient(conn),
+		allowedRoots: allowedRoots,
+		closeFn:      func() error { return nil },
+	}
+}
+
+// Close releases the connection and any port-forward started by Connect.
+func (c *Client) Close() error {
+	return c.closeFn()
+}
+
+// listPageSize is how many entries List and ListPages request per call.
+const listPageSize = 1000
+
+// List returns the entries of the directory at path, sorted by name. Large
+// directories are fetched a page at a time.
+func (c *Client) List(ctx context.Context, path string) ([]*api.FileInfo, error) {
+	var entries []*api.FileInfo
+	err := c.ListPages(ctx, path, ListOptions{}, func(page []*api.FileInfo) error {
+		entries = append(entries, page...)
+		return nil
+	})
+	return entries, err
+}
+
+// ListOptions selects and orders the entries returned by ListPages and
+// ListStream. The agent applies them before paging, so only matching
+// entries are transferred. The zero value lists every entry by name.
+type ListOptions struct {
+	// SortBy is "name" (the default), "size" or "mtime". ListStream
+	// ignores it.
+	SortBy string
+	// Descending reverses the sort order.
+	Descending bool
+	// NameGlob keeps entries whose name matches a path.Match pattern such
+	// as "*.log".
+	NameGlob string
+	// MinSize keeps entries of at least this many bytes.
+	MinSize int64
+	// ModifiedSince keeps entries modified at or after this time.
+	ModifiedSince time.Time
+}
+
+func (o ListOptions) filtered() bool {
+	return o != ListOptions{}
+}
+
+func (o ListOptions) request(req *api.ListRequest) *api.ListRequest {
+	req.SortBy, req.NameGlob, req.MinSize = o.SortBy, o.NameGlob, o.MinSize
+	if o.Descending {
+		req.Order = "desc"
+	}
+	if !o.ModifiedSince.IsZero() {
+		req.ModifiedSince = timestamppb.New(o.ModifiedSince)
+	}
+	return req
+}
+
+// errListFilters is returned for list options against an agent that would
+// ignore them and return every entry by name.
+var errListFilters = errors.New("the agent predates sorting and filtering listings; upgrade the agent or list without them")
+
+// ListPages calls fn with each page of the entries of the directory at path,
+// so very large directories can be processed without holding every entry.
+// Agents that predate paging return everything in one page.
+func (c *Client) ListPages(ctx context.Context, path string, opts ListOptions, fn func([]*api.FileInfo) error) error {
+	if opts.filtered() && !c.Supports(ctx, api.FeatureListFilters) {
+		return errListFilters
+	}
+	req := opts.request(&api.ListRequest{Path: path, AllowedRoots: c.allowedRoots, PageSize: listPageSize})
+	for {
+		resp, err := c.agent.ListDirectory(ctx, req)
+		if err != nil {
+			return err
+		}
+		if err := fn(resp.Entries); err != nil {
+			return err
+		}
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// ListStream calls fn with batches of the entries of the directory at path
+// as the agent reads them, in directory order rather than sorted, so the
+// first entries of a huge directory arrive without waiting for the rest.
+// Agents without ListDirectoryStream are listed with ListPages instead.
+// opts.SortBy and opts.Descending are ignored.
+func (c *Client) ListStream(ctx context.Context, path string, opts ListOptions, fn func([]*api.FileInfo) error) error {
+	opts.SortBy, opts.Descending = "", false
+	if !c.Supports(ctx, "ListDirectoryStream") {
+		return c.ListPages(ctx, path, opts, fn)
+	}
+	if opts.filtered() && !c.Supports(ctx, api.FeatureListFilters) {
+		return errListFilters
+	}
+	stream, err := c.agent.ListDirectoryStream(ctx, opts.request(&api.ListRequest{Path: path, AllowedRoots: c.allowedRoots}))
+	if err != nil {
+		return err
+	}
+	for first := true; ; first = false {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if first && status.Code(err) == codes.Unimplemented {
+			return c.ListPages(ctx, path, opts, fn)
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(resp.Entries); err != nil {
+			return err
+		}
+	}
+}
+
+// Stat returns information about the file or directory at path.
+func (c *Client) Stat(ctx context.Context, path string) (*api.FileInfo, error) {
+	resp, err := c.agent.Stat(ctx, &api.StatRequest{Path: path, AllowedRoots: c.allowedRoots})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Info, nil
+}
+
+// Preview returns the metadata of path with up to headBytes from the start
+// and tailBytes from the end of the file, in one call. With both zero the
+// agent returns a 4KB head.
+func (c *Client) Preview(ctx context.Context, path string, headBytes, tailBytes int64) (*api.PreviewResponse, error) {
+	if !c.Supports(ctx, "Preview") {
+		return nil, errPreview
+	}
+	return c.agent.Preview(ctx, &api.PreviewRequest{Path: path, HeadBytes: headBytes, TailBytes: tailBytes, AllowedRoots: c.allowedRoots})
+}
+
+// Search returns lines matching the regular expression pattern in files
+// under path. maxMatches of zero uses the agent default (100).
+func (c *Client) Search(ctx context.Context, path, pattern string, maxMatches int32) (*api.SearchResponse, error) {
+	if !c.Supports(ctx, "Search") {
+		return nil, errSearch
+	}
+	return c.agent.Search(ctx, &api.SearchRequest{Path: path, Pattern: pattern, MaxMatches: maxMatches, AllowedRoots: c.allowedRoots})
+}
+
+// FindOptions selects the entries returned by Find. The zero value matches
+// every entry.
+type FindOptions struct {
+	// NameGlob keeps entries whose name matches a path.Match pattern such
+	// as "*.log".
+	NameGlob string
+	// MinSize keeps entries of at least this many bytes.
+	MinSize int64
+	// ModifiedSince keeps entries modified at or after this time.
+	ModifiedSince time.Time
+	// Type keeps entries of this type, e.g. api.FileType_FILE_TYPE_REGULAR.
+	Type api.FileType
+	// MaxResults caps the matches; zero uses the agent default (1000).
+	MaxResults int32
+}
+
+// Find returns the entries in the tree under the directory at path that
+// pass opts. The agent walks the tree and filters, so only matches are
+// transferred.
+func (c *Client) Find(ctx context.Context, path string, opts FindOptions) (*api.FindResponse, error) {
+	if !c.Supports(ctx, "Find") {
+		return nil, errFind
+	}
+	req := &api.FindRequest{
+		Path:         path,
+		NameGlob:     opts.NameGlob,
+		MinSize:      opts.MinSize,
+		FileType:     opts.Type,
+		MaxResults:   opts.MaxResults,
+		AllowedRoots: c.allowedRoots,
+	}
+	if !opts.ModifiedSince.IsZero() {
+		req.ModifiedSince = timestamppb.New(opts.ModifiedSince)
+	}
+	return c.agent.Find(ctx, req)
+}
+
+// errPreview, errSearch and errFind are returned for RPCs the agent does
+// not advertise.
+var (
+	errPreview = errors.New("the agent predates previews (Preview); upgrade the agent or read the file instead")
+	errSearch  = errors.New("the agent predates searching (Search); upgrade the agent")
+	errFind    = errors.New("the agent predates finding files (Find); upgrade the agent or use explore with filters")
+)
+
+// Read returns up to length bytes of the file at path starting at offset. A
+// length of zero reads as much as the agent allows in one call (1MB), and
+// longer lengths are reduced to that limit when the agent reports it.
+func (c *Client) Read(ctx context.Context, path string, offset, length int64) (*api.ReadResponse, error) {
+	if caps, err := c.AgentCapabilities(ctx); err == nil && caps.MaxReadSize > 0 {
+		length = min(length, caps.MaxReadSize)
+	}
+	return c.agent.ReadFile(ctx, &api.ReadRequest{Path: path, Offset: offset, Length: length, AllowedRoots: c.allowedRoots})
+}
+
+// ReadDecompressed is Read on the decompressed content of a gzip, zstd or
+// bzip2 file; offset and length count decompressed bytes. Other files are
+// read as they are.
+func (c *Client) ReadDecompressed(ctx context.Context, path string, offset, length int64) (*api.ReadResponse, error) {
+	if !c.Supports(ctx, api.FeatureDecompress) {
+		return nil, errDecompress
+	}
+	return c.agent.ReadFile(ctx, &api.ReadRequest{Path: path, Offset: offset, Length: length, AllowedRoots: c.allowedRoots, Decompress: true})
+}
+
+// ReadRanges returns one part per range of the file at path, in order. A
+// negative offset counts back from the end of the file. Agents that
+// predate ranges get a Stat, when an offset is negative, and one read per
+// range instead of a single call.
+func (c *Client) ReadRanges(ctx context.Context, path string, ranges []*api.ByteRange) ([]*api.ReadPart, error) {
+	if c.Supports(ctx, api.FeatureReadRanges) {
+		resp, err := c.agent.ReadFile(ctx, &api.ReadRequest{Path: path, Ranges: ranges, AllowedRoots: c.allowedRoots})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Parts, nil
+	}
+	var size int64
+	for _, rg := range ranges {
+		if rg.Offset < 0 {
+			info, err := c.Stat(ctx, path)
+			if err != nil {
+				return nil, err
+			}
+			size = info.SizeBytes
+			break
+		}
+	}
+	parts := make([]*api.ReadPart, 0, len(ranges))
+	for _, rg := range ranges {
+		offset := rg.Offset
+		if offset < 0 {
+			offset = max(0, size+offset)
+		}
+		resp, err := c.Read(ctx, path, offset, rg.Length)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &api.ReadPart{Offset: offset, Data: resp.Data, Eof: resp.Eof})
+	}
+	return parts, nil
+}
+
+// Stream copies the whole file at path to w in chunks of chunkSize bytes.
+func (c *Client) Stream(ctx context.Context, path string, chunkSize int64, w io.Writer) error {
+	return c.stream(ctx, &api.StreamRequest{Path: path, ChunkSize: chunkSize, AllowedRoots: c.allowedRoots}, w)
+}
+
+// StreamDecompressed is Stream on the decompressed content of a gzip, zstd
+// or bzip2 file. Other files are streamed as they are.
+func (c *Client) StreamDecompressed(ctx context.Context, path string, chunkSize int64, w io.Writer) error {
+	if !c.Supports(ctx, api.FeatureDecompress) {
+		return errDecompress
+	}
+	return c.stream(ctx, &api.StreamRequest{Path: path, ChunkSize: chunkSize, AllowedRoots: c.allowedRoots, Decompress: true}, w)
+}
+
+// StreamFiltered streams the output of the jq filter, e.g. ".level,.msg",
+// for each line of a JSON-lines file at path. The agent evaluates the
+// filter, so only the selected fields cross the network; lines that are not
+// JSON are skipped. See package jq for the supported filters. With
+// decompress, a gzip, zstd or bzip2 file is decompressed first.
+func (c *Client) StreamFiltered(ctx context.Context, path, filter string, decompress bool, w io.Writer) error {
+	if !c.Supports(ctx, api.FeatureJQ) {
+		return errJQ
+	}
+	if decompress && !c.Supports(ctx, api.FeatureDecompress) {
+		return errDecompress
+	}
+	return c.stream(ctx, &api.StreamRequest{Path: path, Jq: filter, Decompress: decompress, AllowedRoots: c.allowedRoots}, w)
+}
+
+// errJQ is returned for a filter against an agent that would ignore it and
+// send the whole file.
+var errJQ = errors.New("the agent predates JSON filtering; upgrade the agent or read the file unfiltered")
+
+// errDecompress is returned for decompression against an agent that would
+// ignore the request and send compressed bytes.
+var errDecompress = errors.New("the agent predates decompression; upgrade the agent or read the file without decompressing")
+
+func (c *Client) stream(ctx context.Context, req *api.StreamRequest, w io.Writer) error {
+	stream, err := c.agent.StreamFile(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(resp.Data); err != nil {
+			return err
+		}
+	}
+}
+
+// TailOptions controls Tail.
+type TailOptions struct {
+	// FromStart copies the existing contents before following. By default
+	// Tail starts at the current end of the file.
+	FromStart bool
+	// Since starts at the first line timestamped within this window of now.
+	// It needs a file whose lines begin with timestamps.
+	Since time.Duration
+	// Pattern is a regular expression; only matching lines are written.
+	// The agent filters, so non-matching lines never cross the network.
+	Pattern string
+	// NoFollow returns once the existing contents have been written.
+	NoFollow bool
+	// PollInterval is how often the file is checked for new data when the
+	// agent predates TailFile (default 1s).
+	PollInterval time.Duration
+	// ReconnectFor keeps a followed tail going when its stream breaks, as
+	// when a port-forward or the agent restarts: the stream is opened
+	// again, retrying for up to this long, and each file resumes after the
+	// last data received. Agents that predate resuming continue from the
+	// end of each file instead. Zero returns the first error.
+	ReconnectFor time.Duration
+	// OnReconnect, if set, is called with the error that broke the stream
+	// before reconnecting.
+	OnReconnect func(err error)
+}
+
+// Tail follows the file at path, writing appended data to w until ctx is
+// cancelled. If the file shrinks, for example after log rotation, Tail
+// starts again from the beginning.
+func (c *Client) Tail(ctx context.Context, path string, opts TailOptions, w io.Writer) error {
+	filtered := opts.Since != 0 || opts.Pattern != ""
+	if !c.Supports(ctx, "TailFile") {
+		if filtered {
+			return errTailFilters
+		}
+		return c.pollTail(ctx, path, opts, w)
+	}
+	wrote := false
+	err := c.tailStream(ctx, opts.request(&api.TailRequest{Path: path}, c.allowedRoots), opts, func(resp *api.ReadResponse) error {
+		wrote = true
+		_, err := w.Write(resp.Data)
+		return err
+	})
+	if !wrote && status.Code(err) == codes.Unimplemented {
+		if filtered {
+			return errTailFilters
+		}
+		return c.pollTail(ctx, path, opts, w)
+	}
+	return err
+}
+
+// TailFiles is Tail for several files, or the files matching glob patterns
+// such as /var/log/app/*.log, in one stream. The agent matches globs again
+// as it follows, so new files are picked up. fn receives the data with the
+// path it came from, in the order the agent read it. PollInterval does not
+// apply; an agent without multi-file tailing returns an upgrade hint.
+func (c *Client) TailFiles(ctx context.Context, paths []string, opts TailOptions, fn func(path string, data []byte) error) error {
+	if !c.Supports(ctx, api.FeatureTailPaths) {
+		return errTailPaths
+	}
+	return c.tailStream(ctx, opts.request(&api.TailRequest{Paths: paths}, c.allowedRoots), opts, func(resp *api.ReadResponse) error {
+		return fn(resp.Path, resp.Data)
+	})
+}
+
+func (o TailOptions) request(req *api.TailRequest, allowedRoots []string) *api.TailRequest {
+	req.SinceSeconds = int64(o.Since / time.Second)
+	req.Pattern = o.Pattern
+	req.Follow = !o.NoFollow
+	req.FromStart = o.FromStart
+	req.AllowedRoots = allowedRoots
+	return req
+}
+
+const (
+	minReconnectBackoff = 500 * time.Millisecond
+	maxReconnectBackoff = 5 * time.Second
+)
+
+// tailStream runs req, calling fn with each message. When a followed
+// stream breaks and opts.ReconnectFor allows, it is opened again, resuming
+// each file after the last message received from it.
+func (c *Client) tailStream(ctx context.Context, req *api.TailRequest, opts TailOptions, fn func(*api.ReadResponse) error) error {
+	resume := c.Supports(ctx, api.FeatureTailResume)
+	offsets := make(map[string]int64)
+	backoff := minReconnectBackoff
+	var broken time.Time
+	for {
+		received, err := c.tailOnce(ctx, req, offsets, fn)
+		if err == nil || !req.Follow || opts.ReconnectFor <= 0 || ctx.Err() != nil || !reconnectable(err) {
+			return tailError(ctx, err)
+		}
+		if received || broken.IsZero() {
+			broken, backoff = time.Now(), minReconnectBackoff
+			if opts.OnReconnect != nil {
+				opts.OnReconnect(err)
+			}
+		} else if time.Since(broken) > opts.ReconnectFor {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+		if c.redial != nil {
+			if err := c.redial(ctx); err != nil && time.Since(broken) > opts.ReconnectFor {
+				return err
+			}
+		}
+		req = resumeRequest(req, offsets, resume)
+	}
+}
+
+// tailOnce runs one TailFile stream, recording the offset each file's
+// messages reach. It reports whether any message arrived.
+func (c *Client) tailOnce(ctx context.Context, req *api.TailRequest, offsets map[string]int64, fn func(*api.ReadResponse) error) (bool, error) {
+	stream, err := c.agent.TailFile(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	for received := false; ; received = true {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return received, nil
+		}
+		if err != nil {
+			return received, err
+		}
+		if resp.Offset > 0 {
+			key := resp.Path
+			if key == "" {
+				key = req.Path
+			}
+			offsets[key] = resp.Offset
+		}
+		if err := fn(resp); err != nil {
+			return true, err
+		}
+	}
+}
+
+// reconnectable reports whether a stream error is a dropped connection,
+// such as a port-forward or agent restart, or the agent's limit on how
+// long one stream may run.
+func reconnectable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// resumeRequest continues req after the data already received. Agents
+// that cannot resume continue from the end of each file, which may skip
+// lines written while the stream was down.
+func resumeRequest(req *api.TailRequest, offsets map[string]int64, resume bool) *api.TailRequest {
+	next := proto.Clone(req).(*api.TailRequest)
+	if !resume {
+		next.SinceSeconds, next.FromStart = 0, false
+		return next
+	}
+	next.ResumeOffsets = maps.Clone(offsets)
+	return next
+}
+
+// errTailPaths is returned by TailFiles for an agent that tails one file per
+// call.
+var errTailPaths = errors.New("the agent predates tailing several files or globs at once; upgrade the agent or tail one file")
+
+// errTailFilters is returned for Since or Pattern against an agent without
+// TailFile, which the filtering runs in.
+var errTailFilters = errors.New("the agent predates filtering by time or pattern (TailFile); upgrade the agent or tail without filters")
+
+// pollTail implements Tail with Stat and ReadFile for agents without the
+// TailFile RPC.
+func (c *Client) pollTail(ctx context.Context, path string, opts TailOptions, w io.Writer) error {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultTailInterval
+	}
+
+	var offset int64
+	if !opts.FromStart {
+		info, err := c.Stat(ctx, path)
+		if err != nil {
+			return err
+		}
+		offset = info.SizeBytes
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		info, err := c.Stat(ctx, path)
+		if err != nil {
+			return tailError(ctx, err)
+		}
+		if info.SizeBytes < offset {
+			offset = 0
+		}
+		for offset < info.SizeBytes {
+			resp, err := c.Read(ctx, path, offset, 0)
+			if err != nil {
+				return tailError(ctx, err)
+			}
+			if len(resp.Data) == 0 {
+				break
+			}
+			if _, err := w.Write(resp.Data); err != nil {
+				return err
+			}
+			offset += int64(len(resp.Data))
+		}
+		if opts.NoFollow {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// tailError reports cancellation as ctx.Err() rather than the RPC status it
+// surfaced through.
+func tailError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Health reports the agent's readiness and version.
+func (c *Client) Health(ctx context.Context) (*api.HealthResponse, error) {
+	return c.agent.Health(ctx, &emptypb.Empty{})
+}
+
+// Shutdown asks the agent to exit after grace. It reports false when the
+// agent was already shutting down.
+func (c *Client) Shutdown(ctx context.Context, reason string, grace time.Duration) (bool, error) {
+	resp, err := c.agent.Shutdown(ctx, &api.ShutdownRequest{Reason: reason, GraceSeconds: int64(grace / time.Second)})
+	if err != nil {
+		return false, err
+	}
+	return resp.Accepted, nil
+}
@@ -0,0 +1,739 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	pulsaartesting "github.com/VrushankPatel/pulsaar/pkg/testing"
+)
+
+func newTestClient(t *testing.T, dir string, allowedRoots ...string) (*Client, *pulsaartesting.Agent) {
+	t.Helper()
+	agent := pulsaartesting.NewDirAgent(dir)
+	srv := pulsaartesting.Serve(agent)
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return New(conn, allowedRoots...), agent
+}
+
+func TestClientListStatRead(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, agent := newTestClient(t, dir, "/")
+	ctx := context.Background()
+
+	entries, err := c.List(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "app.log" {
+		t.Errorf("unexpected entries: %v", entries)
+	}
+
+	info, err := c.Stat(ctx, "/app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.SizeBytes != 11 {
+		t.Errorf("expected size 11, got %d", info.SizeBytes)
+	}
+
+	resp, err := c.Read(ctx, "/app.log", 6, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Data) != "world" || !resp.Eof {
+		t.Errorf("unexpected read response: %q eof=%t", resp.Data, resp.Eof)
+	}
+
+	if _, err := c.Stat(ctx, "/missing"); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+
+	preview, err := c.Preview(ctx, "/app.log", 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(preview.Head) != "hello" || string(preview.Tail) != "world" || preview.Info.SizeBytes != 11 {
+		t.Errorf("unexpected preview: %v", preview)
+	}
+
+	found, err := c.Search(ctx, "/", "wor", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Matches) != 1 || found.Matches[0].Path != "/app.log" {
+		t.Errorf("unexpected search matches: %v", found.Matches)
+	}
+
+	for _, req := range agent.Requests() {
+		if len(req.AllowedRoots) != 1 || req.AllowedRoots[0] != "/" {
+			t.Errorf("expected allowed roots [/] on every request, got %v", req.AllowedRoots)
+		}
+	}
+}
+
+func TestClientStream(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data")
+	content := strings.Repeat("0123456789", 100)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestClient(t, dir)
+
+	var buf bytes.Buffer
+	if err := c.Stream(context.Background(), "/data", 64, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != content {
+		t.Errorf("streamed %d bytes, want %d", buf.Len(), len(content))
+	}
+}
+
+func TestClientStreamVerified(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data")
+	content := strings.Repeat("0123456789", 100)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, agent := newTestClient(t, dir)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := c.StreamVerified(ctx, "/data", 64, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != content {
+		t.Errorf("streamed %d bytes, want %d", buf.Len(), len(content))
+	}
+
+	// Rewrite the source while it is streamed, as if it were tampered with.
+	changed := writerFunc(func(p []byte) (int, error) {
+		return len(p), os.WriteFile(path, []byte(strings.ToUpper(strings.Repeat("abcdefghij", 100))), 0644)
+	})
+	var mismatch *MismatchError
+	if err := c.StreamVerified(ctx, "/data", 64, changed); !errors.As(err, &mismatch) || mismatch.Size != int64(len(content)) {
+		t.Errorf("expected a mismatch over %d bytes, got %v", len(content), err)
+	}
+
+	agent.Supported = []string{"StreamFile"}
+	c.info, c.caps = nil, nil
+	if err := c.StreamVerified(ctx, "/data", 64, io.Discard); !errors.Is(err, ErrChecksum) {
+		t.Errorf("expected ErrChecksum from an agent without Checksum, got %v", err)
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// syncBuffer guards a bytes.Buffer shared between Tail and the test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, buf *syncBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for buf.String() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %q, got %q", want, buf.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestClient(t, dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var buf syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- c.Tail(ctx, "/app.log", TailOptions{PollInterval: 10 * time.Millisecond}, &buf) }()
+
+	time.Sleep(50 * time.Millisecond)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("new\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	waitFor(t, &buf, "new\n")
+
+	// Truncation, as after copytruncate rotation, restarts from the top.
+	if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, &buf, "new\nx\n")
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestClientTailFromStart(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestClient(t, dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf syncBuffer
+	go func() {
+		_ = c.Tail(ctx, "/app.log", TailOptions{FromStart: true, PollInterval: 10 * time.Millisecond}, &buf)
+	}()
+	waitFor(t, &buf, "old\n")
+}
+
+func TestClientTailFilters(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte("INFO a\nERROR b\nINFO c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := newTestClient(t, dir)
+
+	var buf bytes.Buffer
+	if err := c.Tail(context.Background(), "/app.log", TailOptions{FromStart: true, Pattern: "ERROR", NoFollow: true}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "ERROR b\n" {
+		t.Errorf("expected only the matching line, got %q", buf.String())
+	}
+}
+
+// legacyAgent is an agent from before the TailFile RPC.
+type legacyAgent struct {
+	*pulsaartesting.Agent
+}
+
+func (legacyAgent) TailFile(*api.TailRequest, api.PulsaarAgent_TailFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method TailFile not implemented")
+}
+
+func TestClientTailFallsBackToPolling(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	agent := pulsaartesting.NewDirAgent(dir)
+	agent.Supported = []string{}
+	srv := pulsaartesting.Serve(legacyAgent{agent})
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	c := New(conn)
+
+	var buf bytes.Buffer
+	if err := c.Tail(context.Background(), "/app.log", TailOptions{FromStart: true, NoFollow: true}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "old\n" {
+		t.Errorf("expected the file contents, got %q", buf.String())
+	}
+
+	err = c.Tail(context.Background(), "/app.log", TailOptions{Pattern: "x", NoFollow: true}, &buf)
+	if err != errTailFilters {
+		t.Errorf("expected an upgrade hint when filters need the agent, got %v", err)
+	}
+}
+
+// flakyTailAgent drops its first TailFile stream after sending the
+// existing contents, as a restarted port-forward would.
+type flakyTailAgent struct {
+	*pulsaartesting.Agent
+	mu   sync.Mutex
+	reqs []*api.TailRequest
+}
+
+func (a *flakyTailAgent) TailFile(req *api.TailRequest, stream api.PulsaarAgent_TailFileServer) error {
+	a.mu.Lock()
+	a.reqs = append(a.reqs, req)
+	first := len(a.reqs) == 1
+	a.mu.Unlock()
+	if first {
+		once := proto.Clone(req).(*api.TailRequest)
+		once.Follow = false
+		if err := a.Agent.TailFile(once, stream); err != nil {
+			return err
+		}
+		return status.Error(codes.Unavailable, "connection reset")
+	}
+	return a.Agent.TailFile(req, stream)
+}
+
+func TestClientTailReconnects(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	agent := &flakyTailAgent{Agent: pulsaartesting.NewDirAgent(dir)}
+	srv := pulsaartesting.Serve(agent)
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	c := New(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf syncBuffer
+	reconnects := make(chan error, 1)
+	opts := TailOptions{FromStart: true, ReconnectFor: 5 * time.Second, OnReconnect: func(err error) { reconnects <- err }}
+	go func() { _ = c.Tail(ctx, "/app.log", opts, &buf) }()
+	waitFor(t, &buf, "one\n")
+	if err := <-reconnects; status.Code(err) != codes.Unavailable {
+		t.Errorf("expected to reconnect after the stream broke, got %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("two\n")
+	_ = f.Close()
+	// The existing line is not sent again.
+	waitFor(t, &buf, "one\ntwo\n")
+
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	if off := agent.reqs[1].ResumeOffsets["/app.log"]; off != 4 {
+		t.Errorf("expected to resume at offset 4, got %v", agent.reqs[1].ResumeOffsets)
+	}
+}
+
+func TestClientTailWithoutReconnect(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := pulsaartesting.Serve(&flakyTailAgent{Agent: pulsaartesting.NewDirAgent(dir)})
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	err = New(conn).Tail(context.Background(), "/app.log", TailOptions{FromStart: true}, io.Discard)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expected the broken stream to end the tail, got %v", err)
+	}
+}
+
+func TestClientHealth(t *testing.T) {
+	c, _ := newTestClient(t, t.TempDir())
+	resp, err := c.Health(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Ready || resp.Version != "test" {
+		t.Errorf("unexpected health response: %v", resp)
+	}
+}
+
+func TestTLSConfigFromEnv(t *testing.T) {
+	t.Setenv("PULSAAR_CLIENT_CERT_FILE", "")
+	t.Setenv("PULSAAR_CLIENT_KEY_FILE", "")
+	t.Setenv("PULSAAR_CA_FILE", "")
+	config, err := TLSConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.InsecureSkipVerify {
+		t.Error("expected InsecureSkipVerify without certificates")
+	}
+
+	t.Setenv("PULSAAR_CA_FILE", filepath.Join(t.TempDir(), "missing.pem"))
+	if _, err := TLSConfigFromEnv(); err == nil {
+		t.Error("expected error for missing CA file")
+	}
+}
+
+func TestConnectRejectsUnknownMethod(t *testing.T) {
+	_, err := Connect(context.Background(), Options{
+		Pod:              "web-0",
+		ConnectionMethod: "ssh",
+		RESTConfig:       &rest.Config{Host: "https://127.0.0.1:6443"},
+		SkipAccessCheck:  true,
+		SkipInjection:    true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown connection method") {
+		t.Errorf("expected unknown connection method error, got %v", err)
+	}
+}
+
+func TestConnectRejectsBadAgentPort(t *testing.T) {
+	_, err := Connect(context.Background(), Options{
+		Pod:             "web-0",
+		AgentPort:       70000,
+		RESTConfig:      &rest.Config{Host: "https://127.0.0.1:6443"},
+		SkipAccessCheck: true,
+		SkipInjection:   true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid agent port") {
+		t.Errorf("expected invalid agent port error, got %v", err)
+	}
+}
+
+func TestOptionsProxyURL(t *testing.T) {
+	config := &rest.Config{Host: "https://k8s:6443"}
+	opts := Options{Namespace: "shop", Pod: "web-0"}
+	if got := opts.proxyURL(config); got != "https://k8s:6443/api/v1/namespaces/shop/pods/web-0/proxy/" {
+		t.Errorf("unexpected default proxy URL %s", got)
+	}
+	opts.AgentPort = 9443
+	if got := opts.proxyURL(config); got != "https://k8s:6443/api/v1/namespaces/shop/pods/web-0:9443/proxy/" {
+		t.Errorf("unexpected proxy URL with a port %s", got)
+	}
+	if opts.agentPort() != 9443 || (Options{}).agentPort() != 50051 {
+		t.Error("expected AgentPort to override the default port")
+	}
+}
+
+func TestValidateImage(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("0f", 32)
+	for _, ref := range []string{
+		"pulsaar/agent:latest",
+		"registry.internal:5000/team/pulsaar-agent:v1.2.3",
+		"registry.internal/pulsaar/agent@" + digest,
+		"registry.internal/pulsaar/agent:v1@" + digest,
+	} {
+		if err := ValidateImage(ref); err != nil {
+			t.Errorf("%s: %v", ref, err)
+		}
+	}
+	for ref, want := range map[string]string{
+		"":                                "invalid image reference",
+		"Pulsaar/Agent":                   "invalid image reference",
+		"pulsaar/agent:latest extra":      "invalid image reference",
+		"pulsaar/agent@sha256:abc":        "invalid digest",
+		"pulsaar/agent@md5:" + digest[7:]: "invalid digest",
+	} {
+		if err := ValidateImage(ref); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected %q, got %v", ref, want, err)
+		}
+	}
+}
+
+func TestDefaultImage(t *testing.T) {
+	t.Setenv("PULSAAR_AGENT_IMAGE", "")
+	t.Setenv("PULSAAR_AGENT_WINDOWS_IMAGE", "")
+	linux := &corev1.Pod{}
+	windows := &corev1.Pod{Spec: corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}}}
+	if got := defaultImage(linux, AgentImages{}); got != defaultAgentImage {
+		t.Errorf("expected %s, got %s", defaultAgentImage, got)
+	}
+	if got := defaultImage(windows, AgentImages{}); got != windowsAgentImage {
+		t.Errorf("expected %s, got %s", windowsAgentImage, got)
+	}
+	t.Setenv("PULSAAR_AGENT_WINDOWS_IMAGE", "registry.local/agent:windows")
+	if got := defaultImage(windows, AgentImages{}); got != "registry.local/agent:windows" {
+		t.Errorf("expected the PULSAAR_AGENT_WINDOWS_IMAGE override, got %s", got)
+	}
+	defaults := AgentImages{Linux: "registry.local/agent@sha256:" + strings.Repeat("a", 64), Windows: "registry.local/agent:win"}
+	if got := defaultImage(windows, defaults); got != defaults.Windows {
+		t.Errorf("expected DefaultAgentImages to override the environment, got %s", got)
+	}
+	if got := defaultImage(linux, defaults); got != defaults.Linux {
+		t.Errorf("expected the Linux default, got %s", got)
+	}
+}
+
+func TestClientDecompress(t *testing.T) {
+	dir := t.TempDir()
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write([]byte("rotated\n"))
+	_ = w.Close()
+	if err := os.WriteFile(filepath.Join(dir, "app.log.1.gz"), gz.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, agent := newTestClient(t, dir)
+
+	resp, err := c.ReadDecompressed(context.Background(), "/app.log.1.gz", 0, 0)
+	if err != nil || string(resp.Data) != "rotated\n" {
+		t.Errorf("expected the decompressed contents, got %q, %v", resp.GetData(), err)
+	}
+	var buf bytes.Buffer
+	if err := c.StreamDecompressed(context.Background(), "/app.log.1.gz", 4, &buf); err != nil || buf.String() != "rotated\n" {
+		t.Errorf("expected the decompressed stream, got %q, %v", buf.String(), err)
+	}
+
+	agent.Supported = []string{"ReadFile", "StreamFile"}
+	c.info, c.caps = nil, nil
+	if _, err := c.ReadDecompressed(context.Background(), "/app.log.1.gz", 0, 0); err != errDecompress {
+		t.Errorf("expected an upgrade hint from an older agent, got %v", err)
+	}
+	if err := c.StreamDecompressed(context.Background(), "/app.log.1.gz", 0, &buf); err != errDecompress {
+		t.Errorf("expected an upgrade hint from an older agent, got %v", err)
+	}
+}
+
+func TestClientStreamFiltered(t *testing.T) {
+	dir := t.TempDir()
+	log := "{\"level\":\"info\",\"msg\":\"ready\"}\nnot json\n{\"level\":\"error\",\"msg\":\"disk full\"}\n"
+	if err := os.WriteFile(filepath.Join(dir, "app.jsonl"), []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, agent := newTestClient(t, dir)
+
+	var buf bytes.Buffer
+	if err := c.StreamFiltered(context.Background(), "/app.jsonl", "{level, msg}", false, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"level\":\"info\",\"msg\":\"ready\"}\n{\"level\":\"error\",\"msg\":\"disk full\"}\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	agent.Supported = []string{"StreamFile", api.FeatureDecompress}
+	c.info, c.caps = nil, nil
+	if err := c.StreamFiltered(context.Background(), "/app.jsonl", ".msg", false, &buf); err != errJQ {
+		t.Errorf("expected an upgrade hint from an older agent, got %v", err)
+	}
+}
+
+func TestClientTailFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.log", "b.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+" line\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, agent := newTestClient(t, dir)
+
+	var got []string
+	err := c.TailFiles(context.Background(), []string{"/*.log"}, TailOptions{FromStart: true, NoFollow: true}, func(path string, data []byte) error {
+		got = append(got, path+"="+string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "") != "/a.log=a.log line\n/b.log=b.log line\n" {
+		t.Errorf("unexpected tagged output %q", got)
+	}
+
+	agent.Supported = []string{"TailFile"}
+	c.info, c.caps = nil, nil
+	if err := c.TailFiles(context.Background(), []string{"/*.log"}, TailOptions{NoFollow: true}, nil); err != errTailPaths {
+		t.Errorf("expected an upgrade hint from an older agent, got %v", err)
+	}
+}
+
+func TestClientListPages(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < listPageSize+1; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%04d.msg", i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, _ := newTestClient(t, dir)
+
+	var sizes []int
+	err := c.ListPages(context.Background(), "/", ListOptions{}, func(page []*api.FileInfo) error {
+		sizes = append(sizes, len(page))
+		return nil
+	})
+	if err != nil || len(sizes) != 2 || sizes[0] != listPageSize || sizes[1] != 1 {
+		t.Errorf("expected two pages, got %v, %v", sizes, err)
+	}
+
+	entries, err := c.List(context.Background(), "/")
+	if err != nil || len(entries) != listPageSize+1 || entries[listPageSize].Name != "1000.msg" {
+		t.Errorf("expected every entry in order, got %d, %v", len(entries), err)
+	}
+}
+
+func TestClientListStream(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b", "a"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, agent := newTestClient(t, dir)
+
+	var names []string
+	collect := func(page []*api.FileInfo) error {
+		for _, e := range page {
+			names = append(names, e.Name)
+		}
+		return nil
+	}
+	if err := c.ListStream(context.Background(), "/", ListOptions{}, collect); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, "") != "ab" || agent.Requests()[0].Operation != "ListDirectoryStream" {
+		t.Errorf("unexpected entries %v from %v", names, agent.Requests())
+	}
+
+	// Older agents are listed a page at a time.
+	agent.Supported = []string{"ListDirectory"}
+	c.info, c.caps = nil, nil
+	names = nil
+	if err := c.ListStream(context.Background(), "/", ListOptions{}, collect); err != nil {
+		t.Fatal(err)
+	}
+	if requests := agent.Requests(); strings.Join(names, "") != "ab" || requests[len(requests)-1].Operation != "ListDirectory" {
+		t.Errorf("expected a paged listing, got %v from %v", names, requests)
+	}
+}
+
+func TestClientListOptions(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"a.log": 5, "b.log": 20, "c.txt": 50, "d.log": 10} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, agent := newTestClient(t, dir)
+
+	var names []string
+	collect := func(page []*api.FileInfo) error {
+		for _, e := range page {
+			names = append(names, e.Name)
+		}
+		return nil
+	}
+	opts := ListOptions{SortBy: "size", Descending: true, NameGlob: "*.log", MinSize: 10}
+	if err := c.ListPages(context.Background(), "/", opts, collect); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, " ") != "b.log d.log" {
+		t.Errorf("unexpected entries %v", names)
+	}
+
+	// Agents that would ignore the options are not asked.
+	agent.Supported = []string{"ListDirectory", "ListDirectoryStream"}
+	c.info, c.caps = nil, nil
+	if err := c.ListPages(context.Background(), "/", opts, collect); !errors.Is(err, errListFilters) {
+		t.Errorf("expected errListFilters, got %v", err)
+	}
+	if err := c.ListStream(context.Background(), "/", opts, collect); !errors.Is(err, errListFilters) {
+		t.Errorf("expected errListFilters from ListStream, got %v", err)
+	}
+	if err := c.ListStream(context.Background(), "/", ListOptions{SortBy: "size"}, collect); err != nil {
+		t.Errorf("expected the sort order to be ignored by ListStream, got %v", err)
+	}
+}
+
+func TestClientFind(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"a.log": 5, "logs/b.log": 200, "logs/old/c.log": 300, "logs/d.txt": 500} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, agent := newTestClient(t, dir)
+
+	resp, err := c.Find(context.Background(), "/", FindOptions{NameGlob: "*.log", MinSize: 100, Type: api.FileType_FILE_TYPE_REGULAR})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, m := range resp.Matches {
+		paths = append(paths, m.Path)
+	}
+	if strings.Join(paths, " ") != "/logs/b.log /logs/old/c.log" {
+		t.Errorf("unexpected matches %v", paths)
+	}
+
+	agent.Supported = []string{"ListDirectory"}
+	c.info, c.caps = nil, nil
+	if _, err := c.Find(context.Background(), "/", FindOptions{}); !errors.Is(err, errFind) {
+		t.Errorf("expected errFind, got %v", err)
+	}
+}
+
+func TestClientReadRanges(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"), []byte("HEADER....middle....FOOTER"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, agent := newTestClient(t, dir)
+	ranges := []*api.ByteRange{{Offset: 0, Length: 6}, {Offset: 10, Length: 6}, {Offset: -6, Length: 10}}
+	check := func(parts []*api.ReadPart, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range parts {
+			got = append(got, fmt.Sprintf("%d:%s:%v", p.Offset, p.Data, p.Eof))
+		}
+		if want := "0:HEADER:false 10:middle:false 20:FOOTER:true"; strings.Join(got, " ") != want {
+			t.Errorf("got %v, want %s", got, want)
+		}
+	}
+
+	before := len(agent.Requests())
+	check(c.ReadRanges(context.Background(), "/data.bin", ranges))
+	if n := len(agent.Requests()) - before; n != 1 {
+		t.Errorf("expected one ReadFile call, got %d requests", n)
+	}
+
+	// Older agents get a Stat for the negative offset and a read per range.
+	agent.Supported = []string{"Stat", "ReadFile"}
+	c.info, c.caps = nil, nil
+	check(c.ReadRanges(context.Background(), "/data.bin", ranges))
+}
@@ -0,0 +1,754 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+// Connection methods accepted in Options.ConnectionMethod.
+const (
+	PortForward    = "port-forward"
+	APIServerProxy = "apiserver-proxy"
+)
+
+const (
+	agentContainerName = "pulsaar-agent"
+	agentPort          = 50051
+	defaultAgentImage  = "pulsaar/agent:latest"
+	windowsAgentImage  = "pulsaar/agent:latest-windows"
+
+	defaultInjectTimeout = 30 * time.Second
+)
+
+// Default limits for an injected agent; see Options.AgentCPU and
+// Options.AgentMemory.
+const (
+	DefaultAgentCPU    = "1"
+	DefaultAgentMemory = "128Mi"
+)
+
+// injectPollInterval is how often waitForAgent checks the pod.
+var injectPollInterval = time.Second
+
+// Options describes which pod to reach and how.
+type Options struct {
+	Namespace string
+	Pod       string
+	// Node targets the host-mode agent on a node instead of Pod. Namespace
+	// then defaults to HostAgentNamespace and injection is skipped.
+	Node string
+	// ConnectionMethod is PortForward (default) or APIServerProxy.
+	ConnectionMethod string
+	// RESTConfig is used for Kubernetes API calls; nil loads the in-cluster
+	// config or the kubeconfig (see KubeConfig).
+	RESTConfig *rest.Config
+	// TLSConfig secures the connection to the agent; nil uses
+	// TLSConfigFromEnv.
+	TLSConfig *tls.Config
+	// AgentImage is the ephemeral agent image for every pod; empty picks
+	// one by the pod's OS from DefaultAgentImages, PULSAAR_AGENT_IMAGE or
+	// PULSAAR_AGENT_WINDOWS_IMAGE, or the published images.
+	AgentImage string
+	// DefaultAgentImages overrides the environment per OS when AgentImage
+	// is empty.
+	DefaultAgentImages AgentImages
+	// InjectTimeout bounds the wait for an injected agent to start; 0 uses
+	// 30s. Image pulls on cold nodes can take longer.
+	InjectTimeout time.Duration
+	// Progress, if set, receives a line each time the injected agent
+	// container changes state, e.g. while its image is pulled.
+	Progress io.Writer
+	// AllowedRoots is sent with every request; empty defers to the roots
+	// configured on the agent.
+	AllowedRoots []string
+	// SkipAccessCheck disables the SelfSubjectAccessReview check.
+	SkipAccessCheck bool
+	// AccessCacheTTL, when positive, reuses a successful access check for
+	// the same credentials and pod for this long (see CheckAccessCached).
+	AccessCacheTTL time.Duration
+	// AccessCacheDir holds cached checks; empty uses "access" under
+	// DefaultCacheDir.
+	AccessCacheDir string
+	// AgentCPU and AgentMemory cap an injected agent, as Kubernetes
+	// quantities such as "500m" and "64Mi"; empty uses DefaultAgentCPU and
+	// DefaultAgentMemory, and "0" removes the cap. Ephemeral containers
+	// cannot have resource limits, so they are applied by the agent's Go
+	// runtime: CPU as GOMAXPROCS, rounded up to whole cores, and memory as
+	// the GOMEMLIMIT soft limit.
+	AgentCPU    string
+	AgentMemory string
+	// AgentPrivilege is what an agent injected into a Linux pod may read:
+	// privilege.NonRoot, the default, privilege.DACReadSearch or
+	// privilege.Root (see package privilege). With TargetContainer, a
+	// non-root agent runs as the target's user so it can see its files.
+	AgentPrivilege string
+	// TargetContainer, if set, injects the agent into that container's
+	// process namespace so paths resolve in its filesystem rather than the
+	// agent image's. Linux pods only.
+	TargetContainer string
+	// SkipInjection assumes the agent is already running in the pod, e.g.
+	// as a webhook-injected sidecar, so no pod update permission is needed.
+	SkipInjection bool
+	// AgentPort is the agent's port in the pod; 0 uses 50051.
+	AgentPort int
+	// SessionTTL, when positive, keeps the connection open after Close so
+	// later calls for the same pod within the TTL reuse it (see Session).
+	SessionTTL time.Duration
+	// SessionDir holds session records; empty uses DefaultSessionDir.
+	SessionDir string
+	// IdentityTokenFile, if set, is a ServiceAccount token issued for the
+	// "pulsaar" audience, such as a projected volume, sent with every
+	// request so the agent can audit which ServiceAccount made it. It is
+	// read for each request, so a rotated token is picked up.
+	IdentityTokenFile string
+	// Proxy is an http, https, socks5 or socks5h URL through which the
+	// Kubernetes API calls, kubectl port-forward and the apiserver-proxy
+	// connection to the agent go. Empty uses HTTPS_PROXY and NO_PROXY from
+	// the environment.
+	Proxy string
+	// As and AsGroups impersonate another user for every Kubernetes API
+	// call, the access check and kubectl port-forward, so an admin can see
+	// what that user could reach through Pulsaar (see WithImpersonation).
+	As       string
+	AsGroups []string
+}
+
+// Connect verifies the caller may access the pod, injects the agent as an
+// ephemeral container if needed and dials it.
+func Connect(ctx context.Context, opts Options) (*Client, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = "default"
+		if opts.Node != "" {
+			opts.Namespace = HostAgentNamespace()
+		}
+	}
+	if opts.ConnectionMethod == "" {
+		opts.ConnectionMethod = PortForward
+	}
+	if opts.AgentPort < 0 || opts.AgentPort > 65535 {
+		return nil, fmt.Errorf("invalid agent port %d; must be between 1 and 65535", opts.AgentPort)
+	}
+	if opts.TargetContainer != "" && (opts.SkipInjection || opts.Node != "") {
+		return nil, fmt.Errorf("targeting container %q needs an injected agent; it cannot be combined with skipping injection or a node", opts.TargetContainer)
+	}
+	if _, err := agentLimits(opts); err != nil {
+		return nil, err
+	}
+	if opts.AgentPrivilege != "" {
+		if err := privilege.Validate(opts.AgentPrivilege); err != nil {
+			return nil, err
+		}
+	}
+	for _, image := range []string{opts.AgentImage, opts.DefaultAgentImages.Linux, opts.DefaultAgentImages.Windows} {
+		if image == "" {
+			continue
+		}
+		if err := ValidateImage(image); err != nil {
+			return nil, err
+		}
+	}
+	config := opts.RESTConfig
+	if config == nil {
+		var err error
+		if config, err = KubeConfig(); err != nil {
+			return nil, classify(ErrConnection, fmt.Errorf("unable to connect to Kubernetes cluster. Please check your kubeconfig or in-cluster configuration. Error: %v", err))
+		}
+	}
+	config, err := WithProxy(config, opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	if config, err = WithImpersonation(config, opts.As, opts.AsGroups); err != nil {
+		return nil, err
+	}
+	tlsConfig := opts.TLSConfig
+	if tlsConfig == nil {
+		var err error
+		if tlsConfig, err = TLSConfigFromEnv(); err != nil {
+			return nil, fmt.Errorf("failed to create TLS configuration. Check your certificate files and environment variables (PULSAAR_CLIENT_CERT_FILE, PULSAAR_CLIENT_KEY_FILE, PULSAAR_CA_FILE). Error: %v", err)
+		}
+	}
+
+	if opts.Node != "" {
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Kubernetes client. Verify your cluster connection and credentials. Error: %v", err)
+		}
+		if opts.Pod, err = FindHostAgent(ctx, clientset, opts.Namespace, opts.Node); err != nil {
+			return nil, err
+		}
+		opts.SkipInjection = true
+	}
+
+	creds := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	if opts.IdentityTokenFile != "" {
+		creds = append(creds, grpc.WithPerRPCCredentials(tokenFileCredentials(opts.IdentityTokenFile)))
+	}
+	if opts.ConnectionMethod == APIServerProxy {
+		u, err := opts.agentProxy(config)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			creds = append(creds, grpc.WithContextDialer(proxyDialer(u)))
+		}
+	}
+	var session *Session
+	if opts.SessionTTL > 0 {
+		dir := opts.SessionDir
+		if dir == "" {
+			dir = DefaultSessionDir()
+		}
+		file := sessionFile(dir, config, opts.Namespace, opts.Pod, opts.ConnectionMethod)
+		if c, ok := reuseSession(ctx, file, opts.proxyURL(config), opts, creds...); ok {
+			return c, nil
+		}
+		now := time.Now()
+		session = &Session{
+			Host:             config.Host,
+			Namespace:        opts.Namespace,
+			Pod:              opts.Pod,
+			ConnectionMethod: opts.ConnectionMethod,
+			Created:          now,
+			Expires:          now.Add(opts.SessionTTL),
+			file:             file,
+		}
+	}
+
+	if !opts.SkipAccessCheck {
+		if err := CheckAccessCached(ctx, config, opts.Namespace, opts.Pod, opts.AccessCacheTTL, opts.AccessCacheDir); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.SkipInjection {
+		if err := injectAgent(ctx, config, opts); err != nil {
+			return nil, withClass(err, fmt.Errorf("failed to inject Pulsaar agent into pod %s/%s. Ensure the pod supports ephemeral containers and you have permissions to update pods. Error: %v", opts.Namespace, opts.Pod, err))
+		}
+	}
+
+	switch opts.ConnectionMethod {
+	case PortForward:
+		// Find a free local port
+		lis, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return nil, fmt.Errorf("unable to find a free local port for port-forwarding. This may indicate too many open connections. Error: %v", err)
+		}
+		localPort := lis.Addr().(*net.TCPAddr).Port
+		if err := lis.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close temporary listener. Error: %v", err)
+		}
+
+		pf, err := startPortForward(ctx, opts, localPort, session)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := grpc.NewClient(pf.addr, creds...)
+		if err != nil {
+			pf.stop()
+			return nil, classify(ErrConnection, fmt.Errorf("failed to establish gRPC connection via port-forward. Check TLS configuration and agent availability. Error: %v", err))
+		}
+		c := New(conn, opts.AllowedRoots...)
+		if session != nil {
+			session.LocalPort, session.PID = localPort, pf.cmd.Process.Pid
+			// Without a start time the port-forward could not be told
+			// apart from a later process with its PID, so it is not kept.
+			start, err := processStart(session.PID)
+			session.PIDStart = start
+			if err == nil && session.save() == nil {
+				if startSessionReaper(session.file) == nil {
+					// The port-forward now belongs to the session.
+					c.closeFn = conn.Close
+					return c, nil
+				}
+				// Nothing would stop it at expiry, so it is not kept.
+				_ = os.Remove(session.file)
+			}
+		}
+		var pfMu sync.Mutex
+		c.redial = func(ctx context.Context) error {
+			pfMu.Lock()
+			defer pfMu.Unlock()
+			select {
+			case <-pf.exited:
+			default:
+				return nil
+			}
+			// gRPC reconnects by itself once the port is forwarded again.
+			next, err := startPortForward(ctx, opts, localPort, nil)
+			if err != nil {
+				return err
+			}
+			pf = next
+			return nil
+		}
+		c.closeFn = func() error {
+			err := conn.Close()
+			pfMu.Lock()
+			defer pfMu.Unlock()
+			pf.stop()
+			return err
+		}
+		return c, nil
+	case APIServerProxy:
+		proxyURL := opts.proxyURL(config)
+		conn, err := grpc.NewClient(proxyURL, creds...)
+		if err != nil {
+			return nil, classify(ErrConnection, fmt.Errorf("failed to establish gRPC connection via apiserver proxy. Check TLS configuration and agent availability. Error: %v", err))
+		}
+		if session != nil {
+			_ = session.save()
+		}
+		c := New(conn, opts.AllowedRoots...)
+		c.closeFn = conn.Close
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unknown connection method '%s'. Supported methods: port-forward, apiserver-proxy", opts.ConnectionMethod)
+	}
+}
+
+// ProxyURL is the apiserver proxy address of the pod.
+func ProxyURL(config *rest.Config, namespace, pod string) string {
+	return config.Host + "/api/v1/namespaces/" + namespace + "/pods/" + pod + "/proxy/"
+}
+
+// proxyURL is ProxyURL, addressing AgentPort when it is set.
+func (o Options) proxyURL(config *rest.Config) string {
+	if o.AgentPort == 0 {
+		return ProxyURL(config, o.Namespace, o.Pod)
+	}
+	return ProxyURL(config, o.Namespace, fmt.Sprintf("%s:%d", o.Pod, o.AgentPort))
+}
+
+func (o Options) agentPort() int {
+	if o.AgentPort == 0 {
+		return agentPort
+	}
+	return o.AgentPort
+}
+
+// TLSConfigFromEnv builds the agent TLS configuration from
+// PULSAAR_CLIENT_CERT_FILE, PULSAAR_CLIENT_KEY_FILE and PULSAAR_CA_FILE.
+// Without a CA or client certificate the agent certificate is not verified,
+// which suits port-forwarded connections to self-signed agents.
+func TLSConfigFromEnv() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: true, // Default for MVP port-forward
+	}
+
+	clientCertFile := os.Getenv("PULSAAR_CLIENT_CERT_FILE")
+	clientKeyFile := os.Getenv("PULSAAR_CLIENT_KEY_FILE")
+	caFile := os.Getenv("PULSAAR_CA_FILE")
+
+	if clientCertFile != "" && clientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client cert: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+		config.InsecureSkipVerify = false // Use proper verification if client cert provided
+	}
+
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse CA certificate")
+		}
+		config.RootCAs = caCertPool
+		config.InsecureSkipVerify = false
+	}
+
+	return config, nil
+}
+
+// tokenFileCredentials sends the token in a file as a bearer token.
+type tokenFileCredentials string
+
+func (f tokenFileCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := os.ReadFile(string(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity token: %v", err)
+	}
+	return map[string]string{"authorization": "Bearer " + strings.TrimSpace(string(token))}, nil
+}
+
+func (f tokenFileCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// CheckAccess confirms through a SelfSubjectAccessReview that the identity
+// in config may get the pod. The API server authenticates the request
+// itself, so this works with client certificates, OIDC and exec plugins as
+// well as bearer tokens.
+func CheckAccess(ctx context.Context, config *rest.Config, namespace, pod string) error {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client. Verify your cluster connection and credentials. Error: %v", err)
+	}
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "get",
+				Resource:  "pods",
+				Name:      pod,
+			},
+		},
+	}
+	result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return classifyAPIError(err, fmt.Errorf("failed to check RBAC permissions. Verify your credentials with 'kubectl auth whoami' and your cluster connectivity. Error: %v", err))
+	}
+	if !result.Status.Allowed {
+		return classify(ErrAccessDenied, fmt.Errorf("access denied to pod %s/%s. Check your RBAC permissions for 'get' verb on pods in namespace %s", namespace, pod, namespace))
+	}
+
+	return nil
+}
+
+// InjectAgent adds the Pulsaar agent to the pod as an ephemeral container,
+// unless it is already present, and waits up to 30s for it to run.
+func InjectAgent(ctx context.Context, config *rest.Config, namespace, podName, image string) error {
+	return injectAgent(ctx, config, Options{Namespace: namespace, Pod: podName, AgentImage: image})
+}
+
+func injectAgent(ctx context.Context, config *rest.Config, opts Options) error {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %v", err)
+	}
+	namespace, podName := opts.Namespace, opts.Pod
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return classifyAPIError(err, fmt.Errorf("failed to get pod: %v", err))
+	}
+
+	agent, err := ephemeralAgent(pod, opts)
+	if err != nil {
+		return err
+	}
+	if agent != nil {
+		pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, *agent)
+
+		// Patch the pod
+		_, err = clientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(ctx, podName, pod, metav1.UpdateOptions{})
+		if err != nil {
+			return classifyAPIError(err, fmt.Errorf("failed to update ephemeral containers: %v", err))
+		}
+	}
+
+	timeout := opts.InjectTimeout
+	if timeout <= 0 {
+		timeout = defaultInjectTimeout
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = io.Discard
+	}
+	return waitForAgent(ctx, clientset, namespace, podName, timeout, progress)
+}
+
+// ephemeralAgent returns the agent container to add to pod, or nil if the
+// pod already has an agent that serves opts.
+func ephemeralAgent(pod *corev1.Pod, opts Options) (*corev1.EphemeralContainer, error) {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != agentContainerName {
+			continue
+		}
+		if opts.TargetContainer != "" {
+			return nil, fmt.Errorf("pod %s/%s runs the agent as a sidecar, which cannot target container %q", pod.Namespace, pod.Name, opts.TargetContainer)
+		}
+		return nil, nil
+	}
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if ec.Name != agentContainerName {
+			continue
+		}
+		// Injected earlier; it may still be starting. Ephemeral containers
+		// cannot be changed, so a different target needs a new pod.
+		if opts.TargetContainer != "" && ec.TargetContainerName != opts.TargetContainer {
+			injected := "without a target container"
+			if ec.TargetContainerName != "" {
+				injected = fmt.Sprintf("targeting container %q", ec.TargetContainerName)
+			}
+			return nil, fmt.Errorf("the agent in pod %s/%s was injected %s and cannot be changed; restart the pod to target %q", pod.Namespace, pod.Name, injected, opts.TargetContainer)
+		}
+		return nil, nil
+	}
+
+	image := opts.AgentImage
+	if image == "" {
+		image = defaultImage(pod, opts.DefaultAgentImages)
+	}
+	agent := &corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:  agentContainerName,
+			Image: image,
+			Ports: []corev1.ContainerPort{
+				{
+					ContainerPort: agentPort,
+					Name:          "grpc",
+				},
+			},
+		},
+	}
+	if opts.TargetContainer != "" {
+		if err := checkTargetContainer(pod, opts.TargetContainer); err != nil {
+			return nil, err
+		}
+		agent.TargetContainerName = opts.TargetContainer
+		agent.Env = []corev1.EnvVar{
+			{Name: "PULSAAR_TARGET_CONTAINER", Value: opts.TargetContainer},
+			{Name: "PULSAAR_CONTAINER_NAME", Value: opts.TargetContainer},
+		}
+	}
+	limits, err := agentLimits(opts)
+	if err != nil {
+		return nil, err
+	}
+	agent.Env = append(agent.Env, limits...)
+	if !isWindowsPod(pod) {
+		level := opts.AgentPrivilege
+		if level == "" {
+			level = privilege.NonRoot
+		}
+		agent.SecurityContext = privilege.SecurityContext(level)
+		agent.Command = privilege.Command(level)
+		if opts.TargetContainer != "" && level != privilege.Root {
+			if err := runAsTarget(agent.SecurityContext, pod, opts.TargetContainer); err != nil {
+				return nil, err
+			}
+		}
+		agent.Env = append(agent.Env, corev1.EnvVar{Name: privilege.Env, Value: level})
+	}
+	return agent, nil
+}
+
+// runAsTarget runs the agent as container's user and group. Reading a
+// process's root through /proc needs the same user, or root.
+func runAsTarget(sc *corev1.SecurityContext, pod *corev1.Pod, container string) error {
+	var user, group *int64
+	if pod.Spec.SecurityContext != nil {
+		user, group = pod.Spec.SecurityContext.RunAsUser, pod.Spec.SecurityContext.RunAsGroup
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container && c.SecurityContext != nil {
+			if c.SecurityContext.RunAsUser != nil {
+				user = c.SecurityContext.RunAsUser
+			}
+			if c.SecurityContext.RunAsGroup != nil {
+				group = c.SecurityContext.RunAsGroup
+			}
+		}
+	}
+	switch {
+	case user == nil:
+		return fmt.Errorf("container %q does not set runAsUser, so a non-root agent cannot run as its user to read its filesystem; set the agent privilege to %s to target it", container, privilege.Root)
+	case *user == 0:
+		return fmt.Errorf("container %q runs as root, so only an agent with privilege %s can read its filesystem", container, privilege.Root)
+	}
+	sc.RunAsUser, sc.RunAsGroup = user, group
+	return nil
+}
+
+// agentLimits returns the Go runtime settings that cap an injected agent
+// at opts.AgentCPU and opts.AgentMemory.
+func agentLimits(opts Options) ([]corev1.EnvVar, error) {
+	var env []corev1.EnvVar
+	cpu, err := parseAgentLimit("CPU", opts.AgentCPU, DefaultAgentCPU)
+	if err != nil {
+		return nil, err
+	}
+	if !cpu.IsZero() {
+		procs := (cpu.MilliValue() + 999) / 1000
+		env = append(env, corev1.EnvVar{Name: "GOMAXPROCS", Value: strconv.FormatInt(procs, 10)})
+	}
+	memory, err := parseAgentLimit("memory", opts.AgentMemory, DefaultAgentMemory)
+	if err != nil {
+		return nil, err
+	}
+	if !memory.IsZero() {
+		env = append(env, corev1.EnvVar{Name: "GOMEMLIMIT", Value: strconv.FormatInt(memory.Value(), 10)})
+	}
+	return env, nil
+}
+
+func parseAgentLimit(name, value, def string) (resource.Quantity, error) {
+	if value == "" {
+		value = def
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return q, fmt.Errorf("invalid agent %s limit %q: %v", name, value, err)
+	}
+	if q.Sign() < 0 {
+		return q, fmt.Errorf("invalid agent %s limit %q: must not be negative", name, value)
+	}
+	return q, nil
+}
+
+// checkTargetContainer verifies the agent can read container's filesystem
+// through its main process, which it sees as PID 1.
+func checkTargetContainer(pod *corev1.Pod, container string) error {
+	names := make([]string, 0, len(pod.Spec.Containers))
+	found := false
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+		found = found || c.Name == container
+	}
+	if !found {
+		return classify(ErrNotFound, fmt.Errorf("container %q not found in pod %s/%s; containers: %s", container, pod.Namespace, pod.Name, strings.Join(names, ", ")))
+	}
+	if isWindowsPod(pod) {
+		return fmt.Errorf("targeting a container is not supported on Windows pods")
+	}
+	if pod.Spec.ShareProcessNamespace != nil && *pod.Spec.ShareProcessNamespace {
+		return fmt.Errorf("pod %s/%s shares one process namespace between its containers, so the agent cannot tell container %q apart; read its files without a target container", pod.Namespace, pod.Name, container)
+	}
+	return nil
+}
+
+// waitForAgent polls until the agent container runs, writing a line to
+// progress each time its state changes. It gives up early on states that do
+// not recover, such as an invalid image name or an exited container.
+func waitForAgent(ctx context.Context, clientset kubernetes.Interface, namespace, podName string, timeout time.Duration, progress io.Writer) error {
+	lastState := "pending"
+	var fatal error
+	err := wait.PollUntilContextTimeout(ctx, injectPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != agentContainerName {
+				continue
+			}
+			state, done, failed := describeAgentState(status.State)
+			if state != lastState {
+				_, _ = fmt.Fprintf(progress, "Agent container in %s/%s: %s\n", namespace, podName, state)
+				lastState = state
+			}
+			if failed {
+				fatal = fmt.Errorf("agent container in pod %s/%s cannot start: %s", namespace, podName, state)
+				return false, fatal
+			}
+			return done, nil
+		}
+		return false, nil
+	})
+	if fatal != nil {
+		return fatal
+	}
+	if err != nil {
+		return fmt.Errorf("failed to wait for ephemeral container after %s (last state: %s). Image pulls on cold nodes can be slow; increase the injection timeout if the image is still pulling. Error: %v", timeout, lastState, err)
+	}
+	return nil
+}
+
+// describeAgentState summarises a container state for progress output and
+// reports whether the container is running or has failed for good.
+func describeAgentState(state corev1.ContainerState) (desc string, running, failed bool) {
+	switch {
+	case state.Running != nil:
+		return "running", true, false
+	case state.Terminated != nil:
+		t := state.Terminated
+		desc = fmt.Sprintf("terminated: %s (exit code %d)", t.Reason, t.ExitCode)
+		if t.Message != "" {
+			desc += ": " + t.Message
+		}
+		return desc, false, true
+	case state.Waiting != nil:
+		w := state.Waiting
+		desc = "waiting: " + w.Reason
+		if w.Reason == "ContainerCreating" {
+			desc = "waiting: ContainerCreating (pulling image or starting)"
+		}
+		if w.Message != "" {
+			desc += ": " + w.Message
+		}
+		switch w.Reason {
+		case "InvalidImageName", "ErrImageNeverPull", "CreateContainerConfigError":
+			return desc, false, true
+		}
+		return desc, false, false
+	}
+	return "pending", false, false
+}
+
+// AgentImages are agent image references per pod OS. Either may be empty.
+type AgentImages struct {
+	Linux   string
+	Windows string
+}
+
+// defaultImage picks the agent image for pod's OS: the override in
+// defaults, then the environment, then the published image. Windows pods
+// use PULSAAR_AGENT_WINDOWS_IMAGE, as the webhook does.
+func defaultImage(pod *corev1.Pod, defaults AgentImages) string {
+	override, env, image := defaults.Linux, "PULSAAR_AGENT_IMAGE", defaultAgentImage
+	if isWindowsPod(pod) {
+		override, env, image = defaults.Windows, "PULSAAR_AGENT_WINDOWS_IMAGE", windowsAgentImage
+	}
+	if override != "" {
+		return override
+	}
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return image
+}
+
+// imageRefPattern matches [registry[:port]/]name[:tag][@sha256:digest].
+var imageRefPattern = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:[._-]+[a-z0-9]+)*(?:/[a-z0-9]+(?:[._-]+[a-z0-9]+)*)*(?::\w[\w.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// ValidateImage checks an agent image reference. Pin a digest with
+// name@sha256:<digest> so every node runs the same image.
+func ValidateImage(ref string) error {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		digest := ref[i+1:]
+		if !strings.HasPrefix(digest, "sha256:") || len(digest) != len("sha256:")+64 {
+			return fmt.Errorf("invalid digest in image %q; expected @sha256: followed by 64 hex characters", ref)
+		}
+	}
+	if !imageRefPattern.MatchString(ref) {
+		return fmt.Errorf("invalid image reference %q", ref)
+	}
+	return nil
+}
+
+// isWindowsPod reports whether pod runs on a Windows node.
+func isWindowsPod(pod *corev1.Pod) bool {
+	if pod.Spec.OS != nil {
+		return pod.Spec.OS.Name == corev1.Windows
+	}
+	return pod.Spec.NodeSelector[corev1.LabelOSStable] == string(corev1.Windows)
+}
@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CheckStatus is the outcome of a diagnostic check.
+type CheckStatus string
+
+// Diagnostic check outcomes.
+const (
+	CheckPass CheckStatus = "PASS"
+	CheckWarn CheckStatus = "WARN"
+	CheckFail CheckStatus = "FAIL"
+	CheckSkip CheckStatus = "SKIP"
+)
+
+// CheckResult is one diagnostic check. Hint says how to fix a warning or
+// failure.
+type CheckResult struct {
+	Name   string
+	Status CheckStatus
+	Detail string
+	Hint   string
+}
+
+// certExpiryWarning is how close to expiry a client certificate is flagged.
+const certExpiryWarning = 7 * 24 * time.Hour
+
+// DiagnoseTLS checks the agent TLS settings read by TLSConfigFromEnv.
+func DiagnoseTLS(now time.Time) CheckResult {
+	result := CheckResult{Name: "tls"}
+	certFile, keyFile := os.Getenv("PULSAAR_CLIENT_CERT_FILE"), os.Getenv("PULSAAR_CLIENT_KEY_FILE")
+	if (certFile == "") != (keyFile == "") {
+		result.Status = CheckFail
+		result.Detail = "only one of PULSAAR_CLIENT_CERT_FILE and PULSAAR_CLIENT_KEY_FILE is set"
+		result.Hint = "set both to use a client certificate, or neither"
+		return result
+	}
+	config, err := TLSConfigFromEnv()
+	if err != nil {
+		result.Status = CheckFail
+		result.Detail = err.Error()
+		result.Hint = "check the files named by PULSAAR_CLIENT_CERT_FILE, PULSAAR_CLIENT_KEY_FILE and PULSAAR_CA_FILE"
+		return result
+	}
+	if len(config.Certificates) > 0 && config.Certificates[0].Leaf != nil {
+		leaf := config.Certificates[0].Leaf
+		switch {
+		case now.After(leaf.NotAfter):
+			result.Status = CheckFail
+			result.Detail = fmt.Sprintf("client certificate expired on %s", leaf.NotAfter.Format(time.RFC3339))
+			result.Hint = "issue a new client certificate"
+			return result
+		case leaf.NotAfter.Sub(now) < certExpiryWarning:
+			result.Status = CheckWarn
+			result.Detail = fmt.Sprintf("client certificate expires on %s", leaf.NotAfter.Format(time.RFC3339))
+			result.Hint = "renew the client certificate soon"
+			return result
+		}
+	}
+	if config.InsecureSkipVerify {
+		result.Status = CheckWarn
+		result.Detail = "no CA or client certificate configured; the agent certificate is not verified"
+		result.Hint = "set PULSAAR_CA_FILE (and a client certificate for mTLS) outside of port-forwarded development setups"
+		return result
+	}
+	result.Status = CheckPass
+	result.Detail = "certificates loaded"
+	if config.RootCAs != nil {
+		result.Detail += "; agent certificate verified against PULSAAR_CA_FILE"
+	}
+	return result
+}
+
+// DiagnoseCluster checks what Connect needs from the cluster for
+// opts.Namespace and opts.Pod: RBAC, ephemeral container support and, with
+// a pod, the pod itself and its agent image. agentRunning reports whether
+// the pod already runs an agent that can be asked for its health.
+func DiagnoseCluster(ctx context.Context, clientset kubernetes.Interface, opts Options) (results []CheckResult, agentRunning bool) {
+	results = diagnoseRBAC(ctx, clientset, opts)
+	if !opts.SkipInjection {
+		results = append(results, diagnoseEphemeralContainers(clientset))
+	}
+	if opts.Pod == "" {
+		return append(results, diagnoseImageRef(opts)), false
+	}
+
+	pod, err := clientset.CoreV1().Pods(opts.Namespace).Get(ctx, opts.Pod, metav1.GetOptions{})
+	if err != nil {
+		return append(results, CheckResult{
+			Name:   "pod",
+			Status: CheckFail,
+			Detail: fmt.Sprintf("cannot get pod %s/%s: %v", opts.Namespace, opts.Pod, err),
+			Hint:   "check the pod name and namespace with: kubectl get pods -n " + opts.Namespace,
+		}), false
+	}
+	result := CheckResult{Name: "pod", Status: CheckPass, Detail: fmt.Sprintf("%s/%s is %s", opts.Namespace, opts.Pod, pod.Status.Phase)}
+	if pod.Status.Phase != corev1.PodRunning {
+		result.Status = CheckFail
+		result.Hint = "the agent can only run in a running pod"
+	}
+	results = append(results, result)
+	image, agentRunning := diagnoseAgentImage(ctx, clientset, pod, opts)
+	return append(results, image), agentRunning
+}
+
+// accessChecks are the permissions Connect uses, as verb, resource and
+// subresource.
+func accessChecks(opts Options) [][3]string {
+	checks := [][3]string{{"get", "pods", ""}}
+	if !opts.SkipInjection {
+		checks = append(checks, [3]string{"update", "pods", "ephemeralcontainers"})
+	}
+	if opts.ConnectionMethod == APIServerProxy {
+		return append(checks, [3]string{"get", "pods", "proxy"})
+	}
+	return append(checks, [3]string{"create", "pods", "portforward"})
+}
+
+func diagnoseRBAC(ctx context.Context, clientset kubernetes.Interface, opts Options) []CheckResult {
+	var results []CheckResult
+	for _, check := range accessChecks(opts) {
+		verb, resource := check[0], check[1]
+		if check[2] != "" {
+			resource += "/" + check[2]
+		}
+		result := CheckResult{Name: "rbac"}
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   opts.Namespace,
+					Verb:        verb,
+					Resource:    check[1],
+					Subresource: check[2],
+					Name:        opts.Pod,
+				},
+			},
+		}
+		resp, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		switch {
+		case err != nil:
+			result.Status = CheckFail
+			result.Detail = fmt.Sprintf("cannot check %s %s: %v", verb, resource, err)
+			result.Hint = "verify your credentials with: kubectl auth whoami"
+		case resp.Status.Allowed:
+			result.Status = CheckPass
+			result.Detail = fmt.Sprintf("%s %s in %s", verb, resource, opts.Namespace)
+		default:
+			result.Status = CheckFail
+			result.Detail = fmt.Sprintf("%s %s in %s is denied", verb, resource, opts.Namespace)
+			result.Hint = fmt.Sprintf("ask a cluster admin for a Role granting %q on %q", verb, resource)
+			if check[2] == "ephemeralcontainers" {
+				result.Hint += ", or use --no-inject with a pre-provisioned agent"
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func diagnoseEphemeralContainers(clientset kubernetes.Interface) CheckResult {
+	result := CheckResult{Name: "ephemeral-containers"}
+	resources, err := clientset.Discovery().ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		result.Status = CheckFail
+		result.Detail = fmt.Sprintf("cannot list core API resources: %v", err)
+		result.Hint = "check cluster connectivity"
+		return result
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == "pods/ephemeralcontainers" {
+			result.Status = CheckPass
+			result.Detail = "pods/ephemeralcontainers is served"
+			return result
+		}
+	}
+	result.Status = CheckFail
+	result.Detail = "the API server does not serve pods/ephemeralcontainers"
+	result.Hint = "ephemeral containers need Kubernetes 1.25 or later; otherwise deploy the agent as a sidecar and use --no-inject"
+	return result
+}
+
+// diagnoseImageRef validates the agent image when there is no pod to
+// pick the image for.
+func diagnoseImageRef(opts Options) CheckResult {
+	image := opts.AgentImage
+	if image == "" {
+		image = defaultImage(&corev1.Pod{}, opts.DefaultAgentImages)
+	}
+	if err := ValidateImage(image); err != nil {
+		return CheckResult{Name: "agent-image", Status: CheckFail, Detail: err.Error(), Hint: "fix --agent-image, the config file or PULSAAR_AGENT_IMAGE"}
+	}
+	return CheckResult{Name: "agent-image", Status: CheckPass, Detail: image + " (pass --pod to check the pod's node)"}
+}
+
+// diagnoseAgentImage reports on the agent already in pod, or whether the
+// image to inject is cached on the pod's node.
+func diagnoseAgentImage(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod, opts Options) (CheckResult, bool) {
+	result := CheckResult{Name: "agent-image"}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == agentContainerName {
+			result.Status = CheckPass
+			result.Detail = "agent runs as a sidecar (" + c.Image + ")"
+			return result, true
+		}
+	}
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.Name != agentContainerName {
+			continue
+		}
+		state, running, failed := describeAgentState(status.State)
+		result.Detail = fmt.Sprintf("injected agent (%s) is %s", status.Image, state)
+		switch {
+		case failed:
+			result.Status = CheckFail
+			result.Hint = "ephemeral containers cannot be removed; restart the pod to inject a corrected image"
+		case status.State.Waiting != nil && (status.State.Waiting.Reason == "ErrImagePull" || status.State.Waiting.Reason == "ImagePullBackOff"):
+			result.Status = CheckFail
+			result.Hint = "check the image name and that the node can pull it (registry access, imagePullSecrets)"
+		default:
+			result.Status = CheckPass
+		}
+		return result, running
+	}
+	if opts.SkipInjection {
+		result.Status = CheckFail
+		result.Detail = "no agent in the pod and injection is disabled"
+		result.Hint = "deploy the agent sidecar, or drop --no-inject"
+		return result, false
+	}
+
+	image := opts.AgentImage
+	if image == "" {
+		image = defaultImage(pod, opts.DefaultAgentImages)
+	}
+	if err := ValidateImage(image); err != nil {
+		result.Status = CheckFail
+		result.Detail = err.Error()
+		result.Hint = "fix --agent-image, the config file or PULSAAR_AGENT_IMAGE"
+		return result, false
+	}
+	if pod.Spec.NodeName != "" {
+		node, err := clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+		if err == nil {
+			for _, img := range node.Status.Images {
+				for _, name := range img.Names {
+					if name == image || name == "docker.io/"+image || name == "docker.io/library/"+image {
+						result.Status = CheckPass
+						result.Detail = fmt.Sprintf("%s is cached on node %s", image, node.Name)
+						return result, false
+					}
+				}
+			}
+		}
+	}
+	result.Status = CheckWarn
+	result.Detail = fmt.Sprintf("%s is not known to be on node %s; it is pulled on injection", image, pod.Spec.NodeName)
+	result.Hint = "make sure the node can pull it (registry access, imagePullSecrets), or raise --inject-timeout for slow pulls"
+	return result, false
+}
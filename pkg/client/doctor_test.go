@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// writeClientCert writes a self-signed certificate and key valid until
+// notAfter, returning their paths.
+func writeClientCert(t *testing.T, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "pulsaar-cli"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestDiagnoseTLS(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	certFile, keyFile := writeClientCert(t, notAfter)
+
+	for _, tc := range []struct {
+		name, cert, key string
+		now             time.Time
+		want            CheckStatus
+		detail          string
+	}{
+		{"unset", "", "", notAfter, CheckWarn, "not verified"},
+		{"cert without key", certFile, "", notAfter, CheckFail, "only one of"},
+		{"missing file", certFile, keyFile + ".missing", notAfter, CheckFail, "failed to load client cert"},
+		{"valid", certFile, keyFile, notAfter.AddDate(0, -1, 0), CheckPass, "certificates loaded"},
+		{"expiring", certFile, keyFile, notAfter.Add(-24 * time.Hour), CheckWarn, "expires on 2030-01-01"},
+		{"expired", certFile, keyFile, notAfter.Add(time.Hour), CheckFail, "expired on 2030-01-01"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("PULSAAR_CLIENT_CERT_FILE", tc.cert)
+			t.Setenv("PULSAAR_CLIENT_KEY_FILE", tc.key)
+			t.Setenv("PULSAAR_CA_FILE", "")
+			got := DiagnoseTLS(tc.now)
+			if got.Status != tc.want || !strings.Contains(got.Detail, tc.detail) {
+				t.Errorf("expected %s containing %q, got %s: %s", tc.want, tc.detail, got.Status, got.Detail)
+			}
+		})
+	}
+}
+
+// doctorClientset allows every access review except the denied
+// subresources and serves core resources.
+func doctorClientset(pod *corev1.Pod, denied ...string) *fake.Clientset {
+	clientset := fake.NewClientset(pod)
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = true
+		for _, d := range denied {
+			if review.Spec.ResourceAttributes.Subresource == d {
+				review.Status.Allowed = false
+			}
+		}
+		return true, review, nil
+	})
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods"}, {Name: "pods/ephemeralcontainers"}},
+	}}
+	return clientset
+}
+
+func runningPod() *corev1.Pod {
+	pod := agentPod()
+	pod.Spec.NodeName = "node-1"
+	pod.Status.Phase = corev1.PodRunning
+	return pod
+}
+
+func resultsByName(results []CheckResult) map[string][]CheckResult {
+	byName := map[string][]CheckResult{}
+	for _, r := range results {
+		byName[r.Name] = append(byName[r.Name], r)
+	}
+	return byName
+}
+
+func TestDiagnoseClusterReportsDeniedInjection(t *testing.T) {
+	clientset := doctorClientset(runningPod(), "ephemeralcontainers")
+	results, running := DiagnoseCluster(context.Background(), clientset, Options{Namespace: "shop", Pod: "web-0", AgentImage: "registry.internal/pulsaar/agent:1.2.0"})
+	if running {
+		t.Error("no agent should be reported as running")
+	}
+	byName := resultsByName(results)
+	rbac := byName["rbac"]
+	if len(rbac) != 3 || rbac[0].Status != CheckPass || rbac[2].Status != CheckPass || rbac[2].Detail != "create pods/portforward in shop" {
+		t.Errorf("unexpected RBAC results: %+v", rbac)
+	}
+	if rbac[1].Status != CheckFail || !strings.Contains(rbac[1].Hint, "--no-inject") {
+		t.Errorf("expected the ephemeral container update to be denied with a hint, got %+v", rbac[1])
+	}
+	if got := byName["ephemeral-containers"]; len(got) != 1 || got[0].Status != CheckPass {
+		t.Errorf("unexpected feature check: %+v", got)
+	}
+	if got := byName["agent-image"]; len(got) != 1 || got[0].Status != CheckWarn || !strings.Contains(got[0].Detail, "not known to be on node node-1") {
+		t.Errorf("unexpected image check: %+v", got)
+	}
+}
+
+func TestDiagnoseClusterFindsCachedImage(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status:     corev1.NodeStatus{Images: []corev1.ContainerImage{{Names: []string{"docker.io/pulsaar/agent:latest"}}}},
+	}
+	clientset := doctorClientset(runningPod())
+	if _, err := clientset.CoreV1().Nodes().Create(context.Background(), node, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PULSAAR_AGENT_IMAGE", "")
+	results, _ := DiagnoseCluster(context.Background(), clientset, Options{Namespace: "shop", Pod: "web-0", ConnectionMethod: APIServerProxy})
+	byName := resultsByName(results)
+	if got := byName["agent-image"][0]; got.Status != CheckPass || got.Detail != "pulsaar/agent:latest is cached on node node-1" {
+		t.Errorf("unexpected image check: %+v", got)
+	}
+	if got := byName["rbac"][2].Detail; got != "get pods/proxy in shop" {
+		t.Errorf("expected the proxy permission to be checked, got %q", got)
+	}
+}
+
+func TestDiagnoseClusterReportsAgentState(t *testing.T) {
+	pod := runningPod()
+	pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{
+		Name:  agentContainerName,
+		Image: "pulsaar/agent:typo",
+		State: waiting("ImagePullBackOff", "Back-off pulling image"),
+	}}
+	results, running := DiagnoseCluster(context.Background(), doctorClientset(pod), Options{Namespace: "shop", Pod: "web-0"})
+	got := resultsByName(results)["agent-image"][0]
+	if running || got.Status != CheckFail || !strings.Contains(got.Detail, "ImagePullBackOff") {
+		t.Errorf("expected a failed image pull, got running=%t %+v", running, got)
+	}
+
+	pod.Status.EphemeralContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	results, running = DiagnoseCluster(context.Background(), doctorClientset(pod), Options{Namespace: "shop", Pod: "web-0"})
+	if got := resultsByName(results)["agent-image"][0]; !running || got.Status != CheckPass {
+		t.Errorf("expected a running agent, got running=%t %+v", running, got)
+	}
+}
+
+func TestDiagnoseClusterWithoutEphemeralContainers(t *testing.T) {
+	clientset := doctorClientset(runningPod())
+	clientset.Resources[0].APIResources = []metav1.APIResource{{Name: "pods"}}
+	results, _ := DiagnoseCluster(context.Background(), clientset, Options{Namespace: "shop"})
+	byName := resultsByName(results)
+	if got := byName["ephemeral-containers"][0]; got.Status != CheckFail || !strings.Contains(got.Hint, "1.25") {
+		t.Errorf("expected the feature check to fail, got %+v", got)
+	}
+	if _, ok := byName["pod"]; ok {
+		t.Error("pod checks should not run without a pod")
+	}
+
+	results, _ = DiagnoseCluster(context.Background(), clientset, Options{Namespace: "shop", SkipInjection: true})
+	byName = resultsByName(results)
+	if _, ok := byName["ephemeral-containers"]; ok || len(byName["rbac"]) != 2 {
+		t.Errorf("injection checks should be skipped with SkipInjection, got %+v", results)
+	}
+}
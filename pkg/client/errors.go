@@ -0,0 +1,56 @@
+package client
+
+import (
+	"errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Failure classes of Connect and CheckAccess errors, for use with errors.Is.
+// Errors from agent calls carry a gRPC status instead.
+var (
+	// ErrUnauthenticated means the cluster did not accept the credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrAccessDenied means RBAC does not allow the caller to access the pod.
+	ErrAccessDenied = errors.New("access denied")
+	// ErrNotFound means the pod, or a host agent on the node, does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConnection means the cluster or agent could not be reached.
+	ErrConnection = errors.New("connection failed")
+)
+
+// classifiedError tags err with a failure class without changing its text.
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string   { return e.err.Error() }
+func (e *classifiedError) Unwrap() []error { return []error{e.class, e.err} }
+
+func classify(class, err error) error {
+	return &classifiedError{class: class, err: err}
+}
+
+// withClass gives err the class of cause, if it has one.
+func withClass(cause, err error) error {
+	var ce *classifiedError
+	if errors.As(cause, &ce) {
+		return classify(ce.class, err)
+	}
+	return err
+}
+
+// classifyAPIError picks a class for a failed Kubernetes API call, falling
+// back to ErrConnection.
+func classifyAPIError(apiErr, err error) error {
+	switch {
+	case apierrors.IsUnauthorized(apiErr):
+		return classify(ErrUnauthenticated, err)
+	case apierrors.IsForbidden(apiErr):
+		return classify(ErrAccessDenied, err)
+	case apierrors.IsNotFound(apiErr):
+		return classify(ErrNotFound, err)
+	}
+	return classify(ErrConnection, err)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestCheckAccessErrorClasses(t *testing.T) {
+	var allowed atomic.Bool
+	config, _ := fakeReviewServer(t, &allowed)
+	ctx := context.Background()
+
+	err := CheckAccess(ctx, config, "shop", "web-0")
+	if !errors.Is(err, ErrAccessDenied) || err.Error() != "access denied to pod shop/web-0. Check your RBAC permissions for 'get' verb on pods in namespace shop" {
+		t.Errorf("expected ErrAccessDenied with the original message, got %v", err)
+	}
+
+	// Credentials other than a bearer token are left to the API server.
+	noToken := *config
+	noToken.BearerToken = ""
+	allowed.Store(true)
+	if err := CheckAccess(ctx, &noToken, "shop", "web-0"); err != nil {
+		t.Errorf("expected a check without a bearer token to succeed, got %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Unauthorized","code":401}`))
+	}))
+	defer srv.Close()
+	if err := CheckAccess(ctx, &rest.Config{Host: srv.URL, BearerToken: "expired"}, "shop", "web-0"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated for a 401, got %v", err)
+	}
+
+	srv.Close()
+	if err := CheckAccess(ctx, &rest.Config{Host: srv.URL, BearerToken: "t"}, "shop", "web-0"); !errors.Is(err, ErrConnection) {
+		t.Errorf("expected ErrConnection for an unreachable cluster, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+
+	"k8s.io/client-go/rest"
+)
+
+// WithImpersonation returns a copy of config whose Kubernetes API calls act
+// as user and groups, like kubectl --as and --as-group. The caller's own
+// credentials need the "impersonate" verb on those users and groups. An
+// empty user leaves config unchanged.
+func WithImpersonation(config *rest.Config, user string, groups []string) (*rest.Config, error) {
+	if user == "" {
+		if len(groups) > 0 {
+			return nil, fmt.Errorf("impersonating groups %v needs a user to impersonate as well", groups)
+		}
+		return config, nil
+	}
+	config = rest.CopyConfig(config)
+	config.Impersonate = rest.ImpersonationConfig{UserName: user, Groups: slices.Clone(groups)}
+	return config, nil
+}
+
+// impersonationArgs are the kubectl flags that act as the user opts
+// impersonates.
+func (o Options) impersonationArgs() []string {
+	if o.As == "" {
+		return nil
+	}
+	args := []string{"--as", o.As}
+	for _, g := range o.AsGroups {
+		args = append(args, "--as-group", g)
+	}
+	return args
+}
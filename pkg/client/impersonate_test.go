@@ -0,0 +1,33 @@
+package client
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestWithImpersonation(t *testing.T) {
+	config := &rest.Config{Host: "https://cluster.example:6443", BearerToken: "admin"}
+	if same, err := WithImpersonation(config, "", nil); err != nil || same != config {
+		t.Errorf("expected no impersonation to leave the config alone, got %v", err)
+	}
+	if _, err := WithImpersonation(config, "", []string{"dev"}); err == nil {
+		t.Error("expected groups without a user to be rejected")
+	}
+
+	as, err := WithImpersonation(config, "alice", []string{"dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if as.Impersonate.UserName != "alice" || !slices.Equal(as.Impersonate.Groups, []string{"dev"}) || config.Impersonate.UserName != "" {
+		t.Errorf("expected a copy impersonating alice, got %+v", as.Impersonate)
+	}
+	dir := t.TempDir()
+	if accessCacheFile(dir, config, "shop", "web-0") == accessCacheFile(dir, as, "shop", "web-0") {
+		t.Error("expected impersonated access checks to be cached apart")
+	}
+	if sessionFile(dir, config, "shop", "web-0", PortForward) == sessionFile(dir, as, "shop", "web-0", PortForward) {
+		t.Error("expected impersonated sessions to be kept apart")
+	}
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+func agentPod(states ...corev1.ContainerState) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "shop"}}
+	if len(states) > 0 {
+		pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{{Name: agentContainerName, State: states[0]}}
+	}
+	return pod
+}
+
+func waiting(reason, message string) corev1.ContainerState {
+	return corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}}
+}
+
+// sequenceClientset returns the given container states from successive pod
+// gets, repeating the last one.
+func sequenceClientset(states ...corev1.ContainerState) *fake.Clientset {
+	clientset := fake.NewClientset()
+	calls := 0
+	clientset.PrependReactor("get", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		state := states[min(calls, len(states)-1)]
+		calls++
+		return true, agentPod(state), nil
+	})
+	return clientset
+}
+
+func withFastInjectPoll(t *testing.T) {
+	original := injectPollInterval
+	injectPollInterval = 5 * time.Millisecond
+	t.Cleanup(func() { injectPollInterval = original })
+}
+
+func TestWaitForAgentReportsProgress(t *testing.T) {
+	withFastInjectPoll(t)
+	clientset := sequenceClientset(
+		waiting("ContainerCreating", ""),
+		waiting("ContainerCreating", ""),
+		waiting("ErrImagePull", "registry timeout"),
+		corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	)
+	var progress bytes.Buffer
+	if err := waitForAgent(context.Background(), clientset, "shop", "web-0", time.Second, &progress); err != nil {
+		t.Fatal(err)
+	}
+	want := "Agent container in shop/web-0: waiting: ContainerCreating (pulling image or starting)\n" +
+		"Agent container in shop/web-0: waiting: ErrImagePull: registry timeout\n" +
+		"Agent container in shop/web-0: running\n"
+	if progress.String() != want {
+		t.Errorf("unexpected progress:\n%s", progress.String())
+	}
+}
+
+func TestWaitForAgentFailsFast(t *testing.T) {
+	withFastInjectPoll(t)
+	clientset := sequenceClientset(waiting("InvalidImageName", `couldn't parse image name "bad image"`))
+	start := time.Now()
+	err := waitForAgent(context.Background(), clientset, "shop", "web-0", time.Minute, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "cannot start: waiting: InvalidImageName") {
+		t.Errorf("expected an invalid image error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("expected an unrecoverable state to stop the wait early")
+	}
+}
+
+func TestWaitForAgentTimeout(t *testing.T) {
+	withFastInjectPoll(t)
+	clientset := sequenceClientset(waiting("ImagePullBackOff", "Back-off pulling image"))
+	err := waitForAgent(context.Background(), clientset, "shop", "web-0", 50*time.Millisecond, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "after 50ms (last state: waiting: ImagePullBackOff: Back-off pulling image)") {
+		t.Errorf("expected a timeout naming the last state, got %v", err)
+	}
+}
+
+func TestDescribeAgentState(t *testing.T) {
+	terminated := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1, Message: "no TLS certificate"}}
+	if desc, running, failed := describeAgentState(terminated); desc != "terminated: Error (exit code 1): no TLS certificate" || running || !failed {
+		t.Errorf("unexpected terminated state %q %t %t", desc, running, failed)
+	}
+	if desc, running, failed := describeAgentState(corev1.ContainerState{}); desc != "pending" || running || failed {
+		t.Errorf("unexpected empty state %q %t %t", desc, running, failed)
+	}
+}
+
+func TestEphemeralAgentTargetContainer(t *testing.T) {
+	pod := agentPod()
+	pod.Spec.Containers = []corev1.Container{{Name: "app", SecurityContext: &corev1.SecurityContext{RunAsUser: &[]int64{1000}[0]}}, {Name: "envoy"}}
+
+	agent, err := ephemeralAgent(pod, Options{TargetContainer: "app", AgentImage: "pulsaar/agent:1.2.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent.TargetContainerName != "app" || agent.Image != "pulsaar/agent:1.2.0" {
+		t.Errorf("expected the agent to target app, got %+v", agent)
+	}
+	if len(agent.Env) == 0 || agent.Env[0] != (corev1.EnvVar{Name: "PULSAAR_TARGET_CONTAINER", Value: "app"}) {
+		t.Errorf("expected the agent to be told its target, got %+v", agent.Env)
+	}
+	if sc := agent.SecurityContext; *sc.RunAsUser != 1000 || !*sc.RunAsNonRoot {
+		t.Errorf("expected the agent to run as the target's user, got %+v", sc)
+	}
+	if agent, _ := ephemeralAgent(pod, Options{AgentImage: "pulsaar/agent:1.2.0"}); agent.TargetContainerName != "" || agent.Env[0].Name == "PULSAAR_TARGET_CONTAINER" {
+		t.Errorf("expected no target by default, got %+v", agent)
+	}
+
+	_, err = ephemeralAgent(pod, Options{TargetContainer: "db"})
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "containers: app, envoy") {
+		t.Errorf("expected a not found error listing the containers, got %v", err)
+	}
+
+	shared := pod.DeepCopy()
+	shared.Spec.ShareProcessNamespace = &[]bool{true}[0]
+	if _, err := ephemeralAgent(shared, Options{TargetContainer: "app"}); err == nil || !strings.Contains(err.Error(), "shares one process namespace") {
+		t.Errorf("expected shared process namespaces to be refused, got %v", err)
+	}
+
+	unset := pod.DeepCopy()
+	unset.Spec.Containers[0].SecurityContext = nil
+	if _, err := ephemeralAgent(unset, Options{TargetContainer: "app"}); err == nil || !strings.Contains(err.Error(), "does not set runAsUser") {
+		t.Errorf("expected a target without a user to be refused, got %v", err)
+	}
+	unset.Spec.SecurityContext = &corev1.PodSecurityContext{RunAsUser: &[]int64{0}[0]}
+	if _, err := ephemeralAgent(unset, Options{TargetContainer: "app"}); err == nil || !strings.Contains(err.Error(), "runs as root") {
+		t.Errorf("expected a root target to need a root agent, got %v", err)
+	}
+	if agent, err := ephemeralAgent(unset, Options{TargetContainer: "app", AgentPrivilege: privilege.Root}); err != nil || *agent.SecurityContext.RunAsUser != 0 {
+		t.Errorf("expected a root agent to target a root container, got %+v, %v", agent, err)
+	}
+}
+
+func TestEphemeralAgentPrivilege(t *testing.T) {
+	pod := agentPod()
+	pod.Spec.Containers = []corev1.Container{{Name: "app"}}
+
+	agent, err := ephemeralAgent(pod, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := agent.SecurityContext
+	if *sc.RunAsUser != privilege.UID || *sc.AllowPrivilegeEscalation || len(sc.Capabilities.Add) != 0 || agent.Command != nil {
+		t.Errorf("expected a non-root agent by default, got %+v", sc)
+	}
+	if env := agent.Env[len(agent.Env)-1]; env != (corev1.EnvVar{Name: privilege.Env, Value: privilege.NonRoot}) {
+		t.Errorf("expected the agent to be told its privilege, got %+v", env)
+	}
+
+	agent, err = ephemeralAgent(pod, Options{AgentPrivilege: privilege.DACReadSearch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(agent.Command) != "[/usr/local/bin/agent-dac-read-search]" || fmt.Sprint(agent.SecurityContext.Capabilities.Add) != "[DAC_READ_SEARCH]" {
+		t.Errorf("expected the capability binary and capability, got %v %+v", agent.Command, agent.SecurityContext)
+	}
+
+	windows := pod.DeepCopy()
+	windows.Spec.OS = &corev1.PodOS{Name: corev1.Windows}
+	if agent, err := ephemeralAgent(windows, Options{}); err != nil || agent.SecurityContext != nil {
+		t.Errorf("expected no Linux security context on Windows, got %+v, %v", agent, err)
+	}
+
+	if _, err := Connect(context.Background(), Options{Pod: "web-0", AgentPrivilege: "admin"}); err == nil || !strings.Contains(err.Error(), `invalid agent privilege "admin"`) {
+		t.Errorf("expected Connect to reject the privilege, got %v", err)
+	}
+}
+
+func TestEphemeralAgentAlreadyInjected(t *testing.T) {
+	pod := agentPod()
+	pod.Spec.Containers = []corev1.Container{{Name: "app"}}
+	pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: agentContainerName},
+		TargetContainerName:      "app",
+	}}
+	for _, target := range []string{"", "app"} {
+		if agent, err := ephemeralAgent(pod, Options{TargetContainer: target}); agent != nil || err != nil {
+			t.Errorf("target %q: expected the injected agent to be reused, got %+v, %v", target, agent, err)
+		}
+	}
+
+	pod.Spec.EphemeralContainers[0].TargetContainerName = ""
+	if _, err := ephemeralAgent(pod, Options{TargetContainer: "app"}); err == nil || !strings.Contains(err.Error(), "injected without a target container and cannot be changed") {
+		t.Errorf("expected a retarget to be refused, got %v", err)
+	}
+
+	pod.Spec.EphemeralContainers = nil
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: agentContainerName})
+	if _, err := ephemeralAgent(pod, Options{TargetContainer: "app"}); err == nil || !strings.Contains(err.Error(), "runs the agent as a sidecar") {
+		t.Errorf("expected a sidecar agent not to be targeted, got %v", err)
+	}
+}
+
+func TestAgentLimits(t *testing.T) {
+	tests := []struct {
+		cpu, memory string
+		want        []corev1.EnvVar
+	}{
+		{"", "", []corev1.EnvVar{{Name: "GOMAXPROCS", Value: "1"}, {Name: "GOMEMLIMIT", Value: "134217728"}}},
+		{"1500m", "64Mi", []corev1.EnvVar{{Name: "GOMAXPROCS", Value: "2"}, {Name: "GOMEMLIMIT", Value: "67108864"}}},
+		{"0", "1G", []corev1.EnvVar{{Name: "GOMEMLIMIT", Value: "1000000000"}}},
+		{"0", "0", nil},
+	}
+	for _, tt := range tests {
+		got, err := agentLimits(Options{AgentCPU: tt.cpu, AgentMemory: tt.memory})
+		if err != nil {
+			t.Errorf("%q/%q: %v", tt.cpu, tt.memory, err)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q/%q: got %v, want %v", tt.cpu, tt.memory, got, tt.want)
+		}
+	}
+
+	if _, err := agentLimits(Options{AgentMemory: "lots"}); err == nil || !strings.Contains(err.Error(), `invalid agent memory limit "lots"`) {
+		t.Errorf("expected an invalid memory error, got %v", err)
+	}
+	if _, err := agentLimits(Options{AgentCPU: "-1"}); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("expected a negative CPU error, got %v", err)
+	}
+	if _, err := Connect(context.Background(), Options{Pod: "web-0", AgentCPU: "two"}); err == nil || !strings.Contains(err.Error(), "invalid agent CPU limit") {
+		t.Errorf("expected Connect to reject the limit before contacting the cluster, got %v", err)
+	}
+}
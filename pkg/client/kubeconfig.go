@@ -0,0 +1,39 @@
+package client
+
+import (
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	// Register the kubeconfig auth providers, such as oidc, so their users
+	// can connect. The removed gcp and azure providers then fail with
+	// client-go's advice to switch to their exec plugins.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+// kubeClientConfig loads configuration the way kubectl does: the files
+// listed in $KUBECONFIG, merged, or ~/.kube/config, at their current
+// context, and the in-cluster configuration when neither exists.
+func kubeClientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+}
+
+// KubeConfig returns the configuration kubectl would use, including the
+// cluster's proxy-url. Exec credential plugins, such as the AWS, GKE and
+// Azure ones, are run as client-go needs tokens, so an expired token is
+// replaced on the next request, and may prompt on the terminal when the
+// plugin asks to.
+func KubeConfig() (*rest.Config, error) {
+	return kubeClientConfig().ClientConfig()
+}
+
+// KubeNamespace returns the namespace of the kubeconfig's current context,
+// or the pod's own namespace in a cluster, and "default" when neither sets
+// one.
+func KubeNamespace() string {
+	namespace, _, err := kubeClientConfig().Namespace()
+	if err != nil || namespace == "" {
+		return "default"
+	}
+	return namespace
+}
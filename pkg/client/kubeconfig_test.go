@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestKubeConfigExecPlugin connects the way managed-cluster users do: a
+// kubeconfig from a $KUBECONFIG list whose user runs an exec plugin. Each
+// token the plugin prints has already expired, so every request must run
+// it again.
+func TestKubeConfigExecPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake plugin is a shell script")
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	var mu sync.Mutex
+	var tokens []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"apiVersion":"authorization.k8s.io/v1","kind":"SelfSubjectAccessReview","status":{"allowed":true}}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "cloud-auth-plugin")
+	script := `#!/bin/sh
+n=$(($(cat "$0.count" 2>/dev/null || echo 0) + 1))
+echo $n > "$0.count"
+echo '{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"token-'$n'","expirationTimestamp":"2000-01-01T00:00:00Z"}}'
+`
+	if err := os.WriteFile(plugin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	clusters := filepath.Join(dir, "clusters")
+	if err := os.WriteFile(clusters, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: managed
+  cluster:
+    server: `+srv.URL+`
+    insecure-skip-tls-verify: true
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	users := filepath.Join(dir, "users")
+	if err := os.WriteFile(users, []byte(`apiVersion: v1
+kind: Config
+current-context: managed
+contexts:
+- name: managed
+  context:
+    cluster: managed
+    user: cloud
+users:
+- name: cloud
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: `+plugin+`
+      interactiveMode: Never
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", strings.Join([]string{clusters, users}, string(os.PathListSeparator)))
+
+	config, err := KubeConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := CheckAccess(context.Background(), config, "shop", "web-0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(tokens) != 2 || tokens[0] != "Bearer token-1" || tokens[1] != "Bearer token-2" {
+		t.Errorf("expected a fresh plugin token for each request, got %v", tokens)
+	}
+}
+
+func TestKubeNamespace(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config")
+	if err := os.WriteFile(file, []byte(`apiVersion: v1
+kind: Config
+current-context: payments
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example:6443
+    proxy-url: http://proxy.corp:3128
+contexts:
+- name: payments
+  context:
+    cluster: prod
+    namespace: payments
+users: []
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", file)
+	if ns := KubeNamespace(); ns != "payments" {
+		t.Errorf("expected the current context's namespace, got %q", ns)
+	}
+	config, err := KubeConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := (Options{}).agentProxy(config); u == nil || u.String() != "http://proxy.corp:3128" {
+		t.Errorf("expected the cluster's proxy-url, got %v", u)
+	}
+
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "missing"))
+	if ns := KubeNamespace(); ns != "default" {
+		t.Errorf("expected default without a kubeconfig, got %q", ns)
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HostAgentSelector matches the host-mode agent pods of the Helm chart's
+// DaemonSet.
+const HostAgentSelector = "app.kubernetes.io/component=host-agent"
+
+// HostAgentNamespace returns PULSAAR_HOST_AGENT_NAMESPACE, or pulsaar-system.
+func HostAgentNamespace() string {
+	if ns := os.Getenv("PULSAAR_HOST_AGENT_NAMESPACE"); ns != "" {
+		return ns
+	}
+	return "pulsaar-system"
+}
+
+// FindHostAgent returns the name of the running host-mode agent pod on node.
+func FindHostAgent(ctx context.Context, clientset kubernetes.Interface, namespace, node string) (string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: HostAgentSelector,
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node).String(),
+	})
+	if err != nil {
+		return "", classifyAPIError(err, fmt.Errorf("failed to list host agents in namespace %s: %v", namespace, err))
+	}
+	for _, pod := range pods.Items {
+		// Fake clientsets ignore field selectors, so check the node too.
+		if pod.Spec.NodeName == node && pod.Status.Phase == corev1.PodRunning {
+			return pod.Name, nil
+		}
+	}
+	return "", classify(ErrNotFound, fmt.Errorf("no running host agent on node %s in namespace %s. Enable hostAgent in the Helm chart or set PULSAAR_HOST_AGENT_NAMESPACE", node, namespace))
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func hostAgentPod(name, node string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "pulsaar-system",
+			Labels:    map[string]string{"app.kubernetes.io/component": "host-agent"},
+		},
+		Spec:   corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestFindHostAgent(t *testing.T) {
+	clientset := fake.NewClientset(
+		hostAgentPod("host-agent-old", "node-a", corev1.PodFailed),
+		hostAgentPod("host-agent-a", "node-a", corev1.PodRunning),
+		hostAgentPod("host-agent-b", "node-b", corev1.PodRunning),
+	)
+	ctx := context.Background()
+
+	pod, err := FindHostAgent(ctx, clientset, "pulsaar-system", "node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod != "host-agent-a" {
+		t.Errorf("expected host-agent-a, got %s", pod)
+	}
+	if _, err := FindHostAgent(ctx, clientset, "pulsaar-system", "node-c"); err == nil {
+		t.Error("expected an error for a node without a host agent")
+	}
+}
+
+func TestHostAgentNamespace(t *testing.T) {
+	t.Setenv("PULSAAR_HOST_AGENT_NAMESPACE", "")
+	if ns := HostAgentNamespace(); ns != "pulsaar-system" {
+		t.Errorf("expected pulsaar-system, got %s", ns)
+	}
+	t.Setenv("PULSAAR_HOST_AGENT_NAMESPACE", "ops")
+	if ns := HostAgentNamespace(); ns != "ops" {
+		t.Errorf("expected ops, got %s", ns)
+	}
+}
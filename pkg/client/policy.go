@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Where the agent looks up its allowed roots. A pod annotation takes
+// precedence over the namespace ConfigMap, which takes precedence over the
+// agent's PULSAAR_ALLOWED_ROOTS.
+const (
+	ConfigMapName          = "pulsaar-config"
+	AllowedRootsKey        = "allowed-roots"
+	AllowedRootsAnnotation = "pulsaar.io/allowed-roots"
+)
+
+// Policy is the access scope configured for a namespace and, optionally, one
+// pod in it. Nil root lists mean the setting is absent.
+type Policy struct {
+	Namespace      string
+	ConfigMapRoots []string
+	Pod            string
+	PodRoots       []string
+}
+
+// Effective returns the roots an agent started now would use and where they
+// come from. ok is false when neither setting exists and the agent falls
+// back to its own defaults.
+func (p *Policy) Effective() (roots []string, source string, ok bool) {
+	if p.PodRoots != nil {
+		return p.PodRoots, "pod annotation " + AllowedRootsAnnotation, true
+	}
+	if p.ConfigMapRoots != nil {
+		return p.ConfigMapRoots, "ConfigMap " + ConfigMapName, true
+	}
+	return nil, "", false
+}
+
+// splitRoots parses a comma-separated root list the same way the agent does.
+func splitRoots(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	roots := strings.Split(s, ",")
+	for i, root := range roots {
+		roots[i] = strings.TrimSpace(root)
+	}
+	return roots
+}
+
+// ValidateAllowedRoots checks and normalizes roots: each must be an absolute
+// path without ".." components. Duplicates are dropped.
+func ValidateAllowedRoots(roots []string) ([]string, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("at least one allowed root is required")
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, root := range roots {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			return nil, fmt.Errorf("allowed roots must not contain empty entries")
+		}
+		if !strings.HasPrefix(root, "/") {
+			return nil, fmt.Errorf("allowed root %q must be an absolute path", root)
+		}
+		for _, part := range strings.Split(root, "/") {
+			if part == ".." {
+				return nil, fmt.Errorf("allowed root %q must not contain '..'", root)
+			}
+		}
+		root = path.Clean(root)
+		if !seen[root] {
+			seen[root] = true
+			out = append(out, root)
+		}
+	}
+	return out, nil
+}
+
+// GetPolicy reads the namespace ConfigMap and, when pod is set, the pod's
+// annotation.
+func GetPolicy(ctx context.Context, clientset kubernetes.Interface, namespace, pod string) (*Policy, error) {
+	p := &Policy{Namespace: namespace, Pod: pod}
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read ConfigMap %s/%s: %v", namespace, ConfigMapName, err)
+	default:
+		if v, ok := cm.Data[AllowedRootsKey]; ok {
+			p.ConfigMapRoots = splitRoots(v)
+		}
+	}
+
+	if pod != "" {
+		po, err := clientset.CoreV1().Pods(namespace).Get(ctx, pod, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod %s/%s: %v", namespace, pod, err)
+		}
+		if v, ok := po.Annotations[AllowedRootsAnnotation]; ok {
+			p.PodRoots = splitRoots(v)
+		}
+	}
+	return p, nil
+}
+
+// SetAllowedRoots validates roots and stores them in the pod annotation when
+// pod is set, otherwise in the namespace ConfigMap, creating it if needed.
+// Agents read the setting at startup.
+func SetAllowedRoots(ctx context.Context, clientset kubernetes.Interface, namespace, pod string, roots []string) error {
+	roots, err := ValidateAllowedRoots(roots)
+	if err != nil {
+		return err
+	}
+	value := strings.Join(roots, ",")
+
+	if pod != "" {
+		patch, err := json.Marshal(map[string]any{
+			"metadata": map[string]any{"annotations": map[string]string{AllowedRootsAnnotation: value}},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := clientset.CoreV1().Pods(namespace).Patch(ctx, pod, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to annotate pod %s/%s: %v", namespace, pod, err)
+		}
+		return nil
+	}
+
+	configMaps := clientset.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: namespace},
+			Data:       map[string]string{AllowedRootsKey: value},
+		}
+		if _, err := configMaps.Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create ConfigMap %s/%s: %v", namespace, ConfigMapName, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read ConfigMap %s/%s: %v", namespace, ConfigMapName, err)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[AllowedRootsKey] = value
+	if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s/%s: %v", namespace, ConfigMapName, err)
+	}
+	return nil
+}
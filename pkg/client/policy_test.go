@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateAllowedRoots(t *testing.T) {
+	roots, err := ValidateAllowedRoots([]string{" /var/log/ ", "/app/config", "/var/log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/var/log", "/app/config"}; !reflect.DeepEqual(roots, want) {
+		t.Errorf("expected %v, got %v", want, roots)
+	}
+
+	for _, bad := range [][]string{nil, {""}, {"var/log"}, {"/app/../etc"}, {"/app", " "}} {
+		if _, err := ValidateAllowedRoots(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSetAndGetPolicy(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "shop"}})
+
+	p, err := GetPolicy(ctx, clientset, "shop", "web-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := p.Effective(); ok {
+		t.Error("expected no policy before set")
+	}
+
+	if err := SetAllowedRoots(ctx, clientset, "shop", "", []string{"/var/log"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAllowedRoots(ctx, clientset, "shop", "", []string{"/var/log", "/app/config"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err = GetPolicy(ctx, clientset, "shop", "web-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, source, _ := p.Effective()
+	if !reflect.DeepEqual(roots, []string{"/var/log", "/app/config"}) || source != "ConfigMap pulsaar-config" {
+		t.Errorf("unexpected effective policy %v from %s", roots, source)
+	}
+
+	if err := SetAllowedRoots(ctx, clientset, "shop", "web-0", []string{"/tmp"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err = GetPolicy(ctx, clientset, "shop", "web-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, source, _ = p.Effective()
+	if !reflect.DeepEqual(roots, []string{"/tmp"}) || source != "pod annotation "+AllowedRootsAnnotation {
+		t.Errorf("expected pod annotation to win, got %v from %s", roots, source)
+	}
+
+	if err := SetAllowedRoots(ctx, clientset, "shop", "", []string{"relative"}); err == nil {
+		t.Error("expected invalid roots to be rejected before any update")
+	}
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// portForwardTimeout bounds the wait for kubectl port-forward to accept
+// connections.
+var portForwardTimeout = 15 * time.Second
+
+// kubectlCommand builds the kubectl invocation. Tests replace it.
+var kubectlCommand = func(args ...string) *exec.Cmd {
+	return exec.Command("kubectl", args...)
+}
+
+// portForward is a running kubectl port-forward.
+type portForward struct {
+	cmd    *exec.Cmd
+	addr   string
+	stderr func() string
+	exited chan struct{}
+	err    error // the Wait result, set before exited is closed
+}
+
+// startPortForward runs kubectl port-forward to the agent on localPort and
+// waits until the port accepts connections. It fails early with kubectl's
+// error output if kubectl exits, and kills kubectl if ctx is cancelled.
+// With a session, kubectl is detached and logs to a file beside the session
+// record so it can outlive the CLI; otherwise it dies with the CLI.
+func startPortForward(ctx context.Context, opts Options, localPort int, session *Session) (*portForward, error) {
+	pf := &portForward{
+		cmd:    kubectlCommand(append(opts.impersonationArgs(), "port-forward", fmt.Sprintf("%s/%s", opts.Namespace, opts.Pod), fmt.Sprintf("%d:%d", localPort, opts.agentPort()))...),
+		addr:   fmt.Sprintf("localhost:%d", localPort),
+		exited: make(chan struct{}),
+	}
+	if opts.Proxy != "" {
+		pf.cmd.Env = append(pf.cmd.Environ(), proxyEnv(opts.Proxy)...)
+	}
+	if session != nil {
+		detach(pf.cmd)
+		logFile := session.file + ".log"
+		if err := os.MkdirAll(filepath.Dir(logFile), 0o700); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = f.Close() }()
+		pf.cmd.Stderr = f
+		pf.stderr = func() string {
+			data, _ := os.ReadFile(logFile)
+			return string(data)
+		}
+	} else {
+		bindToParent(pf.cmd)
+		buf := &lockedBuffer{}
+		pf.cmd.Stderr = buf
+		pf.stderr = buf.String
+	}
+
+	if err := pf.cmd.Start(); err != nil {
+		return nil, classify(ErrConnection, fmt.Errorf("failed to start kubectl port-forward. Ensure kubectl is installed, accessible, and you have permissions to port-forward to the pod. Error: %v", err))
+	}
+	go func() {
+		pf.err = pf.cmd.Wait()
+		close(pf.exited)
+	}()
+
+	if err := pf.waitReady(ctx); err != nil {
+		pf.stop()
+		return nil, err
+	}
+	if session == nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				pf.stop()
+			case <-pf.exited:
+			}
+		}()
+	}
+	return pf, nil
+}
+
+func (pf *portForward) waitReady(ctx context.Context) error {
+	deadline := time.NewTimer(portForwardTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if conn, err := net.DialTimeout("tcp", pf.addr, time.Second); err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		select {
+		case <-pf.exited:
+			return classify(ErrConnection, fmt.Errorf("kubectl port-forward exited before it was ready (%v)%s", pf.err, pf.output()))
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return classify(ErrConnection, fmt.Errorf("kubectl port-forward was not ready after %s%s", portForwardTimeout, pf.output()))
+		case <-ticker.C:
+		}
+	}
+}
+
+// output formats kubectl's error output for an error message.
+func (pf *portForward) output() string {
+	if out := strings.TrimSpace(pf.stderr()); out != "" {
+		return ": " + out
+	}
+	return ""
+}
+
+// stop kills kubectl and waits for it to exit.
+func (pf *portForward) stop() {
+	select {
+	case <-pf.exited:
+		return
+	default:
+	}
+	_ = pf.cmd.Process.Kill()
+	<-pf.exited
+}
+
+// lockedBuffer collects output written by the exec package's copying
+// goroutine.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Keep the start, where kubectl reports why it failed.
+	if room := 4096 - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
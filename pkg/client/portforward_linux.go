@@ -0,0 +1,12 @@
+package client
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// bindToParent has the kernel kill cmd when the CLI exits, however it
+// exits.
+func bindToParent(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package client
+
+import "os/exec"
+
+// bindToParent is a no-op where the kernel cannot tie a child's lifetime to
+// its parent; Close and context cancellation still stop kubectl.
+func bindToParent(cmd *exec.Cmd) {}
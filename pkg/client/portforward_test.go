@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperKubectl stands in for kubectl port-forward when run by
+// fakeKubectl; PULSAAR_FAKE_KUBECTL picks its behaviour.
+func TestHelperKubectl(t *testing.T) {
+	mode := os.Getenv("PULSAAR_FAKE_KUBECTL")
+	if mode == "" {
+		return
+	}
+	args := os.Args[len(os.Args)-2:]
+	switch mode {
+	case "listen":
+		lis, err := net.Listen("tcp", "localhost:"+strings.Split(args[1], ":")[0])
+		if err != nil {
+			os.Exit(3)
+		}
+		for {
+			conn, err := lis.Accept()
+			if err == nil {
+				_ = conn.Close()
+			}
+		}
+	case "fail":
+		fmt.Fprintf(os.Stderr, "error: pods %q not found\n", strings.TrimPrefix(args[0], "shop/"))
+		os.Exit(1)
+	case "proxy":
+		fmt.Fprintf(os.Stderr, "proxy %s\n", os.Getenv("HTTPS_PROXY"))
+		os.Exit(1)
+	case "args":
+		fmt.Fprintf(os.Stderr, "args %s\n", strings.Join(os.Args[slices.Index(os.Args, "--")+1:], " "))
+		os.Exit(1)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func fakeKubectl(t *testing.T, mode string) {
+	t.Helper()
+	original := kubectlCommand
+	t.Cleanup(func() { kubectlCommand = original })
+	kubectlCommand = func(args ...string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestHelperKubectl$", "--"}, args...)...)
+		cmd.Env = append(os.Environ(), "PULSAAR_FAKE_KUBECTL="+mode)
+		return cmd
+	}
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = lis.Close() }()
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func exitedWithin(pf *portForward, d time.Duration) bool {
+	select {
+	case <-pf.exited:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func TestStartPortForwardWaitsForPort(t *testing.T) {
+	fakeKubectl(t, "listen")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pf, err := startPortForward(ctx, Options{Namespace: "shop", Pod: "web-0"}, freePort(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", pf.addr)
+	if err != nil {
+		t.Fatalf("expected the port to be ready, got %v", err)
+	}
+	_ = conn.Close()
+
+	// Cancelling the command's context stops kubectl.
+	cancel()
+	if !exitedWithin(pf, 5*time.Second) {
+		pf.stop()
+		t.Error("expected kubectl to be killed when the context is cancelled")
+	}
+}
+
+func TestStartPortForwardReportsEarlyExit(t *testing.T) {
+	fakeKubectl(t, "fail")
+	_, err := startPortForward(context.Background(), Options{Namespace: "shop", Pod: "web-0"}, freePort(t), nil)
+	if !errors.Is(err, ErrConnection) || !strings.Contains(err.Error(), `exited before it was ready (exit status 1): error: pods "web-0" not found`) {
+		t.Errorf("expected kubectl's error output, got %v", err)
+	}
+}
+
+func TestStartPortForwardTimeout(t *testing.T) {
+	fakeKubectl(t, "hang")
+	original := portForwardTimeout
+	portForwardTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { portForwardTimeout = original })
+
+	_, err := startPortForward(context.Background(), Options{Namespace: "shop", Pod: "web-0"}, freePort(t), nil)
+	if !errors.Is(err, ErrConnection) || !strings.Contains(err.Error(), "not ready after 200ms") {
+		t.Errorf("expected a readiness timeout, got %v", err)
+	}
+}
+
+func TestStartPortForwardSessionLog(t *testing.T) {
+	fakeKubectl(t, "fail")
+	session := &Session{file: filepath.Join(t.TempDir(), "sessions", "abc.json")}
+	_, err := startPortForward(context.Background(), Options{Namespace: "shop", Pod: "web-1"}, freePort(t), session)
+	if err == nil || !strings.Contains(err.Error(), `pods "web-1" not found`) {
+		t.Errorf("expected the logged error output, got %v", err)
+	}
+	if _, err := os.Stat(session.file + ".log"); err != nil {
+		t.Errorf("expected a session log file, got %v", err)
+	}
+	_ = session.Close()
+	if _, err := os.Stat(session.file + ".log"); !os.IsNotExist(err) {
+		t.Errorf("expected Close to remove the log file, got %v", err)
+	}
+}
+
+func TestStartPortForwardProxy(t *testing.T) {
+	fakeKubectl(t, "proxy")
+	opts := Options{Namespace: "shop", Pod: "web-0", Proxy: "socks5://localhost:1080"}
+	_, err := startPortForward(context.Background(), opts, freePort(t), nil)
+	if err == nil || !strings.Contains(err.Error(), "proxy socks5://localhost:1080") {
+		t.Errorf("expected kubectl to get the proxy in HTTPS_PROXY, got %v", err)
+	}
+}
+
+func TestStartPortForwardImpersonation(t *testing.T) {
+	fakeKubectl(t, "args")
+	opts := Options{Namespace: "shop", Pod: "web-0", AgentPort: 50051, As: "alice", AsGroups: []string{"dev", "oncall"}}
+	_, err := startPortForward(context.Background(), opts, 4000, nil)
+	if err == nil || !strings.Contains(err.Error(), "args --as alice --as-group dev --as-group oncall port-forward shop/web-0 4000:50051") {
+		t.Errorf("expected kubectl to impersonate alice, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// processStart returns when pid started, in clock ticks since boot, from
+// /proc/<pid>/stat. A PID reused by another process starts later.
+func processStart(pid int) (string, error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return "", err
+	}
+	// The command name may contain spaces and parentheses; the fields
+	// after it start at the last ')'. starttime is the 22nd field.
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return "", fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	fields := bytes.Fields(data[i+1:])
+	if len(fields) < 20 {
+		return "", fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	return string(fields[19]), nil
+}
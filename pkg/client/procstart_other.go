@@ -0,0 +1,24 @@
+//go:build !linux && !windows
+
+package client
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// processStart returns when pid started, as ps reports it. A PID reused by
+// another process started later.
+func processStart(pid int) (string, error) {
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	start := strings.TrimSpace(string(out))
+	if start == "" {
+		return "", errors.New("no such process")
+	}
+	return start, nil
+}
@@ -0,0 +1,256 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"k8s.io/client-go/rest"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// Session records an agent connection kept open between CLI invocations.
+// While it is unexpired, Connect reuses it and skips the access check,
+// injection and tunnel setup. Expires is fixed when the session is created
+// and is not extended by reuse.
+type Session struct {
+	Host             string `json:"host"`
+	Namespace        string `json:"namespace"`
+	Pod              string `json:"pod"`
+	ConnectionMethod string `json:"connection_method"`
+	// LocalPort and PID identify the kubectl port-forward, if any, and
+	// PIDStart when it started, so a reused PID is not taken for it.
+	LocalPort int       `json:"local_port,omitempty"`
+	PID       int       `json:"pid,omitempty"`
+	PIDStart  string    `json:"pid_start,omitempty"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires"`
+
+	file string
+}
+
+// sessionReaperEnv, when set, makes the process the reaper of the session
+// record it names instead of running its program; see startSessionReaper.
+const sessionReaperEnv = "PULSAAR_SESSION_REAPER"
+
+// reaperCheckInterval bounds each wait of a reaper, so the expiry is read
+// against the wall clock again after the machine sleeps.
+const reaperCheckInterval = time.Minute
+
+func init() {
+	if file := os.Getenv(sessionReaperEnv); file != "" {
+		reapSession(file)
+		os.Exit(0)
+	}
+}
+
+// startSessionReaper starts a detached copy of this program that closes
+// the session recorded in file once it expires, so its port-forward does
+// not outlive the TTL when no later run of the CLI notices.
+func startSessionReaper(file string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), sessionReaperEnv+"="+file)
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// reapSession waits until the session recorded in file expires, or its
+// port-forward exits, and closes it. It stops once the record is gone.
+func reapSession(file string) {
+	for {
+		s, err := readSession(file)
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		if s.Expired(now) {
+			_ = s.Close()
+			return
+		}
+		time.Sleep(min(s.Expires.Sub(now), reaperCheckInterval))
+	}
+}
+
+// DefaultCacheDir returns PULSAAR_CACHE_DIR, or pulsaar under the user
+// cache directory.
+func DefaultCacheDir() string {
+	if dir := os.Getenv("PULSAAR_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	return filepath.Join(cache, "pulsaar")
+}
+
+// DefaultSessionDir returns PULSAAR_SESSION_DIR, or sessions under
+// DefaultCacheDir.
+func DefaultSessionDir() string {
+	if dir := os.Getenv("PULSAAR_SESSION_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(DefaultCacheDir(), "sessions")
+}
+
+// sessionFile names a session record. Reusing a session skips the access
+// check, so sessions are kept apart per set of credentials, including the
+// user being impersonated, like cached access checks.
+func sessionFile(dir string, config *rest.Config, namespace, pod, method string) string {
+	key := []string{config.Host, credentialKey(config), namespace, pod, method}
+	sum := sha256.Sum256([]byte(strings.Join(key, "\x00")))
+	return filepath.Join(dir, namespace+"_"+pod+"_"+hex.EncodeToString(sum[:6])+".json")
+}
+
+// target is the address the session's gRPC connection dials.
+func (s *Session) target(proxyURL string) string {
+	if s.ConnectionMethod == PortForward {
+		return fmt.Sprintf("localhost:%d", s.LocalPort)
+	}
+	return proxyURL
+}
+
+// Expired reports whether the session is past its expiry or its
+// port-forward has exited.
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.Expires) || (s.PID != 0 && !s.portForwardRunning())
+}
+
+// portForwardRunning reports whether the session's port-forward is still
+// running: its PID is in use by a process that started when it did.
+// Records without a start time are never trusted.
+func (s *Session) portForwardRunning() bool {
+	if s.PIDStart == "" {
+		return false
+	}
+	start, err := processStart(s.PID)
+	return err == nil && start == s.PIDStart
+}
+
+// Close stops the session's port-forward and removes its record. A PID
+// that now belongs to another process is left alone.
+func (s *Session) Close() error {
+	if s.PID != 0 && s.portForwardRunning() {
+		if p, err := os.FindProcess(s.PID); err == nil {
+			_ = p.Kill()
+		}
+	}
+	_ = os.Remove(s.file + ".log")
+	if err := os.Remove(s.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Session) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.file), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.file, data, 0o600)
+}
+
+func readSession(file string) (*Session, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{file: file}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSessions returns the sessions recorded in dir. Expired sessions are
+// closed and left out.
+func ListSessions(dir string) ([]*Session, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var sessions []*Session
+	for _, file := range files {
+		s, err := readSession(file)
+		if err != nil {
+			_ = os.Remove(file)
+			continue
+		}
+		if s.Expired(now) {
+			_ = s.Close()
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// CloseSessions closes the sessions in dir for namespace and pod; empty
+// values match any. It returns how many were closed.
+func CloseSessions(dir, namespace, pod string) (int, error) {
+	sessions, err := ListSessions(dir)
+	if err != nil {
+		return 0, err
+	}
+	closed := 0
+	for _, s := range sessions {
+		if (namespace != "" && s.Namespace != namespace) || (pod != "" && s.Pod != pod) {
+			continue
+		}
+		if err := s.Close(); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// reuseSession returns a client on a live session for the target, or false
+// when there is none. Sessions that fail a health check are closed.
+func reuseSession(ctx context.Context, file, proxyURL string, opts Options, creds ...grpc.DialOption) (*Client, bool) {
+	s, err := readSession(file)
+	if err != nil {
+		return nil, false
+	}
+	if s.Expired(time.Now()) {
+		_ = s.Close()
+		return nil, false
+	}
+	conn, err := grpc.NewClient(s.target(proxyURL), creds...)
+	if err != nil {
+		_ = s.Close()
+		return nil, false
+	}
+	healthCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := api.NewPulsaarAgentClient(conn).Health(healthCtx, &emptypb.Empty{}); err != nil {
+		_ = conn.Close()
+		_ = s.Close()
+		return nil, false
+	}
+	c := New(conn, opts.AllowedRoots...)
+	c.closeFn = conn.Close
+	return c, true
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/client-go/rest"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	pulsaartesting "github.com/VrushankPatel/pulsaar/pkg/testing"
+)
+
+func TestReuseSession(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	api.RegisterPulsaarAgentServer(srv, pulsaartesting.NewAgent(fstest.MapFS{"app/a": {Data: []byte("a")}}))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	dir := t.TempDir()
+	file := sessionFile(dir, &rest.Config{Host: "https://cluster"}, "shop", "web-0", PortForward)
+	s := &Session{
+		Host:             "https://cluster",
+		Namespace:        "shop",
+		Pod:              "web-0",
+		ConnectionMethod: PortForward,
+		LocalPort:        lis.Addr().(*net.TCPAddr).Port,
+		Created:          time.Now(),
+		Expires:          time.Now().Add(time.Minute),
+		file:             file,
+	}
+	if err := s.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	creds := grpc.WithTransportCredentials(insecure.NewCredentials())
+	c, ok := reuseSession(context.Background(), file, "", Options{}, creds)
+	if !ok {
+		t.Fatal("expected the live session to be reused")
+	}
+	if _, err := c.Stat(context.Background(), "/app/a"); err != nil {
+		t.Errorf("stat over reused session: %v", err)
+	}
+	_ = c.Close()
+
+	srv.Stop()
+	if _, ok := reuseSession(context.Background(), file, "", Options{}, creds); ok {
+		t.Error("expected a session whose agent is gone not to be reused")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("expected the dead session to be removed")
+	}
+}
+
+func TestListAndCloseSessions(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for _, s := range []*Session{
+		{Namespace: "shop", Pod: "web-0", Expires: now.Add(time.Minute)},
+		{Namespace: "shop", Pod: "web-1", Expires: now.Add(time.Minute)},
+		{Namespace: "ops", Pod: "db-0", Expires: now.Add(-time.Minute)},
+	} {
+		s.file = sessionFile(dir, &rest.Config{}, s.Namespace, s.Pod, PortForward)
+		if err := s.save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := ListSessions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 live sessions, got %d", len(sessions))
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 2 {
+		t.Errorf("expected expired and corrupt records to be removed, %d left", len(files))
+	}
+
+	closed, err := CloseSessions(dir, "shop", "web-1")
+	if err != nil || closed != 1 {
+		t.Fatalf("expected 1 session closed, got %d (%v)", closed, err)
+	}
+	if closed, _ := CloseSessions(dir, "", ""); closed != 1 {
+		t.Errorf("expected the remaining session to be closed, got %d", closed)
+	}
+}
+
+func TestSessionFilePerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	alice := &rest.Config{Host: "https://cluster", BearerToken: "alice-token"}
+	bob := &rest.Config{Host: "https://cluster", BearerToken: "bob-token"}
+	if sessionFile(dir, alice, "shop", "web-0", PortForward) == sessionFile(dir, bob, "shop", "web-0", PortForward) {
+		t.Error("expected sessions of different users on one cluster to be kept apart")
+	}
+}
+
+func TestSessionPortForwardIdentity(t *testing.T) {
+	start, err := processStart(os.Getpid())
+	if err != nil {
+		t.Skipf("cannot read process start times here: %v", err)
+	}
+	now := time.Now()
+	s := &Session{PID: os.Getpid(), PIDStart: start, Expires: now.Add(time.Minute), file: filepath.Join(t.TempDir(), "s.json")}
+	if s.Expired(now) {
+		t.Error("expected a session whose port-forward runs to be live")
+	}
+
+	// The PID now belongs to a process that started at another time, here
+	// this test: Close must not kill it.
+	s.PIDStart = "reused"
+	if !s.Expired(now) {
+		t.Error("expected a session whose PID was reused to be expired")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s.PIDStart = ""
+	if !s.Expired(now) {
+		t.Error("expected a session without a start time not to be trusted")
+	}
+}
+
+func TestSessionReaperStopsExpiredPortForward(t *testing.T) {
+	fakeKubectl(t, "listen")
+	now := time.Now()
+	s := &Session{ConnectionMethod: PortForward, Created: now, Expires: now.Add(time.Second), file: filepath.Join(t.TempDir(), "s.json")}
+	pf, err := startPortForward(context.Background(), Options{Namespace: "shop", Pod: "web-0"}, freePort(t), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pf.stop()
+	s.PID = pf.cmd.Process.Pid
+	if s.PIDStart, err = processStart(s.PID); err != nil {
+		t.Skipf("cannot read process start times here: %v", err)
+	}
+	if err := s.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing but the reaper looks at the session again.
+	if err := startSessionReaper(s.file); err != nil {
+		t.Fatal(err)
+	}
+	if !exitedWithin(pf, 10*time.Second) {
+		t.Fatal("expected the port-forward to be stopped once the session expired")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(s.file); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the expired session's record to be removed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package client
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts cmd in its own process group so it outlives the CLI and is
+// not interrupted by Ctrl-C in the terminal.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
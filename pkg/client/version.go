@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// AgentInfo returns the agent's Health response. It is requested once per
+// Client and reused.
+func (c *Client) AgentInfo(ctx context.Context) (*api.HealthResponse, error) {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	if c.info != nil {
+		return c.info, nil
+	}
+	info, err := c.Health(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.info = info
+	return info, nil
+}
+
+// AgentCapabilities returns what the agent supports. It is requested once
+// per Client and reused. Agents that predate the Capabilities RPC are
+// described from their Health response, without limits, codecs or auth
+// modes.
+func (c *Client) AgentCapabilities(ctx context.Context) (*api.CapabilitiesResponse, error) {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	if c.caps != nil {
+		return c.caps, nil
+	}
+	caps, err := c.agent.Capabilities(ctx, &emptypb.Empty{})
+	if status.Code(err) == codes.Unimplemented {
+		caps, err = c.healthCapabilities(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.caps = caps
+	return caps, nil
+}
+
+// healthCapabilities splits the names in HealthResponse.Capabilities into
+// RPCs and features.
+func (c *Client) healthCapabilities(ctx context.Context) (*api.CapabilitiesResponse, error) {
+	info, err := c.AgentInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	caps := &api.CapabilitiesResponse{Version: info.Version}
+	for _, name := range info.Capabilities { //nolint:staticcheck // agents without the Capabilities RPC only report it here
+		if slices.Contains(api.Features(), name) {
+			caps.Features = append(caps.Features, name)
+		} else {
+			caps.Rpcs = append(caps.Rpcs, name)
+		}
+	}
+	return caps, nil
+}
+
+// Supports reports whether the agent implements the named RPC, e.g.
+// "TailFile", or request feature, e.g. api.FeatureJQ. Agents that do not
+// report capabilities, or cannot be asked, are assumed to, so the call
+// itself decides.
+func (c *Client) Supports(ctx context.Context, name string) bool {
+	caps, err := c.AgentCapabilities(ctx)
+	if err != nil || len(caps.Rpcs)+len(caps.Features) == 0 {
+		return true
+	}
+	return caps.Supports(name)
+}
+
+// compatibilityKey is the part of a semantic version that must match:
+// the major version, or major.minor before 1.0. ok is false for versions
+// that do not parse, such as development builds.
+func compatibilityKey(version string) (key string, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	for i, p := range parts {
+		// Drop pre-release and build suffixes such as 1.2.0-rc.1.
+		if j := strings.IndexAny(p, "-+"); j >= 0 {
+			parts[i] = p[:j]
+		}
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", false
+	}
+	if major > 0 {
+		return parts[0], true
+	}
+	if len(parts) < 2 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(parts[1]); err != nil {
+		return "", false
+	}
+	return "0." + parts[1], true
+}
+
+// VersionSkew returns an error if the CLI and agent versions are
+// incompatible: different major versions, or different minor versions
+// before 1.0. Versions that are not semantic versions, such as "dev", are
+// not compared.
+func VersionSkew(cliVersion, agentVersion string) error {
+	cliKey, ok := compatibilityKey(cliVersion)
+	if !ok {
+		return nil
+	}
+	agentKey, ok := compatibilityKey(agentVersion)
+	if !ok || cliKey == agentKey {
+		return nil
+	}
+	return fmt.Errorf("CLI version %s is not compatible with agent version %s", cliVersion, agentVersion)
+}
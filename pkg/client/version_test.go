@@ -0,0 +1,140 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	pulsaartesting "github.com/VrushankPatel/pulsaar/pkg/testing"
+)
+
+func TestVersionSkew(t *testing.T) {
+	for _, tc := range []struct {
+		cli, agent string
+		skew       bool
+	}{
+		{"v1.4.0", "1.2.3", false},
+		{"v2.0.0", "v1.9.9", true},
+		{"v2.0.0-rc.1", "2.1.0+build.5", false},
+		{"0.4.1", "v0.4.0", false},
+		{"0.5.0", "0.4.9", true},
+		{"dev", "v1.0.0", false},
+		{"v1.0.0", "test", false},
+		{"v1.0.0", "", false},
+	} {
+		err := VersionSkew(tc.cli, tc.agent)
+		if (err != nil) != tc.skew {
+			t.Errorf("VersionSkew(%q, %q) = %v, want skew %t", tc.cli, tc.agent, err, tc.skew)
+		}
+	}
+}
+
+func TestClientSupports(t *testing.T) {
+	agent := pulsaartesting.NewAgent(nil)
+	srv := pulsaartesting.Serve(agent)
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx := context.Background()
+	if c := New(conn); !c.Supports(ctx, "TailFile") || c.Supports(ctx, "Exec") {
+		t.Error("expected only advertised RPCs to be supported")
+	}
+	agent.Supported = []string{}
+	if c := New(conn); !c.Supports(ctx, "Exec") {
+		t.Error("agents that report no capabilities should be assumed to support every RPC")
+	}
+
+	// AgentInfo is asked once per client.
+	c := New(conn)
+	agent.Version = "v1.0.0"
+	first, _ := c.AgentInfo(ctx)
+	agent.Version = "v2.0.0"
+	second, _ := c.AgentInfo(ctx)
+	if first != second || second.Version != "v1.0.0" {
+		t.Errorf("expected the first Health response to be reused, got %v", second)
+	}
+}
+
+func TestClientAgentCapabilities(t *testing.T) {
+	agent := pulsaartesting.NewAgent(fstest.MapFS{"big": {Data: make([]byte, 2*pulsaartesting.MaxReadSize)}})
+	srv := pulsaartesting.Serve(agent)
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx := context.Background()
+	caps, err := New(conn).AgentCapabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.MaxReadSize != pulsaartesting.MaxReadSize || !caps.Supports("Search") || !caps.Supports(api.FeatureJQ) {
+		t.Errorf("expected the Capabilities response, got %v", caps)
+	}
+
+	// Reads longer than the agent allows are cut to its limit instead of
+	// failing.
+	resp, err := New(conn).Read(ctx, "/big", 0, 2*pulsaartesting.MaxReadSize)
+	if err != nil || int64(len(resp.Data)) != pulsaartesting.MaxReadSize {
+		t.Errorf("expected a read of the agent's limit, got %v", err)
+	}
+
+	// Agents without the RPC are described from Health.
+	agent.Supported = []string{"Stat", "ReadFile", api.FeatureDecompress}
+	caps, err = New(conn).AgentCapabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(caps.Rpcs, []string{"Stat", "ReadFile"}) || !slices.Equal(caps.Features, []string{api.FeatureDecompress}) || caps.MaxReadSize != 0 {
+		t.Errorf("expected capabilities from Health, got %v", caps)
+	}
+	if _, err := New(conn).Search(ctx, "/", "x", 0); err != errSearch {
+		t.Errorf("expected an upgrade hint for Search, got %v", err)
+	}
+}
+
+func TestClientTailSkipsUnadvertisedTailFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	agent := pulsaartesting.NewDirAgent(dir)
+	agent.Supported = []string{"Stat", "ReadFile", "Health"}
+	srv := pulsaartesting.Serve(agent)
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	c := New(conn)
+
+	var buf bytes.Buffer
+	if err := c.Tail(context.Background(), "/app.log", TailOptions{FromStart: true, NoFollow: true}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "old\n" {
+		t.Errorf("expected the file contents, got %q", buf.String())
+	}
+	for _, req := range agent.Requests() {
+		if req.Operation == "TailFile" {
+			t.Error("TailFile should not be called on an agent that does not advertise it")
+		}
+	}
+	err = c.Tail(context.Background(), "/app.log", TailOptions{Since: 1, NoFollow: true}, &buf)
+	if err == nil || !strings.Contains(err.Error(), "upgrade the agent") {
+		t.Errorf("expected an upgrade hint, got %v", err)
+	}
+}
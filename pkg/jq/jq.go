@@ -0,0 +1,328 @@
+// Package jq evaluates the subset of jq filters that the Pulsaar agent runs
+// on JSON-lines files, so only the selected fields cross the network:
+//
+//	.                 the whole value
+//	.level            a field; ."user-id" and .["user-id"] for other keys
+//	.req.headers[0]   nested fields and array indexes, negative from the end
+//	.level, .msg      each filter's output in turn
+//	{level, msg}      an object of those fields; {status: .res.code} renames
+//
+// A field of a value that is not an object, or an index past the end of an
+// array, is null, so lines with different shapes do not fail the read.
+// Output is compact JSON, one value per line, as from jq -c.
+package jq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotJSON is returned by Apply for a line that is not a JSON value.
+var ErrNotJSON = errors.New("not a JSON value")
+
+// Filter is a parsed filter expression.
+type Filter struct {
+	terms []term
+}
+
+// term is one comma-separated part of a filter: a path, or an object built
+// from named paths when keys is set.
+type term struct {
+	path   []step
+	keys   []string
+	values [][]step
+}
+
+// step is a field name or, when isIndex is set, an array index.
+type step struct {
+	field   string
+	index   int
+	isIndex bool
+}
+
+// Parse parses a filter such as ".level,.msg".
+func Parse(expr string) (*Filter, error) {
+	p := &parser{s: expr}
+	f := &Filter{}
+	for {
+		p.skipSpace()
+		t, err := p.term()
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %v", expr, err)
+		}
+		f.terms = append(f.terms, t)
+		p.skipSpace()
+		if p.done() {
+			return f, nil
+		}
+		if !p.consume(',') {
+			return nil, fmt.Errorf("invalid filter %q: unexpected %q at offset %d", expr, p.s[p.i], p.i)
+		}
+	}
+}
+
+// Apply writes the filter's output for one JSON line to buf, one value per
+// line.
+func (f *Filter) Apply(line []byte, buf *bytes.Buffer) error {
+	line = bytes.TrimSpace(line)
+	if !json.Valid(line) {
+		return ErrNotJSON
+	}
+	for _, t := range f.terms {
+		if t.keys == nil {
+			writeCompact(buf, lookup(line, t.path))
+			buf.WriteByte('\n')
+			continue
+		}
+		buf.WriteByte('{')
+		for i, k := range t.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(k)
+			buf.Write(key)
+			buf.WriteByte(':')
+			writeCompact(buf, lookup(line, t.values[i]))
+		}
+		buf.WriteString("}\n")
+	}
+	return nil
+}
+
+var null = json.RawMessage("null")
+
+func lookup(v json.RawMessage, path []step) json.RawMessage {
+	for _, s := range path {
+		if s.isIndex {
+			var a []json.RawMessage
+			if json.Unmarshal(v, &a) != nil {
+				return null
+			}
+			i := s.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i < 0 || i >= len(a) {
+				return null
+			}
+			v = a[i]
+			continue
+		}
+		var o map[string]json.RawMessage
+		if json.Unmarshal(v, &o) != nil {
+			return null
+		}
+		field, ok := o[s.field]
+		if !ok {
+			return null
+		}
+		v = field
+	}
+	return v
+}
+
+func writeCompact(buf *bytes.Buffer, v json.RawMessage) {
+	if err := json.Compact(buf, v); err != nil {
+		buf.WriteString("null")
+	}
+}
+
+type parser struct {
+	s string
+	i int
+}
+
+func (p *parser) done() bool { return p.i >= len(p.s) }
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.i++
+	return true
+}
+
+func (p *parser) skipSpace() {
+	for !p.done() && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+func (p *parser) term() (term, error) {
+	if p.consume('{') {
+		return p.object()
+	}
+	path, err := p.path()
+	return term{path: path}, err
+}
+
+// path parses a path starting with '.'.
+func (p *parser) path() ([]step, error) {
+	if !p.consume('.') {
+		return nil, p.unexpected("'.' or '{'")
+	}
+	var steps []step
+	// The first field follows the leading dot directly: .a, ."a", .[0].
+	switch c := p.peek(); {
+	case c == '"':
+		name, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step{field: name})
+	case isIdentStart(c):
+		steps = append(steps, step{field: p.ident()})
+	case c == '.':
+		return nil, errors.New("recursive descent (..) is not supported")
+	}
+	for {
+		switch {
+		case p.consume('['):
+			s, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		case p.peek() == '.':
+			p.i++
+			switch c := p.peek(); {
+			case c == '"':
+				name, err := p.str()
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, step{field: name})
+			case isIdentStart(c):
+				steps = append(steps, step{field: p.ident()})
+			case c == '[':
+				// .a.[0] is the same as .a[0].
+			default:
+				return nil, p.unexpected("a field name")
+			}
+		default:
+			return steps, nil
+		}
+	}
+}
+
+// bracket parses the rest of [0] or ["name"].
+func (p *parser) bracket() (step, error) {
+	p.skipSpace()
+	var s step
+	if p.peek() == '"' {
+		name, err := p.str()
+		if err != nil {
+			return s, err
+		}
+		s = step{field: name}
+	} else {
+		start := p.i
+		p.consume('-')
+		for !p.done() && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+			p.i++
+		}
+		n, err := strconv.Atoi(p.s[start:p.i])
+		if err != nil {
+			p.i = start
+			return s, p.unexpected("an array index or quoted field name")
+		}
+		s = step{index: n, isIndex: true}
+	}
+	p.skipSpace()
+	if !p.consume(']') {
+		return s, p.unexpected("']'")
+	}
+	return s, nil
+}
+
+// object parses the rest of {a, "b": .c}.
+func (p *parser) object() (term, error) {
+	t := term{keys: []string{}}
+	for {
+		p.skipSpace()
+		var key string
+		switch c := p.peek(); {
+		case c == '"':
+			k, err := p.str()
+			if err != nil {
+				return t, err
+			}
+			key = k
+		case isIdentStart(c):
+			key = p.ident()
+		default:
+			return t, p.unexpected("a key")
+		}
+		value := []step{{field: key}}
+		p.skipSpace()
+		if p.consume(':') {
+			p.skipSpace()
+			v, err := p.path()
+			if err != nil {
+				return t, err
+			}
+			value = v
+			p.skipSpace()
+		}
+		t.keys = append(t.keys, key)
+		t.values = append(t.values, value)
+		if p.consume('}') {
+			return t, nil
+		}
+		if !p.consume(',') {
+			return t, p.unexpected("',' or '}'")
+		}
+	}
+}
+
+func (p *parser) ident() string {
+	start := p.i
+	for !p.done() && (isIdentStart(p.s[p.i]) || p.s[p.i] >= '0' && p.s[p.i] <= '9') {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// str parses a double-quoted JSON string.
+func (p *parser) str() (string, error) {
+	start := p.i
+	p.i++
+	for !p.done() && p.s[p.i] != '"' {
+		if p.s[p.i] == '\\' {
+			p.i++
+		}
+		p.i++
+	}
+	if p.done() {
+		p.i = start
+		return "", errors.New("unterminated string")
+	}
+	p.i++
+	var s string
+	if err := json.Unmarshal([]byte(p.s[start:p.i]), &s); err != nil {
+		return "", fmt.Errorf("invalid string at offset %d: %v", start, err)
+	}
+	return s, nil
+}
+
+func (p *parser) unexpected(want string) error {
+	if p.done() {
+		return fmt.Errorf("expected %s at end of filter", want)
+	}
+	return fmt.Errorf("expected %s at offset %d, found %q", want, p.i, p.s[p.i])
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
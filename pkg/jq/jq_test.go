@@ -0,0 +1,85 @@
+package jq
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const line = `{"level": "error", "msg": "disk full", "req": {"id": "a1", "tags": ["x", "y", "z"]}, "user-id": 7}`
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{".", `{"level":"error","msg":"disk full","req":{"id":"a1","tags":["x","y","z"]},"user-id":7}` + "\n"},
+		{".level,.msg", "\"error\"\n\"disk full\"\n"},
+		{" .level , .msg ", "\"error\"\n\"disk full\"\n"},
+		{".req.id", "\"a1\"\n"},
+		{".req.tags[0]", "\"x\"\n"},
+		{".req.tags[-1]", "\"z\"\n"},
+		{".req.tags.[1]", "\"y\"\n"},
+		{".req.tags[5]", "null\n"},
+		{`."user-id"`, "7\n"},
+		{`.["user-id"]`, "7\n"},
+		{`.req["id"]`, "\"a1\"\n"},
+		{".missing.deeper", "null\n"},
+		{".level.deeper", "null\n"},
+		{".req.tags", `["x","y","z"]` + "\n"},
+		{"{level, msg}", `{"level":"error","msg":"disk full"}` + "\n"},
+		{`{level, id: .req.id, "user": ."user-id"}`, `{"level":"error","id":"a1","user":7}` + "\n"},
+		{"{msg}, .level", `{"msg":"disk full"}` + "\n\"error\"\n"},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.filter)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.filter, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := f.Apply([]byte(line), &buf); err != nil {
+			t.Errorf("%q: %v", tt.filter, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.filter, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestApplyNotJSON(t *testing.T) {
+	f, err := Parse(".msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	for _, l := range []string{"plain text", `{"msg": `, ""} {
+		if err := f.Apply([]byte(l), &buf); err != ErrNotJSON {
+			t.Errorf("%q: expected ErrNotJSON, got %v", l, err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct{ filter, want string }{
+		{"", "expected '.' or '{' at end of filter"},
+		{"level", `expected '.' or '{' at offset 0, found 'l'`},
+		{".level |.msg", `unexpected '|' at offset 7`},
+		{".a[x]", "expected an array index or quoted field name at offset 3"},
+		{".a[0", "expected ']' at end of filter"},
+		{`."a`, "unterminated string"},
+		{"{level", "expected ',' or '}' at end of filter"},
+		{"{.level}", "expected a key at offset 1"},
+		{"..", "recursive descent (..) is not supported"},
+		{".a.", "expected a field name at end of filter"},
+		{".a.-", "expected a field name at offset 3"},
+	} {
+		_, err := Parse(tt.filter)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q): expected %q, got %v", tt.filter, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package listing filters, sorts and pages directory entries for the
+// ListDirectory RPC, so the agent and pkg/testing give the same pages and
+// accept each other's page tokens.
+package listing
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"time"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+// MaxPageSize caps ListRequest.page_size.
+const MaxPageSize = 10000
+
+// Sort orders accepted in ListRequest.sort_by.
+const (
+	SortByName  = "name"
+	SortBySize  = "size"
+	SortByMtime = "mtime"
+)
+
+// Entry describes a directory entry. Size and ModTime are only read when
+// the query needs them; see Query.NeedsInfo.
+type Entry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Query is the filtering, sorting and paging of a ListRequest.
+type Query struct {
+	sortBy        string
+	desc          bool
+	nameGlob      string
+	minSize       int64
+	modifiedSince time.Time
+	pageSize      int32
+	after         *cursor
+}
+
+// cursor is the decoded page token: the sort key of the last entry
+// returned.
+type cursor struct {
+	SortBy  string `json:"s"`
+	Desc    bool   `json:"d,omitempty"`
+	Name    string `json:"n"`
+	Size    int64  `json:"z,omitempty"`
+	ModTime int64  `json:"m,omitempty"`
+}
+
+// NewQuery validates req. Errors describe the invalid field and are meant
+// to be returned as InvalidArgument.
+func NewQuery(req *api.ListRequest) (*Query, error) {
+	q := &Query{sortBy: req.SortBy, nameGlob: req.NameGlob, minSize: req.MinSize, pageSize: req.PageSize}
+	switch q.sortBy {
+	case "":
+		q.sortBy = SortByName
+	case SortByName, SortBySize, SortByMtime:
+	default:
+		return nil, fmt.Errorf("unsupported sort_by %q; use name, size or mtime", req.SortBy)
+	}
+	switch req.Order {
+	case "", "asc":
+	case "desc":
+		q.desc = true
+	default:
+		return nil, fmt.Errorf("unsupported order %q; use asc or desc", req.Order)
+	}
+	if _, err := path.Match(q.nameGlob, ""); err != nil {
+		return nil, fmt.Errorf("invalid name_glob %q: %v", q.nameGlob, err)
+	}
+	if q.minSize < 0 {
+		return nil, fmt.Errorf("min_size must not be negative")
+	}
+	if req.ModifiedSince != nil {
+		q.modifiedSince = req.ModifiedSince.AsTime()
+	}
+	if q.pageSize < 0 || q.pageSize > MaxPageSize {
+		return nil, fmt.Errorf("page size must be between 0 and %d", MaxPageSize)
+	}
+	if req.PageToken != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(req.PageToken)
+		var c cursor
+		if err == nil {
+			err = json.Unmarshal(raw, &c)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid page token")
+		}
+		if c.SortBy != q.sortBy || c.Desc != q.desc {
+			return nil, fmt.Errorf("page token is for a different sort order")
+		}
+		q.after = &c
+	}
+	return q, nil
+}
+
+// NeedsInfo reports whether entries must have Size and ModTime set.
+func (q *Query) NeedsInfo() bool {
+	return q.sortBy != SortByName || q.minSize > 0 || !q.modifiedSince.IsZero()
+}
+
+// Match reports whether e passes the filters.
+func (q *Query) Match(e Entry) bool {
+	if q.nameGlob != "" {
+		if ok, _ := path.Match(q.nameGlob, e.Name); !ok {
+			return false
+		}
+	}
+	return e.Size >= q.minSize && !e.ModTime.Before(q.modifiedSince)
+}
+
+// Page returns the page of items that match, in sort order, after the page
+// token, with the token of the next page or "" for the last one. describe
+// returns an item's Entry, or false to skip the item, e.g. a file deleted
+// since the directory was read. A page size of zero returns every match.
+// The token records the last entry's sort key, so entries created or
+// removed between calls do not shift later pages.
+func Page[T any](q *Query, items []T, describe func(T) (Entry, bool)) ([]T, string) {
+	type described struct {
+		item  T
+		entry Entry
+	}
+	var matched []described
+	for _, item := range items {
+		if e, ok := describe(item); ok && q.Match(e) {
+			matched = append(matched, described{item, e})
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b described) int { return q.compare(a.entry, b.entry) })
+	if q.after != nil {
+		after := Entry{Name: q.after.Name, Size: q.after.Size, ModTime: time.Unix(0, q.after.ModTime)}
+		i := sort.Search(len(matched), func(i int) bool { return q.compare(matched[i].entry, after) > 0 })
+		matched = matched[i:]
+	}
+	next := ""
+	if q.pageSize > 0 && len(matched) > int(q.pageSize) {
+		matched = matched[:q.pageSize]
+		last := matched[len(matched)-1].entry
+		c := cursor{SortBy: q.sortBy, Desc: q.desc, Name: last.Name}
+		if q.sortBy == SortBySize {
+			c.Size = last.Size
+		}
+		if q.sortBy == SortByMtime {
+			c.ModTime = last.ModTime.UnixNano()
+		}
+		raw, _ := json.Marshal(c)
+		next = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	page := make([]T, len(matched))
+	for i, m := range matched {
+		page[i] = m.item
+	}
+	return page, next
+}
+
+// compare orders entries by the sort key, then by name so that the order,
+// and so each page, is stable.
+func (q *Query) compare(a, b Entry) int {
+	var c int
+	switch q.sortBy {
+	case SortBySize:
+		c = cmp.Compare(a.Size, b.Size)
+	case SortByMtime:
+		c = a.ModTime.Compare(b.ModTime)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.Name, b.Name)
+	}
+	if q.desc {
+		return -c
+	}
+	return c
+}
@@ -0,0 +1,89 @@
+package listing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+func names(entries []Entry) string {
+	var s []string
+	for _, e := range entries {
+		s = append(s, e.Name)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestPageByMtime(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Name: "a", ModTime: now.Add(-3 * time.Hour)},
+		{Name: "b", ModTime: now.Add(-time.Hour)},
+		{Name: "c", ModTime: now.Add(-time.Hour)},
+		{Name: "d", ModTime: now.Add(-2 * time.Hour)},
+		{Name: "e", ModTime: now.Add(-48 * time.Hour)},
+	}
+	self := func(e Entry) (Entry, bool) { return e, true }
+	req := &api.ListRequest{SortBy: "mtime", Order: "desc", ModifiedSince: timestamppb.New(now.Add(-24 * time.Hour)), PageSize: 2}
+	var pages []string
+	for {
+		q, err := NewQuery(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, next := Page(q, entries, self)
+		pages = append(pages, names(page))
+		if next == "" {
+			break
+		}
+		req.PageToken = next
+	}
+	// Equal times are ordered by name, reversed with the rest.
+	if strings.Join(pages, ",") != "c b,d a" {
+		t.Errorf("unexpected pages %q", pages)
+	}
+
+	req.SortBy = "size"
+	if _, err := NewQuery(req); err == nil {
+		t.Error("expected a token for another sort order to be rejected")
+	}
+}
+
+func TestNewQueryInvalid(t *testing.T) {
+	for _, req := range []*api.ListRequest{
+		{SortBy: "owner"},
+		{Order: "up"},
+		{NameGlob: "[a"},
+		{MinSize: -1},
+		{PageSize: MaxPageSize + 1},
+		{PageToken: "bm90IGpzb24"},
+	} {
+		if _, err := NewQuery(req); err == nil {
+			t.Errorf("%+v: expected an error", req)
+		}
+	}
+}
+
+func TestNeedsInfo(t *testing.T) {
+	for _, tt := range []struct {
+		req  *api.ListRequest
+		want bool
+	}{
+		{&api.ListRequest{NameGlob: "*.log", Order: "desc"}, false},
+		{&api.ListRequest{SortBy: "size"}, true},
+		{&api.ListRequest{MinSize: 1}, true},
+		{&api.ListRequest{ModifiedSince: timestamppb.Now()}, true},
+	} {
+		q, err := NewQuery(tt.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.NeedsInfo() != tt.want {
+			t.Errorf("%+v: NeedsInfo() = %v", tt.req, !tt.want)
+		}
+	}
+}
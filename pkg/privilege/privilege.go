@@ -0,0 +1,133 @@
+// Package privilege describes how much the Pulsaar agent may read on Linux
+// and the container settings that grant it. The agent image runs as an
+// unprivileged user; a second copy of the binary carries
+// CAP_DAC_READ_SEARCH as a file capability for agents that must read files
+// regardless of their permissions, such as node logs.
+package privilege
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Privilege levels. The agent is configured with one of NonRoot,
+// DACReadSearch and Root and refuses to serve when it runs with another.
+// Elevated is only reported, for an agent holding capabilities beyond
+// CAP_DAC_READ_SEARCH without running as root.
+const (
+	NonRoot       = "non-root"
+	DACReadSearch = "dac-read-search"
+	Root          = "root"
+	Elevated      = "elevated"
+)
+
+// Env is the agent's environment variable naming its privilege level.
+const Env = "PULSAAR_PRIVILEGE"
+
+// UID and GID are the unprivileged user and group of the agent image.
+const (
+	UID = 65532
+	GID = 65532
+)
+
+// DACReadSearchBinary is the agent binary that holds CAP_DAC_READ_SEARCH
+// as a file capability. The container must also be allowed the capability
+// and privilege escalation, since no_new_privs would drop it on exec.
+const DACReadSearchBinary = "/usr/local/bin/agent-dac-read-search"
+
+// Validate checks that level is one the agent can be configured with.
+func Validate(level string) error {
+	switch level {
+	case NonRoot, DACReadSearch, Root:
+		return nil
+	}
+	return fmt.Errorf("invalid agent privilege %q; use %s, %s or %s", level, NonRoot, DACReadSearch, Root)
+}
+
+// SecurityContext returns the Linux container security context that runs
+// the agent at level.
+func SecurityContext(level string) *corev1.SecurityContext {
+	switch level {
+	case Root:
+		return &corev1.SecurityContext{RunAsUser: ptr(int64(0)), RunAsNonRoot: ptr(false)}
+	case DACReadSearch:
+		return &corev1.SecurityContext{
+			RunAsUser:    ptr(int64(UID)),
+			RunAsGroup:   ptr(int64(GID)),
+			RunAsNonRoot: ptr(true),
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+				Add:  []corev1.Capability{"DAC_READ_SEARCH"},
+			},
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}
+	}
+	return &corev1.SecurityContext{
+		RunAsUser:                ptr(int64(UID)),
+		RunAsGroup:               ptr(int64(GID)),
+		RunAsNonRoot:             ptr(true),
+		AllowPrivilegeEscalation: ptr(false),
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// Command returns the container command that runs the agent at level, or
+// nil for the image's default.
+func Command(level string) []string {
+	if level == DACReadSearch {
+		return []string{DACReadSearchBinary}
+	}
+	return nil
+}
+
+// Of names the privilege of a process with effective user ID uid and
+// effective capability set capEff, as in /proc/self/status.
+func Of(uid int, capEff uint64) string {
+	const capDACReadSearch = 1 << 2
+	switch {
+	case uid == 0:
+		return Root
+	case capEff&^capDACReadSearch != 0:
+		return Elevated
+	case capEff&capDACReadSearch != 0:
+		return DACReadSearch
+	}
+	return NonRoot
+}
+
+// capabilityNames are the Linux capabilities by bit number.
+var capabilityNames = []string{
+	"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_DAC_READ_SEARCH", "CAP_FOWNER",
+	"CAP_FSETID", "CAP_KILL", "CAP_SETGID", "CAP_SETUID",
+	"CAP_SETPCAP", "CAP_LINUX_IMMUTABLE", "CAP_NET_BIND_SERVICE", "CAP_NET_BROADCAST",
+	"CAP_NET_ADMIN", "CAP_NET_RAW", "CAP_IPC_LOCK", "CAP_IPC_OWNER",
+	"CAP_SYS_MODULE", "CAP_SYS_RAWIO", "CAP_SYS_CHROOT", "CAP_SYS_PTRACE",
+	"CAP_SYS_PACCT", "CAP_SYS_ADMIN", "CAP_SYS_BOOT", "CAP_SYS_NICE",
+	"CAP_SYS_RESOURCE", "CAP_SYS_TIME", "CAP_SYS_TTY_CONFIG", "CAP_MKNOD",
+	"CAP_LEASE", "CAP_AUDIT_WRITE", "CAP_AUDIT_CONTROL", "CAP_SETFCAP",
+	"CAP_MAC_OVERRIDE", "CAP_MAC_ADMIN", "CAP_SYSLOG", "CAP_WAKE_ALARM",
+	"CAP_BLOCK_SUSPEND", "CAP_AUDIT_READ", "CAP_PERFMON", "CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE",
+}
+
+// CapabilityNames names the capabilities in the set caps, as in
+// /proc/self/status, in bit order. Bits newer than this list are named by
+// number.
+func CapabilityNames(caps uint64) []string {
+	var names []string
+	for bit := 0; bit < 64; bit++ {
+		if caps&(1<<bit) == 0 {
+			continue
+		}
+		if bit < len(capabilityNames) {
+			names = append(names, capabilityNames[bit])
+		} else {
+			names = append(names, fmt.Sprintf("CAP_%d", bit))
+		}
+	}
+	return names
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,61 @@
+package privilege
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	for _, c := range []struct {
+		uid    int
+		capEff uint64
+		want   string
+	}{
+		{0, 0x1ffffffffff, Root},
+		{0, 0, Root},
+		{UID, 0, NonRoot},
+		{UID, 1 << 2, DACReadSearch},
+		{UID, 1<<2 | 1<<1, Elevated},
+		{1000, 1 << 21, Elevated},
+	} {
+		if got := Of(c.uid, c.capEff); got != c.want {
+			t.Errorf("Of(%d, %#x) = %s, want %s", c.uid, c.capEff, got, c.want)
+		}
+	}
+}
+
+func TestCapabilityNames(t *testing.T) {
+	if got := fmt.Sprint(CapabilityNames(1<<2 | 1<<21 | 1<<40 | 1<<45)); got != "[CAP_DAC_READ_SEARCH CAP_SYS_ADMIN CAP_CHECKPOINT_RESTORE CAP_45]" {
+		t.Errorf("unexpected names %s", got)
+	}
+	if CapabilityNames(0) != nil {
+		t.Error("expected no names for an empty set")
+	}
+}
+
+func TestSecurityContext(t *testing.T) {
+	sc := SecurityContext(NonRoot)
+	if *sc.RunAsUser != UID || !*sc.RunAsNonRoot || *sc.AllowPrivilegeEscalation || len(sc.Capabilities.Add) != 0 || sc.Capabilities.Drop[0] != "ALL" {
+		t.Errorf("unexpected non-root context %+v", sc)
+	}
+	if Command(NonRoot) != nil {
+		t.Error("expected the image's command for non-root")
+	}
+
+	// File capabilities are lost under no_new_privs, so escalation must
+	// stay allowed for the capability to reach the agent.
+	sc = SecurityContext(DACReadSearch)
+	if sc.AllowPrivilegeEscalation != nil || len(sc.Capabilities.Add) != 1 || sc.Capabilities.Add[0] != "DAC_READ_SEARCH" {
+		t.Errorf("unexpected dac-read-search context %+v", sc)
+	}
+	if cmd := Command(DACReadSearch); len(cmd) != 1 || cmd[0] != DACReadSearchBinary {
+		t.Errorf("expected the capable binary, got %v", cmd)
+	}
+
+	if sc := SecurityContext(Root); *sc.RunAsUser != 0 || *sc.RunAsNonRoot {
+		t.Errorf("unexpected root context %+v", sc)
+	}
+	if err := Validate(Elevated); err == nil {
+		t.Error("expected elevated to be rejected as a configured level")
+	}
+}
@@ -0,0 +1,710 @@
+// Package testing provides an in-memory Pulsaar agent for tests. It serves
+// the PulsaarAgent API from an fs.FS over an in-process listener, so code
+// built on pkg/client or the generated API client can be exercised without
+// TLS, Kubernetes or a running pod.
+//
+//	agent := testing.NewAgent(fstest.MapFS{"app/config.yaml": {Data: []byte("debug: true")}})
+//	srv := testing.Serve(agent)
+//	defer srv.Close()
+//	conn, err := srv.Dial()
+//	c := client.New(conn)
+package testing
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/listing"
+)
+
+// Limits enforced by the real agent.
+const (
+	MaxReadSize          int64 = 1024 * 1024
+	MaxReadRanges              = 64
+	DefaultChunkSize     int64 = 64 * 1024
+	DefaultSearchMatches       = 100
+	MaxSearchMatches           = 1000
+	MaxListPageSize            = listing.MaxPageSize
+	DefaultFindResults         = 1000
+	MaxFindResults             = 10000
+	ListStreamBatch            = 500
+)
+
+// Request records one call made to an Agent.
+type Request struct {
+	Operation    string
+	Path         string
+	AllowedRoots []string
+}
+
+// Agent implements api.PulsaarAgentServer on top of an fs.FS. Agent paths
+// are absolute and resolved against the root of the file system, as they
+// would be inside a container. Access checks, read limits and error codes
+// match the real agent.
+type Agent struct {
+	api.UnimplementedPulsaarAgentServer
+
+	fsys fs.FS
+	// AllowedRoots applies when a request carries none, like the agent's
+	// configured roots. Defaults to "/".
+	AllowedRoots []string
+	// Version is reported by Health. Defaults to "test".
+	Version string
+	// Supported lists the RPCs and features reported by Health and
+	// Capabilities. Defaults to everything; set it to an empty slice to act
+	// like an agent that predates capability reporting. Capabilities
+	// answers UNIMPLEMENTED unless "Capabilities" is listed, so clients
+	// fall back to Health.
+	Supported []string
+	// TailInterval is how often a following TailFile rereads the file.
+	// Defaults to 10ms.
+	TailInterval time.Duration
+
+	mu       sync.Mutex
+	requests []Request
+	shutdown bool
+}
+
+// NewAgent returns an agent serving fsys, e.g. an fstest.MapFS.
+func NewAgent(fsys fs.FS) *Agent {
+	return &Agent{fsys: fsys, AllowedRoots: []string{"/"}, Version: "test", Supported: api.Capabilities()}
+}
+
+// NewDirAgent returns an agent whose "/" is dir. Files changed under dir are
+// visible to later requests, which suits tail-style tests.
+func NewDirAgent(dir string) *Agent {
+	return NewAgent(os.DirFS(dir))
+}
+
+// Requests returns the calls received so far, oldest first.
+func (a *Agent) Requests() []Request {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Request(nil), a.requests...)
+}
+
+// ShutdownRequested reports whether Shutdown has been called.
+func (a *Agent) ShutdownRequested() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.shutdown
+}
+
+// check records the request and applies the allowed-roots check.
+func (a *Agent) check(operation, p string, roots []string) (string, error) {
+	a.mu.Lock()
+	a.requests = append(a.requests, Request{Operation: operation, Path: p, AllowedRoots: roots})
+	a.mu.Unlock()
+
+	if len(roots) == 0 {
+		roots = a.AllowedRoots
+	}
+	if !isPathAllowed(p, roots) {
+		return "", status.Errorf(codes.PermissionDenied, "Access to path '%s' is not allowed. Allowed roots: %v", p, roots)
+	}
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		name = "."
+	}
+	return name, nil
+}
+
+// fileErrorCode matches the agent's codes for a failed open or stat.
+func fileErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return codes.NotFound
+	case errors.Is(err, fs.ErrPermission):
+		return codes.PermissionDenied
+	}
+	return codes.Internal
+}
+
+// isPathAllowed matches the agent's check.
+func isPathAllowed(p string, allowedRoots []string) bool {
+	cleanPath := path.Clean(p)
+	for _, root := range allowedRoots {
+		cleanRoot := path.Clean(root)
+		if cleanRoot == "/" || cleanPath == cleanRoot || strings.HasPrefix(cleanPath, cleanRoot+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func fileInfo(name string, info fs.FileInfo) *api.FileInfo {
+	return &api.FileInfo{
+		Name:      name,
+		IsDir:     info.IsDir(),
+		SizeBytes: info.Size(),
+		Mode:      info.Mode().String(),
+		Mtime:     timestamppb.New(info.ModTime()),
+		FileType:  api.FileTypeOf(info.Mode()),
+	}
+}
+
+// linkInfo sets the target of fi, the file at name, if it is a symlink.
+func (a *Agent) linkInfo(name string, fi *api.FileInfo) *api.FileInfo {
+	if fi.FileType == api.FileType_FILE_TYPE_SYMLINK {
+		fi.LinkTarget, _ = fs.ReadLink(a.fsys, name)
+	}
+	return fi
+}
+
+func (a *Agent) ListDirectory(ctx context.Context, req *api.ListRequest) (*api.ListResponse, error) {
+	name, err := a.check("ListDirectory", req.Path, req.AllowedRoots)
+	if err != nil {
+		return nil, err
+	}
+	q, err := listing.NewQuery(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid list request: %v", err)
+	}
+	infos, err := a.readDir(req.Path, name)
+	if err != nil {
+		return nil, err
+	}
+	infos, next := listing.Page(q, infos, listEntry)
+	return &api.ListResponse{Entries: infos, NextPageToken: next}, nil
+}
+
+func (a *Agent) readDir(reqPath, name string) ([]*api.FileInfo, error) {
+	entries, err := fs.ReadDir(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Unable to list contents of directory '%s': %v", reqPath, err)
+	}
+	var infos []*api.FileInfo
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			infos = append(infos, a.linkInfo(path.Join(name, entry.Name()), fileInfo(entry.Name(), info)))
+		}
+	}
+	return infos, nil
+}
+
+func listEntry(info *api.FileInfo) (listing.Entry, bool) {
+	return listing.Entry{Name: info.Name, Size: info.SizeBytes, ModTime: info.Mtime.AsTime()}, true
+}
+
+// ListDirectoryStream sends the entries of a directory in batches of
+// ListStreamBatch, applying the request's filters. Unlike the real agent,
+// entries are sorted by name.
+func (a *Agent) ListDirectoryStream(req *api.ListRequest, stream api.PulsaarAgent_ListDirectoryStreamServer) error {
+	name, err := a.check("ListDirectoryStream", req.Path, req.AllowedRoots)
+	if err != nil {
+		return err
+	}
+	q, err := listing.NewQuery(&api.ListRequest{NameGlob: req.NameGlob, MinSize: req.MinSize, ModifiedSince: req.ModifiedSince})
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid list request: %v", err)
+	}
+	infos, err := a.readDir(req.Path, name)
+	if err != nil {
+		return err
+	}
+	infos, _ = listing.Page(q, infos, listEntry)
+	for batch := range slices.Chunk(infos, ListStreamBatch) {
+		if err := stream.Send(&api.ListResponse{Entries: batch}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Agent) Stat(ctx context.Context, req *api.StatRequest) (*api.StatResponse, error) {
+	name, err := a.check("Stat", req.Path, req.AllowedRoots)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.Stat(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+	return &api.StatResponse{Info: fileInfo(path.Base(req.Path), info)}, nil
+}
+
+func (a *Agent) ReadFile(ctx context.Context, req *api.ReadRequest) (*api.ReadResponse, error) {
+	name, err := a.check("ReadFile", req.Path, req.AllowedRoots)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Ranges) > 0 {
+		return a.readRanges(req, name)
+	}
+	readLen := req.Length
+	if readLen == 0 {
+		readLen = MaxReadSize
+	}
+	if readLen > MaxReadSize {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested read length (%d bytes) exceeds the maximum allowed size of %d bytes", readLen, MaxReadSize)
+	}
+	data, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to open file '%s' for reading: %v", req.Path, err)
+	}
+	if req.Decompress {
+		if data, err = decompress(data); err != nil {
+			return nil, status.Errorf(codes.Internal, "Unable to decompress file '%s': %v", req.Path, err)
+		}
+	}
+	if req.Offset < 0 {
+		return nil, status.Errorf(codes.Internal, "Unable to read file '%s': negative offset", req.Path)
+	}
+	data = data[min(req.Offset, int64(len(data))):]
+	if int64(len(data)) > readLen {
+		return &api.ReadResponse{Data: data[:readLen]}, nil
+	}
+	return &api.ReadResponse{Data: data, Eof: true}, nil
+}
+
+// readRanges serves a ReadRequest with ranges, with the real agent's
+// limits.
+func (a *Agent) readRanges(req *api.ReadRequest, name string) (*api.ReadResponse, error) {
+	if len(req.Ranges) > MaxReadRanges {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested %d ranges; at most %d are allowed", len(req.Ranges), MaxReadRanges)
+	}
+	if req.Decompress {
+		return nil, status.Errorf(codes.InvalidArgument, "Ranges cannot be combined with decompress")
+	}
+	var total int64
+	for _, rg := range req.Ranges {
+		if rg.Length <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Each range needs a positive length")
+		}
+		total += rg.Length
+	}
+	if total > MaxReadSize {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested ranges total %d bytes, exceeding the maximum allowed size of %d bytes", total, MaxReadSize)
+	}
+	data, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to open file '%s' for reading: %v", req.Path, err)
+	}
+	size := int64(len(data))
+	resp := &api.ReadResponse{}
+	for _, rg := range req.Ranges {
+		offset := rg.Offset
+		if offset < 0 {
+			offset = max(0, size+offset)
+		}
+		start := min(offset, size)
+		end := min(start+rg.Length, size)
+		resp.Parts = append(resp.Parts, &api.ReadPart{Offset: offset, Data: data[start:end], Eof: end-start < rg.Length})
+	}
+	return resp, nil
+}
+
+func (a *Agent) StreamFile(req *api.StreamRequest, stream api.PulsaarAgent_StreamFileServer) error {
+	name, err := a.check("StreamFile", req.Path, req.AllowedRoots)
+	if err != nil {
+		return err
+	}
+	chunkSize := req.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > MaxReadSize {
+		return status.Errorf(codes.InvalidArgument, "Requested chunk size (%d bytes) exceeds the maximum allowed size of %d bytes", chunkSize, MaxReadSize)
+	}
+	f, err := a.fsys.Open(name)
+	if err != nil {
+		return status.Errorf(fileErrorCode(err), "Unable to open file '%s' for streaming: %v", req.Path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if req.Decompress {
+		data, err := io.ReadAll(f)
+		if err == nil {
+			data, err = decompress(data)
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "Unable to decompress file '%s': %v", req.Path, err)
+		}
+		r = bytes.NewReader(data)
+	}
+	if req.Jq != "" {
+		return streamFiltered(req, r, stream)
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := r.Read(buf)
+		if err != nil && err != io.EOF {
+			return status.Errorf(codes.Internal, "Unable to read file '%s' during streaming: %v", req.Path, err)
+		}
+		if n == 0 {
+			return nil
+		}
+		eof := err == io.EOF
+		if err := stream.Send(&api.ReadResponse{Data: buf[:n], Eof: eof}); err != nil {
+			return err
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+
+// Preview returns metadata with the first and last bytes of a file,
+// defaulting to a 4KB head.
+func (a *Agent) Preview(ctx context.Context, req *api.PreviewRequest) (*api.PreviewResponse, error) {
+	name, err := a.check("Preview", req.Path, req.AllowedRoots)
+	if err != nil {
+		return nil, err
+	}
+	headLen, tailLen := req.HeadBytes, req.TailBytes
+	if headLen < 0 || tailLen < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Preview sizes must not be negative")
+	}
+	if headLen > MaxReadSize || tailLen > MaxReadSize {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested preview size exceeds the maximum allowed size of %d bytes", MaxReadSize)
+	}
+	if headLen == 0 && tailLen == 0 {
+		headLen = 4 * 1024
+	}
+	info, err := fs.Stat(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+	resp := &api.PreviewResponse{Info: fileInfo(path.Base(req.Path), info)}
+	if !info.Mode().IsRegular() {
+		return resp, nil
+	}
+	data, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to open file '%s' for reading: %v", req.Path, err)
+	}
+	size := int64(len(data))
+	resp.Head = data[:min(headLen, size)]
+	if start := max(int64(len(resp.Head)), size-tailLen); tailLen > 0 && start < size {
+		resp.Tail = data[start:]
+	}
+	return resp, nil
+}
+
+// Checksum returns the SHA-256 of a file, or of its first Length bytes.
+func (a *Agent) Checksum(ctx context.Context, req *api.ChecksumRequest) (*api.ChecksumResponse, error) {
+	name, err := a.check("Checksum", req.Path, req.AllowedRoots)
+	if err != nil {
+		return nil, err
+	}
+	if req.Length < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Checksum length must not be negative")
+	}
+	info, err := fs.Stat(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, status.Errorf(codes.FailedPrecondition, "'%s' is not a regular file", req.Path)
+	}
+	data, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to open file '%s' for reading: %v", req.Path, err)
+	}
+	if req.Length > 0 {
+		data = data[:min(req.Length, int64(len(data)))]
+	}
+	sum := sha256.Sum256(data)
+	return &api.ChecksumResponse{Algorithm: "sha256", Digest: hex.EncodeToString(sum[:]), SizeBytes: int64(len(data))}, nil
+}
+
+// TailFile streams matching whole lines from the files. Unlike the real
+// agent, Since only recognises RFC 3339 timestamps at the start of a line,
+// lines without one follow the decision for the line before, and globs are
+// only matched when the call starts.
+func (a *Agent) TailFile(req *api.TailRequest, stream api.PulsaarAgent_TailFileServer) error {
+	patterns := req.Paths
+	if req.Path != "" {
+		patterns = append([]string{req.Path}, patterns...)
+	}
+	if len(patterns) == 0 {
+		return status.Errorf(codes.InvalidArgument, "No path to tail")
+	}
+	tagged := len(patterns) > 1
+	var tails []*fakeTail
+	for _, p := range patterns {
+		name, err := a.check("TailFile", p, req.AllowedRoots)
+		if err != nil {
+			return err
+		}
+		names := []string{name}
+		if strings.ContainsAny(name, "*?[") {
+			tagged = true
+			if names, err = fs.Glob(a.fsys, name); err != nil {
+				return status.Errorf(codes.InvalidArgument, "Invalid glob pattern '%s': %v", p, err)
+			}
+		}
+		for _, name := range names {
+			tails = append(tails, &fakeTail{name: name, path: "/" + name})
+		}
+	}
+	if len(tails) == 0 {
+		return status.Errorf(codes.NotFound, "No files match %s", strings.Join(patterns, ", "))
+	}
+	if req.SinceSeconds < 0 {
+		return status.Errorf(codes.InvalidArgument, "since_seconds must not be negative")
+	}
+	var re *regexp.Regexp
+	if req.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(req.Pattern); err != nil {
+			return status.Errorf(codes.InvalidArgument, "Invalid tail pattern: %v", err)
+		}
+	}
+	for _, t := range tails {
+		if !tagged {
+			t.path = ""
+		}
+		data, err := fs.ReadFile(a.fsys, t.name)
+		if err != nil {
+			return status.Errorf(fileErrorCode(err), "Unable to open file '%s' for tailing: %v", "/"+t.name, err)
+		}
+		t.data = data
+		t.offset = int64(len(data))
+		if req.FromStart || req.SinceSeconds > 0 {
+			t.offset = 0
+		}
+		t.inWindow = req.SinceSeconds == 0
+		if off, ok := req.ResumeOffsets["/"+t.name]; ok {
+			t.offset, t.inWindow = off, true
+		}
+	}
+	since := time.Now().Add(-time.Duration(req.SinceSeconds) * time.Second)
+	interval := a.TailInterval
+	if interval <= 0 {
+		interval = 10 * time.Millisecond
+	}
+	for {
+		for _, t := range tails {
+			if out := t.lines(re, since, req.Follow); len(out) > 0 {
+				if err := stream.Send(&api.ReadResponse{Data: out, Path: t.path, Offset: t.offset}); err != nil {
+					return err
+				}
+			}
+		}
+		if !req.Follow {
+			return nil
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-time.After(interval):
+		}
+		for _, t := range tails {
+			data, err := fs.ReadFile(a.fsys, t.name)
+			if err != nil {
+				return status.Errorf(codes.Internal, "Unable to read file '%s' during tailing: %v", "/"+t.name, err)
+			}
+			t.data = data
+		}
+	}
+}
+
+// fakeTail is one file followed by TailFile. path is set on messages when
+// several files are followed.
+type fakeTail struct {
+	name     string
+	path     string
+	data     []byte
+	offset   int64
+	inWindow bool
+}
+
+// lines returns the matching whole lines added since the last call, and a
+// trailing partial line unless following.
+func (t *fakeTail) lines(re *regexp.Regexp, since time.Time, follow bool) []byte {
+	if t.offset > int64(len(t.data)) {
+		t.offset = 0
+	}
+	var out []byte
+	rest := t.data[t.offset:]
+	for len(rest) > 0 {
+		end := bytes.IndexByte(rest, '\n')
+		if end < 0 {
+			if follow {
+				break
+			}
+			end = len(rest) - 1
+		}
+		line := rest[:end+1]
+		rest = rest[end+1:]
+		t.offset += int64(len(line))
+		if !t.inWindow {
+			if i := bytes.IndexByte(line, ' '); i > 0 {
+				if ts, err := time.Parse(time.RFC3339, string(line[:i])); err == nil {
+					t.inWindow = !ts.Before(since)
+				}
+			}
+		}
+		if t.inWindow && (re == nil || re.Match(bytes.TrimSuffix(line, []byte("\n")))) {
+			out = append(out, line...)
+		}
+	}
+	return out
+}
+
+// Search matches lines of regular, non-binary files under the path. Unlike
+// the real agent it has no scan-size limits.
+func (a *Agent) Search(ctx context.Context, req *api.SearchRequest) (*api.SearchResponse, error) {
+	name, err := a.check("Search", req.Path, req.AllowedRoots)
+	if err != nil {
+		return nil, err
+	}
+	if req.Pattern == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "A search pattern is required.")
+	}
+	re, err := regexp.Compile(req.Pattern)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid search pattern: %v", err)
+	}
+	maxMatches := int(req.MaxMatches)
+	if maxMatches <= 0 {
+		maxMatches = DefaultSearchMatches
+	}
+	if maxMatches > MaxSearchMatches {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested max matches (%d) exceeds the maximum of %d", maxMatches, MaxSearchMatches)
+	}
+	if _, err := fs.Stat(a.fsys, name); err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+
+	resp := &api.SearchResponse{}
+	err = fs.WalkDir(a.fsys, name, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := fs.ReadFile(a.fsys, p)
+		if err != nil || bytes.IndexByte(data[:min(len(data), 512)], 0) >= 0 {
+			return nil
+		}
+		resp.FilesSearched++
+		for i, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if len(resp.Matches) >= maxMatches {
+				resp.Truncated = true
+				return fs.SkipAll
+			}
+			resp.Matches = append(resp.Matches, &api.SearchMatch{Path: "/" + p, Line: int64(i + 1), Text: line})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Unable to search '%s': %v", req.Path, err)
+	}
+	return resp, nil
+}
+
+// Find walks the tree under the path and returns the entries that pass the
+// filters. Unlike the real agent it has no limit on entries scanned.
+func (a *Agent) Find(ctx context.Context, req *api.FindRequest) (*api.FindResponse, error) {
+	name, err := a.check("Find", req.Path, req.AllowedRoots)
+	if err != nil {
+		return nil, err
+	}
+	q, err := listing.NewQuery(&api.ListRequest{NameGlob: req.NameGlob, MinSize: req.MinSize, ModifiedSince: req.ModifiedSince})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid find request: %v", err)
+	}
+	maxResults := int(req.MaxResults)
+	if maxResults <= 0 {
+		maxResults = DefaultFindResults
+	}
+	if maxResults > MaxFindResults {
+		return nil, status.Errorf(codes.InvalidArgument, "Requested max results (%d) exceeds the maximum of %d", maxResults, MaxFindResults)
+	}
+	info, err := fs.Stat(a.fsys, name)
+	if err != nil {
+		return nil, status.Errorf(fileErrorCode(err), "Unable to get information for path '%s': %v", req.Path, err)
+	}
+	if !info.IsDir() {
+		return nil, status.Errorf(codes.InvalidArgument, "'%s' is not a directory", req.Path)
+	}
+
+	resp := &api.FindResponse{}
+	err = fs.WalkDir(a.fsys, name, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == name {
+			return nil
+		}
+		resp.EntriesScanned++
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fi := a.linkInfo(p, fileInfo(d.Name(), info))
+		if e, _ := listEntry(fi); !q.Match(e) {
+			return nil
+		}
+		if req.FileType != api.FileType_FILE_TYPE_UNSPECIFIED && fi.FileType != req.FileType {
+			return nil
+		}
+		if len(resp.Matches) >= maxResults {
+			resp.Truncated = true
+			return fs.SkipAll
+		}
+		resp.Matches = append(resp.Matches, &api.FindMatch{Path: "/" + p, Info: fi})
+		return nil
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Unable to search '%s': %v", req.Path, err)
+	}
+	return resp, nil
+}
+
+func (a *Agent) Health(ctx context.Context, req *emptypb.Empty) (*api.HealthResponse, error) {
+	//nolint:staticcheck // for clients that predate the Capabilities RPC
+	return &api.HealthResponse{Ready: true, Version: a.Version, StatusMessage: "Agent ready", Capabilities: a.Supported}, nil
+}
+
+// Capabilities reports Supported split into RPCs and features, with the
+// real agent's limits and codecs.
+func (a *Agent) Capabilities(ctx context.Context, req *emptypb.Empty) (*api.CapabilitiesResponse, error) {
+	if !slices.Contains(a.Supported, "Capabilities") {
+		return nil, status.Errorf(codes.Unimplemented, "method Capabilities not implemented")
+	}
+	resp := &api.CapabilitiesResponse{
+		Version:           a.Version,
+		CompressionCodecs: []string{"gzip", "zstd", "bzip2"},
+		MaxReadSize:       MaxReadSize,
+		AuthModes:         []string{api.AuthModeTLS},
+	}
+	for _, name := range a.Supported {
+		if slices.Contains(api.Features(), name) {
+			resp.Features = append(resp.Features, name)
+		} else {
+			resp.Rpcs = append(resp.Rpcs, name)
+		}
+	}
+	return resp, nil
+}
+
+// Shutdown records the request; the agent keeps serving.
+func (a *Agent) Shutdown(ctx context.Context, req *api.ShutdownRequest) (*api.ShutdownResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests = append(a.requests, Request{Operation: "Shutdown"})
+	accepted := !a.shutdown
+	a.shutdown = true
+	return &api.ShutdownResponse{Accepted: accepted}, nil
+}
@@ -0,0 +1,193 @@
+package testing_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	pulsaartesting "github.com/VrushankPatel/pulsaar/pkg/testing"
+)
+
+func newAgentClient(t *testing.T, agent *pulsaartesting.Agent) api.PulsaarAgentClient {
+	t.Helper()
+	srv := pulsaartesting.Serve(agent)
+	t.Cleanup(srv.Close)
+	conn, err := srv.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return api.NewPulsaarAgentClient(conn)
+}
+
+func TestAgentServesMapFS(t *testing.T) {
+	agent := pulsaartesting.NewAgent(fstest.MapFS{
+		"app/config.yaml": {Data: []byte("debug: true\n")},
+		"app/data.bin":    {Data: make([]byte, 100)},
+		"etc/passwd":      {Data: []byte("root:x:0:0")},
+	})
+	agent.AllowedRoots = []string{"/app"}
+	c := newAgentClient(t, agent)
+	ctx := context.Background()
+
+	list, err := c.ListDirectory(ctx, &api.ListRequest{Path: "/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(list.Entries))
+	}
+
+	stat, err := c.Stat(ctx, &api.StatRequest{Path: "/app/config.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Info.Name != "config.yaml" || stat.Info.SizeBytes != 12 {
+		t.Errorf("unexpected stat: %v", stat.Info)
+	}
+
+	read, err := c.ReadFile(ctx, &api.ReadRequest{Path: "/app/config.yaml", Offset: 7, Length: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(read.Data) != "true" || read.Eof {
+		t.Errorf("unexpected read: %q eof=%t", read.Data, read.Eof)
+	}
+
+	stream, err := c.StreamFile(ctx, &api.StreamRequest{Path: "/app/data.bin", ChunkSize: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(resp.Data)
+	}
+	if total != 100 {
+		t.Errorf("streamed %d bytes, want 100", total)
+	}
+
+	preview, err := c.Preview(ctx, &api.PreviewRequest{Path: "/app/config.yaml", HeadBytes: 5, TailBytes: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(preview.Head) != "debug" || string(preview.Tail) != "true\n" {
+		t.Errorf("unexpected preview: %q %q", preview.Head, preview.Tail)
+	}
+
+	if got := len(agent.Requests()); got != 5 {
+		t.Errorf("expected 5 recorded requests, got %d", got)
+	}
+}
+
+func TestAgentEnforcesAgentRules(t *testing.T) {
+	agent := pulsaartesting.NewAgent(fstest.MapFS{"app/a": {Data: []byte("a")}})
+	c := newAgentClient(t, agent)
+	ctx := context.Background()
+
+	_, err := c.ReadFile(ctx, &api.ReadRequest{Path: "/etc/shadow", AllowedRoots: []string{"/app"}})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied, got %v", err)
+	}
+	_, err = c.ReadFile(ctx, &api.ReadRequest{Path: "/app/a", Length: pulsaartesting.MaxReadSize + 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+	_, err = c.Stat(ctx, &api.StatRequest{Path: "/app/missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for a missing file, got %v", err)
+	}
+}
+
+func TestAgentHealthAndShutdown(t *testing.T) {
+	agent := pulsaartesting.NewAgent(fstest.MapFS{})
+	c := newAgentClient(t, agent)
+	ctx := context.Background()
+
+	health, err := c.Health(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !health.Ready || health.Version != "test" {
+		t.Errorf("unexpected health: %v", health)
+	}
+
+	resp, err := c.Shutdown(ctx, &api.ShutdownRequest{Reason: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Accepted || !agent.ShutdownRequested() {
+		t.Error("expected shutdown to be recorded")
+	}
+}
+
+func TestAgentSearch(t *testing.T) {
+	agent := pulsaartesting.NewAgent(fstest.MapFS{
+		"var/log/app.log":   {Data: []byte("start\nOOMKilled\n")},
+		"var/log/old.log":   {Data: []byte("OOMKilled again\n")},
+		"var/log/blob.bin":  {Data: []byte("OOMKilled\x00")},
+		"etc/secret.conf":   {Data: []byte("OOMKilled\n")},
+		"var/log/empty.log": {Data: nil},
+	})
+	c := newAgentClient(t, agent)
+	ctx := context.Background()
+
+	resp, err := c.Search(ctx, &api.SearchRequest{Path: "/var/log", Pattern: "OOMKilled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Matches) != 2 || resp.Matches[0].Path != "/var/log/app.log" || resp.Matches[0].Line != 2 {
+		t.Errorf("unexpected matches: %v", resp.Matches)
+	}
+
+	resp, err = c.Search(ctx, &api.SearchRequest{Path: "/var/log", Pattern: "OOMKilled", MaxMatches: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Matches) != 1 || !resp.Truncated {
+		t.Errorf("expected a truncated single match, got %v", resp)
+	}
+
+	_, err = c.Search(ctx, &api.SearchRequest{Path: "/etc", Pattern: "x", AllowedRoots: []string{"/var/log"}})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestAgentTailFile(t *testing.T) {
+	now := time.Now()
+	log := fmt.Sprintf("%s INFO old\n%s ERROR old\n%s ERROR recent\n  at main.go:10\n%s INFO recent\n",
+		now.Add(-time.Hour).Format(time.RFC3339), now.Add(-time.Hour).Format(time.RFC3339),
+		now.Add(-time.Minute).Format(time.RFC3339), now.Format(time.RFC3339))
+	c := newAgentClient(t, pulsaartesting.NewAgent(fstest.MapFS{"app.log": {Data: []byte(log)}}))
+
+	stream, err := c.TailFile(context.Background(), &api.TailRequest{Path: "/app.log", SinceSeconds: 600, Pattern: "ERROR|at "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(resp.Data); !strings.HasSuffix(got, " ERROR recent\n  at main.go:10\n") || strings.Count(got, "\n") != 2 {
+		t.Errorf("unexpected tail output %q", got)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("expected EOF without --follow, got %v", err)
+	}
+}
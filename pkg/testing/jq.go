@@ -0,0 +1,32 @@
+package testing
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+	"github.com/VrushankPatel/pulsaar/pkg/jq"
+)
+
+// streamFiltered sends the output of req.Jq for each JSON line of r in one
+// message, skipping lines that are not JSON, as the agent does.
+func streamFiltered(req *api.StreamRequest, r io.Reader, stream api.PulsaarAgent_StreamFileServer) error {
+	filter, err := jq.Parse(req.Jq)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), int(MaxReadSize))
+	var buf bytes.Buffer
+	for scanner.Scan() {
+		_ = filter.Apply(scanner.Bytes(), &buf)
+	}
+	if err := scanner.Err(); err != nil {
+		return status.Errorf(codes.Internal, "Unable to read file '%s' during streaming: %v", req.Path, err)
+	}
+	return stream.Send(&api.ReadResponse{Data: buf.Bytes(), Eof: true})
+}
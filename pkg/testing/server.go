@@ -0,0 +1,43 @@
+package testing
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	api "github.com/VrushankPatel/pulsaar/api/v1"
+)
+
+const bufferSize = 1024 * 1024
+
+// Server runs an api.PulsaarAgentServer on an in-process listener.
+type Server struct {
+	lis  *bufconn.Listener
+	grpc *grpc.Server
+}
+
+// Serve starts serving agent, which may be an *Agent or any other
+// implementation. Call Close when done.
+func Serve(agent api.PulsaarAgentServer, opts ...grpc.ServerOption) *Server {
+	s := &Server{lis: bufconn.Listen(bufferSize), grpc: grpc.NewServer(opts...)}
+	api.RegisterPulsaarAgentServer(s.grpc, agent)
+	go func() { _ = s.grpc.Serve(s.lis) }()
+	return s
+}
+
+// Dial returns a connection to the server. The caller closes it.
+func (s *Server) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return s.lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, opts...)
+	return grpc.NewClient("passthrough:///pulsaar-agent", opts...)
+}
+
+// Close stops the server and closes open streams.
+func (s *Server) Close() {
+	s.grpc.Stop()
+}
@@ -0,0 +1,224 @@
+// Package webhook is the pod mutation made by the Pulsaar admission webhook.
+// The webhook server applies it to pods as they are created, and
+// pulsaar inject --dry-run renders it offline.
+package webhook
+
+import (
+	"encoding/json"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+const (
+	// InjectAnnotation is "true" on a pod, as an annotation or label, to
+	// inject the agent and "false" to keep it out. As a namespace label it
+	// sets the default for the namespace's pods.
+	InjectAnnotation = "pulsaar.io/inject-agent"
+	// ContainerName is the name of the injected agent container.
+	ContainerName = "pulsaar-agent"
+	// TLSVolumeName is the injected volume holding the agent's TLS Secret.
+	TLSVolumeName = "pulsaar-tls"
+	// PrivilegeAnnotation on a Linux pod sets the injected agent's
+	// privilege level, overriding PULSAAR_AGENT_PRIVILEGE. The agent runs
+	// non-root when neither is a valid level.
+	PrivilegeAnnotation = "pulsaar.io/agent-privilege"
+
+	defaultImage        = "pulsaar/agent:latest"
+	defaultWindowsImage = "pulsaar/agent:latest-windows"
+)
+
+// Images are agent images per pod OS. Empty fields fall back to
+// PULSAAR_AGENT_IMAGE and PULSAAR_AGENT_WINDOWS_IMAGE, then the published
+// images.
+type Images struct {
+	Linux   string
+	Windows string
+}
+
+// Policy decides which pods get the agent. The pod's own
+// pulsaar.io/inject-agent annotation or label takes precedence, then its
+// namespace's label, then the cluster default.
+type Policy struct {
+	// Namespace is the pulsaar.io/inject-agent label of the pod's
+	// namespace, or "" when it has none.
+	Namespace string
+	// Default applies to pods that neither they nor their namespace
+	// decide for.
+	Default bool
+}
+
+// ShouldInject reports whether pod gets the agent under p. Values other
+// than "true" and "false" are ignored.
+func (p Policy) ShouldInject(pod *corev1.Pod) bool {
+	for _, value := range []string{pod.Annotations[InjectAnnotation], pod.Labels[InjectAnnotation], p.Namespace} {
+		switch value {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	}
+	return p.Default
+}
+
+// ShouldInject reports whether pod asks for the agent itself.
+func ShouldInject(pod *corev1.Pod) bool {
+	return Policy{}.ShouldInject(pod)
+}
+
+// IsWindowsPod reports whether pod runs on a Windows node, from spec.os or
+// the kubernetes.io/os node selector.
+func IsWindowsPod(pod *corev1.Pod) bool {
+	if pod.Spec.OS != nil {
+		return pod.Spec.OS.Name == corev1.Windows
+	}
+	return pod.Spec.NodeSelector[corev1.LabelOSStable] == string(corev1.Windows)
+}
+
+// Sidecar returns the agent container and TLS volume injected into pod,
+// using the Windows image and paths on Windows nodes.
+func Sidecar(pod *corev1.Pod, images Images) (corev1.Container, corev1.Volume) {
+	image := firstNonEmpty(images.Linux, os.Getenv("PULSAAR_AGENT_IMAGE"), defaultImage)
+	tlsDir, sep := "/etc/pulsaar/tls", "/"
+	if IsWindowsPod(pod) {
+		image = firstNonEmpty(images.Windows, os.Getenv("PULSAAR_AGENT_WINDOWS_IMAGE"), defaultWindowsImage)
+		tlsDir, sep = `C:\etc\pulsaar\tls`, `\`
+	}
+	sidecar := corev1.Container{
+		Name:  ContainerName,
+		Image: image,
+		Ports: []corev1.ContainerPort{
+			{
+				ContainerPort: 50051,
+				Name:          "grpc",
+			},
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "PULSAAR_TLS_CERT_FILE",
+				Value: tlsDir + sep + "tls.crt",
+			},
+			{
+				Name:  "PULSAAR_TLS_KEY_FILE",
+				Value: tlsDir + sep + "tls.key",
+			},
+			// The pod's identity comes from the downward API: pods
+			// created by controllers have no name yet when admitted, and
+			// the agent needs no API access to learn it.
+			fieldEnv("PULSAAR_POD_NAME", "metadata.name"),
+			fieldEnv("PULSAAR_NAMESPACE", "metadata.namespace"),
+			fieldEnv("PULSAAR_NODE_NAME", "spec.nodeName"),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      TLSVolumeName,
+				MountPath: tlsDir,
+				ReadOnly:  true,
+			},
+		},
+	}
+	if !IsWindowsPod(pod) {
+		level := agentPrivilege(pod)
+		sidecar.SecurityContext = privilege.SecurityContext(level)
+		sidecar.Command = privilege.Command(level)
+		sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: privilege.Env, Value: level})
+	}
+	volume := corev1.Volume{
+		Name: TLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: "pulsaar-tls",
+			},
+		},
+	}
+	return sidecar, volume
+}
+
+// Mutate injects the agent into pod if it asks for it itself.
+func Mutate(pod *corev1.Pod, images Images) ([]byte, error) {
+	return Policy{}.Mutate(pod, images)
+}
+
+// Mutate injects the agent into pod if p says so, and returns the
+// equivalent JSON patch. Only what the pod lacks is added, so a pod
+// admitted again, on reinvocation or when a controller resubmits it, gets
+// no second agent. It returns nil for pods that need no change.
+func (p Policy) Mutate(pod *corev1.Pod, images Images) ([]byte, error) {
+	if !p.ShouldInject(pod) {
+		return nil, nil
+	}
+	sidecar, volume := Sidecar(pod, images)
+	var patch []map[string]interface{}
+	if !HasContainer(pod) {
+		pod.Spec.Containers = append(pod.Spec.Containers, sidecar)
+		patch = append(patch, map[string]interface{}{
+			"op":    "add",
+			"path":  "/spec/containers/-",
+			"value": sidecar,
+		})
+	}
+	if !HasVolume(pod) {
+		// Appending needs the array to exist; a pod without volumes
+		// gets a new one.
+		op := map[string]interface{}{"op": "add", "path": "/spec/volumes/-", "value": volume}
+		if len(pod.Spec.Volumes) == 0 {
+			op["path"], op["value"] = "/spec/volumes", []corev1.Volume{volume}
+		}
+		pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+		patch = append(patch, op)
+	}
+	if len(patch) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(patch)
+}
+
+// HasContainer reports whether pod already has the agent container.
+func HasContainer(pod *corev1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == ContainerName {
+			return true
+		}
+	}
+	return false
+}
+
+// HasVolume reports whether pod already has the agent's TLS volume.
+func HasVolume(pod *corev1.Pod) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == TLSVolumeName {
+			return true
+		}
+	}
+	return false
+}
+
+// agentPrivilege is the privilege level of the agent injected into pod.
+func agentPrivilege(pod *corev1.Pod) string {
+	for _, level := range []string{pod.Annotations[PrivilegeAnnotation], os.Getenv("PULSAAR_AGENT_PRIVILEGE")} {
+		if level != "" && privilege.Validate(level) == nil {
+			return level
+		}
+	}
+	return privilege.NonRoot
+}
+
+// fieldEnv is an environment variable set from a field of the pod.
+func fieldEnv(name, fieldPath string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name:      name,
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: fieldPath}},
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
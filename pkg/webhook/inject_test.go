@@ -0,0 +1,225 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/VrushankPatel/pulsaar/pkg/privilege"
+)
+
+func TestMutate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected bool // true if patch is generated
+	}{
+		{
+			name: "no annotation",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: "nginx"},
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "with annotation",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"pulsaar.io/inject-agent": "true",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: "nginx"},
+					},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := Mutate(tt.pod, Images{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (patch != nil) != tt.expected {
+				t.Errorf("expected patch %v, got %v", tt.expected, patch != nil)
+			}
+			if patch != nil {
+				var operations []map[string]interface{}
+				if err := json.Unmarshal(patch, &operations); err != nil {
+					t.Fatalf("invalid patch: %v", err)
+				}
+				if len(operations) != 2 {
+					t.Errorf("expected 2 operations, got %d", len(operations))
+				}
+			}
+		})
+	}
+}
+
+func TestMutateWindows(t *testing.T) {
+	t.Setenv("PULSAAR_AGENT_WINDOWS_IMAGE", "registry.local/pulsaar-agent:windows")
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"pulsaar.io/inject-agent": "true"},
+		},
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{"kubernetes.io/os": "windows"},
+			Containers:   []corev1.Container{{Name: "app", Image: "iis"}},
+		},
+	}
+	patch, err := Mutate(pod, Images{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var operations []struct {
+		Value json.RawMessage `json:"value"`
+	}
+	var sidecar corev1.Container
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		t.Fatalf("invalid patch: %v", err)
+	}
+	if err := json.Unmarshal(operations[0].Value, &sidecar); err != nil {
+		t.Fatalf("invalid container: %v", err)
+	}
+	if sidecar.Image != "registry.local/pulsaar-agent:windows" {
+		t.Errorf("expected Windows image, got %s", sidecar.Image)
+	}
+	if sidecar.VolumeMounts[0].MountPath != `C:\etc\pulsaar\tls` || sidecar.Env[0].Value != `C:\etc\pulsaar\tls\tls.crt` {
+		t.Errorf("expected Windows TLS paths, got %s and %s", sidecar.VolumeMounts[0].MountPath, sidecar.Env[0].Value)
+	}
+
+	if !IsWindowsPod(&corev1.Pod{Spec: corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}}}) {
+		t.Error("expected spec.os windows to be detected")
+	}
+	if IsWindowsPod(&corev1.Pod{Spec: corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Linux}, NodeSelector: map[string]string{"kubernetes.io/os": "windows"}}}) {
+		t.Error("expected spec.os to take precedence over the node selector")
+	}
+}
+
+func TestMutateOnlyAddsWhatIsMissing(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{InjectAnnotation: "true"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx"}},
+			Volumes:    []corev1.Volume{{Name: "data"}},
+		},
+	}
+	patch, err := Mutate(pod, Images{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(patch, &ops); err != nil || len(ops) != 2 || ops[1].Path != "/spec/volumes/-" {
+		t.Fatalf("expected the volume to be appended, got %s, %v", patch, err)
+	}
+	if again, err := Mutate(pod, Images{}); err != nil || again != nil {
+		t.Errorf("expected no patch for an injected pod, got %s, %v", again, err)
+	}
+
+	// A resubmitted pod that kept the container but lost the volume gets
+	// only the volume.
+	pod.Spec.Volumes = pod.Spec.Volumes[:1]
+	patch, err = Mutate(pod, Images{})
+	if err := json.Unmarshal(patch, &ops); err != nil || len(ops) != 1 || ops[0].Path != "/spec/volumes/-" {
+		t.Errorf("expected only the volume, got %s, %v", patch, err)
+	}
+	if len(pod.Spec.Containers) != 2 {
+		t.Errorf("expected one agent container, got %d containers", len(pod.Spec.Containers))
+	}
+}
+
+func TestSidecarIdentityFromDownwardAPI(t *testing.T) {
+	// Pods of a ReplicaSet are admitted with only a generateName.
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "web-7d9f-"}}
+	sidecar, _ := Sidecar(pod, Images{})
+	want := map[string]string{
+		"PULSAAR_POD_NAME":  "metadata.name",
+		"PULSAAR_NAMESPACE": "metadata.namespace",
+		"PULSAAR_NODE_NAME": "spec.nodeName",
+	}
+	for _, env := range sidecar.Env {
+		if path, ok := want[env.Name]; ok {
+			if env.ValueFrom == nil || env.ValueFrom.FieldRef == nil || env.ValueFrom.FieldRef.FieldPath != path {
+				t.Errorf("expected %s from %s, got %+v", env.Name, path, env)
+			}
+			delete(want, env.Name)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing %v", want)
+	}
+}
+
+func TestSidecarPrivilege(t *testing.T) {
+	pod := &corev1.Pod{}
+	sidecar, _ := Sidecar(pod, Images{})
+	if sc := sidecar.SecurityContext; sc == nil || !*sc.RunAsNonRoot || *sc.AllowPrivilegeEscalation || sidecar.Command != nil {
+		t.Errorf("expected a non-root agent by default, got %+v", sc)
+	}
+
+	t.Setenv("PULSAAR_AGENT_PRIVILEGE", privilege.DACReadSearch)
+	sidecar, _ = Sidecar(pod, Images{})
+	if len(sidecar.Command) != 1 || sidecar.Command[0] != privilege.DACReadSearchBinary {
+		t.Errorf("expected the cluster default to apply, got %v", sidecar.Command)
+	}
+
+	pod.Annotations = map[string]string{PrivilegeAnnotation: privilege.Root}
+	sidecar, _ = Sidecar(pod, Images{})
+	if *sidecar.SecurityContext.RunAsUser != 0 || sidecar.Env[len(sidecar.Env)-1].Value != privilege.Root {
+		t.Errorf("expected the annotation to take precedence, got %+v", sidecar)
+	}
+	pod.Annotations[PrivilegeAnnotation] = "admin"
+	if sidecar, _ = Sidecar(pod, Images{}); sidecar.Command[0] != privilege.DACReadSearchBinary {
+		t.Errorf("expected an invalid annotation to be ignored, got %v", sidecar.Command)
+	}
+
+	pod.Spec.OS = &corev1.PodOS{Name: corev1.Windows}
+	if sidecar, _ = Sidecar(pod, Images{}); sidecar.SecurityContext != nil {
+		t.Errorf("expected no Linux security context on Windows, got %+v", sidecar.SecurityContext)
+	}
+}
+
+func TestPolicyPrecedence(t *testing.T) {
+	pod := func(annotation, label string) *corev1.Pod {
+		p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}, Labels: map[string]string{}}}
+		if annotation != "" {
+			p.Annotations[InjectAnnotation] = annotation
+		}
+		if label != "" {
+			p.Labels[InjectAnnotation] = label
+		}
+		return p
+	}
+	for _, tt := range []struct {
+		annotation, label string
+		policy            Policy
+		want              bool
+	}{
+		{"", "", Policy{}, false},
+		{"", "", Policy{Default: true}, true},
+		{"", "", Policy{Namespace: "true"}, true},
+		{"", "", Policy{Namespace: "false", Default: true}, false},
+		{"", "false", Policy{Namespace: "true"}, false},
+		{"false", "", Policy{Namespace: "true", Default: true}, false},
+		{"true", "", Policy{Namespace: "false"}, true},
+		{"true", "false", Policy{}, true},
+		{"yes", "", Policy{Namespace: "true"}, true},
+	} {
+		if got := tt.policy.ShouldInject(pod(tt.annotation, tt.label)); got != tt.want {
+			t.Errorf("annotation %q, label %q, %+v: got %v, want %v", tt.annotation, tt.label, tt.policy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,608 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// verifiedTTL is how long a verified image digest is reused before the
+	// registry is asked again.
+	verifiedTTL = 5 * time.Minute
+	// registryTimeout keeps registry round trips within the webhook's own
+	// timeout.
+	registryTimeout = 4 * time.Second
+
+	maxManifestSize = 4 << 20
+	maxPayloadSize  = 1 << 20
+
+	signatureAnnotation   = "dev.cosignproject.cosign/signature"
+	certificateAnnotation = "dev.sigstore.cosign/certificate"
+	chainAnnotation       = "dev.sigstore.cosign/chain"
+	bundleAnnotation      = "dev.sigstore.cosign/bundle"
+	signatureType         = "cosign container image signature"
+)
+
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Fulcio certificate extensions naming the OIDC issuer of the signer's
+// identity: the current DER-encoded one and the deprecated raw one.
+var (
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+)
+
+// VerifyConfig is how agent images must be signed, as PEM and strings.
+// Key selects key-based signing; Identity selects keyless signing with a
+// Fulcio certificate, which also needs FulcioRoots and RekorKey. Either
+// may be set, or both, in which case a signature passing either is enough.
+type VerifyConfig struct {
+	// Key is the cosign public key, as written by cosign generate-key-pair.
+	Key []byte
+	// FulcioRoots are the Fulcio CA certificates keyless signing
+	// certificates must chain to.
+	FulcioRoots []byte
+	// RekorKey is the Rekor transparency log's public key, which signs the
+	// log entry proving when a keyless signature was made.
+	RekorKey []byte
+	// Identity is a regular expression the certificate's email or URI
+	// subject alternative name must match in full.
+	Identity string
+	// Issuer is the OIDC issuer that must have vouched for the identity,
+	// e.g. https://token.actions.githubusercontent.com.
+	Issuer string
+}
+
+// ImageVerifier checks cosign signatures on agent images before they are
+// injected. Signatures are read from the image's registry, which must allow
+// anonymous pulls or bearer tokens issued without credentials.
+type ImageVerifier struct {
+	key         crypto.PublicKey
+	fulcioRoots *x509.CertPool
+	rekorKey    crypto.PublicKey
+	identity    *regexp.Regexp
+	issuer      string
+
+	// Client fetches from registries; tests replace it.
+	Client *http.Client
+
+	mu       sync.Mutex
+	verified map[string]verifiedImage
+}
+
+type verifiedImage struct {
+	pinned string
+	at     time.Time
+}
+
+// NewImageVerifier returns a verifier for cfg, or nil when cfg asks for no
+// verification.
+func NewImageVerifier(cfg VerifyConfig) (*ImageVerifier, error) {
+	if len(cfg.Key) == 0 && cfg.Identity == "" {
+		if len(cfg.FulcioRoots) > 0 || len(cfg.RekorKey) > 0 || cfg.Issuer != "" {
+			return nil, errors.New("keyless verification needs a signer identity")
+		}
+		return nil, nil
+	}
+	v := &ImageVerifier{
+		issuer:   cfg.Issuer,
+		Client:   &http.Client{Timeout: registryTimeout},
+		verified: map[string]verifiedImage{},
+	}
+	var err error
+	if len(cfg.Key) > 0 {
+		if v.key, err = parsePublicKey(cfg.Key); err != nil {
+			return nil, fmt.Errorf("invalid cosign public key: %v", err)
+		}
+	}
+	if cfg.Identity == "" {
+		return v, nil
+	}
+	if v.identity, err = regexp.Compile("^(?:" + cfg.Identity + ")$"); err != nil {
+		return nil, fmt.Errorf("invalid signer identity %q: %v", cfg.Identity, err)
+	}
+	if len(cfg.FulcioRoots) == 0 || len(cfg.RekorKey) == 0 {
+		return nil, errors.New("keyless verification needs the Fulcio root certificates and the Rekor public key")
+	}
+	v.fulcioRoots = x509.NewCertPool()
+	if !v.fulcioRoots.AppendCertsFromPEM(cfg.FulcioRoots) {
+		return nil, errors.New("no certificates found in the Fulcio roots")
+	}
+	if v.rekorKey, err = parsePublicKey(cfg.RekorKey); err != nil {
+		return nil, fmt.Errorf("invalid Rekor public key: %v", err)
+	}
+	return v, nil
+}
+
+// ImageVerifierFromEnv returns the verifier configured by
+// PULSAAR_COSIGN_KEY, PULSAAR_COSIGN_FULCIO_ROOT, PULSAAR_COSIGN_REKOR_KEY,
+// PULSAAR_COSIGN_IDENTITY and PULSAAR_COSIGN_ISSUER, or nil when none are
+// set. The key and certificate variables hold PEM or the path of a PEM file.
+func ImageVerifierFromEnv() (*ImageVerifier, error) {
+	var cfg VerifyConfig
+	var err error
+	for _, f := range []struct {
+		env string
+		dst *[]byte
+	}{
+		{"PULSAAR_COSIGN_KEY", &cfg.Key},
+		{"PULSAAR_COSIGN_FULCIO_ROOT", &cfg.FulcioRoots},
+		{"PULSAAR_COSIGN_REKOR_KEY", &cfg.RekorKey},
+	} {
+		if *f.dst, err = pemSetting(os.Getenv(f.env)); err != nil {
+			return nil, fmt.Errorf("%s: %v", f.env, err)
+		}
+	}
+	cfg.Identity = os.Getenv("PULSAAR_COSIGN_IDENTITY")
+	cfg.Issuer = os.Getenv("PULSAAR_COSIGN_ISSUER")
+	return NewImageVerifier(cfg)
+}
+
+// pemSetting returns value if it is PEM, else the contents of the file it
+// names.
+func pemSetting(value string) ([]byte, error) {
+	if value == "" || strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// Verify checks that image carries a valid cosign signature and returns it
+// pinned to the verified digest, so the kubelet pulls exactly what was
+// verified even if the tag moves.
+func (v *ImageVerifier) Verify(ctx context.Context, image string) (string, error) {
+	v.mu.Lock()
+	cached, ok := v.verified[image]
+	v.mu.Unlock()
+	if ok && time.Since(cached.at) < verifiedTTL {
+		return cached.pinned, nil
+	}
+
+	ref, err := parseImageRef(image)
+	if err != nil {
+		return "", err
+	}
+	reg := &registry{client: v.Client, ref: ref}
+	manifest, err := reg.get(ctx, "manifests/"+ref.reference(), manifestTypes, maxManifestSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve image %s: %v", image, err)
+	}
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+	if ref.digest != "" && ref.digest != digest {
+		return "", fmt.Errorf("image %s: registry returned a manifest with digest %s", image, digest)
+	}
+	if err := v.verifyDigest(ctx, reg, digest); err != nil {
+		return "", fmt.Errorf("image %s: %v", image, err)
+	}
+
+	pinned := ref.name + "@" + digest
+	v.mu.Lock()
+	v.verified[image] = verifiedImage{pinned: pinned, at: time.Now()}
+	v.mu.Unlock()
+	return pinned, nil
+}
+
+// verifyDigest reads the cosign signature manifest for digest and checks
+// its signatures until one passes.
+func (v *ImageVerifier) verifyDigest(ctx context.Context, reg *registry, digest string) error {
+	tag := strings.Replace(digest, ":", "-", 1) + ".sig"
+	raw, err := reg.get(ctx, "manifests/"+tag, manifestTypes, maxManifestSize)
+	if errors.Is(err, errNotFound) {
+		return fmt.Errorf("no cosign signature found for %s", digest)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the signatures of %s: %v", digest, err)
+	}
+	var manifest struct {
+		Layers []struct {
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("invalid signature manifest: %v", err)
+	}
+
+	err = fmt.Errorf("no cosign signature found for %s", digest)
+	for _, layer := range manifest.Layers {
+		sig, ok := layer.Annotations[signatureAnnotation]
+		if !ok {
+			continue
+		}
+		payload, getErr := reg.get(ctx, "blobs/"+layer.Digest, nil, maxPayloadSize)
+		if getErr != nil {
+			err = fmt.Errorf("failed to read signature payload %s: %v", layer.Digest, getErr)
+			continue
+		}
+		if fmt.Sprintf("sha256:%x", sha256.Sum256(payload)) != layer.Digest {
+			err = fmt.Errorf("signature payload %s does not match its digest", layer.Digest)
+			continue
+		}
+		if err = v.verifySignature(digest, payload, sig, layer.Annotations); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// verifySignature checks one signature layer: that its payload names
+// digest and that the signature over it is from the configured key or
+// keyless signer.
+func (v *ImageVerifier) verifySignature(digest string, payload []byte, sig string, annotations map[string]string) error {
+	var simple struct {
+		Critical struct {
+			Image struct {
+				Digest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+			Type string `json:"type"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simple); err != nil {
+		return fmt.Errorf("invalid signature payload: %v", err)
+	}
+	if simple.Critical.Type != signatureType || simple.Critical.Image.Digest != digest {
+		return fmt.Errorf("signature payload is for %s, not %s", simple.Critical.Image.Digest, digest)
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %v", err)
+	}
+
+	err = errors.New("signature is not from the configured key or signer")
+	if v.key != nil {
+		if err = checkSignature(v.key, payload, rawSig); err == nil {
+			return nil
+		}
+		err = errors.New("signature does not match the cosign public key")
+	}
+	if v.identity != nil && annotations[certificateAnnotation] != "" {
+		err = v.verifyKeyless(payload, rawSig, annotations)
+	}
+	return err
+}
+
+// verifyKeyless checks a signature made with a short-lived Fulcio
+// certificate: the certificate chains to the Fulcio roots at the time Rekor
+// logged the signature, names the configured identity and issuer, and the
+// signed log entry is for this signature and payload.
+func (v *ImageVerifier) verifyKeyless(payload, sig []byte, annotations map[string]string) error {
+	block, _ := pem.Decode([]byte(annotations[certificateAnnotation]))
+	if block == nil {
+		return errors.New("invalid signing certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid signing certificate: %v", err)
+	}
+	entry, err := v.verifyBundle(annotations[bundleAnnotation])
+	if err != nil {
+		return err
+	}
+
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM([]byte(annotations[chainAnnotation]))
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.fulcioRoots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Unix(entry.IntegratedTime, 0),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return fmt.Errorf("signing certificate is not from Fulcio: %v", err)
+	}
+	if identity := certIdentity(cert, v.identity); identity == "" {
+		return fmt.Errorf("signing certificate identity %s does not match %s", strings.Join(certIdentities(cert), ", "), v.identity)
+	}
+	if v.issuer != "" {
+		if issuer := certIssuer(cert); issuer != v.issuer {
+			return fmt.Errorf("signing certificate issuer %q is not %q", issuer, v.issuer)
+		}
+	}
+	if err := checkSignature(cert.PublicKey, payload, sig); err != nil {
+		return errors.New("signature does not match the signing certificate")
+	}
+
+	// The log entry must record this signature, over this payload, by this
+	// certificate; otherwise its timestamp proves nothing about it.
+	body, err := base64.StdEncoding.DecodeString(entry.Body)
+	if err != nil {
+		return fmt.Errorf("invalid Rekor entry: %v", err)
+	}
+	var rekord struct {
+		Spec struct {
+			Signature struct {
+				Content   []byte `json:"content"`
+				PublicKey struct {
+					Content []byte `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+			Data struct {
+				Hash struct {
+					Value string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(body, &rekord); err != nil {
+		return fmt.Errorf("invalid Rekor entry: %v", err)
+	}
+	logged, _ := pem.Decode(rekord.Spec.Signature.PublicKey.Content)
+	hash := sha256.Sum256(payload)
+	if !bytes.Equal(rekord.Spec.Signature.Content, sig) || logged == nil || !bytes.Equal(logged.Bytes, cert.Raw) ||
+		rekord.Spec.Data.Hash.Value != hex.EncodeToString(hash[:]) {
+		return errors.New("Rekor entry does not match the signature")
+	}
+	return nil
+}
+
+// rekorPayload is a Rekor log entry as signed in its signed entry
+// timestamp. Fields are in canonical JSON order, so marshalling it
+// reproduces the signed bytes.
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// verifyBundle checks the Rekor bundle attached to a keyless signature and
+// returns its log entry.
+func (v *ImageVerifier) verifyBundle(raw string) (rekorPayload, error) {
+	var bundle struct {
+		SignedEntryTimestamp []byte
+		Payload              rekorPayload
+	}
+	if raw == "" {
+		return bundle.Payload, errors.New("keyless signature has no Rekor bundle")
+	}
+	if err := json.Unmarshal([]byte(raw), &bundle); err != nil {
+		return bundle.Payload, fmt.Errorf("invalid Rekor bundle: %v", err)
+	}
+	signed, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return bundle.Payload, err
+	}
+	if err := checkSignature(v.rekorKey, signed, bundle.SignedEntryTimestamp); err != nil {
+		return bundle.Payload, errors.New("Rekor bundle is not signed by the Rekor public key")
+	}
+	return bundle.Payload, nil
+}
+
+func checkSignature(key crypto.PublicKey, payload, sig []byte) error {
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, hash[:], sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+func certIdentities(cert *x509.Certificate) []string {
+	ids := append([]string{}, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+	return ids
+}
+
+// certIdentity returns the first subject alternative name of cert that
+// matches identity, or "".
+func certIdentity(cert *x509.Certificate, identity *regexp.Regexp) string {
+	for _, id := range certIdentities(cert) {
+		if identity.MatchString(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+func certIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV2) {
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV1) {
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// imageRef is an image reference split into what the registry API needs.
+type imageRef struct {
+	name   string // the reference without its digest, as written
+	host   string
+	repo   string
+	tag    string
+	digest string
+}
+
+func (r imageRef) reference() string {
+	if r.digest != "" {
+		return r.digest
+	}
+	return r.tag
+}
+
+func parseImageRef(image string) (imageRef, error) {
+	ref := imageRef{name: image}
+	if i := strings.Index(image, "@"); i >= 0 {
+		ref.name, ref.digest = image[:i], image[i+1:]
+		if !regexp.MustCompile(`^sha256:[0-9a-f]{64}$`).MatchString(ref.digest) {
+			return ref, fmt.Errorf("invalid image %q: unsupported digest", image)
+		}
+	}
+	repo := ref.name
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, ref.tag = repo[:i], repo[i+1:]
+	}
+	if ref.tag == "" && ref.digest == "" {
+		ref.tag = "latest"
+	}
+	first, rest, found := strings.Cut(repo, "/")
+	switch {
+	case found && (strings.ContainsAny(first, ".:") || first == "localhost"):
+		ref.host, ref.repo = first, rest
+	case found:
+		ref.host, ref.repo = "registry-1.docker.io", repo
+	default:
+		ref.host, ref.repo = "registry-1.docker.io", "library/"+repo
+	}
+	if ref.host == "docker.io" || ref.host == "index.docker.io" {
+		ref.host = "registry-1.docker.io"
+	}
+	if ref.repo == "" {
+		return ref, fmt.Errorf("invalid image %q", image)
+	}
+	return ref, nil
+}
+
+var errNotFound = errors.New("not found")
+
+// registry reads from one repository with the OCI distribution API,
+// fetching an anonymous bearer token when the registry asks for one.
+type registry struct {
+	client *http.Client
+	ref    imageRef
+	token  string
+}
+
+func (r *registry) get(ctx context.Context, path string, accept []string, limit int64) ([]byte, error) {
+	u := "https://" + r.ref.host + "/v2/" + r.ref.repo + "/" + path
+	resp, err := r.do(ctx, u, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+		if r.token, err = r.fetchToken(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = r.do(ctx, u, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s returned %s", r.ref.host, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", path, limit)
+	}
+	return body, nil
+}
+
+func (r *registry) do(ctx context.Context, u string, accept []string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	return r.client.Do(req)
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// fetchToken answers a Bearer challenge with an anonymous pull token.
+func (r *registry) fetchToken(ctx context.Context, challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("%s requires credentials", r.ref.host)
+	}
+	params := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return "", fmt.Errorf("%s sent an invalid token realm %q", r.ref.host, params["realm"])
+	}
+	q := realm.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	q.Set("scope", "repository:"+r.ref.repo+":pull")
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s refused an anonymous token: %s", realm.Host, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxPayloadSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token from %s: %v", realm.Host, err)
+	}
+	return firstNonEmpty(token.Token, token.AccessToken), nil
+}
@@ -0,0 +1,254 @@
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRegistry serves manifests and blobs for one repository, handing out
+// an anonymous token as Docker Hub does.
+type fakeRegistry struct {
+	*httptest.Server
+	manifests map[string][]byte
+	blobs     map[string][]byte
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			if req.URL.Query().Get("scope") != "repository:pulsaar/agent:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"anonymous"}`))
+			return
+		}
+		if req.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var content []byte
+		if ref, ok := strings.CutPrefix(req.URL.Path, "/v2/pulsaar/agent/manifests/"); ok {
+			content = r.manifests[ref]
+		} else if digest, ok := strings.CutPrefix(req.URL.Path, "/v2/pulsaar/agent/blobs/"); ok {
+			content = r.blobs[digest]
+		}
+		if content == nil {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// push adds an image under tag and returns its reference and digest.
+func (r *fakeRegistry) push(tag string) (string, string) {
+	manifest := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","annotations":{"tag":%q}}`, tag))
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+	r.manifests[tag] = manifest
+	r.manifests[digest] = manifest
+	return strings.TrimPrefix(r.URL, "https://") + "/pulsaar/agent:" + tag, digest
+}
+
+// sign attaches a signature layer for digest made with key.
+func (r *fakeRegistry) sign(t *testing.T, digest string, key *ecdsa.PrivateKey) (payload, sig []byte) {
+	payload = []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"pulsaar/agent"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadDigest := fmt.Sprintf("sha256:%x", hash)
+	r.blobs[payloadDigest] = payload
+	annotations := map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(sig)}
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"layers": []map[string]interface{}{{
+			"mediaType":   "application/vnd.dev.cosign.simplesigning.v1+json",
+			"digest":      payloadDigest,
+			"annotations": annotations,
+		}},
+	})
+	r.manifests[strings.Replace(digest, ":", "-", 1)+".sig"] = manifest
+	return payload, sig
+}
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestImageVerifierKey(t *testing.T) {
+	reg := newFakeRegistry(t)
+	key, pub := newKey(t)
+	other, _ := newKey(t)
+	v, err := NewImageVerifier(VerifyConfig{Key: pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Client = reg.Client()
+
+	signed, digest := reg.push("v1")
+	reg.sign(t, digest, key)
+	pinned, err := v.Verify(context.Background(), signed)
+	if err != nil {
+		t.Fatalf("expected a signed image to verify: %v", err)
+	}
+	if pinned != signed+"@"+digest {
+		t.Errorf("expected the image pinned to %s, got %s", digest, pinned)
+	}
+
+	unsigned, _ := reg.push("v2")
+	if _, err := v.Verify(context.Background(), unsigned); err == nil || !strings.Contains(err.Error(), "no cosign signature") {
+		t.Errorf("expected an unsigned image to be rejected, got %v", err)
+	}
+
+	forged, digest := reg.push("v3")
+	reg.sign(t, digest, other)
+	if _, err := v.Verify(context.Background(), forged); err == nil || !strings.Contains(err.Error(), "does not match the cosign public key") {
+		t.Errorf("expected a signature by another key to be rejected, got %v", err)
+	}
+
+	// A signature copied from another image names the wrong digest.
+	copied, digest := reg.push("v4")
+	reg.manifests[strings.Replace(digest, ":", "-", 1)+".sig"] = reg.manifests[strings.Replace(strings.TrimPrefix(pinned, signed+"@"), ":", "-", 1)+".sig"]
+	if _, err := v.Verify(context.Background(), copied); err == nil || !strings.Contains(err.Error(), "signature payload is for") {
+		t.Errorf("expected a signature for another digest to be rejected, got %v", err)
+	}
+}
+
+func TestImageVerifierKeyless(t *testing.T) {
+	reg := newFakeRegistry(t)
+	rekor, rekorPub := newKey(t)
+	rootKey, _ := newKey(t)
+	root := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sigstore"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, root, root, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ = x509.ParseCertificate(rootDER)
+
+	signer, _ := newKey(t)
+	issuer, _ := asn1.MarshalWithParams("https://token.actions.githubusercontent.com", "utf8")
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{"release@pulsaar.io"},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}, root, &signer.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+
+	image, digest := reg.push("v1")
+	// Sign once to learn the payload and signature, then attach them with
+	// the certificate and a Rekor bundle logging them.
+	payload, sig := reg.sign(t, digest, signer)
+	hash := sha256.Sum256(payload)
+	body, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]interface{}{
+			"data":      map[string]interface{}{"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(hash[:])}},
+			"signature": map[string]interface{}{"content": sig, "publicKey": map[string][]byte{"content": leafPEM}},
+		},
+	})
+	entry := rekorPayload{Body: base64.StdEncoding.EncodeToString(body), IntegratedTime: time.Now().Unix(), LogID: "c0d23d6a", LogIndex: 7}
+	signed, _ := json.Marshal(entry)
+	signedHash := sha256.Sum256(signed)
+	set, _ := ecdsa.SignASN1(rand.Reader, rekor, signedHash[:])
+	bundle, _ := json.Marshal(map[string]interface{}{"SignedEntryTimestamp": set, "Payload": entry})
+
+	var manifest map[string]interface{}
+	_ = json.Unmarshal(reg.manifests[strings.Replace(digest, ":", "-", 1)+".sig"], &manifest)
+	annotations := manifest["layers"].([]interface{})[0].(map[string]interface{})["annotations"].(map[string]interface{})
+	annotations[certificateAnnotation] = string(leafPEM)
+	annotations[bundleAnnotation] = string(bundle)
+	reg.manifests[strings.Replace(digest, ":", "-", 1)+".sig"], _ = json.Marshal(manifest)
+
+	rootPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw})
+	for _, tt := range []struct {
+		identity, issuer, err string
+	}{
+		{`.*@pulsaar\.io`, "https://token.actions.githubusercontent.com", ""},
+		{`ops@pulsaar\.io`, "", "does not match"},
+		{`release@pulsaar\.io`, "https://accounts.google.com", "issuer"},
+	} {
+		v, err := NewImageVerifier(VerifyConfig{FulcioRoots: rootPEM, RekorKey: rekorPub, Identity: tt.identity, Issuer: tt.issuer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.Client = reg.Client()
+		_, err = v.Verify(context.Background(), image)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: expected the keyless signature to verify: %v", tt.identity, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.identity, tt.err, err)
+		}
+	}
+
+	if _, err := NewImageVerifier(VerifyConfig{Identity: "x"}); err == nil {
+		t.Error("expected keyless verification without trust roots to be refused")
+	}
+}
+
+func TestParseImageRef(t *testing.T) {
+	for image, want := range map[string]string{
+		"pulsaar/agent:latest":     "registry-1.docker.io pulsaar/agent latest",
+		"busybox":                  "registry-1.docker.io library/busybox latest",
+		"ghcr.io/pulsaar/agent:v1": "ghcr.io pulsaar/agent v1",
+		"localhost:5000/agent":     "localhost:5000 agent latest",
+		"docker.io/pulsaar/agent@sha256:" + strings.Repeat("a", 64): "registry-1.docker.io pulsaar/agent sha256:" + strings.Repeat("a", 64),
+	} {
+		ref, err := parseImageRef(image)
+		if err != nil {
+			t.Fatalf("%s: %v", image, err)
+		}
+		if got := ref.host + " " + ref.repo + " " + ref.reference(); got != want {
+			t.Errorf("%s: got %q, want %q", image, got, want)
+		}
+	}
+	if _, err := parseImageRef("agent@sha256:abc"); err == nil {
+		t.Error("expected a malformed digest to be rejected")
+	}
+}
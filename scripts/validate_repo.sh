@@ -5,11 +5,16 @@ set -euo pipefail
 test -f vision.md || { echo "vision.md missing"; exit 2; }
 
 # 2. Ensure proto exists
-test -f api/pulsaar.proto || { echo "api/pulsaar.proto missing"; exit 2; }
+test -f api/v1/pulsaar.proto || { echo "api/v1/pulsaar.proto missing"; exit 2; }
 
 # 3. Generate proto go stubs if protoc present
 if command -v protoc >/dev/null 2>&1; then
-  protoc --go_out=. --go-grpc_out=. api/pulsaar.proto
+  protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative api/v1/pulsaar.proto
+fi
+
+# 3a. Reject incompatible API changes if buf present
+if command -v buf >/dev/null 2>&1 && git rev-parse --verify -q master >/dev/null; then
+  buf breaking --against '.git#branch=master' || { echo "api/v1 has breaking changes"; exit 2; }
 fi
 
 # 4. Check that progress.md contains 'Next steps'
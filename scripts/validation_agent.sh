@@ -1,8 +1,8 @@
 #!/usr/bin/env bash
 set -euo pipefail
 
-# 1. Parse api/pulsaar.proto for RPCs
-rpcs=$(grep 'rpc ' api/pulsaar.proto | sed 's/.*rpc \([A-Za-z]*\).*/\1/')
+# 1. Parse api/v1/pulsaar.proto for RPCs
+rpcs=$(grep 'rpc ' api/v1/pulsaar.proto | sed 's/.*rpc \([A-Za-z]*\).*/\1/')
 echo "RPCs found: $rpcs"
 
 # 2. For every RPC ensure an implementation exists